@@ -0,0 +1,521 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/api/common"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
+	azStorage "github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/gofrs/uuid"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cleanupName             = "cleanup"
+	cleanupShortDescription = "Find and delete the orphaned resources of an existing Kubernetes cluster"
+	cleanupLongDescription  = "Find the network interfaces without a virtual machine, the unattached managed disks, the OS and data disk VHD blobs without a virtual machine and the role assignments of deleted principals that failed scale and upgrade operations left in the resource group of a cluster built with AKS Engine. The orphaned resources are only listed, unless --delete is given."
+)
+
+// vhdContainers are the storage containers of the OS and data disk VHD blobs of clusters which do not use managed disks
+var vhdContainers = []string{"vhds", "osdisk"}
+
+type cleanupCmd struct {
+	authProvider
+
+	// user input
+	resourceGroupName string
+	location          string
+	apiModelPath      string
+	delete            bool
+	yes               bool
+
+	// derived
+	containerService *api.ContainerService
+	apiVersion       string
+	locale           *gotext.Locale
+	client           armhelpers.AKSEngineClient
+	nameSuffix       string
+	in               io.Reader
+	out              io.Writer
+}
+
+// orphanedResource is a resource of the cluster which is not used anymore
+type orphanedResource struct {
+	Type   string
+	Name   string
+	Reason string
+	delete func(ctx context.Context) error
+}
+
+func newCleanupCmd() *cobra.Command {
+	cc := cleanupCmd{
+		authProvider: &authArgs{},
+		in:           os.Stdin,
+		out:          os.Stdout,
+	}
+
+	command := &cobra.Command{
+		Use:   cleanupName,
+		Short: cleanupShortDescription,
+		Long:  cleanupLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cc.validate(cmd); err != nil {
+				return errors.Wrap(err, "validating cleanupCmd")
+			}
+			return cc.run()
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&cc.location, "location", "l", "", "location the cluster is deployed in (required)")
+	f.StringVarP(&cc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed (required)")
+	f.StringVarP(&cc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file (required)")
+	f.BoolVar(&cc.delete, "delete", false, "delete the orphaned resources, they are only listed by default")
+	f.BoolVarP(&cc.yes, "yes", "y", false, "do not ask for confirmation before deleting the orphaned resources")
+
+	addAuthFlags(cc.getAuthArgs(), f)
+
+	return command
+}
+
+func (cc *cleanupCmd) validate(cmd *cobra.Command) error {
+	var err error
+
+	cc.locale, err = i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+
+	if cc.resourceGroupName == "" {
+		cmd.Usage()
+		return errors.New("--resource-group must be specified")
+	}
+
+	if cc.location == "" {
+		cmd.Usage()
+		return errors.New("--location must be specified")
+	}
+	cc.location = helpers.NormalizeAzureRegion(cc.location)
+
+	if cc.apiModelPath == "" {
+		cmd.Usage()
+		return errors.New("--api-model must be specified")
+	}
+
+	if cc.yes && !cc.delete {
+		cmd.Usage()
+		return errors.New("--yes can only be specified with --delete")
+	}
+
+	return nil
+}
+
+func (cc *cleanupCmd) run() error {
+	var err error
+
+	if err = cc.getAuthArgs().validateAuthArgs(); err != nil {
+		return errors.Wrap(err, "failed to get validate auth args")
+	}
+
+	if cc.client, err = cc.authProvider.getClient(); err != nil {
+		return errors.Wrap(err, "failed to get client")
+	}
+
+	if err = cc.loadCluster(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+
+	orphans, err := cc.getOrphanedResources(ctx)
+	if err != nil {
+		return err
+	}
+	return cc.cleanup(ctx, orphans)
+}
+
+func (cc *cleanupCmd) loadCluster() error {
+	var err error
+
+	if _, err = os.Stat(cc.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", cc.apiModelPath)
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: cc.locale,
+		},
+	}
+	cc.containerService, cc.apiVersion, err = apiloader.LoadContainerServiceFromFile(cc.apiModelPath, true, true, nil)
+	if err != nil {
+		return errors.Wrap(err, "parsing the api model")
+	}
+
+	if !cc.containerService.Properties.OrchestratorProfile.IsKubernetes() {
+		return errors.New("cleanup is only supported for Kubernetes clusters")
+	}
+
+	//allows to identify VMs in the resource group that belong to this cluster.
+	cc.nameSuffix = cc.containerService.Properties.GetClusterID()
+	return nil
+}
+
+// cleanup prints the orphaned resources and deletes them if --delete is specified and the deletion is confirmed
+func (cc *cleanupCmd) cleanup(ctx context.Context, orphans []orphanedResource) error {
+	if len(orphans) == 0 {
+		fmt.Fprintln(cc.out, "No orphaned resources found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cc.out, 0, 0, 2, ' ', tabwriter.FilterHTML)
+	fmt.Fprintln(w, "TYPE\tNAME\tREASON")
+	for _, o := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", o.Type, o.Name, o.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !cc.delete {
+		fmt.Fprintf(cc.out, "Found %d orphaned resources, run again with --delete to delete them.\n", len(orphans))
+		return nil
+	}
+	if !cc.yes && !cc.confirm(fmt.Sprintf("Delete the %d orphaned resources listed above?", len(orphans))) {
+		log.Infoln("Orphaned resources were not deleted")
+		return nil
+	}
+
+	var failed int
+	for _, o := range orphans {
+		log.Infof("Deleting %s %s", o.Type, o.Name)
+		if err := o.delete(ctx); err != nil {
+			log.Errorf("Failed to delete %s %s: %s", o.Type, o.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to delete %d of %d orphaned resources", failed, len(orphans))
+	}
+	return nil
+}
+
+// confirm asks the question and returns true if the answer is yes
+func (cc *cleanupCmd) confirm(question string) bool {
+	fmt.Fprintf(cc.out, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(cc.in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// getOrphanedResources lists the resources of the cluster which are not used by its virtual machines anymore
+func (cc *cleanupCmd) getOrphanedResources(ctx context.Context) ([]orphanedResource, error) {
+	vmNames, err := cc.getVMNames(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing virtual machines")
+	}
+	var orphans []orphanedResource
+
+	nics, err := cc.getOrphanedNetworkInterfaces(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing network interfaces")
+	}
+	orphans = append(orphans, nics...)
+
+	disks, err := cc.getOrphanedManagedDisks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing managed disks")
+	}
+	orphans = append(orphans, disks...)
+
+	blobs, err := cc.getOrphanedBlobs(ctx, vmNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing VHD blobs")
+	}
+	orphans = append(orphans, blobs...)
+
+	if cc.containerService.Properties.IsAzureStackCloud() {
+		log.Warnln("Skipping role assignments, Azure Stack does not support listing them")
+		return orphans, nil
+	}
+	roleAssignments, err := cc.getOrphanedRoleAssignments(ctx, vmNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing role assignments")
+	}
+	return append(orphans, roleAssignments...), nil
+}
+
+// getVMNames returns the names of the virtual machines of the cluster, identified by their resourceNameSuffix tag
+// or, if they are not tagged, by their name
+func (cc *cleanupCmd) getVMNames(ctx context.Context) ([]string, error) {
+	var names []string
+	for page, err := cc.client.ListVirtualMachines(ctx, cc.resourceGroupName); page.NotDone(); err = page.Next() {
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range page.Values() {
+			suffix := getTag(vm.Tags, "resourceNameSuffix")
+			if suffix == cc.nameSuffix || suffix == cc.nameSuffix[:4] || (suffix == "" && cc.isClusterResource(*vm.Name)) {
+				names = append(names, *vm.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// getOrphanedNetworkInterfaces returns the network interfaces of the cluster which are not attached to a VM
+func (cc *cleanupCmd) getOrphanedNetworkInterfaces(ctx context.Context) ([]orphanedResource, error) {
+	var orphans []orphanedResource
+	for page, err := cc.client.ListNetworkInterfaces(ctx, cc.resourceGroupName); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, nic := range page.Values() {
+			if !cc.isClusterResource(*nic.Name) || (nic.InterfacePropertiesFormat != nil && nic.VirtualMachine != nil) {
+				continue
+			}
+			name := *nic.Name
+			orphans = append(orphans, orphanedResource{
+				Type:   "network interface",
+				Name:   name,
+				Reason: "not attached to a virtual machine",
+				delete: func(ctx context.Context) error {
+					return cc.client.DeleteNetworkInterface(ctx, cc.resourceGroupName, name)
+				},
+			})
+		}
+	}
+	return orphans, nil
+}
+
+// getOrphanedManagedDisks returns the managed disks of the cluster which are not attached to a VM
+func (cc *cleanupCmd) getOrphanedManagedDisks(ctx context.Context) ([]orphanedResource, error) {
+	var orphans []orphanedResource
+	for page, err := cc.client.ListManagedDisksByResourceGroup(ctx, cc.resourceGroupName); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, disk := range page.Values() {
+			if !cc.isClusterResource(*disk.Name) || disk.ManagedBy != nil {
+				continue
+			}
+			name := *disk.Name
+			orphans = append(orphans, orphanedResource{
+				Type:   "managed disk",
+				Name:   name,
+				Reason: "not attached to a virtual machine",
+				delete: func(ctx context.Context) error {
+					return cc.client.DeleteManagedDisk(ctx, cc.resourceGroupName, name)
+				},
+			})
+		}
+	}
+	return orphans, nil
+}
+
+// getOrphanedBlobs returns the VHD blobs of the cluster whose virtual machine does not exist anymore, the name of
+// these blobs starts with the name of their virtual machine
+func (cc *cleanupCmd) getOrphanedBlobs(ctx context.Context, vmNames []string) ([]orphanedResource, error) {
+	accountNames, err := cc.client.ListStorageAccountNames(ctx, cc.resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []orphanedResource
+	for _, accountName := range accountNames {
+		storageClient, err := cc.client.GetStorageClient(ctx, cc.resourceGroupName, accountName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting a client for storage account %s", accountName)
+		}
+		for _, container := range vhdContainers {
+			blobs, err := storageClient.ListBlobs(container)
+			if err != nil {
+				return nil, errors.Wrapf(err, "listing the blobs of %s/%s", accountName, container)
+			}
+			for _, blob := range blobs {
+				if !strings.HasSuffix(blob, ".vhd") || !cc.isClusterResource(blob) || hasVMNamePrefix(blob, vmNames) {
+					continue
+				}
+				container, blob := container, blob
+				orphans = append(orphans, orphanedResource{
+					Type:   "blob",
+					Name:   fmt.Sprintf("%s/%s/%s", accountName, container, blob),
+					Reason: "virtual machine does not exist",
+					delete: func(ctx context.Context) error {
+						return storageClient.DeleteBlob(container, blob, &azStorage.DeleteBlobOptions{})
+					},
+				})
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// getOrphanedRoleAssignments returns the role assignments which the cluster templates grant to the managed identities
+// of the cluster in its resource group, and whose principal, e.g. the identity of a deleted virtual machine, does not
+// exist anymore
+func (cc *cleanupCmd) getOrphanedRoleAssignments(ctx context.Context, vmNames []string) ([]orphanedResource, error) {
+	identities := cc.getClusterRoleAssignmentNames(vmNames)
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	scope := fmt.Sprintf(armhelpers.AADRoleResourceGroupScopeTemplate, cc.getAuthArgs().SubscriptionID.String(), cc.resourceGroupName)
+	var principalIDs []string
+	roleAssignments := map[string][]authorization.RoleAssignment{}
+	for page, err := cc.client.ListRoleAssignmentsForScope(ctx, scope); page.NotDone(); err = page.Next() {
+		if err != nil {
+			return nil, err
+		}
+		for _, roleAssignment := range page.Values() {
+			if roleAssignment.Name == nil || roleAssignment.Properties == nil || roleAssignment.Properties.PrincipalID == nil {
+				continue
+			}
+			// the role assignments of the subscription and of the other resource groups are listed too
+			if !isInScope(roleAssignment.Properties.Scope, scope) {
+				continue
+			}
+			if _, ok := identities[strings.ToLower(*roleAssignment.Name)]; !ok {
+				continue
+			}
+			principalID := *roleAssignment.Properties.PrincipalID
+			if _, ok := roleAssignments[principalID]; !ok {
+				principalIDs = append(principalIDs, principalID)
+			}
+			roleAssignments[principalID] = append(roleAssignments[principalID], roleAssignment)
+		}
+	}
+	if len(principalIDs) == 0 {
+		return nil, nil
+	}
+
+	existingIDs, err := cc.client.GetDirectoryObjectIDs(ctx, principalIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting the principals of the role assignments")
+	}
+	existing := map[string]bool{}
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	var orphans []orphanedResource
+	for _, principalID := range principalIDs {
+		if existing[principalID] {
+			continue
+		}
+		for _, roleAssignment := range roleAssignments[principalID] {
+			id := *roleAssignment.ID
+			orphans = append(orphans, orphanedResource{
+				Type:   "role assignment",
+				Name:   id,
+				Reason: fmt.Sprintf("principal %s of %s does not exist", principalID, identities[strings.ToLower(*roleAssignment.Name)]),
+				delete: func(ctx context.Context) error {
+					_, err := cc.client.DeleteRoleAssignmentByID(ctx, id)
+					return err
+				},
+			})
+		}
+	}
+	return orphans, nil
+}
+
+// getClusterRoleAssignmentNames returns the names of the role assignments which the cluster templates grant in the
+// resource group to the user assigned identity or to the system assigned identities of the virtual machines and scale
+// sets of the cluster, mapped to the description of their identity. The names are generated by the guid() function
+// of the templates from the names of the identities, the indexes of the virtual machines which may have been deleted
+// are bounded by the maximum agent count above the highest index of the remaining ones.
+func (cc *cleanupCmd) getClusterRoleAssignmentNames(vmNames []string) map[string]string {
+	kubernetesConfig := cc.containerService.Properties.OrchestratorProfile.KubernetesConfig
+	if kubernetesConfig == nil || !kubernetesConfig.UseManagedIdentity {
+		return nil
+	}
+
+	names := map[string]string{}
+	if kubernetesConfig.UserAssignedID != "" {
+		resourceGroupID := fmt.Sprintf(armhelpers.AADRoleResourceGroupScopeTemplate, cc.getAuthArgs().SubscriptionID.String(), cc.resourceGroupName)
+		names[armGUID(kubernetesConfig.UserAssignedID+"roleAssignment"+resourceGroupID)] = fmt.Sprintf("user assigned identity %s", kubernetesConfig.UserAssignedID)
+		return names
+	}
+
+	addVMs := func(prefix string) {
+		for i := 0; i <= getHighestVMIndex(prefix, vmNames)+common.MaxAgentCount; i++ {
+			vmName := fmt.Sprintf("%s%d", prefix, i)
+			names[armGUID("Microsoft.Compute/virtualMachines/"+vmName+"vmidentity")] = fmt.Sprintf("virtual machine %s", vmName)
+		}
+	}
+	if masterProfile := cc.containerService.Properties.MasterProfile; masterProfile != nil && !masterProfile.IsVirtualMachineScaleSets() {
+		addVMs(cc.containerService.Properties.GetMasterVMPrefix())
+	}
+	for i, profile := range cc.containerService.Properties.AgentPoolProfiles {
+		prefix := cc.containerService.Properties.GetAgentVMPrefix(profile, i)
+		if profile.IsVirtualMachineScaleSets() {
+			names[armGUID("Microsoft.Compute/virtualMachineScaleSets/"+prefix+"vmidentity")] = fmt.Sprintf("scale set %s", prefix)
+		} else {
+			addVMs(prefix)
+		}
+	}
+	return names
+}
+
+// armGUIDNamespace is the namespace of the name-based UUIDs generated by the guid() function of ARM templates
+var armGUIDNamespace = uuid.Must(uuid.FromString("11fb06fb-712d-4ddd-98c7-e71bbd588830"))
+
+// armGUID returns the UUID which the guid() function of ARM templates generates from the same arguments
+func armGUID(args ...string) string {
+	return uuid.NewV5(armGUIDNamespace, strings.Join(args, "-")).String()
+}
+
+// getHighestVMIndex returns the highest index of the virtual machines named after the prefix, or -1 if there is none
+func getHighestVMIndex(prefix string, vmNames []string) int {
+	highest := -1
+	for _, vmName := range vmNames {
+		if !strings.HasPrefix(vmName, prefix) {
+			continue
+		}
+		if index, err := strconv.Atoi(strings.TrimPrefix(vmName, prefix)); err == nil && index > highest {
+			highest = index
+		}
+	}
+	return highest
+}
+
+// isInScope returns true if the scope of a role assignment is the resource group scope or a resource inside it
+func isInScope(roleAssignmentScope *string, scope string) bool {
+	if roleAssignmentScope == nil {
+		return false
+	}
+	s := strings.ToLower(*roleAssignmentScope)
+	scope = strings.ToLower(scope)
+	return s == scope || strings.HasPrefix(s, scope+"/")
+}
+
+func (cc *cleanupCmd) isClusterResource(name string) bool {
+	return isClusterResource(name, cc.nameSuffix)
+}
+
+// hasVMNamePrefix returns true if the resource is named after one of the virtual machines, e.g. the OS disk
+// k8s-agentpool1-12345678-1-osdisk of the VM k8s-agentpool1-12345678-1
+func hasVMNamePrefix(name string, vmNames []string) bool {
+	for _, vmName := range vmNames {
+		if strings.HasPrefix(name, vmName+"-") {
+			return true
+		}
+	}
+	return false
+}
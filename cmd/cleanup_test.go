@@ -0,0 +1,244 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func TestNewCleanupCmd(t *testing.T) {
+	output := newCleanupCmd()
+	if output.Use != cleanupName || output.Short != cleanupShortDescription || output.Long != cleanupLongDescription {
+		t.Fatalf("cleanup command should have use %s equal %s, short %s equal %s and long %s equal to %s", output.Use, cleanupName, output.Short, cleanupShortDescription, output.Long, cleanupLongDescription)
+	}
+
+	expectedFlags := []string{"location", "resource-group", "api-model", "delete", "yes"}
+	for _, f := range expectedFlags {
+		if output.Flags().Lookup(f) == nil {
+			t.Fatalf("cleanup command should have flag %s", f)
+		}
+	}
+}
+
+func TestCleanupCmdShouldBeValidated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &cobra.Command{}
+
+	cases := []struct {
+		cc          *cleanupCmd
+		expectedErr error
+	}{
+		{
+			cc:          &cleanupCmd{location: "westus", apiModelPath: "./not/used"},
+			expectedErr: errors.New("--resource-group must be specified"),
+		},
+		{
+			cc:          &cleanupCmd{resourceGroupName: "test", apiModelPath: "./not/used"},
+			expectedErr: errors.New("--location must be specified"),
+		},
+		{
+			cc:          &cleanupCmd{resourceGroupName: "test", location: "westus"},
+			expectedErr: errors.New("--api-model must be specified"),
+		},
+		{
+			cc:          &cleanupCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", yes: true},
+			expectedErr: errors.New("--yes can only be specified with --delete"),
+		},
+		{
+			cc:          &cleanupCmd{resourceGroupName: "test", location: "West US", apiModelPath: "./not/used", delete: true, yes: true},
+			expectedErr: nil,
+		},
+	}
+
+	for _, c := range cases {
+		err := c.cc.validate(r)
+		if c.expectedErr != nil {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.cc.location).To(Equal("westus"))
+		}
+	}
+}
+
+func TestGetOrphanedResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cc := newCleanupTestCmd(&armhelpers.MockAKSEngineClient{})
+	orphans, err := cc.getOrphanedResources(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var names []string
+	for _, o := range orphans {
+		names = append(names, o.Type+" "+o.Name+": "+o.Reason)
+	}
+	g.Expect(names).To(Equal([]string{
+		"network interface k8s-agentpool1-12345678-nic-1: not attached to a virtual machine",
+		"managed disk k8s-agentpool1-12345678-1-osdisk: not attached to a virtual machine",
+		"blob account1/osdisk/k8s-agentpool1-12345678-1-osdisk.vhd: virtual machine does not exist",
+		"role assignment role-assignment-2: principal principal-2 of virtual machine k8s-agentpool1-12345678-1 does not exist",
+	}))
+}
+
+func TestCleanup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &armhelpers.MockAKSEngineClient{}
+	cc := newCleanupTestCmd(client)
+	orphans, err := cc.getOrphanedResources(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// orphaned resources are only listed by default
+	out := &bytes.Buffer{}
+	cc.out = out
+	g.Expect(cc.cleanup(context.Background(), orphans)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("network interface  k8s-agentpool1-12345678-nic-1"))
+	g.Expect(out.String()).To(HaveSuffix("Found 4 orphaned resources, run again with --delete to delete them.\n"))
+
+	// the deletion must be confirmed
+	cc.delete = true
+	client.FailDeleteNetworkInterface = true
+	cc.in = strings.NewReader("n\n")
+	g.Expect(cc.cleanup(context.Background(), orphans)).To(Succeed())
+
+	cc.in = strings.NewReader("y\n")
+	err = cc.cleanup(context.Background(), orphans)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("failed to delete 1 of 4 orphaned resources"))
+
+	client.FailDeleteNetworkInterface = false
+	cc.yes = true
+	cc.in = strings.NewReader("")
+	g.Expect(cc.cleanup(context.Background(), orphans)).To(Succeed())
+
+	out.Reset()
+	g.Expect(cc.cleanup(context.Background(), nil)).To(Succeed())
+	g.Expect(out.String()).To(Equal("No orphaned resources found.\n"))
+}
+
+func TestGetClusterRoleAssignmentNames(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cc := newCleanupTestCmd(&armhelpers.MockAKSEngineClient{})
+	vmNames := []string{"k8s-master-12345678-0", "k8s-agentpool1-12345678-0", "k8s-agentpool1-12345678-150"}
+	names := cc.getClusterRoleAssignmentNames(vmNames)
+	g.Expect(names).To(HaveKeyWithValue(armGUID("Microsoft.Compute/virtualMachines/k8s-master-12345678-2vmidentity"), "virtual machine k8s-master-12345678-2"))
+	g.Expect(names).To(HaveKeyWithValue(armGUID("Microsoft.Compute/virtualMachines/k8s-agentpool1-12345678-250vmidentity"), "virtual machine k8s-agentpool1-12345678-250"))
+	g.Expect(names).NotTo(HaveKey(armGUID("Microsoft.Compute/virtualMachines/k8s-agentpool1-12345678-251vmidentity")))
+
+	// the system assigned identity of a scale set is granted a single role assignment
+	cc.containerService.Properties.AgentPoolProfiles[0].AvailabilityProfile = api.VirtualMachineScaleSets
+	names = cc.getClusterRoleAssignmentNames(vmNames)
+	g.Expect(names).To(HaveKeyWithValue(armGUID("Microsoft.Compute/virtualMachineScaleSets/k8s-agentpool1-12345678-vmssvmidentity"), "scale set k8s-agentpool1-12345678-vmss"))
+	g.Expect(names).NotTo(HaveKey(armGUID("Microsoft.Compute/virtualMachines/k8s-agentpool1-12345678-0vmidentity")))
+
+	// the user assigned identity replaces the system assigned identities
+	cc.containerService.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedID = "testcluster-identity"
+	names = cc.getClusterRoleAssignmentNames(vmNames)
+	g.Expect(names).To(Equal(map[string]string{
+		armGUID("testcluster-identityroleAssignment/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg"): "user assigned identity testcluster-identity",
+	}))
+
+	// the role assignments of a service principal are not granted by the cluster templates
+	cc.containerService.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = false
+	g.Expect(cc.getClusterRoleAssignmentNames(vmNames)).To(BeEmpty())
+}
+
+func newCleanupTestCmd(client *armhelpers.MockAKSEngineClient) *cleanupCmd {
+	client.FakeListVirtualMachineResult = func() []compute.VirtualMachine {
+		return []compute.VirtualMachine{
+			client.MakeFakeVirtualMachine("k8s-master-12345678-0", "Kubernetes:1.9.10"),
+			client.MakeFakeVirtualMachine("k8s-agentpool1-12345678-0", "Kubernetes:1.9.10"),
+		}
+	}
+	client.FakeListNetworkInterfacesResult = func() []network.Interface {
+		return []network.Interface{
+			{
+				Name: to.StringPtr("k8s-master-12345678-nic-0"),
+				InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+					VirtualMachine: &network.SubResource{ID: to.StringPtr("k8s-master-12345678-0")},
+				},
+			},
+			{
+				Name:                      to.StringPtr("k8s-agentpool1-12345678-nic-1"),
+				InterfacePropertiesFormat: &network.InterfacePropertiesFormat{},
+			},
+			{
+				Name:                      to.StringPtr("unrelated-nic"),
+				InterfacePropertiesFormat: &network.InterfacePropertiesFormat{},
+			},
+		}
+	}
+	client.FakeListManagedDisksResult = func() []compute.Disk {
+		return []compute.Disk{
+			{Name: to.StringPtr("k8s-master-12345678-0-etcddisk"), ManagedBy: to.StringPtr("k8s-master-12345678-0")},
+			{Name: to.StringPtr("k8s-agentpool1-12345678-1-osdisk")},
+			{Name: to.StringPtr("unrelated-disk")},
+		}
+	}
+	client.FakeListStorageAccountNamesResult = func() []string {
+		return []string{"account1"}
+	}
+	client.FakeListBlobsResult = func(accountName, containerName string) []string {
+		if containerName == "vhds" {
+			return []string{"k8s-master-12345678-0-osdisk.vhd", "k8s-master-12345678-0-etcddisk.vhd"}
+		}
+		return []string{"k8s-agentpool1-12345678-0-osdisk.vhd", "k8s-agentpool1-12345678-1-osdisk.vhd", "unrelated-osdisk.vhd"}
+	}
+	client.FakeListRoleAssignmentsForScopeResult = func() []authorization.RoleAssignment {
+		scope := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg"
+		return []authorization.RoleAssignment{
+			{
+				ID:         to.StringPtr("role-assignment-1"),
+				Name:       to.StringPtr(armGUID("Microsoft.Compute/virtualMachines/k8s-master-12345678-0vmidentity")),
+				Properties: &authorization.RoleAssignmentPropertiesWithScope{Scope: to.StringPtr(scope), PrincipalID: to.StringPtr("principal-1")},
+			},
+			{
+				ID:         to.StringPtr("role-assignment-2"),
+				Name:       to.StringPtr(armGUID("Microsoft.Compute/virtualMachines/k8s-agentpool1-12345678-1vmidentity")),
+				Properties: &authorization.RoleAssignmentPropertiesWithScope{Scope: to.StringPtr(scope), PrincipalID: to.StringPtr("principal-2")},
+			},
+			{
+				ID:         to.StringPtr("unrelated-role-assignment"),
+				Name:       to.StringPtr("5c2e1a3e-1f5b-4c5e-9a1d-0b9f5e1c2d3a"),
+				Properties: &authorization.RoleAssignmentPropertiesWithScope{Scope: to.StringPtr(scope), PrincipalID: to.StringPtr("principal-3")},
+			},
+			{
+				ID:         to.StringPtr("subscription-role-assignment"),
+				Name:       to.StringPtr(armGUID("Microsoft.Compute/virtualMachines/k8s-agentpool1-12345678-2vmidentity")),
+				Properties: &authorization.RoleAssignmentPropertiesWithScope{Scope: to.StringPtr("/subscriptions/00000000-0000-0000-0000-000000000000"), PrincipalID: to.StringPtr("principal-4")},
+			},
+		}
+	}
+	client.FakeGetDirectoryObjectIDsResult = func(objectIDs []string) []string {
+		return []string{"principal-1"}
+	}
+
+	cs := api.CreateMockContainerService("testcluster", "1.9.10", 3, 2, false)
+	cs.Properties.ClusterID = "12345678"
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = true
+
+	return &cleanupCmd{
+		authProvider:      &authArgs{},
+		location:          "westus",
+		resourceGroupName: "rg",
+		client:            client,
+		containerService:  cs,
+		nameSuffix:        "12345678",
+	}
+}
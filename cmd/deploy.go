@@ -26,6 +26,7 @@ import (
 	"github.com/Azure/aks-engine/pkg/engine/transform"
 	"github.com/Azure/aks-engine/pkg/helpers"
 	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/aks-engine/pkg/operations"
 	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pkg/errors"
@@ -39,6 +40,7 @@ const (
 
 type deployCmd struct {
 	authProvider
+	linkedTemplatesArgs
 	apimodelPath      string
 	dnsPrefix         string
 	autoSuffix        bool
@@ -103,6 +105,7 @@ func newDeployCmd() *cobra.Command {
 	f.StringArrayVar(&dc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 
 	addAuthFlags(dc.getAuthArgs(), f)
+	addLinkedTemplatesFlags(&dc.linkedTemplatesArgs, f)
 
 	return deployCmd
 }
@@ -379,6 +382,29 @@ func (dc *deployCmd) run() error {
 		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", dc.apimodelPath)
 	}
 
+	if dc.containerService.Properties.OrchestratorProfile.IsKubernetes() && dc.containerService.Properties.MasterProfile != nil {
+		if plan, planErr := dc.containerService.GetNetworkPlan(nil); planErr == nil {
+			for _, problem := range plan.Problems {
+				log.Warnf("network plan: %s", problem)
+			}
+		}
+	}
+
+	cx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+
+	if err = operations.CheckComputeCapacity(cx, dc.client, log.NewEntry(log.StandardLogger()), dc.location, operations.GetClusterVMRequirements(dc.containerService.Properties)); err != nil {
+		return errors.Wrap(err, "checking the compute capacity")
+	}
+
+	if err = operations.CheckCustomNetworkResources(cx, dc.client, dc.getAuthArgs().SubscriptionID.String(), dc.location, dc.containerService.Properties); err != nil {
+		return errors.Wrap(err, "checking the custom route table and network security group")
+	}
+
+	if err = operations.ResolveGalleryImages(cx, dc.client, dc.location, dc.containerService.Properties); err != nil {
+		return errors.Wrap(err, "resolving the Shared Image Gallery images")
+	}
+
 	template, parameters, err := templateGenerator.GenerateTemplateV2(dc.containerService, engine.DefaultGeneratorCode, BuildTag)
 	if err != nil {
 		return errors.Wrapf(err, "generating template %s", dc.apimodelPath)
@@ -413,13 +439,17 @@ func (dc *deployCmd) run() error {
 	}
 
 	deploymentSuffix := dc.random.Int31()
-	cx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
-	defer cancel()
+
+	deploymentName := fmt.Sprintf("%s-%d", dc.resourceGroup, deploymentSuffix)
+	templateJSON, parametersJSON, err = operations.SplitTemplateForDeployment(cx, dc.client, log.NewEntry(log.StandardLogger()), dc.getLinkedTemplatesStorage(dc.resourceGroup), dc.containerService, deploymentName, templateJSON, parametersJSON)
+	if err != nil {
+		return errors.Wrap(err, "preparing template for deployment")
+	}
 
 	if res, err := dc.client.DeployTemplate(
 		cx,
 		dc.resourceGroup,
-		fmt.Sprintf("%s-%d", dc.resourceGroup, deploymentSuffix),
+		deploymentName,
 		templateJSON,
 		parametersJSON,
 	); err != nil {
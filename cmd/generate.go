@@ -8,11 +8,15 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/aks-engine/pkg/engine"
 	"github.com/Azure/aks-engine/pkg/engine/transform"
+	"github.com/Azure/aks-engine/pkg/helpers"
 	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/aks-engine/pkg/operations"
 	"github.com/gofrs/uuid"
 	"github.com/leonelquinteros/gotext"
 	"github.com/pkg/errors"
@@ -26,6 +30,11 @@ const (
 	generateLongDescription  = "Generates an Azure Resource Manager template, parameters file and other assets for a cluster"
 )
 
+const (
+	outputFormatARM       = "arm"
+	outputFormatTerraform = "terraform"
+)
+
 type generateCmd struct {
 	apimodelPath      string
 	outputDirectory   string // can be auto-determined from clusterDefinition
@@ -33,12 +42,19 @@ type generateCmd struct {
 	caPrivateKeyPath  string
 	noPrettyPrint     bool
 	parametersOnly    bool
+	outputFormat      string
 	set               []string
+	seed              string
+	seedDate          string
+	addonsDir         string
+
+	templateSizeThreshold int
 
 	// derived
 	containerService *api.ContainerService
 	apiVersion       string
 	locale           *gotext.Locale
+	seedNotBefore    time.Time
 
 	rawClientID string
 
@@ -58,6 +74,19 @@ func newGenerateCmd() *cobra.Command {
 				return errors.Wrap(err, "validating generateCmd")
 			}
 
+			if gc.seed != "" {
+				log.Warnf("generating keys, certificates and secrets from --seed, anyone knowing the seed can recreate them")
+				helpers.SetRandomSeed(gc.seed, gc.seedNotBefore)
+				defer helpers.ResetRandomSource()
+			}
+
+			if gc.addonsDir != "" {
+				if err := api.LoadAddonsFromDirectory(gc.addonsDir); err != nil {
+					return errors.Wrap(err, "loading addons in generateCmd")
+				}
+				defer api.ResetAddonRegistry()
+			}
+
 			if err := gc.mergeAPIModel(); err != nil {
 				return errors.Wrap(err, "merging API model in generateCmd")
 			}
@@ -84,6 +113,11 @@ func newGenerateCmd() *cobra.Command {
 	f.StringArrayVar(&gc.set, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	f.BoolVar(&gc.noPrettyPrint, "no-pretty-print", false, "skip pretty printing the output")
 	f.BoolVar(&gc.parametersOnly, "parameters-only", false, "only output parameters files")
+	f.StringVar(&gc.outputFormat, "output-format", outputFormatARM, "deployment format to generate, \"arm\" or \"terraform\" (also writes azuredeploy.tf.json)")
+	f.StringVar(&gc.seed, "seed", "", "seed of the randomness of generated keys, certificates and secrets, generating twice with the same api model and seed produces identical artifacts")
+	f.StringVar(&gc.seedDate, "seed-date", "", "date, in YYYY-MM-DD format, from which certificates generated with --seed are valid, today in UTC when absent")
+	f.StringVar(&gc.addonsDir, "addons-dir", "", "directory of third-party addons to register, each in a subdirectory holding an addon.json descriptor and a manifest.yaml template")
+	f.IntVar(&gc.templateSizeThreshold, "template-size-threshold", engine.DefaultTemplateSizeThreshold, "template size in bytes above which agent pools are written to linked templates for manual upload")
 	f.StringVar(&gc.rawClientID, "client-id", "", "client id")
 	f.StringVar(&gc.ClientSecret, "client-secret", "", "client secret")
 	return generateCmd
@@ -113,6 +147,28 @@ func (gc *generateCmd) validate(cmd *cobra.Command, args []string) error {
 		return errors.Errorf("specified api model does not exist (%s)", gc.apimodelPath)
 	}
 
+	switch gc.outputFormat {
+	case "":
+		gc.outputFormat = outputFormatARM
+	case outputFormatARM:
+	case outputFormatTerraform:
+		if gc.parametersOnly {
+			return errors.New("--parameters-only cannot be used with --output-format terraform")
+		}
+	default:
+		return errors.Errorf("invalid --output-format %q, expected %q or %q", gc.outputFormat, outputFormatARM, outputFormatTerraform)
+	}
+
+	gc.seedNotBefore = time.Now()
+	if gc.seedDate != "" {
+		if gc.seed == "" {
+			return errors.New("--seed-date can only be used with --seed")
+		}
+		if gc.seedNotBefore, err = time.Parse("2006-01-02", gc.seedDate); err != nil {
+			return errors.Errorf("invalid --seed-date %q, expected a date in YYYY-MM-DD format", gc.seedDate)
+		}
+	}
+
 	gc.ClientID, _ = uuid.FromString(gc.rawClientID)
 
 	return nil
@@ -225,6 +281,14 @@ func (gc *generateCmd) run() error {
 		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", gc.apimodelPath)
 	}
 
+	if err = gc.containerService.ValidateAddons(); err != nil {
+		return errors.Wrap(err, "validating addons")
+	}
+
+	if pools := operations.GetLatestGalleryImagePools(gc.containerService.Properties); len(pools) > 0 {
+		log.Warnf("the Shared Image Gallery image version of %s is latest, which generate does not pin: Azure deploys the latest version of the image, and the generated apimodel.json does not record it", strings.Join(pools, ", "))
+	}
+
 	//TODO remove these debug statements when we're new template generation implementation is enabled!
 	//bts, _ := json.Marshal(gc.containerService)
 	//log.Info(string(bts))
@@ -234,6 +298,13 @@ func (gc *generateCmd) run() error {
 		return errors.Wrapf(err, "generating template %s", gc.apimodelPath)
 	}
 
+	var linkedTemplates []engine.LinkedTemplate
+	if !gc.parametersOnly {
+		if template, linkedTemplates, err = engine.SplitTemplateBySize(gc.containerService, template, gc.templateSizeThreshold); err != nil {
+			return errors.Wrap(err, "splitting template into linked templates")
+		}
+	}
+
 	if !gc.noPrettyPrint {
 		if template, err = transform.PrettyPrintArmTemplate(template); err != nil {
 			return errors.Wrap(err, "pretty-printing template")
@@ -252,5 +323,32 @@ func (gc *generateCmd) run() error {
 		return errors.Wrap(err, "writing artifacts")
 	}
 
+	if len(linkedTemplates) > 0 {
+		if err = writer.WriteLinkedTemplates(linkedTemplates, gc.outputDirectory); err != nil {
+			return errors.Wrap(err, "writing linked templates")
+		}
+		log.Warnf("the template exceeds %d bytes, agent pools were written to linked templates in %s", gc.templateSizeThreshold, path.Join(gc.outputDirectory, engine.LinkedTemplatesDirectory))
+		log.Warnf("upload them to a storage container and deploy with the %s parameter set to its URI and %s to a SAS token granting read access", engine.LinkedTemplatesLocationParameter, engine.LinkedTemplatesSasTokenParameter)
+	}
+
+	if gc.outputFormat == outputFormatTerraform {
+		config, report, err := templateGenerator.GenerateTerraformConfig(gc.containerService, engine.DefaultGeneratorCode, BuildTag)
+		if err != nil {
+			return errors.Wrapf(err, "generating Terraform configuration %s", gc.apimodelPath)
+		}
+		for _, resource := range report.UnmappedResources {
+			log.Warnf("resource has no Terraform mapping and was not included: %s", resource)
+		}
+		for _, expression := range report.UnresolvedExpressions {
+			log.Warnf("expression could not be translated to Terraform: %s", expression)
+		}
+		for _, dependency := range report.UnresolvedDependencies {
+			log.Warnf("dependency could not be translated to Terraform: %s", dependency)
+		}
+		if err = writer.WriteTerraformArtifacts(config, gc.outputDirectory); err != nil {
+			return errors.Wrap(err, "writing Terraform artifacts")
+		}
+	}
+
 	return nil
 }
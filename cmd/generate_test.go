@@ -4,10 +4,16 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/engine"
+	"github.com/Azure/aks-engine/pkg/helpers"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -19,7 +25,7 @@ func TestNewGenerateCmd(t *testing.T) {
 		t.Fatalf("generate command should have use %s equal %s, short %s equal %s and long %s equal to %s", command.Use, generateName, command.Short, generateShortDescription, command.Long, generateLongDescription)
 	}
 
-	expectedFlags := []string{"api-model", "output-directory", "ca-certificate-path", "ca-private-key-path", "set", "no-pretty-print", "parameters-only", "client-id", "client-secret"}
+	expectedFlags := []string{"api-model", "output-directory", "ca-certificate-path", "ca-private-key-path", "set", "no-pretty-print", "parameters-only", "output-format", "seed", "seed-date", "addons-dir", "template-size-threshold", "client-id", "client-secret"}
 	for _, f := range expectedFlags {
 		if command.Flags().Lookup(f) == nil {
 			t.Fatalf("generate command should have flag %s", f)
@@ -60,6 +66,40 @@ func TestGenerateCmdValidate(t *testing.T) {
 		t.Fatalf("expected error validating multiple args")
 	}
 
+	// validate --output-format
+	g = &generateCmd{outputFormat: "terraform"}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err != nil {
+		t.Fatalf("unexpected error validating --output-format terraform: %s", err.Error())
+	}
+
+	g = &generateCmd{outputFormat: "terraform", parametersOnly: true}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err == nil {
+		t.Fatalf("expected error validating --output-format terraform with --parameters-only")
+	}
+
+	g = &generateCmd{outputFormat: "bicep"}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err == nil {
+		t.Fatalf("expected error validating an unknown --output-format")
+	}
+
+	// validate --seed-date
+	g = &generateCmd{seed: "seed", seedDate: "2020-06-01"}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err != nil {
+		t.Fatalf("unexpected error validating --seed-date: %s", err.Error())
+	}
+	if !g.seedNotBefore.Equal(time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected --seed-date to be parsed, got %s", g.seedNotBefore)
+	}
+
+	g = &generateCmd{seed: "seed", seedDate: "06/01/2020"}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err == nil {
+		t.Fatalf("expected error validating an invalid --seed-date")
+	}
+
+	g = &generateCmd{seedDate: "2020-06-01"}
+	if err = g.validate(r, []string{"../pkg/engine/testdata/simple/kubernetes.json"}); err == nil {
+		t.Fatalf("expected error validating --seed-date without --seed")
+	}
 }
 
 func TestGenerateCmdMergeAPIModel(t *testing.T) {
@@ -833,3 +873,84 @@ func TestExampleAPIModels(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateCmdLinkedTemplates(t *testing.T) {
+	g := &generateCmd{
+		apimodelPath:          "../examples/kubernetes.json",
+		outputDirectory:       "_test_output_linked",
+		templateSizeThreshold: 1024,
+		set:                   []string{"masterProfile.dnsPrefix=my-cluster,linuxProfile.ssh.publicKeys[0].keyData=\"ssh-rsa AAAAB3NO8b9== azureuser@cluster.local\",servicePrincipalProfile.clientId=\"123a4321-c6eb-4b61-9d6f-7db123e14a7a\",servicePrincipalProfile.secret=\"=#msRock5!t=\""},
+	}
+	r := &cobra.Command{}
+	if err := g.validate(r, []string{}); err != nil {
+		t.Fatalf("unexpected error validating generate command: %s", err.Error())
+	}
+	if err := g.mergeAPIModel(); err != nil {
+		t.Fatalf("unexpected error merging api model: %s", err.Error())
+	}
+	if err := g.loadAPIModel(); err != nil {
+		t.Fatalf("unexpected error loading api model: %s", err.Error())
+	}
+	defer os.RemoveAll(g.outputDirectory)
+
+	if err := g.run(); err != nil {
+		t.Fatalf("unexpected error running generate: %s", err.Error())
+	}
+	linkedTemplate := path.Join(g.outputDirectory, engine.LinkedTemplatesDirectory, "agentpool1.json")
+	if _, err := os.Stat(linkedTemplate); err != nil {
+		t.Fatalf("expected linked template %s to be written: %s", linkedTemplate, err.Error())
+	}
+	b, err := ioutil.ReadFile(path.Join(g.outputDirectory, "azuredeploy.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading main template: %s", err.Error())
+	}
+	if !strings.Contains(string(b), engine.LinkedTemplateDeploymentName("agentpool1")) {
+		t.Errorf("expected main template to deploy the agentpool1 linked template")
+	}
+}
+
+func TestGenerateCmdSeed(t *testing.T) {
+	generate := func(outputDirectory string) {
+		command := newGenerateCmd()
+		command.SetArgs([]string{
+			"--api-model", "../examples/kubernetes.json",
+			"--output-directory", outputDirectory,
+			"--seed", "seed",
+			"--seed-date", "2020-06-01",
+			"--set", "masterProfile.dnsPrefix=my-cluster,linuxProfile.ssh.publicKeys[0].keyData=\"ssh-rsa AAAAB3NO8b9== azureuser@cluster.local\",servicePrincipalProfile.clientId=\"123a4321-c6eb-4b61-9d6f-7db123e14a7a\",servicePrincipalProfile.secret=\"=#msRock5!t=\"",
+		})
+		if err := command.Execute(); err != nil {
+			t.Fatalf("unexpected error running generate: %s", err.Error())
+		}
+	}
+	first := "_test_output_seed_1"
+	second := "_test_output_seed_2"
+	defer os.RemoveAll(first)
+	defer os.RemoveAll(second)
+	generate(first)
+	generate(second)
+
+	files, err := ioutil.ReadDir(first)
+	if err != nil {
+		t.Fatalf("unexpected error reading output directory: %s", err.Error())
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		a, err := ioutil.ReadFile(path.Join(first, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(path.Join(second, file.Name()))
+		if err != nil {
+			t.Fatalf("expected %s to be generated again: %s", file.Name(), err.Error())
+		}
+		if string(a) != string(b) {
+			t.Errorf("expected %s to be identical when generated with the same seed", file.Name())
+		}
+	}
+	if helpers.IsRandomSeeded() {
+		t.Errorf("expected the source of randomness to be reset after generate")
+	}
+}
@@ -27,6 +27,7 @@ type getVersionsCmd struct {
 	orchestrator string
 	version      string
 	windows      bool
+	showAddons   bool
 	output       string
 }
 
@@ -44,6 +45,7 @@ func newGetVersionsCmd() *cobra.Command {
 	gvc.orchestrator = "Kubernetes" // orchestrator is always Kubernetes
 	f.StringVar(&gvc.version, "version", "", "Kubernetes version (optional)")
 	f.BoolVar(&gvc.windows, "windows", false, "Kubernetes cluster with Windows nodes (optional)")
+	f.BoolVar(&gvc.showAddons, "show-addons", false, "list the addons available with each version in human-readable output (optional)")
 	getVersionsCmdDescription := fmt.Sprintf("Output format. Allowed values: %s",
 		strings.Join(outputFormatOptions, ", "))
 	f.StringVarP(&gvc.output, "output", "o", "human", getVersionsCmdDescription)
@@ -66,7 +68,11 @@ func (gvc *getVersionsCmd) run(cmd *cobra.Command, args []string) error {
 		fmt.Println(string(data))
 	case "human":
 		w := tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', tabwriter.FilterHTML)
-		fmt.Fprintln(w, "Version\tUpgrades")
+		if gvc.showAddons {
+			fmt.Fprintln(w, "Version\tUpgrades\tAddons")
+		} else {
+			fmt.Fprintln(w, "Version\tUpgrades")
+		}
 		// iterate in reverse so the newest Kubernetes release is listed first
 		for i := len(orchs.Orchestrators) - 1; i >= 0; i-- {
 			o := orchs.Orchestrators[i]
@@ -80,6 +86,9 @@ func (gvc *getVersionsCmd) run(cmd *cobra.Command, args []string) error {
 					fmt.Fprintf(w, ", ")
 				}
 			}
+			if gvc.showAddons {
+				fmt.Fprintf(w, "\t%s", strings.Join(o.Addons, ", "))
+			}
 			fmt.Fprintln(w)
 		}
 		w.Flush()
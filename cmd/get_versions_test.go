@@ -17,6 +17,7 @@ var _ = Describe("The get-versions command", func() {
 		Expect(command.Long).Should(Equal(getVersionsLongDescription))
 		Expect(command.Flags().Lookup("orchestrator")).To(BeNil())
 		Expect(command.Flags().Lookup("version")).NotTo(BeNil())
+		Expect(command.Flags().Lookup("show-addons")).NotTo(BeNil())
 
 		command.SetArgs([]string{})
 		err := command.Execute()
@@ -43,6 +44,17 @@ var _ = Describe("The get-versions command", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
+	It("should support listing addons in human-readable output", func() {
+		command := &getVersionsCmd{
+			orchestrator: "kubernetes",
+			version:      "1.13.3",
+			showAddons:   true,
+			output:       "human",
+		}
+		err := command.run(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
 	It("should error on an invalid output option", func() {
 		command := &getVersionsCmd{
 			orchestrator: "kubernetes",
@@ -0,0 +1,527 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	"github.com/Azure/aks-engine/pkg/engine/transform"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/aks-engine/pkg/operations"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	migrateIdentityName             = "migrate-identity"
+	migrateIdentityShortDescription = "Switch an existing Kubernetes cluster from a service principal to a managed identity"
+	migrateIdentityLongDescription  = "Create a user assigned identity for a cluster built with AKS Engine that uses a service principal, grant it the roles of the cluster, assign it to the virtual machines and scale sets of the cluster, configure the cloud provider of every node to use it and re-roll the nodes one at a time. The api model is updated to use the identity. Clusters with Windows agent pools, the cluster-autoscaler or the aci-connector addon cannot be migrated."
+)
+
+const (
+	// migrateIdentityDrainTimeout is how long to wait for the pods of an agent node to be evicted before rebooting it
+	migrateIdentityDrainTimeout = 10 * time.Minute
+	// migrateIdentityNodeReadyTimeout is how long to wait for a node to be ready again once its virtual machine is rebooted
+	migrateIdentityNodeReadyTimeout = 20 * time.Minute
+)
+
+// migrateIdentityPollInterval is how often the readiness of a rebooted node is checked
+var migrateIdentityPollInterval = 10 * time.Second
+
+type migrateIdentityCmd struct {
+	authProvider
+
+	// user input
+	resourceGroupName string
+	location          string
+	apiModelPath      string
+	sshFilepath       string
+	masterFQDN        string
+	identityName      string
+
+	// derived
+	containerService   *api.ContainerService
+	apiVersion         string
+	locale             *gotext.Locale
+	client             armhelpers.AKSEngineClient
+	logger             *log.Entry
+	nameSuffix         string
+	identityClientID   string
+	clusterVMs         map[string]clusterVM
+	sshConfig          *ssh.ClientConfig
+	sshCommandExecuter func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error)
+}
+
+// clusterVM is a virtual machine of the cluster, or an instance of one of its scale sets
+type clusterVM struct {
+	name       string
+	scaleSet   string
+	instanceID string
+}
+
+func newMigrateIdentityCmd() *cobra.Command {
+	mic := migrateIdentityCmd{
+		authProvider:       &authArgs{},
+		sshCommandExecuter: executeCmd,
+	}
+
+	command := &cobra.Command{
+		Use:   migrateIdentityName,
+		Short: migrateIdentityShortDescription,
+		Long:  migrateIdentityLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := mic.validate(cmd); err != nil {
+				return errors.Wrap(err, "validating migrateIdentityCmd")
+			}
+			return mic.run()
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&mic.location, "location", "l", "", "location the cluster is deployed in (required)")
+	f.StringVarP(&mic.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed (required)")
+	f.StringVarP(&mic.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file (required)")
+	f.StringVarP(&mic.sshFilepath, "ssh", "", "", "the filepath of a valid private ssh key to access the cluster's nodes (required)")
+	f.StringVar(&mic.masterFQDN, "apiserver", "", "apiserver endpoint (required)")
+	f.StringVar(&mic.identityName, "identity-name", "", "name of the user assigned identity to create (derived from DNS prefix if absent)")
+
+	addAuthFlags(mic.getAuthArgs(), f)
+
+	return command
+}
+
+func (mic *migrateIdentityCmd) validate(cmd *cobra.Command) error {
+	var err error
+
+	mic.locale, err = i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+
+	if mic.resourceGroupName == "" {
+		cmd.Usage()
+		return errors.New("--resource-group must be specified")
+	}
+
+	if mic.location == "" {
+		cmd.Usage()
+		return errors.New("--location must be specified")
+	}
+	mic.location = helpers.NormalizeAzureRegion(mic.location)
+
+	if mic.apiModelPath == "" {
+		cmd.Usage()
+		return errors.New("--api-model must be specified")
+	}
+
+	if mic.sshFilepath == "" {
+		cmd.Usage()
+		return errors.New("--ssh must be specified")
+	}
+
+	if mic.masterFQDN == "" {
+		cmd.Usage()
+		return errors.New("--apiserver must be specified")
+	}
+
+	return nil
+}
+
+func (mic *migrateIdentityCmd) run() error {
+	var err error
+
+	mic.logger = log.NewEntry(log.New())
+
+	if err = mic.getAuthArgs().validateAuthArgs(); err != nil {
+		return errors.Wrap(err, "failed to get validate auth args")
+	}
+
+	if mic.client, err = mic.authProvider.getClient(); err != nil {
+		return errors.Wrap(err, "failed to get client")
+	}
+
+	if err = mic.loadCluster(); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(mic.sshFilepath); os.IsNotExist(err) {
+		return errors.Errorf("specified ssh filepath does not exist (%s)", mic.sshFilepath)
+	}
+	mic.setSSHConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+
+	log.Infof("Creating user assigned identity %s", mic.identityName)
+	identityID, err := mic.createIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("Assigning the identity to the virtual machines of the cluster")
+	if err = mic.assignIdentity(ctx, identityID); err != nil {
+		return err
+	}
+
+	log.Infoln("Configuring the nodes to use the identity and re-rolling them")
+	if err = mic.migrateNodes(ctx); err != nil {
+		return err
+	}
+
+	return mic.saveAPIModel()
+}
+
+func (mic *migrateIdentityCmd) loadCluster() error {
+	var err error
+
+	if _, err = os.Stat(mic.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", mic.apiModelPath)
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: mic.locale,
+		},
+	}
+	mic.containerService, mic.apiVersion, err = apiloader.LoadContainerServiceFromFile(mic.apiModelPath, true, true, nil)
+	if err != nil {
+		return errors.Wrap(err, "parsing the api model")
+	}
+
+	properties := mic.containerService.Properties
+	if err = validateIdentityMigration(properties); err != nil {
+		return err
+	}
+
+	if mic.identityName == "" {
+		mic.identityName = fmt.Sprintf("%s-identity", properties.MasterProfile.DNSPrefix)
+	}
+
+	//allows to identify VMs in the resource group that belong to this cluster.
+	mic.nameSuffix = properties.GetClusterID()
+	return nil
+}
+
+// validateIdentityMigration returns an error if the cluster cannot be migrated to a user assigned identity, or if some
+// of its components would keep using the service principal after the migration: only azure.json on the Linux nodes
+// is updated, the Windows nodes and the addon secrets holding the service principal credentials are not
+func validateIdentityMigration(properties *api.Properties) error {
+	if !properties.OrchestratorProfile.IsKubernetes() {
+		return errors.New("migrate-identity is only supported for Kubernetes clusters")
+	}
+	if properties.IsHostedMasterProfile() {
+		return errors.New("migrate-identity is not supported for clusters with a hosted master")
+	}
+	if properties.IsAzureStackCloud() {
+		return errors.New("migrate-identity is not supported on Azure Stack, which does not support user assigned identities")
+	}
+	kubernetesConfig := properties.OrchestratorProfile.KubernetesConfig
+	if kubernetesConfig != nil && kubernetesConfig.UseManagedIdentity {
+		return errors.New("the cluster already uses a managed identity")
+	}
+	if properties.HasWindows() {
+		return errors.New("migrate-identity is not supported for clusters with Windows agent pools, their nodes would keep using the service principal")
+	}
+	if kubernetesConfig != nil {
+		for _, addon := range []string{api.ClusterAutoscalerAddonName, api.ACIConnectorAddonName} {
+			if kubernetesConfig.IsAddonEnabled(addon) {
+				return errors.Errorf("migrate-identity is not supported for clusters with the %s addon enabled, its secret would keep the service principal credentials", addon)
+			}
+		}
+	}
+	return nil
+}
+
+// createIdentity creates the user assigned identity and deploys its role assignments, the same as the ones of a cluster
+// deployed with a managed identity, and returns the resource ID of the identity
+func (mic *migrateIdentityCmd) createIdentity(ctx context.Context) (string, error) {
+	identity, err := mic.client.CreateUserAssignedID(mic.location, mic.resourceGroupName, mic.identityName)
+	if err != nil {
+		return "", errors.Wrapf(err, "creating user assigned identity %s", mic.identityName)
+	}
+	if identity.ID == nil || identity.IdentityProperties == nil || identity.IdentityProperties.ClientID == nil {
+		return "", errors.Errorf("user assigned identity %s has no client ID", mic.identityName)
+	}
+	mic.identityClientID = identity.IdentityProperties.ClientID.String()
+
+	enableUserAssignedID(mic.containerService, mic.identityName)
+
+	translator := engine.Context{
+		Translator: &i18n.Translator{
+			Locale: mic.locale,
+		},
+	}
+	templateGenerator, err := engine.InitializeTemplateGenerator(translator)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to initialize template generator")
+	}
+	if _, err = mic.containerService.SetPropertiesDefaults(false, true); err != nil {
+		return "", errors.Wrapf(err, "error in SetPropertiesDefaults template %s", mic.apiModelPath)
+	}
+	template, parameters, err := templateGenerator.GenerateTemplateV2(mic.containerService, engine.DefaultGeneratorCode, BuildTag)
+	if err != nil {
+		return "", errors.Wrapf(err, "error generating template %s", mic.apiModelPath)
+	}
+
+	templateJSON := make(map[string]interface{})
+	parametersJSON := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(template), &templateJSON); err != nil {
+		return "", errors.Wrap(err, "error unmarshaling template")
+	}
+	if err = json.Unmarshal([]byte(parameters), &parametersJSON); err != nil {
+		return "", errors.Wrap(err, "error unmarshaling parameters")
+	}
+
+	transformer := transform.Transformer{Translator: translator.Translator}
+	if err = transformer.NormalizeForK8sIdentityMigration(mic.logger, templateJSON); err != nil {
+		return "", errors.Wrapf(err, "error transforming the template for identity migration %s", mic.apiModelPath)
+	}
+
+	deploymentName := fmt.Sprintf("%s-identity-%d", mic.resourceGroupName, time.Now().Unix())
+	if _, err = mic.client.DeployTemplate(ctx, mic.resourceGroupName, deploymentName, templateJSON, parametersJSON); err != nil {
+		return "", errors.Wrap(err, "deploying the role assignments of the identity")
+	}
+	return *identity.ID, nil
+}
+
+// assignIdentity assigns the user assigned identity to the virtual machines and scale sets of the cluster
+func (mic *migrateIdentityCmd) assignIdentity(ctx context.Context, identityID string) error {
+	mic.clusterVMs = map[string]clusterVM{}
+
+	for vmListPage, err := mic.client.ListVirtualMachines(ctx, mic.resourceGroupName); vmListPage.NotDone(); err = vmListPage.Next() {
+		if err != nil {
+			return errors.Wrap(err, "listing virtual machines")
+		}
+		for _, vm := range vmListPage.Values() {
+			if !isClusterResource(*vm.Name, mic.nameSuffix) {
+				continue
+			}
+			log.Debugf("Assigning the identity to virtual machine %s", *vm.Name)
+			if err = mic.client.AssignUserAssignedIDToVirtualMachine(ctx, mic.resourceGroupName, *vm.Name, identityID); err != nil {
+				return errors.Wrapf(err, "assigning the identity to virtual machine %s", *vm.Name)
+			}
+			mic.clusterVMs[strings.ToLower(*vm.Name)] = clusterVM{name: *vm.Name}
+		}
+	}
+
+	for vmScaleSetPage, err := mic.client.ListVirtualMachineScaleSets(ctx, mic.resourceGroupName); vmScaleSetPage.NotDone(); err = vmScaleSetPage.NextWithContext(ctx) {
+		if err != nil {
+			return errors.Wrap(err, "listing virtual machine scale sets")
+		}
+		for _, vmScaleSet := range vmScaleSetPage.Values() {
+			if !isClusterResource(*vmScaleSet.Name, mic.nameSuffix) {
+				continue
+			}
+			log.Debugf("Assigning the identity to virtual machine scale set %s", *vmScaleSet.Name)
+			if err = mic.client.AssignUserAssignedIDToVirtualMachineScaleSet(ctx, mic.resourceGroupName, *vmScaleSet.Name, identityID); err != nil {
+				return errors.Wrapf(err, "assigning the identity to virtual machine scale set %s", *vmScaleSet.Name)
+			}
+			for vmScaleSetVMsPage, err := mic.client.ListVirtualMachineScaleSetVMs(ctx, mic.resourceGroupName, *vmScaleSet.Name); vmScaleSetVMsPage.NotDone(); err = vmScaleSetVMsPage.NextWithContext(ctx) {
+				if err != nil {
+					return errors.Wrapf(err, "listing the virtual machines of scale set %s", *vmScaleSet.Name)
+				}
+				for _, vm := range vmScaleSetVMsPage.Values() {
+					nodeName := strings.ToLower(*vm.Name)
+					if p := vm.VirtualMachineScaleSetVMProperties; p != nil && p.OsProfile != nil && p.OsProfile.ComputerName != nil {
+						nodeName = strings.ToLower(*p.OsProfile.ComputerName)
+					}
+					mic.clusterVMs[nodeName] = clusterVM{name: *vm.Name, scaleSet: *vmScaleSet.Name, instanceID: *vm.InstanceID}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateNodes configures the cloud provider of the nodes to use the identity, then reboots the nodes one at a
+// time, masters first, so that every component reading azure.json picks up the identity
+func (mic *migrateIdentityCmd) migrateNodes(ctx context.Context) error {
+	kubeClient, err := mic.getKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get Kubernetes Client")
+	}
+	nodeList, err := kubeClient.ListNodes()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster nodes")
+	}
+
+	var masterNodes, agentNodes []v1.Node
+	for _, node := range nodeList.Items {
+		if node.Status.NodeInfo.OperatingSystem == "windows" {
+			return errors.Errorf("node %s is a Windows node, which cannot be migrated", node.Name)
+		}
+		if strings.Contains(node.Name, "master") {
+			masterNodes = append(masterNodes, node)
+		} else {
+			agentNodes = append(agentNodes, node)
+		}
+	}
+
+	command := updateAzureJSONForIdentityCommand(mic.identityClientID)
+	for _, node := range append(masterNodes, agentNodes...) {
+		log.Infof("Migrating node %s", node.Name)
+		out, err := mic.sshCommandExecuter(command, mic.masterFQDN, node.Name, "22", mic.sshConfig)
+		if err != nil {
+			log.Printf("Command %s output: %s\n", command, out)
+			return errors.Wrapf(err, "updating azure.json on node %s", node.Name)
+		}
+
+		isAgent := !strings.Contains(node.Name, "master")
+		if isAgent {
+			if err = operations.SafelyDrainNodeWithClient(kubeClient, mic.logger, node.Name, migrateIdentityDrainTimeout); err != nil {
+				return errors.Wrapf(err, "draining node %s", node.Name)
+			}
+		}
+		if err = mic.rebootNode(ctx, node.Name); err != nil {
+			return err
+		}
+		if err = waitForNodeReady(kubeClient, node.Name, migrateIdentityNodeReadyTimeout); err != nil {
+			return err
+		}
+		if isAgent {
+			if err = uncordonNode(kubeClient, node.Name); err != nil {
+				return errors.Wrapf(err, "uncordoning node %s", node.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// updateAzureJSONForIdentityCommand returns the command that configures the cloud provider of a node to authenticate
+// with the user assigned identity of the specified client ID, the same as a node deployed with a managed identity
+func updateAzureJSONForIdentityCommand(clientID string) string {
+	return fmt.Sprintf(`sudo sed -i `+
+		`-e 's|"aadClientId": "[^"]*"|"aadClientId": "msi"|' `+
+		`-e 's|"aadClientSecret": "[^"]*"|"aadClientSecret": "msi"|' `+
+		`-e 's|"useManagedIdentityExtension": false|"useManagedIdentityExtension": true|' `+
+		`-e 's|"userAssignedIdentityID": "[^"]*"|"userAssignedIdentityID": "%s"|' `+
+		`/etc/kubernetes/azure.json`, clientID)
+}
+
+func (mic *migrateIdentityCmd) rebootNode(ctx context.Context, nodeName string) error {
+	vm, ok := mic.clusterVMs[strings.ToLower(nodeName)]
+	if !ok {
+		return errors.Errorf("no virtual machine found for node %s", nodeName)
+	}
+	log.Debugf("Rebooting virtual machine %s", vm.name)
+	if vm.scaleSet != "" {
+		instanceIDs := &compute.VirtualMachineScaleSetVMInstanceIDs{InstanceIds: &[]string{vm.instanceID}}
+		if err := mic.client.RestartVirtualMachineScaleSets(ctx, mic.resourceGroupName, vm.scaleSet, instanceIDs); err != nil {
+			return errors.Wrapf(err, "rebooting virtual machine %s", vm.name)
+		}
+		return nil
+	}
+	if err := mic.client.RestartVirtualMachine(ctx, mic.resourceGroupName, vm.name); err != nil {
+		return errors.Wrapf(err, "rebooting virtual machine %s", vm.name)
+	}
+	return nil
+}
+
+func waitForNodeReady(client armhelpers.KubernetesClient, nodeName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		node, err := client.GetNode(nodeName)
+		if err == nil && isNodeReady(node) {
+			log.Infof("Node %s is ready", nodeName)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("node %s was not ready within %v", nodeName, timeout)
+		}
+		log.Debugf("Node %s not ready yet...", nodeName)
+		time.Sleep(migrateIdentityPollInterval)
+	}
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func uncordonNode(client armhelpers.KubernetesClient, nodeName string) error {
+	node, err := client.GetNode(nodeName)
+	if err != nil {
+		return err
+	}
+	node.Spec.Unschedulable = false
+	_, err = client.UpdateNode(node)
+	return err
+}
+
+func (mic *migrateIdentityCmd) getKubeClient() (armhelpers.KubernetesClient, error) {
+	kubeconfig, err := engine.GenerateKubeConfig(mic.containerService.Properties, mic.location)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating kubeconfig")
+	}
+	kubeClient, err := mic.client.GetKubernetesClient("", kubeconfig, time.Second*1, time.Duration(60)*time.Minute)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get a Kubernetes client")
+	}
+	return kubeClient, nil
+}
+
+func (mic *migrateIdentityCmd) setSSHConfig() {
+	mic.sshConfig = &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            "azureuser",
+		Auth: []ssh.AuthMethod{
+			publicKeyFile(mic.sshFilepath),
+		},
+	}
+}
+
+// saveAPIModel enables the user assigned identity in the api model, so that the nodes created by later operations
+// use it too
+func (mic *migrateIdentityCmd) saveAPIModel() error {
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: mic.locale,
+		},
+	}
+	containerService, apiVersion, err := apiloader.LoadContainerServiceFromFile(mic.apiModelPath, false, true, nil)
+	if err != nil {
+		return err
+	}
+	enableUserAssignedID(containerService, mic.identityName)
+
+	b, err := apiloader.SerializeContainerService(containerService, apiVersion)
+	if err != nil {
+		return err
+	}
+
+	f := helpers.FileSaver{
+		Translator: &i18n.Translator{
+			Locale: mic.locale,
+		},
+	}
+	dir, file := filepath.Split(mic.apiModelPath)
+	return f.SaveFile(dir, file, b)
+}
+
+func enableUserAssignedID(cs *api.ContainerService, identityName string) {
+	if cs.Properties.OrchestratorProfile.KubernetesConfig == nil {
+		cs.Properties.OrchestratorProfile.KubernetesConfig = &api.KubernetesConfig{}
+	}
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = true
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedID = identityName
+}
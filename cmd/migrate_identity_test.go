@@ -0,0 +1,295 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewMigrateIdentityCmd(t *testing.T) {
+	output := newMigrateIdentityCmd()
+	if output.Use != migrateIdentityName || output.Short != migrateIdentityShortDescription || output.Long != migrateIdentityLongDescription {
+		t.Fatalf("migrate-identity command should have use %s equal %s, short %s equal %s and long %s equal to %s", output.Use, migrateIdentityName, output.Short, migrateIdentityShortDescription, output.Long, migrateIdentityLongDescription)
+	}
+
+	expectedFlags := []string{"location", "resource-group", "api-model", "ssh", "apiserver", "identity-name"}
+	for _, f := range expectedFlags {
+		if output.Flags().Lookup(f) == nil {
+			t.Fatalf("migrate-identity command should have flag %s", f)
+		}
+	}
+}
+
+func TestMigrateIdentityCmdShouldBeValidated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &cobra.Command{}
+
+	cases := []struct {
+		mic         *migrateIdentityCmd
+		expectedErr error
+	}{
+		{
+			mic:         &migrateIdentityCmd{location: "westus", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.com"},
+			expectedErr: errors.New("--resource-group must be specified"),
+		},
+		{
+			mic:         &migrateIdentityCmd{resourceGroupName: "test", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.com"},
+			expectedErr: errors.New("--location must be specified"),
+		},
+		{
+			mic:         &migrateIdentityCmd{resourceGroupName: "test", location: "westus", sshFilepath: "./not/used", masterFQDN: "example.com"},
+			expectedErr: errors.New("--api-model must be specified"),
+		},
+		{
+			mic:         &migrateIdentityCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", masterFQDN: "example.com"},
+			expectedErr: errors.New("--ssh must be specified"),
+		},
+		{
+			mic:         &migrateIdentityCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", sshFilepath: "./not/used"},
+			expectedErr: errors.New("--apiserver must be specified"),
+		},
+		{
+			mic:         &migrateIdentityCmd{resourceGroupName: "test", location: "West US", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.com"},
+			expectedErr: nil,
+		},
+	}
+
+	for _, c := range cases {
+		err := c.mic.validate(r)
+		if c.expectedErr != nil {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.mic.location).To(Equal("westus"))
+		}
+	}
+}
+
+func TestValidateIdentityMigration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cases := []struct {
+		name        string
+		update      func(cs *api.ContainerService)
+		expectedErr string
+	}{
+		{
+			name:   "Linux cluster with a service principal",
+			update: func(cs *api.ContainerService) {},
+		},
+		{
+			name: "cluster with a managed identity",
+			update: func(cs *api.ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = true
+			},
+			expectedErr: "the cluster already uses a managed identity",
+		},
+		{
+			name: "cluster with a Windows agent pool",
+			update: func(cs *api.ContainerService) {
+				cs.Properties.AgentPoolProfiles[0].OSType = api.Windows
+			},
+			expectedErr: "migrate-identity is not supported for clusters with Windows agent pools, their nodes would keep using the service principal",
+		},
+		{
+			name: "cluster with the cluster-autoscaler addon",
+			update: func(cs *api.ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []api.KubernetesAddon{
+					{Name: api.ClusterAutoscalerAddonName, Enabled: to.BoolPtr(true)},
+				}
+			},
+			expectedErr: "migrate-identity is not supported for clusters with the cluster-autoscaler addon enabled, its secret would keep the service principal credentials",
+		},
+		{
+			name: "cluster with the aci-connector addon",
+			update: func(cs *api.ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []api.KubernetesAddon{
+					{Name: api.ACIConnectorAddonName, Enabled: to.BoolPtr(true)},
+				}
+			},
+			expectedErr: "migrate-identity is not supported for clusters with the aci-connector addon enabled, its secret would keep the service principal credentials",
+		},
+	}
+
+	for _, c := range cases {
+		cs := api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+		c.update(cs)
+		err := validateIdentityMigration(cs.Properties)
+		if c.expectedErr == "" {
+			g.Expect(err).NotTo(HaveOccurred(), c.name)
+		} else {
+			g.Expect(err).To(HaveOccurred(), c.name)
+			g.Expect(err.Error()).To(Equal(c.expectedErr), c.name)
+		}
+	}
+}
+
+func TestMigrateIdentityCreateIdentity(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mic := &migrateIdentityCmd{
+		location:          "westus",
+		resourceGroupName: "rg",
+		identityName:      "testcluster-identity",
+		client:            &armhelpers.MockAKSEngineClient{},
+		containerService:  api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, false),
+	}
+	mic.logger = log.NewEntry(log.New())
+
+	identityID, err := mic.createIdentity(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(identityID).To(Equal("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/testcluster-identity"))
+	g.Expect(mic.identityClientID).To(Equal("22222222-2222-2222-2222-222222222222"))
+	kubernetesConfig := mic.containerService.Properties.OrchestratorProfile.KubernetesConfig
+	g.Expect(kubernetesConfig.UseManagedIdentity).To(BeTrue())
+	g.Expect(kubernetesConfig.UserAssignedID).To(Equal("testcluster-identity"))
+
+	mic.client = &armhelpers.MockAKSEngineClient{FailDeployTemplate: true}
+	_, err = mic.createIdentity(context.Background())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("deploying the role assignments of the identity"))
+}
+
+func TestMigrateIdentityNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	migrateIdentityPollInterval = 0
+
+	client := &armhelpers.MockAKSEngineClient{
+		MockKubernetesClient: &armhelpers.MockKubernetesClient{},
+	}
+	client.FakeListVirtualMachineResult = func() []compute.VirtualMachine {
+		return []compute.VirtualMachine{
+			client.MakeFakeVirtualMachine("k8s-master-1234", "Kubernetes:1.9.10"),
+			client.MakeFakeVirtualMachine("k8s-agentpool1-87654321-0", "Kubernetes:1.9.10"),
+		}
+	}
+	client.FakeListVirtualMachineScaleSetsResult = func() []compute.VirtualMachineScaleSet {
+		return []compute.VirtualMachineScaleSet{{Name: &[]string{"k8s-agentpool3-1234-vmss"}[0]}}
+	}
+	client.FakeListVirtualMachineScaleSetVMsResult = func() []compute.VirtualMachineScaleSetVM {
+		return []compute.VirtualMachineScaleSetVM{client.MakeFakeVirtualMachineScaleSetVMWithGivenName("Kubernetes:1.9.10", "k8s-agentpool3-1234")}
+	}
+
+	var migratedNodes []string
+	mic := &migrateIdentityCmd{
+		location:          "westus",
+		resourceGroupName: "rg",
+		masterFQDN:        "example.westus.cloudapp.azure.com",
+		client:            client,
+		containerService:  api.CreateMockContainerService("testcluster", "1.9.10", 3, 2, false),
+		nameSuffix:        "1234",
+		identityClientID:  "22222222-2222-2222-2222-222222222222",
+		logger:            log.NewEntry(log.New()),
+		sshConfig:         &ssh.ClientConfig{},
+		sshCommandExecuter: func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error) {
+			g.Expect(command).To(ContainSubstring(`"userAssignedIdentityID": "22222222-2222-2222-2222-222222222222"`))
+			migratedNodes = append(migratedNodes, hostname)
+			return "", nil
+		},
+	}
+
+	g.Expect(mic.assignIdentity(context.Background(), "identityID")).To(Succeed())
+	g.Expect(mic.clusterVMs).To(HaveLen(2))
+	g.Expect(mic.clusterVMs).To(HaveKeyWithValue("k8s-agentpool3-1234", clusterVM{name: "k8s-agentpool1-12345678-0", scaleSet: "k8s-agentpool3-1234-vmss", instanceID: "someguidthatshouldbeunique"}))
+
+	g.Expect(mic.migrateNodes(context.Background())).To(Succeed())
+	g.Expect(migratedNodes).To(Equal([]string{"k8s-master-1234", "k8s-agentpool3-1234"}))
+
+	client.FailRestartVirtualMachineScaleSets = true
+	err := mic.migrateNodes(context.Background())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("rebooting virtual machine k8s-agentpool1-12345678-0: RestartVirtualMachineScaleSets failed"))
+
+	client.FailAssignUserAssignedID = true
+	err = mic.assignIdentity(context.Background(), "identityID")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("assigning the identity to virtual machine k8s-master-1234: AssignUserAssignedIDToVirtualMachine failed"))
+}
+
+func TestUpdateAzureJSONForIdentityCommand(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	command := updateAzureJSONForIdentityCommand("22222222-2222-2222-2222-222222222222")
+	g.Expect(command).To(HavePrefix("sudo sed -i "))
+	g.Expect(command).To(HaveSuffix(" /etc/kubernetes/azure.json"))
+	for _, expression := range []string{
+		`'s|"aadClientId": "[^"]*"|"aadClientId": "msi"|'`,
+		`'s|"aadClientSecret": "[^"]*"|"aadClientSecret": "msi"|'`,
+		`'s|"useManagedIdentityExtension": false|"useManagedIdentityExtension": true|'`,
+		`'s|"userAssignedIdentityID": "[^"]*"|"userAssignedIdentityID": "22222222-2222-2222-2222-222222222222"|'`,
+	} {
+		g.Expect(command).To(ContainSubstring("-e " + expression))
+	}
+}
+
+func TestMigrateIdentitySaveAPIModel(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "migrate-identity")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	apiModelPath := filepath.Join(dir, "apimodel.json")
+	g.Expect(ioutil.WriteFile(apiModelPath, []byte(migrateIdentityTestAPIModel), 0600)).To(Succeed())
+
+	mic := &migrateIdentityCmd{apiModelPath: apiModelPath, identityName: "testcluster-identity"}
+	g.Expect(mic.saveAPIModel()).To(Succeed())
+
+	b, err := ioutil.ReadFile(apiModelPath)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring(`"useManagedIdentity": true`))
+	g.Expect(string(b)).To(ContainSubstring(`"userAssignedID": "testcluster-identity"`))
+	g.Expect(strings.Count(string(b), `"clientId": "clientID"`)).To(Equal(1))
+}
+
+const migrateIdentityTestAPIModel = `{
+  "apiVersion": "vlabs",
+  "properties": {
+    "orchestratorProfile": {
+      "orchestratorType": "Kubernetes",
+      "orchestratorVersion": "1.15.7"
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "testcluster",
+      "vmSize": "Standard_D2_v3"
+    },
+    "agentPoolProfiles": [
+      {
+        "name": "agentpool1",
+        "count": 2,
+        "vmSize": "Standard_D2_v3"
+      }
+    ],
+    "linuxProfile": {
+      "adminUsername": "azureuser",
+      "ssh": {
+        "publicKeys": [
+          {
+            "keyData": "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC"
+          }
+        ]
+      }
+    },
+    "servicePrincipalProfile": {
+      "clientId": "clientID",
+      "secret": "secret"
+    }
+  }
+}`
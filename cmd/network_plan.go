@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	networkPlanName             = "network-plan"
+	networkPlanShortDescription = "Report the address ranges a Kubernetes cluster will consume"
+	networkPlanLongDescription  = "Compute, without deploying anything, the addresses an api model will consume: the static IPs of the masters, the node and Azure CNI pod addresses of every pool up to its maxCount, the service CIDR, the DNS service IP, the cluster subnet and the docker bridge subnet. Report the overlaps between these ranges and the subnets that are too small for them. Exits with a non-zero status if any problem is found."
+)
+
+type networkPlanCmd struct {
+	// user input
+	apiModelPath string
+	subnetCIDRs  map[string]string
+	output       string
+
+	// derived
+	containerService *api.ContainerService
+	apiVersion       string
+	locale           *gotext.Locale
+	out              io.Writer
+}
+
+func newNetworkPlanCmd() *cobra.Command {
+	npc := networkPlanCmd{
+		out: os.Stdout,
+	}
+
+	command := &cobra.Command{
+		Use:   networkPlanName,
+		Short: networkPlanShortDescription,
+		Long:  networkPlanLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := npc.validate(cmd, args); err != nil {
+				return errors.Wrap(err, "validating networkPlanCmd")
+			}
+			return npc.run()
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&npc.apiModelPath, "api-model", "m", "", "path to the apimodel file (required)")
+	f.StringToStringVar(&npc.subnetCIDRs, "subnet", map[string]string{}, "address ranges of the custom VNET subnets, as subnet ID or name=CIDR pairs (can specify multiple or separate values with commas: subnet1=10.239.0.0/16,subnet2=10.238.0.0/16)")
+	networkPlanCmdDescription := fmt.Sprintf("Output format. Allowed values: %s",
+		strings.Join(outputFormatOptions, ", "))
+	f.StringVarP(&npc.output, "output", "o", "human", networkPlanCmdDescription)
+
+	return command
+}
+
+func (npc *networkPlanCmd) validate(cmd *cobra.Command, args []string) error {
+	var err error
+
+	npc.locale, err = i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+
+	if npc.apiModelPath == "" {
+		if len(args) == 1 {
+			npc.apiModelPath = args[0]
+		} else if len(args) > 1 {
+			cmd.Usage()
+			return errors.New("too many arguments were provided to 'network-plan'")
+		} else {
+			cmd.Usage()
+			return errors.New("--api-model must be specified")
+		}
+	}
+
+	if npc.output != "human" && npc.output != "json" {
+		return errors.Errorf(`output format "%s" is not supported`, npc.output)
+	}
+
+	return nil
+}
+
+func (npc *networkPlanCmd) run() error {
+	if err := npc.loadAPIModel(); err != nil {
+		return err
+	}
+
+	plan, err := npc.containerService.GetNetworkPlan(npc.subnetCIDRs)
+	if err != nil {
+		return errors.Wrap(err, "computing the network plan")
+	}
+	if err = npc.printPlan(plan); err != nil {
+		return err
+	}
+	if len(plan.Problems) > 0 {
+		return errors.Errorf("found %d problems in the network plan", len(plan.Problems))
+	}
+	return nil
+}
+
+func (npc *networkPlanCmd) loadAPIModel() error {
+	var err error
+
+	if _, err = os.Stat(npc.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", npc.apiModelPath)
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: npc.locale,
+		},
+	}
+	npc.containerService, npc.apiVersion, err = apiloader.LoadContainerServiceFromFile(npc.apiModelPath, false, false, nil)
+	if err != nil {
+		return errors.Wrap(err, "parsing the api model")
+	}
+
+	if npc.containerService.Properties.OrchestratorProfile == nil || !npc.containerService.Properties.OrchestratorProfile.IsKubernetes() {
+		return errors.New("network-plan is only supported for Kubernetes clusters")
+	}
+
+	if _, err = npc.containerService.SetPropertiesDefaults(false, false); err != nil {
+		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", npc.apiModelPath)
+	}
+	return nil
+}
+
+func (npc *networkPlanCmd) printPlan(plan *api.NetworkPlan) error {
+	if npc.output == "json" {
+		data, err := helpers.JSONMarshalIndent(plan, "", "  ", false)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(npc.out, string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(npc.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Subnet\tCIDR\tUsable\tNeeded\tNeeded at Max Count")
+	for _, s := range plan.Subnets {
+		cidr := s.CIDR
+		if cidr == "" {
+			cidr = "unknown"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", s.Name, cidr, s.Usable, s.Needed, s.MaxNeeded)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Pool\tSubnet\tNodes\tMax Nodes\tIPs per Node\tAddresses\tMax Addresses")
+	for _, a := range plan.Allocations {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n", a.Name, a.Subnet, a.Nodes, a.MaxNodes, a.IPAddressCount, a.Addresses, a.MaxAddresses)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Range\tAddresses\tCount")
+	for _, r := range plan.Ranges {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", r.Name, r.Range, r.Addresses)
+	}
+	w.Flush()
+
+	if len(plan.Warnings) > 0 {
+		fmt.Fprintln(npc.out)
+		fmt.Fprintln(npc.out, "Warnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(npc.out, "  - %s\n", warning)
+		}
+	}
+	fmt.Fprintln(npc.out)
+	if len(plan.Problems) == 0 {
+		fmt.Fprintln(npc.out, "No problems found")
+		return nil
+	}
+	fmt.Fprintln(npc.out, "Problems:")
+	for _, p := range plan.Problems {
+		fmt.Fprintf(npc.out, "  - %s\n", p)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func TestNewNetworkPlanCmd(t *testing.T) {
+	output := newNetworkPlanCmd()
+	if output.Use != networkPlanName || output.Short != networkPlanShortDescription || output.Long != networkPlanLongDescription {
+		t.Fatalf("network-plan command should have use %s equal %s, short %s equal %s and long %s equal to %s", output.Use, networkPlanName, output.Short, networkPlanShortDescription, output.Long, networkPlanLongDescription)
+	}
+
+	expectedFlags := []string{"api-model", "subnet", "output"}
+	for _, f := range expectedFlags {
+		if output.Flags().Lookup(f) == nil {
+			t.Fatalf("network-plan command should have flag %s", f)
+		}
+	}
+}
+
+func TestNetworkPlanCmdShouldBeValidated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &cobra.Command{}
+
+	cases := []struct {
+		npc          *networkPlanCmd
+		args         []string
+		expectedErr  error
+		expectedPath string
+	}{
+		{
+			npc:         &networkPlanCmd{output: "human"},
+			expectedErr: errors.New("--api-model must be specified"),
+		},
+		{
+			npc:         &networkPlanCmd{output: "human"},
+			args:        []string{"one.json", "two.json"},
+			expectedErr: errors.New("too many arguments were provided to 'network-plan'"),
+		},
+		{
+			npc:         &networkPlanCmd{apiModelPath: "./not/used", output: "yaml"},
+			expectedErr: errors.New(`output format "yaml" is not supported`),
+		},
+		{
+			npc:          &networkPlanCmd{output: "json"},
+			args:         []string{"./not/used"},
+			expectedPath: "./not/used",
+		},
+	}
+
+	for _, c := range cases {
+		err := c.npc.validate(r, c.args)
+		if c.expectedErr != nil {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.npc.apiModelPath).To(Equal(c.expectedPath))
+		}
+	}
+}
+
+func TestNetworkPlanRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out := &bytes.Buffer{}
+	npc := &networkPlanCmd{
+		apiModelPath: "../examples/kubernetes.json",
+		output:       "human",
+		out:          out,
+	}
+	g.Expect(npc.run()).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("k8s-subnet"))
+	g.Expect(out.String()).To(ContainSubstring("10.240.0.0/12"))
+	g.Expect(out.String()).To(ContainSubstring("No problems found"))
+
+	out.Reset()
+	npc = &networkPlanCmd{
+		apiModelPath: "../examples/vnet/kubernetesvnet-azure-cni.json",
+		subnetCIDRs:  map[string]string{"SUBNET_NAME": "10.239.0.0/25"},
+		output:       "json",
+		out:          out,
+	}
+	err := npc.run()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("found 3 problems in the network plan"))
+
+	plan := &api.NetworkPlan{}
+	g.Expect(json.Unmarshal(out.Bytes(), plan)).To(Succeed())
+	g.Expect(plan.Subnets).To(HaveLen(1))
+	g.Expect(plan.Subnets[0].Name).To(Equal("SUBNET_NAME"))
+	g.Expect(plan.Subnets[0].Needed).To(Equal(uint64(156)))
+	g.Expect(plan.Problems).To(Equal([]string{
+		"subnet SUBNET_NAME (10.239.0.0/25) does not contain the master static IPs 10.239.255.239",
+		"subnet SUBNET_NAME (10.239.0.0/25) does not contain the internal load balancer 10.239.255.249",
+		"subnet SUBNET_NAME (10.239.0.0/25) has 123 usable addresses but the cluster needs 156",
+	}))
+
+	npc = &networkPlanCmd{apiModelPath: "./not/there.json", output: "human", out: out}
+	err = npc.run()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("specified api model does not exist (./not/there.json)"))
+}
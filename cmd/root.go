@@ -16,7 +16,9 @@ import (
 	"github.com/Azure/aks-engine/pkg/api/vlabs"
 	"github.com/Azure/aks-engine/pkg/armhelpers"
 	"github.com/Azure/aks-engine/pkg/armhelpers/azurestack"
+	"github.com/Azure/aks-engine/pkg/engine"
 	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/operations"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
@@ -24,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	ini "gopkg.in/ini.v1"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
@@ -70,6 +73,11 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newUpgradeCmd())
 	rootCmd.AddCommand(newScaleCmd())
 	rootCmd.AddCommand(newRotateCertsCmd())
+	rootCmd.AddCommand(newUpgradeAddonsCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newCleanupCmd())
+	rootCmd.AddCommand(newMigrateIdentityCmd())
+	rootCmd.AddCommand(newNetworkPlanCmd())
 	rootCmd.AddCommand(getCompletionCmd(rootCmd))
 
 	return rootCmd
@@ -125,6 +133,67 @@ func addAuthFlags(authArgs *authArgs, f *flag.FlagSet) {
 	f.StringVar(&authArgs.language, "language", "en-us", "language to return error messages in")
 }
 
+type linkedTemplatesArgs struct {
+	storageAccount string
+	resourceGroup  string
+	sizeThreshold  int
+}
+
+func addLinkedTemplatesFlags(linkedTemplatesArgs *linkedTemplatesArgs, f *flag.FlagSet) {
+	f.StringVar(&linkedTemplatesArgs.storageAccount, "linked-templates-storage-account", "", "storage account agent pools are uploaded to as linked templates when the template exceeds --template-size-threshold")
+	f.StringVar(&linkedTemplatesArgs.resourceGroup, "linked-templates-resource-group", "", "resource group of --linked-templates-storage-account (defaults to the cluster resource group)")
+	f.IntVar(&linkedTemplatesArgs.sizeThreshold, "template-size-threshold", engine.DefaultTemplateSizeThreshold, "template size in bytes above which agent pools are split into linked templates")
+}
+
+func (linkedTemplatesArgs *linkedTemplatesArgs) getLinkedTemplatesStorage(clusterResourceGroup string) operations.LinkedTemplatesStorage {
+	resourceGroup := linkedTemplatesArgs.resourceGroup
+	if resourceGroup == "" {
+		resourceGroup = clusterResourceGroup
+	}
+	return operations.LinkedTemplatesStorage{
+		ResourceGroup:  resourceGroup,
+		StorageAccount: linkedTemplatesArgs.storageAccount,
+		SizeThreshold:  linkedTemplatesArgs.sizeThreshold,
+	}
+}
+
+type proxyArgs struct {
+	proxy       string
+	proxySSHKey string
+}
+
+func addProxyFlags(proxyArgs *proxyArgs, f *flag.FlagSet) {
+	f.StringVar(&proxyArgs.proxy, "proxy", "", "SOCKS5 proxy (socks5://[user:password@]host:port) or SSH bastion (ssh://user@host:port) to reach the apiserver of a private cluster through")
+	f.StringVar(&proxyArgs.proxySSHKey, "proxy-ssh-key", "", "the filepath of the private ssh key to authenticate to the SSH bastion of --proxy with")
+}
+
+// configureClient makes the Kubernetes clients of client reach the apiserver through --proxy, if set
+func (proxyArgs *proxyArgs) configureClient(client armhelpers.AKSEngineClient) error {
+	if proxyArgs.proxy == "" {
+		return nil
+	}
+	dialer, err := helpers.NewProxyDialer(proxyArgs.proxy, proxyArgs.proxySSHKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid --proxy")
+	}
+	client.SetKubernetesClientDialer(dialer.DialContext)
+	return nil
+}
+
+// getKubeConfigServer returns the apiserver URL of the current context of a kubeconfig
+func getKubeConfigServer(kubeconfig string) (string, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return "", errors.Wrap(err, "parsing kubeconfig")
+	}
+	if kubeContext, ok := config.Contexts[config.CurrentContext]; ok {
+		if cluster, ok := config.Clusters[kubeContext.Cluster]; ok && cluster.Server != "" {
+			return cluster.Server, nil
+		}
+	}
+	return "", errors.New("kubeconfig has no apiserver for its current context")
+}
+
 //this allows the authArgs to be stubbed behind the authProvider interface, and be its own provider when not in tests.
 func (authArgs *authArgs) getAuthArgs() *authArgs {
 	return authArgs
@@ -42,7 +42,7 @@ func TestNewRootCmd(t *testing.T) {
 	if command.Use != rootName || command.Short != rootShortDescription || command.Long != rootLongDescription {
 		t.Fatalf("root command should have use %s equal %s, short %s equal %s and long %s equal to %s", command.Use, rootName, command.Short, rootShortDescription, command.Long, rootLongDescription)
 	}
-	expectedCommands := []*cobra.Command{getCompletionCmd(command), newDeployCmd(), newGenerateCmd(), newGetVersionsCmd(), newOrchestratorsCmd(), newRotateCertsCmd(), newScaleCmd(), newUpgradeCmd(), newVersionCmd()}
+	expectedCommands := []*cobra.Command{newCleanupCmd(), getCompletionCmd(command), newDeployCmd(), newGenerateCmd(), newGetVersionsCmd(), newMigrateIdentityCmd(), newNetworkPlanCmd(), newOrchestratorsCmd(), newRotateCertsCmd(), newScaleCmd(), newStatusCmd(), newUpgradeCmd(), newUpgradeAddonsCmd(), newVersionCmd()}
 	rc := command.Commands()
 	for i, c := range expectedCommands {
 		if rc[i].Use != c.Use {
@@ -435,3 +435,54 @@ func prepareCustomCloudProfile() *api.ContainerService {
 
 	return cs
 }
+
+func TestGetLinkedTemplatesStorage(t *testing.T) {
+	args := &linkedTemplatesArgs{
+		storageAccount: "storageaccount",
+		sizeThreshold:  1024,
+	}
+	storage := args.getLinkedTemplatesStorage("clusterrg")
+	if storage.ResourceGroup != "clusterrg" || storage.StorageAccount != "storageaccount" || storage.SizeThreshold != 1024 {
+		t.Fatalf("unexpected linked templates storage %+v", storage)
+	}
+
+	args.resourceGroup = "storagerg"
+	if storage = args.getLinkedTemplatesStorage("clusterrg"); storage.ResourceGroup != "storagerg" {
+		t.Fatalf("expected linked templates resource group storagerg, got %s", storage.ResourceGroup)
+	}
+}
+
+func TestProxyArgsConfigureClient(t *testing.T) {
+	client := &armhelpers.MockAKSEngineClient{}
+
+	args := &proxyArgs{}
+	if err := args.configureClient(client); err != nil {
+		t.Fatalf("expected no error without --proxy, got %s", err)
+	}
+
+	args.proxy = "socks5://127.0.0.1:1080"
+	if err := args.configureClient(client); err != nil {
+		t.Fatalf("expected no error with a SOCKS5 proxy, got %s", err)
+	}
+
+	args.proxy = "ssh://azureuser@bastion.example.com"
+	if err := args.configureClient(client); err == nil {
+		t.Fatalf("expected an error with an SSH bastion and no --proxy-ssh-key")
+	}
+}
+
+func TestGetKubeConfigServer(t *testing.T) {
+	kubeconfig := `{"apiVersion":"v1","kind":"Config","clusters":[{"name":"mycluster","cluster":{"server":"https://10.239.255.249"}}],"contexts":[{"name":"mycluster","context":{"cluster":"mycluster","user":"mycluster-admin"}}],"current-context":"mycluster","users":[{"name":"mycluster-admin","user":{}}]}`
+
+	server, err := getKubeConfigServer(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if server != "https://10.239.255.249" {
+		t.Fatalf("expected server https://10.239.255.249, got %s", server)
+	}
+
+	if _, err = getKubeConfigServer(`{"apiVersion":"v1","kind":"Config"}`); err == nil {
+		t.Fatalf("expected an error for a kubeconfig without current context")
+	}
+}
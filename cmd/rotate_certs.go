@@ -146,7 +146,7 @@ func (rcc *rotateCertsCmd) run(cmd *cobra.Command, args []string) error {
 	log.Infoln("Generating new certificates")
 
 	// reset the certificateProfile and use the exisiting certificate generation code to generate new certificates.
-	rcc.containerService.Properties.CertificateProfile = &api.CertificateProfile{}
+	rcc.resetCertificateProfile()
 	certsGenerated, _, err := rcc.containerService.SetDefaultCerts()
 	if !certsGenerated || err != nil {
 		return errors.Wrap(err, "generating new certificates")
@@ -206,6 +206,16 @@ func (rcc *rotateCertsCmd) run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// resetCertificateProfile removes the certificates from the api model, keeping the pkiProfile so that new
+// certificates are generated with the same keys and validity
+func (rcc *rotateCertsCmd) resetCertificateProfile() {
+	var pkiProfile *api.PKIProfile
+	if rcc.containerService.Properties.CertificateProfile != nil {
+		pkiProfile = rcc.containerService.Properties.CertificateProfile.PKIProfile
+	}
+	rcc.containerService.Properties.CertificateProfile = &api.CertificateProfile{PKIProfile: pkiProfile}
+}
+
 func (rcc *rotateCertsCmd) writeArtifacts() error {
 	ctx := engine.Context{
 		Translator: &i18n.Translator{
@@ -259,6 +259,24 @@ func TestWriteArtifacts(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 }
 
+func TestResetCertificateProfile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cs := api.CreateMockContainerService("testcluster", "1.10.13", 3, 2, true)
+	pkiProfile := &api.PKIProfile{
+		CA: &api.PKICertificateProfile{KeyAlgorithm: "ECDSA", ValidityDays: 365},
+	}
+	cs.Properties.CertificateProfile.PKIProfile = pkiProfile
+	rcc := rotateCertsCmd{
+		containerService: cs,
+	}
+	rcc.resetCertificateProfile()
+	g.Expect(cs.Properties.CertificateProfile).To(Equal(&api.CertificateProfile{PKIProfile: pkiProfile}))
+
+	cs.Properties.CertificateProfile = nil
+	rcc.resetCertificateProfile()
+	g.Expect(cs.Properties.CertificateProfile).To(Equal(&api.CertificateProfile{}))
+}
+
 func TestUpdateKubeconfig(t *testing.T) {
 	g := NewGomegaWithT(t)
 	cs := api.CreateMockContainerService("testcluster", "1.10.13", 3, 2, false)
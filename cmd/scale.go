@@ -32,6 +32,8 @@ import (
 
 type scaleCmd struct {
 	authArgs
+	linkedTemplatesArgs
+	proxyArgs
 
 	// user input
 	apiModelPath         string
@@ -88,6 +90,8 @@ func newScaleCmd() *cobra.Command {
 	f.MarkDeprecated("master-FQDN", "--apiserver is preferred")
 
 	addAuthFlags(&sc.authArgs, f)
+	addLinkedTemplatesFlags(&sc.linkedTemplatesArgs, f)
+	addProxyFlags(&sc.proxyArgs, f)
 
 	return scaleCmd
 }
@@ -174,6 +178,10 @@ func (sc *scaleCmd) load() error {
 		return errors.Wrap(err, "failed to get client")
 	}
 
+	if err = sc.proxyArgs.configureClient(sc.client); err != nil {
+		return err
+	}
+
 	_, err = sc.client.EnsureResourceGroup(ctx, sc.resourceGroupName, sc.location, nil)
 	if err != nil {
 		return err
@@ -228,6 +236,13 @@ func (sc *scaleCmd) load() error {
 	if err != nil {
 		return errors.New("Unable to derive kubeconfig from api model")
 	}
+
+	// private clusters are reached through the proxy at the internal address of their apiserver
+	if sc.apiserverURL == "" && sc.proxy != "" && sc.containerService.Properties.OrchestratorProfile.IsPrivateCluster() {
+		if sc.apiserverURL, err = getKubeConfigServer(sc.kubeconfig); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -414,6 +429,18 @@ func (sc *scaleCmd) run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if sc.newDesiredAgentCount > currentNodeCount {
+		requirements := []operations.VMRequirement{{
+			Pool:   sc.agentPool.Name,
+			VMSize: sc.agentPool.VMSize,
+			Count:  sc.newDesiredAgentCount - currentNodeCount,
+			Zones:  sc.agentPool.AvailabilityZones,
+		}}
+		if err := operations.CheckComputeCapacity(ctx, sc.client, sc.logger, sc.location, requirements); err != nil {
+			return errors.Wrap(err, "checking the compute capacity")
+		}
+	}
+
 	translator := engine.Context{
 		Translator: &i18n.Translator{
 			Locale: sc.locale,
@@ -491,10 +518,15 @@ func (sc *scaleCmd) run(cmd *cobra.Command, args []string) error {
 		sc.logger.Infof("Nodes in pool %s before scaling:\n", sc.agentPoolToScale)
 		operations.PrintNodes(sc.nodes)
 	}
+	deploymentName := fmt.Sprintf("%s-%d", sc.resourceGroupName, deploymentSuffix)
+	templateJSON, parametersJSON, err = operations.SplitTemplateForDeployment(ctx, sc.client, sc.logger, sc.getLinkedTemplatesStorage(sc.resourceGroupName), sc.containerService, deploymentName, templateJSON, parametersJSON)
+	if err != nil {
+		return errors.Wrap(err, "preparing template for deployment")
+	}
 	_, err = sc.client.DeployTemplate(
 		ctx,
 		sc.resourceGroupName,
-		fmt.Sprintf("%s-%d", sc.resourceGroupName, deploymentSuffix),
+		deploymentName,
 		templateJSON,
 		parametersJSON)
 	if err != nil {
@@ -0,0 +1,527 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	statusName             = "status"
+	statusShortDescription = "Report the health of an existing Kubernetes cluster"
+	statusLongDescription  = "Report the provisioning and power state of the virtual machines of a cluster built with AKS Engine, the readiness and version of its nodes, its unhealthy kube-system pods and, if an ssh key is provided, the health of the etcd members. Exits with a non-zero status if any problem is found."
+)
+
+// etcdHealthCommand checks the health of the etcd member of a master node, with the client certificates set in
+// /etc/environment by the provisioning scripts
+const etcdHealthCommand = "sudo bash -c '. /etc/environment && ETCDCTL_API=3 etcdctl --command-timeout=30s --cacert=${ETCDCTL_CA_FILE} --cert=${ETCDCTL_CERT_FILE} --key=${ETCDCTL_KEY_FILE} --endpoints=${ETCDCTL_ENDPOINTS} endpoint health'"
+
+type statusCmd struct {
+	authProvider
+
+	// user input
+	resourceGroupName string
+	location          string
+	apiModelPath      string
+	sshFilepath       string
+	masterFQDN        string
+	output            string
+
+	// derived
+	containerService   *api.ContainerService
+	apiVersion         string
+	locale             *gotext.Locale
+	client             armhelpers.AKSEngineClient
+	nameSuffix         string
+	sshConfig          *ssh.ClientConfig
+	sshCommandExecuter func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error)
+	out                io.Writer
+}
+
+// clusterStatus is the health of the virtual machines, nodes, kube-system pods and etcd members of a cluster
+type clusterStatus struct {
+	VirtualMachines []vmStatus   `json:"virtualMachines"`
+	Nodes           []nodeStatus `json:"nodes"`
+	UnhealthyPods   []podStatus  `json:"unhealthyPods"`
+	Etcd            []etcdStatus `json:"etcd,omitempty"`
+	Problems        []string     `json:"problems"`
+}
+
+type vmStatus struct {
+	Name                string `json:"name"`
+	PoolName            string `json:"poolName,omitempty"`
+	ScaleSet            string `json:"scaleSet,omitempty"`
+	ProvisioningState   string `json:"provisioningState"`
+	PowerState          string `json:"powerState"`
+	OrchestratorVersion string `json:"orchestratorVersion,omitempty"`
+	nodeName            string
+}
+
+type nodeStatus struct {
+	Name           string `json:"name"`
+	Ready          bool   `json:"ready"`
+	KubeletVersion string `json:"kubeletVersion"`
+}
+
+type podStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Node      string `json:"node"`
+	Phase     string `json:"phase"`
+	Reason    string `json:"reason,omitempty"`
+	Restarts  int32  `json:"restarts"`
+}
+
+type etcdStatus struct {
+	Node    string `json:"node"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+func newStatusCmd() *cobra.Command {
+	sc := statusCmd{
+		authProvider:       &authArgs{},
+		sshCommandExecuter: executeCmd,
+		out:                os.Stdout,
+	}
+
+	command := &cobra.Command{
+		Use:   statusName,
+		Short: statusShortDescription,
+		Long:  statusLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := sc.validate(cmd); err != nil {
+				return errors.Wrap(err, "validating statusCmd")
+			}
+			return sc.run()
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&sc.location, "location", "l", "", "location the cluster is deployed in (required)")
+	f.StringVarP(&sc.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed (required)")
+	f.StringVarP(&sc.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file (required)")
+	f.StringVarP(&sc.sshFilepath, "ssh", "", "", "the filepath of a valid private ssh key to access the master nodes, to check the health of etcd (optional)")
+	f.StringVar(&sc.masterFQDN, "apiserver", "", "apiserver endpoint, required with --ssh")
+	statusCmdDescription := fmt.Sprintf("Output format. Allowed values: %s",
+		strings.Join(outputFormatOptions, ", "))
+	f.StringVarP(&sc.output, "output", "o", "human", statusCmdDescription)
+
+	addAuthFlags(sc.getAuthArgs(), f)
+
+	return command
+}
+
+func (sc *statusCmd) validate(cmd *cobra.Command) error {
+	var err error
+
+	sc.locale, err = i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+
+	if sc.resourceGroupName == "" {
+		cmd.Usage()
+		return errors.New("--resource-group must be specified")
+	}
+
+	if sc.location == "" {
+		cmd.Usage()
+		return errors.New("--location must be specified")
+	}
+	sc.location = helpers.NormalizeAzureRegion(sc.location)
+
+	if sc.apiModelPath == "" {
+		cmd.Usage()
+		return errors.New("--api-model must be specified")
+	}
+
+	if sc.sshFilepath != "" && sc.masterFQDN == "" {
+		cmd.Usage()
+		return errors.New("--apiserver must be specified with --ssh")
+	}
+
+	if sc.output != "human" && sc.output != "json" {
+		return errors.Errorf(`output format "%s" is not supported`, sc.output)
+	}
+
+	return nil
+}
+
+func (sc *statusCmd) run() error {
+	var err error
+
+	if err = sc.getAuthArgs().validateAuthArgs(); err != nil {
+		return errors.Wrap(err, "failed to get validate auth args")
+	}
+
+	if sc.client, err = sc.authProvider.getClient(); err != nil {
+		return errors.Wrap(err, "failed to get client")
+	}
+
+	if err = sc.loadCluster(); err != nil {
+		return err
+	}
+
+	if sc.sshFilepath != "" {
+		if _, err = os.Stat(sc.sshFilepath); os.IsNotExist(err) {
+			return errors.Errorf("specified ssh filepath does not exist (%s)", sc.sshFilepath)
+		}
+		sc.setSSHConfig()
+	}
+
+	status, err := sc.getClusterStatus()
+	if err != nil {
+		return err
+	}
+	if err = sc.printStatus(status); err != nil {
+		return err
+	}
+	if len(status.Problems) > 0 {
+		return errors.Errorf("found %d problems in the cluster", len(status.Problems))
+	}
+	return nil
+}
+
+func (sc *statusCmd) loadCluster() error {
+	var err error
+
+	if _, err = os.Stat(sc.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", sc.apiModelPath)
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: sc.locale,
+		},
+	}
+	sc.containerService, sc.apiVersion, err = apiloader.LoadContainerServiceFromFile(sc.apiModelPath, true, true, nil)
+	if err != nil {
+		return errors.Wrap(err, "parsing the api model")
+	}
+
+	if !sc.containerService.Properties.OrchestratorProfile.IsKubernetes() {
+		return errors.New("status is only supported for Kubernetes clusters")
+	}
+
+	//allows to identify VMs in the resource group that belong to this cluster.
+	sc.nameSuffix = sc.containerService.Properties.GetClusterID()
+	return nil
+}
+
+// getClusterStatus collects the status of the cluster, the problems it finds are listed in the status
+func (sc *statusCmd) getClusterStatus() (*clusterStatus, error) {
+	status := &clusterStatus{}
+	version := sc.containerService.Properties.OrchestratorProfile.OrchestratorVersion
+
+	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+
+	vms, err := sc.getVMStatuses(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing virtual machines")
+	}
+	status.VirtualMachines = vms
+	for _, vm := range vms {
+		if vm.ProvisioningState != "Succeeded" {
+			status.addProblem("virtual machine %s is in provisioning state %s", vm.Name, vm.ProvisioningState)
+		}
+		if vm.PowerState != "PowerState/running" {
+			status.addProblem("virtual machine %s is in power state %s", vm.Name, vm.PowerState)
+		}
+		if vm.OrchestratorVersion != "" && vm.OrchestratorVersion != version {
+			status.addProblem("virtual machine %s is tagged with Kubernetes version %s, the api model version is %s", vm.Name, vm.OrchestratorVersion, version)
+		}
+	}
+
+	kubeClient, err := sc.getKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Kubernetes Client")
+	}
+	nodeList, err := kubeClient.ListNodes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster nodes")
+	}
+	nodeNames := map[string]bool{}
+	var masterNodes []string
+	for _, node := range nodeList.Items {
+		n := nodeStatus{
+			Name:           node.Name,
+			KubeletVersion: strings.TrimPrefix(node.Status.NodeInfo.KubeletVersion, "v"),
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady {
+				n.Ready = condition.Status == v1.ConditionTrue
+			}
+		}
+		status.Nodes = append(status.Nodes, n)
+		nodeNames[strings.ToLower(node.Name)] = true
+		if strings.Contains(node.Name, "master") {
+			masterNodes = append(masterNodes, node.Name)
+		}
+		if !n.Ready {
+			status.addProblem("node %s is not ready", n.Name)
+		}
+		if n.KubeletVersion != version {
+			status.addProblem("node %s runs Kubernetes version %s, the api model version is %s", n.Name, n.KubeletVersion, version)
+		}
+	}
+	for _, vm := range vms {
+		if !nodeNames[vm.nodeName] {
+			status.addProblem("virtual machine %s is not registered as a node", vm.Name)
+		}
+	}
+
+	podList, err := kubeClient.ListAllPods()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster pods")
+	}
+	for _, pod := range podList.Items {
+		if pod.Namespace != metav1.NamespaceSystem {
+			continue
+		}
+		if p, healthy := getPodStatus(pod); !healthy {
+			status.UnhealthyPods = append(status.UnhealthyPods, p)
+			status.addProblem("pod %s/%s on node %s is %s", p.Namespace, p.Name, p.Node, p.describe())
+		}
+	}
+
+	if sc.sshConfig != nil {
+		for _, node := range masterNodes {
+			e := sc.getEtcdStatus(node)
+			status.Etcd = append(status.Etcd, e)
+			if !e.Healthy {
+				status.addProblem("etcd member on node %s is unhealthy: %s", e.Node, e.Message)
+			}
+		}
+	}
+	return status, nil
+}
+
+// getVMStatuses returns the status of the virtual machines and VMSS instances of the cluster in the resource group
+func (sc *statusCmd) getVMStatuses(ctx context.Context) ([]vmStatus, error) {
+	var statuses []vmStatus
+	for vmListPage, err := sc.client.ListVirtualMachines(ctx, sc.resourceGroupName); vmListPage.NotDone(); err = vmListPage.Next() {
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range vmListPage.Values() {
+			if !sc.isClusterResource(*vm.Name) {
+				continue
+			}
+			s := vmStatus{
+				Name:                *vm.Name,
+				PoolName:            getTag(vm.Tags, "poolName"),
+				OrchestratorVersion: getOrchestratorVersionTag(vm.Tags),
+				nodeName:            strings.ToLower(*vm.Name),
+			}
+			if vm.VirtualMachineProperties != nil && vm.VirtualMachineProperties.ProvisioningState != nil {
+				s.ProvisioningState = *vm.VirtualMachineProperties.ProvisioningState
+			}
+			if s.PowerState, err = sc.client.GetVirtualMachinePowerState(ctx, sc.resourceGroupName, *vm.Name); err != nil {
+				return nil, errors.Wrapf(err, "getting the power state of virtual machine %s", *vm.Name)
+			}
+			statuses = append(statuses, s)
+		}
+	}
+
+	for vmScaleSetPage, err := sc.client.ListVirtualMachineScaleSets(ctx, sc.resourceGroupName); vmScaleSetPage.NotDone(); err = vmScaleSetPage.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, vmScaleSet := range vmScaleSetPage.Values() {
+			if !sc.isClusterResource(*vmScaleSet.Name) {
+				continue
+			}
+			for vmScaleSetVMsPage, err := sc.client.ListVirtualMachineScaleSetVMs(ctx, sc.resourceGroupName, *vmScaleSet.Name); vmScaleSetVMsPage.NotDone(); err = vmScaleSetVMsPage.NextWithContext(ctx) {
+				if err != nil {
+					return nil, err
+				}
+				for _, vm := range vmScaleSetVMsPage.Values() {
+					s := vmStatus{
+						Name:     *vm.Name,
+						PoolName: getTag(vm.Tags, "poolName"),
+						ScaleSet: *vmScaleSet.Name,
+						nodeName: strings.ToLower(*vm.Name),
+					}
+					if p := vm.VirtualMachineScaleSetVMProperties; p != nil {
+						if p.ProvisioningState != nil {
+							s.ProvisioningState = *p.ProvisioningState
+						}
+						if p.OsProfile != nil && p.OsProfile.ComputerName != nil {
+							s.nodeName = strings.ToLower(*p.OsProfile.ComputerName)
+						}
+						// instances share the tags of the scale set, which only reflect their version if they run its latest model
+						if p.LatestModelApplied != nil && *p.LatestModelApplied {
+							s.OrchestratorVersion = getOrchestratorVersionTag(vm.Tags)
+						}
+					}
+					if s.PowerState, err = sc.client.GetVirtualMachineScaleSetVMPowerState(ctx, sc.resourceGroupName, *vmScaleSet.Name, *vm.InstanceID); err != nil {
+						return nil, errors.Wrapf(err, "getting the power state of virtual machine %s", *vm.Name)
+					}
+					statuses = append(statuses, s)
+				}
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func (sc *statusCmd) isClusterResource(name string) bool {
+	return isClusterResource(name, sc.nameSuffix)
+}
+
+// isClusterResource returns true if the name of a resource, e.g. a VM or VMSS, has the name suffix of the cluster.
+// Windows resources contain a substring of the name suffix.
+func isClusterResource(name, nameSuffix string) bool {
+	return strings.Contains(name, nameSuffix) || strings.Contains(name, nameSuffix[:4]+"k8s")
+}
+
+func (sc *statusCmd) getKubeClient() (armhelpers.KubernetesClient, error) {
+	kubeconfig, err := engine.GenerateKubeConfig(sc.containerService.Properties, sc.location)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating kubeconfig")
+	}
+	kubeClient, err := sc.client.GetKubernetesClient("", kubeconfig, time.Second*1, time.Duration(60)*time.Minute)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get a Kubernetes client")
+	}
+	return kubeClient, nil
+}
+
+func (sc *statusCmd) getEtcdStatus(node string) etcdStatus {
+	out, err := sc.sshCommandExecuter(etcdHealthCommand, sc.masterFQDN, node, "22", sc.sshConfig)
+	out = strings.TrimSpace(strings.TrimPrefix(out, node+" -> "))
+	if err != nil {
+		if out == "" {
+			out = err.Error()
+		}
+		return etcdStatus{Node: node, Message: out}
+	}
+	return etcdStatus{Node: node, Healthy: strings.Contains(out, "is healthy"), Message: out}
+}
+
+func (sc *statusCmd) setSSHConfig() {
+	sc.sshConfig = &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            sc.containerService.Properties.LinuxProfile.AdminUsername,
+		Auth: []ssh.AuthMethod{
+			publicKeyFile(sc.sshFilepath),
+		},
+	}
+}
+
+func (sc *statusCmd) printStatus(status *clusterStatus) error {
+	if sc.output == "json" {
+		data, err := helpers.JSONMarshalIndent(status, "", "  ", false)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(sc.out, string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(sc.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Virtual Machine\tPool\tProvisioning State\tPower State\tVersion")
+	for _, vm := range status.VirtualMachines {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", vm.Name, vm.PoolName, vm.ProvisioningState, strings.TrimPrefix(vm.PowerState, "PowerState/"), vm.OrchestratorVersion)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Node\tReady\tVersion")
+	for _, n := range status.Nodes {
+		fmt.Fprintf(w, "%s\t%t\t%s\n", n.Name, n.Ready, n.KubeletVersion)
+	}
+	if len(status.UnhealthyPods) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Unhealthy Pod\tNode\tStatus\tRestarts")
+		for _, p := range status.UnhealthyPods {
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\t%d\n", p.Namespace, p.Name, p.Node, p.describe(), p.Restarts)
+		}
+	}
+	if len(status.Etcd) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Etcd Member\tHealthy")
+		for _, e := range status.Etcd {
+			fmt.Fprintf(w, "%s\t%t\n", e.Node, e.Healthy)
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(sc.out)
+	if len(status.Problems) == 0 {
+		fmt.Fprintln(sc.out, "No problems found")
+		return nil
+	}
+	fmt.Fprintln(sc.out, "Problems:")
+	for _, p := range status.Problems {
+		fmt.Fprintf(sc.out, "  - %s\n", p)
+	}
+	return nil
+}
+
+func (s *clusterStatus) addProblem(format string, args ...interface{}) {
+	s.Problems = append(s.Problems, fmt.Sprintf(format, args...))
+}
+
+// getPodStatus returns the status of a pod, and whether it is healthy: a pod is unhealthy if it is neither running
+// nor completed, or if one of its containers is waiting to be restarted
+func getPodStatus(pod v1.Pod) (podStatus, bool) {
+	p := podStatus{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Node:      pod.Spec.NodeName,
+		Phase:     string(pod.Status.Phase),
+		Reason:    pod.Status.Reason,
+	}
+	healthy := pod.Status.Phase == v1.PodRunning || pod.Status.Phase == v1.PodSucceeded
+	for _, c := range pod.Status.ContainerStatuses {
+		p.Restarts += c.RestartCount
+		if c.State.Waiting != nil && c.State.Waiting.Reason != "" && c.State.Waiting.Reason != "ContainerCreating" {
+			p.Reason = c.State.Waiting.Reason
+			healthy = false
+		}
+	}
+	return p, healthy
+}
+
+func (p podStatus) describe() string {
+	if p.Reason != "" {
+		return fmt.Sprintf("%s (%s)", p.Phase, p.Reason)
+	}
+	return p.Phase
+}
+
+func getTag(tags map[string]*string, name string) string {
+	if tags != nil && tags[name] != nil {
+		return *tags[name]
+	}
+	return ""
+}
+
+// getOrchestratorVersionTag returns the Kubernetes version of the orchestrator tag of a VM, e.g. Kubernetes:1.14.6
+func getOrchestratorVersionTag(tags map[string]*string) string {
+	parts := strings.Split(getTag(tags, "orchestrator"), ":")
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
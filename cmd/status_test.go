@@ -0,0 +1,186 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewStatusCmd(t *testing.T) {
+	output := newStatusCmd()
+	if output.Use != statusName || output.Short != statusShortDescription || output.Long != statusLongDescription {
+		t.Fatalf("status command should have use %s equal %s, short %s equal %s and long %s equal to %s", output.Use, statusName, output.Short, statusShortDescription, output.Long, statusLongDescription)
+	}
+
+	expectedFlags := []string{"location", "resource-group", "api-model", "ssh", "apiserver", "output"}
+	for _, f := range expectedFlags {
+		if output.Flags().Lookup(f) == nil {
+			t.Fatalf("status command should have flag %s", f)
+		}
+	}
+}
+
+func TestStatusCmdShouldBeValidated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &cobra.Command{}
+
+	cases := []struct {
+		sc          *statusCmd
+		expectedErr error
+	}{
+		{
+			sc:          &statusCmd{location: "westus", apiModelPath: "./not/used", output: "human"},
+			expectedErr: errors.New("--resource-group must be specified"),
+		},
+		{
+			sc:          &statusCmd{resourceGroupName: "test", apiModelPath: "./not/used", output: "human"},
+			expectedErr: errors.New("--location must be specified"),
+		},
+		{
+			sc:          &statusCmd{resourceGroupName: "test", location: "westus", output: "human"},
+			expectedErr: errors.New("--api-model must be specified"),
+		},
+		{
+			sc:          &statusCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", sshFilepath: "./not/used", output: "human"},
+			expectedErr: errors.New("--apiserver must be specified with --ssh"),
+		},
+		{
+			sc:          &statusCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", output: "yaml"},
+			expectedErr: errors.New(`output format "yaml" is not supported`),
+		},
+		{
+			sc:          &statusCmd{resourceGroupName: "test", location: "West US", apiModelPath: "./not/used", output: "json"},
+			expectedErr: nil,
+		},
+	}
+
+	for _, c := range cases {
+		err := c.sc.validate(r)
+		if c.expectedErr != nil {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.sc.location).To(Equal("westus"))
+		}
+	}
+}
+
+func TestGetClusterStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &armhelpers.MockAKSEngineClient{
+		MockKubernetesClient: &armhelpers.MockKubernetesClient{
+			PodsList: &v1.PodList{
+				Items: []v1.Pod{
+					newStatusTestPod(metav1.NamespaceSystem, "coredns-1234", v1.PodRunning, ""),
+					newStatusTestPod(metav1.NamespaceSystem, "metrics-server-1234", v1.PodRunning, "CrashLoopBackOff"),
+					newStatusTestPod(metav1.NamespaceDefault, "app-1234", v1.PodFailed, ""),
+				},
+			},
+		},
+	}
+	client.FakeListVirtualMachineResult = func() []compute.VirtualMachine {
+		master := client.MakeFakeVirtualMachine("k8s-master-1234", "Kubernetes:1.9.10")
+		master.VirtualMachineProperties.ProvisioningState = to.StringPtr("Succeeded")
+		agent := client.MakeFakeVirtualMachine("k8s-agentpool1-1234-0", "Kubernetes:1.9.10")
+		agent.VirtualMachineProperties.ProvisioningState = to.StringPtr("Failed")
+		other := client.MakeFakeVirtualMachine("k8s-agentpool1-87654321-0", "Kubernetes:1.9.10")
+		return []compute.VirtualMachine{master, agent, other}
+	}
+
+	var etcdNodes []string
+	sc := &statusCmd{
+		location:          "westus",
+		resourceGroupName: "rg",
+		masterFQDN:        "example.westus.cloudapp.azure.com",
+		output:            "json",
+		client:            client,
+		containerService:  api.CreateMockContainerService("testcluster", "1.9.10", 3, 2, false),
+		nameSuffix:        "1234",
+		sshConfig:         &ssh.ClientConfig{},
+		sshCommandExecuter: func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error) {
+			etcdNodes = append(etcdNodes, hostname)
+			return hostname + " -> https://127.0.0.1:2379 is healthy: successfully committed proposal: took = 1.5ms\n", nil
+		},
+	}
+
+	status, err := sc.getClusterStatus()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(status.VirtualMachines).To(HaveLen(2))
+	g.Expect(status.VirtualMachines[0].PowerState).To(Equal("PowerState/running"))
+	g.Expect(status.Nodes).To(HaveLen(2))
+	g.Expect(status.UnhealthyPods).To(HaveLen(1))
+	g.Expect(status.UnhealthyPods[0].Name).To(Equal("metrics-server-1234"))
+	g.Expect(etcdNodes).To(Equal([]string{"k8s-master-1234"}))
+	g.Expect(status.Etcd).To(HaveLen(1))
+	g.Expect(status.Etcd[0].Healthy).To(BeTrue())
+	g.Expect(status.Problems).To(ConsistOf(
+		"virtual machine k8s-agentpool1-1234-0 is in provisioning state Failed",
+		"node k8s-agentpool3-1234 is not ready",
+		"node k8s-agentpool3-1234 runs Kubernetes version 1.9.9, the api model version is 1.9.10",
+		"virtual machine k8s-agentpool1-1234-0 is not registered as a node",
+		"pod kube-system/metrics-server-1234 on node k8s-agentpool1-1234-0 is Running (CrashLoopBackOff)",
+	))
+
+	out := &bytes.Buffer{}
+	sc.out = out
+	g.Expect(sc.printStatus(status)).To(Succeed())
+	var printed clusterStatus
+	g.Expect(json.Unmarshal(out.Bytes(), &printed)).To(Succeed())
+	g.Expect(printed.Problems).To(Equal(status.Problems))
+
+	out.Reset()
+	sc.output = "human"
+	g.Expect(sc.printStatus(status)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("k8s-agentpool1-1234-0  agentpool1  Failed"))
+	g.Expect(out.String()).To(ContainSubstring("  - node k8s-agentpool3-1234 is not ready\n"))
+}
+
+func TestGetEtcdStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sc := &statusCmd{
+		sshCommandExecuter: func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error) {
+			return hostname + " -> https://127.0.0.1:2379 is unhealthy: failed to commit proposal: context deadline exceeded\n", errors.New("Process exited with status 1")
+		},
+	}
+	e := sc.getEtcdStatus("k8s-master-1234-0")
+	g.Expect(e.Healthy).To(BeFalse())
+	g.Expect(e.Message).To(Equal("https://127.0.0.1:2379 is unhealthy: failed to commit proposal: context deadline exceeded"))
+
+	sc.sshCommandExecuter = func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error) {
+		return "", errors.New("ssh: handshake failed")
+	}
+	e = sc.getEtcdStatus("k8s-master-1234-0")
+	g.Expect(e.Healthy).To(BeFalse())
+	g.Expect(e.Message).To(Equal("ssh: handshake failed"))
+}
+
+func newStatusTestPod(namespace, name string, phase v1.PodPhase, waitingReason string) v1.Pod {
+	pod := v1.Pod{}
+	pod.Namespace = namespace
+	pod.Name = name
+	pod.Spec.NodeName = "k8s-agentpool1-1234-0"
+	pod.Status.Phase = phase
+	containerStatus := v1.ContainerStatus{Name: name, RestartCount: 3}
+	if waitingReason != "" {
+		containerStatus.State.Waiting = &v1.ContainerStateWaiting{Reason: waitingReason}
+	}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{containerStatus}
+	return pod
+}
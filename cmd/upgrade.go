@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Azure/aks-engine/pkg/api"
@@ -15,6 +17,7 @@ import (
 	"github.com/Azure/aks-engine/pkg/engine"
 	"github.com/Azure/aks-engine/pkg/helpers"
 	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/aks-engine/pkg/operations"
 	"github.com/Azure/aks-engine/pkg/operations/kubernetesupgrade"
 	"github.com/leonelquinteros/gotext"
 	"github.com/pkg/errors"
@@ -31,6 +34,8 @@ const (
 
 type upgradeCmd struct {
 	authProvider
+	linkedTemplatesArgs
+	proxyArgs
 
 	// user input
 	resourceGroupName           string
@@ -41,6 +46,8 @@ type upgradeCmd struct {
 	timeoutInMinutes            int
 	cordonDrainTimeoutInMinutes int
 	force                       bool
+	nodeImageOnly               bool
+	nodePools                   []string
 
 	// derived
 	containerService    *api.ContainerService
@@ -74,7 +81,11 @@ func newUpgradeCmd() *cobra.Command {
 	f.IntVar(&uc.timeoutInMinutes, "vm-timeout", -1, "how long to wait for each vm to be upgraded in minutes")
 	f.IntVar(&uc.cordonDrainTimeoutInMinutes, "cordon-drain-timeout", -1, "how long to wait for each vm to be cordoned in minutes")
 	f.BoolVarP(&uc.force, "force", "f", false, "force upgrading the cluster to desired version. Allows same version upgrades and downgrades.")
+	f.BoolVar(&uc.nodeImageOnly, "node-image-only", false, "replace the nodes of the agent pools with nodes of the OS image of the api model, without upgrading the masters or the Kubernetes version")
+	f.StringSliceVar(&uc.nodePools, "node-pool", nil, "agent pool whose nodes are replaced by --node-image-only, can be repeated (defaults to the Windows agent pools)")
 	addAuthFlags(uc.getAuthArgs(), f)
+	addLinkedTemplatesFlags(&uc.linkedTemplatesArgs, f)
+	addProxyFlags(&uc.proxyArgs, f)
 
 	f.MarkDeprecated("deployment-dir", "deployment-dir is no longer required for scale or upgrade. Please use --api-model.")
 
@@ -110,11 +121,16 @@ func (uc *upgradeCmd) validate(cmd *cobra.Command) error {
 		uc.cordonDrainTimeout = &cordonDrainTimeout
 	}
 
-	if uc.upgradeVersion == "" {
+	if uc.upgradeVersion == "" && !uc.nodeImageOnly {
 		cmd.Usage()
 		return errors.New("--upgrade-version must be specified")
 	}
 
+	if len(uc.nodePools) > 0 && !uc.nodeImageOnly {
+		cmd.Usage()
+		return errors.New("--node-pool can only be used with --node-image-only")
+	}
+
 	if uc.apiModelPath == "" && uc.deploymentDirectory == "" {
 		cmd.Usage()
 		return errors.New("--api-model must be specified")
@@ -171,6 +187,10 @@ func (uc *upgradeCmd) loadCluster() error {
 		return errors.Wrap(err, "failed to get client")
 	}
 
+	if err = uc.proxyArgs.configureClient(uc.client); err != nil {
+		return err
+	}
+
 	_, err = uc.client.EnsureResourceGroup(ctx, uc.resourceGroupName, uc.location, nil)
 	if err != nil {
 		return errors.Wrap(err, "error ensuring resource group")
@@ -210,12 +230,19 @@ func (uc *upgradeCmd) initialize() error {
 		return errors.New("--location does not match api model location")
 	}
 
+	if uc.nodeImageOnly {
+		return uc.initializeNodeImageUpgrade()
+	}
+
 	if !uc.force {
 		err := uc.validateTargetVersion()
 		if err != nil {
 			return errors.Wrap(err, "Invalid upgrade target version. Consider using --force if you really want to proceed")
 		}
 	}
+	if err := uc.validateTargetAddons(); err != nil {
+		return errors.Wrap(err, "Invalid upgrade target version. Consider using --force if you really want to proceed")
+	}
 	uc.containerService.Properties.OrchestratorProfile.OrchestratorVersion = uc.upgradeVersion
 
 	//allows to identify VMs in the resource group that belong to this cluster.
@@ -231,6 +258,88 @@ func (uc *upgradeCmd) initialize() error {
 	return nil
 }
 
+// initializeNodeImageUpgrade selects the Windows agent pools, whose nodes are replaced with nodes of the image
+// of the api model. The Kubernetes version of the cluster can't change.
+func (uc *upgradeCmd) initializeNodeImageUpgrade() error {
+	currentVersion := uc.containerService.Properties.OrchestratorProfile.OrchestratorVersion
+	if uc.upgradeVersion != "" && uc.upgradeVersion != currentVersion {
+		return errors.Errorf("--node-image-only keeps Kubernetes version %s, --upgrade-version %s can't be used with it", currentVersion, uc.upgradeVersion)
+	}
+	uc.upgradeVersion = currentVersion
+
+	uc.nameSuffix = uc.containerService.Properties.GetClusterID()
+
+	uc.agentPoolsToUpgrade = make(map[string]bool)
+	if len(uc.nodePools) > 0 {
+		for _, name := range uc.nodePools {
+			if uc.containerService.Properties.GetAgentPoolIndexByName(name) == -1 {
+				return errors.Errorf("--node-pool %s is not an agent pool of the cluster", name)
+			}
+			uc.agentPoolsToUpgrade[name] = true
+		}
+	} else {
+		for _, agentPool := range uc.containerService.Properties.AgentPoolProfiles {
+			if agentPool.IsWindows() {
+				uc.agentPoolsToUpgrade[agentPool.Name] = true
+			}
+		}
+		if len(uc.agentPoolsToUpgrade) == 0 {
+			return errors.New("--node-image-only upgrades the Windows agent pools unless --node-pool is specified, but the cluster has none")
+		}
+	}
+
+	pools := make([]string, 0, len(uc.agentPoolsToUpgrade))
+	for name := range uc.agentPoolsToUpgrade {
+		pools = append(pools, name)
+	}
+	sort.Strings(pools)
+	log.Infoln(fmt.Sprintf("Upgrading the node image of agent pools %s of cluster with name suffix: %s", strings.Join(pools, ", "), uc.nameSuffix))
+	return nil
+}
+
+// validateTargetAddons fails if enabled addons of the cluster do not support the upgrade version, unless the upgrade
+// is forced. It warns about the enabled addons which are not enabled by default anymore at the upgrade version.
+func (uc *upgradeCmd) validateTargetAddons() error {
+	if unsupported := uc.containerService.GetAddonsUnsupportedByKubernetesVersion(uc.upgradeVersion); len(unsupported) > 0 {
+		if !uc.force {
+			return errors.Errorf("the following enabled addons do not support Kubernetes version %s: %s", uc.upgradeVersion, strings.Join(unsupported, ", "))
+		}
+		log.Warnf("The following enabled addons do not support Kubernetes version %s and may not work after the upgrade: %s", uc.upgradeVersion, strings.Join(unsupported, ", "))
+	}
+	for _, name := range uc.containerService.GetAddonsNotEnabledByDefaultInKubernetesVersion(uc.upgradeVersion) {
+		log.Warnf("Addon %s is not enabled by default with Kubernetes version %s, it remains enabled after the upgrade", name, uc.upgradeVersion)
+	}
+	return nil
+}
+
+// checkComputeCapacity verifies that the subscription can create the extra VM of each agent pool during its upgrade.
+// A failed check is only a warning if the upgrade is forced.
+func (uc *upgradeCmd) checkComputeCapacity() error {
+	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+
+	var requirementSets [][]operations.VMRequirement
+	for _, requirements := range operations.GetUpgradeSurgeVMRequirements(uc.containerService.Properties) {
+		if !uc.nodeImageOnly || uc.agentPoolsToUpgrade[requirements[0].Pool] {
+			requirementSets = append(requirementSets, requirements)
+		}
+	}
+	err := operations.CheckComputeCapacity(ctx, uc.client, log.NewEntry(log.StandardLogger()), uc.location, requirementSets...)
+	if err != nil && uc.force {
+		log.Warnf("Upgrading the cluster despite the failed compute capacity check: %s", err)
+		return nil
+	}
+	return err
+}
+
+// resolveGalleryImages resolves the Shared Image Gallery images whose version is latest, so that the upgraded nodes are
+// created from the latest image versions, which the saved api model records.
+func (uc *upgradeCmd) resolveGalleryImages() error {
+	ctx, cancel := context.WithTimeout(context.Background(), armhelpers.DefaultARMOperationTimeout)
+	defer cancel()
+	return operations.ResolveGalleryImages(ctx, uc.client, uc.location, uc.containerService.Properties)
+}
+
 func (uc *upgradeCmd) run(cmd *cobra.Command, args []string) error {
 	err := uc.validate(cmd)
 	if err != nil {
@@ -242,6 +351,14 @@ func (uc *upgradeCmd) run(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "loading existing cluster")
 	}
 
+	if err = uc.checkComputeCapacity(); err != nil {
+		return errors.Wrap(err, "checking the compute capacity. Consider using --force if you really want to proceed")
+	}
+
+	if err = uc.resolveGalleryImages(); err != nil {
+		return errors.Wrap(err, "resolving the Shared Image Gallery images")
+	}
+
 	upgradeCluster := kubernetesupgrade.UpgradeCluster{
 		Translator: &i18n.Translator{
 			Locale: uc.locale,
@@ -259,6 +376,8 @@ func (uc *upgradeCmd) run(cmd *cobra.Command, args []string) error {
 	upgradeCluster.NameSuffix = uc.nameSuffix
 	upgradeCluster.AgentPoolsToUpgrade = uc.agentPoolsToUpgrade
 	upgradeCluster.Force = uc.force
+	upgradeCluster.NodeImageOnly = uc.nodeImageOnly
+	upgradeCluster.LinkedTemplatesStorage = uc.getLinkedTemplatesStorage(uc.resourceGroupName)
 
 	kubeConfig, err := engine.GenerateKubeConfig(uc.containerService.Properties, uc.location)
 	if err != nil {
@@ -0,0 +1,458 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/ghodss/yaml"
+	"github.com/leonelquinteros/gotext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	upgradeAddonsName             = "upgrade-addons"
+	upgradeAddonsShortDescription = "Upgrade the addons of an existing Kubernetes cluster"
+	upgradeAddonsLongDescription  = "Regenerate the addon manifests of a cluster built with AKS Engine from its apimodel, push the manifests which differ from the ones on the master nodes and wait for kube-addon-manager to roll out the addon workloads. Master nodes are not re-imaged."
+	addonsDirectory               = "/etc/kubernetes/addons"
+)
+
+var nodePlaceholderRegexp = regexp.MustCompile(`<[A-Za-z][A-Za-z0-9]*>`)
+
+type upgradeAddonsCmd struct {
+	authProvider
+
+	// user input
+	resourceGroupName string
+	location          string
+	apiModelPath      string
+	sshFilepath       string
+	masterFQDN        string
+	timeoutInMinutes  int
+	dryRun            bool
+
+	// derived
+	containerService   *api.ContainerService
+	apiVersion         string
+	locale             *gotext.Locale
+	client             armhelpers.AKSEngineClient
+	masterNodes        []v1.Node
+	sshConfig          *ssh.ClientConfig
+	sshCommandExecuter func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error)
+	pollInterval       time.Duration
+}
+
+// addonWorkload is a deployment or daemonset of an addon manifest, with the images of its containers
+type addonWorkload struct {
+	kind      string
+	namespace string
+	name      string
+	images    []string
+}
+
+func newUpgradeAddonsCmd() *cobra.Command {
+	uac := upgradeAddonsCmd{
+		authProvider:       &authArgs{},
+		sshCommandExecuter: executeCmd,
+		pollInterval:       10 * time.Second,
+	}
+
+	command := &cobra.Command{
+		Use:   upgradeAddonsName,
+		Short: upgradeAddonsShortDescription,
+		Long:  upgradeAddonsLongDescription,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := uac.validate(cmd); err != nil {
+				return errors.Wrap(err, "validating upgradeAddonsCmd")
+			}
+			return uac.run()
+		},
+	}
+
+	f := command.Flags()
+	f.StringVarP(&uac.location, "location", "l", "", "location the cluster is deployed in (required)")
+	f.StringVarP(&uac.resourceGroupName, "resource-group", "g", "", "the resource group where the cluster is deployed (required)")
+	f.StringVarP(&uac.apiModelPath, "api-model", "m", "", "path to the generated apimodel.json file (required)")
+	f.StringVarP(&uac.sshFilepath, "ssh", "", "", "the filepath of a valid private ssh key to access the cluster's nodes (required)")
+	f.StringVar(&uac.masterFQDN, "apiserver", "", "apiserver endpoint (required)")
+	f.IntVar(&uac.timeoutInMinutes, "timeout", 10, "how long to wait for the upgraded addon workloads to become ready in minutes")
+	f.BoolVar(&uac.dryRun, "dry-run", false, "only list the addon manifests which differ from the ones on the master nodes")
+
+	addAuthFlags(uac.getAuthArgs(), f)
+
+	return command
+}
+
+func (uac *upgradeAddonsCmd) validate(cmd *cobra.Command) error {
+	var err error
+
+	uac.locale, err = i18n.LoadTranslations()
+	if err != nil {
+		return errors.Wrap(err, "error loading translation files")
+	}
+
+	if uac.resourceGroupName == "" {
+		cmd.Usage()
+		return errors.New("--resource-group must be specified")
+	}
+
+	if uac.location == "" {
+		cmd.Usage()
+		return errors.New("--location must be specified")
+	}
+	uac.location = helpers.NormalizeAzureRegion(uac.location)
+
+	if uac.apiModelPath == "" {
+		cmd.Usage()
+		return errors.New("--api-model must be specified")
+	}
+
+	if uac.sshFilepath == "" {
+		cmd.Usage()
+		return errors.New("--ssh must be specified")
+	}
+
+	if uac.masterFQDN == "" {
+		cmd.Usage()
+		return errors.New("--apiserver must be specified")
+	}
+
+	return nil
+}
+
+func (uac *upgradeAddonsCmd) run() error {
+	var err error
+
+	if err = uac.getAuthArgs().validateAuthArgs(); err != nil {
+		return errors.Wrap(err, "failed to get validate auth args")
+	}
+
+	if uac.client, err = uac.authProvider.getClient(); err != nil {
+		return errors.Wrap(err, "failed to get client")
+	}
+
+	if err = uac.loadCluster(); err != nil {
+		return err
+	}
+
+	manifests, err := engine.GetAddonManifests(uac.containerService)
+	if err != nil {
+		return errors.Wrap(err, "generating addon manifests")
+	}
+
+	if err = uac.getMasterNodes(); err != nil {
+		return errors.Wrap(err, "listing master nodes")
+	}
+
+	if _, err = os.Stat(uac.sshFilepath); os.IsNotExist(err) {
+		return errors.Errorf("specified ssh filepath does not exist (%s)", uac.sshFilepath)
+	}
+	uac.setSSHConfig()
+
+	upgraded := map[string]engine.AddonManifest{}
+	for _, host := range uac.masterNodes {
+		changed, err := uac.syncManifests(host.Name, manifests)
+		if err != nil {
+			return errors.Wrapf(err, "upgrading addons on node %s", host.Name)
+		}
+		for _, m := range changed {
+			upgraded[m.DestinationFile] = m
+		}
+	}
+
+	if len(upgraded) == 0 {
+		log.Infoln("Addons are up to date")
+		return nil
+	}
+	if uac.dryRun {
+		return nil
+	}
+
+	var workloads []addonWorkload
+	for _, m := range upgraded {
+		w, err := getAddonWorkloads(m.Content)
+		if err != nil {
+			return errors.Wrapf(err, "parsing %s", m.DestinationFile)
+		}
+		workloads = append(workloads, w...)
+	}
+	log.Infoln("Waiting for kube-addon-manager to roll out the upgraded addons")
+	if err = uac.waitForWorkloads(workloads); err != nil {
+		return errors.Wrap(err, "waiting for addon workloads")
+	}
+
+	log.Infoln("Successfully upgraded addons")
+	return nil
+}
+
+func (uac *upgradeAddonsCmd) loadCluster() error {
+	var err error
+
+	if _, err = os.Stat(uac.apiModelPath); os.IsNotExist(err) {
+		return errors.Errorf("specified api model does not exist (%s)", uac.apiModelPath)
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: uac.locale,
+		},
+	}
+	uac.containerService, uac.apiVersion, err = apiloader.LoadContainerServiceFromFile(uac.apiModelPath, true, true, nil)
+	if err != nil {
+		return errors.Wrap(err, "parsing the api model")
+	}
+
+	if !uac.containerService.Properties.OrchestratorProfile.IsKubernetes() {
+		return errors.New("upgrade-addons is only supported for Kubernetes clusters")
+	}
+
+	// the apimodel is the source of truth for the addons, so the images it specifies are not reset to the defaults
+	if _, err = uac.containerService.SetPropertiesDefaults(false, false); err != nil {
+		return errors.Wrapf(err, "in SetPropertiesDefaults template %s", uac.apiModelPath)
+	}
+
+	if err = uac.containerService.ValidateAddons(); err != nil {
+		return errors.Wrap(err, "validating addons")
+	}
+	return nil
+}
+
+func (uac *upgradeAddonsCmd) getMasterNodes() error {
+	kubeClient, err := uac.getKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get Kubernetes Client")
+	}
+	nodeList, err := kubeClient.ListNodes()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster nodes")
+	}
+	for _, node := range nodeList.Items {
+		if strings.Contains(node.Name, "master") {
+			uac.masterNodes = append(uac.masterNodes, node)
+		}
+	}
+	return nil
+}
+
+func (uac *upgradeAddonsCmd) getKubeClient() (armhelpers.KubernetesClient, error) {
+	kubeconfig, err := engine.GenerateKubeConfig(uac.containerService.Properties, uac.location)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating kubeconfig")
+	}
+	kubeClient, err := uac.client.GetKubernetesClient("", kubeconfig, time.Second*1, time.Duration(60)*time.Minute)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get a Kubernetes client")
+	}
+	return kubeClient, nil
+}
+
+// syncManifests compares the addon manifests with the ones on a master node, and writes the ones which differ
+// unless it is a dry run. It returns the manifests which differ.
+func (uac *upgradeAddonsCmd) syncManifests(hostname string, manifests []engine.AddonManifest) ([]engine.AddonManifest, error) {
+	var changed []engine.AddonManifest
+	for _, m := range manifests {
+		target := fmt.Sprintf("%s/%s", addonsDirectory, m.DestinationFile)
+		out, err := uac.sshCommandExecuter(fmt.Sprintf("sudo cat %s 2>/dev/null || true", target), uac.masterFQDN, hostname, "22", uac.sshConfig)
+		if err != nil {
+			log.Printf("Command `sudo cat %s` output: %s\n", target, out)
+			return nil, errors.Wrapf(err, "reading %s", target)
+		}
+		current := strings.TrimPrefix(out, hostname+" -> ")
+
+		content, err := resolveNodePlaceholders(m.Content, current)
+		if err != nil {
+			log.Warnf("Skipping %s on node %s: %s", m.DestinationFile, hostname, err)
+			continue
+		}
+		if content == current {
+			continue
+		}
+		log.Infof("Addon manifest %s differs on node %s", m.DestinationFile, hostname)
+		changed = append(changed, engine.AddonManifest{DestinationFile: m.DestinationFile, Content: content})
+		if uac.dryRun {
+			continue
+		}
+
+		cmd := fmt.Sprintf("echo '%s' | base64 -d | sudo tee %s > /dev/null", base64.StdEncoding.EncodeToString([]byte(content)), target)
+		if out, err = uac.sshCommandExecuter(cmd, uac.masterFQDN, hostname, "22", uac.sshConfig); err != nil {
+			log.Printf("Command to write %s output: %s\n", target, out)
+			return nil, errors.Wrapf(err, "writing %s", target)
+		}
+	}
+	return changed, nil
+}
+
+// resolveNodePlaceholders replaces the placeholders left in a generated manifest, which the provisioning scripts
+// substitute from the environment of the node, with their values in the manifest currently on the node. Each line
+// holding a placeholder is matched against the lines of the current manifest.
+func resolveNodePlaceholders(generated, current string) (string, error) {
+	if !nodePlaceholderRegexp.MatchString(generated) {
+		return generated, nil
+	}
+	currentLines := strings.Split(current, "\n")
+	lines := strings.Split(generated, "\n")
+	for i, line := range lines {
+		placeholders := nodePlaceholderRegexp.FindAllString(line, -1)
+		if len(placeholders) == 0 {
+			continue
+		}
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		for j, segment := range nodePlaceholderRegexp.Split(line, -1) {
+			if j > 0 {
+				pattern.WriteString("(.*)")
+			}
+			pattern.WriteString(regexp.QuoteMeta(segment))
+		}
+		pattern.WriteString("$")
+		lineRegexp := regexp.MustCompile(pattern.String())
+		resolved := false
+		for _, currentLine := range currentLines {
+			if values := lineRegexp.FindStringSubmatch(currentLine); values != nil {
+				r := line
+				for j, placeholder := range placeholders {
+					r = strings.Replace(r, placeholder, values[j+1], 1)
+				}
+				lines[i] = r
+				resolved = true
+				break
+			}
+		}
+		if !resolved {
+			return "", errors.Errorf("the value of %s is set on the node and cannot be found in its current manifest", strings.Join(placeholders, ", "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// getAddonWorkloads returns the deployments and daemonsets of an addon manifest
+func getAddonWorkloads(manifest string) ([]addonWorkload, error) {
+	var workloads []addonWorkload
+	for _, doc := range strings.Split("\n"+manifest, "\n---") {
+		var resource struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Image string `json:"image"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &resource); err != nil {
+			return nil, err
+		}
+		if resource.Kind != "Deployment" && resource.Kind != "DaemonSet" {
+			continue
+		}
+		w := addonWorkload{
+			kind:      resource.Kind,
+			namespace: resource.Metadata.Namespace,
+			name:      resource.Metadata.Name,
+		}
+		if w.namespace == "" {
+			w.namespace = metav1.NamespaceDefault
+		}
+		for _, c := range resource.Spec.Template.Spec.Containers {
+			w.images = append(w.images, c.Image)
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+// waitForWorkloads waits until kube-addon-manager has applied the upgraded workloads, which then run the images of
+// the manifests, and their rollout is complete
+func (uac *upgradeAddonsCmd) waitForWorkloads(workloads []addonWorkload) error {
+	kubeClient, err := uac.getKubeClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get Kubernetes Client")
+	}
+	deadline := time.Now().Add(time.Duration(uac.timeoutInMinutes) * time.Minute)
+	for _, w := range workloads {
+		for {
+			ready, err := isAddonWorkloadReady(kubeClient, w)
+			if err != nil {
+				log.Debugf("Getting %s %s/%s: %s", w.kind, w.namespace, w.name, err)
+			}
+			if ready {
+				log.Infof("%s %s/%s is ready", w.kind, w.namespace, w.name)
+				break
+			}
+			if time.Now().After(deadline) {
+				return errors.Errorf("%s %s/%s is not ready after %d minutes", w.kind, w.namespace, w.name, uac.timeoutInMinutes)
+			}
+			time.Sleep(uac.pollInterval)
+		}
+	}
+	return nil
+}
+
+func isAddonWorkloadReady(kubeClient armhelpers.KubernetesClient, w addonWorkload) (bool, error) {
+	var podSpec v1.PodSpec
+	switch w.kind {
+	case "Deployment":
+		d, err := kubeClient.GetDeployment(w.namespace, w.name)
+		if err != nil {
+			return false, err
+		}
+		var replicas int32 = 1
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		if d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedReplicas != replicas || d.Status.AvailableReplicas != replicas {
+			return false, nil
+		}
+		podSpec = d.Spec.Template.Spec
+	case "DaemonSet":
+		ds, err := kubeClient.GetDaemonSet(w.namespace, w.name)
+		if err != nil {
+			return false, err
+		}
+		if ds.Status.ObservedGeneration < ds.Generation || ds.Status.UpdatedNumberScheduled != ds.Status.DesiredNumberScheduled || ds.Status.NumberAvailable != ds.Status.DesiredNumberScheduled {
+			return false, nil
+		}
+		podSpec = ds.Spec.Template.Spec
+	}
+	if len(podSpec.Containers) != len(w.images) {
+		return false, nil
+	}
+	for i, c := range podSpec.Containers {
+		if c.Image != w.images[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (uac *upgradeAddonsCmd) setSSHConfig() {
+	uac.sshConfig = &ssh.ClientConfig{
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            uac.containerService.Properties.LinuxProfile.AdminUsername,
+		Auth: []ssh.AuthMethod{
+			publicKeyFile(uac.sshFilepath),
+		},
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewUpgradeAddonsCmd(t *testing.T) {
+	output := newUpgradeAddonsCmd()
+	if output.Use != upgradeAddonsName || output.Short != upgradeAddonsShortDescription || output.Long != upgradeAddonsLongDescription {
+		t.Fatalf("upgrade-addons command should have use %s equal %s, short %s equal %s and long %s equal to %s", output.Use, upgradeAddonsName, output.Short, upgradeAddonsShortDescription, output.Long, upgradeAddonsLongDescription)
+	}
+
+	expectedFlags := []string{"location", "resource-group", "api-model", "ssh", "apiserver", "timeout", "dry-run"}
+	for _, f := range expectedFlags {
+		if output.Flags().Lookup(f) == nil {
+			t.Fatalf("upgrade-addons command should have flag %s", f)
+		}
+	}
+}
+
+func TestUpgradeAddonsCmdShouldBeValidated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &cobra.Command{}
+
+	cases := []struct {
+		uac         *upgradeAddonsCmd
+		expectedErr error
+	}{
+		{
+			uac:         &upgradeAddonsCmd{location: "westus", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.westus.cloudapp.azure.com"},
+			expectedErr: errors.New("--resource-group must be specified"),
+		},
+		{
+			uac:         &upgradeAddonsCmd{resourceGroupName: "test", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.westus.cloudapp.azure.com"},
+			expectedErr: errors.New("--location must be specified"),
+		},
+		{
+			uac:         &upgradeAddonsCmd{resourceGroupName: "test", location: "westus", sshFilepath: "./not/used", masterFQDN: "example.westus.cloudapp.azure.com"},
+			expectedErr: errors.New("--api-model must be specified"),
+		},
+		{
+			uac:         &upgradeAddonsCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", masterFQDN: "example.westus.cloudapp.azure.com"},
+			expectedErr: errors.New("--ssh must be specified"),
+		},
+		{
+			uac:         &upgradeAddonsCmd{resourceGroupName: "test", location: "westus", apiModelPath: "./not/used", sshFilepath: "./not/used"},
+			expectedErr: errors.New("--apiserver must be specified"),
+		},
+		{
+			uac:         &upgradeAddonsCmd{resourceGroupName: "test", location: "West US", apiModelPath: "./not/used", sshFilepath: "./not/used", masterFQDN: "example.westus.cloudapp.azure.com"},
+			expectedErr: nil,
+		},
+	}
+
+	for _, c := range cases {
+		err := c.uac.validate(r)
+		if c.expectedErr != nil {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(Equal(c.expectedErr.Error()))
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(c.uac.location).To(Equal("westus"))
+		}
+	}
+}
+
+func TestResolveNodePlaceholders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	generated := "image: example.azurecr.io/cluster-autoscaler:v1.15.1\nClientID: <clientID>\nResourceGroup: <rg>\n"
+	current := "image: example.azurecr.io/cluster-autoscaler:v1.14.4\nClientID: Y2xpZW50\nResourceGroup: cmc=\n"
+	resolved, err := resolveNodePlaceholders(generated, current)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved).To(Equal("image: example.azurecr.io/cluster-autoscaler:v1.15.1\nClientID: Y2xpZW50\nResourceGroup: cmc=\n"))
+
+	_, err = resolveNodePlaceholders(generated, "")
+	g.Expect(err).To(HaveOccurred())
+
+	resolved, err = resolveNodePlaceholders("image: example.azurecr.io/metrics-server:v0.3.4\n", "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved).To(Equal("image: example.azurecr.io/metrics-server:v0.3.4\n"))
+}
+
+func TestUpgradeAddonsSyncManifests(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	files := map[string]string{
+		"/etc/kubernetes/addons/coredns.yaml":                        "image: coredns:1.5.0\n",
+		"/etc/kubernetes/addons/kube-metrics-server-deployment.yaml": "image: metrics-server:v0.3.3\n",
+		"/etc/kubernetes/addons/aci-connector-deployment.yaml":       "cert: <changed>\nimage: aci:1.0\n",
+	}
+	catRegexp := regexp.MustCompile(`^sudo cat (\S+) `)
+	writeRegexp := regexp.MustCompile(`^echo '(\S+)' \| base64 -d \| sudo tee (\S+) > /dev/null$`)
+	var writes []string
+	uac := &upgradeAddonsCmd{
+		masterFQDN: "example.westus.cloudapp.azure.com",
+		sshCommandExecuter: func(command, masterFQDN, hostname string, port string, config *ssh.ClientConfig) (string, error) {
+			if m := catRegexp.FindStringSubmatch(command); m != nil {
+				return hostname + " -> " + files[m[1]], nil
+			}
+			if m := writeRegexp.FindStringSubmatch(command); m != nil {
+				b, err := base64.StdEncoding.DecodeString(m[1])
+				if err != nil {
+					return "", err
+				}
+				files[m[2]] = string(b)
+				writes = append(writes, m[2])
+				return hostname + " -> ", nil
+			}
+			return "", errors.Errorf("unexpected command %s", command)
+		},
+	}
+	manifests := []engine.AddonManifest{
+		{DestinationFile: "coredns.yaml", Content: "image: coredns:1.5.0\n"},
+		{DestinationFile: "kube-metrics-server-deployment.yaml", Content: "image: metrics-server:v0.3.4\n"},
+		{DestinationFile: "aci-connector-deployment.yaml", Content: "cert: <cert>\nimage: aci:1.1\n"},
+		{DestinationFile: "new-addon.yaml", Content: "image: new:1.0\n"},
+	}
+
+	uac.dryRun = true
+	changed, err := uac.syncManifests("k8s-master-12345678-0", manifests)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(HaveLen(3))
+	g.Expect(writes).To(BeEmpty())
+
+	uac.dryRun = false
+	changed, err = uac.syncManifests("k8s-master-12345678-0", manifests)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(HaveLen(3))
+	g.Expect(writes).To(ConsistOf(
+		"/etc/kubernetes/addons/kube-metrics-server-deployment.yaml",
+		"/etc/kubernetes/addons/aci-connector-deployment.yaml",
+		"/etc/kubernetes/addons/new-addon.yaml",
+	))
+	g.Expect(files["/etc/kubernetes/addons/kube-metrics-server-deployment.yaml"]).To(Equal("image: metrics-server:v0.3.4\n"))
+	g.Expect(files["/etc/kubernetes/addons/aci-connector-deployment.yaml"]).To(Equal("cert: <changed>\nimage: aci:1.1\n"))
+
+	changed, err = uac.syncManifests("k8s-master-12345678-0", manifests[:2])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(BeEmpty())
+
+	uac.masterFQDN = "unreachable"
+	uac.sshCommandExecuter = mockExecuteCmd
+	_, err = uac.syncManifests("k8s-master-12345678-0", manifests)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetAddonWorkloads(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	manifest := strings.Join([]string{
+		"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: metrics-server\n  namespace: kube-system",
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: metrics-server\n  namespace: kube-system\nspec:\n  template:\n    spec:\n      containers:\n      - name: metrics-server\n        image: metrics-server:v0.3.4",
+		"apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: agent\nspec:\n  template:\n    spec:\n      containers:\n      - name: agent\n        image: agent:1.0\n      - name: sidecar\n        image: sidecar:1.0",
+	}, "\n---\n")
+	workloads, err := getAddonWorkloads(manifest)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(workloads).To(Equal([]addonWorkload{
+		{kind: "Deployment", namespace: "kube-system", name: "metrics-server", images: []string{"metrics-server:v0.3.4"}},
+		{kind: "DaemonSet", namespace: "default", name: "agent", images: []string{"agent:1.0", "sidecar:1.0"}},
+	}))
+}
+
+func TestIsAddonWorkloadReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	kubeClient := &armhelpers.MockKubernetesClient{}
+	ready, err := isAddonWorkloadReady(kubeClient, addonWorkload{kind: "Deployment", namespace: "kube-system", name: "metrics-server"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+
+	ready, err = isAddonWorkloadReady(kubeClient, addonWorkload{kind: "DaemonSet", namespace: "kube-system", name: "agent"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, err = isAddonWorkloadReady(kubeClient, addonWorkload{kind: "DaemonSet", namespace: "kube-system", name: "agent", images: []string{"agent:1.0"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+
+	kubeClient.FailGetDaemonSetCount = 1
+	_, err = isAddonWorkloadReady(kubeClient, addonWorkload{kind: "DaemonSet", namespace: "kube-system", name: "agent"})
+	g.Expect(err).To(HaveOccurred())
+}
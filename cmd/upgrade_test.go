@@ -11,6 +11,8 @@ import (
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/aks-engine/pkg/armhelpers"
 
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -105,6 +107,35 @@ func TestUpgradeCommandShouldBeValidated(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			uc: &upgradeCmd{
+				resourceGroupName: "test",
+				apiModelPath:      "./not/used",
+				location:          "southcentralus",
+				nodeImageOnly:     true,
+			},
+			expectedErr: nil,
+		},
+		{
+			uc: &upgradeCmd{
+				resourceGroupName: "test",
+				apiModelPath:      "./not/used",
+				location:          "southcentralus",
+				upgradeVersion:    "1.9.0",
+				nodePools:         []string{"agentpool1"},
+			},
+			expectedErr: errors.New("--node-pool can only be used with --node-image-only"),
+		},
+		{
+			uc: &upgradeCmd{
+				resourceGroupName: "test",
+				apiModelPath:      "./not/used",
+				location:          "southcentralus",
+				nodeImageOnly:     true,
+				nodePools:         []string{"agentpool1"},
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, c := range cases {
@@ -129,6 +160,9 @@ func TestCreateUpgradeCommand(t *testing.T) {
 	g.Expect(command.Flags().Lookup("resource-group")).NotTo(BeNil())
 	g.Expect(command.Flags().Lookup("api-model")).NotTo(BeNil())
 	g.Expect(command.Flags().Lookup("upgrade-version")).NotTo(BeNil())
+	g.Expect(command.Flags().Lookup("linked-templates-storage-account")).NotTo(BeNil())
+	g.Expect(command.Flags().Lookup("proxy")).NotTo(BeNil())
+	g.Expect(command.Flags().Lookup("proxy-ssh-key")).NotTo(BeNil())
 
 	command.SetArgs([]string{})
 	if err := command.Execute(); err == nil {
@@ -211,6 +245,133 @@ func TestUpgradeShouldSuceedForValidUpgradePath(t *testing.T) {
 	resetValidVersions()
 }
 
+func TestUpgradeShouldFailForUnsupportedAddon(t *testing.T) {
+	setupValidVersions(map[string]bool{
+		"1.13.3": true,
+		"1.12.8": true,
+	})
+	defer resetValidVersions()
+	defer api.ResetAddonRegistry()
+	g := NewGomegaWithT(t)
+	err := api.RegisterAddon(&api.FileAddon{
+		AddonName:         "log-shipper",
+		SupportedVersions: "<1.13.0",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	newUpgradeCmd := func(force bool) *upgradeCmd {
+		uc := &upgradeCmd{
+			resourceGroupName:           "rg",
+			apiModelPath:                "./not/used",
+			upgradeVersion:              "1.13.3",
+			location:                    "centralus",
+			timeoutInMinutes:            60,
+			cordonDrainTimeoutInMinutes: 60,
+			force:                       force,
+			client:                      &armhelpers.MockAKSEngineClient{},
+		}
+		uc.containerService = api.CreateMockContainerService("testcluster", "1.12.8", 3, 2, false)
+		uc.containerService.Location = "centralus"
+		uc.containerService.Properties.OrchestratorProfile.KubernetesConfig.Addons = []api.KubernetesAddon{
+			{
+				Name:    "log-shipper",
+				Enabled: to.BoolPtr(true),
+			},
+		}
+		return uc
+	}
+
+	err = newUpgradeCmd(false).initialize()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("the following enabled addons do not support Kubernetes version 1.13.3: log-shipper"))
+
+	err = newUpgradeCmd(true).initialize()
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestUpgradeComputeCapacityCheck(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newUpgradeCmd := func(force bool) *upgradeCmd {
+		client := &armhelpers.MockAKSEngineClient{}
+		client.FakeListResourceSkusResult = func() []compute.ResourceSku {
+			return []compute.ResourceSku{
+				{
+					ResourceType: to.StringPtr("virtualMachines"),
+					Name:         to.StringPtr("Standard_D2_v2"),
+					Family:       to.StringPtr("standardDv2Family"),
+					Locations:    &[]string{"centralus"},
+					Capabilities: &[]compute.ResourceSkuCapabilities{{Name: to.StringPtr("vCPUs"), Value: to.StringPtr("2")}},
+				},
+			}
+		}
+		client.FakeListComputeUsagesResult = func() []compute.Usage {
+			return []compute.Usage{
+				{
+					Name:         &compute.UsageName{Value: to.StringPtr("standardDv2Family")},
+					CurrentValue: to.Int32Ptr(10),
+					Limit:        to.Int64Ptr(10),
+				},
+			}
+		}
+		uc := &upgradeCmd{
+			location: "centralus",
+			force:    force,
+			client:   client,
+		}
+		uc.containerService = api.CreateMockContainerService("testcluster", "1.12.8", 3, 2, false)
+		return uc
+	}
+
+	err := newUpgradeCmd(false).checkComputeCapacity()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("standardDv2Family: 2 required, 0 available out of a quota of 10"))
+
+	err = newUpgradeCmd(true).checkComputeCapacity()
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestUpgradeResolveGalleryImages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &armhelpers.MockAKSEngineClient{}
+	client.FakeListGalleryImageVersionsResult = func(image string) []compute.GalleryImageVersion {
+		return []compute.GalleryImageVersion{
+			{
+				Name: to.StringPtr("2019.10.2"),
+				GalleryImageVersionProperties: &compute.GalleryImageVersionProperties{
+					ProvisioningState: compute.ProvisioningState2Succeeded,
+					PublishingProfile: &compute.GalleryImageVersionPublishingProfile{
+						TargetRegions: &[]compute.TargetRegion{{Name: to.StringPtr("Central US")}},
+					},
+				},
+			},
+		}
+	}
+	uc := &upgradeCmd{
+		location: "centralus",
+		client:   client,
+	}
+	uc.containerService = api.CreateMockContainerService("testcluster", "1.15.3", 3, 2, false)
+	uc.containerService.Properties.AgentPoolProfiles[0].ImageRef = &api.ImageReference{
+		Name:           "ubuntu",
+		ResourceGroup:  "images",
+		SubscriptionID: "00000000-0000-0000-0000-000000000000",
+		Gallery:        "gallery",
+		Version:        "latest",
+	}
+
+	err := uc.resolveGalleryImages()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(uc.containerService.Properties.AgentPoolProfiles[0].ImageRef.Version).To(Equal("2019.10.2"))
+
+	client.FailGetGalleryImage = true
+	uc.containerService.Properties.AgentPoolProfiles[0].ImageRef.Version = "latest"
+	err = uc.resolveGalleryImages()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("getting image ubuntu of gallery gallery: GetGalleryImage failed"))
+}
+
 func TestUpgradeFailWithPathWhenAzureDeployJsonIsInvalid(t *testing.T) {
 	g := NewGomegaWithT(t)
 	upgradeCmd := &upgradeCmd{
@@ -282,3 +443,64 @@ func TestUpgradeForceDowngradeShouldSetVersionOnContainerService(t *testing.T) {
 	g.Expect(upgradeCmd.containerService.Properties.OrchestratorProfile.OrchestratorVersion).To(Equal("1.10.12"))
 	resetValidVersions()
 }
+
+func TestUpgradeNodeImageOnly(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newUpgradeCmd := func(upgradeVersion string) *upgradeCmd {
+		uc := &upgradeCmd{
+			resourceGroupName: "rg",
+			apiModelPath:      "./not/used",
+			upgradeVersion:    upgradeVersion,
+			location:          "centralus",
+			nodeImageOnly:     true,
+			client:            &armhelpers.MockAKSEngineClient{},
+		}
+		uc.containerService = api.CreateMockContainerService("testcluster", "1.15.3", 3, 2, false)
+		uc.containerService.Location = "centralus"
+		winPool := *uc.containerService.Properties.AgentPoolProfiles[0]
+		winPool.Name = "winpool"
+		winPool.OSType = api.Windows
+		uc.containerService.Properties.AgentPoolProfiles = append(uc.containerService.Properties.AgentPoolProfiles, &winPool)
+		return uc
+	}
+
+	uc := newUpgradeCmd("")
+	err := uc.initialize()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(uc.upgradeVersion).To(Equal("1.15.3"))
+	g.Expect(uc.containerService.Properties.OrchestratorProfile.OrchestratorVersion).To(Equal("1.15.3"))
+	g.Expect(uc.agentPoolsToUpgrade).To(Equal(map[string]bool{"winpool": true}))
+
+	err = newUpgradeCmd("1.15.3").initialize()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = newUpgradeCmd("1.16.0").initialize()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("--node-image-only keeps Kubernetes version 1.15.3, --upgrade-version 1.16.0 can't be used with it"))
+
+	uc = newUpgradeCmd("")
+	uc.containerService.Properties.AgentPoolProfiles = uc.containerService.Properties.AgentPoolProfiles[:1]
+	err = uc.initialize()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("--node-image-only upgrades the Windows agent pools unless --node-pool is specified, but the cluster has none"))
+
+	uc = newUpgradeCmd("")
+	uc.nodePools = []string{"agentpool1"}
+	err = uc.initialize()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(uc.upgradeVersion).To(Equal("1.15.3"))
+	g.Expect(uc.agentPoolsToUpgrade).To(Equal(map[string]bool{"agentpool1": true}))
+
+	uc = newUpgradeCmd("")
+	uc.nodePools = []string{"agentpool1", "winpool"}
+	err = uc.initialize()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(uc.agentPoolsToUpgrade).To(Equal(map[string]bool{"agentpool1": true, "winpool": true}))
+
+	uc = newUpgradeCmd("")
+	uc.nodePools = []string{"missingpool"}
+	err = uc.initialize()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(Equal("--node-pool missingpool is not an agent pool of the cluster"))
+}
@@ -7,6 +7,12 @@ CNI_BIN_DIR="/opt/cni/bin"
 CNI_DOWNLOADS_DIR="/opt/cni/downloads"
 CONTAINERD_DOWNLOADS_DIR="/opt/containerd/downloads"
 UBUNTU_RELEASE=$(lsb_release -r -s)
+# the package mirror serves the Ubuntu archive under /ubuntu and packages.microsoft.com under /microsoft
+if [[ -n "${PACKAGE_MIRROR_URL:-}" ]]; then
+    MICROSOFT_PACKAGES_URL="${PACKAGE_MIRROR_URL%/}/microsoft"
+else
+    MICROSOFT_PACKAGES_URL="https://packages.microsoft.com"
+fi
 
 removeEtcd() {
     if [[ $OS == $COREOS_OS_NAME ]]; then
@@ -35,9 +41,21 @@ installEtcd() {
     fi
 }
 
+configurePackageMirror() {
+    if [[ -z "${PACKAGE_MIRROR_URL:-}" ]]; then
+        return
+    fi
+    sed -i -E "s#https?://([a-z0-9.-]+\.)?(archive|security)\.ubuntu\.com/ubuntu/?#${PACKAGE_MIRROR_URL%/}/ubuntu/#g" /etc/apt/sources.list || exit $ERR_APT_MIRROR_CONFIG_FAIL
+    for apt_list in /etc/apt/sources.list.d/*.list; do
+        [[ -f $apt_list ]] || continue
+        sed -i "s#https://packages.microsoft.com#${MICROSOFT_PACKAGES_URL}#g" $apt_list || exit $ERR_APT_MIRROR_CONFIG_FAIL
+    done
+}
+
 installDeps() {
-    retrycmd_if_failure_no_stats 120 5 25 curl -fsSL https://packages.microsoft.com/config/ubuntu/${UBUNTU_RELEASE}/packages-microsoft-prod.deb > /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_DOWNLOAD_TIMEOUT
+    retrycmd_if_failure_no_stats 120 5 25 curl -fsSL ${MICROSOFT_PACKAGES_URL}/config/ubuntu/${UBUNTU_RELEASE}/packages-microsoft-prod.deb > /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_DOWNLOAD_TIMEOUT
     retrycmd_if_failure 60 5 10 dpkg -i /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_PKG_ADD_FAIL
+    configurePackageMirror
     aptmarkWALinuxAgent hold
     apt_get_update || exit $ERR_APT_UPDATE_TIMEOUT
     apt_get_dist_upgrade || exit $ERR_APT_DIST_UPGRADE_TIMEOUT
@@ -114,9 +132,10 @@ installMoby() {
         echo "dockerd $MOBY_VERSION is already installed, skipping Moby download"
     else
         removeMoby
-        retrycmd_if_failure_no_stats 120 5 25 curl https://packages.microsoft.com/config/ubuntu/${UBUNTU_RELEASE}/prod.list > /tmp/microsoft-prod.list || exit $ERR_MOBY_APT_LIST_TIMEOUT
+        retrycmd_if_failure_no_stats 120 5 25 curl ${MICROSOFT_PACKAGES_URL}/config/ubuntu/${UBUNTU_RELEASE}/prod.list > /tmp/microsoft-prod.list || exit $ERR_MOBY_APT_LIST_TIMEOUT
         retrycmd_if_failure 10 5 10 cp /tmp/microsoft-prod.list /etc/apt/sources.list.d/ || exit $ERR_MOBY_APT_LIST_TIMEOUT
-        retrycmd_if_failure_no_stats 120 5 25 curl https://packages.microsoft.com/keys/microsoft.asc | gpg --dearmor > /tmp/microsoft.gpg || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
+        configurePackageMirror
+        retrycmd_if_failure_no_stats 120 5 25 curl ${MICROSOFT_PACKAGES_URL}/keys/microsoft.asc | gpg --dearmor > /tmp/microsoft.gpg || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
         retrycmd_if_failure 10 5 10 cp /tmp/microsoft.gpg /etc/apt/trusted.gpg.d/ || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
         apt_get_update || exit $ERR_APT_UPDATE_TIMEOUT
         MOBY_CLI=${MOBY_VERSION}
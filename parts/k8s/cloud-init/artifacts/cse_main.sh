@@ -64,6 +64,10 @@ else
     FULL_INSTALL_REQUIRED=true
 fi
 
+if [[ $OS == $UBUNTU_OS_NAME ]]; then
+    configurePackageMirror
+fi
+
 if [[ $OS == $UBUNTU_OS_NAME ]] && [ "$FULL_INSTALL_REQUIRED" = "true" ]; then
     installDeps
 else
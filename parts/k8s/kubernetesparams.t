@@ -210,6 +210,13 @@
       },
       "type": "string"
     },
+    "packageMirrorURL": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The apt mirror of the Ubuntu archive and of packages.microsoft.com used by the nodes."
+      },
+      "type": "string"
+    },
     "kubernetesCcmImageSpec": {
       "defaultValue": "",
       "metadata": {
@@ -68,9 +68,15 @@ $global:KubeBinariesPackageSASURL = "{{WrapAsParameter "kubeBinariesSASURL"}}"
 $global:WindowsKubeBinariesURL = "{{WrapAsParameter "windowsKubeBinariesURL"}}"
 $global:KubeBinariesVersion = "{{WrapAsParameter "kubeBinariesVersion"}}"
 
+## Container runtime, docker or containerd
+$global:ContainerRuntime = "{{WrapAsParameter "containerRuntime"}}"
+
 ## Docker Version
 $global:DockerVersion = "{{WrapAsParameter "windowsDockerVersion"}}"
 
+## Containerd package
+$global:ContainerdURL = "{{WrapAsParameter "windowsContainerdURL"}}"
+
 ## VM configuration passed by Azure
 $global:WindowsTelemetryGUID = "{{WrapAsParameter "windowsTelemetryGUID"}}"
 {{if eq GetIdentitySystem "adfs"}}
@@ -100,7 +106,11 @@ $global:KubeletNodeLabels = "{{GetAgentKubernetesLabelsDeprecated . "',variables
 $global:KubeletConfigArgs = @( {{GetKubeletConfigKeyValsPsh .KubernetesConfig }} )
 
 $global:UseManagedIdentityExtension = "{{WrapAsVariable "useManagedIdentityExtension"}}"
+{{if UserAssignedIDEnabled}}
+$global:UserAssignedClientID = "{{WrapAsVerbatim "reference(concat('Microsoft.ManagedIdentity/userAssignedIdentities/', variables('userAssignedID')), '2018-11-30').clientId"}}"
+{{else}}
 $global:UserAssignedClientID = "{{WrapAsVariable "userAssignedClientID"}}"
+{{end}}
 $global:UseInstanceMetadata = "{{WrapAsVariable "useInstanceMetadata"}}"
 
 $global:LoadBalancerSku = "{{WrapAsVariable "loadBalancerSku"}}"
@@ -142,13 +152,14 @@ Expand-Archive scripts.zip -DestinationPath "C:\\AzureData\\"
 . c:\AzureData\k8s\windowscnifunc.ps1
 . c:\AzureData\k8s\windowsazurecnifunc.ps1
 . c:\AzureData\k8s\windowsinstallopensshfunc.ps1
+. c:\AzureData\k8s\windowscontainerdfunc.ps1
 
 function
 Update-ServiceFailureActions()
 {
     sc.exe failure "kubelet" actions= restart/60000/restart/60000/restart/60000 reset= 900
     sc.exe failure "kubeproxy" actions= restart/60000/restart/60000/restart/60000 reset= 900
-    sc.exe failure "docker" actions= restart/60000/restart/60000/restart/60000 reset= 900
+    sc.exe failure "$global:ContainerRuntime" actions= restart/60000/restart/60000/restart/60000 reset= 900
 }
 
 try
@@ -172,8 +183,21 @@ try
         Write-Log "Create required data directories as needed"
         Initialize-DataDirectories
 
-        Write-Log "Install docker"
-        Install-Docker -DockerVersion $global:DockerVersion
+        if ($global:ContainerRuntime -eq "containerd") {
+            Write-Log "Install containerd"
+            if ($global:NetworkPlugin -eq "azure") {
+                Install-Containerd -ContainerdUrl $global:ContainerdURL `
+                                   -CNIBinDir $global:AzureCNIBinDir `
+                                   -CNIConfDir $global:AzureCNIConfDir
+            } else {
+                Install-Containerd -ContainerdUrl $global:ContainerdURL `
+                                   -CNIBinDir $global:CNIPath `
+                                   -CNIConfDir $global:CNIConfigPath
+            }
+        } else {
+            Write-Log "Install docker"
+            Install-Docker -DockerVersion $global:DockerVersion
+        }
 
         Write-Log "Download kubelet binaries and unzip"
         Get-KubePackage -KubeBinariesSASURL $global:KubeBinariesPackageSASURL
@@ -230,8 +254,11 @@ try
                          -AgentCertificate $global:AgentCertificate
 
 
-        Write-Log "Create the Pause Container kubletwin/pause"
-        New-InfraContainer -KubeDir $global:KubeDir
+        # containerd pulls the sandbox image of its config
+        if ($global:ContainerRuntime -ne "containerd") {
+            Write-Log "Create the Pause Container kubletwin/pause"
+            New-InfraContainer -KubeDir $global:KubeDir
+        }
 
         Write-Log "Configuring networking with NetworkPlugin:$global:NetworkPlugin"
 
@@ -283,7 +310,8 @@ try
             -KubeClusterCIDR $global:KubeClusterCIDR `
             -KubeServiceCIDR $global:KubeServiceCIDR `
             -HNSModule $global:HNSModule `
-            -KubeletNodeLabels $global:KubeletNodeLabels
+            -KubeletNodeLabels $global:KubeletNodeLabels `
+            -ContainerRuntime $global:ContainerRuntime
 
         # Install OpenSSH if SSH enabled
         $sshEnabled = [System.Convert]::ToBoolean("{{ WindowsSSHEnabled }}")
@@ -0,0 +1,87 @@
+$global:ContainerdInstallLocation = "$Env:ProgramFiles\containerd"
+$global:ContainerdPipe = "npipe:////./pipe/containerd-containerd"
+
+function
+Install-Containerd {
+    Param(
+        [Parameter(Mandatory = $true)][string]
+        $ContainerdUrl,
+        [Parameter(Mandatory = $true)][string]
+        $CNIBinDir,
+        [Parameter(Mandatory = $true)][string]
+        $CNIConfDir,
+        [string]
+        $PauseImage = "mcr.microsoft.com/k8s/core/pause:1.2.0"
+    )
+
+    $svc = Get-Service -Name containerd -ErrorAction SilentlyContinue
+    if ($null -ne $svc) {
+        Write-Log "Stopping containerd service"
+        $svc | Stop-Service
+    }
+
+    # The zip contains containerd.exe, containerd-shim-runhcs-v1.exe and ctr.exe, with crictl.exe in bin\
+    $tempdir = New-TemporaryDirectory
+    $zipfile = [Io.path]::Combine($tempdir, "containerd.zip")
+    for ($i = 0; $i -le 10; $i++) {
+        DownloadFileOverHttp -Url $ContainerdUrl -DestinationPath $zipfile
+        if ($?) {
+            break
+        }
+        else {
+            Write-Log $Error[0].Exception.Message
+        }
+    }
+    Expand-Archive -path $zipfile -DestinationPath $global:ContainerdInstallLocation -Force
+    del $tempdir -Recurse
+
+    Add-SystemPathEntry $global:ContainerdInstallLocation
+    Add-SystemPathEntry ([Io.path]::Combine($global:ContainerdInstallLocation, "bin"))
+
+    $configFile = [Io.path]::Combine($global:ContainerdInstallLocation, "config.toml")
+    Write-Log "Writing containerd config to $configFile"
+    $config = @"
+version = 2
+root = "C:\\ProgramData\\containerd\\root"
+state = "C:\\ProgramData\\containerd\\state"
+
+[grpc]
+  address = "\\\\.\\pipe\\containerd-containerd"
+
+[plugins]
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "$PauseImage"
+    [plugins."io.containerd.grpc.v1.cri".containerd]
+      snapshotter = "windows"
+      default_runtime_name = "runhcs-wcow-process"
+      [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runhcs-wcow-process]
+        runtime_type = "io.containerd.runhcs.v1"
+    [plugins."io.containerd.grpc.v1.cri".cni]
+      bin_dir = "$($CNIBinDir.Replace("\", "\\"))"
+      conf_dir = "$($CNIConfDir.Replace("\", "\\"))"
+"@
+    $config | Out-File -encoding ASCII -filepath $configFile
+
+    $containerdExe = [Io.path]::Combine($global:ContainerdInstallLocation, "containerd.exe")
+    if ($null -eq $svc) {
+        Write-Log "Registering containerd as a service"
+        & $containerdExe --register-service --config $configFile
+    }
+    Write-Log "Starting containerd service"
+    Start-Service containerd
+}
+
+function
+Add-SystemPathEntry {
+    Param(
+        [Parameter(Mandatory = $true)][string]
+        $Directory
+    )
+    $path = [System.Environment]::GetEnvironmentVariable("Path", [System.EnvironmentVariableTarget]::Machine)
+    if (-not ($path -split ";" -contains $Directory)) {
+        [System.Environment]::SetEnvironmentVariable("Path", "$path;$Directory", [System.EnvironmentVariableTarget]::Machine)
+    }
+    if (-not ($env:Path -split ";" -contains $Directory)) {
+        $env:Path += ";$Directory"
+    }
+}
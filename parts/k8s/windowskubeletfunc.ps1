@@ -67,7 +67,7 @@ Write-AzureConfig {
     "primaryAvailabilitySetName": "$PrimaryAvailabilitySetName",
     "primaryScaleSetName": "$PrimaryScaleSetName",
     "useManagedIdentityExtension": $UseManagedIdentityExtension,
-    "userAssignedIdentityID": $UserAssignedClientID,
+    "userAssignedIdentityID": "$UserAssignedClientID",
     "useInstanceMetadata": $UseInstanceMetadata,
     "loadBalancerSku": "$LoadBalancerSku",
     "excludeMasterFromStandardLB": $ExcludeMasterFromStandardLB
@@ -238,7 +238,9 @@ New-NSSMService {
         $KubeletStartFile,
         [string]
         [Parameter(Mandatory = $true)]
-        $KubeProxyStartFile
+        $KubeProxyStartFile,
+        [string]
+        $ContainerRuntime = "docker"
     )
 
     # setup kubelet
@@ -247,7 +249,7 @@ New-NSSMService {
     & "$KubeDir\nssm.exe" set Kubelet AppParameters $KubeletStartFile
     & "$KubeDir\nssm.exe" set Kubelet DisplayName Kubelet
     & "$KubeDir\nssm.exe" set Kubelet AppRestartDelay 5000
-    & "$KubeDir\nssm.exe" set Kubelet DependOnService docker
+    & "$KubeDir\nssm.exe" set Kubelet DependOnService $ContainerRuntime
     & "$KubeDir\nssm.exe" set Kubelet Description Kubelet
     & "$KubeDir\nssm.exe" set Kubelet Start SERVICE_AUTO_START
     & "$KubeDir\nssm.exe" set Kubelet ObjectName LocalSystem
@@ -318,7 +320,9 @@ Install-KubernetesServices {
         [Parameter(Mandatory = $true)][string]
         $HNSModule,
         [Parameter(Mandatory = $true)][string]
-        $KubeletNodeLabels
+        $KubeletNodeLabels,
+        [string]
+        $ContainerRuntime = "docker"
     )
 
     # Calculate some local paths
@@ -335,6 +339,14 @@ Install-KubernetesServices {
     # Only args that need to be calculated or combined with other ones on the Windows agent should be added here.
 
 
+    if ($ContainerRuntime -eq "containerd") {
+        $KubeletArgList += @("--container-runtime=remote", "--container-runtime-endpoint=$global:ContainerdPipe", "--runtime-request-timeout=15m")
+        $RemoveContainersCommand = "crictl.exe --runtime-endpoint $global:ContainerdPipe ps -q | foreach {crictl.exe --runtime-endpoint $global:ContainerdPipe rm -f `$_}"
+    }
+    else {
+        $RemoveContainersCommand = "docker ps -q | foreach {docker rm `$_ -f}"
+    }
+
     # Regex to strip version to Major.Minor.Build format such that the following check does not crash for version like x.y.z-alpha
     [regex]$regex = "^[0-9.]+"
     $KubeBinariesVersionStripped = $regex.Matches($KubeBinariesVersion).Value
@@ -417,7 +429,7 @@ if (!`$hnsNetwork)
 if (`$hnsNetwork)
 {
     # Cleanup all containers
-    docker ps -q | foreach {docker rm `$_ -f}
+    $RemoveContainersCommand
 
     Write-Host "Cleaning up old HNS network found"
     Remove-HnsNetwork `$hnsNetwork
@@ -563,7 +575,7 @@ try
     {
         # Kubelet has been restarted with existing network.
         # Cleanup all containers
-        docker ps -q | foreach {docker rm `$_ -f}
+        $RemoveContainersCommand
         # cleanup network
         Write-Host "Cleaning up old HNS network found"
         Remove-HnsNetwork `$hnsNetwork
@@ -619,5 +631,6 @@ $KubeDir\kube-proxy.exe --v=3 --proxy-mode=kernelspace --hostname-override=$env:
 
     New-NSSMService -KubeDir $KubeDir `
         -KubeletStartFile $KubeletStartFile `
-        -KubeProxyStartFile $KubeProxyStartFile
+        -KubeProxyStartFile $KubeProxyStartFile `
+        -ContainerRuntime $ContainerRuntime
 }
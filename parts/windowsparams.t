@@ -29,6 +29,27 @@
       },
       "type": "string"
     },
+    "windowsContainerdURL": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The download url for the containerd package of the Windows nodes, when containerRuntime is containerd"
+      },
+      "type": "string"
+    },
+    "agentWindowsImageName": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The name of the managed image or Shared Image Gallery image definition of the Windows agent virtual machines."
+      },
+      "type": "string"
+    },
+    "agentWindowsImageResourceGroup": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The resource group of the managed image or Shared Image Gallery of the Windows agent virtual machines."
+      },
+      "type": "string"
+    },
  {{end}}
     "windowsAdminUsername": {
       "type": "string",
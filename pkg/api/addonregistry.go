@@ -0,0 +1,376 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/Azure/aks-engine/pkg/api/common"
+)
+
+const (
+	// AddonDescriptorFile is the name of the file describing an addon in a directory loaded by LoadAddonsFromDirectory
+	AddonDescriptorFile = "addon.json"
+	// AddonManifestFile is the name of the manifest template of an addon in a directory loaded by LoadAddonsFromDirectory
+	AddonManifestFile = "manifest.yaml"
+)
+
+// AddonDescriptor describes a Kubernetes addon that aks-engine can deliver to the master nodes
+type AddonDescriptor interface {
+	// Name is the name of the addon in kubernetesConfig.addons
+	Name() string
+	// Defaults returns the default containers and config of the addon for the cluster, and whether it is enabled by default
+	Defaults(cs *ContainerService) KubernetesAddon
+	// KubernetesVersions is the semver range of the Kubernetes versions the addon supports, e.g. ">=1.12.0 <1.16.0".
+	// All versions are supported if it is empty.
+	KubernetesVersions() string
+	// Manifest locates the manifest template of the addon, the addon has no manifest if it is the zero value
+	Manifest() AddonManifest
+	// Validate returns an error if the enabled addon cannot be delivered to the cluster
+	Validate(addon KubernetesAddon, cs *ContainerService) error
+}
+
+// AddonManifest locates the manifest template of an addon
+type AddonManifest struct {
+	// SourceFile is the name of a template embedded in aks-engine, under parts/k8s/containeraddons
+	SourceFile string
+	// Template is the text of the template, it takes precedence over SourceFile
+	Template string
+	// DestinationFile is the name of the file the manifest is written to under /etc/kubernetes/addons
+	DestinationFile string
+}
+
+var (
+	addonRegistryLock sync.RWMutex
+	addonRegistry     = newBuiltinAddonRegistry()
+)
+
+func newBuiltinAddonRegistry() []AddonDescriptor {
+	descriptors := make([]AddonDescriptor, len(builtinAddons))
+	for i := range builtinAddons {
+		descriptors[i] = builtinAddons[i]
+	}
+	return descriptors
+}
+
+// RegisterAddon adds an addon to the registry, after the addons already registered. It is an error to register two
+// addons with the same name.
+func RegisterAddon(descriptor AddonDescriptor) error {
+	if descriptor.Name() == "" {
+		return errors.New("addons must have a name")
+	}
+	if r := descriptor.KubernetesVersions(); r != "" {
+		if _, err := semver.ParseRange(r); err != nil {
+			return errors.Wrapf(err, "parsing the Kubernetes versions of addon %s", descriptor.Name())
+		}
+	}
+	addonRegistryLock.Lock()
+	defer addonRegistryLock.Unlock()
+	for _, d := range addonRegistry {
+		if d.Name() == descriptor.Name() {
+			return errors.Errorf("addon %s is already registered", descriptor.Name())
+		}
+	}
+	addonRegistry = append(addonRegistry, descriptor)
+	return nil
+}
+
+// GetAddonDescriptor returns the registered addon of the given name, nil if there is none
+func GetAddonDescriptor(name string) AddonDescriptor {
+	addonRegistryLock.RLock()
+	defer addonRegistryLock.RUnlock()
+	for _, d := range addonRegistry {
+		if d.Name() == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// GetAddonDescriptors returns the registered addons, in the order they were registered
+func GetAddonDescriptors() []AddonDescriptor {
+	addonRegistryLock.RLock()
+	defer addonRegistryLock.RUnlock()
+	descriptors := make([]AddonDescriptor, len(addonRegistry))
+	copy(descriptors, addonRegistry)
+	return descriptors
+}
+
+// ResetAddonRegistry removes the addons registered after the built-in addons
+func ResetAddonRegistry() {
+	addonRegistryLock.Lock()
+	defer addonRegistryLock.Unlock()
+	addonRegistry = newBuiltinAddonRegistry()
+}
+
+// RegisterAddonDescriptors registers the third-party addons persisted in the api model which are not registered yet,
+// so that the commands which regenerate the template deliver the addons registered when it was generated. An addon
+// registered from an --addons-dir takes precedence over the one persisted in the api model.
+func (cs *ContainerService) RegisterAddonDescriptors() error {
+	if cs.Properties == nil || cs.Properties.OrchestratorProfile == nil || cs.Properties.OrchestratorProfile.KubernetesConfig == nil {
+		return nil
+	}
+	for _, d := range cs.Properties.OrchestratorProfile.KubernetesConfig.AddonDescriptors {
+		if GetAddonDescriptor(d.AddonName) != nil {
+			continue
+		}
+		descriptor := d
+		if err := RegisterAddon(&descriptor); err != nil {
+			return errors.Wrapf(err, "registering the addon %s of the api model", d.AddonName)
+		}
+	}
+	return nil
+}
+
+// persistAddonDescriptors writes the registered third-party addons to the api model, so that the api model written to
+// the output directory registers them again
+func (k *KubernetesConfig) persistAddonDescriptors() {
+	k.AddonDescriptors = nil
+	for _, descriptor := range GetAddonDescriptors() {
+		if f, ok := descriptor.(*FileAddon); ok {
+			k.AddonDescriptors = append(k.AddonDescriptors, *f)
+		}
+	}
+}
+
+// IsAddonSupportedByKubernetesVersion returns true if the Kubernetes version is in the range of versions supported by the addon
+func IsAddonSupportedByKubernetesVersion(descriptor AddonDescriptor, version string) bool {
+	return common.IsKubernetesVersionInRange(version, descriptor.KubernetesVersions())
+}
+
+// GetAddonsUnsupportedByKubernetesVersion returns the names of the enabled addons of the cluster which are registered
+// and do not support the Kubernetes version
+func (cs *ContainerService) GetAddonsUnsupportedByKubernetesVersion(version string) []string {
+	var names []string
+	o := cs.Properties.OrchestratorProfile
+	if o == nil || o.KubernetesConfig == nil {
+		return names
+	}
+	for _, addon := range o.KubernetesConfig.Addons {
+		descriptor := GetAddonDescriptor(addon.Name)
+		if descriptor != nil && addon.IsEnabled() && !IsAddonSupportedByKubernetesVersion(descriptor, version) {
+			names = append(names, addon.Name)
+		}
+	}
+	return names
+}
+
+// GetAddonsNotEnabledByDefaultInKubernetesVersion returns the names of the enabled addons of the cluster which are
+// enabled by default at the current Kubernetes version of the cluster, but not at the given version
+func (cs *ContainerService) GetAddonsNotEnabledByDefaultInKubernetesVersion(version string) []string {
+	var names []string
+	o := cs.Properties.OrchestratorProfile
+	if o == nil || o.KubernetesConfig == nil {
+		return names
+	}
+	// the defaults are derived from a shallow copy of the cluster at the other version
+	target := *cs
+	properties := *cs.Properties
+	targetOrchestratorProfile := *o
+	targetOrchestratorProfile.OrchestratorVersion = version
+	properties.OrchestratorProfile = &targetOrchestratorProfile
+	target.Properties = &properties
+	for _, addon := range o.KubernetesConfig.Addons {
+		descriptor := GetAddonDescriptor(addon.Name)
+		if descriptor == nil || !addon.IsEnabled() {
+			continue
+		}
+		current, next := descriptor.Defaults(cs), descriptor.Defaults(&target)
+		if current.IsEnabled() && !next.IsEnabled() {
+			names = append(names, addon.Name)
+		}
+	}
+	return names
+}
+
+// GetAddonsSupportedByKubernetesVersion returns the names of the registered addons which support the Kubernetes version
+func GetAddonsSupportedByKubernetesVersion(version string) []string {
+	var names []string
+	for _, descriptor := range GetAddonDescriptors() {
+		if IsAddonSupportedByKubernetesVersion(descriptor, version) {
+			names = append(names, descriptor.Name())
+		}
+	}
+	return names
+}
+
+// ValidateAddons validates the enabled addons of the cluster which are registered, against the Kubernetes versions
+// they support and their validation hooks. Addons without a descriptor, such as chart addons, are not validated. It
+// is called once the addon defaults are set, so that addons enabled by default are validated too.
+func (cs *ContainerService) ValidateAddons() error {
+	o := cs.Properties.OrchestratorProfile
+	if o == nil || o.KubernetesConfig == nil {
+		return nil
+	}
+	for _, addon := range o.KubernetesConfig.Addons {
+		descriptor := GetAddonDescriptor(addon.Name)
+		if descriptor != nil && addon.Chart != nil {
+			return errors.Errorf("addon %s has its own manifest and cannot reference a chart", addon.Name)
+		}
+		if descriptor == nil || !addon.IsEnabled() {
+			continue
+		}
+		if o.OrchestratorVersion != "" && !IsAddonSupportedByKubernetesVersion(descriptor, o.OrchestratorVersion) {
+			return errors.Errorf("addon %s supports Kubernetes versions %s, the cluster version is %s", addon.Name, descriptor.KubernetesVersions(), o.OrchestratorVersion)
+		}
+		if err := descriptor.Validate(addon, cs); err != nil {
+			return errors.Wrapf(err, "validating addon %s", addon.Name)
+		}
+	}
+	return nil
+}
+
+// builtinAddon is an addon delivered with aks-engine, whose manifest is embedded in aks-engine. Built-in addons are
+// validated by the versioned api models.
+type builtinAddon struct {
+	name     string
+	defaults func(c addonDefaultsContext) KubernetesAddon
+	manifest AddonManifest
+}
+
+func (b builtinAddon) Name() string {
+	return b.name
+}
+
+func (b builtinAddon) Defaults(cs *ContainerService) KubernetesAddon {
+	o := cs.Properties.OrchestratorProfile
+	return b.defaults(addonDefaultsContext{
+		cs:            cs,
+		o:             o,
+		specConfig:    cs.GetCloudSpecConfig().KubernetesSpecConfig,
+		k8sComponents: K8sComponentsByVersionMap[o.OrchestratorVersion],
+	})
+}
+
+func (b builtinAddon) KubernetesVersions() string {
+	return common.BuiltinAddonKubernetesVersions[b.name].Range()
+}
+
+func (b builtinAddon) Manifest() AddonManifest {
+	return b.manifest
+}
+
+func (b builtinAddon) Validate(addon KubernetesAddon, cs *ContainerService) error {
+	return nil
+}
+
+// FileAddon is an addon described by an addon.json file, see LoadAddonsFromDirectory
+type FileAddon struct {
+	AddonName string `json:"name"`
+	// Enabled is whether the addon is enabled by default
+	Enabled           bool                      `json:"enabled,omitempty"`
+	SupportedVersions string                    `json:"kubernetesVersions,omitempty"`
+	Containers        []KubernetesContainerSpec `json:"containers,omitempty"`
+	Config            map[string]string         `json:"config,omitempty"`
+	// RequiredConfig are the keys of Config which must have a value for the addon to be enabled
+	RequiredConfig []string `json:"requiredConfig,omitempty"`
+	// DestinationFile is the name of the manifest on the master nodes, <name>.yaml by default
+	DestinationFile string `json:"destinationFile,omitempty"`
+	// ManifestTemplate is the text of the manifest.yaml template
+	ManifestTemplate string `json:"-"`
+}
+
+// Name returns the name of the addon
+func (f *FileAddon) Name() string {
+	return f.AddonName
+}
+
+// Defaults returns the containers and config of the addon.json file
+func (f *FileAddon) Defaults(cs *ContainerService) KubernetesAddon {
+	addon := KubernetesAddon{
+		Name:       f.AddonName,
+		Enabled:    to.BoolPtr(f.Enabled && (cs.Properties.OrchestratorProfile == nil || IsAddonSupportedByKubernetesVersion(f, cs.Properties.OrchestratorProfile.OrchestratorVersion))),
+		Containers: make([]KubernetesContainerSpec, len(f.Containers)),
+		Config:     make(map[string]string, len(f.Config)),
+	}
+	copy(addon.Containers, f.Containers)
+	for k, v := range f.Config {
+		addon.Config[k] = v
+	}
+	return addon
+}
+
+// KubernetesVersions returns the Kubernetes versions supported by the addon
+func (f *FileAddon) KubernetesVersions() string {
+	return f.SupportedVersions
+}
+
+// Manifest returns the manifest.yaml template of the addon
+func (f *FileAddon) Manifest() AddonManifest {
+	destinationFile := f.DestinationFile
+	if destinationFile == "" {
+		destinationFile = f.AddonName + ".yaml"
+	}
+	return AddonManifest{
+		Template:        f.ManifestTemplate,
+		DestinationFile: destinationFile,
+	}
+}
+
+// Validate checks that the required config of the addon has values
+func (f *FileAddon) Validate(addon KubernetesAddon, cs *ContainerService) error {
+	if addon.Data != "" {
+		return nil
+	}
+	for _, key := range f.RequiredConfig {
+		if addon.Config[key] == "" {
+			return errors.Errorf("config %s is required", key)
+		}
+	}
+	return nil
+}
+
+// LoadAddonFromDirectory reads the addon.json descriptor and manifest.yaml template of an addon from a directory
+func LoadAddonFromDirectory(dir string) (*FileAddon, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, AddonDescriptorFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading addon descriptor")
+	}
+	addon := &FileAddon{}
+	if err = json.Unmarshal(b, addon); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", filepath.Join(dir, AddonDescriptorFile))
+	}
+	if addon.AddonName == "" {
+		addon.AddonName = filepath.Base(dir)
+	}
+	if b, err = ioutil.ReadFile(filepath.Join(dir, AddonManifestFile)); err != nil {
+		return nil, errors.Wrap(err, "reading addon manifest")
+	}
+	addon.ManifestTemplate = string(b)
+	return addon, nil
+}
+
+// LoadAddonsFromDirectory registers the addons of each subdirectory of dir, in lexical order. Each subdirectory holds
+// an addon.json descriptor and a manifest.yaml template.
+func LoadAddonsFromDirectory(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "reading addons directory")
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		addonDir := filepath.Join(dir, entry.Name())
+		if _, err = os.Stat(filepath.Join(addonDir, AddonDescriptorFile)); os.IsNotExist(err) {
+			continue
+		}
+		addon, err := LoadAddonFromDirectory(addonDir)
+		if err != nil {
+			return errors.Wrapf(err, "loading addon from %s", addonDir)
+		}
+		if err = RegisterAddon(addon); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"github.com/Azure/aks-engine/pkg/i18n"
+)
+
+func TestBuiltinAddonDescriptors(t *testing.T) {
+	expected := []string{
+		HeapsterAddonName,
+		TillerAddonName,
+		ACIConnectorAddonName,
+		ClusterAutoscalerAddonName,
+		BlobfuseFlexVolumeAddonName,
+		SMBFlexVolumeAddonName,
+		KeyVaultFlexVolumeAddonName,
+		DashboardAddonName,
+		ReschedulerAddonName,
+		MetricsServerAddonName,
+		NVIDIADevicePluginAddonName,
+		ContainerMonitoringAddonName,
+		IPMASQAgentAddonName,
+		AzureCNINetworkMonitoringAddonName,
+		AzureNetworkPolicyAddonName,
+		DNSAutoscalerAddonName,
+		CalicoAddonName,
+		AADPodIdentityAddonName,
+		AppGwIngressAddonName,
+		NodeTerminationHandlerAddonName,
+	}
+	descriptors := GetAddonDescriptors()
+	if len(descriptors) != len(expected) {
+		t.Fatalf("expected %d built-in addons, got %d", len(expected), len(descriptors))
+	}
+	for i, name := range expected {
+		if descriptors[i].Name() != name {
+			t.Errorf("expected addon %d to be %s, got %s", i, name, descriptors[i].Name())
+		}
+		if name != AppGwIngressAddonName && descriptors[i].Manifest().SourceFile == "" {
+			t.Errorf("expected addon %s to have an embedded manifest", name)
+		}
+	}
+}
+
+func TestRegisterAddon(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	addon := &FileAddon{AddonName: "my-addon", SupportedVersions: ">=1.13.0"}
+	if err := RegisterAddon(addon); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	if GetAddonDescriptor("my-addon") != addon {
+		t.Errorf("expected my-addon to be registered")
+	}
+	descriptors := GetAddonDescriptors()
+	if descriptors[len(descriptors)-1] != addon {
+		t.Errorf("expected my-addon to be registered after the built-in addons")
+	}
+
+	for _, invalid := range []*FileAddon{
+		{AddonName: "my-addon"},
+		{AddonName: TillerAddonName},
+		{},
+		{AddonName: "bad-range", SupportedVersions: "1.13"},
+	} {
+		if err := RegisterAddon(invalid); err == nil {
+			t.Errorf("expected error registering addon %+v", invalid)
+		}
+	}
+
+	ResetAddonRegistry()
+	if GetAddonDescriptor("my-addon") != nil {
+		t.Errorf("expected my-addon to be removed from the registry")
+	}
+	if GetAddonDescriptor(TillerAddonName) == nil {
+		t.Errorf("expected built-in addons to stay registered")
+	}
+}
+
+func TestLoadAddonsFromDirectory(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	dir, err := ioutil.TempDir("", "addons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeAddon := func(name, descriptor, manifest string) {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name, AddonDescriptorFile), []byte(descriptor), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name, AddonManifestFile), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeAddon("log-shipper", `{
+		"enabled": true,
+		"kubernetesVersions": ">=1.13.0",
+		"containers": [{"name": "shipper", "image": "example.azurecr.io/shipper:1.0", "cpuRequests": "50m"}],
+		"config": {"endpoint": ""},
+		"requiredConfig": ["endpoint"]
+	}`, `image: {{ContainerImage "shipper"}}`)
+	writeAddon("policy-agent", `{"name": "policy", "destinationFile": "policy-agent-daemonset.yaml"}`, "kind: DaemonSet")
+	if err = os.MkdirAll(filepath.Join(dir, "not-an-addon"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = LoadAddonsFromDirectory(dir); err != nil {
+		t.Fatalf("unexpected error loading addons: %s", err)
+	}
+
+	logShipper := GetAddonDescriptor("log-shipper")
+	if logShipper == nil {
+		t.Fatalf("expected log-shipper to be named after its directory")
+	}
+	if logShipper.KubernetesVersions() != ">=1.13.0" {
+		t.Errorf("expected log-shipper to support Kubernetes >=1.13.0, got %s", logShipper.KubernetesVersions())
+	}
+	if m := logShipper.Manifest(); m.Template != `image: {{ContainerImage "shipper"}}` || m.DestinationFile != "log-shipper.yaml" {
+		t.Errorf("unexpected log-shipper manifest %+v", m)
+	}
+	policy := GetAddonDescriptor("policy")
+	if policy == nil {
+		t.Fatalf("expected policy to be registered")
+	}
+	if policy.Manifest().DestinationFile != "policy-agent-daemonset.yaml" {
+		t.Errorf("expected policy manifest to be written to policy-agent-daemonset.yaml, got %s", policy.Manifest().DestinationFile)
+	}
+
+	cs := CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	defaults := logShipper.Defaults(cs)
+	if !defaults.IsEnabled() || defaults.Containers[0].Image != "example.azurecr.io/shipper:1.0" {
+		t.Errorf("unexpected log-shipper defaults %+v", defaults)
+	}
+	cs = CreateMockContainerService("testcluster", "1.12.8", 3, 2, false)
+	if to.Bool(logShipper.Defaults(cs).Enabled) {
+		t.Errorf("expected log-shipper to be disabled by default on Kubernetes 1.12")
+	}
+
+	if err = LoadAddonsFromDirectory(dir); err == nil {
+		t.Errorf("expected error loading the same addons twice")
+	}
+	if err = LoadAddonsFromDirectory(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected error loading addons from a missing directory")
+	}
+}
+
+func TestValidateAddons(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	if err := RegisterAddon(&FileAddon{AddonName: "log-shipper", SupportedVersions: ">=1.13.0", RequiredConfig: []string{"endpoint"}}); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+
+	cases := []struct {
+		name          string
+		version       string
+		addon         KubernetesAddon
+		expectedError bool
+	}{
+		{
+			name:    "enabled with required config",
+			version: "1.14.6",
+			addon:   KubernetesAddon{Name: "log-shipper", Enabled: to.BoolPtr(true), Config: map[string]string{"endpoint": "https://logs.example.com"}},
+		},
+		{
+			name:          "missing required config",
+			version:       "1.14.6",
+			addon:         KubernetesAddon{Name: "log-shipper", Enabled: to.BoolPtr(true), Config: map[string]string{"endpoint": ""}},
+			expectedError: true,
+		},
+		{
+			name:          "unsupported Kubernetes version",
+			version:       "1.12.8",
+			addon:         KubernetesAddon{Name: "log-shipper", Enabled: to.BoolPtr(true), Config: map[string]string{"endpoint": "https://logs.example.com"}},
+			expectedError: true,
+		},
+		{
+			name:    "disabled",
+			version: "1.12.8",
+			addon:   KubernetesAddon{Name: "log-shipper", Enabled: to.BoolPtr(false)},
+		},
+		{
+			name:          "registered addon with a chart",
+			version:       "1.14.6",
+			addon:         KubernetesAddon{Name: "log-shipper", Enabled: to.BoolPtr(true), Chart: &KubernetesAddonChart{Path: "charts/log-shipper"}},
+			expectedError: true,
+		},
+		{
+			name:    "not registered",
+			version: "1.14.6",
+			addon:   KubernetesAddon{Name: "unknown", Enabled: to.BoolPtr(true)},
+		},
+	}
+	for _, c := range cases {
+		cs := CreateMockContainerService("testcluster", c.version, 3, 2, false)
+		cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []KubernetesAddon{c.addon}
+		err := cs.ValidateAddons()
+		if c.expectedError && err == nil {
+			t.Errorf("%s: expected error validating addons", c.name)
+		}
+		if !c.expectedError && err != nil {
+			t.Errorf("%s: unexpected error validating addons: %s", c.name, err)
+		}
+	}
+}
+
+func TestSetAddonsConfigWithRegisteredAddon(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	if err := RegisterAddon(&FileAddon{AddonName: "log-shipper", Enabled: true, Config: map[string]string{"endpoint": "https://logs.example.com", "level": "info"}}); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	cs := CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []KubernetesAddon{
+		{Name: "log-shipper", Config: map[string]string{"level": "debug"}},
+	}
+	cs.setAddonsConfig(false)
+
+	addons := cs.Properties.OrchestratorProfile.KubernetesConfig.Addons
+	i := getAddonsIndexByName(addons, "log-shipper")
+	if i < 0 {
+		t.Fatalf("expected log-shipper to be in the addons")
+	}
+	if !addons[i].IsEnabled() || addons[i].Config["endpoint"] != "https://logs.example.com" || addons[i].Config["level"] != "debug" {
+		t.Errorf("expected log-shipper defaults to be merged with the user config, got %+v", addons[i])
+	}
+	if getAddonsIndexByName(addons, TillerAddonName) < 0 {
+		t.Errorf("expected built-in addons to be in the addons")
+	}
+}
+
+func TestPersistAddonDescriptors(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	logShipper := &FileAddon{
+		AddonName:         "log-shipper",
+		Enabled:           true,
+		SupportedVersions: ">=1.12.0",
+		Containers:        []KubernetesContainerSpec{{Name: "shipper", Image: "example.azurecr.io/shipper:1.0"}},
+		ManifestTemplate:  `image: {{ContainerImage "shipper"}}`,
+	}
+	if err := RegisterAddon(logShipper); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	cs := CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	cs.setAddonsConfig(false)
+	descriptors := cs.Properties.OrchestratorProfile.KubernetesConfig.AddonDescriptors
+	if len(descriptors) != 1 || descriptors[0].AddonName != "log-shipper" || descriptors[0].ManifestTemplate != logShipper.ManifestTemplate {
+		t.Fatalf("expected the registered addon to be persisted in the api model, got %+v", descriptors)
+	}
+
+	apiloader := &Apiloader{Translator: &i18n.Translator{}}
+	b, err := apiloader.SerializeContainerService(cs, "vlabs")
+	if err != nil {
+		t.Fatalf("unexpected error serializing the api model: %s", err)
+	}
+	ResetAddonRegistry()
+	if _, _, err = apiloader.DeserializeContainerService(b, false, true, nil); err != nil {
+		t.Fatalf("unexpected error loading the api model: %s", err)
+	}
+	registered := GetAddonDescriptor("log-shipper")
+	if registered == nil {
+		t.Fatalf("expected loading the api model to register the persisted addon")
+	}
+	if registered.KubernetesVersions() != ">=1.12.0" || registered.Manifest().Template != logShipper.ManifestTemplate {
+		t.Errorf("unexpected persisted addon %+v", registered)
+	}
+
+	// an addon registered from an --addons-dir takes precedence over the persisted one
+	ResetAddonRegistry()
+	updated := *logShipper
+	updated.ManifestTemplate = `image: {{ContainerImage "shipper"}} # updated`
+	if err = RegisterAddon(&updated); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	loaded, _, err := apiloader.DeserializeContainerService(b, false, true, nil)
+	if err != nil {
+		t.Fatalf("unexpected error loading the api model: %s", err)
+	}
+	if GetAddonDescriptor("log-shipper").Manifest().Template != updated.ManifestTemplate {
+		t.Errorf("expected the registered addon to take precedence over the persisted one")
+	}
+	loaded.setAddonsConfig(true)
+	if d := loaded.Properties.OrchestratorProfile.KubernetesConfig.AddonDescriptors; len(d) != 1 || d[0].ManifestTemplate != updated.ManifestTemplate {
+		t.Errorf("expected the registered addon to be persisted in the api model, got %+v", d)
+	}
+}
+
+func TestBuiltinAddonKubernetesVersions(t *testing.T) {
+	clusterAutoscaler := GetAddonDescriptor(ClusterAutoscalerAddonName)
+	if clusterAutoscaler.KubernetesVersions() != ">=1.9.0 <1.17.0" {
+		t.Errorf("expected cluster-autoscaler to support Kubernetes >=1.9.0 <1.17.0, got %s", clusterAutoscaler.KubernetesVersions())
+	}
+	if IsAddonSupportedByKubernetesVersion(clusterAutoscaler, "1.8.15") {
+		t.Errorf("expected cluster-autoscaler not to support Kubernetes 1.8.15")
+	}
+	if IsAddonSupportedByKubernetesVersion(clusterAutoscaler, "1.17.0") {
+		t.Errorf("expected cluster-autoscaler not to support Kubernetes 1.17.0, which has no cluster-autoscaler image")
+	}
+	if ipMasqAgent := GetAddonDescriptor(IPMASQAgentAddonName); ipMasqAgent.KubernetesVersions() != "" {
+		t.Errorf("expected ip-masq-agent to support all Kubernetes versions, got %s", ipMasqAgent.KubernetesVersions())
+	}
+	rescheduler := GetAddonDescriptor(ReschedulerAddonName)
+	if !IsAddonSupportedByKubernetesVersion(rescheduler, "1.15.4") || IsAddonSupportedByKubernetesVersion(rescheduler, "1.16.0-beta.1") {
+		t.Errorf("expected rescheduler to support Kubernetes 1.15.4 and not 1.16.0-beta.1")
+	}
+
+	for _, name := range GetAddonsSupportedByKubernetesVersion("1.8.15") {
+		if name == ClusterAutoscalerAddonName || name == NVIDIADevicePluginAddonName {
+			t.Errorf("expected %s not to be available with Kubernetes 1.8.15", name)
+		}
+	}
+	if len(GetAddonsSupportedByKubernetesVersion("1.14.6")) != len(GetAddonDescriptors()) {
+		t.Errorf("expected all the built-in addons to be available with Kubernetes 1.14.6")
+	}
+}
+
+func TestGetAddonsUnsupportedByKubernetesVersion(t *testing.T) {
+	defer ResetAddonRegistry()
+
+	if err := RegisterAddon(&FileAddon{AddonName: "log-shipper", SupportedVersions: "<1.13.0"}); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	cs := CreateMockContainerService("testcluster", "1.12.8", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []KubernetesAddon{
+		{Name: "log-shipper", Enabled: to.BoolPtr(true)},
+		{Name: HeapsterAddonName, Enabled: to.BoolPtr(true)},
+		{Name: "unknown", Enabled: to.BoolPtr(true)},
+	}
+
+	if unsupported := cs.GetAddonsUnsupportedByKubernetesVersion("1.12.8"); len(unsupported) != 0 {
+		t.Errorf("expected all the addons to support Kubernetes 1.12.8, got %v", unsupported)
+	}
+	unsupported := cs.GetAddonsUnsupportedByKubernetesVersion("1.13.5")
+	if len(unsupported) != 1 || unsupported[0] != "log-shipper" {
+		t.Errorf("expected log-shipper not to support Kubernetes 1.13.5, got %v", unsupported)
+	}
+
+	notEnabledByDefault := cs.GetAddonsNotEnabledByDefaultInKubernetesVersion("1.13.5")
+	if len(notEnabledByDefault) != 1 || notEnabledByDefault[0] != HeapsterAddonName {
+		t.Errorf("expected heapster not to be enabled by default with Kubernetes 1.13.5, got %v", notEnabledByDefault)
+	}
+	if cs.Properties.OrchestratorProfile.OrchestratorVersion != "1.12.8" {
+		t.Errorf("expected the cluster version to be unchanged, got %s", cs.Properties.OrchestratorProfile.OrchestratorVersion)
+	}
+
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons[0].Enabled = to.BoolPtr(false)
+	if unsupported = cs.GetAddonsUnsupportedByKubernetesVersion("1.13.5"); len(unsupported) != 0 {
+		t.Errorf("expected disabled addons to be ignored, got %v", unsupported)
+	}
+}
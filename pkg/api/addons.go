@@ -4,7 +4,9 @@
 package api
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Azure/go-autorest/autorest/to"
 
@@ -13,20 +15,227 @@ import (
 
 func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 	o := cs.Properties.OrchestratorProfile
-	clusterDNSPrefix := "aks-engine-cluster"
-	if cs != nil && cs.Properties != nil && cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.DNSPrefix != "" {
-		clusterDNSPrefix = cs.Properties.MasterProfile.DNSPrefix
+	o.KubernetesConfig.persistAddonDescriptors()
+	var defaultAddons []KubernetesAddon
+	for _, descriptor := range GetAddonDescriptors() {
+		defaultAddons = append(defaultAddons, descriptor.Defaults(cs))
+	}
+
+	// Add default addons specification, if no user-provided spec exists
+	if o.KubernetesConfig.Addons == nil {
+		o.KubernetesConfig.Addons = defaultAddons
+	} else {
+		for _, addon := range defaultAddons {
+			o.KubernetesConfig.Addons = appendAddonIfNotPresent(o.KubernetesConfig.Addons, addon)
+		}
+	}
+
+	for _, addon := range defaultAddons {
+		synthesizeAddonsConfig(o.KubernetesConfig.Addons, addon, isUpdate)
+	}
+
+	if len(o.KubernetesConfig.PodSecurityPolicyConfig) > 0 && isUpdate {
+		if base64Data, ok := o.KubernetesConfig.PodSecurityPolicyConfig["data"]; ok {
+			pspAddonsConfig := KubernetesAddon{
+				Name: PodSecurityPolicyAddonName,
+				Data: base64Data,
+			}
+			o.KubernetesConfig.Addons = appendAddonIfNotPresent(o.KubernetesConfig.Addons, pspAddonsConfig)
+		}
 	}
-	cloudSpecConfig := cs.GetCloudSpecConfig()
-	k8sComponents := K8sComponentsByVersionMap[o.OrchestratorVersion]
-	specConfig := cloudSpecConfig.KubernetesSpecConfig
-	defaultsHeapsterAddonsConfig := KubernetesAddon{
+
+	// Specific back-compat business logic for the network policy addons
+	// Ensure the addons follow NetworkPolicy on upgrade no matter what, so that the network policy engine can be switched
+	if isUpdate {
+		for _, name := range []string{CalicoAddonName, AzureNetworkPolicyAddonName} {
+			i := getAddonsIndexByName(o.KubernetesConfig.Addons, name)
+			j := getAddonsIndexByName(defaultAddons, name)
+			if i < 0 || j < 0 || to.Bool(o.KubernetesConfig.Addons[i].Enabled) == to.Bool(defaultAddons[j].Enabled) {
+				continue
+			}
+			o.KubernetesConfig.Addons[i].Enabled = defaultAddons[j].Enabled
+			// Assume addon configuration was pruned due to an inherited enabled=false, so re-apply default values
+			o.KubernetesConfig.Addons[i] = assignDefaultAddonVals(o.KubernetesConfig.Addons[i], defaultAddons[j], isUpdate)
+		}
+	}
+}
+
+// addonDefaultsContext holds the cluster settings the defaults of built-in addons are derived from
+type addonDefaultsContext struct {
+	cs            *ContainerService
+	o             *OrchestratorProfile
+	specConfig    KubernetesSpecConfig
+	k8sComponents map[string]string
+}
+
+// builtinAddons are the addons delivered with aks-engine, in the order they are added to the api model
+var builtinAddons = []builtinAddon{
+	{
+		name:     HeapsterAddonName,
+		defaults: defaultHeapsterAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-heapster-deployment.yaml",
+			DestinationFile: "kube-heapster-deployment.yaml",
+		},
+	},
+	{
+		name:     TillerAddonName,
+		defaults: defaultTillerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-tiller-deployment.yaml",
+			DestinationFile: "kube-tiller-deployment.yaml",
+		},
+	},
+	{
+		name:     ACIConnectorAddonName,
+		defaults: defaultACIConnectorAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-aci-connector-deployment.yaml",
+			DestinationFile: "aci-connector-deployment.yaml",
+		},
+	},
+	{
+		name:     ClusterAutoscalerAddonName,
+		defaults: defaultClusterAutoscalerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-cluster-autoscaler-deployment.yaml",
+			DestinationFile: "cluster-autoscaler-deployment.yaml",
+		},
+	},
+	{
+		name:     BlobfuseFlexVolumeAddonName,
+		defaults: defaultBlobfuseFlexVolumeAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-blobfuse-flexvolume-installer.yaml",
+			DestinationFile: "blobfuse-flexvolume-installer.yaml",
+		},
+	},
+	{
+		name:     SMBFlexVolumeAddonName,
+		defaults: defaultSMBFlexVolumeAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-smb-flexvolume-installer.yaml",
+			DestinationFile: "smb-flexvolume-installer.yaml",
+		},
+	},
+	{
+		name:     KeyVaultFlexVolumeAddonName,
+		defaults: defaultKeyVaultFlexVolumeAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-keyvault-flexvolume-installer.yaml",
+			DestinationFile: "keyvault-flexvolume-installer.yaml",
+		},
+	},
+	{
+		name:     DashboardAddonName,
+		defaults: defaultDashboardAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-kubernetes-dashboard-deployment.yaml",
+			DestinationFile: "kubernetes-dashboard-deployment.yaml",
+		},
+	},
+	{
+		name:     ReschedulerAddonName,
+		defaults: defaultReschedulerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-kube-rescheduler-deployment.yaml",
+			DestinationFile: "kube-rescheduler-deployment.yaml",
+		},
+	},
+	{
+		name:     MetricsServerAddonName,
+		defaults: defaultMetricsServerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-metrics-server-deployment.yaml",
+			DestinationFile: "kube-metrics-server-deployment.yaml",
+		},
+	},
+	{
+		name:     NVIDIADevicePluginAddonName,
+		defaults: defaultNVIDIADevicePluginAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-nvidia-device-plugin-daemonset.yaml",
+			DestinationFile: "nvidia-device-plugin.yaml",
+		},
+	},
+	{
+		name:     ContainerMonitoringAddonName,
+		defaults: defaultContainerMonitoringAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-omsagent-daemonset.yaml",
+			DestinationFile: "omsagent-daemonset.yaml",
+		},
+	},
+	{
+		name:     IPMASQAgentAddonName,
+		defaults: defaultIPMasqAgentAddon,
+		manifest: AddonManifest{
+			SourceFile:      "ip-masq-agent.yaml",
+			DestinationFile: "ip-masq-agent.yaml",
+		},
+	},
+	{
+		name:     AzureCNINetworkMonitoringAddonName,
+		defaults: defaultAzureCNINetworkMonitorAddon,
+		manifest: AddonManifest{
+			SourceFile:      "azure-cni-networkmonitor.yaml",
+			DestinationFile: "azure-cni-networkmonitor.yaml",
+		},
+	},
+	{
+		name:     AzureNetworkPolicyAddonName,
+		defaults: defaultAzureNetworkPolicyAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-azure-npm-daemonset.yaml",
+			DestinationFile: "azure-npm-daemonset.yaml",
+		},
+	},
+	{
+		name:     DNSAutoscalerAddonName,
+		defaults: defaultDNSAutoScalerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "dns-autoscaler.yaml",
+			DestinationFile: "dns-autoscaler.yaml",
+		},
+	},
+	{
+		name:     CalicoAddonName,
+		defaults: defaultCalicoDaemonSetAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-calico-daemonset.yaml",
+			DestinationFile: "calico-daemonset.yaml",
+		},
+	},
+	{
+		name:     AADPodIdentityAddonName,
+		defaults: defaultAADPodIdentityAddon,
+		manifest: AddonManifest{
+			SourceFile:      "kubernetesmasteraddons-aad-pod-identity-deployment.yaml",
+			DestinationFile: "aad-pod-identity-deployment.yaml",
+		},
+	},
+	{
+		name:     AppGwIngressAddonName,
+		defaults: defaultAppGwAddon,
+	},
+	{
+		name:     NodeTerminationHandlerAddonName,
+		defaults: defaultNodeTerminationHandlerAddon,
+		manifest: AddonManifest{
+			SourceFile:      "node-termination-handler.yaml",
+			DestinationFile: "node-termination-handler.yaml",
+		},
+	},
+}
+
+func defaultHeapsterAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    HeapsterAddonName,
-		Enabled: to.BoolPtr(DefaultHeapsterAddonEnabled && !common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.13.0")),
+		Enabled: to.BoolPtr(DefaultHeapsterAddonEnabled && !common.IsKubernetesVersionGe(c.o.OrchestratorVersion, "1.13.0")),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           HeapsterAddonName,
-				Image:          specConfig.KubernetesImageBase + k8sComponents["heapster"],
+				Image:          c.specConfig.KubernetesImageBase + c.k8sComponents["heapster"],
 				CPURequests:    "88m",
 				MemoryRequests: "204Mi",
 				CPULimits:      "88m",
@@ -34,7 +243,7 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 			{
 				Name:           "heapster-nanny",
-				Image:          specConfig.KubernetesImageBase + k8sComponents["addonresizer"],
+				Image:          c.specConfig.KubernetesImageBase + c.k8sComponents["addonresizer"],
 				CPURequests:    "88m",
 				MemoryRequests: "204Mi",
 				CPULimits:      "88m",
@@ -42,8 +251,10 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultTillerAddonsConfig := KubernetesAddon{
+func defaultTillerAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    TillerAddonName,
 		Enabled: to.BoolPtr(DefaultTillerAddonEnabled),
 		Containers: []KubernetesContainerSpec{
@@ -53,17 +264,19 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "150Mi",
 				CPULimits:      "50m",
 				MemoryLimits:   "150Mi",
-				Image:          specConfig.TillerImageBase + k8sComponents[TillerAddonName],
+				Image:          c.specConfig.TillerImageBase + c.k8sComponents[TillerAddonName],
 			},
 		},
 		Config: map[string]string{
 			"max-history": strconv.Itoa(DefaultTillerMaxHistory),
 		},
 	}
+}
 
-	defaultACIConnectorAddonsConfig := KubernetesAddon{
+func defaultACIConnectorAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    ACIConnectorAddonName,
-		Enabled: to.BoolPtr(DefaultACIConnectorAddonEnabled && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultACIConnectorAddonEnabled && !c.cs.Properties.IsAzureStackCloud()),
 		Config: map[string]string{
 			"region":   "westus",
 			"nodeName": "aci-connector",
@@ -77,18 +290,28 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "150Mi",
 				CPULimits:      "50m",
 				MemoryLimits:   "150Mi",
-				Image:          specConfig.ACIConnectorImageBase + k8sComponents[ACIConnectorAddonName],
+				Image:          c.specConfig.ACIConnectorImageBase + c.k8sComponents[ACIConnectorAddonName],
 			},
 		},
 	}
+}
 
-	defaultClusterAutoscalerAddonsConfig := KubernetesAddon{
+func defaultClusterAutoscalerAddon(c addonDefaultsContext) KubernetesAddon {
+	expander := "random"
+	fallbackNodeGroups, priorities := getClusterAutoscalerFallbackConfig(c.cs.Properties)
+	if priorities != "" {
+		expander = "priority"
+	}
+	return KubernetesAddon{
 		Name:    ClusterAutoscalerAddonName,
-		Enabled: to.BoolPtr(DefaultClusterAutoscalerAddonEnabled && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultClusterAutoscalerAddonEnabled && !c.cs.Properties.IsAzureStackCloud()),
 		Config: map[string]string{
-			"min-nodes":     "1",
-			"max-nodes":     "5",
-			"scan-interval": "10s",
+			"min-nodes":            "1",
+			"max-nodes":            "5",
+			"scan-interval":        "10s",
+			"expander":             expander,
+			"fallback-node-groups": fallbackNodeGroups,
+			"priorities":           priorities,
 		},
 		Containers: []KubernetesContainerSpec{
 			{
@@ -97,14 +320,52 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "300Mi",
 				CPULimits:      "100m",
 				MemoryLimits:   "300Mi",
-				Image:          specConfig.KubernetesImageBase + k8sComponents[ClusterAutoscalerAddonName],
+				Image:          c.specConfig.KubernetesImageBase + c.k8sComponents[ClusterAutoscalerAddonName],
 			},
 		},
 	}
+}
+
+// getClusterAutoscalerFallbackConfig returns the scale sets of the low priority pools with a fallback pool and of their
+// fallback pools, other than the primary scale set, as a comma-separated list, and the priorities of the priority
+// expander which make the cluster-autoscaler scale up the low priority pools first, and their fallback pools when
+// the low priority capacity is unavailable. Both are empty if no low priority pool has a fallback pool.
+func getClusterAutoscalerFallbackConfig(p *Properties) (string, string) {
+	var nodeGroups, lowPriorityNodeGroups, fallbackNodeGroups []string
+	found := map[string]bool{p.GetPrimaryScaleSetName(): true}
+	addNodeGroup := func(poolName string) string {
+		index := p.GetAgentPoolIndexByName(poolName)
+		if index < 0 {
+			return ""
+		}
+		name := p.GetAgentVMPrefix(p.AgentPoolProfiles[index], index)
+		if !found[name] {
+			found[name] = true
+			nodeGroups = append(nodeGroups, name)
+		}
+		return fmt.Sprintf("\"^%s$\"", name)
+	}
+	for _, pool := range p.AgentPoolProfiles {
+		if !pool.IsLowPriorityScaleSet() || pool.FallbackPool == "" {
+			continue
+		}
+		lowPriorityNodeGroups = append(lowPriorityNodeGroups, addNodeGroup(pool.Name))
+		if fallback := addNodeGroup(pool.FallbackPool); fallback != "" {
+			fallbackNodeGroups = append(fallbackNodeGroups, fallback)
+		}
+	}
+	if len(lowPriorityNodeGroups) == 0 {
+		return "", ""
+	}
+	// the node groups which match no expression are only scaled up if no other node group can be
+	priorities := fmt.Sprintf("{1: [\".*\"], 10: [%s], 20: [%s]}", strings.Join(fallbackNodeGroups, ", "), strings.Join(lowPriorityNodeGroups, ", "))
+	return strings.Join(nodeGroups, ","), priorities
+}
 
-	defaultBlobfuseFlexVolumeAddonsConfig := KubernetesAddon{
+func defaultBlobfuseFlexVolumeAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    BlobfuseFlexVolumeAddonName,
-		Enabled: to.BoolPtr(DefaultBlobfuseFlexVolumeAddonEnabled && common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.8.0") && !cs.Properties.HasCoreOS() && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultBlobfuseFlexVolumeAddonEnabled && common.IsKubernetesVersionGe(c.o.OrchestratorVersion, "1.8.0") && !c.cs.Properties.HasCoreOS() && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           BlobfuseFlexVolumeAddonName,
@@ -116,10 +377,12 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultSMBFlexVolumeAddonsConfig := KubernetesAddon{
+func defaultSMBFlexVolumeAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    SMBFlexVolumeAddonName,
-		Enabled: to.BoolPtr(DefaultSMBFlexVolumeAddonEnabled && common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.8.0") && !cs.Properties.HasCoreOS() && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultSMBFlexVolumeAddonEnabled && common.IsKubernetesVersionGe(c.o.OrchestratorVersion, "1.8.0") && !c.cs.Properties.HasCoreOS() && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           SMBFlexVolumeAddonName,
@@ -131,10 +394,17 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultKeyVaultFlexVolumeAddonsConfig := KubernetesAddon{
+func defaultKeyVaultFlexVolumeAddon(c addonDefaultsContext) KubernetesAddon {
+	// the usevmmanagedidentity volume option, to access the key vaults with the identity of the nodes, is supported from v0.0.13
+	image := "mcr.microsoft.com/k8s/flexvolume/keyvault-flexvolume:v0.0.7"
+	if c.o.KubernetesConfig.UseManagedIdentity {
+		image = "mcr.microsoft.com/k8s/flexvolume/keyvault-flexvolume:v0.0.13"
+	}
+	return KubernetesAddon{
 		Name:    KeyVaultFlexVolumeAddonName,
-		Enabled: to.BoolPtr(DefaultKeyVaultFlexVolumeAddonEnabled && !cs.Properties.HasCoreOS() && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultKeyVaultFlexVolumeAddonEnabled && !c.cs.Properties.HasCoreOS() && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           KeyVaultFlexVolumeAddonName,
@@ -142,12 +412,14 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "100Mi",
 				CPULimits:      "50m",
 				MemoryLimits:   "100Mi",
-				Image:          "mcr.microsoft.com/k8s/flexvolume/keyvault-flexvolume:v0.0.7",
+				Image:          image,
 			},
 		},
 	}
+}
 
-	defaultDashboardAddonsConfig := KubernetesAddon{
+func defaultDashboardAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    DashboardAddonName,
 		Enabled: to.BoolPtr(DefaultDashboardAddonEnabled),
 		Containers: []KubernetesContainerSpec{
@@ -157,14 +429,16 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "150Mi",
 				CPULimits:      "300m",
 				MemoryLimits:   "150Mi",
-				Image:          specConfig.KubernetesImageBase + k8sComponents[DashboardAddonName],
+				Image:          c.specConfig.KubernetesImageBase + c.k8sComponents[DashboardAddonName],
 			},
 		},
 	}
+}
 
-	defaultReschedulerAddonsConfig := KubernetesAddon{
+func defaultReschedulerAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    ReschedulerAddonName,
-		Enabled: to.BoolPtr(DefaultReschedulerAddonEnabled && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultReschedulerAddonEnabled && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           ReschedulerAddonName,
@@ -172,25 +446,29 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "100Mi",
 				CPULimits:      "10m",
 				MemoryLimits:   "100Mi",
-				Image:          specConfig.KubernetesImageBase + k8sComponents[ReschedulerAddonName],
+				Image:          c.specConfig.KubernetesImageBase + c.k8sComponents[ReschedulerAddonName],
 			},
 		},
 	}
+}
 
-	defaultMetricsServerAddonsConfig := KubernetesAddon{
+func defaultMetricsServerAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    MetricsServerAddonName,
-		Enabled: to.BoolPtr(DefaultMetricsServerAddonEnabled && common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.9.0")),
+		Enabled: to.BoolPtr(DefaultMetricsServerAddonEnabled && common.IsKubernetesVersionGe(c.o.OrchestratorVersion, "1.9.0")),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:  MetricsServerAddonName,
-				Image: specConfig.KubernetesImageBase + k8sComponents[MetricsServerAddonName],
+				Image: c.specConfig.KubernetesImageBase + c.k8sComponents[MetricsServerAddonName],
 			},
 		},
 	}
+}
 
-	defaultNVIDIADevicePluginAddonsConfig := KubernetesAddon{
+func defaultNVIDIADevicePluginAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    NVIDIADevicePluginAddonName,
-		Enabled: to.BoolPtr(cs.Properties.IsNvidiaDevicePluginCapable() && !cs.Properties.HasCoreOS() && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(c.cs.Properties.IsNvidiaDevicePluginCapable() && !c.cs.Properties.HasCoreOS() && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name: NVIDIADevicePluginAddonName,
@@ -199,14 +477,20 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "100Mi",
 				CPULimits:      "50m",
 				MemoryLimits:   "100Mi",
-				Image:          specConfig.NVIDIAImageBase + k8sComponents[NVIDIADevicePluginAddonName],
+				Image:          c.specConfig.NVIDIAImageBase + c.k8sComponents[NVIDIADevicePluginAddonName],
 			},
 		},
 	}
+}
 
-	defaultContainerMonitoringAddonsConfig := KubernetesAddon{
+func defaultContainerMonitoringAddon(c addonDefaultsContext) KubernetesAddon {
+	clusterDNSPrefix := "aks-engine-cluster"
+	if c.cs.Properties.MasterProfile != nil && c.cs.Properties.MasterProfile.DNSPrefix != "" {
+		clusterDNSPrefix = c.cs.Properties.MasterProfile.DNSPrefix
+	}
+	return KubernetesAddon{
 		Name:    ContainerMonitoringAddonName,
-		Enabled: to.BoolPtr(DefaultContainerMonitoringAddonEnabled && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultContainerMonitoringAddonEnabled && !c.cs.Properties.IsAzureStackCloud()),
 		Config: map[string]string{
 			"omsAgentVersion":       "1.10.0.1",
 			"dockerProviderVersion": "6.0.0-0",
@@ -224,10 +508,12 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultIPMasqAgentAddonsConfig := KubernetesAddon{
+func defaultIPMasqAgentAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    IPMASQAgentAddonName,
-		Enabled: to.BoolPtr(DefaultIPMasqAgentAddonEnabled && o.KubernetesConfig.NetworkPlugin != NetworkPluginCilium),
+		Enabled: to.BoolPtr(DefaultIPMasqAgentAddonEnabled && c.o.KubernetesConfig.NetworkPlugin != NetworkPluginCilium),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           IPMASQAgentAddonName,
@@ -235,29 +521,33 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 				MemoryRequests: "50Mi",
 				CPULimits:      "50m",
 				MemoryLimits:   "250Mi",
-				Image:          specConfig.KubernetesImageBase + "ip-masq-agent-amd64:v2.3.0",
+				Image:          c.specConfig.KubernetesImageBase + "ip-masq-agent-amd64:v2.3.0",
 			},
 		},
 		Config: map[string]string{
-			"non-masquerade-cidr": cs.Properties.GetNonMasqueradeCIDR(),
-			"non-masq-cni-cidr":   cs.Properties.GetAzureCNICidr(),
+			"non-masquerade-cidr": c.cs.Properties.GetNonMasqueradeCIDR(),
+			"non-masq-cni-cidr":   c.cs.Properties.GetAzureCNICidr(),
 		},
 	}
+}
 
-	defaultAzureCNINetworkMonitorAddonsConfig := KubernetesAddon{
+func defaultAzureCNINetworkMonitorAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    AzureCNINetworkMonitoringAddonName,
-		Enabled: to.BoolPtr(o.IsAzureCNI() && o.KubernetesConfig.NetworkPolicy != NetworkPolicyCalico),
+		Enabled: to.BoolPtr(c.o.IsAzureCNI() && c.o.KubernetesConfig.NetworkPolicy != NetworkPolicyCalico),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:  AzureCNINetworkMonitoringAddonName,
-				Image: specConfig.AzureCNIImageBase + k8sComponents[AzureCNINetworkMonitoringAddonName],
+				Image: c.specConfig.AzureCNIImageBase + c.k8sComponents[AzureCNINetworkMonitoringAddonName],
 			},
 		},
 	}
+}
 
-	defaultAzureNetworkPolicyAddonsConfig := KubernetesAddon{
+func defaultAzureNetworkPolicyAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    AzureNetworkPolicyAddonName,
-		Enabled: to.BoolPtr(o.KubernetesConfig.NetworkPlugin == NetworkPluginAzure && o.KubernetesConfig.NetworkPolicy == NetworkPolicyAzure),
+		Enabled: to.BoolPtr(c.o.KubernetesConfig.NetworkPlugin == NetworkPluginAzure && c.o.KubernetesConfig.NetworkPolicy == NetworkPolicyAzure),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:  AzureNetworkPolicyAddonName,
@@ -269,8 +559,10 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultDNSAutoScalerAddonsConfig := KubernetesAddon{
+func defaultDNSAutoScalerAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name: DNSAutoscalerAddonName,
 		// TODO enable this when it has been smoke tested
 		//common.IsKubernetesVersionGe(p.OrchestratorProfile.OrchestratorVersion, "1.12.0"),
@@ -278,43 +570,52 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           DNSAutoscalerAddonName,
-				Image:          specConfig.KubernetesImageBase + "cluster-proportional-autoscaler-amd64:1.1.1",
+				Image:          c.specConfig.KubernetesImageBase + "cluster-proportional-autoscaler-amd64:1.1.1",
 				CPURequests:    "20m",
 				MemoryRequests: "100Mi",
 			},
 		},
 	}
+}
 
-	defaultsCalicoDaemonSetAddonsConfig := KubernetesAddon{
+func defaultCalicoDaemonSetAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    CalicoAddonName,
-		Enabled: to.BoolPtr(o.KubernetesConfig.NetworkPolicy == NetworkPolicyCalico),
+		Enabled: to.BoolPtr(c.o.KubernetesConfig.NetworkPolicy == NetworkPolicyCalico),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:  "calico-typha",
-				Image: specConfig.CalicoImageBase + "typha:v3.8.0",
+				Image: c.specConfig.CalicoImageBase + "typha:v3.8.0",
 			},
 			{
 				Name:  "calico-cni",
-				Image: specConfig.CalicoImageBase + "cni:v3.8.0",
+				Image: c.specConfig.CalicoImageBase + "cni:v3.8.0",
 			},
 			{
 				Name:  "calico-node",
-				Image: specConfig.CalicoImageBase + "node:v3.8.0",
+				Image: c.specConfig.CalicoImageBase + "node:v3.8.0",
 			},
 			{
 				Name:  "calico-pod2daemon",
-				Image: specConfig.CalicoImageBase + "pod2daemon-flexvol:v3.8.0",
+				Image: c.specConfig.CalicoImageBase + "pod2daemon-flexvol:v3.8.0",
 			},
 			{
 				Name:  "calico-cluster-proportional-autoscaler",
-				Image: specConfig.KubernetesImageBase + "cluster-proportional-autoscaler-amd64:1.1.2-r2",
+				Image: c.specConfig.KubernetesImageBase + "cluster-proportional-autoscaler-amd64:1.1.2-r2",
 			},
 		},
 	}
+}
 
-	defaultsAADPodIdentityAddonsConfig := KubernetesAddon{
+func defaultAADPodIdentityAddon(c addonDefaultsContext) KubernetesAddon {
+	// mic authenticates with the managed identity of the nodes, instead of the service principal of azure.json, from 1.5
+	micImage := "mcr.microsoft.com/k8s/aad-pod-identity/mic:1.2"
+	if c.o.KubernetesConfig.UseManagedIdentity {
+		micImage = "mcr.microsoft.com/k8s/aad-pod-identity/mic:1.5"
+	}
+	return KubernetesAddon{
 		Name:    AADPodIdentityAddonName,
-		Enabled: to.BoolPtr(DefaultAADPodIdentityAddonEnabled && !cs.Properties.IsAzureStackCloud()),
+		Enabled: to.BoolPtr(DefaultAADPodIdentityAddonEnabled && !c.cs.Properties.IsAzureStackCloud()),
 		Containers: []KubernetesContainerSpec{
 			{
 				Name:           "nmi",
@@ -326,7 +627,7 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 			{
 				Name:           "mic",
-				Image:          "mcr.microsoft.com/k8s/aad-pod-identity/mic:1.2",
+				Image:          micImage,
 				CPURequests:    "100m",
 				MemoryRequests: "300Mi",
 				CPULimits:      "100m",
@@ -334,8 +635,10 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			},
 		},
 	}
+}
 
-	defaultAppGwAddonsConfig := KubernetesAddon{
+func defaultAppGwAddon(c addonDefaultsContext) KubernetesAddon {
+	return KubernetesAddon{
 		Name:    AppGwIngressAddonName,
 		Enabled: to.BoolPtr(DefaultAppGwIngressAddonEnabled),
 		Config: map[string]string{
@@ -344,60 +647,31 @@ func (cs *ContainerService) setAddonsConfig(isUpdate bool) {
 			"appgw-private-ip": "",
 		},
 	}
+}
 
-	defaultAddons := []KubernetesAddon{
-		defaultsHeapsterAddonsConfig,
-		defaultTillerAddonsConfig,
-		defaultACIConnectorAddonsConfig,
-		defaultClusterAutoscalerAddonsConfig,
-		defaultBlobfuseFlexVolumeAddonsConfig,
-		defaultSMBFlexVolumeAddonsConfig,
-		defaultKeyVaultFlexVolumeAddonsConfig,
-		defaultDashboardAddonsConfig,
-		defaultReschedulerAddonsConfig,
-		defaultMetricsServerAddonsConfig,
-		defaultNVIDIADevicePluginAddonsConfig,
-		defaultContainerMonitoringAddonsConfig,
-		defaultAzureCNINetworkMonitorAddonsConfig,
-		defaultAzureNetworkPolicyAddonsConfig,
-		defaultIPMasqAgentAddonsConfig,
-		defaultDNSAutoScalerAddonsConfig,
-		defaultsCalicoDaemonSetAddonsConfig,
-		defaultsAADPodIdentityAddonsConfig,
-		defaultAppGwAddonsConfig,
-	}
-	// Add default addons specification, if no user-provided spec exists
-	if o.KubernetesConfig.Addons == nil {
-		o.KubernetesConfig.Addons = defaultAddons
-	} else {
-		for _, addon := range defaultAddons {
-			o.KubernetesConfig.Addons = appendAddonIfNotPresent(o.KubernetesConfig.Addons, addon)
-		}
-	}
-
-	for _, addon := range defaultAddons {
-		synthesizeAddonsConfig(o.KubernetesConfig.Addons, addon, isUpdate)
-	}
-
-	if len(o.KubernetesConfig.PodSecurityPolicyConfig) > 0 && isUpdate {
-		if base64Data, ok := o.KubernetesConfig.PodSecurityPolicyConfig["data"]; ok {
-			pspAddonsConfig := KubernetesAddon{
-				Name: PodSecurityPolicyAddonName,
-				Data: base64Data,
-			}
-			o.KubernetesConfig.Addons = appendAddonIfNotPresent(o.KubernetesConfig.Addons, pspAddonsConfig)
-		}
-	}
-
-	// Specific back-compat business logic for calico addon
-	// Ensure addon is set to Enabled w/ proper containers config no matter what if NetworkPolicy == calico
-	i := getAddonsIndexByName(o.KubernetesConfig.Addons, CalicoAddonName)
-	if isUpdate && o.KubernetesConfig.NetworkPolicy == NetworkPolicyCalico && i > -1 && o.KubernetesConfig.Addons[i].Enabled != to.BoolPtr(true) {
-		j := getAddonsIndexByName(defaultAddons, CalicoAddonName)
-		// Ensure calico is statically set to enabled
-		o.KubernetesConfig.Addons[i].Enabled = to.BoolPtr(true)
-		// Assume addon configuration was pruned due to an inherited enabled=false, so re-apply default values
-		o.KubernetesConfig.Addons[i] = assignDefaultAddonVals(o.KubernetesConfig.Addons[i], defaultAddons[j], isUpdate)
+func defaultNodeTerminationHandlerAddon(c addonDefaultsContext) KubernetesAddon {
+	// kubectl of the hyperkube image the nodes run, which is pulled from the image base or private registry of the cluster
+	kubectlImage := c.o.KubernetesConfig.KubernetesImageBase + c.k8sComponents["hyperkube"]
+	if c.o.KubernetesConfig.CustomHyperkubeImage != "" {
+		kubectlImage = c.o.KubernetesConfig.CustomHyperkubeImage
+	}
+	return KubernetesAddon{
+		Name:    NodeTerminationHandlerAddonName,
+		Enabled: to.BoolPtr(c.cs.Properties.HasLowPriorityScaleSets() && common.IsKubernetesVersionGe(c.o.OrchestratorVersion, "1.10.0") && !c.cs.Properties.IsAzureStackCloud()),
+		Config: map[string]string{
+			"poll-interval":      "5",
+			"drain-grace-period": "20",
+		},
+		Containers: []KubernetesContainerSpec{
+			{
+				Name:           NodeTerminationHandlerAddonName,
+				CPURequests:    "10m",
+				MemoryRequests: "50Mi",
+				CPULimits:      "50m",
+				MemoryLimits:   "100Mi",
+				Image:          kubectlImage,
+			},
+		},
 	}
 }
 
@@ -193,6 +193,7 @@ func TestSetAddonsConfig(t *testing.T) {
 			VnetCNILinuxPluginsDownloadURL:   "VnetCNILinuxPluginsDownloadURL",
 			VnetCNIWindowsPluginsDownloadURL: "VnetCNIWindowsPluginsDownloadURL",
 			ContainerdDownloadURLBase:        "ContainerdDownloadURLBase",
+			WindowsContainerdDownloadURL:     "WindowsContainerdDownloadURL",
 		},
 		EndpointConfig: AzureEndpointConfig{
 			ResourceManagerVMDNSSuffix: "ResourceManagerVMDNSSuffix",
@@ -3198,3 +3199,202 @@ func TestSetAddonsConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLowPriorityScaleSetAddons(t *testing.T) {
+	mockCS := getMockBaseContainerService("1.15.3")
+	mockCS.Properties.ClusterID = "12345678"
+	mockCS.Properties.AgentPoolProfiles = []*AgentPoolProfile{
+		{
+			Name:                "system",
+			AvailabilityProfile: VirtualMachineScaleSets,
+		},
+		{
+			Name:                "spot",
+			AvailabilityProfile: VirtualMachineScaleSets,
+			ScaleSetPriority:    ScaleSetPriorityLow,
+			FallbackPool:        "regular",
+		},
+		{
+			Name:                "regular",
+			AvailabilityProfile: VirtualMachineScaleSets,
+		},
+	}
+	o := mockCS.Properties.OrchestratorProfile
+	o.OrchestratorType = Kubernetes
+	o.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    ClusterAutoscalerAddonName,
+			Enabled: to.BoolPtr(true),
+		},
+	}
+
+	mockCS.setAddonsConfig(false)
+
+	if !o.KubernetesConfig.IsAddonEnabled(NodeTerminationHandlerAddonName) {
+		t.Fatalf("expected the addon %s to be enabled with a low priority pool", NodeTerminationHandlerAddonName)
+	}
+	expectedImage := o.KubernetesConfig.KubernetesImageBase + K8sComponentsByVersionMap["1.15.3"]["hyperkube"]
+	if image := o.KubernetesConfig.GetAddonByName(NodeTerminationHandlerAddonName).Containers[0].Image; image != expectedImage {
+		t.Fatalf("expected addon %s to use the hyperkube image %s, got %s", NodeTerminationHandlerAddonName, expectedImage, image)
+	}
+	expectedConfig := map[string]string{
+		"expander":             "priority",
+		"fallback-node-groups": "k8s-spot-12345678-vmss,k8s-regular-12345678-vmss",
+		"priorities":           `{1: [".*"], 10: ["^k8s-regular-12345678-vmss$"], 20: ["^k8s-spot-12345678-vmss$"]}`,
+	}
+	config := o.KubernetesConfig.GetAddonByName(ClusterAutoscalerAddonName).Config
+	for key, val := range expectedConfig {
+		if config[key] != val {
+			t.Fatalf("expected addon %s to have config %s=%s, got %s=%s", ClusterAutoscalerAddonName, key, val, key, config[key])
+		}
+	}
+
+	// without a fallback pool, the cluster-autoscaler keeps its default expander
+	mockCS = getMockBaseContainerService("1.15.3")
+	mockCS.Properties.AgentPoolProfiles = []*AgentPoolProfile{
+		{
+			Name:                "spot",
+			AvailabilityProfile: VirtualMachineScaleSets,
+			ScaleSetPriority:    ScaleSetPriorityLow,
+		},
+	}
+	o = mockCS.Properties.OrchestratorProfile
+	o.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    ClusterAutoscalerAddonName,
+			Enabled: to.BoolPtr(true),
+		},
+	}
+
+	mockCS.setAddonsConfig(false)
+
+	config = o.KubernetesConfig.GetAddonByName(ClusterAutoscalerAddonName).Config
+	if config["expander"] != "random" || config["fallback-node-groups"] != "" || config["priorities"] != "" {
+		t.Fatalf("expected addon %s to have the random expander without fallback node groups, got config %v", ClusterAutoscalerAddonName, config)
+	}
+
+	// the node termination handler uses the custom hyperkube image of a private registry
+	mockCS = getMockBaseContainerService("1.15.3")
+	mockCS.Properties.AgentPoolProfiles = []*AgentPoolProfile{
+		{
+			Name:                "spot",
+			AvailabilityProfile: VirtualMachineScaleSets,
+			ScaleSetPriority:    ScaleSetPriorityLow,
+		},
+	}
+	o = mockCS.Properties.OrchestratorProfile
+	o.KubernetesConfig.PrivateAzureRegistryServer = "registry.example.com"
+	o.KubernetesConfig.CustomHyperkubeImage = "registry.example.com/hyperkube-amd64:v1.15.3"
+	mockCS.setAddonsConfig(false)
+	if image := o.KubernetesConfig.GetAddonByName(NodeTerminationHandlerAddonName).Containers[0].Image; image != o.KubernetesConfig.CustomHyperkubeImage {
+		t.Fatalf("expected addon %s to use the custom hyperkube image %s, got %s", NodeTerminationHandlerAddonName, o.KubernetesConfig.CustomHyperkubeImage, image)
+	}
+
+	// the node termination handler is only enabled with low priority pools
+	mockCS = getMockBaseContainerService("1.15.3")
+	mockCS.setAddonsConfig(false)
+	if mockCS.Properties.OrchestratorProfile.KubernetesConfig.IsAddonEnabled(NodeTerminationHandlerAddonName) {
+		t.Fatalf("expected the addon %s to be disabled without a low priority pool", NodeTerminationHandlerAddonName)
+	}
+}
+
+func TestManagedIdentityAddonImages(t *testing.T) {
+	mockCS := getMockBaseContainerService("1.15.3")
+	o := mockCS.Properties.OrchestratorProfile
+	o.OrchestratorType = Kubernetes
+	o.KubernetesConfig.UseManagedIdentity = true
+	o.KubernetesConfig.UserAssignedID = "clusterIdentity"
+	o.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    KeyVaultFlexVolumeAddonName,
+			Enabled: to.BoolPtr(true),
+		},
+		{
+			Name:    AADPodIdentityAddonName,
+			Enabled: to.BoolPtr(true),
+		},
+	}
+
+	mockCS.setAddonsConfig(false)
+
+	// keyvault-flexvolume and mic only authenticate with the identity of the nodes from these versions
+	expectedImages := map[string]string{
+		KeyVaultFlexVolumeAddonName: "mcr.microsoft.com/k8s/flexvolume/keyvault-flexvolume:v0.0.13",
+		"mic":                       "mcr.microsoft.com/k8s/aad-pod-identity/mic:1.5",
+	}
+	found := 0
+	for _, addon := range o.KubernetesConfig.Addons {
+		for _, container := range addon.Containers {
+			if image, ok := expectedImages[container.Name]; ok {
+				found++
+				if container.Image != image {
+					t.Fatalf("expected container %s of addon %s to have image %s with a managed identity, got %s", container.Name, addon.Name, image, container.Image)
+				}
+			}
+		}
+	}
+	if found != len(expectedImages) {
+		t.Fatalf("expected to find the containers %v, found %d of them", expectedImages, found)
+	}
+}
+
+func TestNetworkPolicyAddonsUpgrade(t *testing.T) {
+	// a cluster deployed with the azure network policy manager, upgraded to calico
+	mockCS := getMockBaseContainerService("1.15.3")
+	o := mockCS.Properties.OrchestratorProfile
+	o.OrchestratorType = Kubernetes
+	o.KubernetesConfig.NetworkPlugin = NetworkPluginAzure
+	o.KubernetesConfig.NetworkPolicy = NetworkPolicyCalico
+	o.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    CalicoAddonName,
+			Enabled: to.BoolPtr(false),
+		},
+		{
+			Name:    AzureNetworkPolicyAddonName,
+			Enabled: to.BoolPtr(true),
+		},
+	}
+
+	mockCS.setAddonsConfig(true)
+
+	if !o.KubernetesConfig.IsAddonEnabled(CalicoAddonName) {
+		t.Fatalf("expected the addon %s to be enabled after switching to calico", CalicoAddonName)
+	}
+	if len(o.KubernetesConfig.GetAddonByName(CalicoAddonName).Containers) == 0 {
+		t.Fatalf("expected the addon %s to have the default containers after switching to calico", CalicoAddonName)
+	}
+	if o.KubernetesConfig.IsAddonEnabled(AzureNetworkPolicyAddonName) {
+		t.Fatalf("expected the addon %s to be disabled after switching to calico", AzureNetworkPolicyAddonName)
+	}
+
+	// and back to the azure network policy manager
+	o.KubernetesConfig.NetworkPolicy = NetworkPolicyAzure
+
+	mockCS.setAddonsConfig(true)
+
+	if o.KubernetesConfig.IsAddonEnabled(CalicoAddonName) {
+		t.Fatalf("expected the addon %s to be disabled after switching to azure", CalicoAddonName)
+	}
+	if !o.KubernetesConfig.IsAddonEnabled(AzureNetworkPolicyAddonName) {
+		t.Fatalf("expected the addon %s to be enabled after switching to azure", AzureNetworkPolicyAddonName)
+	}
+
+	// a new deployment keeps addons disabled by the user
+	mockCS = getMockBaseContainerService("1.15.3")
+	o = mockCS.Properties.OrchestratorProfile
+	o.KubernetesConfig.NetworkPlugin = NetworkPluginAzure
+	o.KubernetesConfig.NetworkPolicy = NetworkPolicyAzure
+	o.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    AzureNetworkPolicyAddonName,
+			Enabled: to.BoolPtr(false),
+		},
+	}
+
+	mockCS.setAddonsConfig(false)
+
+	if o.KubernetesConfig.IsAddonEnabled(AzureNetworkPolicyAddonName) {
+		t.Fatalf("expected the addon %s to stay disabled", AzureNetworkPolicyAddonName)
+	}
+}
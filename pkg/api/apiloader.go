@@ -4,9 +4,9 @@
 package api
 
 import (
-	"crypto/rand"
 	"encoding/json"
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
 
 	v20170831 "github.com/Azure/aks-engine/pkg/api/agentPoolOnlyApi/v20170831"
@@ -45,7 +45,7 @@ func (a *Apiloader) LoadContainerServiceFromFile(jsonFile string, validate, isUp
 	if e != nil {
 		return nil, "", a.Translator.Errorf("error reading file %s: %s", jsonFile, e.Error())
 	}
-	return a.DeserializeContainerService(contents, validate, isUpdate, existingContainerService)
+	return a.deserializeContainerService(contents, filepath.Dir(jsonFile), validate, isUpdate, existingContainerService)
 }
 
 // LoadDefaultContainerServiceProperties loads the default API model
@@ -73,6 +73,11 @@ func LoadDefaultContainerServiceProperties() (TypeMeta, *vlabs.Properties) {
 
 // DeserializeContainerService loads an AKS Engine Cluster API Model, validates it, and returns the unversioned representation
 func (a *Apiloader) DeserializeContainerService(contents []byte, validate, isUpdate bool, existingContainerService *ContainerService) (*ContainerService, string, error) {
+	return a.deserializeContainerService(contents, "", validate, isUpdate, existingContainerService)
+}
+
+// deserializeContainerService loads an AKS Engine Cluster API Model whose relative file paths are resolved against dir
+func (a *Apiloader) deserializeContainerService(contents []byte, dir string, validate, isUpdate bool, existingContainerService *ContainerService) (*ContainerService, string, error) {
 	m := &TypeMeta{}
 	if err := json.Unmarshal(contents, &m); err != nil {
 		return nil, "", err
@@ -85,7 +90,7 @@ func (a *Apiloader) DeserializeContainerService(contents []byte, validate, isUpd
 	case "2017-08-31", "2018-03-31":
 		cs, _, err = a.LoadContainerServiceForAgentPoolOnlyCluster(contents, version, validate, isUpdate, "", existingContainerService)
 	default:
-		cs, err = a.LoadContainerService(contents, version, validate, isUpdate, existingContainerService)
+		cs, err = a.loadContainerService(contents, version, dir, validate, isUpdate, existingContainerService)
 	}
 	return cs, version, err
 }
@@ -96,6 +101,15 @@ func (a *Apiloader) LoadContainerService(
 	version string,
 	validate, isUpdate bool,
 	existingContainerService *ContainerService) (*ContainerService, error) {
+	return a.loadContainerService(contents, version, "", validate, isUpdate, existingContainerService)
+}
+
+// loadContainerService loads an AKS Cluster API Model whose relative file paths are resolved against dir
+func (a *Apiloader) loadContainerService(
+	contents []byte,
+	version, dir string,
+	validate, isUpdate bool,
+	existingContainerService *ContainerService) (*ContainerService, error) {
 	var curOrchVersion string
 	hasExistingCS := existingContainerService != nil
 	if hasExistingCS {
@@ -220,6 +234,9 @@ func (a *Apiloader) LoadContainerService(
 				return nil, e
 			}
 		}
+		if e := loadAuditPolicyFile(containerService, dir); e != nil {
+			return nil, e
+		}
 		if validate {
 			if e := containerService.Validate(isUpdate); e != nil {
 				return nil, e
@@ -231,6 +248,9 @@ func (a *Apiloader) LoadContainerService(
 		if unversioned, err = ConvertVLabsContainerService(containerService, isUpdate); err != nil {
 			return nil, err
 		}
+		if err = unversioned.RegisterAddonDescriptors(); err != nil {
+			return nil, err
+		}
 		if curOrchVersion != "" &&
 			(containerService.Properties.OrchestratorProfile == nil ||
 				(containerService.Properties.OrchestratorProfile.OrchestratorVersion == "" &&
@@ -244,6 +264,33 @@ func (a *Apiloader) LoadContainerService(
 	}
 }
 
+// loadAuditPolicyFile reads the policy file of the audit profile into its policy, so that the apimodel written to the
+// output directory holds the policy, and upgrade applies it again. A relative policy file is resolved against dir,
+// the directory of the apimodel file.
+func loadAuditPolicyFile(cs *vlabs.ContainerService, dir string) error {
+	if cs.Properties == nil || cs.Properties.OrchestratorProfile == nil || cs.Properties.OrchestratorProfile.KubernetesConfig == nil {
+		return nil
+	}
+	a := cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile
+	if a == nil || a.PolicyFile == "" {
+		return nil
+	}
+	if a.Policy != "" {
+		return errors.New("auditProfile.policy and auditProfile.policyFile are mutually exclusive")
+	}
+	policyFile := a.PolicyFile
+	if !filepath.IsAbs(policyFile) {
+		policyFile = filepath.Join(dir, policyFile)
+	}
+	b, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return errors.Wrap(err, "reading auditProfile.policyFile")
+	}
+	a.Policy = string(b)
+	a.PolicyFile = ""
+	return nil
+}
+
 // LoadContainerServiceForAgentPoolOnlyCluster loads an AKS Cluster API Model, validates it, and returns the unversioned representation
 func (a *Apiloader) LoadContainerServiceForAgentPoolOnlyCluster(
 	contents []byte,
@@ -342,7 +389,7 @@ func (a *Apiloader) LoadContainerServiceForAgentPoolOnlyCluster(
 		if !hasExistingCS && managedCluster.Properties.LinuxProfile == nil {
 			linuxProfile := &v20180331.LinuxProfile{}
 			linuxProfile.AdminUsername = "azureuser"
-			_, publicKey, err := helpers.CreateSSH(rand.Reader, a.Translator)
+			_, publicKey, err := helpers.CreateSSH(helpers.RandomReader(), a.Translator)
 			if err != nil {
 				return nil, IsSSHAutoGenerated, err
 			}
@@ -463,6 +463,95 @@ func TestDeserializeContainerService(t *testing.T) {
 	}
 }
 
+func TestLoadAuditPolicyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditpolicy")
+	if err != nil {
+		t.Fatalf("unexpected error creating a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	policy := "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"
+	policyFile := path.Join(dir, "audit-policy.yaml")
+	if err = ioutil.WriteFile(policyFile, []byte(policy), 0600); err != nil {
+		t.Fatalf("unexpected error writing the audit policy: %s", err)
+	}
+
+	cs := &vlabs.ContainerService{
+		Properties: &vlabs.Properties{
+			OrchestratorProfile: &vlabs.OrchestratorProfile{
+				KubernetesConfig: &vlabs.KubernetesConfig{
+					AuditProfile: &vlabs.AuditProfile{PolicyFile: policyFile},
+				},
+			},
+		},
+	}
+	if err = loadAuditPolicyFile(cs, ""); err != nil {
+		t.Fatalf("unexpected error loading the audit policy file: %s", err)
+	}
+	a := cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile
+	if a.Policy != policy || a.PolicyFile != "" {
+		t.Errorf("expected the policy file to be read into the policy, got policy %q and policy file %q", a.Policy, a.PolicyFile)
+	}
+
+	a.PolicyFile = policyFile
+	if err = loadAuditPolicyFile(cs, ""); err == nil || err.Error() != "auditProfile.policy and auditProfile.policyFile are mutually exclusive" {
+		t.Errorf("expected an error for both a policy and a policy file, got %v", err)
+	}
+
+	a.Policy = ""
+	a.PolicyFile = path.Join(dir, "missing.yaml")
+	if err = loadAuditPolicyFile(cs, ""); err == nil {
+		t.Errorf("expected an error for a missing policy file")
+	}
+
+	if err = loadAuditPolicyFile(&vlabs.ContainerService{Properties: &vlabs.Properties{}}, dir); err != nil {
+		t.Errorf("unexpected error without audit profile: %s", err)
+	}
+
+	// a relative policy file is resolved against the directory of the apimodel, not the working directory
+	a.PolicyFile = "audit-policy.yaml"
+	if err = loadAuditPolicyFile(cs, dir); err != nil {
+		t.Fatalf("unexpected error loading the relative audit policy file: %s", err)
+	}
+	if a.Policy != policy {
+		t.Errorf("expected the relative policy file to be read into the policy, got policy %q", a.Policy)
+	}
+
+	apiModel := `{
+  "apiVersion": "vlabs",
+  "properties": {
+    "orchestratorProfile": {
+      "orchestratorType": "Kubernetes",
+      "kubernetesConfig": {
+        "auditProfile": {
+          "policyFile": "audit-policy.yaml"
+        }
+      }
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "auditpolicy",
+      "vmSize": "Standard_D2_v2"
+    }
+  }
+}`
+	apiModelFile := path.Join(dir, "apimodel.json")
+	if err = ioutil.WriteFile(apiModelFile, []byte(apiModel), 0600); err != nil {
+		t.Fatalf("unexpected error writing the apimodel: %s", err)
+	}
+	apiloader := &Apiloader{
+		Translator: &i18n.Translator{
+			Locale: gotext.NewLocale(path.Join("..", "..", "translations"), "en_US"),
+		},
+	}
+	loaded, _, err := apiloader.LoadContainerServiceFromFile(apiModelFile, false, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error loading an apimodel with a relative audit policy file: %s", err)
+	}
+	if p := loaded.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile.Policy; p != policy {
+		t.Errorf("expected the policy file next to the apimodel to be read into the policy, got policy %q", p)
+	}
+}
+
 func TestLoadDefaultContainerServiceProperties(t *testing.T) {
 	m, p := LoadDefaultContainerServiceProperties()
 
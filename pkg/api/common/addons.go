@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/blang/semver"
+)
+
+// AddonKubernetesVersions is the range of Kubernetes versions an addon can be delivered to
+type AddonKubernetesVersions struct {
+	// MinVersion is the earliest supported version, all versions up to MaxVersion are supported if it is empty
+	MinVersion string
+	// MaxVersion is the first version which is not supported anymore, all versions from MinVersion are supported if
+	// it is empty
+	MaxVersion string
+}
+
+// Range returns the versions as a semver range, e.g. ">=1.9.0 <1.16.0", or an empty string if all versions are supported
+func (a AddonKubernetesVersions) Range() string {
+	switch {
+	case a.MinVersion != "" && a.MaxVersion != "":
+		return fmt.Sprintf(">=%s <%s", a.MinVersion, a.MaxVersion)
+	case a.MinVersion != "":
+		return fmt.Sprintf(">=%s", a.MinVersion)
+	case a.MaxVersion != "":
+		return fmt.Sprintf("<%s", a.MaxVersion)
+	}
+	return ""
+}
+
+// BuiltinAddonKubernetesVersions is the compatibility matrix of the built-in addons, by addon name. The built-in addons
+// which are not in the matrix support all the Kubernetes versions supported by aks-engine. The addons whose image is
+// pinned for each minor version of Kubernetes in k8sComponentVersions support the minor versions in that table only.
+var BuiltinAddonKubernetesVersions = map[string]AddonKubernetesVersions{
+	"aci-connector":            {MaxVersion: "1.17.0"},
+	"azure-cni-networkmonitor": {MaxVersion: "1.17.0"},
+	"blobfuse-flexvolume":      {MinVersion: "1.8.0"},
+	"cluster-autoscaler":       {MinVersion: "1.9.0", MaxVersion: "1.17.0"},
+	"container-monitoring":     {MinVersion: "1.7.0", MaxVersion: "1.17.0"},
+	"heapster":                 {MaxVersion: "1.17.0"},
+	"kubernetes-dashboard":     {MaxVersion: "1.17.0"},
+	"metrics-server":           {MaxVersion: "1.17.0"},
+	"node-termination-handler": {MinVersion: "1.10.0"},
+	"nvidia-device-plugin":     {MinVersion: "1.10.0", MaxVersion: "1.17.0"},
+	// the rescheduler only reschedules pods with the critical pod annotation, which Kubernetes ignores from 1.16
+	"rescheduler":    {MaxVersion: "1.16.0-alpha.1"},
+	"smb-flexvolume": {MinVersion: "1.8.0"},
+	"tiller":         {MaxVersion: "1.17.0"},
+}
+
+// IsKubernetesVersionInRange returns true if the Kubernetes version is in the semver range, e.g. ">=1.9.0 <1.16.0".
+// All versions are in the empty range, and invalid versions or ranges are in none.
+func IsKubernetesVersionInRange(version, versionRange string) bool {
+	if versionRange == "" {
+		return true
+	}
+	r, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return false
+	}
+	v, err := semver.Make(version)
+	if err != nil {
+		return false
+	}
+	return r(v)
+}
@@ -0,0 +1,46 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package common
+
+import "testing"
+
+func TestAddonKubernetesVersionsRange(t *testing.T) {
+	cases := []struct {
+		versions AddonKubernetesVersions
+		expected string
+	}{
+		{AddonKubernetesVersions{}, ""},
+		{AddonKubernetesVersions{MinVersion: "1.9.0"}, ">=1.9.0"},
+		{AddonKubernetesVersions{MaxVersion: "1.16.0"}, "<1.16.0"},
+		{AddonKubernetesVersions{MinVersion: "1.9.0", MaxVersion: "1.16.0"}, ">=1.9.0 <1.16.0"},
+	}
+	for _, c := range cases {
+		if r := c.versions.Range(); r != c.expected {
+			t.Errorf("expected range %q for %+v, got %q", c.expected, c.versions, r)
+		}
+	}
+}
+
+func TestIsKubernetesVersionInRange(t *testing.T) {
+	cases := []struct {
+		version      string
+		versionRange string
+		expected     bool
+	}{
+		{"1.6.9", "", true},
+		{"1.9.0", ">=1.9.0", true},
+		{"1.8.15", ">=1.9.0", false},
+		{"1.15.4", ">=1.9.0 <1.16.0", true},
+		{"1.16.0", ">=1.9.0 <1.16.0", false},
+		{"1.15.4", "<1.16.0-alpha.1", true},
+		{"1.16.0-beta.1", "<1.16.0-alpha.1", false},
+		{"not-a-version", ">=1.9.0", false},
+		{"1.9.0", "not-a-range", false},
+	}
+	for _, c := range cases {
+		if actual := IsKubernetesVersionInRange(c.version, c.versionRange); actual != c.expected {
+			t.Errorf("expected IsKubernetesVersionInRange(%q, %q) to be %t", c.version, c.versionRange, c.expected)
+		}
+	}
+}
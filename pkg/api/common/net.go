@@ -4,6 +4,8 @@
 package common
 
 import (
+	"encoding/binary"
+	"math"
 	"net"
 	"regexp"
 
@@ -46,6 +48,31 @@ func IP4BroadcastAddress(n *net.IPNet) net.IP {
 	return last
 }
 
+// IP4Add returns the IPv4 address offset addresses after the given one, or nil if ip is not an IPv4 address.
+func IP4Add(ip net.IP, offset int) net.IP {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil
+	}
+	result := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(result, binary.BigEndian.Uint32(ip4)+uint32(offset))
+	return result
+}
+
+// CidrAddressCount returns the number of addresses in the given subnet, capped to math.MaxUint64 for large IPv6 subnets.
+func CidrAddressCount(n *net.IPNet) uint64 {
+	ones, bits := n.Mask.Size()
+	if bits-ones >= 64 {
+		return math.MaxUint64
+	}
+	return uint64(1) << uint(bits-ones)
+}
+
+// CidrsOverlap returns true if the two given subnets share at least one address.
+func CidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
 // GetVNETSubnetIDComponents extract subscription, resourcegroup, vnetname, subnetname from the vnetSubnetID
 func GetVNETSubnetIDComponents(vnetSubnetID string) (string, string, string, string, error) {
 	vnetSubnetIDRegex := `^\/subscriptions\/([^\/]*)\/resourceGroups\/([^\/]*)\/providers\/Microsoft.Network\/virtualNetworks\/([^\/]*)\/subnets\/([^\/]*)$`
@@ -59,3 +86,18 @@ func GetVNETSubnetIDComponents(vnetSubnetID string) (string, string, string, str
 	}
 	return submatches[1], submatches[2], submatches[3], submatches[4], nil
 }
+
+// GetResourceIDComponents extract subscription, resourcegroup and name from the ID of a Microsoft.Network resource
+// of the given type, e.g. routeTables or networkSecurityGroups
+func GetResourceIDComponents(resourceID, resourceType string) (string, string, string, error) {
+	resourceIDRegex := `^\/subscriptions\/([^\/]*)\/resourceGroups\/([^\/]*)\/providers\/Microsoft.Network\/` + regexp.QuoteMeta(resourceType) + `\/([^\/]*)$`
+	re, err := regexp.Compile(resourceIDRegex)
+	if err != nil {
+		return "", "", "", err
+	}
+	submatches := re.FindStringSubmatch(resourceID)
+	if len(submatches) != 4 {
+		return "", "", "", errors.Errorf("Unable to parse %s. Please use an ID with format /subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/%s/NAME", resourceID, resourceType)
+	}
+	return submatches[1], submatches[2], submatches[3], nil
+}
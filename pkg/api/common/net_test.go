@@ -4,6 +4,7 @@
 package common
 
 import (
+	"math"
 	"net"
 	"testing"
 )
@@ -92,3 +93,142 @@ func Test_GetVNETSubnetIDComponents(t *testing.T) {
 		}
 	}
 }
+
+func Test_GetResourceIDComponents(t *testing.T) {
+	scenarios := []struct {
+		resourceID    string
+		resourceType  string
+		expectedSubID string
+		expectedRG    string
+		expectedName  string
+		expectedErr   bool
+	}{
+		{
+			resourceID:    "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/RT_NAME",
+			resourceType:  "routeTables",
+			expectedSubID: "SUB_ID",
+			expectedRG:    "RG_NAME",
+			expectedName:  "RT_NAME",
+		},
+		{
+			resourceID:    "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME",
+			resourceType:  "networkSecurityGroups",
+			expectedSubID: "SUB_ID",
+			expectedRG:    "RG_NAME",
+			expectedName:  "NSG_NAME",
+		},
+		{
+			resourceID:   "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME",
+			resourceType: "routeTables",
+			expectedErr:  true,
+		},
+		{
+			resourceID:   "badResourceID",
+			resourceType: "routeTables",
+			expectedErr:  true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		subID, rg, name, err := GetResourceIDComponents(scenario.resourceID, scenario.resourceType)
+		if scenario.expectedErr != (err != nil) {
+			t.Errorf("expected error %t parsing %s, got %v", scenario.expectedErr, scenario.resourceID, err)
+		}
+		if subID != scenario.expectedSubID || rg != scenario.expectedRG || name != scenario.expectedName {
+			t.Errorf("expected subID %s, rg %s and name %s but instead got subID %s, rg %s and name %s", scenario.expectedSubID, scenario.expectedRG, scenario.expectedName, subID, rg, name)
+		}
+	}
+}
+
+func Test_IP4Add(t *testing.T) {
+	scenarios := []struct {
+		ip       string
+		offset   int
+		expected string
+	}{
+		{
+			ip:       "10.240.255.5",
+			offset:   4,
+			expected: "10.240.255.9",
+		},
+		{
+			ip:       "10.240.0.255",
+			offset:   1,
+			expected: "10.240.1.0",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		if ip := IP4Add(net.ParseIP(scenario.ip), scenario.offset); ip.String() != scenario.expected {
+			t.Errorf("expected %v + %d to be %v but was %v", scenario.ip, scenario.offset, scenario.expected, ip)
+		}
+	}
+
+	if ip := IP4Add(net.ParseIP("2001:1234:5678:9abc::4"), 1); ip != nil {
+		t.Errorf("expected IP4Add of an IPv6 address to be nil but was %v", ip)
+	}
+}
+
+func Test_CidrAddressCount(t *testing.T) {
+	scenarios := []struct {
+		cidr     string
+		expected uint64
+	}{
+		{
+			cidr:     "10.240.0.0/16",
+			expected: 65536,
+		},
+		{
+			cidr:     "10.16.32.32/27",
+			expected: 32,
+		},
+		{
+			cidr:     "2001:1234:5678:9a00::/56",
+			expected: math.MaxUint64,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		_, cidr, _ := net.ParseCIDR(scenario.cidr)
+		if count := CidrAddressCount(cidr); count != scenario.expected {
+			t.Errorf("expected subnet %v to have %d addresses but had %d", scenario.cidr, scenario.expected, count)
+		}
+	}
+}
+
+func Test_CidrsOverlap(t *testing.T) {
+	scenarios := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{
+			a:        "10.0.0.0/8",
+			b:        "10.0.0.0/16",
+			expected: true,
+		},
+		{
+			a:        "10.244.0.0/16",
+			b:        "10.240.0.0/12",
+			expected: true,
+		},
+		{
+			a:        "10.0.0.0/16",
+			b:        "10.240.0.0/16",
+			expected: false,
+		},
+		{
+			a:        "172.17.0.1/16",
+			b:        "10.0.0.0/8",
+			expected: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		_, a, _ := net.ParseCIDR(scenario.a)
+		_, b, _ := net.ParseCIDR(scenario.b)
+		if overlap := CidrsOverlap(a, b); overlap != scenario.expected {
+			t.Errorf("expected overlap of %v and %v to be %v but was %v", scenario.a, scenario.b, scenario.expected, overlap)
+		}
+	}
+}
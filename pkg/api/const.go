@@ -50,6 +50,15 @@ const (
 	KubernetesWindowsDockerVersion = "18.09.7"
 	// KubernetesDefaultWindowsSku is the default SKU for Windows VMs in kubernetes
 	KubernetesDefaultWindowsSku = "Datacenter-Core-1809-with-Containers-smalldisk"
+	// ImageVersionLatest is the version of a Shared Image Gallery image resolved to its latest version by deploy and upgrade
+	ImageVersionLatest = "latest"
+)
+
+const (
+	// AuditPolicyFile is the path of the audit policy on the masters
+	AuditPolicyFile = "/etc/kubernetes/addons/audit-policy.yaml"
+	// AuditWebhookConfigFile is the path of the kubeconfig of the audit webhook backend on the masters
+	AuditWebhookConfigFile = "/etc/kubernetes/audit-webhook-kubeconfig.yaml"
 )
 
 // validation values
@@ -102,6 +111,10 @@ const (
 	ScaleSetEvictionPolicyDelete = "Delete"
 	// ScaleSetEvictionPolicyDeallocate means a Low-priority VM ScaleSet will deallocate, rather than delete, VMs.
 	ScaleSetEvictionPolicyDeallocate = "Deallocate"
+	// LowPriorityNodeLabel is the label of the nodes of Low-priority VM ScaleSets
+	LowPriorityNodeLabel = "kubernetes.azure.com/scalesetpriority=low"
+	// LowPriorityNodeTaint is the taint of the nodes of Low-priority VM ScaleSets, so that only the pods tolerating evictions are scheduled on them
+	LowPriorityNodeTaint = "kubernetes.azure.com/scalesetpriority=low:NoSchedule"
 )
 
 // Supported container runtimes
@@ -157,6 +170,12 @@ const (
 	DefaultLoadBalancerSku = "Basic"
 	// StandardLoadBalancerSku is the string const for Azure Standard Load Balancer
 	StandardLoadBalancerSku = "Standard"
+	// OutboundTypeLoadBalancer is the string const for the egress of the agents through the outbound rules of the agent load balancer
+	OutboundTypeLoadBalancer = "loadBalancer"
+	// OutboundTypeUserDefinedRouting is the string const for the egress of the cluster through user-defined routes, e.g. to Azure Firewall
+	OutboundTypeUserDefinedRouting = "userDefinedRouting"
+	// DefaultOutboundType determines the aks-engine provided default for the egress of the cluster
+	DefaultOutboundType = OutboundTypeLoadBalancer
 	// DefaultExcludeMasterFromStandardLB determines the aks-engine provided default for excluding master nodes from standard load balancer.
 	DefaultExcludeMasterFromStandardLB = true
 	// DefaultSecureKubeletEnabled determines the aks-engine provided default for securing kubelet communications
@@ -203,6 +222,8 @@ const (
 	CalicoAddonName = "calico-daemonset"
 	// IPMASQAgentAddonName is the name of the ip masq agent addon
 	IPMASQAgentAddonName = "ip-masq-agent"
+	// NodeTerminationHandlerAddonName is the name of the addon draining the nodes of Low-priority VM ScaleSets before their eviction
+	NodeTerminationHandlerAddonName = "node-termination-handler"
 	// PodSecurityPolicyAddonName is the name of the PodSecurityPolicy addon
 	PodSecurityPolicyAddonName = "pod-security-policy"
 	// DefaultPrivateClusterEnabled determines the aks-engine provided default for enabling kubernetes Private Cluster
@@ -215,6 +236,10 @@ const (
 	NetworkPluginKubenet = "kubenet"
 	// NetworkPluginAzure is the string expression for Azure CNI plugin.
 	NetworkPluginAzure = "azure"
+	// IPFamilyIPv4 is the string expression for the IPv4 family of the ipFamilies config
+	IPFamilyIPv4 = "IPv4"
+	// IPFamilyIPv6 is the string expression for the IPv6 family of the ipFamilies config
+	IPFamilyIPv6 = "IPv6"
 	// DefaultSinglePlacementGroup determines the aks-engine provided default for supporting large VMSS
 	// (true = single placement group 0-100 VMs, false = multiple placement group 0-1000 VMs)
 	DefaultSinglePlacementGroup = true
@@ -342,6 +367,9 @@ const (
 	// CNIPluginVer specifies the version of CNI implementation
 	// https://github.com/containernetworking/plugins
 	CNIPluginVer = "v0.7.5"
+	// WindowsContainerdVer specifies the version of the containerd package for Windows nodes, which is mirrored
+	// to https://acs-mirror.azureedge.net/containerd/windows
+	WindowsContainerdVer = "v1.3.4"
 )
 
 const (
@@ -533,3 +561,11 @@ const TLSStrongCipherSuitesAPIServer = "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS
 
 // TLSStrongCipherSuitesKubelet is a kube-bench-recommended allowed cipher suites for kubelet
 const TLSStrongCipherSuitesKubelet = "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_256_GCM_SHA384,TLS_RSA_WITH_AES_128_GCM_SHA256"
+
+// Overlay types
+const (
+	// OverlayTypeStrategicMerge patches a manifest with a Kubernetes strategic merge patch
+	OverlayTypeStrategicMerge = "strategicMerge"
+	// OverlayTypeJSON6902 patches a manifest with JSON patch (RFC 6902) operations
+	OverlayTypeJSON6902 = "json6902"
+)
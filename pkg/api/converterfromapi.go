@@ -177,6 +177,10 @@ func ConvertOrchestratorVersionProfileToVLabs(api *OrchestratorVersionProfile) *
 			}
 		}
 	}
+	if api.Addons != nil {
+		vlabsProfile.Addons = make([]string, len(api.Addons))
+		copy(vlabsProfile.Addons, api.Addons)
+	}
 	return vlabsProfile
 }
 
@@ -605,6 +609,15 @@ func convertWindowsProfileToVLabs(api *WindowsProfile, vlabsProfile *vlabs.Windo
 	vlabsProfile.WindowsOffer = api.WindowsOffer
 	vlabsProfile.WindowsSku = api.WindowsSku
 	vlabsProfile.WindowsDockerVersion = api.WindowsDockerVersion
+	vlabsProfile.WindowsContainerdURL = api.WindowsContainerdURL
+	if api.ImageRef != nil {
+		vlabsProfile.ImageRef = &vlabs.ImageReference{}
+		vlabsProfile.ImageRef.Name = api.ImageRef.Name
+		vlabsProfile.ImageRef.ResourceGroup = api.ImageRef.ResourceGroup
+		vlabsProfile.ImageRef.SubscriptionID = api.ImageRef.SubscriptionID
+		vlabsProfile.ImageRef.Gallery = api.ImageRef.Gallery
+		vlabsProfile.ImageRef.Version = api.ImageRef.Version
+	}
 	vlabsProfile.Secrets = []vlabs.KeyVaultSecrets{}
 	for _, s := range api.Secrets {
 		secret := &vlabs.KeyVaultSecrets{}
@@ -706,6 +719,7 @@ func convertKubernetesConfigToVLabs(apiCfg *KubernetesConfig, vlabsCfg *vlabs.Ku
 	vlabsCfg.ClusterSubnet = apiCfg.ClusterSubnet
 	vlabsCfg.DNSServiceIP = apiCfg.DNSServiceIP
 	vlabsCfg.ServiceCidr = apiCfg.ServiceCIDR
+	vlabsCfg.IPFamilies = apiCfg.IPFamilies
 	vlabsCfg.NetworkPolicy = apiCfg.NetworkPolicy
 	vlabsCfg.NetworkPlugin = apiCfg.NetworkPlugin
 	vlabsCfg.ContainerRuntime = apiCfg.ContainerRuntime
@@ -753,6 +767,9 @@ func convertKubernetesConfigToVLabs(apiCfg *KubernetesConfig, vlabsCfg *vlabs.Ku
 	vlabsCfg.ProxyMode = vlabs.KubeProxyMode(apiCfg.ProxyMode)
 	vlabsCfg.PrivateAzureRegistryServer = apiCfg.PrivateAzureRegistryServer
 	vlabsCfg.OutboundRuleIdleTimeoutInMinutes = apiCfg.OutboundRuleIdleTimeoutInMinutes
+	vlabsCfg.OutboundType = apiCfg.OutboundType
+	vlabsCfg.OutboundNextHopIPAddress = apiCfg.OutboundNextHopIPAddress
+	vlabsCfg.PackageMirrorURL = apiCfg.PackageMirrorURL
 	convertAddonsToVlabs(apiCfg, vlabsCfg)
 	convertKubeletConfigToVlabs(apiCfg, vlabsCfg)
 	convertControllerManagerConfigToVlabs(apiCfg, vlabsCfg)
@@ -761,6 +778,8 @@ func convertKubernetesConfigToVLabs(apiCfg *KubernetesConfig, vlabsCfg *vlabs.Ku
 	convertSchedulerConfigToVlabs(apiCfg, vlabsCfg)
 	convertPrivateClusterToVlabs(apiCfg, vlabsCfg)
 	convertPodSecurityPolicyConfigToVlabs(apiCfg, vlabsCfg)
+	convertManifestOverlaysToVlabs(apiCfg, vlabsCfg)
+	convertAuditProfileToVlabs(apiCfg, vlabsCfg)
 }
 
 func convertKubeletConfigToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig) {
@@ -821,6 +840,7 @@ func convertPrivateClusterToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig
 	if a.PrivateCluster != nil {
 		v.PrivateCluster = &vlabs.PrivateCluster{}
 		v.PrivateCluster.Enabled = a.PrivateCluster.Enabled
+		v.PrivateCluster.EnablePrivateDNSZone = a.PrivateCluster.EnablePrivateDNSZone
 		if a.PrivateCluster.JumpboxProfile != nil {
 			v.PrivateCluster.JumpboxProfile = &vlabs.PrivateJumpboxProfile{}
 			convertPrivateJumpboxProfileToVlabs(a.PrivateCluster.JumpboxProfile, v.PrivateCluster.JumpboxProfile)
@@ -845,7 +865,9 @@ func convertAddonsToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig) {
 			Enabled: a.Addons[i].Enabled,
 			Config:  map[string]string{},
 			Data:    a.Addons[i].Data,
+			Chart:   convertAddonChartToVlabs(a.Addons[i].Chart),
 		})
+		v.Addons[i].Overlays = convertOverlaysToVlabs(a.Addons[i].Overlays)
 		for j := range a.Addons[i].Containers {
 			v.Addons[i].Containers = append(v.Addons[i].Containers, vlabs.KubernetesContainerSpec{
 				Name:           a.Addons[i].Containers[j].Name,
@@ -863,6 +885,34 @@ func convertAddonsToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig) {
 			}
 		}
 	}
+	v.AddonDescriptors = nil
+	for _, d := range a.AddonDescriptors {
+		descriptor := vlabs.AddonDescriptor{
+			Name:               d.AddonName,
+			Enabled:            d.Enabled,
+			KubernetesVersions: d.SupportedVersions,
+			DestinationFile:    d.DestinationFile,
+			Manifest:           d.ManifestTemplate,
+		}
+		for _, c := range d.Containers {
+			descriptor.Containers = append(descriptor.Containers, vlabs.KubernetesContainerSpec{
+				Name:           c.Name,
+				Image:          c.Image,
+				CPURequests:    c.CPURequests,
+				MemoryRequests: c.MemoryRequests,
+				CPULimits:      c.CPULimits,
+				MemoryLimits:   c.MemoryLimits,
+			})
+		}
+		if d.Config != nil {
+			descriptor.Config = map[string]string{}
+			for key, val := range d.Config {
+				descriptor.Config[key] = val
+			}
+		}
+		descriptor.RequiredConfig = append(descriptor.RequiredConfig, d.RequiredConfig...)
+		v.AddonDescriptors = append(v.AddonDescriptors, descriptor)
+	}
 }
 
 func convertMasterProfileToV20160930(api *MasterProfile, v20160930 *v20160930.MasterProfile) {
@@ -908,6 +958,8 @@ func convertMasterProfileToVLabs(api *MasterProfile, vlabsProfile *vlabs.MasterP
 	vlabsProfile.VnetSubnetID = api.VnetSubnetID
 	vlabsProfile.AgentVnetSubnetID = api.AgentVnetSubnetID
 	vlabsProfile.FirstConsecutiveStaticIP = api.FirstConsecutiveStaticIP
+	vlabsProfile.RouteTableID = api.RouteTableID
+	vlabsProfile.NetworkSecurityGroupID = api.NetworkSecurityGroupID
 	vlabsProfile.VnetCidr = api.VnetCidr
 	vlabsProfile.SetSubnet(api.Subnet)
 	vlabsProfile.SetSubnetIPv6(api.SubnetIPv6)
@@ -1015,10 +1067,13 @@ func convertAgentPoolProfileToVLabs(api *AgentPoolProfile, p *vlabs.AgentPoolPro
 	p.AvailabilityProfile = api.AvailabilityProfile
 	p.ScaleSetPriority = api.ScaleSetPriority
 	p.ScaleSetEvictionPolicy = api.ScaleSetEvictionPolicy
+	p.FallbackPool = api.FallbackPool
 	p.StorageProfile = api.StorageProfile
 	p.DiskSizesGB = []int{}
 	p.DiskSizesGB = append(p.DiskSizesGB, api.DiskSizesGB...)
 	p.VnetSubnetID = api.VnetSubnetID
+	p.RouteTableID = api.RouteTableID
+	p.NetworkSecurityGroupID = api.NetworkSecurityGroupID
 	p.SetSubnet(api.Subnet)
 	p.FQDN = api.FQDN
 	p.CustomNodeLabels = map[string]string{}
@@ -1180,6 +1235,32 @@ func convertCertificateProfileToVLabs(api *CertificateProfile, vlabs *vlabs.Cert
 	vlabs.EtcdClientPrivateKey = api.EtcdClientPrivateKey
 	vlabs.EtcdPeerCertificates = api.EtcdPeerCertificates
 	vlabs.EtcdPeerPrivateKeys = api.EtcdPeerPrivateKeys
+	vlabs.PKIProfile = convertPKIProfileToVLabs(api.PKIProfile)
+}
+
+func convertPKIProfileToVLabs(api *PKIProfile) *vlabs.PKIProfile {
+	if api == nil {
+		return nil
+	}
+	return &vlabs.PKIProfile{
+		CA:         convertPKICertificateProfileToVLabs(api.CA),
+		APIServer:  convertPKICertificateProfileToVLabs(api.APIServer),
+		Client:     convertPKICertificateProfileToVLabs(api.Client),
+		EtcdServer: convertPKICertificateProfileToVLabs(api.EtcdServer),
+		EtcdClient: convertPKICertificateProfileToVLabs(api.EtcdClient),
+		EtcdPeer:   convertPKICertificateProfileToVLabs(api.EtcdPeer),
+	}
+}
+
+func convertPKICertificateProfileToVLabs(api *PKICertificateProfile) *vlabs.PKICertificateProfile {
+	if api == nil {
+		return nil
+	}
+	return &vlabs.PKICertificateProfile{
+		KeyAlgorithm: api.KeyAlgorithm,
+		KeySize:      api.KeySize,
+		ValidityDays: api.ValidityDays,
+	}
 }
 
 func convertAADProfileToVLabs(api *AADProfile, vlabs *vlabs.AADProfile) {
@@ -1265,6 +1346,7 @@ func convertAzureEnvironmentSpecConfigToVLabs(api *AzureEnvironmentSpecConfig, v
 		VnetCNILinuxPluginsDownloadURL:   api.KubernetesSpecConfig.VnetCNILinuxPluginsDownloadURL,
 		VnetCNIWindowsPluginsDownloadURL: api.KubernetesSpecConfig.VnetCNIWindowsPluginsDownloadURL,
 		ContainerdDownloadURLBase:        api.KubernetesSpecConfig.ContainerdDownloadURLBase,
+		WindowsContainerdDownloadURL:     api.KubernetesSpecConfig.WindowsContainerdDownloadURL,
 	}
 	vlabses.OSImageConfig = map[vlabs.Distro]vlabs.AzureOSImageConfig{}
 	for k, v := range api.OSImageConfig {
@@ -1276,3 +1358,69 @@ func convertAzureEnvironmentSpecConfigToVLabs(api *AzureEnvironmentSpecConfig, v
 		}
 	}
 }
+
+func convertAddonChartToVlabs(a *KubernetesAddonChart) *vlabs.KubernetesAddonChart {
+	if a == nil {
+		return nil
+	}
+	v := &vlabs.KubernetesAddonChart{
+		Path:        a.Path,
+		Version:     a.Version,
+		ReleaseName: a.ReleaseName,
+		Namespace:   a.Namespace,
+	}
+	if a.Values != nil {
+		v.Values = map[string]interface{}{}
+		for key, val := range a.Values {
+			v.Values[key] = val
+		}
+	}
+	return v
+}
+
+func convertManifestOverlaysToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig) {
+	if a.ManifestOverlays == nil {
+		return
+	}
+	v.ManifestOverlays = map[string][]vlabs.KubernetesOverlay{}
+	for component, overlays := range a.ManifestOverlays {
+		v.ManifestOverlays[component] = convertOverlaysToVlabs(overlays)
+	}
+}
+
+func convertAuditProfileToVlabs(a *KubernetesConfig, v *vlabs.KubernetesConfig) {
+	if a.AuditProfile == nil {
+		return
+	}
+	v.AuditProfile = &vlabs.AuditProfile{
+		Policy:            a.AuditProfile.Policy,
+		PolicyFile:        a.AuditProfile.PolicyFile,
+		LogMaxAge:         a.AuditProfile.LogMaxAge,
+		LogMaxBackup:      a.AuditProfile.LogMaxBackup,
+		LogMaxSize:        a.AuditProfile.LogMaxSize,
+		WebhookURL:        a.AuditProfile.WebhookURL,
+		WebhookKubeconfig: a.AuditProfile.WebhookKubeconfig,
+	}
+}
+
+func convertOverlaysToVlabs(a []KubernetesOverlay) []vlabs.KubernetesOverlay {
+	if a == nil {
+		return nil
+	}
+	v := []vlabs.KubernetesOverlay{}
+	for i := range a {
+		overlay := vlabs.KubernetesOverlay{
+			Type:  a[i].Type,
+			Patch: a[i].Patch,
+		}
+		if a[i].Target != nil {
+			overlay.Target = &vlabs.KubernetesOverlayTarget{
+				Kind:      a[i].Target.Kind,
+				Name:      a[i].Target.Name,
+				Namespace: a[i].Target.Namespace,
+			}
+		}
+		v = append(v, overlay)
+	}
+	return v
+}
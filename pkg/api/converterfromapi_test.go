@@ -169,6 +169,7 @@ func TestConvertAzureEnvironmentSpecConfigToVLabs(t *testing.T) {
 						VnetCNILinuxPluginsDownloadURL:   "VnetCNILinuxPluginsDownloadURL",
 						VnetCNIWindowsPluginsDownloadURL: "VnetCNIWindowsPluginsDownloadURL",
 						ContainerdDownloadURLBase:        "ContainerdDownloadURLBase",
+						WindowsContainerdDownloadURL:     "WindowsContainerdDownloadURL",
 					},
 					DCOSSpecConfig: DCOSSpecConfig{
 						DCOS188BootstrapDownloadURL:     "DCOS188BootstrapDownloadURL",
@@ -248,6 +249,9 @@ func TestConvertAzureEnvironmentSpecConfigToVLabs(t *testing.T) {
 	if vlabscsSpec.KubernetesSpecConfig.ContainerdDownloadURLBase != csSpec.KubernetesSpecConfig.ContainerdDownloadURLBase {
 		t.Errorf("incorrect ContainerdDownloadURLBase, expect: '%s', actual: '%s'", csSpec.KubernetesSpecConfig.ContainerdDownloadURLBase, vlabscsSpec.KubernetesSpecConfig.ContainerdDownloadURLBase)
 	}
+	if vlabscsSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL != csSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL {
+		t.Errorf("incorrect WindowsContainerdDownloadURL, expect: '%s', actual: '%s'", csSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL, vlabscsSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL)
+	}
 
 	//DockerSpecConfig
 	if vlabscsSpec.DockerSpecConfig.DockerComposeDownloadURL != csSpec.DockerSpecConfig.DockerComposeDownloadURL {
@@ -536,6 +536,15 @@ func convertVLabsWindowsProfile(vlabs *vlabs.WindowsProfile, api *WindowsProfile
 	api.WindowsOffer = vlabs.WindowsOffer
 	api.WindowsSku = vlabs.WindowsSku
 	api.WindowsDockerVersion = vlabs.WindowsDockerVersion
+	api.WindowsContainerdURL = vlabs.WindowsContainerdURL
+	if vlabs.ImageRef != nil {
+		api.ImageRef = &ImageReference{}
+		api.ImageRef.Name = vlabs.ImageRef.Name
+		api.ImageRef.ResourceGroup = vlabs.ImageRef.ResourceGroup
+		api.ImageRef.SubscriptionID = vlabs.ImageRef.SubscriptionID
+		api.ImageRef.Gallery = vlabs.ImageRef.Gallery
+		api.ImageRef.Version = vlabs.ImageRef.Version
+	}
 	api.Secrets = []KeyVaultSecrets{}
 	for _, s := range vlabs.Secrets {
 		secret := &KeyVaultSecrets{}
@@ -672,6 +681,7 @@ func convertVLabsKubernetesConfig(vlabs *vlabs.KubernetesConfig, api *Kubernetes
 	api.ClusterSubnet = vlabs.ClusterSubnet
 	api.DNSServiceIP = vlabs.DNSServiceIP
 	api.ServiceCIDR = vlabs.ServiceCidr
+	api.IPFamilies = vlabs.IPFamilies
 	api.NetworkPlugin = vlabs.NetworkPlugin
 	api.ContainerRuntime = vlabs.ContainerRuntime
 	api.MaxPods = vlabs.MaxPods
@@ -718,6 +728,9 @@ func convertVLabsKubernetesConfig(vlabs *vlabs.KubernetesConfig, api *Kubernetes
 	api.ProxyMode = KubeProxyMode(vlabs.ProxyMode)
 	api.PrivateAzureRegistryServer = vlabs.PrivateAzureRegistryServer
 	api.OutboundRuleIdleTimeoutInMinutes = vlabs.OutboundRuleIdleTimeoutInMinutes
+	api.OutboundType = vlabs.OutboundType
+	api.OutboundNextHopIPAddress = vlabs.OutboundNextHopIPAddress
+	api.PackageMirrorURL = vlabs.PackageMirrorURL
 	convertAddonsToAPI(vlabs, api)
 	convertKubeletConfigToAPI(vlabs, api)
 	convertControllerManagerConfigToAPI(vlabs, api)
@@ -726,6 +739,8 @@ func convertVLabsKubernetesConfig(vlabs *vlabs.KubernetesConfig, api *Kubernetes
 	convertSchedulerConfigToAPI(vlabs, api)
 	convertPrivateClusterToAPI(vlabs, api)
 	convertPodSecurityPolicyConfigToAPI(vlabs, api)
+	convertManifestOverlaysToAPI(vlabs, api)
+	convertAuditProfileToAPI(vlabs, api)
 }
 
 func setVlabsKubernetesDefaults(vp *vlabs.Properties, api *OrchestratorProfile) {
@@ -764,7 +779,9 @@ func convertAddonsToAPI(v *vlabs.KubernetesConfig, a *KubernetesConfig) {
 			Enabled: v.Addons[i].Enabled,
 			Config:  map[string]string{},
 			Data:    v.Addons[i].Data,
+			Chart:   convertAddonChartToAPI(v.Addons[i].Chart),
 		})
+		a.Addons[i].Overlays = convertOverlaysToAPI(v.Addons[i].Overlays)
 		for j := range v.Addons[i].Containers {
 			a.Addons[i].Containers = append(a.Addons[i].Containers, KubernetesContainerSpec{
 				Name:           v.Addons[i].Containers[j].Name,
@@ -782,6 +799,34 @@ func convertAddonsToAPI(v *vlabs.KubernetesConfig, a *KubernetesConfig) {
 			}
 		}
 	}
+	a.AddonDescriptors = nil
+	for _, d := range v.AddonDescriptors {
+		descriptor := FileAddon{
+			AddonName:         d.Name,
+			Enabled:           d.Enabled,
+			SupportedVersions: d.KubernetesVersions,
+			DestinationFile:   d.DestinationFile,
+			ManifestTemplate:  d.Manifest,
+		}
+		for _, c := range d.Containers {
+			descriptor.Containers = append(descriptor.Containers, KubernetesContainerSpec{
+				Name:           c.Name,
+				Image:          c.Image,
+				CPURequests:    c.CPURequests,
+				MemoryRequests: c.MemoryRequests,
+				CPULimits:      c.CPULimits,
+				MemoryLimits:   c.MemoryLimits,
+			})
+		}
+		if d.Config != nil {
+			descriptor.Config = map[string]string{}
+			for key, val := range d.Config {
+				descriptor.Config[key] = val
+			}
+		}
+		descriptor.RequiredConfig = append(descriptor.RequiredConfig, d.RequiredConfig...)
+		a.AddonDescriptors = append(a.AddonDescriptors, descriptor)
+	}
 }
 
 func convertCustomFilesToAPI(v *vlabs.MasterProfile, a *MasterProfile) {
@@ -842,6 +887,7 @@ func convertPrivateClusterToAPI(v *vlabs.KubernetesConfig, a *KubernetesConfig)
 	if v.PrivateCluster != nil {
 		a.PrivateCluster = &PrivateCluster{}
 		a.PrivateCluster.Enabled = v.PrivateCluster.Enabled
+		a.PrivateCluster.EnablePrivateDNSZone = v.PrivateCluster.EnablePrivateDNSZone
 		if v.PrivateCluster.JumpboxProfile != nil {
 			a.PrivateCluster.JumpboxProfile = &PrivateJumpboxProfile{}
 			convertPrivateJumpboxProfileToAPI(v.PrivateCluster.JumpboxProfile, a.PrivateCluster.JumpboxProfile)
@@ -911,6 +957,8 @@ func convertVLabsMasterProfile(vlabs *vlabs.MasterProfile, api *MasterProfile) {
 	api.VnetSubnetID = vlabs.VnetSubnetID
 	api.AgentVnetSubnetID = vlabs.AgentVnetSubnetID
 	api.FirstConsecutiveStaticIP = vlabs.FirstConsecutiveStaticIP
+	api.RouteTableID = vlabs.RouteTableID
+	api.NetworkSecurityGroupID = vlabs.NetworkSecurityGroupID
 	api.VnetCidr = vlabs.VnetCidr
 	api.Subnet = vlabs.GetSubnet()
 	api.SubnetIPv6 = vlabs.GetSubnetIPv6()
@@ -1033,10 +1081,13 @@ func convertVLabsAgentPoolProfile(vlabs *vlabs.AgentPoolProfile, api *AgentPoolP
 	api.AvailabilityProfile = vlabs.AvailabilityProfile
 	api.ScaleSetPriority = vlabs.ScaleSetPriority
 	api.ScaleSetEvictionPolicy = vlabs.ScaleSetEvictionPolicy
+	api.FallbackPool = vlabs.FallbackPool
 	api.StorageProfile = vlabs.StorageProfile
 	api.DiskSizesGB = []int{}
 	api.DiskSizesGB = append(api.DiskSizesGB, vlabs.DiskSizesGB...)
 	api.VnetSubnetID = vlabs.VnetSubnetID
+	api.RouteTableID = vlabs.RouteTableID
+	api.NetworkSecurityGroupID = vlabs.NetworkSecurityGroupID
 	api.Subnet = vlabs.GetSubnet()
 	api.IPAddressCount = vlabs.IPAddressCount
 	api.FQDN = vlabs.FQDN
@@ -1212,6 +1263,32 @@ func convertVLabsCertificateProfile(vlabs *vlabs.CertificateProfile, api *Certif
 	api.EtcdClientPrivateKey = vlabs.EtcdClientPrivateKey
 	api.EtcdPeerCertificates = vlabs.EtcdPeerCertificates
 	api.EtcdPeerPrivateKeys = vlabs.EtcdPeerPrivateKeys
+	api.PKIProfile = convertVLabsPKIProfile(vlabs.PKIProfile)
+}
+
+func convertVLabsPKIProfile(vlabs *vlabs.PKIProfile) *PKIProfile {
+	if vlabs == nil {
+		return nil
+	}
+	return &PKIProfile{
+		CA:         convertVLabsPKICertificateProfile(vlabs.CA),
+		APIServer:  convertVLabsPKICertificateProfile(vlabs.APIServer),
+		Client:     convertVLabsPKICertificateProfile(vlabs.Client),
+		EtcdServer: convertVLabsPKICertificateProfile(vlabs.EtcdServer),
+		EtcdClient: convertVLabsPKICertificateProfile(vlabs.EtcdClient),
+		EtcdPeer:   convertVLabsPKICertificateProfile(vlabs.EtcdPeer),
+	}
+}
+
+func convertVLabsPKICertificateProfile(vlabs *vlabs.PKICertificateProfile) *PKICertificateProfile {
+	if vlabs == nil {
+		return nil
+	}
+	return &PKICertificateProfile{
+		KeyAlgorithm: vlabs.KeyAlgorithm,
+		KeySize:      vlabs.KeySize,
+		ValidityDays: vlabs.ValidityDays,
+	}
 }
 
 func convertVLabsAADProfile(vlabs *vlabs.AADProfile, api *AADProfile) {
@@ -1316,6 +1393,7 @@ func convertAzureEnvironmentSpecConfig(vlabses *vlabs.AzureEnvironmentSpecConfig
 		VnetCNILinuxPluginsDownloadURL:   vlabses.KubernetesSpecConfig.VnetCNILinuxPluginsDownloadURL,
 		VnetCNIWindowsPluginsDownloadURL: vlabses.KubernetesSpecConfig.VnetCNIWindowsPluginsDownloadURL,
 		ContainerdDownloadURLBase:        vlabses.KubernetesSpecConfig.ContainerdDownloadURLBase,
+		WindowsContainerdDownloadURL:     vlabses.KubernetesSpecConfig.WindowsContainerdDownloadURL,
 	}
 	api.OSImageConfig = map[Distro]AzureOSImageConfig{}
 	for k, v := range vlabses.OSImageConfig {
@@ -1327,3 +1405,69 @@ func convertAzureEnvironmentSpecConfig(vlabses *vlabs.AzureEnvironmentSpecConfig
 		}
 	}
 }
+
+func convertAddonChartToAPI(v *vlabs.KubernetesAddonChart) *KubernetesAddonChart {
+	if v == nil {
+		return nil
+	}
+	a := &KubernetesAddonChart{
+		Path:        v.Path,
+		Version:     v.Version,
+		ReleaseName: v.ReleaseName,
+		Namespace:   v.Namespace,
+	}
+	if v.Values != nil {
+		a.Values = map[string]interface{}{}
+		for key, val := range v.Values {
+			a.Values[key] = val
+		}
+	}
+	return a
+}
+
+func convertManifestOverlaysToAPI(v *vlabs.KubernetesConfig, a *KubernetesConfig) {
+	if v.ManifestOverlays == nil {
+		return
+	}
+	a.ManifestOverlays = map[string][]KubernetesOverlay{}
+	for component, overlays := range v.ManifestOverlays {
+		a.ManifestOverlays[component] = convertOverlaysToAPI(overlays)
+	}
+}
+
+func convertAuditProfileToAPI(v *vlabs.KubernetesConfig, a *KubernetesConfig) {
+	if v.AuditProfile == nil {
+		return
+	}
+	a.AuditProfile = &AuditProfile{
+		Policy:            v.AuditProfile.Policy,
+		PolicyFile:        v.AuditProfile.PolicyFile,
+		LogMaxAge:         v.AuditProfile.LogMaxAge,
+		LogMaxBackup:      v.AuditProfile.LogMaxBackup,
+		LogMaxSize:        v.AuditProfile.LogMaxSize,
+		WebhookURL:        v.AuditProfile.WebhookURL,
+		WebhookKubeconfig: v.AuditProfile.WebhookKubeconfig,
+	}
+}
+
+func convertOverlaysToAPI(v []vlabs.KubernetesOverlay) []KubernetesOverlay {
+	if v == nil {
+		return nil
+	}
+	a := []KubernetesOverlay{}
+	for i := range v {
+		overlay := KubernetesOverlay{
+			Type:  v[i].Type,
+			Patch: v[i].Patch,
+		}
+		if v[i].Target != nil {
+			overlay.Target = &KubernetesOverlayTarget{
+				Kind:      v[i].Target.Kind,
+				Name:      v[i].Target.Name,
+				Namespace: v[i].Target.Namespace,
+			}
+		}
+		a = append(a, overlay)
+	}
+	return a
+}
@@ -5,6 +5,7 @@ package api
 
 import (
 	"net/url"
+	"reflect"
 	"testing"
 
 	"github.com/Azure/go-autorest/autorest/to"
@@ -428,6 +429,7 @@ func TestConvertAzureEnvironmentSpecConfig(t *testing.T) {
 						VnetCNILinuxPluginsDownloadURL:   "VnetCNILinuxPluginsDownloadURL",
 						VnetCNIWindowsPluginsDownloadURL: "VnetCNIWindowsPluginsDownloadURL",
 						ContainerdDownloadURLBase:        "ContainerdDownloadURLBase",
+						WindowsContainerdDownloadURL:     "WindowsContainerdDownloadURL",
 					},
 					DCOSSpecConfig: vlabs.DCOSSpecConfig{
 						DCOS188BootstrapDownloadURL:     "DCOS188BootstrapDownloadURL",
@@ -511,6 +513,9 @@ func TestConvertAzureEnvironmentSpecConfig(t *testing.T) {
 	if csSpec.KubernetesSpecConfig.ContainerdDownloadURLBase != vlabscsSpec.KubernetesSpecConfig.ContainerdDownloadURLBase {
 		t.Errorf("incorrect ContainerdDownloadURLBase, expect: '%s', actual: '%s'", vlabscsSpec.KubernetesSpecConfig.ContainerdDownloadURLBase, csSpec.KubernetesSpecConfig.ContainerdDownloadURLBase)
 	}
+	if csSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL != vlabscsSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL {
+		t.Errorf("incorrect WindowsContainerdDownloadURL, expect: '%s', actual: '%s'", vlabscsSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL, csSpec.KubernetesSpecConfig.WindowsContainerdDownloadURL)
+	}
 
 	//DockerSpecConfig
 	if csSpec.DockerSpecConfig.DockerComposeDownloadURL != vlabscsSpec.DockerSpecConfig.DockerComposeDownloadURL {
@@ -1015,3 +1020,192 @@ func TestConvertVLabsContainerService(t *testing.T) {
 	}
 
 }
+
+func TestConvertVLabsPKIProfile(t *testing.T) {
+	vlabsProfile := &vlabs.CertificateProfile{
+		CaCertificate: "SampleCACert",
+		PKIProfile: &vlabs.PKIProfile{
+			CA:         &vlabs.PKICertificateProfile{KeyAlgorithm: "RSA", KeySize: 4096, ValidityDays: 3650},
+			APIServer:  &vlabs.PKICertificateProfile{KeyAlgorithm: "ECDSA", KeySize: 256, ValidityDays: 365},
+			EtcdServer: &vlabs.PKICertificateProfile{KeyAlgorithm: "ECDSA"},
+		},
+	}
+	apiProfile := &CertificateProfile{}
+	convertVLabsCertificateProfile(vlabsProfile, apiProfile)
+	expected := &PKIProfile{
+		CA:         &PKICertificateProfile{KeyAlgorithm: "RSA", KeySize: 4096, ValidityDays: 3650},
+		APIServer:  &PKICertificateProfile{KeyAlgorithm: "ECDSA", KeySize: 256, ValidityDays: 365},
+		EtcdServer: &PKICertificateProfile{KeyAlgorithm: "ECDSA"},
+	}
+	if !equality.Semantic.DeepEqual(apiProfile.PKIProfile, expected) {
+		t.Errorf("expected pkiProfile %+v, got %+v", expected, apiProfile.PKIProfile)
+	}
+
+	roundTrip := &vlabs.CertificateProfile{}
+	convertCertificateProfileToVLabs(apiProfile, roundTrip)
+	if !equality.Semantic.DeepEqual(roundTrip, vlabsProfile) {
+		t.Errorf("expected certificateProfile %+v after converting back to vlabs, got %+v", vlabsProfile, roundTrip)
+	}
+
+	apiProfile = &CertificateProfile{}
+	convertVLabsCertificateProfile(&vlabs.CertificateProfile{}, apiProfile)
+	if apiProfile.PKIProfile != nil {
+		t.Errorf("expected no pkiProfile, got %+v", apiProfile.PKIProfile)
+	}
+}
+
+func TestConvertAddonChart(t *testing.T) {
+	v := &vlabs.KubernetesConfig{
+		Addons: []vlabs.KubernetesAddon{
+			{
+				Name:    "hello",
+				Enabled: to.BoolPtr(true),
+				Chart: &vlabs.KubernetesAddonChart{
+					Path:        "charts/hello-0.1.0.tgz",
+					Version:     "0.1.0",
+					ReleaseName: "greeter",
+					Namespace:   "hello-system",
+					Values:      map[string]interface{}{"replicaCount": 2},
+				},
+			},
+			{
+				Name: "no-chart",
+			},
+		},
+	}
+	a := &KubernetesConfig{}
+	convertAddonsToAPI(v, a)
+	if !reflect.DeepEqual(a.Addons[0].Chart, &KubernetesAddonChart{
+		Path:        "charts/hello-0.1.0.tgz",
+		Version:     "0.1.0",
+		ReleaseName: "greeter",
+		Namespace:   "hello-system",
+		Values:      map[string]interface{}{"replicaCount": 2},
+	}) {
+		t.Errorf("unexpected chart %+v", a.Addons[0].Chart)
+	}
+	if a.Addons[1].Chart != nil {
+		t.Errorf("expected addon without a chart to have a nil chart")
+	}
+
+	converted := &vlabs.KubernetesConfig{}
+	convertAddonsToVlabs(a, converted)
+	if !reflect.DeepEqual(converted.Addons[0].Chart, v.Addons[0].Chart) || converted.Addons[1].Chart != nil {
+		t.Errorf("expected chart to convert back to vlabs, got %+v", converted.Addons[0].Chart)
+	}
+}
+
+func TestConvertAddonDescriptors(t *testing.T) {
+	v := &vlabs.KubernetesConfig{
+		AddonDescriptors: []vlabs.AddonDescriptor{
+			{
+				Name:               "log-shipper",
+				Enabled:            true,
+				KubernetesVersions: ">=1.12.0",
+				Containers:         []vlabs.KubernetesContainerSpec{{Name: "log-shipper", Image: "example.azurecr.io/log-shipper:1.0"}},
+				Config:             map[string]string{"level": "info"},
+				RequiredConfig:     []string{"endpoint"},
+				DestinationFile:    "log-shipper-daemonset.yaml",
+				Manifest:           "kind: DaemonSet\n",
+			},
+		},
+	}
+	a := &KubernetesConfig{}
+	convertAddonsToAPI(v, a)
+	expected := []FileAddon{
+		{
+			AddonName:         "log-shipper",
+			Enabled:           true,
+			SupportedVersions: ">=1.12.0",
+			Containers:        []KubernetesContainerSpec{{Name: "log-shipper", Image: "example.azurecr.io/log-shipper:1.0"}},
+			Config:            map[string]string{"level": "info"},
+			RequiredConfig:    []string{"endpoint"},
+			DestinationFile:   "log-shipper-daemonset.yaml",
+			ManifestTemplate:  "kind: DaemonSet\n",
+		},
+	}
+	if !reflect.DeepEqual(a.AddonDescriptors, expected) {
+		t.Errorf("unexpected addon descriptors %+v", a.AddonDescriptors)
+	}
+
+	converted := &vlabs.KubernetesConfig{}
+	convertAddonsToVlabs(a, converted)
+	if !reflect.DeepEqual(converted.AddonDescriptors, v.AddonDescriptors) {
+		t.Errorf("expected addon descriptors to convert back to vlabs, got %+v", converted.AddonDescriptors)
+	}
+}
+
+func TestConvertOverlays(t *testing.T) {
+	overlays := []vlabs.KubernetesOverlay{
+		{
+			Patch: "kind: DaemonSet\nmetadata:\n  name: kube-proxy\n",
+		},
+		{
+			Type:   vlabs.OverlayTypeJSON6902,
+			Target: &vlabs.KubernetesOverlayTarget{Kind: "Pod", Name: "kube-apiserver", Namespace: "kube-system"},
+			Patch:  "- op: add\n  path: /spec/containers/0/args/-\n  value: --v=4\n",
+		},
+	}
+	v := &vlabs.KubernetesConfig{
+		Addons: []vlabs.KubernetesAddon{
+			{
+				Name:     "kube-proxy",
+				Overlays: overlays[:1],
+			},
+		},
+		ManifestOverlays: map[string][]vlabs.KubernetesOverlay{
+			"kube-apiserver": overlays[1:],
+		},
+	}
+	a := &KubernetesConfig{}
+	convertVLabsKubernetesConfig(v, a)
+	if !reflect.DeepEqual(a.Addons[0].Overlays, []KubernetesOverlay{{Patch: overlays[0].Patch}}) {
+		t.Errorf("unexpected addon overlays %+v", a.Addons[0].Overlays)
+	}
+	if !reflect.DeepEqual(a.ManifestOverlays, map[string][]KubernetesOverlay{
+		"kube-apiserver": {
+			{
+				Type:   OverlayTypeJSON6902,
+				Target: &KubernetesOverlayTarget{Kind: "Pod", Name: "kube-apiserver", Namespace: "kube-system"},
+				Patch:  overlays[1].Patch,
+			},
+		},
+	}) {
+		t.Errorf("unexpected manifest overlays %+v", a.ManifestOverlays)
+	}
+
+	converted := &vlabs.KubernetesConfig{}
+	convertKubernetesConfigToVLabs(a, converted)
+	if !reflect.DeepEqual(converted.Addons[0].Overlays, v.Addons[0].Overlays) || !reflect.DeepEqual(converted.ManifestOverlays, v.ManifestOverlays) {
+		t.Errorf("expected overlays to convert back to vlabs, got %+v and %+v", converted.Addons[0].Overlays, converted.ManifestOverlays)
+	}
+}
+
+func TestConvertVLabsAuditProfile(t *testing.T) {
+	v := &vlabs.KubernetesConfig{
+		AuditProfile: &vlabs.AuditProfile{
+			Policy:            "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n",
+			LogMaxAge:         to.IntPtr(7),
+			LogMaxBackup:      to.IntPtr(0),
+			LogMaxSize:        to.IntPtr(50),
+			WebhookKubeconfig: "kind: Config\n",
+		},
+	}
+	a := &KubernetesConfig{}
+	convertVLabsKubernetesConfig(v, a)
+	if !reflect.DeepEqual(a.AuditProfile, &AuditProfile{
+		Policy:            v.AuditProfile.Policy,
+		LogMaxAge:         to.IntPtr(7),
+		LogMaxBackup:      to.IntPtr(0),
+		LogMaxSize:        to.IntPtr(50),
+		WebhookKubeconfig: v.AuditProfile.WebhookKubeconfig,
+	}) {
+		t.Errorf("unexpected audit profile %+v", a.AuditProfile)
+	}
+
+	converted := &vlabs.KubernetesConfig{}
+	convertKubernetesConfigToVLabs(a, converted)
+	if !reflect.DeepEqual(converted.AuditProfile, v.AuditProfile) {
+		t.Errorf("expected the audit profile to convert back to vlabs, got %+v", converted.AuditProfile)
+	}
+}
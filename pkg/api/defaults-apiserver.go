@@ -87,7 +87,7 @@ func (cs *ContainerService) setAPIServerConfig() {
 
 	// Audit Policy configuration
 	if common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.8.0") {
-		defaultAPIServerConfig["--audit-policy-file"] = "/etc/kubernetes/addons/audit-policy.yaml"
+		defaultAPIServerConfig["--audit-policy-file"] = AuditPolicyFile
 	}
 
 	// RBAC configuration
@@ -108,6 +108,28 @@ func (cs *ContainerService) setAPIServerConfig() {
 	admissionControlKey, admissionControlValues := getDefaultAdmissionControls(cs)
 	defaultAPIServerConfig[admissionControlKey] = admissionControlValues
 
+	// The audit profile owns the audit log rotation options, the ones it does not set are reset to their default
+	if a := o.KubernetesConfig.AuditProfile; a != nil {
+		if o.KubernetesConfig.APIServerConfig == nil {
+			o.KubernetesConfig.APIServerConfig = map[string]string{}
+		}
+		auditLogRotation := []struct {
+			key   string
+			value *int
+		}{
+			{"--audit-log-maxage", a.LogMaxAge},
+			{"--audit-log-maxbackup", a.LogMaxBackup},
+			{"--audit-log-maxsize", a.LogMaxSize},
+		}
+		for _, option := range auditLogRotation {
+			if option.value != nil {
+				o.KubernetesConfig.APIServerConfig[option.key] = strconv.Itoa(*option.value)
+			} else {
+				delete(o.KubernetesConfig.APIServerConfig, option.key)
+			}
+		}
+	}
+
 	// If no user-configurable apiserver config values exists, use the defaults
 	if o.KubernetesConfig.APIServerConfig == nil {
 		o.KubernetesConfig.APIServerConfig = defaultAPIServerConfig
@@ -128,6 +150,22 @@ func (cs *ContainerService) setAPIServerConfig() {
 		}
 	}
 
+	// The audit profile overrides the audit options of the apiserver config, so that upgrade applies its changes
+	if o.KubernetesConfig.HasAuditPolicy() {
+		o.KubernetesConfig.APIServerConfig["--audit-policy-file"] = AuditPolicyFile
+	}
+	if o.KubernetesConfig.HasAuditWebhook() {
+		o.KubernetesConfig.APIServerConfig["--audit-webhook-config-file"] = AuditWebhookConfigFile
+	} else if o.KubernetesConfig.APIServerConfig["--audit-webhook-config-file"] == AuditWebhookConfigFile {
+		// the webhook was removed from the audit profile
+		delete(o.KubernetesConfig.APIServerConfig, "--audit-webhook-config-file")
+	}
+
+	// Enables the IPv4 and IPv6 service CIDRs of ipv6 dual stack clusters
+	if cs.Properties.IsIPv6DualStack() {
+		addDefaultFeatureGates(o.KubernetesConfig.APIServerConfig, o.OrchestratorVersion, "1.16.0-alpha.1", "IPv6DualStack=true")
+	}
+
 	// We don't support user-configurable values for the following,
 	// so any of the value assignments below will override user-provided values
 	for key, val := range staticAPIServerConfig {
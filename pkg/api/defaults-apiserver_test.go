@@ -434,6 +434,77 @@ func TestAPIServerAuditPolicyBackCompatOverride(t *testing.T) {
 	}
 }
 
+func TestAPIServerAuditProfile(t *testing.T) {
+	// Validate that the audit profile overrides the audit options of the apiserver config, e.g. the ones of an upgraded cluster
+	cs := CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig = map[string]string{
+		"--audit-log-maxage":    "30",
+		"--audit-log-maxbackup": "10",
+		"--audit-log-maxsize":   "100",
+		"--audit-policy-file":   "/etc/kubernetes/custom-audit-policy.yaml",
+	}
+	cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile = &AuditProfile{
+		Policy:       "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n",
+		LogMaxAge:    to.IntPtr(7),
+		LogMaxBackup: to.IntPtr(0),
+		WebhookURL:   "https://audit.example.com/events",
+	}
+	cs.setAPIServerConfig()
+	a := cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	expected := map[string]string{
+		"--audit-log-maxage":          "7",
+		"--audit-log-maxbackup":       "0",
+		"--audit-log-maxsize":         "100",
+		"--audit-policy-file":         AuditPolicyFile,
+		"--audit-webhook-config-file": AuditWebhookConfigFile,
+	}
+	for key, val := range expected {
+		if a[key] != val {
+			t.Fatalf("got unexpected '%s' API server config value with an audit profile: %s, expected: %s", key, a[key], val)
+		}
+	}
+
+	// Validate that removing the webhook from the audit profile removes its option, and that the apiserver config
+	// keeps its audit log rotation options without audit profile
+	cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile = nil
+	cs.setAPIServerConfig()
+	a = cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	if _, ok := a["--audit-webhook-config-file"]; ok {
+		t.Fatalf("got a '--audit-webhook-config-file' API server config value without audit webhook: %s", a["--audit-webhook-config-file"])
+	}
+	if a["--audit-log-maxage"] != "7" {
+		t.Fatalf("got unexpected '--audit-log-maxage' API server config value without audit profile: %s", a["--audit-log-maxage"])
+	}
+
+	// Validate that removing the log rotation from the audit profile resets its options to their default
+	cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile = &AuditProfile{
+		LogMaxBackup: to.IntPtr(5),
+	}
+	cs.setAPIServerConfig()
+	a = cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	expected = map[string]string{
+		"--audit-log-maxage":    "30",
+		"--audit-log-maxbackup": "5",
+		"--audit-log-maxsize":   "100",
+	}
+	for key, val := range expected {
+		if a[key] != val {
+			t.Fatalf("got unexpected '%s' API server config value with an audit profile without log rotation: %s, expected: %s", key, a[key], val)
+		}
+	}
+
+	// Validate that the audit profile sets its options when the apiserver config is empty
+	cs = CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.AuditProfile = &AuditProfile{
+		LogMaxSize: to.IntPtr(50),
+	}
+	cs.setAPIServerConfig()
+	a = cs.Properties.OrchestratorProfile.KubernetesConfig.APIServerConfig
+	if a["--audit-log-maxsize"] != "50" || a["--audit-log-maxage"] != "30" {
+		t.Fatalf("got unexpected audit log rotation API server config values with an empty apiserver config: %s, %s", a["--audit-log-maxsize"], a["--audit-log-maxage"])
+	}
+}
+
 func TestAPIServerWeakCipherSuites(t *testing.T) {
 	// Test allowed versions
 	for _, version := range []string{"1.10.0", "1.11.0", "1.12.0", "1.13.0", "1.14.0"} {
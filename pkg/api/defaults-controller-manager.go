@@ -67,6 +67,11 @@ func (cs *ContainerService) setControllerManagerConfig() {
 	// Enable the consumption of local ephemeral storage and also the sizeLimit property of an emptyDir volume.
 	addDefaultFeatureGates(o.KubernetesConfig.ControllerManagerConfig, o.OrchestratorVersion, "1.10.0", "LocalStorageCapacityIsolation=true")
 
+	// Enables the allocation of IPv4 and IPv6 pod CIDRs to the nodes of ipv6 dual stack clusters
+	if cs.Properties.IsIPv6DualStack() {
+		addDefaultFeatureGates(o.KubernetesConfig.ControllerManagerConfig, o.OrchestratorVersion, "1.16.0-alpha.1", "IPv6DualStack=true")
+	}
+
 	// We don't support user-configurable values for the following,
 	// so any of the value assignments below will override user-provided values
 	for key, val := range staticControllerManagerConfig {
@@ -121,6 +121,9 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 	setMissingKubeletValues(o.KubernetesConfig, defaultKubeletConfig)
 	addDefaultFeatureGates(o.KubernetesConfig.KubeletConfig, o.OrchestratorVersion, "1.8.0", "PodPriority=true")
 	addDefaultFeatureGates(o.KubernetesConfig.KubeletConfig, o.OrchestratorVersion, minVersionRotateCerts, "RotateKubeletServerCertificate=true")
+	if cs.Properties.IsIPv6DualStack() {
+		addDefaultFeatureGates(o.KubernetesConfig.KubeletConfig, o.OrchestratorVersion, "1.16.0-alpha.1", "IPv6DualStack=true")
+	}
 
 	// Override default cloud-provider?
 	if to.Bool(o.KubernetesConfig.UseCloudControllerManager) {
@@ -201,6 +204,11 @@ func (cs *ContainerService) setKubeletConfig(isUpgrade bool) {
 
 		setMissingKubeletValues(profile.KubernetesConfig, o.KubernetesConfig.KubeletConfig)
 
+		// Taint the nodes of low priority scale sets, so that only the pods tolerating their eviction are scheduled on them
+		if profile.IsLowPriorityScaleSet() {
+			addKubeletTaint(profile.KubernetesConfig.KubeletConfig, LowPriorityNodeTaint)
+		}
+
 		// For N Series (GPU) VMs
 		if strings.Contains(profile.VMSize, "Standard_N") {
 			if !cs.Properties.IsNVIDIADevicePluginEnabled() && !common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.11.0") {
@@ -259,7 +267,10 @@ func removeKubeletFlags(k map[string]string, v string) {
 
 func setMissingKubeletValues(p *KubernetesConfig, d map[string]string) {
 	if p.KubeletConfig == nil {
-		p.KubeletConfig = d
+		p.KubeletConfig = make(map[string]string, len(d))
+		for key, val := range d {
+			p.KubeletConfig[key] = val
+		}
 	} else {
 		for key, val := range d {
 			// If we don't have a user-configurable value for each option
@@ -270,3 +281,17 @@ func setMissingKubeletValues(p *KubernetesConfig, d map[string]string) {
 		}
 	}
 }
+
+// addKubeletTaint adds a taint to the taints registered by the kubelet, unless it is already registered
+func addKubeletTaint(k map[string]string, taint string) {
+	taints := k["--register-with-taints"]
+	for _, t := range strings.Split(taints, ",") {
+		if t == taint {
+			return
+		}
+	}
+	if taints != "" {
+		taints += ","
+	}
+	k["--register-with-taints"] = taints + taint
+}
@@ -1917,3 +1917,40 @@ func TestSupportPodPidsLimitFeatureGateInAgentPool(t *testing.T) {
 	}
 
 }
+
+func TestKubeletLowPriorityTaint(t *testing.T) {
+	cs := CreateMockContainerService("testcluster", defaultTestClusterVer, 3, 2, false)
+	cs.Properties.AgentPoolProfiles = []*AgentPoolProfile{
+		{
+			Name:                "regular",
+			AvailabilityProfile: VirtualMachineScaleSets,
+		},
+		{
+			Name:                "spot",
+			AvailabilityProfile: VirtualMachineScaleSets,
+			ScaleSetPriority:    ScaleSetPriorityLow,
+			KubernetesConfig: &KubernetesConfig{
+				KubeletConfig: map[string]string{
+					"--register-with-taints": "sku=gpu:NoSchedule",
+				},
+			},
+		},
+	}
+	cs.setKubeletConfig(false)
+	if taints, ok := cs.Properties.AgentPoolProfiles[0].KubernetesConfig.KubeletConfig["--register-with-taints"]; ok {
+		t.Fatalf("got unexpected '--register-with-taints' kubelet config value for a regular priority pool: %s", taints)
+	}
+	if taints := cs.Properties.OrchestratorProfile.KubernetesConfig.KubeletConfig["--register-with-taints"]; taints != "" {
+		t.Fatalf("got unexpected '--register-with-taints' kubelet config value for the cluster: %s", taints)
+	}
+	expected := "sku=gpu:NoSchedule," + LowPriorityNodeTaint
+	if taints := cs.Properties.AgentPoolProfiles[1].KubernetesConfig.KubeletConfig["--register-with-taints"]; taints != expected {
+		t.Fatalf("got unexpected '--register-with-taints' kubelet config value for a low priority pool: expected %s, got %s", expected, taints)
+	}
+
+	// the taint is not added twice on upgrade
+	cs.setKubeletConfig(true)
+	if taints := cs.Properties.AgentPoolProfiles[1].KubernetesConfig.KubeletConfig["--register-with-taints"]; taints != expected {
+		t.Fatalf("got unexpected '--register-with-taints' kubelet config value for a low priority pool after upgrade: expected %s, got %s", expected, taints)
+	}
+}
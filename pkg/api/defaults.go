@@ -5,10 +5,10 @@ package api
 
 import (
 	"bytes"
-	"crypto/rand"
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"sort"
 	"strconv"
@@ -169,6 +169,10 @@ func (cs *ContainerService) setOrchestratorDefaults(isUpgrade, isScale bool) {
 				o.KubernetesConfig.ContainerdVersion = DefaultContainerdVersion
 			}
 		}
+		// the EnableIPv6DualStack feature flag predates ipFamilies
+		if len(o.KubernetesConfig.IPFamilies) == 0 && cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+			o.KubernetesConfig.IPFamilies = []string{IPFamilyIPv4, IPFamilyIPv6}
+		}
 		if o.KubernetesConfig.ClusterSubnet == "" {
 			if o.IsAzureCNI() {
 				// When Azure CNI is enabled, all masters, agents and pods share the same large subnet.
@@ -177,13 +181,13 @@ func (cs *ContainerService) setOrchestratorDefaults(isUpgrade, isScale bool) {
 			} else {
 				o.KubernetesConfig.ClusterSubnet = DefaultKubernetesClusterSubnet
 				// ipv4 and ipv6 subnet for dual stack
-				if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+				if cs.Properties.IsIPv6DualStack() {
 					o.KubernetesConfig.ClusterSubnet = strings.Join([]string{DefaultKubernetesClusterSubnet, DefaultKubernetesClusterSubnetIPv6}, ",")
 				}
 			}
 		} else {
 			// ensure 2 subnets exists if ipv6 dual stack feature is enabled
-			if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") && !o.IsAzureCNI() {
+			if cs.Properties.IsIPv6DualStack() && !o.IsAzureCNI() {
 				clusterSubnets := strings.Split(o.KubernetesConfig.ClusterSubnet, ",")
 				if len(clusterSubnets) == 1 {
 					// if error exists, then it'll be caught by validate
@@ -336,6 +340,9 @@ func (cs *ContainerService) setOrchestratorDefaults(isUpgrade, isScale bool) {
 			a.OrchestratorProfile.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes == 0 {
 			a.OrchestratorProfile.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes = DefaultOutboundRuleIdleTimeoutInMinutes
 		}
+		if a.OrchestratorProfile.KubernetesConfig.OutboundType == "" {
+			a.OrchestratorProfile.KubernetesConfig.OutboundType = DefaultOutboundType
+		}
 
 		// First, Configure addons
 		cs.setAddonsConfig(isUpdate)
@@ -801,7 +808,7 @@ func (cs *ContainerService) SetDefaultCerts() (bool, []net.IP, error) {
 		caPair = &helpers.PkiKeyCertPair{CertificatePem: p.CertificateProfile.CaCertificate, PrivateKeyPem: p.CertificateProfile.CaPrivateKey}
 	} else {
 		var err error
-		caPair, err = helpers.CreatePkiKeyCertPair("ca")
+		caPair, err = helpers.CreatePkiKeyCertPair("ca", p.CertificateProfile.GetPkiOptions().CA)
 		if err != nil {
 			return false, ips, err
 		}
@@ -809,13 +816,13 @@ func (cs *ContainerService) SetDefaultCerts() (bool, []net.IP, error) {
 		p.CertificateProfile.CaPrivateKey = caPair.PrivateKeyPem
 	}
 
-	cidrFirstIP, err := common.CidrStringFirstIP(p.OrchestratorProfile.KubernetesConfig.ServiceCIDR)
+	cidrFirstIP, err := common.CidrStringFirstIP(p.OrchestratorProfile.KubernetesConfig.GetFirstServiceCIDR())
 	if err != nil {
 		return false, ips, err
 	}
 	ips = append(ips, cidrFirstIP)
 
-	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, etcdClientPair, etcdPeerPairs, err := helpers.CreatePki(masterExtraFQDNs, ips, DefaultKubernetesClusterDomain, caPair, p.MasterProfile.Count)
+	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, etcdClientPair, etcdPeerPairs, err := helpers.CreatePki(masterExtraFQDNs, ips, DefaultKubernetesClusterDomain, caPair, p.MasterProfile.Count, p.CertificateProfile.GetPkiOptions())
 	if err != nil {
 		return false, ips, err
 	}
@@ -944,6 +951,6 @@ func mapToString(valueMap map[string]string) string {
 
 func generateEtcdEncryptionKey() string {
 	b := make([]byte, 32)
-	rand.Read(b)
+	io.ReadFull(helpers.RandomReader(), b)
 	return base64.StdEncoding.EncodeToString(b)
 }
@@ -0,0 +1,350 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// azureReservedAddressCount is the number of addresses Azure reserves in every subnet: the network address,
+	// the default gateway, the two addresses mapped to the Azure DNS and the broadcast address
+	azureReservedAddressCount = 5
+	// azureReservedLeadingAddressCount is the number of reserved addresses at the start of every subnet
+	azureReservedLeadingAddressCount = 4
+	// defaultNodeCIDRMaskSize is the size of the pod CIDR kube-controller-manager allocates to every node
+	defaultNodeCIDRMaskSize = 24
+)
+
+// NetworkPlan lists the address ranges a Kubernetes cluster consumes, and the overlaps and exhaustions found in them
+type NetworkPlan struct {
+	Subnets     []*NetworkPlanSubnet     `json:"subnets"`
+	Allocations []*NetworkPlanAllocation `json:"allocations"`
+	Ranges      []*NetworkPlanRange      `json:"ranges"`
+	Warnings    []string                 `json:"warnings"`
+	Problems    []string                 `json:"problems"`
+}
+
+// NetworkPlanSubnet is a subnet the nodes, and with Azure CNI the pods, of the cluster get their addresses from
+type NetworkPlanSubnet struct {
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"`
+	// CIDR is empty if the address range of a custom VNET subnet is unknown
+	CIDR string `json:"cidr,omitempty"`
+	// Usable is the number of addresses of the subnet that are not reserved by Azure
+	Usable uint64 `json:"usable"`
+	// Needed is the number of addresses the cluster needs in the subnet
+	Needed uint64 `json:"needed"`
+	// MaxNeeded is the number of addresses the cluster needs in the subnet once its pools are scaled to their maxCount
+	MaxNeeded uint64 `json:"maxNeeded"`
+	network   *net.IPNet
+}
+
+// NetworkPlanAllocation is the addresses the nodes of the master or of an agent pool allocate from a subnet
+type NetworkPlanAllocation struct {
+	Name           string `json:"name"`
+	Subnet         string `json:"subnet"`
+	Nodes          int    `json:"nodes"`
+	MaxNodes       int    `json:"maxNodes"`
+	IPAddressCount int    `json:"ipAddressCount"`
+	Addresses      uint64 `json:"addresses"`
+	MaxAddresses   uint64 `json:"maxAddresses"`
+}
+
+// NetworkPlanRange is a range of addresses used by the cluster outside of the node allocations
+type NetworkPlanRange struct {
+	Name      string `json:"name"`
+	Range     string `json:"range"`
+	Addresses uint64 `json:"addresses"`
+	network   *net.IPNet
+}
+
+// GetNetworkPlan computes the address ranges the cluster consumes and checks them for overlaps and exhaustion.
+// It expects the defaults of the container service to be set. The address ranges of the custom VNET subnets
+// are not part of the api model, they are looked up by subnet ID or name in subnetCIDRs.
+func (cs *ContainerService) GetNetworkPlan(subnetCIDRs map[string]string) (*NetworkPlan, error) {
+	p := cs.Properties
+	if p == nil || p.OrchestratorProfile == nil || !p.OrchestratorProfile.IsKubernetes() || p.MasterProfile == nil {
+		return nil, errors.New("a network plan can only be computed for Kubernetes clusters with a master profile")
+	}
+	k := p.OrchestratorProfile.KubernetesConfig
+	if k == nil {
+		return nil, errors.New("the api model has no kubernetesConfig, its defaults must be set first")
+	}
+
+	plan := &NetworkPlan{}
+	m := p.MasterProfile
+
+	// the subnets of the masters and of the agents
+	var masterSubnet, agentSubnet *NetworkPlanSubnet
+	var err error
+	if m.IsCustomVNET() {
+		if masterSubnet, err = plan.addCustomSubnet(m.VnetSubnetID, subnetCIDRs); err != nil {
+			return nil, err
+		}
+	} else if m.IsVirtualMachineScaleSets() {
+		if masterSubnet, err = plan.addSubnet("subnetmaster", "", m.Subnet); err != nil {
+			return nil, err
+		}
+		if agentSubnet, err = plan.addSubnet("subnetagent", "", m.AgentSubnet); err != nil {
+			return nil, err
+		}
+	} else {
+		if masterSubnet, err = plan.addSubnet("k8s-subnet", "", m.Subnet); err != nil {
+			return nil, err
+		}
+		agentSubnet = masterSubnet
+	}
+
+	plan.addAllocation("master", masterSubnet, m.Count, m.Count, m.IPAddressCount)
+	for _, profile := range p.AgentPoolProfiles {
+		subnet := agentSubnet
+		if profile.IsCustomVNET() {
+			if subnet, err = plan.addCustomSubnet(profile.VnetSubnetID, subnetCIDRs); err != nil {
+				return nil, err
+			}
+		} else if m.IsCustomVNET() {
+			subnet = masterSubnet
+		}
+		maxNodes := profile.Count
+		if profile.MaxCount != nil && *profile.MaxCount > maxNodes {
+			maxNodes = *profile.MaxCount
+		}
+		plan.addAllocation(profile.Name, subnet, profile.Count, maxNodes, profile.IPAddressCount)
+	}
+
+	// the static addresses of the masters and of the internal load balancer
+	firstMasterIP := net.ParseIP(m.FirstConsecutiveStaticIP).To4()
+	if firstMasterIP == nil {
+		return nil, errors.Errorf("masterProfile.firstConsecutiveStaticIP '%s' is an invalid IPv4 address", m.FirstConsecutiveStaticIP)
+	}
+	if m.IsVirtualMachineScaleSets() {
+		plan.addStaticIPs(masterSubnet, "internal load balancer", net.IP{firstMasterIP[0], firstMasterIP[1], byte(255), byte(DefaultInternalLbStaticIPOffset)}, 1)
+	} else {
+		plan.addStaticIPs(masterSubnet, "master static IPs", firstMasterIP, m.Count)
+		plan.addStaticIPs(masterSubnet, "internal load balancer", common.IP4Add(firstMasterIP, DefaultInternalLbStaticIPOffset), 1)
+	}
+
+	// the address ranges of the cluster, dual stack clusters have an IPv6 service CIDR after the IPv4 one
+	serviceCIDRs := strings.Split(k.ServiceCIDR, ",")
+	serviceCIDR, err := plan.addRange("service CIDR", serviceCIDRs[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, cidr := range serviceCIDRs[1:] {
+		if _, err = plan.addRange("service CIDR", cidr); err != nil {
+			return nil, err
+		}
+	}
+	dnsServiceIP := net.ParseIP(k.DNSServiceIP)
+	if dnsServiceIP == nil {
+		return nil, errors.Errorf("kubernetesConfig.dnsServiceIP '%s' is an invalid IP address", k.DNSServiceIP)
+	}
+	plan.Ranges = append(plan.Ranges, &NetworkPlanRange{Name: "DNS service IP", Range: dnsServiceIP.String(), Addresses: 1})
+	if !serviceCIDR.network.Contains(dnsServiceIP) {
+		plan.addProblem("the DNS service IP %s is outside of the service CIDR %s", k.DNSServiceIP, serviceCIDRs[0])
+	} else if dnsServiceIP.Equal(common.CidrFirstIP(copyIP(serviceCIDR.network.IP))) {
+		plan.addProblem("the DNS service IP %s is the first address of the service CIDR %s, which is used by the kubernetes service", k.DNSServiceIP, serviceCIDRs[0])
+	}
+	var podCIDRs []*NetworkPlanRange
+	if !p.OrchestratorProfile.IsAzureCNI() {
+		for _, cidr := range strings.Split(k.ClusterSubnet, ",") {
+			podCIDR, e := plan.addRange("cluster subnet", cidr)
+			if e != nil {
+				return nil, e
+			}
+			podCIDRs = append(podCIDRs, podCIDR)
+		}
+	}
+	dockerBridge, err := plan.addRange("docker bridge subnet", k.DockerBridgeSubnet)
+	if err != nil {
+		return nil, err
+	}
+
+	// overlaps
+	for i, a := range plan.Subnets {
+		for _, b := range plan.Subnets[i+1:] {
+			if a.network != nil && b.network != nil && common.CidrsOverlap(a.network, b.network) {
+				plan.addProblem("subnet %s (%s) overlaps subnet %s (%s)", a.Name, a.CIDR, b.Name, b.CIDR)
+			}
+		}
+	}
+	clusterRanges := append([]*NetworkPlanRange{serviceCIDR}, podCIDRs...)
+	clusterRanges = append(clusterRanges, dockerBridge)
+	for i, r := range clusterRanges {
+		for _, subnet := range plan.Subnets {
+			if subnet.network != nil && common.CidrsOverlap(r.network, subnet.network) {
+				plan.addProblem("the %s %s overlaps subnet %s (%s)", r.Name, r.Range, subnet.Name, subnet.CIDR)
+			}
+		}
+		for _, other := range clusterRanges[i+1:] {
+			if common.CidrsOverlap(r.network, other.network) {
+				plan.addProblem("the %s %s overlaps the %s %s", r.Name, r.Range, other.Name, other.Range)
+			}
+		}
+	}
+
+	// exhaustion
+	for _, subnet := range plan.Subnets {
+		if subnet.network == nil {
+			plan.addWarning("the address range of subnet %s is unknown, its capacity and overlaps are not checked", subnet.Name)
+			continue
+		}
+		if subnet.Needed > subnet.Usable {
+			plan.addProblem("subnet %s (%s) has %d usable addresses but the cluster needs %d", subnet.Name, subnet.CIDR, subnet.Usable, subnet.Needed)
+		} else if subnet.MaxNeeded > subnet.Usable {
+			plan.addWarning("subnet %s (%s) has %d usable addresses but the cluster needs %d once its pools are scaled to their maxCount", subnet.Name, subnet.CIDR, subnet.Usable, subnet.MaxNeeded)
+		}
+	}
+	if len(podCIDRs) > 0 {
+		plan.checkPodCIDRCapacity(podCIDRs[0], k.ControllerManagerConfig)
+	}
+
+	return plan, nil
+}
+
+// addSubnet adds a subnet to the plan, or returns the existing subnet with the same name
+func (plan *NetworkPlan) addSubnet(name, id, cidr string) (*NetworkPlanSubnet, error) {
+	for _, subnet := range plan.Subnets {
+		if subnet.Name == name && subnet.ID == id {
+			return subnet, nil
+		}
+	}
+	subnet := &NetworkPlanSubnet{Name: name, ID: id}
+	if cidr != "" {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Errorf("subnet %s has an invalid address range '%s'", name, cidr)
+		}
+		subnet.network = network
+		subnet.CIDR = network.String()
+		if count := common.CidrAddressCount(network); count > azureReservedAddressCount {
+			subnet.Usable = count - azureReservedAddressCount
+		}
+	}
+	plan.Subnets = append(plan.Subnets, subnet)
+	return subnet, nil
+}
+
+// addCustomSubnet adds a custom VNET subnet to the plan, its address range is looked up by subnet ID or name
+func (plan *NetworkPlan) addCustomSubnet(vnetSubnetID string, subnetCIDRs map[string]string) (*NetworkPlanSubnet, error) {
+	_, _, _, name, err := common.GetVNETSubnetIDComponents(vnetSubnetID)
+	if err != nil {
+		return nil, err
+	}
+	cidr, ok := subnetCIDRs[vnetSubnetID]
+	if !ok {
+		cidr = subnetCIDRs[name]
+	}
+	return plan.addSubnet(name, vnetSubnetID, cidr)
+}
+
+func (plan *NetworkPlan) addAllocation(name string, subnet *NetworkPlanSubnet, nodes, maxNodes, ipAddressCount int) {
+	a := &NetworkPlanAllocation{
+		Name:           name,
+		Subnet:         subnet.Name,
+		Nodes:          nodes,
+		MaxNodes:       maxNodes,
+		IPAddressCount: ipAddressCount,
+		Addresses:      uint64(nodes) * uint64(ipAddressCount),
+		MaxAddresses:   uint64(maxNodes) * uint64(ipAddressCount),
+	}
+	subnet.Needed += a.Addresses
+	subnet.MaxNeeded += a.MaxAddresses
+	plan.Allocations = append(plan.Allocations, a)
+}
+
+// addStaticIPs adds count consecutive static addresses of a subnet, starting at first. The static addresses of the
+// masters are the primary addresses of their NICs, they are already counted in the master allocation.
+func (plan *NetworkPlan) addStaticIPs(subnet *NetworkPlanSubnet, name string, first net.IP, count int) {
+	last := common.IP4Add(first, count-1)
+	r := &NetworkPlanRange{Name: name, Range: first.String(), Addresses: uint64(count)}
+	if count > 1 {
+		r.Range = fmt.Sprintf("%s-%s", first, last)
+	}
+	plan.Ranges = append(plan.Ranges, r)
+	if name != "master static IPs" {
+		subnet.Needed += uint64(count)
+		subnet.MaxNeeded += uint64(count)
+	}
+	if subnet.network == nil {
+		return
+	}
+	if !subnet.network.Contains(first) || !subnet.network.Contains(last) {
+		plan.addProblem("subnet %s (%s) does not contain the %s %s", subnet.Name, subnet.CIDR, name, r.Range)
+		return
+	}
+	if compareIP4(first, common.IP4Add(subnet.network.IP, azureReservedLeadingAddressCount)) < 0 ||
+		compareIP4(last, common.IP4BroadcastAddress(subnet.network)) >= 0 {
+		plan.addProblem("some addresses of the %s %s are reserved by Azure in subnet %s (%s)", name, r.Range, subnet.Name, subnet.CIDR)
+	}
+}
+
+func (plan *NetworkPlan) addRange(name, cidr string) (*NetworkPlanRange, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, errors.Errorf("the %s '%s' is an invalid CIDR", name, cidr)
+	}
+	r := &NetworkPlanRange{Name: name, Range: cidr, Addresses: common.CidrAddressCount(network), network: network}
+	plan.Ranges = append(plan.Ranges, r)
+	return r, nil
+}
+
+// checkPodCIDRCapacity checks that the cluster subnet has a pod CIDR for every node, kube-controller-manager
+// allocates one pod CIDR of --node-cidr-mask-size bits to every node when Azure CNI is not used
+func (plan *NetworkPlan) checkPodCIDRCapacity(podCIDR *NetworkPlanRange, controllerManagerConfig map[string]string) {
+	maskSize := defaultNodeCIDRMaskSize
+	if s, ok := controllerManagerConfig["--node-cidr-mask-size"]; ok {
+		if size, err := strconv.Atoi(s); err == nil {
+			maskSize = size
+		}
+	}
+	ones, bits := podCIDR.network.Mask.Size()
+	if bits != 8*net.IPv4len || maskSize < ones || maskSize > bits {
+		return
+	}
+	available := uint64(1) << uint(maskSize-ones)
+	var nodes, maxNodes uint64
+	for _, a := range plan.Allocations {
+		nodes += uint64(a.Nodes)
+		maxNodes += uint64(a.MaxNodes)
+	}
+	if nodes > available {
+		plan.addProblem("the cluster subnet %s has %d pod CIDRs of /%d but the cluster has %d nodes", podCIDR.Range, available, maskSize, nodes)
+	} else if maxNodes > available {
+		plan.addWarning("the cluster subnet %s has %d pod CIDRs of /%d but the cluster has %d nodes once its pools are scaled to their maxCount", podCIDR.Range, available, maskSize, maxNodes)
+	}
+}
+
+func (plan *NetworkPlan) addProblem(format string, args ...interface{}) {
+	plan.Problems = append(plan.Problems, fmt.Sprintf(format, args...))
+}
+
+func (plan *NetworkPlan) addWarning(format string, args ...interface{}) {
+	plan.Warnings = append(plan.Warnings, fmt.Sprintf(format, args...))
+}
+
+func copyIP(ip net.IP) net.IP {
+	c := make(net.IP, len(ip))
+	copy(c, ip)
+	return c
+}
+
+// compareIP4 compares two IPv4 addresses, it returns a negative number if a < b, 0 if they are equal and a positive
+// number if a > b
+func compareIP4(a, b net.IP) int {
+	a4, b4 := a.To4(), b.To4()
+	for i := range a4 {
+		if a4[i] != b4[i] {
+			return int(a4[i]) - int(b4[i])
+		}
+	}
+	return 0
+}
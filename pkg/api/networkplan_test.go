@@ -0,0 +1,265 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+const (
+	networkPlanTestMasterSubnetID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/mastersubnet"
+	networkPlanTestAgentSubnetID  = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/agentsubnet"
+)
+
+func TestGetNetworkPlan(t *testing.T) {
+	cases := []struct {
+		name             string
+		setup            func(cs *ContainerService)
+		subnetCIDRs      map[string]string
+		expectedSubnets  []NetworkPlanSubnet
+		expectedRanges   map[string]string
+		expectedWarnings []string
+		expectedProblems []string
+	}{
+		{
+			name: "default kubenet",
+			setup: func(cs *ContainerService) {
+				cs.Properties.AgentPoolProfiles[0].MaxCount = to.IntPtr(5)
+			},
+			expectedSubnets: []NetworkPlanSubnet{
+				{Name: "k8s-subnet", CIDR: "10.240.0.0/16", Usable: 65531, Needed: 7, MaxNeeded: 9},
+			},
+			expectedRanges: map[string]string{
+				"master static IPs":      "10.240.255.5-10.240.255.7",
+				"internal load balancer": "10.240.255.15",
+				"service CIDR":           "10.0.0.0/16",
+				"DNS service IP":         "10.0.0.10",
+				"cluster subnet":         "10.244.0.0/16",
+				"docker bridge subnet":   "172.17.0.1/16",
+			},
+		},
+		{
+			name: "default Azure CNI",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin = NetworkPluginAzure
+				cs.Properties.AgentPoolProfiles[0].MaxCount = to.IntPtr(5)
+			},
+			expectedSubnets: []NetworkPlanSubnet{
+				{Name: "k8s-subnet", CIDR: "10.240.0.0/12", Usable: 1048571, Needed: 187, MaxNeeded: 249},
+			},
+			expectedRanges: map[string]string{
+				"master static IPs":      "10.255.255.5-10.255.255.7",
+				"internal load balancer": "10.255.255.15",
+			},
+		},
+		{
+			name: "custom VNET with a small agent subnet",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin = NetworkPluginAzure
+				cs.Properties.MasterProfile.VnetSubnetID = networkPlanTestMasterSubnetID
+				cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+				cs.Properties.AgentPoolProfiles[0].VnetSubnetID = networkPlanTestAgentSubnetID
+			},
+			subnetCIDRs: map[string]string{
+				"mastersubnet":               "10.239.0.0/16",
+				networkPlanTestAgentSubnetID: "10.238.0.0/26",
+			},
+			expectedSubnets: []NetworkPlanSubnet{
+				{Name: "mastersubnet", ID: networkPlanTestMasterSubnetID, CIDR: "10.239.0.0/16", Usable: 65531, Needed: 94, MaxNeeded: 94},
+				{Name: "agentsubnet", ID: networkPlanTestAgentSubnetID, CIDR: "10.238.0.0/26", Usable: 59, Needed: 93, MaxNeeded: 93},
+			},
+			expectedProblems: []string{
+				"subnet agentsubnet (10.238.0.0/26) has 59 usable addresses but the cluster needs 93",
+			},
+		},
+		{
+			name: "custom VNET with unknown subnets",
+			setup: func(cs *ContainerService) {
+				cs.Properties.MasterProfile.VnetSubnetID = networkPlanTestMasterSubnetID
+				cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+				cs.Properties.AgentPoolProfiles[0].VnetSubnetID = networkPlanTestMasterSubnetID
+			},
+			expectedSubnets: []NetworkPlanSubnet{
+				{Name: "mastersubnet", ID: networkPlanTestMasterSubnetID, Needed: 7, MaxNeeded: 7},
+			},
+			expectedWarnings: []string{
+				"the address range of subnet mastersubnet is unknown, its capacity and overlaps are not checked",
+			},
+		},
+		{
+			name: "custom VNET with static IPs outside of the master subnet",
+			setup: func(cs *ContainerService) {
+				cs.Properties.MasterProfile.VnetSubnetID = networkPlanTestMasterSubnetID
+				cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.0.1"
+				cs.Properties.AgentPoolProfiles[0].VnetSubnetID = networkPlanTestMasterSubnetID
+			},
+			subnetCIDRs: map[string]string{
+				networkPlanTestMasterSubnetID: "10.239.0.0/29",
+			},
+			expectedProblems: []string{
+				"some addresses of the master static IPs 10.239.0.1-10.239.0.3 are reserved by Azure in subnet mastersubnet (10.239.0.0/29)",
+				"subnet mastersubnet (10.239.0.0/29) does not contain the internal load balancer 10.239.0.11",
+				"subnet mastersubnet (10.239.0.0/29) has 3 usable addresses but the cluster needs 7",
+			},
+		},
+		{
+			name: "overlapping service CIDR and DNS service IP outside of it",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.ServiceCIDR = "10.240.0.0/16"
+			},
+			expectedProblems: []string{
+				"the DNS service IP 10.0.0.10 is outside of the service CIDR 10.240.0.0/16",
+				"the service CIDR 10.240.0.0/16 overlaps subnet k8s-subnet (10.240.0.0/16)",
+			},
+		},
+		{
+			name: "DNS service IP used by the kubernetes service",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.DNSServiceIP = "10.0.0.1"
+			},
+			expectedProblems: []string{
+				"the DNS service IP 10.0.0.1 is the first address of the service CIDR 10.0.0.0/16, which is used by the kubernetes service",
+			},
+		},
+		{
+			name: "dual stack service CIDRs and cluster subnets",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.IPFamilies = []string{IPFamilyIPv4, IPFamilyIPv6}
+				cs.Properties.OrchestratorProfile.KubernetesConfig.ServiceCIDR = "10.0.0.0/16,fd00:1234::/108"
+			},
+			expectedRanges: map[string]string{
+				// the IPv6 ranges follow the IPv4 ones
+				"service CIDR":   "fd00:1234::/108",
+				"DNS service IP": "10.0.0.10",
+				"cluster subnet": "fd00:101::/8",
+			},
+		},
+		{
+			name: "overlapping docker bridge and cluster subnet",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.DockerBridgeSubnet = "10.244.0.1/16"
+			},
+			expectedProblems: []string{
+				"the cluster subnet 10.244.0.0/16 overlaps the docker bridge subnet 10.244.0.1/16",
+			},
+		},
+		{
+			name: "small cluster subnet",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.ClusterSubnet = "10.244.0.0/21"
+				cs.Properties.AgentPoolProfiles[0].MaxCount = to.IntPtr(10)
+			},
+			expectedWarnings: []string{
+				"the cluster subnet 10.244.0.0/21 has 8 pod CIDRs of /24 but the cluster has 13 nodes once its pools are scaled to their maxCount",
+			},
+		},
+		{
+			name: "cluster subnet with larger node pod CIDRs",
+			setup: func(cs *ContainerService) {
+				cs.Properties.OrchestratorProfile.KubernetesConfig.ClusterSubnet = "10.244.0.0/22"
+				cs.Properties.OrchestratorProfile.KubernetesConfig.ControllerManagerConfig = map[string]string{"--node-cidr-mask-size": "23"}
+			},
+			expectedProblems: []string{
+				"the cluster subnet 10.244.0.0/22 has 2 pod CIDRs of /23 but the cluster has 6 nodes",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			cs := CreateMockContainerService("testcluster", "1.15.7", 3, 3, true)
+			cs.Properties.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{}
+			c.setup(cs)
+			cs.SetPropertiesDefaults(false, false)
+
+			plan, err := cs.GetNetworkPlan(c.subnetCIDRs)
+			if err != nil {
+				t.Fatalf("unexpected error computing the network plan: %s", err)
+			}
+			if c.expectedSubnets != nil {
+				if len(plan.Subnets) != len(c.expectedSubnets) {
+					t.Fatalf("expected %d subnets, got %d", len(c.expectedSubnets), len(plan.Subnets))
+				}
+				for i, expected := range c.expectedSubnets {
+					s := *plan.Subnets[i]
+					s.network = nil
+					if s != expected {
+						t.Errorf("expected subnet %+v, got %+v", expected, s)
+					}
+				}
+			}
+			for name, expected := range c.expectedRanges {
+				var actual string
+				for _, r := range plan.Ranges {
+					if r.Name == name {
+						actual = r.Range
+					}
+				}
+				if actual != expected {
+					t.Errorf("expected the %s to be %s, got %s", name, expected, actual)
+				}
+			}
+			if !stringSlicesEqual(plan.Warnings, c.expectedWarnings) {
+				t.Errorf("expected warnings %v, got %v", c.expectedWarnings, plan.Warnings)
+			}
+			if !stringSlicesEqual(plan.Problems, c.expectedProblems) {
+				t.Errorf("expected problems %v, got %v", c.expectedProblems, plan.Problems)
+			}
+		})
+	}
+}
+
+func TestGetNetworkPlanAllocations(t *testing.T) {
+	cs := CreateMockContainerService("testcluster", "1.15.7", 1, 2, true)
+	cs.Properties.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{NetworkPlugin: NetworkPluginAzure}
+	cs.Properties.MasterProfile.AvailabilityProfile = VirtualMachineScaleSets
+	cs.Properties.AgentPoolProfiles[0].AvailabilityProfile = VirtualMachineScaleSets
+	cs.Properties.AgentPoolProfiles[0].MaxCount = to.IntPtr(4)
+	cs.SetPropertiesDefaults(false, false)
+
+	plan, err := cs.GetNetworkPlan(nil)
+	if err != nil {
+		t.Fatalf("unexpected error computing the network plan: %s", err)
+	}
+	expected := []NetworkPlanAllocation{
+		{Name: "master", Subnet: "subnetmaster", Nodes: 1, MaxNodes: 1, IPAddressCount: 31, Addresses: 31, MaxAddresses: 31},
+		{Name: "agentpool1", Subnet: "subnetagent", Nodes: 2, MaxNodes: 4, IPAddressCount: 31, Addresses: 62, MaxAddresses: 124},
+	}
+	if len(plan.Allocations) != len(expected) {
+		t.Fatalf("expected %d allocations, got %d", len(expected), len(plan.Allocations))
+	}
+	for i := range expected {
+		if *plan.Allocations[i] != expected[i] {
+			t.Errorf("expected allocation %+v, got %+v", expected[i], *plan.Allocations[i])
+		}
+	}
+	for _, r := range plan.Ranges {
+		if r.Name == "internal load balancer" && r.Range != "10.240.255.10" {
+			t.Errorf("expected the internal load balancer IP to be 10.240.255.10, got %s", r.Range)
+		}
+	}
+	if len(plan.Problems) != 0 {
+		t.Errorf("expected no problems, got %v", plan.Problems)
+	}
+
+	cs.Properties.OrchestratorProfile.OrchestratorType = DCOS
+	if _, err = cs.GetNetworkPlan(nil); err == nil {
+		t.Errorf("expected an error computing the network plan of a DCOS cluster")
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -156,6 +156,7 @@ func kubernetesInfo(csOrch *OrchestratorProfile, hasWindows bool) ([]*Orchestrat
 					},
 					Default:  ver == common.GetDefaultKubernetesVersion(hasWindows),
 					Upgrades: upgrades,
+					Addons:   GetAddonsSupportedByKubernetesVersion(ver),
 				})
 		}
 	} else {
@@ -175,6 +176,7 @@ func kubernetesInfo(csOrch *OrchestratorProfile, hasWindows bool) ([]*Orchestrat
 				},
 				Default:  csOrch.OrchestratorVersion == common.GetDefaultKubernetesVersion(hasWindows),
 				Upgrades: upgrades,
+				Addons:   GetAddonsSupportedByKubernetesVersion(csOrch.OrchestratorVersion),
 			})
 	}
 	return orchs, nil
@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	v20170831 "github.com/Azure/aks-engine/pkg/api/agentPoolOnlyApi/v20170831"
 	v20180331 "github.com/Azure/aks-engine/pkg/api/agentPoolOnlyApi/v20180331"
@@ -164,6 +165,28 @@ type CertificateProfile struct {
 	EtcdPeerCertificates []string `json:"etcdPeerCertificates,omitempty" conform:"redact"`
 	// EtcdPeerPrivateKeys is list of etcd peer private keys, and signed by the CA
 	EtcdPeerPrivateKeys []string `json:"etcdPeerPrivateKeys,omitempty" conform:"redact"`
+	// PKIProfile selects the keys and validity of the generated certificates
+	PKIProfile *PKIProfile `json:"pkiProfile,omitempty"`
+}
+
+// PKIProfile selects the key algorithm, key size and validity of each class of generated certificates
+type PKIProfile struct {
+	CA         *PKICertificateProfile `json:"ca,omitempty"`
+	APIServer  *PKICertificateProfile `json:"apiServer,omitempty"`
+	Client     *PKICertificateProfile `json:"client,omitempty"`
+	EtcdServer *PKICertificateProfile `json:"etcdServer,omitempty"`
+	EtcdClient *PKICertificateProfile `json:"etcdClient,omitempty"`
+	EtcdPeer   *PKICertificateProfile `json:"etcdPeer,omitempty"`
+}
+
+// PKICertificateProfile selects the key and validity of a class of certificates
+type PKICertificateProfile struct {
+	// KeyAlgorithm is either RSA or ECDSA, RSA by default
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// KeySize is the size in bits of RSA keys, or of the curve of ECDSA keys
+	KeySize int `json:"keySize,omitempty"`
+	// ValidityDays is the number of days the certificate is valid
+	ValidityDays int `json:"validityDays,omitempty"`
 }
 
 // LinuxProfile represents the linux parameters passed to the cluster
@@ -207,6 +230,8 @@ type WindowsProfile struct {
 	WindowsOffer           string            `json:"windowsOffer"`
 	WindowsSku             string            `json:"windowsSku"`
 	WindowsDockerVersion   string            `json:"windowsDockerVersion"`
+	WindowsContainerdURL   string            `json:"windowsContainerdURL,omitempty"`
+	ImageRef               *ImageReference   `json:"imageReference,omitempty"`
 	Secrets                []KeyVaultSecrets `json:"secrets,omitempty"`
 	SSHEnabled             bool              `json:"sshEnabled,omitempty"`
 	EnableAutomaticUpdates *bool             `json:"enableAutomaticUpdates,omitempty"`
@@ -251,6 +276,8 @@ type OrchestratorVersionProfile struct {
 	Default bool `json:"default,omitempty"`
 	// List of available upgrades for this orchestrator version
 	Upgrades []*OrchestratorProfile `json:"upgrades,omitempty"`
+	// Names of the addons available with this orchestrator version
+	Addons []string `json:"addons,omitempty"`
 }
 
 // KubernetesContainerSpec defines configuration for a container spec
@@ -270,6 +297,41 @@ type KubernetesAddon struct {
 	Containers []KubernetesContainerSpec `json:"containers,omitempty"`
 	Config     map[string]string         `json:"config,omitempty"`
 	Data       string                    `json:"data,omitempty"`
+	Chart      *KubernetesAddonChart     `json:"chart,omitempty"`
+	Overlays   []KubernetesOverlay       `json:"overlays,omitempty"`
+}
+
+// KubernetesAddonChart references a Helm chart which is rendered into the manifest of an addon when the template is
+// generated, so that no Helm component runs in the cluster
+type KubernetesAddonChart struct {
+	// Path is a chart directory or packaged .tgz chart, relative to the working directory of aks-engine
+	Path string `json:"path,omitempty"`
+	// Version, if set, must match the version in the Chart.yaml of the chart
+	Version string `json:"version,omitempty"`
+	// ReleaseName is the release name the chart is rendered with, the name of the addon by default
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Namespace is the namespace of the release, kube-system by default
+	Namespace string                 `json:"namespace,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+// KubernetesOverlay is a patch applied to a manifest generated by aks-engine, after its template is rendered
+type KubernetesOverlay struct {
+	// Type is either strategicMerge, the default, or json6902
+	Type string `json:"type,omitempty"`
+	// Target selects the objects of the manifest the patch applies to. A strategic merge patch without target
+	// applies to the object with the kind, name and namespace of the patch.
+	Target *KubernetesOverlayTarget `json:"target,omitempty"`
+	// Patch is the strategic merge patch or the list of JSON patch operations, in YAML or JSON
+	Patch string `json:"patch,omitempty"`
+}
+
+// KubernetesOverlayTarget selects the objects of a manifest an overlay applies to
+type KubernetesOverlayTarget struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Namespace, if set, must match the namespace of the object
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // IsEnabled returns true if the addon is enabled
@@ -292,8 +354,9 @@ func (a KubernetesAddon) GetAddonContainersIndexByName(containerName string) int
 
 // PrivateCluster defines the configuration for a private cluster
 type PrivateCluster struct {
-	Enabled        *bool                  `json:"enabled,omitempty"`
-	JumpboxProfile *PrivateJumpboxProfile `json:"jumpboxProfile,omitempty"`
+	Enabled              *bool                  `json:"enabled,omitempty"`
+	JumpboxProfile       *PrivateJumpboxProfile `json:"jumpboxProfile,omitempty"`
+	EnablePrivateDNSZone *bool                  `json:"enablePrivateDNSZone,omitempty"`
 }
 
 // PrivateJumpboxProfile represents a jumpbox definition
@@ -306,6 +369,22 @@ type PrivateJumpboxProfile struct {
 	StorageProfile string `json:"storageProfile,omitempty"`
 }
 
+// AuditProfile configures the audit logging of the apiserver
+type AuditProfile struct {
+	// Policy is the audit policy, in YAML or JSON. It replaces the default audit policy of the cluster.
+	Policy string `json:"policy,omitempty"`
+	// PolicyFile is the path of a file holding the audit policy. The file is read into Policy when the apimodel is loaded.
+	PolicyFile string `json:"policyFile,omitempty"`
+	// LogMaxAge, LogMaxBackup and LogMaxSize configure the rotation of the audit log
+	LogMaxAge    *int `json:"logMaxAge,omitempty"`
+	LogMaxBackup *int `json:"logMaxBackup,omitempty"`
+	LogMaxSize   *int `json:"logMaxSize,omitempty"`
+	// WebhookURL is the URL of a webhook backend the audit events are also sent to
+	WebhookURL string `json:"webhookURL,omitempty"`
+	// WebhookKubeconfig is the kubeconfig of a webhook backend which needs credentials, instead of WebhookURL
+	WebhookKubeconfig string `json:"webhookKubeconfig,omitempty"`
+}
+
 // CloudProviderConfig contains the KubernetesConfig properties specific to the Cloud Provider
 type CloudProviderConfig struct {
 	CloudProviderBackoff              *bool  `json:"cloudProviderBackoff,omitempty"`
@@ -354,6 +433,7 @@ type KubernetesConfig struct {
 	DockerBridgeSubnet                string            `json:"dockerBridgeSubnet,omitempty"`
 	DNSServiceIP                      string            `json:"dnsServiceIP,omitempty"`
 	ServiceCIDR                       string            `json:"serviceCidr,omitempty"`
+	IPFamilies                        []string          `json:"ipFamilies,omitempty"`
 	UseManagedIdentity                bool              `json:"useManagedIdentity,omitempty"`
 	UserAssignedID                    string            `json:"userAssignedID,omitempty"`
 	UserAssignedClientID              string            `json:"userAssignedClientID,omitempty"` //Note: cannot be provided in config. Used *only* for transferring this to azure.json.
@@ -379,6 +459,7 @@ type KubernetesConfig struct {
 	EnableEncryptionWithExternalKms   *bool             `json:"enableEncryptionWithExternalKms,omitempty"`
 	EnablePodSecurityPolicy           *bool             `json:"enablePodSecurityPolicy,omitempty"`
 	Addons                            []KubernetesAddon `json:"addons,omitempty"`
+	AddonDescriptors                  []FileAddon       `json:"addonDescriptors,omitempty"`
 	KubeletConfig                     map[string]string `json:"kubeletConfig,omitempty"`
 	ControllerManagerConfig           map[string]string `json:"controllerManagerConfig,omitempty"`
 	CloudControllerManagerConfig      map[string]string `json:"cloudControllerManagerConfig,omitempty"`
@@ -411,6 +492,13 @@ type KubernetesConfig struct {
 	ProxyMode                         KubeProxyMode     `json:"kubeProxyMode,omitempty"`
 	PrivateAzureRegistryServer        string            `json:"privateAzureRegistryServer,omitempty"`
 	OutboundRuleIdleTimeoutInMinutes  int32             `json:"outboundRuleIdleTimeoutInMinutes,omitempty"`
+	OutboundType                      string            `json:"outboundType,omitempty"`
+	OutboundNextHopIPAddress          string            `json:"outboundNextHopIPAddress,omitempty"`
+	PackageMirrorURL                  string            `json:"packageMirrorURL,omitempty"`
+	AuditProfile                      *AuditProfile     `json:"auditProfile,omitempty"`
+
+	// ManifestOverlays are the overlays of the manifests of the master components, by component name
+	ManifestOverlays map[string][]KubernetesOverlay `json:"manifestOverlays,omitempty"`
 }
 
 // CustomFile has source as the full absolute source path to a file and dest
@@ -463,6 +551,8 @@ type MasterProfile struct {
 	VnetCidr                 string            `json:"vnetCidr,omitempty"`
 	AgentVnetSubnetID        string            `json:"agentVnetSubnetID,omitempty"`
 	FirstConsecutiveStaticIP string            `json:"firstConsecutiveStaticIP,omitempty"`
+	RouteTableID             string            `json:"routeTableID,omitempty"`
+	NetworkSecurityGroupID   string            `json:"networkSecurityGroupID,omitempty"`
 	Subnet                   string            `json:"subnet"`
 	SubnetIPv6               string            `json:"subnetIPv6"`
 	IPAddressCount           int               `json:"ipAddressCount,omitempty"`
@@ -532,9 +622,12 @@ type AgentPoolProfile struct {
 	PlatformFaultDomainCount            *int                 `json:"platformFaultDomainCount"`
 	ScaleSetPriority                    string               `json:"scaleSetPriority,omitempty"`
 	ScaleSetEvictionPolicy              string               `json:"scaleSetEvictionPolicy,omitempty"`
+	FallbackPool                        string               `json:"fallbackPool,omitempty"`
 	StorageProfile                      string               `json:"storageProfile,omitempty"`
 	DiskSizesGB                         []int                `json:"diskSizesGB,omitempty"`
 	VnetSubnetID                        string               `json:"vnetSubnetID,omitempty"`
+	RouteTableID                        string               `json:"routeTableID,omitempty"`
+	NetworkSecurityGroupID              string               `json:"networkSecurityGroupID,omitempty"`
 	Subnet                              string               `json:"subnet"`
 	IPAddressCount                      int                  `json:"ipAddressCount,omitempty"`
 	Distro                              Distro               `json:"distro,omitempty"`
@@ -911,6 +1004,16 @@ func (p *Properties) AnyAgentUsesAvailabilitySets() bool {
 	return false
 }
 
+// HasLowPriorityScaleSets checks whether any of the agent pools is a Low-priority VM ScaleSet
+func (p *Properties) HasLowPriorityScaleSets() bool {
+	for _, agentProfile := range p.AgentPoolProfiles {
+		if agentProfile.IsLowPriorityScaleSet() {
+			return true
+		}
+	}
+	return false
+}
+
 // AnyAgentIsLinux checks whether any of the agents in the AgentPools are linux
 func (p *Properties) AnyAgentIsLinux() bool {
 	for _, agentProfile := range p.AgentPoolProfiles {
@@ -937,14 +1040,48 @@ func (p *Properties) GetResourcePrefix() string {
 
 // GetRouteTableName returns the route table name of the cluster.
 func (p *Properties) GetRouteTableName() string {
+	if routeTableID := p.GetCustomRouteTableID(); routeTableID != "" {
+		return routeTableID[strings.LastIndex(routeTableID, "/")+1:]
+	}
 	return p.GetResourcePrefix() + "routetable"
 }
 
 // GetNSGName returns the name of the network security group of the cluster.
 func (p *Properties) GetNSGName() string {
+	if nsgID := p.GetCustomNetworkSecurityGroupID(); nsgID != "" {
+		return nsgID[strings.LastIndex(nsgID, "/")+1:]
+	}
 	return p.GetResourcePrefix() + "nsg"
 }
 
+// GetCustomRouteTableID returns the ID of the route table brought by the user, if any.
+// The masters' route table wins over the agent pools' one, as all profiles must share the same route table.
+func (p *Properties) GetCustomRouteTableID() string {
+	if p.MasterProfile != nil && p.MasterProfile.RouteTableID != "" {
+		return p.MasterProfile.RouteTableID
+	}
+	for _, agentPoolProfile := range p.AgentPoolProfiles {
+		if agentPoolProfile.RouteTableID != "" {
+			return agentPoolProfile.RouteTableID
+		}
+	}
+	return ""
+}
+
+// GetCustomNetworkSecurityGroupID returns the ID of the network security group brought by the user, if any.
+// The masters' NSG wins over the agent pools' one, as all profiles must share the same NSG.
+func (p *Properties) GetCustomNetworkSecurityGroupID() string {
+	if p.MasterProfile != nil && p.MasterProfile.NetworkSecurityGroupID != "" {
+		return p.MasterProfile.NetworkSecurityGroupID
+	}
+	for _, agentPoolProfile := range p.AgentPoolProfiles {
+		if agentPoolProfile.NetworkSecurityGroupID != "" {
+			return agentPoolProfile.NetworkSecurityGroupID
+		}
+	}
+	return ""
+}
+
 // GetPrimaryAvailabilitySetName returns the name of the primary availability set of the cluster
 func (p *Properties) GetPrimaryAvailabilitySetName() string {
 	if len(p.AgentPoolProfiles) > 0 {
@@ -970,6 +1107,16 @@ func (p *Properties) IsHostedMasterProfile() bool {
 	return p.HostedMasterProfile != nil
 }
 
+// IsIPv6DualStack returns true if the cluster is dual stack, either with the IPv6 family in ipFamilies
+// or with the legacy EnableIPv6DualStack feature flag
+func (p *Properties) IsIPv6DualStack() bool {
+	if p.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+		return true
+	}
+	return p.OrchestratorProfile != nil && p.OrchestratorProfile.KubernetesConfig != nil &&
+		p.OrchestratorProfile.KubernetesConfig.IsIPv6DualStack()
+}
+
 // IsIPMasqAgentEnabled returns true if the cluster has a hosted master and IpMasqAgent is disabled
 func (p *Properties) IsIPMasqAgentEnabled() bool {
 	if p.HostedMasterProfile != nil {
@@ -1034,6 +1181,33 @@ func (p *Properties) AreAgentProfilesCustomVNET() bool {
 	return false
 }
 
+// GetPkiOptions returns the options of the certificates generated for the cluster
+func (c *CertificateProfile) GetPkiOptions() helpers.PkiOptions {
+	if c == nil || c.PKIProfile == nil {
+		return helpers.PkiOptions{}
+	}
+	return helpers.PkiOptions{
+		CA:         c.PKIProfile.CA.GetCertificateOptions(),
+		APIServer:  c.PKIProfile.APIServer.GetCertificateOptions(),
+		Client:     c.PKIProfile.Client.GetCertificateOptions(),
+		EtcdServer: c.PKIProfile.EtcdServer.GetCertificateOptions(),
+		EtcdClient: c.PKIProfile.EtcdClient.GetCertificateOptions(),
+		EtcdPeer:   c.PKIProfile.EtcdPeer.GetCertificateOptions(),
+	}
+}
+
+// GetCertificateOptions returns the options of a class of certificates, the defaults if p is nil
+func (p *PKICertificateProfile) GetCertificateOptions() helpers.CertificateOptions {
+	if p == nil {
+		return helpers.CertificateOptions{}
+	}
+	return helpers.CertificateOptions{
+		KeyAlgorithm: p.KeyAlgorithm,
+		KeySize:      p.KeySize,
+		Validity:     time.Duration(p.ValidityDays) * 24 * time.Hour,
+	}
+}
+
 // GetClusterID creates a unique 8 string cluster ID.
 func (p *Properties) GetClusterID() string {
 	var mutex = &sync.Mutex{}
@@ -1186,7 +1360,7 @@ func (p *Properties) GetNonMasqueradeCIDR() string {
 		} else {
 			// kube-proxy still only understands single cidr and is not changed for ipv6 dual stack phase 1
 			// so only pass the ipv4 cidr which is the first one in the list as arg to kube proxy
-			if p.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+			if p.IsIPv6DualStack() {
 				nonMasqCidr = strings.Split(p.OrchestratorProfile.KubernetesConfig.ClusterSubnet, ",")[0]
 			} else {
 				nonMasqCidr = p.OrchestratorProfile.KubernetesConfig.ClusterSubnet
@@ -1224,6 +1398,11 @@ func (p *Properties) AnyAgentHasLoadBalancerBackendAddressPoolIDs() bool {
 	return false
 }
 
+// IsLatestVersion returns true if the version of the Shared Image Gallery image is to be resolved to its latest version
+func (i *ImageReference) IsLatestVersion() bool {
+	return i.Version == ImageVersionLatest
+}
+
 // HasImageRef returns true if the customer brought os image
 func (m *MasterProfile) HasImageRef() bool {
 	return m.ImageRef != nil && len(m.ImageRef.Name) > 0 && len(m.ImageRef.ResourceGroup) > 0
@@ -1486,6 +1665,9 @@ func (a *AgentPoolProfile) GetKubernetesLabels(rg string, deprecated bool) strin
 		accelerator := "nvidia"
 		buf.WriteString(fmt.Sprintf(",accelerator=%s", accelerator))
 	}
+	if a.IsLowPriorityScaleSet() {
+		buf.WriteString(fmt.Sprintf(",%s", LowPriorityNodeLabel))
+	}
 	buf.WriteString(fmt.Sprintf(",kubernetes.azure.com/cluster=%s", rg))
 	keys := []string{}
 	for key := range a.CustomNodeLabels {
@@ -1508,6 +1690,24 @@ func (w *WindowsProfile) HasCustomImage() bool {
 	return len(w.WindowsImageSourceURL) > 0
 }
 
+// HasImageRef returns true if the customer brought the windows os image, as a managed image or from a Shared Image Gallery
+func (w *WindowsProfile) HasImageRef() bool {
+	return w.ImageRef != nil && len(w.ImageRef.Name) > 0 && len(w.ImageRef.ResourceGroup) > 0
+}
+
+// HasImageGallery returns true if the customer brought the windows os image from a Shared Image Gallery
+func (w *WindowsProfile) HasImageGallery() bool {
+	return w.ImageRef != nil && len(w.ImageRef.SubscriptionID) > 0 && len(w.ImageRef.Gallery) > 0 && len(w.ImageRef.Version) > 0
+}
+
+// GetWindowsContainerdURL returns the full URL to source the containerd package for Windows nodes from
+func (w *WindowsProfile) GetWindowsContainerdURL(cloudSpecConfig AzureEnvironmentSpecConfig) string {
+	if w.WindowsContainerdURL != "" {
+		return w.WindowsContainerdURL
+	}
+	return cloudSpecConfig.KubernetesSpecConfig.WindowsContainerdDownloadURL
+}
+
 // GetWindowsDockerVersion gets the docker version specified or returns default value
 func (w *WindowsProfile) GetWindowsDockerVersion() string {
 	if w.WindowsDockerVersion != "" {
@@ -1600,6 +1800,12 @@ func (o *OrchestratorProfile) RequireRouteTable() bool {
 	}
 }
 
+// HasRouteTable returns true if the cluster subnets need a route table, either for the pod CIDR routes
+// of the cloud provider or for the default route of user-defined routing
+func (o *OrchestratorProfile) HasRouteTable() bool {
+	return o.RequireRouteTable() || (o.IsKubernetes() && o.KubernetesConfig.IsUserDefinedRouting())
+}
+
 // IsPrivateCluster returns true if this deployment is a private cluster
 func (o *OrchestratorProfile) IsPrivateCluster() bool {
 	if !o.IsKubernetes() {
@@ -1675,6 +1881,21 @@ func (k *KubernetesConfig) IsIPMasqAgentEnabled() bool {
 	return k.IsAddonEnabled(IPMASQAgentAddonName)
 }
 
+// IsIPv6DualStack checks if ipFamilies holds the IPv6 family
+func (k *KubernetesConfig) IsIPv6DualStack() bool {
+	for _, family := range k.IPFamilies {
+		if family == IPFamilyIPv6 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFirstServiceCIDR returns the IPv4 service CIDR, the first of the dual stack service CIDRs
+func (k *KubernetesConfig) GetFirstServiceCIDR() string {
+	return strings.Split(k.ServiceCIDR, ",")[0]
+}
+
 // IsRBACEnabled checks if RBAC is enabled
 func (k *KubernetesConfig) IsRBACEnabled() bool {
 	if k.EnableRbac != nil {
@@ -1884,6 +2105,51 @@ func (k *KubernetesConfig) PrivateJumpboxProvision() bool {
 	return false
 }
 
+// PrivateDNSZoneProvision returns if the private cluster's API server is published in a private DNS zone
+func (k *KubernetesConfig) PrivateDNSZoneProvision() bool {
+	return k != nil && k.PrivateCluster != nil && to.Bool(k.PrivateCluster.Enabled) && to.Bool(k.PrivateCluster.EnablePrivateDNSZone)
+}
+
+// IsUserDefinedRouting returns if the egress of the cluster goes through user-defined routes instead of the agent load balancer
+func (k *KubernetesConfig) IsUserDefinedRouting() bool {
+	return k != nil && k.OutboundType == OutboundTypeUserDefinedRouting
+}
+
+// HasAuditPolicy returns true if the audit profile replaces the default audit policy
+func (k *KubernetesConfig) HasAuditPolicy() bool {
+	return k != nil && k.AuditProfile != nil && k.AuditProfile.Policy != ""
+}
+
+// HasAuditWebhook returns true if the audit events are sent to a webhook backend
+func (k *KubernetesConfig) HasAuditWebhook() bool {
+	return k != nil && k.AuditProfile != nil && (k.AuditProfile.WebhookURL != "" || k.AuditProfile.WebhookKubeconfig != "")
+}
+
+// GetAuditWebhookKubeconfig returns the kubeconfig of the audit webhook backend, the one of the audit profile or one
+// generated for its webhook URL
+func (k *KubernetesConfig) GetAuditWebhookKubeconfig() string {
+	if !k.HasAuditWebhook() {
+		return ""
+	}
+	if k.AuditProfile.WebhookKubeconfig != "" {
+		return k.AuditProfile.WebhookKubeconfig
+	}
+	// a JSON string is a valid YAML double-quoted scalar
+	server, _ := json.Marshal(k.AuditProfile.WebhookURL)
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: audit-webhook
+  cluster:
+    server: %s
+contexts:
+- name: audit-webhook
+  context:
+    cluster: audit-webhook
+current-context: audit-webhook
+`, server)
+}
+
 // RequiresDocker returns if the kubernetes settings require docker binary to be installed.
 func (k *KubernetesConfig) RequiresDocker() bool {
 	runtime := strings.ToLower(k.ContainerRuntime)
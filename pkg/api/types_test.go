@@ -789,6 +789,64 @@ func TestHasStorageProfile(t *testing.T) {
 	}
 }
 
+func TestPrivateDNSZoneProvision(t *testing.T) {
+	cases := []struct {
+		name     string
+		k        *KubernetesConfig
+		expected bool
+	}{
+		{
+			name:     "nil KubernetesConfig",
+			k:        nil,
+			expected: false,
+		},
+		{
+			name:     "no private cluster",
+			k:        &KubernetesConfig{},
+			expected: false,
+		},
+		{
+			name: "private cluster without private DNS zone",
+			k: &KubernetesConfig{
+				PrivateCluster: &PrivateCluster{
+					Enabled: to.BoolPtr(true),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "private DNS zone on a disabled private cluster",
+			k: &KubernetesConfig{
+				PrivateCluster: &PrivateCluster{
+					Enabled:              to.BoolPtr(false),
+					EnablePrivateDNSZone: to.BoolPtr(true),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "private cluster with private DNS zone",
+			k: &KubernetesConfig{
+				PrivateCluster: &PrivateCluster{
+					Enabled:              to.BoolPtr(true),
+					EnablePrivateDNSZone: to.BoolPtr(true),
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if c.k.PrivateDNSZoneProvision() != c.expected {
+				t.Fatalf("expected PrivateDNSZoneProvision() to return %t but instead returned %t", c.expected, c.k.PrivateDNSZoneProvision())
+			}
+		})
+	}
+}
+
 func TestAgentPoolProfileGetKubernetesLabels(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -867,6 +925,17 @@ func TestAgentPoolProfileGetKubernetesLabels(t *testing.T) {
 			deprecated: true,
 			expected:   "kubernetes.azure.com/role=agent,node-role.kubernetes.io/agent=,kubernetes.io/role=agent,agentpool=,storageprofile=managed,storagetier=Standard_LRS,accelerator=nvidia,kubernetes.azure.com/cluster=my-resource-group,mycustomlabel1=foo,mycustomlabel2=bar",
 		},
+		{
+			name: "low priority scale set",
+			ap: AgentPoolProfile{
+				Name:                "spot",
+				AvailabilityProfile: VirtualMachineScaleSets,
+				ScaleSetPriority:    ScaleSetPriorityLow,
+			},
+			rg:         "my-resource-group",
+			deprecated: false,
+			expected:   "kubernetes.azure.com/role=agent,agentpool=spot,kubernetes.azure.com/scalesetpriority=low,kubernetes.azure.com/cluster=my-resource-group",
+		},
 	}
 
 	for _, c := range cases {
@@ -3471,6 +3540,30 @@ func TestGetAzureCNIURLFuncs(t *testing.T) {
 	}
 }
 
+func TestGetWindowsContainerdURL(t *testing.T) {
+	cs := CreateMockContainerService("testcluster", defaultTestClusterVer, 1, 3, false)
+	cs.Location = "eastus"
+	cloudSpecConfig := cs.GetCloudSpecConfig()
+
+	w := WindowsProfile{}
+	expectedURL := "https://acs-mirror.azureedge.net/containerd/windows/containerd-windows-amd64-" + WindowsContainerdVer + ".zip"
+	if url := w.GetWindowsContainerdURL(cloudSpecConfig); url != expectedURL {
+		t.Fatalf("GetWindowsContainerdURL() should return default %s, instead returned %s", expectedURL, url)
+	}
+
+	cs.Location = "chinaeast2"
+	cloudSpecConfig = cs.GetCloudSpecConfig()
+	expectedURL = "https://mirror.azk8s.cn/kubernetes/containerd/containerd-windows-amd64-" + WindowsContainerdVer + ".zip"
+	if url := w.GetWindowsContainerdURL(cloudSpecConfig); url != expectedURL {
+		t.Fatalf("GetWindowsContainerdURL() should return the mirror %s, instead returned %s", expectedURL, url)
+	}
+
+	w.WindowsContainerdURL = "https://custom-url/containerd-windows.0.0.1.zip"
+	if url := w.GetWindowsContainerdURL(cloudSpecConfig); url != w.WindowsContainerdURL {
+		t.Fatalf("GetWindowsContainerdURL() should return custom URL %s, instead returned %s", w.WindowsContainerdURL, url)
+	}
+}
+
 func TestCloudProviderDefaults(t *testing.T) {
 	// Test cloudprovider defaults when no user-provided values
 	v := "1.8.0"
@@ -4310,6 +4403,31 @@ func TestGetRouteTableName(t *testing.T) {
 	if actualNSGName != expectedNSGName {
 		t.Errorf("expected route table name %s, but got %s", actualNSGName, expectedNSGName)
 	}
+
+	p.AgentPoolProfiles[0].RouteTableID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/custom-routetable"
+	p.MasterProfile.NetworkSecurityGroupID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/custom-nsg"
+
+	actualRTName = p.GetRouteTableName()
+	expectedRTName = "custom-routetable"
+
+	actualNSGName = p.GetNSGName()
+	expectedNSGName = "custom-nsg"
+
+	if actualRTName != expectedRTName {
+		t.Errorf("expected route table name %s, but got %s", expectedRTName, actualRTName)
+	}
+
+	if actualNSGName != expectedNSGName {
+		t.Errorf("expected network security group name %s, but got %s", expectedNSGName, actualNSGName)
+	}
+
+	if p.GetCustomRouteTableID() != p.AgentPoolProfiles[0].RouteTableID {
+		t.Errorf("expected the custom route table ID %s, but got %s", p.AgentPoolProfiles[0].RouteTableID, p.GetCustomRouteTableID())
+	}
+
+	if p.GetCustomNetworkSecurityGroupID() != p.MasterProfile.NetworkSecurityGroupID {
+		t.Errorf("expected the custom network security group ID %s, but got %s", p.MasterProfile.NetworkSecurityGroupID, p.GetCustomNetworkSecurityGroupID())
+	}
 }
 
 func TestGetSubnetName(t *testing.T) {
@@ -4980,6 +5098,38 @@ func TestFormatProdFQDNByLocation(t *testing.T) {
 	}
 }
 
+func TestKubernetesConfig_GetAuditWebhookKubeconfig(t *testing.T) {
+	k := &KubernetesConfig{}
+	if k.HasAuditPolicy() || k.HasAuditWebhook() || k.GetAuditWebhookKubeconfig() != "" {
+		t.Errorf("expected no audit policy and no audit webhook without audit profile")
+	}
+
+	k.AuditProfile = &AuditProfile{WebhookURL: "https://audit.example.com/events?cluster=test"}
+	expected := `apiVersion: v1
+kind: Config
+clusters:
+- name: audit-webhook
+  cluster:
+    server: "https://audit.example.com/events?cluster=test"
+contexts:
+- name: audit-webhook
+  context:
+    cluster: audit-webhook
+current-context: audit-webhook
+`
+	if !k.HasAuditWebhook() {
+		t.Errorf("expected an audit webhook with a webhook URL")
+	}
+	if actual := k.GetAuditWebhookKubeconfig(); actual != expected {
+		t.Errorf("expected the kubeconfig of the webhook URL to be %s, but got %s", expected, actual)
+	}
+
+	k.AuditProfile = &AuditProfile{WebhookKubeconfig: "kind: Config\n"}
+	if actual := k.GetAuditWebhookKubeconfig(); actual != "kind: Config\n" {
+		t.Errorf("expected the kubeconfig of the audit profile, but got %s", actual)
+	}
+}
+
 func TestKubernetesConfig_GetAddonScript(t *testing.T) {
 	addon := getMockAddon(IPMASQAgentAddonName)
 	addon.Data = "foobarbazdata"
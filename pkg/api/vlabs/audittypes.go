@@ -0,0 +1,36 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package vlabs
+
+// The types below mirror the audit policy of the audit.k8s.io API group, see
+// https://github.com/kubernetes/apiserver/blob/master/pkg/apis/audit/v1/types.go
+// They are only used to validate the audit policy of the audit profile.
+
+// auditPolicy defines the audit events which are recorded, and the data they include
+type auditPolicy struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Rules      []auditPolicyRule      `json:"rules"`
+	OmitStages []string               `json:"omitStages,omitempty"`
+}
+
+// auditPolicyRule maps requests to an audit level, the first matching rule of the policy sets the level of a request
+type auditPolicyRule struct {
+	Level           string                `json:"level"`
+	Users           []string              `json:"users,omitempty"`
+	UserGroups      []string              `json:"userGroups,omitempty"`
+	Verbs           []string              `json:"verbs,omitempty"`
+	Resources       []auditGroupResources `json:"resources,omitempty"`
+	Namespaces      []string              `json:"namespaces,omitempty"`
+	NonResourceURLs []string              `json:"nonResourceURLs,omitempty"`
+	OmitStages      []string              `json:"omitStages,omitempty"`
+}
+
+// auditGroupResources selects resources of an API group
+type auditGroupResources struct {
+	Group         string   `json:"group,omitempty"`
+	Resources     []string `json:"resources,omitempty"`
+	ResourceNames []string `json:"resourceNames,omitempty"`
+}
@@ -26,6 +26,9 @@ const (
 	Linux   OSType = "Linux"
 )
 
+// ImageVersionLatest is the version of a Shared Image Gallery image resolved to its latest version by deploy and upgrade
+const ImageVersionLatest = "latest"
+
 // the LinuxDistros supported by vlabs
 const (
 	Ubuntu            Distro = "ubuntu"
@@ -70,6 +73,14 @@ const (
 	VirtualMachineScaleSets = "VirtualMachineScaleSets"
 )
 
+// scale set priorities
+const (
+	// ScaleSetPriorityRegular is the default ScaleSet Priority
+	ScaleSetPriorityRegular = "Regular"
+	// ScaleSetPriorityLow means the ScaleSet will use Low-priority VMs
+	ScaleSetPriorityLow = "Low"
+)
+
 // storage profiles
 const (
 	// StorageAccount means that the nodes use raw storage accounts for their os and attached volumes
@@ -123,6 +134,10 @@ const (
 	NetworkPolicyCilium = "cilium"
 	// NetworkPluginCilium is the string expression for cilium network policy config option
 	NetworkPluginCilium = NetworkPolicyCilium
+	// IPFamilyIPv4 is the string expression for the IPv4 family of the ipFamilies config
+	IPFamilyIPv4 = "IPv4"
+	// IPFamilyIPv6 is the string expression for the IPv6 family of the ipFamilies config
+	IPFamilyIPv6 = "IPv6"
 )
 
 const (
@@ -166,3 +181,19 @@ const (
 
 // StandardLoadBalancerSku is the string const for Azure Standard Load Balancer
 const StandardLoadBalancerSku = "Standard"
+
+// Outbound types
+const (
+	// OutboundTypeLoadBalancer is the egress of the agents through the outbound rules of the agent load balancer
+	OutboundTypeLoadBalancer = "loadBalancer"
+	// OutboundTypeUserDefinedRouting is the egress of the cluster through user-defined routes, e.g. to Azure Firewall
+	OutboundTypeUserDefinedRouting = "userDefinedRouting"
+)
+
+// Overlay types
+const (
+	// OverlayTypeStrategicMerge patches a manifest with a Kubernetes strategic merge patch
+	OverlayTypeStrategicMerge = "strategicMerge"
+	// OverlayTypeJSON6902 patches a manifest with JSON patch (RFC 6902) operations
+	OverlayTypeJSON6902 = "json6902"
+)
@@ -27,10 +27,12 @@ type UpgradeProfile struct {
 //  - orchestrator type and version
 //  - whether this orchestrator version is deployed by default if orchestrator release is not specified
 //  - list of available upgrades for this orchestrator version
+//  - names of the addons available with this orchestrator version
 type OrchestratorVersionProfile struct {
 	OrchestratorProfile
 	Default  bool                   `json:"default,omitempty"`
 	Upgrades []*OrchestratorProfile `json:"upgrades,omitempty"`
+	Addons   []string               `json:"addons,omitempty"`
 }
 
 // OrchestratorVersionProfileList contains list of version profiles for supported orchestrators
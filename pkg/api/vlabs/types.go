@@ -124,6 +124,28 @@ type CertificateProfile struct {
 	EtcdPeerCertificates []string `json:"etcdPeerCertificates,omitempty"`
 	// EtcdPeerPrivateKeys is list of etcd peer private keys, and signed by the CA
 	EtcdPeerPrivateKeys []string `json:"etcdPeerPrivateKeys,omitempty"`
+	// PKIProfile selects the keys and validity of the generated certificates
+	PKIProfile *PKIProfile `json:"pkiProfile,omitempty"`
+}
+
+// PKIProfile selects the key algorithm, key size and validity of each class of generated certificates
+type PKIProfile struct {
+	CA         *PKICertificateProfile `json:"ca,omitempty"`
+	APIServer  *PKICertificateProfile `json:"apiServer,omitempty"`
+	Client     *PKICertificateProfile `json:"client,omitempty"`
+	EtcdServer *PKICertificateProfile `json:"etcdServer,omitempty"`
+	EtcdClient *PKICertificateProfile `json:"etcdClient,omitempty"`
+	EtcdPeer   *PKICertificateProfile `json:"etcdPeer,omitempty"`
+}
+
+// PKICertificateProfile selects the key and validity of a class of certificates
+type PKICertificateProfile struct {
+	// KeyAlgorithm is either RSA or ECDSA, RSA by default
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// KeySize is the size in bits of RSA keys, or of the curve of ECDSA keys
+	KeySize int `json:"keySize,omitempty"`
+	// ValidityDays is the number of days the certificate is valid
+	ValidityDays int `json:"validityDays,omitempty"`
 }
 
 // LinuxProfile represents the linux parameters passed to the cluster
@@ -165,6 +187,8 @@ type WindowsProfile struct {
 	WindowsOffer           string            `json:"WindowsOffer"`
 	WindowsSku             string            `json:"WindowsSku"`
 	WindowsDockerVersion   string            `json:"windowsDockerVersion"`
+	WindowsContainerdURL   string            `json:"windowsContainerdURL,omitempty"`
+	ImageRef               *ImageReference   `json:"imageReference,omitempty"`
 	Secrets                []KeyVaultSecrets `json:"secrets,omitempty"`
 	SSHEnabled             bool              `json:"sshEnabled,omitempty"`
 	EnableAutomaticUpdates *bool             `json:"enableAutomaticUpdates,omitempty"`
@@ -242,12 +266,62 @@ type KubernetesAddon struct {
 	Containers []KubernetesContainerSpec `json:"containers,omitempty"`
 	Config     map[string]string         `json:"config,omitempty"`
 	Data       string                    `json:"data,omitempty"`
+	Chart      *KubernetesAddonChart     `json:"chart,omitempty"`
+	Overlays   []KubernetesOverlay       `json:"overlays,omitempty"`
+}
+
+// KubernetesAddonChart references a Helm chart which is rendered into the manifest of an addon when the template is
+// generated, so that no Helm component runs in the cluster
+type KubernetesAddonChart struct {
+	// Path is a chart directory or packaged .tgz chart, relative to the working directory of aks-engine
+	Path string `json:"path,omitempty"`
+	// Version, if set, must match the version in the Chart.yaml of the chart
+	Version string `json:"version,omitempty"`
+	// ReleaseName is the release name the chart is rendered with, the name of the addon by default
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Namespace is the namespace of the release, kube-system by default
+	Namespace string                 `json:"namespace,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+// AddonDescriptor is a third-party addon registered with aks-engine generate --addons-dir, persisted in the api model
+// so that the commands which regenerate the template register it again
+type AddonDescriptor struct {
+	Name               string                    `json:"name,omitempty"`
+	Enabled            bool                      `json:"enabled,omitempty"`
+	KubernetesVersions string                    `json:"kubernetesVersions,omitempty"`
+	Containers         []KubernetesContainerSpec `json:"containers,omitempty"`
+	Config             map[string]string         `json:"config,omitempty"`
+	RequiredConfig     []string                  `json:"requiredConfig,omitempty"`
+	DestinationFile    string                    `json:"destinationFile,omitempty"`
+	// Manifest is the text of the manifest.yaml template of the addon
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// KubernetesOverlay is a patch applied to a manifest generated by aks-engine, after its template is rendered
+type KubernetesOverlay struct {
+	// Type is either strategicMerge, the default, or json6902
+	Type string `json:"type,omitempty"`
+	// Target selects the objects of the manifest the patch applies to. A strategic merge patch without target
+	// applies to the object with the kind, name and namespace of the patch.
+	Target *KubernetesOverlayTarget `json:"target,omitempty"`
+	// Patch is the strategic merge patch or the list of JSON patch operations, in YAML or JSON
+	Patch string `json:"patch,omitempty"`
+}
+
+// KubernetesOverlayTarget selects the objects of a manifest an overlay applies to
+type KubernetesOverlayTarget struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Namespace, if set, must match the namespace of the object
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // PrivateCluster defines the configuration for a private cluster
 type PrivateCluster struct {
-	Enabled        *bool                  `json:"enabled,omitempty"`
-	JumpboxProfile *PrivateJumpboxProfile `json:"jumpboxProfile,omitempty"`
+	Enabled              *bool                  `json:"enabled,omitempty"`
+	JumpboxProfile       *PrivateJumpboxProfile `json:"jumpboxProfile,omitempty"`
+	EnablePrivateDNSZone *bool                  `json:"enablePrivateDNSZone,omitempty"`
 }
 
 // PrivateJumpboxProfile represents a jumpbox definition
@@ -260,6 +334,22 @@ type PrivateJumpboxProfile struct {
 	StorageProfile string `json:"storageProfile,omitempty"`
 }
 
+// AuditProfile configures the audit logging of the apiserver
+type AuditProfile struct {
+	// Policy is the audit policy, in YAML or JSON. It replaces the default audit policy of the cluster.
+	Policy string `json:"policy,omitempty"`
+	// PolicyFile is the path of a file holding the audit policy. The file is read into Policy when the apimodel is loaded.
+	PolicyFile string `json:"policyFile,omitempty"`
+	// LogMaxAge, LogMaxBackup and LogMaxSize configure the rotation of the audit log
+	LogMaxAge    *int `json:"logMaxAge,omitempty"`
+	LogMaxBackup *int `json:"logMaxBackup,omitempty"`
+	LogMaxSize   *int `json:"logMaxSize,omitempty"`
+	// WebhookURL is the URL of a webhook backend the audit events are also sent to
+	WebhookURL string `json:"webhookURL,omitempty"`
+	// WebhookKubeconfig is the kubeconfig of a webhook backend which needs credentials, instead of WebhookURL
+	WebhookKubeconfig string `json:"webhookKubeconfig,omitempty"`
+}
+
 // KubeProxyMode is for iptables and ipvs (and future others)
 type KubeProxyMode string
 
@@ -276,6 +366,7 @@ type KubernetesConfig struct {
 	ClusterSubnet                     string            `json:"clusterSubnet,omitempty"`
 	DNSServiceIP                      string            `json:"dnsServiceIP,omitempty"`
 	ServiceCidr                       string            `json:"serviceCidr,omitempty"`
+	IPFamilies                        []string          `json:"ipFamilies,omitempty"`
 	NetworkPolicy                     string            `json:"networkPolicy,omitempty"`
 	NetworkPlugin                     string            `json:"networkPlugin,omitempty"`
 	ContainerRuntime                  string            `json:"containerRuntime,omitempty"`
@@ -306,6 +397,7 @@ type KubernetesConfig struct {
 	EnableEncryptionWithExternalKms   *bool             `json:"enableEncryptionWithExternalKms,omitempty"`
 	EnablePodSecurityPolicy           *bool             `json:"enablePodSecurityPolicy,omitempty"`
 	Addons                            []KubernetesAddon `json:"addons,omitempty"`
+	AddonDescriptors                  []AddonDescriptor `json:"addonDescriptors,omitempty"`
 	KubeletConfig                     map[string]string `json:"kubeletConfig,omitempty"`
 	ControllerManagerConfig           map[string]string `json:"controllerManagerConfig,omitempty"`
 	CloudControllerManagerConfig      map[string]string `json:"cloudControllerManagerConfig,omitempty"`
@@ -332,6 +424,13 @@ type KubernetesConfig struct {
 	ProxyMode                         KubeProxyMode     `json:"kubeProxyMode,omitempty"`
 	PrivateAzureRegistryServer        string            `json:"privateAzureRegistryServer,omitempty"`
 	OutboundRuleIdleTimeoutInMinutes  int32             `json:"outboundRuleIdleTimeoutInMinutes,omitempty"`
+	OutboundType                      string            `json:"outboundType,omitempty"`
+	OutboundNextHopIPAddress          string            `json:"outboundNextHopIPAddress,omitempty"`
+	PackageMirrorURL                  string            `json:"packageMirrorURL,omitempty"`
+	AuditProfile                      *AuditProfile     `json:"auditProfile,omitempty"`
+
+	// ManifestOverlays are the overlays of the manifests of the master components, by component name
+	ManifestOverlays map[string][]KubernetesOverlay `json:"manifestOverlays,omitempty"`
 }
 
 // CustomFile has source as the full absolute source path to a file and dest
@@ -374,6 +473,8 @@ type MasterProfile struct {
 	VnetCidr                 string            `json:"vnetCidr,omitempty"`
 	AgentVnetSubnetID        string            `json:"agentVnetSubnetID,omitempty"`
 	FirstConsecutiveStaticIP string            `json:"firstConsecutiveStaticIP,omitempty"`
+	RouteTableID             string            `json:"routeTableID,omitempty"`
+	NetworkSecurityGroupID   string            `json:"networkSecurityGroupID,omitempty"`
 	IPAddressCount           int               `json:"ipAddressCount,omitempty" validate:"min=0,max=256"`
 	StorageProfile           string            `json:"storageProfile,omitempty" validate:"eq=StorageAccount|eq=ManagedDisks|len=0"`
 	HTTPSourceAddressPrefix  string            `json:"HTTPSourceAddressPrefix,omitempty"`
@@ -445,9 +546,12 @@ type AgentPoolProfile struct {
 	AvailabilityProfile                 string               `json:"availabilityProfile"`
 	ScaleSetPriority                    string               `json:"scaleSetPriority,omitempty" validate:"eq=Regular|eq=Low|len=0"`
 	ScaleSetEvictionPolicy              string               `json:"scaleSetEvictionPolicy,omitempty" validate:"eq=Delete|eq=Deallocate|len=0"`
+	FallbackPool                        string               `json:"fallbackPool,omitempty"`
 	StorageProfile                      string               `json:"storageProfile" validate:"eq=StorageAccount|eq=ManagedDisks|eq=Ephemeral|len=0"`
 	DiskSizesGB                         []int                `json:"diskSizesGB,omitempty" validate:"max=4,dive,min=1,max=1023"`
 	VnetSubnetID                        string               `json:"vnetSubnetID,omitempty"`
+	RouteTableID                        string               `json:"routeTableID,omitempty"`
+	NetworkSecurityGroupID              string               `json:"networkSecurityGroupID,omitempty"`
 	IPAddressCount                      int                  `json:"ipAddressCount,omitempty" validate:"min=0,max=256"`
 	Distro                              Distro               `json:"distro,omitempty"`
 	KubernetesConfig                    *KubernetesConfig    `json:"kubernetesConfig,omitempty"`
@@ -553,6 +657,16 @@ func (p *Properties) HasWindows() bool {
 	return false
 }
 
+// IsIPv6DualStack returns true if the cluster is dual stack, either with the IPv6 family in ipFamilies
+// or with the legacy EnableIPv6DualStack feature flag
+func (p *Properties) IsIPv6DualStack() bool {
+	if p.FeatureFlags.IsIPv6DualStackEnabled() {
+		return true
+	}
+	return p.OrchestratorProfile != nil && p.OrchestratorProfile.KubernetesConfig != nil &&
+		p.OrchestratorProfile.KubernetesConfig.IsIPv6DualStack()
+}
+
 // HasAvailabilityZones returns true if the cluster contains any profile with zones
 func (p *Properties) HasAvailabilityZones() bool {
 	hasZones := p.MasterProfile != nil && p.MasterProfile.HasAvailabilityZones()
@@ -818,6 +932,16 @@ func (k *KubernetesConfig) RequiresDocker() bool {
 	return runtime == Docker || runtime == ""
 }
 
+// IsIPv6DualStack checks if ipFamilies holds the IPv6 family
+func (k *KubernetesConfig) IsIPv6DualStack() bool {
+	for _, family := range k.IPFamilies {
+		if family == IPFamilyIPv6 {
+			return true
+		}
+	}
+	return false
+}
+
 // IsRBACEnabled checks if RBAC is enabled
 func (k *KubernetesConfig) IsRBACEnabled() bool {
 	if k.EnableRbac != nil {
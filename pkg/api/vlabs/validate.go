@@ -4,7 +4,9 @@
 package vlabs
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
@@ -18,10 +20,12 @@ import (
 	"github.com/Azure/aks-engine/pkg/api/common"
 	"github.com/Azure/aks-engine/pkg/helpers"
 	"github.com/blang/semver"
+	"github.com/ghodss/yaml"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	validator "gopkg.in/go-playground/validator.v9"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
 var (
@@ -29,6 +33,8 @@ var (
 	keyvaultIDRegex *regexp.Regexp
 	labelValueRegex *regexp.Regexp
 	labelKeyRegex   *regexp.Regexp
+	// the versions of the Shared Image Gallery images are Major.Minor.Patch
+	imageVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
 	// Any version has to be mirrored in https://acs-mirror.azureedge.net/github-coreos/etcd-v[Version]-linux-amd64.tar.gz
 	etcdValidVersions = [...]string{"2.2.5", "2.3.0", "2.3.1", "2.3.2", "2.3.3", "2.3.4", "2.3.5", "2.3.6", "2.3.7", "2.3.8",
 		"3.0.0", "3.0.1", "3.0.2", "3.0.3", "3.0.4", "3.0.5", "3.0.6", "3.0.7", "3.0.8", "3.0.9", "3.0.10", "3.0.11", "3.0.12", "3.0.13", "3.0.14", "3.0.15", "3.0.16", "3.0.17",
@@ -36,6 +42,10 @@ var (
 		"3.2.0", "3.2.1", "3.2.2", "3.2.3", "3.2.4", "3.2.5", "3.2.6", "3.2.7", "3.2.8", "3.2.9", "3.2.11", "3.2.12",
 		"3.2.13", "3.2.14", "3.2.15", "3.2.16", "3.2.23", "3.2.24", "3.2.25", "3.2.26", "3.3.0", "3.3.1", "3.3.8", "3.3.9", "3.3.10", "3.3.13"}
 	containerdValidVersions        = [...]string{"1.1.5", "1.1.6", "1.2.4"}
+	manifestOverlayComponents      = [...]string{"kube-apiserver", "kube-controller-manager", "cloud-controller-manager", "kube-scheduler", "kube-addon-manager"}
+	auditPolicyAPIVersions         = [...]string{"audit.k8s.io/v1", "audit.k8s.io/v1beta1", "audit.k8s.io/v1alpha1"}
+	auditLevels                    = [...]string{"None", "Metadata", "Request", "RequestResponse"}
+	auditStages                    = [...]string{"RequestReceived", "ResponseStarted", "ResponseComplete", "Panic"}
 	networkPluginPlusPolicyAllowed = []k8sNetworkConfig{
 		{
 			networkPlugin: "",
@@ -124,6 +134,9 @@ func (a *Properties) validate(isUpdate bool) error {
 	if e := a.validateAgentPoolProfiles(isUpdate); e != nil {
 		return e
 	}
+	if e := a.validateFallbackPools(isUpdate); e != nil {
+		return e
+	}
 	if e := a.validateZones(); e != nil {
 		return e
 	}
@@ -150,6 +163,10 @@ func (a *Properties) validate(isUpdate bool) error {
 	if e := a.validateAADProfile(); e != nil {
 		return e
 	}
+
+	if e := a.validateCertificateProfile(); e != nil {
+		return e
+	}
 	return nil
 }
 
@@ -215,7 +232,7 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 			}
 
 			if o.KubernetesConfig != nil {
-				err := o.KubernetesConfig.Validate(version, a.HasWindows(), a.FeatureFlags.IsIPv6DualStackEnabled())
+				err := o.KubernetesConfig.Validate(version, a.HasWindows(), a.IsIPv6DualStack())
 				if err != nil {
 					return err
 				}
@@ -301,6 +318,18 @@ func (a *Properties) ValidateOrchestratorProfile(isUpdate bool) error {
 				if o.KubernetesConfig.LoadBalancerSku == StandardLoadBalancerSku && o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes != 0 && (o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes < 4 || o.KubernetesConfig.OutboundRuleIdleTimeoutInMinutes > 120) {
 					return errors.New("outboundRuleIdleTimeoutInMinutes shouldn't be less than 4 or greater than 120")
 				}
+				if e := a.validateOutboundType(); e != nil {
+					return e
+				}
+
+				if o.KubernetesConfig.PrivateCluster != nil && to.Bool(o.KubernetesConfig.PrivateCluster.EnablePrivateDNSZone) {
+					if !to.Bool(o.KubernetesConfig.PrivateCluster.Enabled) {
+						return errors.New("enablePrivateDNSZone is only supported with privateCluster.enabled set to true")
+					}
+					if a.IsAzureStackCloud() {
+						return errors.New("enablePrivateDNSZone shouldn't be set to true as Azure Private DNS zones are not yet supported on Azure Stack")
+					}
+				}
 
 				if a.IsAzureStackCloud() {
 					if to.Bool(o.KubernetesConfig.UseInstanceMetadata) {
@@ -363,11 +392,15 @@ func (a *Properties) validateMasterProfile(isUpdate bool) error {
 		if m.IsVirtualMachineScaleSets() && m.VnetSubnetID != "" && m.FirstConsecutiveStaticIP != "" {
 			return errors.New("when masterProfile's availabilityProfile is VirtualMachineScaleSets and a vnetSubnetID is specified, the firstConsecutiveStaticIP should be empty and will be determined by an offset from the first IP in the vnetCidr")
 		}
-		// validate os type is linux if dual stack feature is enabled
-		if a.FeatureFlags.IsIPv6DualStackEnabled() {
+		// validate os type is linux if dual stack is enabled
+		if a.IsIPv6DualStack() {
 			if m.Distro == CoreOS {
 				return errors.Errorf("Dual stack feature is currently supported only with Ubuntu, but master is of distro type %s", m.Distro)
 			}
+			// the subnets and network interfaces of VMSS masters have no IPv6 configuration
+			if m.IsVirtualMachineScaleSets() {
+				return errors.Errorf("Dual stack feature is currently supported only with %s masters", AvailabilitySet)
+			}
 		}
 	}
 
@@ -375,6 +408,9 @@ func (a *Properties) validateMasterProfile(isUpdate bool) error {
 		if err := m.ImageRef.validateImageNameAndGroup(); err != nil {
 			return err
 		}
+		if err := m.ImageRef.validateImageVersion(); err != nil {
+			return err
+		}
 	}
 
 	if m.IsVirtualMachineScaleSets() && a.OrchestratorProfile.OrchestratorType == Kubernetes {
@@ -428,8 +464,8 @@ func (a *Properties) validateAgentPoolProfiles(isUpdate bool) error {
 			return e
 		}
 
-		// validate os type is linux if dual stack feature is enabled
-		if a.FeatureFlags.IsIPv6DualStackEnabled() {
+		// validate os type is linux if dual stack is enabled
+		if a.IsIPv6DualStack() {
 			if agentPoolProfile.OSType == Windows {
 				return errors.Errorf("Dual stack feature is supported only with Linux, but agent pool '%s' is of os type %s", agentPoolProfile.Name, agentPoolProfile.OSType)
 			}
@@ -479,7 +515,12 @@ func (a *Properties) validateAgentPoolProfiles(isUpdate bool) error {
 		}
 
 		if agentPoolProfile.ImageRef != nil {
-			return agentPoolProfile.ImageRef.validateImageNameAndGroup()
+			if e := agentPoolProfile.ImageRef.validateImageNameAndGroup(); e != nil {
+				return e
+			}
+			if e := agentPoolProfile.ImageRef.validateImageVersion(); e != nil {
+				return e
+			}
 		}
 
 		if e := agentPoolProfile.validateAvailabilityProfile(); e != nil {
@@ -546,6 +587,59 @@ func (a *Properties) validateAgentPoolProfiles(isUpdate bool) error {
 	return nil
 }
 
+// validateFallbackPools verifies that the fallback pool of each low priority pool is a regular priority pool of the
+// cluster, which the cluster-autoscaler can scale up when the low priority capacity is unavailable
+func (a *Properties) validateFallbackPools(isUpdate bool) error {
+	pools := make(map[string]*AgentPoolProfile)
+	for _, agentPoolProfile := range a.AgentPoolProfiles {
+		pools[agentPoolProfile.Name] = agentPoolProfile
+	}
+	for _, agentPoolProfile := range a.AgentPoolProfiles {
+		if agentPoolProfile.FallbackPool == "" {
+			continue
+		}
+		if !agentPoolProfile.IsVirtualMachineScaleSets() || agentPoolProfile.ScaleSetPriority != ScaleSetPriorityLow {
+			return errors.Errorf("agent pool %s has a fallbackPool, but fallbackPool is only supported with a scaleSetPriority of %s", agentPoolProfile.Name, ScaleSetPriorityLow)
+		}
+		fallback, ok := pools[agentPoolProfile.FallbackPool]
+		if !ok {
+			return errors.Errorf("the fallbackPool %s of agent pool %s does not exist", agentPoolProfile.FallbackPool, agentPoolProfile.Name)
+		}
+		if fallback.ScaleSetPriority == ScaleSetPriorityLow {
+			return errors.Errorf("the fallbackPool %s of agent pool %s must not be a low priority pool", fallback.Name, agentPoolProfile.Name)
+		}
+		if fallback.OSType != agentPoolProfile.OSType {
+			return errors.Errorf("the fallbackPool %s of agent pool %s must have the same osType", fallback.Name, agentPoolProfile.Name)
+		}
+		if !a.isClusterAutoscalerEnabled() {
+			return errors.Errorf("agent pool %s has a fallbackPool, which requires the cluster-autoscaler addon to be enabled", agentPoolProfile.Name)
+		}
+		// the fallback pools are selected by the priority expander of the cluster-autoscaler, which was added in 1.15
+		o := a.OrchestratorProfile
+		version := common.RationalizeReleaseAndVersion(o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, isUpdate, a.HasWindows())
+		if version == "" {
+			version = o.OrchestratorVersion
+		}
+		if !common.IsKubernetesVersionGe(version, "1.15.0") {
+			return errors.Errorf("agent pool %s has a fallbackPool, which requires Kubernetes version 1.15.0 or greater, but version is %s", agentPoolProfile.Name, version)
+		}
+	}
+	return nil
+}
+
+// isClusterAutoscalerEnabled returns true if the cluster-autoscaler addon is explicitly enabled
+func (a *Properties) isClusterAutoscalerEnabled() bool {
+	if a.OrchestratorProfile == nil || a.OrchestratorProfile.KubernetesConfig == nil {
+		return false
+	}
+	for _, addon := range a.OrchestratorProfile.KubernetesConfig.Addons {
+		if addon.Name == "cluster-autoscaler" {
+			return to.Bool(addon.Enabled)
+		}
+	}
+	return false
+}
+
 func (a *Properties) validateZones() error {
 	if a.OrchestratorProfile.OrchestratorType == Kubernetes {
 		// all zones or no zones should be defined for the cluster
@@ -601,6 +695,21 @@ func (a *Properties) validateAddons() error {
 				}
 			}
 
+			if addon.Chart != nil {
+				if addon.Data != "" || len(addon.Containers) > 0 {
+					return errors.Errorf("Addon %s cannot have data or containers when a chart is specified", addon.Name)
+				}
+				if addon.Chart.Path == "" {
+					return errors.Errorf("Addon %s's chart must have a path", addon.Name)
+				}
+			}
+
+			for i := range addon.Overlays {
+				if e := addon.Overlays[i].validate(); e != nil {
+					return errors.Errorf("Addon %s's overlay %d is invalid: %s", addon.Name, i, e)
+				}
+			}
+
 			switch addon.Name {
 			case "cluster-autoscaler":
 				if to.Bool(addon.Enabled) && isAvailabilitySets {
@@ -644,6 +753,11 @@ func (a *Properties) validateAddons() error {
 				if to.Bool(addon.Enabled) && a.HasCoreOS() {
 					return errors.New("flexvolume add-ons not currently supported on coreos distro. Please use Ubuntu")
 				}
+			case "aad-pod-identity":
+				// mic assigns the pod identities to the nodes, which the system assigned identities of the agents are not allowed to do
+				if to.Bool(addon.Enabled) && a.OrchestratorProfile.KubernetesConfig.UseManagedIdentity && a.OrchestratorProfile.KubernetesConfig.UserAssignedID == "" {
+					return errors.New("aad-pod-identity add-on can only be used with a user assigned identity when useManagedIdentity is true. Please specify \"userAssignedID\" in \"kubernetesConfig\"")
+				}
 			case "appgw-ingress":
 				if to.Bool(addon.Enabled) {
 					if (a.ServicePrincipalProfile == nil || len(a.ServicePrincipalProfile.ObjectID) == 0) &&
@@ -660,6 +774,18 @@ func (a *Properties) validateAddons() error {
 					}
 				}
 			}
+
+			if versions, ok := common.BuiltinAddonKubernetesVersions[addon.Name]; ok && to.Bool(addon.Enabled) {
+				version := common.RationalizeReleaseAndVersion(
+					a.OrchestratorProfile.OrchestratorType,
+					a.OrchestratorProfile.OrchestratorRelease,
+					a.OrchestratorProfile.OrchestratorVersion,
+					false,
+					a.HasWindows())
+				if version != "" && !common.IsKubernetesVersionInRange(version, versions.Range()) {
+					return errors.Errorf("%s add-on supports Kubernetes versions %s, it cannot be enabled with Kubernetes %s", addon.Name, versions.Range(), version)
+				}
+			}
 		}
 	}
 	return nil
@@ -736,6 +862,67 @@ func (a *Properties) validateVNET() error {
 				return errors.Errorf("MasterProfile.VnetCidr '%s' contains invalid cidr notation", a.MasterProfile.VnetCidr)
 			}
 		}
+
+		if e := a.validateCustomRouteTable(subscription); e != nil {
+			return e
+		}
+		if e := a.validateCustomNetworkSecurityGroup(subscription); e != nil {
+			return e
+		}
+	} else {
+		if a.MasterProfile.RouteTableID != "" || a.MasterProfile.NetworkSecurityGroupID != "" {
+			return errors.New("routeTableID and networkSecurityGroupID are only supported with a custom VNET, set vnetSubnetID in the master profile and each agent pool profile")
+		}
+		for _, agentPool := range a.AgentPoolProfiles {
+			if agentPool.RouteTableID != "" || agentPool.NetworkSecurityGroupID != "" {
+				return errors.New("routeTableID and networkSecurityGroupID are only supported with a custom VNET, set vnetSubnetID in the master profile and each agent pool profile")
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Properties) validateCustomRouteTable(vnetSubscription string) error {
+	routeTableID := a.MasterProfile.RouteTableID
+	for _, agentPool := range a.AgentPoolProfiles {
+		if agentPool.RouteTableID != "" && agentPool.RouteTableID != routeTableID {
+			return errors.Errorf("agent pool %s's routeTableID must be empty or equal to the master profile's routeTableID, all the nodes of the cluster share the same route table", agentPool.Name)
+		}
+	}
+	if routeTableID == "" {
+		return nil
+	}
+
+	if a.OrchestratorProfile.KubernetesConfig == nil || a.OrchestratorProfile.KubernetesConfig.NetworkPlugin != "kubenet" {
+		return errors.New("routeTableID is only supported with networkPlugin \"kubenet\", the route table holds the routes to the pod CIDRs of the nodes")
+	}
+	subscription, _, _, err := common.GetResourceIDComponents(routeTableID, "routeTables")
+	if err != nil {
+		return err
+	}
+	if subscription != vnetSubscription {
+		return errors.Errorf("routeTableID %s must be in the subscription of the VNET (%s)", routeTableID, vnetSubscription)
+	}
+	return nil
+}
+
+func (a *Properties) validateCustomNetworkSecurityGroup(vnetSubscription string) error {
+	nsgID := a.MasterProfile.NetworkSecurityGroupID
+	for _, agentPool := range a.AgentPoolProfiles {
+		if agentPool.NetworkSecurityGroupID != "" && agentPool.NetworkSecurityGroupID != nsgID {
+			return errors.Errorf("agent pool %s's networkSecurityGroupID must be empty or equal to the master profile's networkSecurityGroupID, all the nodes of the cluster share the same network security group", agentPool.Name)
+		}
+	}
+	if nsgID == "" {
+		return nil
+	}
+
+	subscription, _, _, err := common.GetResourceIDComponents(nsgID, "networkSecurityGroups")
+	if err != nil {
+		return err
+	}
+	if subscription != vnetSubscription {
+		return errors.Errorf("networkSecurityGroupID %s must be in the subscription of the VNET (%s)", nsgID, vnetSubscription)
 	}
 	return nil
 }
@@ -839,6 +1026,59 @@ func (a *Properties) validateAADProfile() error {
 	return nil
 }
 
+func (a *Properties) validateCertificateProfile() error {
+	if a.CertificateProfile == nil || a.CertificateProfile.PKIProfile == nil {
+		return nil
+	}
+	if a.OrchestratorProfile.OrchestratorType != Kubernetes {
+		return errors.Errorf("'pkiProfile' is only supported by orchestrator '%v'", Kubernetes)
+	}
+	p := a.CertificateProfile.PKIProfile
+	caValidityDays := int(helpers.ValidityDuration / (24 * time.Hour))
+	if p.CA != nil && p.CA.ValidityDays > 0 {
+		caValidityDays = p.CA.ValidityDays
+	}
+	for _, class := range []struct {
+		name    string
+		profile *PKICertificateProfile
+	}{
+		{"ca", p.CA},
+		{"apiServer", p.APIServer},
+		{"client", p.Client},
+		{"etcdServer", p.EtcdServer},
+		{"etcdClient", p.EtcdClient},
+		{"etcdPeer", p.EtcdPeer},
+	} {
+		c := class.profile
+		if c == nil {
+			continue
+		}
+		switch c.KeyAlgorithm {
+		case "", helpers.RSAKeyAlgorithm:
+			switch c.KeySize {
+			case 0, 2048, 3072, 4096:
+			default:
+				return errors.Errorf("pkiProfile.%s.keySize %d is invalid for RSA keys, expected 2048, 3072 or 4096", class.name, c.KeySize)
+			}
+		case helpers.ECDSAKeyAlgorithm:
+			switch c.KeySize {
+			case 0, 256, 384, 521:
+			default:
+				return errors.Errorf("pkiProfile.%s.keySize %d is invalid for ECDSA keys, expected 256, 384 or 521", class.name, c.KeySize)
+			}
+		default:
+			return errors.Errorf("pkiProfile.%s.keyAlgorithm '%s' is invalid, expected '%s' or '%s'", class.name, c.KeyAlgorithm, helpers.RSAKeyAlgorithm, helpers.ECDSAKeyAlgorithm)
+		}
+		if c.ValidityDays < 0 {
+			return errors.Errorf("pkiProfile.%s.validityDays %d must not be negative", class.name, c.ValidityDays)
+		}
+		if c.ValidityDays > caValidityDays {
+			return errors.Errorf("pkiProfile.%s.validityDays %d exceeds the %d days validity of the CA", class.name, c.ValidityDays, caValidityDays)
+		}
+	}
+	return nil
+}
+
 func (a *AgentPoolProfile) validateAvailabilityProfile() error {
 	switch a.AvailabilityProfile {
 	case AvailabilitySet:
@@ -1071,6 +1311,30 @@ func (w *WindowsProfile) Validate(orchestratorType string) error {
 			return errors.New("Windows Custom Images are only supported if the Orchestrator Type is DCOS or Kubernetes")
 		}
 	}
+	if w.ImageRef != nil {
+		if orchestratorType != Kubernetes {
+			return errors.New("WindowsProfile.imageReference is only supported if the Orchestrator Type is Kubernetes")
+		}
+		if w.WindowsImageSourceURL != "" {
+			return errors.New("WindowsProfile.imageReference and WindowsProfile.WindowsImageSourceUrl are mutually exclusive")
+		}
+		if err := w.ImageRef.validateImageNameAndGroup(); err != nil {
+			return err
+		}
+		if err := w.ImageRef.validateImageVersion(); err != nil {
+			return err
+		}
+		if (w.ImageRef.SubscriptionID != "" || w.ImageRef.Gallery != "" || w.ImageRef.Version != "") &&
+			(w.ImageRef.SubscriptionID == "" || w.ImageRef.Gallery == "" || w.ImageRef.Version == "") {
+			return errors.New("WindowsProfile.imageReference of a Shared Image Gallery needs subscriptionId, gallery and version")
+		}
+	}
+	if w.WindowsContainerdURL != "" {
+		u, err := url.Parse(w.WindowsContainerdURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("WindowsProfile.windowsContainerdURL '%s' is invalid, it must be an http or https URL", w.WindowsContainerdURL)
+		}
+	}
 	if e := validate.Var(w.AdminUsername, "required"); e != nil {
 		return errors.New("WindowsProfile.AdminUsername is required, when agent pool specifies windows")
 	}
@@ -1114,9 +1378,18 @@ func (k *KubernetesConfig) Validate(k8sVersion string, hasWindows, ipv6DualStack
 	// number of minimum retries allowed for kubelet to post node status
 	const minKubeletRetries = 4
 
-	// ipv6 dual stack feature is currently only supported with kubenet
-	if ipv6DualStackEnabled && k.NetworkPlugin != "kubenet" {
-		return errors.Errorf("OrchestratorProfile.KubernetesConfig.NetworkPlugin '%s' is invalid. IPv6 dual stack supported only with kubenet.", k.NetworkPlugin)
+	if err := k.validateIPFamilies(); err != nil {
+		return err
+	}
+
+	if ipv6DualStackEnabled {
+		if !common.IsKubernetesVersionGe(k8sVersion, "1.16.0-alpha.1") {
+			return errors.Errorf("IPv6 dual stack is only available in Kubernetes version 1.16.0-alpha.1 or greater, but version is %s", k8sVersion)
+		}
+		// ipv6 dual stack is currently only supported with kubenet
+		if k.NetworkPlugin != "kubenet" {
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.NetworkPlugin '%s' is invalid. IPv6 dual stack supported only with kubenet.", k.NetworkPlugin)
+		}
 	}
 
 	if k.ClusterSubnet != "" {
@@ -1128,11 +1401,14 @@ func (k *KubernetesConfig) Validate(k8sVersion string, hasWindows, ipv6DualStack
 			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ClusterSubnet '%s' is an invalid subnet. Not more than 2 subnets for ipv6 dual stack.", k.ClusterSubnet)
 		}
 
-		for _, clusterSubnet := range clusterSubnets {
-			_, subnet, err := net.ParseCIDR(clusterSubnet)
+		for i, clusterSubnet := range clusterSubnets {
+			ip, subnet, err := net.ParseCIDR(clusterSubnet)
 			if err != nil {
 				return errors.Errorf("OrchestratorProfile.KubernetesConfig.ClusterSubnet '%s' is an invalid subnet", clusterSubnet)
 			}
+			if len(clusterSubnets) == 2 && (ip.To4() != nil) != (i == 0) {
+				return errors.Errorf("OrchestratorProfile.KubernetesConfig.ClusterSubnet '%s' is invalid, the IPv4 subnet must be followed by the IPv6 subnet for ipv6 dual stack", k.ClusterSubnet)
+			}
 
 			if k.NetworkPlugin == "azure" {
 				ones, bits := subnet.Mask.Size()
@@ -1219,26 +1495,47 @@ func (k *KubernetesConfig) Validate(k8sVersion string, hasWindows, ipv6DualStack
 			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' is an invalid IP address", k.DNSServiceIP)
 		}
 
-		_, serviceCidr, err := net.ParseCIDR(k.ServiceCidr)
-		if err != nil {
+		// ipv6 dual stack clusters have an IPv4 and an IPv6 service CIDR, kube-dns listens on the IPv4 one
+		serviceCidrs := strings.Split(k.ServiceCidr, ",")
+		if !ipv6DualStackEnabled && len(serviceCidrs) > 1 {
 			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is an invalid CIDR subnet", k.ServiceCidr)
 		}
+		if ipv6DualStackEnabled && len(serviceCidrs) > 2 {
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is an invalid CIDR subnet. Not more than 2 CIDR subnets for ipv6 dual stack.", k.ServiceCidr)
+		}
+		if len(serviceCidrs) == 2 {
+			ip, _, err := net.ParseCIDR(serviceCidrs[1])
+			if err != nil {
+				return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is an invalid CIDR subnet", serviceCidrs[1])
+			}
+			if ip.To4() != nil {
+				return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is invalid, the IPv4 CIDR subnet must be followed by the IPv6 CIDR subnet for ipv6 dual stack", k.ServiceCidr)
+			}
+		}
+
+		ip, serviceCidr, err := net.ParseCIDR(serviceCidrs[0])
+		if err != nil {
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is an invalid CIDR subnet", serviceCidrs[0])
+		}
+		if len(serviceCidrs) == 2 && ip.To4() == nil {
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ServiceCidr '%s' is invalid, the IPv4 CIDR subnet must be followed by the IPv6 CIDR subnet for ipv6 dual stack", k.ServiceCidr)
+		}
 
 		// Finally validate that the DNS ip is within the subnet
 		if !serviceCidr.Contains(dnsIP) {
-			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' is not within the ServiceCidr '%s'", k.DNSServiceIP, k.ServiceCidr)
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' is not within the ServiceCidr '%s'", k.DNSServiceIP, serviceCidrs[0])
 		}
 
 		// and that the DNS IP is _not_ the subnet broadcast address
 		broadcast := common.IP4BroadcastAddress(serviceCidr)
 		if dnsIP.Equal(broadcast) {
-			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' cannot be the broadcast address of ServiceCidr '%s'", k.DNSServiceIP, k.ServiceCidr)
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' cannot be the broadcast address of ServiceCidr '%s'", k.DNSServiceIP, serviceCidrs[0])
 		}
 
 		// and that the DNS IP is _not_ the first IP in the service subnet
 		firstServiceIP := common.CidrFirstIP(serviceCidr.IP)
 		if firstServiceIP.Equal(dnsIP) {
-			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' cannot be the first IP of ServiceCidr '%s'", k.DNSServiceIP, k.ServiceCidr)
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.DNSServiceIP '%s' cannot be the first IP of ServiceCidr '%s'", k.DNSServiceIP, serviceCidrs[0])
 		}
 	}
 
@@ -1285,9 +1582,256 @@ func (k *KubernetesConfig) Validate(k8sVersion string, hasWindows, ipv6DualStack
 	if e := k.validateNetworkPluginPlusPolicy(); e != nil {
 		return e
 	}
+	if e := k.validateManifestOverlays(); e != nil {
+		return e
+	}
+	if e := k.validatePackageMirrorURL(); e != nil {
+		return e
+	}
+	if e := k.validateAuditProfile(k8sVersion); e != nil {
+		return e
+	}
 	return k.validatePrivateAzureRegistryServer()
 }
 
+func (k *KubernetesConfig) validateAuditProfile(k8sVersion string) error {
+	a := k.AuditProfile
+	if a == nil {
+		return nil
+	}
+	if (a.Policy != "" || a.WebhookURL != "" || a.WebhookKubeconfig != "") && !common.IsKubernetesVersionGe(k8sVersion, "1.8.0") {
+		return errors.Errorf("auditProfile.policy and the audit webhook are only supported with Kubernetes 1.8.0 and later, the version is %s", k8sVersion)
+	}
+	if a.Policy != "" {
+		if e := validateAuditPolicy(a.Policy, k8sVersion); e != nil {
+			return errors.Errorf("auditProfile.policy is invalid: %s", e)
+		}
+	}
+	rotation := []struct {
+		name  string
+		value *int
+	}{
+		{"logMaxAge", a.LogMaxAge},
+		{"logMaxBackup", a.LogMaxBackup},
+		{"logMaxSize", a.LogMaxSize},
+	}
+	for _, r := range rotation {
+		if r.value != nil && *r.value < 0 {
+			return errors.Errorf("auditProfile.%s %d is invalid, it must not be negative", r.name, *r.value)
+		}
+	}
+	if a.WebhookURL != "" {
+		if a.WebhookKubeconfig != "" {
+			return errors.New("auditProfile.webhookURL and auditProfile.webhookKubeconfig are mutually exclusive")
+		}
+		u, err := url.Parse(a.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("auditProfile.webhookURL '%s' is invalid, it must be an http or https URL", a.WebhookURL)
+		}
+	}
+	if a.WebhookKubeconfig != "" {
+		if e := validateAuditWebhookKubeconfig(a.WebhookKubeconfig); e != nil {
+			return errors.Errorf("auditProfile.webhookKubeconfig is invalid: %s", e)
+		}
+	}
+	return nil
+}
+
+// validateAuditPolicy parses the audit policy against the audit.k8s.io types, and checks its rules like the apiserver
+// does when it loads the policy
+func validateAuditPolicy(policy, k8sVersion string) error {
+	b, err := yaml.YAMLToJSON([]byte(policy))
+	if err != nil {
+		return errors.Errorf("the policy is not YAML or JSON: %s", err)
+	}
+	var p auditPolicy
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&p); err != nil {
+		return err
+	}
+	if !stringInSlice(p.APIVersion, auditPolicyAPIVersions[:]) {
+		return errors.Errorf("the apiVersion %s is not one of %s", p.APIVersion, strings.Join(auditPolicyAPIVersions[:], ", "))
+	}
+	if p.APIVersion == "audit.k8s.io/v1" && !common.IsKubernetesVersionGe(k8sVersion, "1.12.0") {
+		return errors.Errorf("the apiVersion %s requires Kubernetes 1.12.0 or later", p.APIVersion)
+	}
+	if p.Kind != "Policy" {
+		return errors.Errorf("the kind %s is not Policy", p.Kind)
+	}
+	if len(p.Rules) == 0 {
+		return errors.New("the policy has no rules")
+	}
+	if e := validateAuditStages(p.OmitStages); e != nil {
+		return e
+	}
+	for i, rule := range p.Rules {
+		if !stringInSlice(rule.Level, auditLevels[:]) {
+			return errors.Errorf("rule %d: the level %s is not one of %s", i, rule.Level, strings.Join(auditLevels[:], ", "))
+		}
+		if len(rule.NonResourceURLs) > 0 && (len(rule.Resources) > 0 || len(rule.Namespaces) > 0) {
+			return errors.Errorf("rule %d: a rule cannot apply to both resources and non-resource URLs", i)
+		}
+		for _, u := range rule.NonResourceURLs {
+			if !strings.HasPrefix(u, "/") || strings.Contains(strings.TrimSuffix(u, "*"), "*") {
+				return errors.Errorf("rule %d: the non-resource URL %s must start with / and may only end with a * wildcard", i, u)
+			}
+		}
+		for _, r := range rule.Resources {
+			if len(r.ResourceNames) > 0 && len(r.Resources) == 0 {
+				return errors.Errorf("rule %d: resourceNames require resources", i)
+			}
+		}
+		if e := validateAuditStages(rule.OmitStages); e != nil {
+			return errors.Errorf("rule %d: %s", i, e)
+		}
+	}
+	return nil
+}
+
+func validateAuditStages(stages []string) error {
+	for _, stage := range stages {
+		if !stringInSlice(stage, auditStages[:]) {
+			return errors.Errorf("the stage %s is not one of %s", stage, strings.Join(auditStages[:], ", "))
+		}
+	}
+	return nil
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAuditWebhookKubeconfig checks that the kubeconfig of the audit webhook backend selects a cluster with a server
+func validateAuditWebhookKubeconfig(kubeconfig string) error {
+	var config clientcmdv1.Config
+	if err := yaml.Unmarshal([]byte(kubeconfig), &config); err != nil {
+		return errors.Errorf("the kubeconfig is not YAML or JSON: %s", err)
+	}
+	cluster := ""
+	for _, c := range config.Contexts {
+		if c.Name == config.CurrentContext {
+			cluster = c.Context.Cluster
+		}
+	}
+	if cluster == "" {
+		return errors.Errorf("the current context '%s' does not select a cluster", config.CurrentContext)
+	}
+	for _, c := range config.Clusters {
+		if c.Name == cluster {
+			if c.Cluster.Server == "" {
+				return errors.Errorf("the cluster %s has no server", cluster)
+			}
+			return nil
+		}
+	}
+	return errors.Errorf("the cluster %s of the current context is not defined", cluster)
+}
+
+func (k *KubernetesConfig) validateManifestOverlays() error {
+	for component, overlays := range k.ManifestOverlays {
+		valid := false
+		for _, c := range manifestOverlayComponents {
+			if component == c {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.Errorf("OrchestratorProfile.KubernetesConfig.ManifestOverlays has an invalid component %s, the valid components are %s", component, strings.Join(manifestOverlayComponents[:], ", "))
+		}
+		for i := range overlays {
+			if e := overlays[i].validate(); e != nil {
+				return errors.Errorf("OrchestratorProfile.KubernetesConfig.ManifestOverlays %s overlay %d is invalid: %s", component, i, e)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that an overlay is well-formed. Whether its target exists in the manifest is checked when the
+// manifest is generated.
+func (o *KubernetesOverlay) validate() error {
+	if o.Patch == "" {
+		return errors.New("the patch is empty")
+	}
+	switch o.Type {
+	case "", OverlayTypeStrategicMerge:
+		var patch map[string]interface{}
+		if err := yaml.Unmarshal([]byte(o.Patch), &patch); err != nil {
+			return errors.Errorf("the patch is not a YAML or JSON object: %s", err)
+		}
+		if o.Target == nil {
+			metadata, _ := patch["metadata"].(map[string]interface{})
+			if patch["kind"] == nil || metadata == nil || metadata["name"] == nil {
+				return errors.New("a strategic merge patch without target must have a kind and a metadata.name")
+			}
+		}
+	case OverlayTypeJSON6902:
+		var operations []map[string]interface{}
+		if err := yaml.Unmarshal([]byte(o.Patch), &operations); err != nil {
+			return errors.Errorf("the patch is not a YAML or JSON list of operations: %s", err)
+		}
+		if o.Target == nil {
+			return errors.New("a json6902 overlay must have a target")
+		}
+	default:
+		return errors.Errorf("the type %s is not one of %s, %s", o.Type, OverlayTypeStrategicMerge, OverlayTypeJSON6902)
+	}
+	if o.Target != nil && (o.Target.Kind == "" || o.Target.Name == "") {
+		return errors.New("the target must have a kind and a name")
+	}
+	return nil
+}
+
+func (a *Properties) validateOutboundType() error {
+	k := a.OrchestratorProfile.KubernetesConfig
+	switch k.OutboundType {
+	case "", OutboundTypeLoadBalancer:
+		if k.OutboundNextHopIPAddress != "" {
+			return errors.Errorf("outboundNextHopIPAddress is only supported with outboundType \"%s\"", OutboundTypeUserDefinedRouting)
+		}
+		return nil
+	case OutboundTypeUserDefinedRouting:
+	default:
+		return errors.Errorf("unknown outboundType '%s' specified, valid values are %s and %s", k.OutboundType, OutboundTypeLoadBalancer, OutboundTypeUserDefinedRouting)
+	}
+
+	if k.OutboundNextHopIPAddress != "" {
+		if ip := net.ParseIP(k.OutboundNextHopIPAddress); ip == nil || ip.To4() == nil {
+			return errors.Errorf("outboundNextHopIPAddress '%s' is not a valid IPv4 address", k.OutboundNextHopIPAddress)
+		}
+	} else if a.MasterProfile == nil || a.MasterProfile.RouteTableID == "" {
+		// a custom route table is expected to already hold the default route of the cluster
+		return errors.Errorf("outboundNextHopIPAddress must be provided with outboundType \"%s\", unless the routeTableID of a custom VNET holds the default route", OutboundTypeUserDefinedRouting)
+	}
+	if a.IsIPv6DualStack() {
+		return errors.Errorf("outboundType \"%s\" is not supported with IPv6 dual stack", OutboundTypeUserDefinedRouting)
+	}
+	for _, agentPool := range a.AgentPoolProfiles {
+		if to.Bool(agentPool.EnableVMSSNodePublicIP) {
+			return errors.Errorf("agent pool %s has enableVMSSNodePublicIP set to true, agents have no public IP with outboundType \"%s\"", agentPool.Name, OutboundTypeUserDefinedRouting)
+		}
+	}
+	return nil
+}
+
+func (k *KubernetesConfig) validatePackageMirrorURL() error {
+	if k.PackageMirrorURL == "" {
+		return nil
+	}
+	u, err := url.Parse(k.PackageMirrorURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("packageMirrorURL '%s' is invalid, it must be an http or https URL", k.PackageMirrorURL)
+	}
+	return nil
+}
+
 func (k *KubernetesConfig) validatePrivateAzureRegistryServer() error {
 
 	// Check PrivateAzureRegistryServer has a valid value.
@@ -1307,6 +1851,24 @@ func (k *KubernetesConfig) validatePrivateAzureRegistryServer() error {
 	return nil
 }
 
+func (k *KubernetesConfig) validateIPFamilies() error {
+	seen := map[string]bool{}
+	for _, family := range k.IPFamilies {
+		if family != IPFamilyIPv4 && family != IPFamilyIPv6 {
+			return errors.Errorf("unknown ipFamily '%s' specified, valid values are %s and %s", family, IPFamilyIPv4, IPFamilyIPv6)
+		}
+		if seen[family] {
+			return errors.Errorf("ipFamily '%s' is specified more than once", family)
+		}
+		seen[family] = true
+	}
+	// IPv6 single stack clusters are not supported, the IPv6 family comes with the IPv4 one
+	if len(k.IPFamilies) > 0 && k.IPFamilies[0] != IPFamilyIPv4 {
+		return errors.Errorf("OrchestratorProfile.KubernetesConfig.IPFamilies '%s' is invalid, the first ipFamily must be %s", strings.Join(k.IPFamilies, ","), IPFamilyIPv4)
+	}
+	return nil
+}
+
 func (k *KubernetesConfig) validateNetworkPlugin() error {
 
 	networkPlugin := k.NetworkPlugin
@@ -1394,8 +1956,23 @@ func (a *Properties) validateContainerRuntime() error {
 	}
 
 	// Make sure we don't use unsupported container runtimes on windows.
-	if (containerRuntime == KataContainers || containerRuntime == Containerd) && a.HasWindows() {
-		return errors.Errorf("containerRuntime %q is not supporting windows agents", containerRuntime)
+	if a.HasWindows() {
+		switch containerRuntime {
+		case KataContainers:
+			return errors.Errorf("containerRuntime %q is not supporting windows agents", containerRuntime)
+		case Containerd:
+			o := a.OrchestratorProfile
+			version := common.RationalizeReleaseAndVersion(o.OrchestratorType, o.OrchestratorRelease, o.OrchestratorVersion, false, true)
+			if version == "" {
+				version = o.OrchestratorVersion
+			}
+			if !common.IsKubernetesVersionGe(version, "1.18.0") {
+				return errors.Errorf("containerRuntime %q with windows agents requires Kubernetes version 1.18.0 or greater, but version is %s", containerRuntime, version)
+			}
+			if a.WindowsProfile != nil && strings.Contains(a.WindowsProfile.WindowsSku, "1803") {
+				return errors.Errorf("containerRuntime %q with windows agents requires Windows Server 2019 or later, but windowsSku is %s", containerRuntime, a.WindowsProfile.WindowsSku)
+			}
+		}
 	}
 
 	return nil
@@ -1511,6 +2088,13 @@ func (i *ImageReference) validateImageNameAndGroup() error {
 	return nil
 }
 
+func (i *ImageReference) validateImageVersion() error {
+	if i.Version != "" && i.Version != ImageVersionLatest && !imageVersionRegex.MatchString(i.Version) {
+		return errors.Errorf("imageReference.version %s is invalid, it must be %s or of the form Major.Minor.Patch", i.Version, ImageVersionLatest)
+	}
+	return nil
+}
+
 func (cs *ContainerService) validateCustomCloudProfile() error {
 	a := cs.Properties
 	if a.CustomCloudProfile != nil {
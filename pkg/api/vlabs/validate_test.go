@@ -311,6 +311,121 @@ func Test_OrchestratorProfile_Validate(t *testing.T) {
 			},
 			expectedError: "outboundRuleIdleTimeoutInMinutes shouldn't be less than 4 or greater than 120",
 		},
+		"should error when outboundType is unknown": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundType: "natGateway",
+					},
+				},
+			},
+			expectedError: "unknown outboundType 'natGateway' specified, valid values are loadBalancer and userDefinedRouting",
+		},
+		"should error when outboundNextHopIPAddress is set with outboundType loadBalancer": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundNextHopIPAddress: "10.0.0.4",
+					},
+				},
+			},
+			expectedError: "outboundNextHopIPAddress is only supported with outboundType \"userDefinedRouting\"",
+		},
+		"should error when outboundType userDefinedRouting has no next hop": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundType: OutboundTypeUserDefinedRouting,
+					},
+				},
+				MasterProfile: &MasterProfile{},
+			},
+			expectedError: "outboundNextHopIPAddress must be provided with outboundType \"userDefinedRouting\", unless the routeTableID of a custom VNET holds the default route",
+		},
+		"should error when outboundNextHopIPAddress is not an IPv4 address": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundType:             OutboundTypeUserDefinedRouting,
+						OutboundNextHopIPAddress: "fd00::4",
+					},
+				},
+			},
+			expectedError: "outboundNextHopIPAddress 'fd00::4' is not a valid IPv4 address",
+		},
+		"should error when outboundType userDefinedRouting is used with VMSS node public IPs": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundType:             OutboundTypeUserDefinedRouting,
+						OutboundNextHopIPAddress: "10.0.0.4",
+					},
+				},
+				AgentPoolProfiles: []*AgentPoolProfile{
+					{
+						Name:                   "agentpool",
+						AvailabilityProfile:    VirtualMachineScaleSets,
+						EnableVMSSNodePublicIP: to.BoolPtr(true),
+					},
+				},
+			},
+			expectedError: "agent pool agentpool has enableVMSSNodePublicIP set to true, agents have no public IP with outboundType \"userDefinedRouting\"",
+		},
+		"should not error when outboundType userDefinedRouting uses the default route of a custom route table": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						OutboundType: OutboundTypeUserDefinedRouting,
+					},
+				},
+				MasterProfile: &MasterProfile{
+					RouteTableID: "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/ROUTE_TABLE_NAME",
+				},
+			},
+		},
+		"should error when packageMirrorURL is not an http URL": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						PackageMirrorURL: "ftp://mirror.example.com/ubuntu",
+					},
+				},
+			},
+			expectedError: "packageMirrorURL 'ftp://mirror.example.com/ubuntu' is invalid, it must be an http or https URL",
+		},
+		"should error when enablePrivateDNSZone is set on a public cluster": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						PrivateCluster: &PrivateCluster{
+							EnablePrivateDNSZone: to.BoolPtr(true),
+						},
+					},
+				},
+			},
+			expectedError: "enablePrivateDNSZone is only supported with privateCluster.enabled set to true",
+		},
+		"should not error when enablePrivateDNSZone is set on a private cluster": {
+			properties: &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: "Kubernetes",
+					KubernetesConfig: &KubernetesConfig{
+						PrivateCluster: &PrivateCluster{
+							Enabled:              to.BoolPtr(true),
+							EnablePrivateDNSZone: to.BoolPtr(true),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for testName, test := range tests {
@@ -1194,6 +1309,60 @@ func TestProperties_ValidateInvalidStruct(t *testing.T) {
 	}
 }
 
+func TestValidateCertificateProfile(t *testing.T) {
+	t.Run("Valid pkiProfiles should pass", func(t *testing.T) {
+		t.Parallel()
+		cs := getK8sDefaultContainerService(false)
+		for _, pkiProfile := range []*PKIProfile{
+			{},
+			{
+				CA:        &PKICertificateProfile{KeyAlgorithm: "RSA", KeySize: 4096, ValidityDays: 3650},
+				APIServer: &PKICertificateProfile{KeyAlgorithm: "ECDSA", ValidityDays: 365},
+				Client:    &PKICertificateProfile{KeyAlgorithm: "ECDSA", KeySize: 384},
+				EtcdPeer:  &PKICertificateProfile{KeySize: 2048},
+			},
+		} {
+			cs.Properties.CertificateProfile = &CertificateProfile{PKIProfile: pkiProfile}
+			if err := cs.Properties.validateCertificateProfile(); err != nil {
+				t.Errorf("should not error %v", err)
+			}
+		}
+	})
+
+	t.Run("Invalid pkiProfiles should NOT pass", func(t *testing.T) {
+		t.Parallel()
+		cs := getK8sDefaultContainerService(false)
+		for _, pkiProfile := range []*PKIProfile{
+			{CA: &PKICertificateProfile{KeyAlgorithm: "DSA"}},
+			{APIServer: &PKICertificateProfile{KeyAlgorithm: "RSA", KeySize: 1024}},
+			{EtcdServer: &PKICertificateProfile{KeyAlgorithm: "ECDSA", KeySize: 4096}},
+			{EtcdClient: &PKICertificateProfile{ValidityDays: -1}},
+			{
+				CA:     &PKICertificateProfile{ValidityDays: 365},
+				Client: &PKICertificateProfile{ValidityDays: 730},
+			},
+			{EtcdPeer: &PKICertificateProfile{ValidityDays: 365 * 31}},
+		} {
+			cs.Properties.CertificateProfile = &CertificateProfile{PKIProfile: pkiProfile}
+			if err := cs.Properties.validateCertificateProfile(); err == nil {
+				t.Errorf("error should have occurred for %+v", pkiProfile)
+			}
+		}
+	})
+
+	t.Run("pkiProfile should not be supported by non-Kubernetes orchestrators", func(t *testing.T) {
+		t.Parallel()
+		cs := getK8sDefaultContainerService(false)
+		cs.Properties.OrchestratorProfile = &OrchestratorProfile{
+			OrchestratorType: DCOS,
+		}
+		cs.Properties.CertificateProfile = &CertificateProfile{PKIProfile: &PKIProfile{}}
+		if err := cs.Properties.validateCertificateProfile(); err == nil {
+			t.Errorf("error should have occurred")
+		}
+	})
+}
+
 func getK8sDefaultContainerService(hasWindows bool) *ContainerService {
 	p := &Properties{
 		OrchestratorProfile: &OrchestratorProfile{
@@ -1286,16 +1455,32 @@ func Test_Properties_ValidateContainerRuntime(t *testing.T) {
 	}
 
 	p.OrchestratorProfile.KubernetesConfig.ContainerRuntime = Containerd
+	p.OrchestratorProfile.OrchestratorVersion = "1.18.0"
 	p.AgentPoolProfiles = []*AgentPoolProfile{
 		{
 			OSType: Windows,
 		},
 	}
-	if err := p.validateContainerRuntime(); err == nil {
+	if err := p.validateContainerRuntime(); err != nil {
 		t.Errorf(
-			"should error on containerd for windows clusters",
+			"should not error on containerd for windows clusters, got %s", err,
 		)
 	}
+
+	p.OrchestratorProfile.OrchestratorVersion = "1.15.3"
+	expectedMsg := "containerRuntime \"containerd\" with windows agents requires Kubernetes version 1.18.0 or greater, but version is 1.15.3"
+	if err := p.validateContainerRuntime(); err == nil || err.Error() != expectedMsg {
+		t.Errorf("expected error %s, but got %v", expectedMsg, err)
+	}
+
+	p.OrchestratorProfile.OrchestratorVersion = "1.18.0"
+	p.WindowsProfile = &WindowsProfile{
+		WindowsSku: "Datacenter-Core-1803-with-Containers-smalldisk",
+	}
+	expectedMsg = "containerRuntime \"containerd\" with windows agents requires Windows Server 2019 or later, but windowsSku is Datacenter-Core-1803-with-Containers-smalldisk"
+	if err := p.validateContainerRuntime(); err == nil || err.Error() != expectedMsg {
+		t.Errorf("expected error %s, but got %v", expectedMsg, err)
+	}
 }
 
 func Test_Properties_ValidateAddons(t *testing.T) {
@@ -1465,6 +1650,31 @@ func Test_Properties_ValidateAddons(t *testing.T) {
 		)
 	}
 
+	// aad-pod-identity add-on needs a user assigned identity with useManagedIdentity
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		UseManagedIdentity: true,
+		Addons: []KubernetesAddon{
+			{
+				Name:    "aad-pod-identity",
+				Enabled: to.BoolPtr(true),
+			},
+		},
+	}
+
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error using aad-pod-identity with a system assigned identity",
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.UserAssignedID = "clusterIdentity"
+	if err := p.validateAddons(); err != nil {
+		t.Error(
+			"should not error using aad-pod-identity with a user assigned identity",
+			err,
+		)
+	}
+
 	// appgw-ingress add-on
 
 	// Basic test with UseManagedIdentity
@@ -1547,30 +1757,410 @@ func Test_Properties_ValidateAddons(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	if err := p.validateAddons(); err == nil {
-		t.Errorf(
-			"should error using when not using 'azure' for Network Plugin",
-		)
-	}
-
-	// Test with missing appgw-subnet
-	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
-		NetworkPlugin: "azure",
-		Addons: []KubernetesAddon{
-			{
-				Name:    "appgw-ingress",
-				Enabled: to.BoolPtr(true),
-				Config:  map[string]string{},
-			},
+	}
+
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error using when not using 'azure' for Network Plugin",
+		)
+	}
+
+	// Test with missing appgw-subnet
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		NetworkPlugin: "azure",
+		Addons: []KubernetesAddon{
+			{
+				Name:    "appgw-ingress",
+				Enabled: to.BoolPtr(true),
+				Config:  map[string]string{},
+			},
+		},
+	}
+
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error when missing the subnet for Application Gateway",
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		Addons: []KubernetesAddon{
+			{
+				Name:    "my-chart",
+				Enabled: to.BoolPtr(true),
+				Chart: &KubernetesAddonChart{
+					Values: map[string]interface{}{"replicas": 2},
+				},
+			},
+		},
+	}
+
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error when a chart has no path",
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Chart.Path = "charts/my-chart-1.0.0.tgz"
+	if err := p.validateAddons(); err != nil {
+		t.Errorf(
+			"should not error on a chart with a path: %s", err,
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Data = "YXBpVmVyc2lvbjogdjE="
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error when a chart is specified with data",
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		Addons: []KubernetesAddon{
+			{
+				Name: "kube-proxy",
+				Overlays: []KubernetesOverlay{
+					{
+						Type:  OverlayTypeJSON6902,
+						Patch: "- op: add\n  path: /spec/template/spec/tolerations/-\n  value: {operator: Exists}\n",
+					},
+				},
+			},
+		},
+	}
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error when a json6902 overlay has no target",
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Overlays[0].Target = &KubernetesOverlayTarget{Kind: "DaemonSet", Name: "kube-proxy"}
+	if err := p.validateAddons(); err != nil {
+		t.Errorf(
+			"should not error on a json6902 overlay with a target: %s", err,
+		)
+	}
+
+	p.AgentPoolProfiles = []*AgentPoolProfile{
+		{
+			AvailabilityProfile: VirtualMachineScaleSets,
+		},
+	}
+	p.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+		Addons: []KubernetesAddon{
+			{
+				Name:    "container-monitoring",
+				Enabled: to.BoolPtr(true),
+			},
+		},
+	}
+	p.OrchestratorProfile.OrchestratorRelease = "1.6"
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error on container-monitoring with k8s < 1.7",
+		)
+	}
+
+	p.OrchestratorProfile.OrchestratorRelease = "1.7"
+	if err := p.validateAddons(); err != nil {
+		t.Errorf(
+			"should not error on container-monitoring with k8s >= 1.7: %s", err,
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Enabled = to.BoolPtr(false)
+	p.OrchestratorProfile.OrchestratorRelease = "1.6"
+	if err := p.validateAddons(); err != nil {
+		t.Errorf(
+			"should not error on a disabled addon with an unsupported k8s version: %s", err,
+		)
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons = []KubernetesAddon{
+		{
+			Name:    "rescheduler",
+			Enabled: to.BoolPtr(true),
+		},
+	}
+	p.OrchestratorProfile.OrchestratorRelease = "1.16"
+	if err := p.validateAddons(); err == nil {
+		t.Errorf(
+			"should error on rescheduler with k8s >= 1.16",
+		)
+	}
+
+	p.OrchestratorProfile.OrchestratorRelease = "1.15"
+	if err := p.validateAddons(); err != nil {
+		t.Errorf(
+			"should not error on rescheduler with k8s < 1.16: %s", err,
+		)
+	}
+}
+
+func TestKubernetesOverlayValidate(t *testing.T) {
+	cases := []struct {
+		name          string
+		overlay       KubernetesOverlay
+		expectedError string
+	}{
+		{
+			name:    "strategic merge patch",
+			overlay: KubernetesOverlay{Patch: "kind: Deployment\nmetadata:\n  name: coredns\n"},
+		},
+		{
+			name: "strategic merge patch with target",
+			overlay: KubernetesOverlay{
+				Type:   OverlayTypeStrategicMerge,
+				Target: &KubernetesOverlayTarget{Kind: "Deployment", Name: "coredns"},
+				Patch:  `{"spec": {"replicas": 2}}`,
+			},
+		},
+		{
+			name: "json6902 patch",
+			overlay: KubernetesOverlay{
+				Type:   OverlayTypeJSON6902,
+				Target: &KubernetesOverlayTarget{Kind: "Deployment", Name: "coredns", Namespace: "kube-system"},
+				Patch:  `[{"op": "replace", "path": "/spec/replicas", "value": 2}]`,
+			},
+		},
+		{
+			name:          "empty patch",
+			overlay:       KubernetesOverlay{},
+			expectedError: "the patch is empty",
+		},
+		{
+			name:          "strategic merge patch without kind",
+			overlay:       KubernetesOverlay{Patch: "metadata:\n  name: coredns\n"},
+			expectedError: "a strategic merge patch without target must have a kind and a metadata.name",
+		},
+		{
+			name:          "strategic merge patch which is a list",
+			overlay:       KubernetesOverlay{Patch: "- op: remove\n"},
+			expectedError: "the patch is not a YAML or JSON object: error unmarshaling JSON: json: cannot unmarshal array into Go value of type map[string]interface {}",
+		},
+		{
+			name: "json6902 patch which is an object",
+			overlay: KubernetesOverlay{
+				Type:   OverlayTypeJSON6902,
+				Target: &KubernetesOverlayTarget{Kind: "Deployment", Name: "coredns"},
+				Patch:  "kind: Deployment\n",
+			},
+			expectedError: "the patch is not a YAML or JSON list of operations: error unmarshaling JSON: json: cannot unmarshal object into Go value of type []map[string]interface {}",
+		},
+		{
+			name: "target without name",
+			overlay: KubernetesOverlay{
+				Type:   OverlayTypeJSON6902,
+				Target: &KubernetesOverlayTarget{Kind: "Deployment"},
+				Patch:  `[{"op": "remove", "path": "/spec/replicas"}]`,
+			},
+			expectedError: "the target must have a kind and a name",
+		},
+		{
+			name:          "unknown type",
+			overlay:       KubernetesOverlay{Type: "merge", Patch: "{}"},
+			expectedError: "the type merge is not one of strategicMerge, json6902",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			err := c.overlay.validate()
+			if c.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.expectedError {
+				t.Fatalf("expected error %q, got %v", c.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateManifestOverlays(t *testing.T) {
+	k := &KubernetesConfig{
+		ManifestOverlays: map[string][]KubernetesOverlay{
+			"kube-apiserver": {
+				{Patch: "kind: Pod\nmetadata:\n  name: kube-apiserver\n"},
+			},
+		},
+	}
+	if err := k.validateManifestOverlays(); err != nil {
+		t.Errorf("should not error on a kube-apiserver overlay: %s", err)
+	}
+
+	k.ManifestOverlays["etcd"] = k.ManifestOverlays["kube-apiserver"]
+	if err := k.validateManifestOverlays(); err == nil {
+		t.Errorf("should error on an overlay of an unknown component")
+	}
+
+	delete(k.ManifestOverlays, "etcd")
+	k.ManifestOverlays["kube-scheduler"] = []KubernetesOverlay{{Type: OverlayTypeJSON6902, Patch: "[]"}}
+	if err := k.validateManifestOverlays(); err == nil {
+		t.Errorf("should error on an invalid overlay")
+	}
+}
+
+func TestValidateAuditProfile(t *testing.T) {
+	policy := `apiVersion: audit.k8s.io/v1beta1
+kind: Policy
+omitStages:
+  - RequestReceived
+rules:
+  - level: RequestResponse
+    resources:
+    - group: ""
+      resources: ["pods"]
+  - level: None
+    userGroups: ["system:authenticated"]
+    nonResourceURLs:
+    - /api*
+    - /version
+  - level: Metadata
+`
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: audit
+  cluster:
+    server: https://audit.example.com/events
+    certificate-authority-data: Y2E=
+users:
+- name: audit
+  user:
+    token: secret
+contexts:
+- name: audit
+  context:
+    cluster: audit
+    user: audit
+current-context: audit
+`
+	cases := []struct {
+		name          string
+		k8sVersion    string
+		profile       AuditProfile
+		expectedError string
+	}{
+		{
+			name:    "policy and log rotation",
+			profile: AuditProfile{Policy: policy, LogMaxAge: to.IntPtr(0), LogMaxBackup: to.IntPtr(5), LogMaxSize: to.IntPtr(200)},
+		},
+		{
+			name:    "JSON policy",
+			profile: AuditProfile{Policy: `{"apiVersion": "audit.k8s.io/v1", "kind": "Policy", "rules": [{"level": "Metadata"}]}`},
+		},
+		{
+			name:    "webhook URL",
+			profile: AuditProfile{WebhookURL: "https://audit.example.com/events"},
+		},
+		{
+			name:    "webhook kubeconfig",
+			profile: AuditProfile{WebhookKubeconfig: kubeconfig},
+		},
+		{
+			name:          "policy before 1.8.0",
+			k8sVersion:    "1.7.16",
+			profile:       AuditProfile{Policy: policy},
+			expectedError: "auditProfile.policy and the audit webhook are only supported with Kubernetes 1.8.0 and later, the version is 1.7.16",
+		},
+		{
+			name:          "policy which is not YAML",
+			profile:       AuditProfile{Policy: "rules: ["},
+			expectedError: "auditProfile.policy is invalid: the policy is not YAML or JSON: yaml: line 1: did not find expected node content",
+		},
+		{
+			name:          "policy with an unknown field",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n  user: [admin]\n"},
+			expectedError: `auditProfile.policy is invalid: json: unknown field "user"`,
+		},
+		{
+			name:          "policy of an unknown API version",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v2\nkind: Policy\nrules:\n- level: Metadata\n"},
+			expectedError: "auditProfile.policy is invalid: the apiVersion audit.k8s.io/v2 is not one of audit.k8s.io/v1, audit.k8s.io/v1beta1, audit.k8s.io/v1alpha1",
+		},
+		{
+			name:          "v1 policy before 1.12.0",
+			k8sVersion:    "1.11.10",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"},
+			expectedError: "auditProfile.policy is invalid: the apiVersion audit.k8s.io/v1 requires Kubernetes 1.12.0 or later",
+		},
+		{
+			name:          "policy without rules",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\n"},
+			expectedError: "auditProfile.policy is invalid: the policy has no rules",
+		},
+		{
+			name:          "rule with an unknown level",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: All\n"},
+			expectedError: "auditProfile.policy is invalid: rule 0: the level All is not one of None, Metadata, Request, RequestResponse",
+		},
+		{
+			name:          "rule with resources and non-resource URLs",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: None\n  namespaces: [default]\n  nonResourceURLs: [/healthz]\n"},
+			expectedError: "auditProfile.policy is invalid: rule 0: a rule cannot apply to both resources and non-resource URLs",
+		},
+		{
+			name:          "rule with an invalid non-resource URL",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: None\n  nonResourceURLs: [/api/*/version]\n"},
+			expectedError: "auditProfile.policy is invalid: rule 0: the non-resource URL /api/*/version must start with / and may only end with a * wildcard",
+		},
+		{
+			name:          "rule with an unknown stage",
+			profile:       AuditProfile{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n  omitStages: [RequestSent]\n"},
+			expectedError: "auditProfile.policy is invalid: rule 0: the stage RequestSent is not one of RequestReceived, ResponseStarted, ResponseComplete, Panic",
+		},
+		{
+			name:          "negative log rotation setting",
+			profile:       AuditProfile{LogMaxSize: to.IntPtr(-1)},
+			expectedError: "auditProfile.logMaxSize -1 is invalid, it must not be negative",
+		},
+		{
+			name:          "webhook URL and kubeconfig",
+			profile:       AuditProfile{WebhookURL: "https://audit.example.com/events", WebhookKubeconfig: kubeconfig},
+			expectedError: "auditProfile.webhookURL and auditProfile.webhookKubeconfig are mutually exclusive",
+		},
+		{
+			name:          "webhook URL which is not http",
+			profile:       AuditProfile{WebhookURL: "audit.example.com"},
+			expectedError: "auditProfile.webhookURL 'audit.example.com' is invalid, it must be an http or https URL",
+		},
+		{
+			name:          "webhook kubeconfig without current context",
+			profile:       AuditProfile{WebhookKubeconfig: strings.Replace(kubeconfig, "current-context: audit", "", 1)},
+			expectedError: "auditProfile.webhookKubeconfig is invalid: the current context '' does not select a cluster",
+		},
+		{
+			name:          "webhook kubeconfig without server",
+			profile:       AuditProfile{WebhookKubeconfig: strings.Replace(kubeconfig, "server: https://audit.example.com/events", "", 1)},
+			expectedError: "auditProfile.webhookKubeconfig is invalid: the cluster audit has no server",
 		},
 	}
 
-	if err := p.validateAddons(); err == nil {
-		t.Errorf(
-			"should error when missing the subnet for Application Gateway",
-		)
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			k8sVersion := c.k8sVersion
+			if k8sVersion == "" {
+				k8sVersion = "1.15.7"
+			}
+			k := &KubernetesConfig{AuditProfile: &c.profile}
+			err := k.validateAuditProfile(k8sVersion)
+			if c.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.expectedError {
+				t.Fatalf("expected error %q, got %v", c.expectedError, err)
+			}
+		})
 	}
 }
 
@@ -1701,6 +2291,39 @@ func TestValidateImageNameAndGroup(t *testing.T) {
 			},
 			expectedErr: errors.New(`imageResourceGroup needs to be specified when imageName is provided`),
 		},
+		{
+			name: "valid run: latest gallery image version",
+			image: ImageReference{
+				Name:           "rhel9000",
+				ResourceGroup:  "club",
+				SubscriptionID: "00000000-0000-0000-0000-000000000000",
+				Gallery:        "gallery",
+				Version:        "latest",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "valid run: pinned gallery image version",
+			image: ImageReference{
+				Name:           "rhel9000",
+				ResourceGroup:  "club",
+				SubscriptionID: "00000000-0000-0000-0000-000000000000",
+				Gallery:        "gallery",
+				Version:        "2019.10.1",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "invalid: gallery image version is not Major.Minor.Patch",
+			image: ImageReference{
+				Name:           "rhel9000",
+				ResourceGroup:  "club",
+				SubscriptionID: "00000000-0000-0000-0000-000000000000",
+				Gallery:        "gallery",
+				Version:        "v1",
+			},
+			expectedErr: errors.New(`imageReference.version v1 is invalid, it must be latest or of the form Major.Minor.Patch`),
+		},
 	}
 
 	for _, test := range tests {
@@ -2363,6 +2986,275 @@ func TestProperties_ValidateVNET(t *testing.T) {
 	}
 }
 
+func TestKubernetesConfig_ValidateIPv6DualStack(t *testing.T) {
+	tests := []struct {
+		name        string
+		k8sVersion  string
+		config      KubernetesConfig
+		expectedMsg string
+	}{
+		{
+			name:       "dual stack kubenet cluster",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+				ClusterSubnet: "10.244.0.0/16,fd00:101::/8",
+				ServiceCidr:   "10.0.0.0/16,fd00:1234::/108",
+				DNSServiceIP:  "10.0.0.10",
+			},
+		},
+		{
+			name:       "IPv4 single stack cluster",
+			k8sVersion: "1.15.3",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4},
+				NetworkPlugin: "azure",
+			},
+		},
+		{
+			name:       "unknown ip family",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, "IPv5"},
+				NetworkPlugin: "kubenet",
+			},
+			expectedMsg: "unknown ipFamily 'IPv5' specified, valid values are IPv4 and IPv6",
+		},
+		{
+			name:       "duplicate ip family",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv4},
+				NetworkPlugin: "kubenet",
+			},
+			expectedMsg: "ipFamily 'IPv4' is specified more than once",
+		},
+		{
+			name:       "IPv6 single stack cluster",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.IPFamilies 'IPv6' is invalid, the first ipFamily must be IPv4",
+		},
+		{
+			name:       "dual stack before Kubernetes 1.16",
+			k8sVersion: "1.15.3",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+			},
+			expectedMsg: "IPv6 dual stack is only available in Kubernetes version 1.16.0-alpha.1 or greater, but version is 1.15.3",
+		},
+		{
+			name:       "dual stack with Azure CNI",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "azure",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.NetworkPlugin 'azure' is invalid. IPv6 dual stack supported only with kubenet.",
+		},
+		{
+			name:       "IPv6 cluster subnet first",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+				ClusterSubnet: "fd00:101::/8,10.244.0.0/16",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.ClusterSubnet 'fd00:101::/8,10.244.0.0/16' is invalid, the IPv4 subnet must be followed by the IPv6 subnet for ipv6 dual stack",
+		},
+		{
+			name:       "two IPv4 service CIDRs",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+				ServiceCidr:   "10.0.0.0/16,10.1.0.0/16",
+				DNSServiceIP:  "10.0.0.10",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.ServiceCidr '10.0.0.0/16,10.1.0.0/16' is invalid, the IPv4 CIDR subnet must be followed by the IPv6 CIDR subnet for ipv6 dual stack",
+		},
+		{
+			name:       "dual stack service CIDRs of a single stack cluster",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				NetworkPlugin: "kubenet",
+				ServiceCidr:   "10.0.0.0/16,fd00:1234::/108",
+				DNSServiceIP:  "10.0.0.10",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.ServiceCidr '10.0.0.0/16,fd00:1234::/108' is an invalid CIDR subnet",
+		},
+		{
+			name:       "DNS service IP outside of the IPv4 service CIDR",
+			k8sVersion: "1.16.0-beta.1",
+			config: KubernetesConfig{
+				IPFamilies:    []string{IPFamilyIPv4, IPFamilyIPv6},
+				NetworkPlugin: "kubenet",
+				ServiceCidr:   "10.0.0.0/16,fd00:1234::/108",
+				DNSServiceIP:  "fd00:1234::a",
+			},
+			expectedMsg: "OrchestratorProfile.KubernetesConfig.DNSServiceIP 'fd00:1234::a' is not within the ServiceCidr '10.0.0.0/16'",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := test.config.Validate(test.k8sVersion, false, test.config.IsIPv6DualStack())
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.expectedMsg {
+				t.Errorf("expected error with message : %s, but got %v", test.expectedMsg, err)
+			}
+		})
+	}
+
+	t.Run("Should not support dual stack with VMSS masters", func(t *testing.T) {
+		t.Parallel()
+		cs := getK8sDefaultContainerService(true)
+		cs.Properties.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{
+			IPFamilies: []string{IPFamilyIPv4, IPFamilyIPv6},
+		}
+		cs.Properties.MasterProfile.AvailabilityProfile = VirtualMachineScaleSets
+		expectedMsg := "Dual stack feature is currently supported only with AvailabilitySet masters"
+		if err := cs.Properties.validateMasterProfile(false); err == nil || err.Error() != expectedMsg {
+			t.Errorf("expected error with message : %s, but got %v", expectedMsg, err)
+		}
+	})
+}
+
+func TestProperties_ValidateCustomRouteTableAndNSG(t *testing.T) {
+	validVNetSubnetID := "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME"
+	validRouteTableID := "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/RT_NAME"
+	validNSGID := "/subscriptions/SUB_ID/resourceGroups/NETWORK_RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME"
+
+	tests := []struct {
+		name          string
+		networkPlugin string
+		vnetSubnetID  string
+		masterRT      string
+		masterNSG     string
+		agentRT       string
+		agentNSG      string
+		expectedMsg   string
+	}{
+		{
+			name:          "custom route table and NSG",
+			networkPlugin: "kubenet",
+			vnetSubnetID:  validVNetSubnetID,
+			masterRT:      validRouteTableID,
+			masterNSG:     validNSGID,
+			agentRT:       validRouteTableID,
+		},
+		{
+			name:          "custom NSG with Azure CNI",
+			networkPlugin: "azure",
+			vnetSubnetID:  validVNetSubnetID,
+			masterNSG:     validNSGID,
+		},
+		{
+			name:          "custom route table without custom VNET",
+			networkPlugin: "kubenet",
+			masterRT:      validRouteTableID,
+			expectedMsg:   "routeTableID and networkSecurityGroupID are only supported with a custom VNET, set vnetSubnetID in the master profile and each agent pool profile",
+		},
+		{
+			name:          "custom NSG without custom VNET in an agent pool",
+			networkPlugin: "kubenet",
+			agentNSG:      validNSGID,
+			expectedMsg:   "routeTableID and networkSecurityGroupID are only supported with a custom VNET, set vnetSubnetID in the master profile and each agent pool profile",
+		},
+		{
+			name:          "custom route table with Azure CNI",
+			networkPlugin: "azure",
+			vnetSubnetID:  validVNetSubnetID,
+			masterRT:      validRouteTableID,
+			expectedMsg:   "routeTableID is only supported with networkPlugin \"kubenet\", the route table holds the routes to the pod CIDRs of the nodes",
+		},
+		{
+			name:          "different route tables",
+			networkPlugin: "kubenet",
+			vnetSubnetID:  validVNetSubnetID,
+			masterRT:      validRouteTableID,
+			agentRT:       "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/OTHER_RT_NAME",
+			expectedMsg:   "agent pool agentpool's routeTableID must be empty or equal to the master profile's routeTableID, all the nodes of the cluster share the same route table",
+		},
+		{
+			name:          "NSG only in an agent pool",
+			networkPlugin: "kubenet",
+			vnetSubnetID:  validVNetSubnetID,
+			agentNSG:      validNSGID,
+			expectedMsg:   "agent pool agentpool's networkSecurityGroupID must be empty or equal to the master profile's networkSecurityGroupID, all the nodes of the cluster share the same network security group",
+		},
+		{
+			name:          "invalid route table ID",
+			networkPlugin: "kubenet",
+			vnetSubnetID:  validVNetSubnetID,
+			masterRT:      validNSGID,
+			expectedMsg:   "Unable to parse " + validNSGID + ". Please use an ID with format /subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/NAME",
+		},
+		{
+			name:          "NSG in another subscription",
+			networkPlugin: "kubenet",
+			vnetSubnetID:  validVNetSubnetID,
+			masterNSG:     "/subscriptions/SUB_ID2/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME",
+			expectedMsg:   "networkSecurityGroupID /subscriptions/SUB_ID2/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME must be in the subscription of the VNET (SUB_ID)",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			p := &Properties{
+				OrchestratorProfile: &OrchestratorProfile{
+					OrchestratorType: Kubernetes,
+					KubernetesConfig: &KubernetesConfig{
+						NetworkPlugin: test.networkPlugin,
+					},
+				},
+				MasterProfile: &MasterProfile{
+					Count:                    1,
+					DNSPrefix:                "foo",
+					VMSize:                   "Standard_DS2_v2",
+					VnetSubnetID:             test.vnetSubnetID,
+					FirstConsecutiveStaticIP: "10.0.0.4",
+					RouteTableID:             test.masterRT,
+					NetworkSecurityGroupID:   test.masterNSG,
+				},
+				AgentPoolProfiles: []*AgentPoolProfile{
+					{
+						Name:                   "agentpool",
+						VMSize:                 "Standard_D2_v2",
+						Count:                  1,
+						AvailabilityProfile:    AvailabilitySet,
+						VnetSubnetID:           test.vnetSubnetID,
+						RouteTableID:           test.agentRT,
+						NetworkSecurityGroupID: test.agentNSG,
+					},
+				},
+			}
+			err := p.validateVNET()
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got %s", err.Error())
+				}
+			} else if err == nil || err.Error() != test.expectedMsg {
+				t.Errorf("expected error message : %s, but got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
 func TestWindowsProfile_Validate(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -2398,6 +3290,74 @@ func TestWindowsProfile_Validate(t *testing.T) {
 			},
 			expectedMsg: "WindowsProfile.AdminPassword is required, when agent pool specifies windows",
 		},
+		{
+			name:             "imageReference with unsupported orchestrator",
+			orchestratorType: "DCOS",
+			w: &WindowsProfile{
+				ImageRef: &ImageReference{
+					Name:          "windows-2019",
+					ResourceGroup: "images",
+				},
+			},
+			expectedMsg: "WindowsProfile.imageReference is only supported if the Orchestrator Type is Kubernetes",
+		},
+		{
+			name:             "imageReference and WindowsImageSourceUrl",
+			orchestratorType: "Kubernetes",
+			w: &WindowsProfile{
+				WindowsImageSourceURL: "http://fakeWindowsImageSourceURL",
+				ImageRef: &ImageReference{
+					Name:          "windows-2019",
+					ResourceGroup: "images",
+				},
+			},
+			expectedMsg: "WindowsProfile.imageReference and WindowsProfile.WindowsImageSourceUrl are mutually exclusive",
+		},
+		{
+			name:             "imageReference without resource group",
+			orchestratorType: "Kubernetes",
+			w: &WindowsProfile{
+				ImageRef: &ImageReference{
+					Name: "windows-2019",
+				},
+			},
+			expectedMsg: "imageResourceGroup needs to be specified when imageName is provided",
+		},
+		{
+			name:             "imageReference of a Shared Image Gallery without version",
+			orchestratorType: "Kubernetes",
+			w: &WindowsProfile{
+				ImageRef: &ImageReference{
+					Name:           "windows-2019",
+					ResourceGroup:  "images",
+					SubscriptionID: "00000000-0000-0000-0000-000000000000",
+					Gallery:        "gallery",
+				},
+			},
+			expectedMsg: "WindowsProfile.imageReference of a Shared Image Gallery needs subscriptionId, gallery and version",
+		},
+		{
+			name:             "imageReference of a Shared Image Gallery with an invalid version",
+			orchestratorType: "Kubernetes",
+			w: &WindowsProfile{
+				ImageRef: &ImageReference{
+					Name:           "windows-2019",
+					ResourceGroup:  "images",
+					SubscriptionID: "00000000-0000-0000-0000-000000000000",
+					Gallery:        "gallery",
+					Version:        "newest",
+				},
+			},
+			expectedMsg: "imageReference.version newest is invalid, it must be latest or of the form Major.Minor.Patch",
+		},
+		{
+			name:             "invalid windowsContainerdURL",
+			orchestratorType: "Kubernetes",
+			w: &WindowsProfile{
+				WindowsContainerdURL: "containerd.zip",
+			},
+			expectedMsg: "WindowsProfile.windowsContainerdURL 'containerd.zip' is invalid, it must be an http or https URL",
+		},
 	}
 
 	for _, test := range tests {
@@ -2760,6 +3720,99 @@ func TestAgentPoolProfile_ValidateAuditDEnabled(t *testing.T) {
 	})
 }
 
+func TestProperties_ValidateFallbackPools(t *testing.T) {
+	tests := []struct {
+		name          string
+		version       string
+		fallbackPool  string
+		fallback      *AgentPoolProfile
+		addons        []KubernetesAddon
+		expectedError string
+	}{
+		{
+			name:         "valid fallback pool",
+			fallbackPool: "regular",
+			fallback:     &AgentPoolProfile{Name: "regular", AvailabilityProfile: VirtualMachineScaleSets},
+			addons:       []KubernetesAddon{{Name: "cluster-autoscaler", Enabled: to.BoolPtr(true)}},
+		},
+		{
+			name:          "missing fallback pool",
+			fallbackPool:  "missing",
+			fallback:      &AgentPoolProfile{Name: "regular", AvailabilityProfile: VirtualMachineScaleSets},
+			addons:        []KubernetesAddon{{Name: "cluster-autoscaler", Enabled: to.BoolPtr(true)}},
+			expectedError: "the fallbackPool missing of agent pool spot does not exist",
+		},
+		{
+			name:          "low priority fallback pool",
+			fallbackPool:  "spot2",
+			fallback:      &AgentPoolProfile{Name: "spot2", AvailabilityProfile: VirtualMachineScaleSets, ScaleSetPriority: ScaleSetPriorityLow},
+			addons:        []KubernetesAddon{{Name: "cluster-autoscaler", Enabled: to.BoolPtr(true)}},
+			expectedError: "the fallbackPool spot2 of agent pool spot must not be a low priority pool",
+		},
+		{
+			name:          "fallback pool of another os type",
+			fallbackPool:  "windows",
+			fallback:      &AgentPoolProfile{Name: "windows", AvailabilityProfile: VirtualMachineScaleSets, OSType: Windows},
+			addons:        []KubernetesAddon{{Name: "cluster-autoscaler", Enabled: to.BoolPtr(true)}},
+			expectedError: "the fallbackPool windows of agent pool spot must have the same osType",
+		},
+		{
+			name:          "cluster-autoscaler disabled",
+			fallbackPool:  "regular",
+			fallback:      &AgentPoolProfile{Name: "regular", AvailabilityProfile: VirtualMachineScaleSets},
+			expectedError: "agent pool spot has a fallbackPool, which requires the cluster-autoscaler addon to be enabled",
+		},
+		{
+			name:          "cluster-autoscaler without priority expander",
+			version:       "1.14.6",
+			fallbackPool:  "regular",
+			fallback:      &AgentPoolProfile{Name: "regular", AvailabilityProfile: VirtualMachineScaleSets},
+			addons:        []KubernetesAddon{{Name: "cluster-autoscaler", Enabled: to.BoolPtr(true)}},
+			expectedError: "agent pool spot has a fallbackPool, which requires Kubernetes version 1.15.0 or greater, but version is 1.14.6",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			cs := getK8sDefaultContainerService(false)
+			cs.Properties.OrchestratorProfile.OrchestratorVersion = "1.15.3"
+			if test.version != "" {
+				cs.Properties.OrchestratorProfile.OrchestratorVersion = test.version
+			}
+			cs.Properties.OrchestratorProfile.KubernetesConfig = &KubernetesConfig{Addons: test.addons}
+			cs.Properties.AgentPoolProfiles = []*AgentPoolProfile{
+				{
+					Name:                "spot",
+					AvailabilityProfile: VirtualMachineScaleSets,
+					ScaleSetPriority:    ScaleSetPriorityLow,
+					FallbackPool:        test.fallbackPool,
+				},
+				test.fallback,
+			}
+			err := cs.Properties.validateFallbackPools(false)
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got %s", err)
+				}
+			} else if err == nil || err.Error() != test.expectedError {
+				t.Errorf("expected error with message %s, but got %v", test.expectedError, err)
+			}
+		})
+	}
+
+	t.Run("fallback pool of a regular priority pool", func(t *testing.T) {
+		t.Parallel()
+		cs := getK8sDefaultContainerService(false)
+		cs.Properties.AgentPoolProfiles[0].FallbackPool = "other"
+		expectedMsg := fmt.Sprintf("agent pool %s has a fallbackPool, but fallbackPool is only supported with a scaleSetPriority of Low", cs.Properties.AgentPoolProfiles[0].Name)
+		if err := cs.Properties.validateFallbackPools(false); err == nil || err.Error() != expectedMsg {
+			t.Errorf("expected error with message %s, but got %v", expectedMsg, err)
+		}
+	})
+}
+
 func TestMasterProfile_ValidateAuditDEnabled(t *testing.T) {
 	t.Run("Should have proper validation for auditd + distro combinations", func(t *testing.T) {
 		t.Parallel()
@@ -3058,6 +4111,30 @@ func TestValidateLocation(t *testing.T) {
 			},
 			expectedErr: errors.New("could not convert EtcdDiskSizeGB to int"),
 		},
+		{
+			name:          "AzureStack EnablePrivateDNSZone is true",
+			location:      "local",
+			propertiesnil: false,
+			cs: &ContainerService{
+				Location: "local",
+				Properties: &Properties{
+					CustomCloudProfile: &CustomCloudProfile{
+						PortalURL: "https://portal.local.cotoso.com",
+					},
+					OrchestratorProfile: &OrchestratorProfile{
+						OrchestratorType:    Kubernetes,
+						OrchestratorVersion: "1.11.10",
+						KubernetesConfig: &KubernetesConfig{
+							PrivateCluster: &PrivateCluster{
+								Enabled:              to.BoolPtr(trueVal),
+								EnablePrivateDNSZone: to.BoolPtr(trueVal),
+							},
+						},
+					},
+				},
+			},
+			expectedErr: errors.New("enablePrivateDNSZone shouldn't be set to true as Azure Private DNS zones are not yet supported on Azure Stack"),
+		},
 		{
 			name:          "AzureStack AcceleratedNetworking is true",
 			location:      "local",
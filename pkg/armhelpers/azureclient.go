@@ -48,10 +48,11 @@ var (
 // AzureClient implements the `AKSEngineClient` interface.
 // This client is backed by real Azure clients talking to an ARM endpoint.
 type AzureClient struct {
-	acceptLanguages []string
-	auxiliaryTokens []string
-	environment     azure.Environment
-	subscriptionID  string
+	acceptLanguages        []string
+	auxiliaryTokens        []string
+	environment            azure.Environment
+	subscriptionID         string
+	kubernetesClientDialer DialFunc
 
 	authorizationClient             authorization.RoleAssignmentsClient
 	deploymentsClient               resources.DeploymentsClient
@@ -60,6 +61,8 @@ type AzureClient struct {
 	resourcesClient                 apimanagement.GroupClient
 	storageAccountsClient           storage.AccountsClient
 	interfacesClient                network.InterfacesClient
+	routeTablesClient               network.RouteTablesClient
+	securityGroupsClient            network.SecurityGroupsClient
 	groupsClient                    resources.GroupsClient
 	providersClient                 resources.ProvidersClient
 	virtualMachinesClient           compute.VirtualMachinesClient
@@ -68,9 +71,12 @@ type AzureClient struct {
 	virtualMachineExtensionsClient  compute.VirtualMachineExtensionsClient
 	disksClient                     compute.DisksClient
 	availabilitySetsClient          compute.AvailabilitySetsClient
+	usageClient                     compute.UsageClient
+	resourceSkusClient              compute.ResourceSkusClient
 
 	applicationsClient      graphrbac.ApplicationsClient
 	servicePrincipalsClient graphrbac.ServicePrincipalsClient
+	objectsClient           graphrbac.ObjectsClient
 }
 
 // NewAzureClientWithCLI creates an AzureClient configured from Azure CLI 2.0 for local development scenarios.
@@ -342,6 +348,8 @@ func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthor
 		resourcesClient:                 apimanagement.NewGroupClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		storageAccountsClient:           storage.NewAccountsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		interfacesClient:                network.NewInterfacesClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
+		routeTablesClient:               network.NewRouteTablesClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
+		securityGroupsClient:            network.NewSecurityGroupsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		groupsClient:                    resources.NewGroupsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		providersClient:                 resources.NewProvidersClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		virtualMachinesClient:           compute.NewVirtualMachinesClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
@@ -350,9 +358,12 @@ func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthor
 		virtualMachineExtensionsClient:  compute.NewVirtualMachineExtensionsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		disksClient:                     compute.NewDisksClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 		availabilitySetsClient:          compute.NewAvailabilitySetsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
+		usageClient:                     compute.NewUsageClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
+		resourceSkusClient:              compute.NewResourceSkusClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
 
 		applicationsClient:      graphrbac.NewApplicationsClientWithBaseURI(env.GraphEndpoint, tenantID),
 		servicePrincipalsClient: graphrbac.NewServicePrincipalsClientWithBaseURI(env.GraphEndpoint, tenantID),
+		objectsClient:           graphrbac.NewObjectsClientWithBaseURI(env.GraphEndpoint, tenantID),
 	}
 
 	c.authorizationClient.Authorizer = armAuthorizer
@@ -362,6 +373,8 @@ func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthor
 	c.resourcesClient.Authorizer = armAuthorizer
 	c.storageAccountsClient.Authorizer = armAuthorizer
 	c.interfacesClient.Authorizer = armAuthorizer
+	c.routeTablesClient.Authorizer = armAuthorizer
+	c.securityGroupsClient.Authorizer = armAuthorizer
 	c.groupsClient.Authorizer = armAuthorizer
 	c.providersClient.Authorizer = armAuthorizer
 	c.virtualMachinesClient.Authorizer = armAuthorizer
@@ -369,6 +382,8 @@ func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthor
 	c.virtualMachineScaleSetVMsClient.Authorizer = armAuthorizer
 	c.disksClient.Authorizer = armAuthorizer
 	c.availabilitySetsClient.Authorizer = armAuthorizer
+	c.usageClient.Authorizer = armAuthorizer
+	c.resourceSkusClient.Authorizer = armAuthorizer
 
 	c.deploymentsClient.PollingDelay = time.Second * 5
 	c.resourcesClient.PollingDelay = time.Second * 5
@@ -391,6 +406,7 @@ func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthor
 
 	c.applicationsClient.Authorizer = graphAuthorizer
 	c.servicePrincipalsClient.Authorizer = graphAuthorizer
+	c.objectsClient.Authorizer = graphAuthorizer
 
 	return c
 }
@@ -457,6 +473,11 @@ func parseRsaPrivateKey(path string) (*rsa.PrivateKey, error) {
 	return nil, errors.Errorf("failed to parse private key as Pkcs#1 or Pkcs#8. (%s). (%s)", errPkcs1, errPkcs8)
 }
 
+// SetKubernetesClientDialer sets the function the Kubernetes clients dial the API server with
+func (az *AzureClient) SetKubernetesClientDialer(dial DialFunc) {
+	az.kubernetesClientDialer = dial
+}
+
 //AddAcceptLanguages sets the list of languages to accept on this request
 func (az *AzureClient) AddAcceptLanguages(languages []string) {
 	az.acceptLanguages = languages
@@ -467,14 +488,19 @@ func (az *AzureClient) AddAcceptLanguages(languages []string) {
 	az.resourcesClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.storageAccountsClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.interfacesClient.Client.RequestInspector = az.addAcceptLanguages()
+	az.routeTablesClient.Client.RequestInspector = az.addAcceptLanguages()
+	az.securityGroupsClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.groupsClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.providersClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.virtualMachinesClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.virtualMachineScaleSetsClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.disksClient.Client.RequestInspector = az.addAcceptLanguages()
+	az.usageClient.Client.RequestInspector = az.addAcceptLanguages()
+	az.resourceSkusClient.Client.RequestInspector = az.addAcceptLanguages()
 
 	az.applicationsClient.Client.RequestInspector = az.addAcceptLanguages()
 	az.servicePrincipalsClient.Client.RequestInspector = az.addAcceptLanguages()
+	az.objectsClient.Client.RequestInspector = az.addAcceptLanguages()
 }
 
 func (az *AzureClient) addAcceptLanguages() autorest.PrepareDecorator {
@@ -530,12 +556,17 @@ func (az *AzureClient) AddAuxiliaryTokens(tokens []string) {
 	az.resourcesClient.Client.RequestInspector = requestWithTokens
 	az.storageAccountsClient.Client.RequestInspector = requestWithTokens
 	az.interfacesClient.Client.RequestInspector = requestWithTokens
+	az.routeTablesClient.Client.RequestInspector = requestWithTokens
+	az.securityGroupsClient.Client.RequestInspector = requestWithTokens
 	az.groupsClient.Client.RequestInspector = requestWithTokens
 	az.providersClient.Client.RequestInspector = requestWithTokens
 	az.virtualMachinesClient.Client.RequestInspector = requestWithTokens
 	az.virtualMachineScaleSetsClient.Client.RequestInspector = requestWithTokens
 	az.disksClient.Client.RequestInspector = requestWithTokens
+	az.usageClient.Client.RequestInspector = requestWithTokens
+	az.resourceSkusClient.Client.RequestInspector = requestWithTokens
 
 	az.applicationsClient.Client.RequestInspector = requestWithTokens
 	az.servicePrincipalsClient.Client.RequestInspector = requestWithTokens
+	az.objectsClient.Client.RequestInspector = requestWithTokens
 }
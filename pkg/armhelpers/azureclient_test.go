@@ -6,8 +6,12 @@ package armhelpers
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
 	"github.com/Azure/go-autorest/autorest"
 
 	. "github.com/Azure/aks-engine/pkg/test"
@@ -38,3 +42,24 @@ var _ = Describe("AzureClient Aux token tests", func() {
 		Expect(request.Header.Get("x-ms-authorization-auxiliary")).To(Equal(fmt.Sprintf("Bearer %s", token)))
 	})
 })
+
+var _ = Describe("AzureClient Shared Image Gallery tests", func() {
+	It("Should get the Hyper-V generation of a gallery image of another subscription", func() {
+		var requestURL *url.URL
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestURL = r.URL
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"ubuntu","properties":{"osType":"Linux","hyperVGeneration":"V2"}}`)
+		}))
+		defer server.Close()
+
+		azureClient := getClient(azure.Environment{ResourceManagerEndpoint: server.URL}, "subID", "tenantID", autorest.NullAuthorizer{}, autorest.NullAuthorizer{})
+		image, err := azureClient.GetGalleryImage(context.Background(), "gallerySubID", "images", "gallery", "ubuntu")
+		Expect(err).To(BeNil())
+		Expect(requestURL.Path).To(Equal("/subscriptions/gallerySubID/resourceGroups/images/providers/Microsoft.Compute/galleries/gallery/images/ubuntu"))
+		Expect(requestURL.Query()["api-version"]).To(Equal([]string{galleryImageAPIVersion}))
+		Expect(*image.Name).To(Equal("ubuntu"))
+		Expect(image.OsType).To(Equal(compute.Linux))
+		Expect(image.HyperVGeneration).To(Equal("V2"))
+	})
+})
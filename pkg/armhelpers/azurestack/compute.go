@@ -11,6 +11,7 @@ import (
 	"github.com/Azure/aks-engine/pkg/armhelpers"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2017-03-30/compute"
 	azcompute "github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -38,6 +39,15 @@ func (az *AzureClient) GetVirtualMachine(ctx context.Context, resourceGroup, nam
 	return azVM, err
 }
 
+// GetVirtualMachinePowerState returns the power state of the specified virtual machine, from its instance view.
+func (az *AzureClient) GetVirtualMachinePowerState(ctx context.Context, resourceGroup, name string) (string, error) {
+	instanceView, err := az.virtualMachinesClient.InstanceView(ctx, resourceGroup, name)
+	if err != nil {
+		return "", fmt.Errorf("fail to get virtual machine instance view, %s", err)
+	}
+	return getPowerState(instanceView.Statuses), nil
+}
+
 // RestartVirtualMachine restarts the specified virtual machine.
 func (az *AzureClient) RestartVirtualMachine(ctx context.Context, resourceGroup, name string) error {
 	future, err := az.virtualMachinesClient.Restart(ctx, resourceGroup, name)
@@ -108,6 +118,15 @@ func (az *AzureClient) ListVirtualMachineScaleSetVMs(ctx context.Context, resour
 	return &c, err
 }
 
+// GetVirtualMachineScaleSetVMPowerState returns the power state of a VM in a VMSS, from its instance view.
+func (az *AzureClient) GetVirtualMachineScaleSetVMPowerState(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) (string, error) {
+	instanceView, err := az.virtualMachineScaleSetVMsClient.GetInstanceView(ctx, resourceGroup, virtualMachineScaleSet, instanceID)
+	if err != nil {
+		return "", fmt.Errorf("fail to get virtual machine scale set vm instance view, %s", err)
+	}
+	return getPowerState(instanceView.Statuses), nil
+}
+
 // DeleteVirtualMachineScaleSetVM deletes a VM in a VMSS
 func (az *AzureClient) DeleteVirtualMachineScaleSetVM(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) error {
 	future, err := az.virtualMachineScaleSetVMsClient.Delete(ctx, resourceGroup, virtualMachineScaleSet, instanceID)
@@ -195,3 +214,54 @@ func (az *AzureClient) GetAvailabilitySetFaultDomainCount(ctx context.Context, r
 	}
 	return count, nil
 }
+
+// getPowerState returns the code of the PowerState status of an instance view, an empty string if there is none
+func getPowerState(statuses *[]compute.InstanceViewStatus) string {
+	if statuses == nil {
+		return ""
+	}
+	for _, status := range *statuses {
+		if status.Code != nil && strings.HasPrefix(*status.Code, "PowerState/") {
+			return *status.Code
+		}
+	}
+	return ""
+}
+
+// AssignUserAssignedIDToVirtualMachine is not supported on Azure Stack, which does not support user assigned identities.
+func (az *AzureClient) AssignUserAssignedIDToVirtualMachine(ctx context.Context, resourceGroup, name, userAssignedIdentityID string) error {
+	return errors.New("error azure stack does not support user assigned msi")
+}
+
+// AssignUserAssignedIDToVirtualMachineScaleSet is not supported on Azure Stack, which does not support user assigned identities.
+func (az *AzureClient) AssignUserAssignedIDToVirtualMachineScaleSet(ctx context.Context, resourceGroup, virtualMachineScaleSet, userAssignedIdentityID string) error {
+	return errors.New("error azure stack does not support user assigned msi")
+}
+
+// ListComputeUsages lists the compute resources used by the subscription in the location, and their quota.
+func (az *AzureClient) ListComputeUsages(ctx context.Context, location string) (armhelpers.ListUsagesResultPage, error) {
+	page, err := az.usageClient.List(ctx, location)
+	return &ListUsagesResultPageClient{
+		lurp: page,
+		err:  err,
+	}, err
+}
+
+// ListResourceSkus lists the compute resource SKUs available to the subscription, e.g. the VM sizes.
+func (az *AzureClient) ListResourceSkus(ctx context.Context) (armhelpers.ResourceSkusResultPage, error) {
+	page, err := az.resourceSkusClient.List(ctx)
+	return &ResourceSkusResultPageClient{
+		rsrp: page,
+		err:  err,
+	}, err
+}
+
+// GetGalleryImage is not supported on Azure Stack, which does not support Shared Image Galleries.
+func (az *AzureClient) GetGalleryImage(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (armhelpers.GalleryImage, error) {
+	return armhelpers.GalleryImage{}, errors.New("error azure stack does not support shared image galleries")
+}
+
+// ListGalleryImageVersions is not supported on Azure Stack, which does not support Shared Image Galleries.
+func (az *AzureClient) ListGalleryImageVersions(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (armhelpers.GalleryImageVersionListPage, error) {
+	return nil, errors.New("error azure stack does not support shared image galleries")
+}
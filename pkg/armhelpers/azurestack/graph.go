@@ -58,6 +58,19 @@ func (az *AzureClient) ListRoleAssignmentsForPrincipal(ctx context.Context, scop
 	return nil, errors.New(errorMessage)
 }
 
+// ListRoleAssignmentsForScope lists the role assignments which apply to the scope, e.g. a resource group, including the
+// inherited ones and the ones of the resources inside the scope
+func (az *AzureClient) ListRoleAssignmentsForScope(ctx context.Context, scope string) (armhelpers.RoleAssignmentListResultPage, error) {
+	errorMessage := "error azure stack does not support listing role assignement"
+	return nil, errors.New(errorMessage)
+}
+
+// GetDirectoryObjectIDs returns the object IDs which exist in the directory among the specified ones
+func (az *AzureClient) GetDirectoryObjectIDs(ctx context.Context, objectIDs []string) ([]string, error) {
+	errorMessage := "error azure stack does not support getting directory objects"
+	return nil, errors.New(errorMessage)
+}
+
 // CreateApp is a simpler method for creating an application
 func (az *AzureClient) CreateApp(ctx context.Context, appName, appURL string, replyURLs *[]string, requiredResourceAccess *[]graphrbac.RequiredResourceAccess) (applicationResp graphrbac.Application, servicePrincipalObjectID, servicePrincipalClientSecret string, err error) {
 	errorMessage := "error azure stack does not support creating application"
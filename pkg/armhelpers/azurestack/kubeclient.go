@@ -21,8 +21,9 @@ import (
 )
 
 const (
-	evictionKind        = "Eviction"
-	evictionSubresource = "pods/eviction"
+	evictionKind                  = "Eviction"
+	evictionSubresource           = "pods/eviction"
+	customResourceDefinitionsPath = "/apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions"
 )
 
 //KubernetesClientSetClient is a Kubernetes client hooked up to a live api server.
@@ -38,6 +39,9 @@ func (az *AzureClient) GetKubernetesClient(apiserverURL, kubeConfig string, inte
 	if err != nil {
 		return nil, err
 	}
+	if az.kubernetesClientDialer != nil {
+		config.Dial = az.kubernetesClientDialer
+	}
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -181,3 +185,59 @@ func (c *KubernetesClientSetClient) GetDeployment(namespace, name string) (*apps
 func (c *KubernetesClientSetClient) UpdateDeployment(namespace string, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
 	return c.clientset.AppsV1().Deployments(namespace).Update(deployment)
 }
+
+// GetDaemonSet returns a given daemonset in a namespace.
+func (c *KubernetesClientSetClient) GetDaemonSet(namespace, name string) (*appsv1.DaemonSet, error) {
+	return c.clientset.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{})
+}
+
+// ListDaemonSets returns the daemonsets in a namespace.
+func (c *KubernetesClientSetClient) ListDaemonSets(namespace string) (*appsv1.DaemonSetList, error) {
+	return c.clientset.AppsV1().DaemonSets(namespace).List(metav1.ListOptions{})
+}
+
+// DeleteDaemonSet deletes a given daemonset in a namespace.
+func (c *KubernetesClientSetClient) DeleteDaemonSet(namespace, name string) error {
+	return c.clientset.AppsV1().DaemonSets(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteDeployment deletes a given deployment in a namespace.
+func (c *KubernetesClientSetClient) DeleteDeployment(namespace, name string) error {
+	return c.clientset.AppsV1().Deployments(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteService deletes a given service in a namespace.
+func (c *KubernetesClientSetClient) DeleteService(namespace, name string) error {
+	return c.clientset.CoreV1().Services(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteConfigMap deletes a given config map in a namespace.
+func (c *KubernetesClientSetClient) DeleteConfigMap(namespace, name string) error {
+	return c.clientset.CoreV1().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteRole deletes a given role in a namespace.
+func (c *KubernetesClientSetClient) DeleteRole(namespace, name string) error {
+	return c.clientset.RbacV1().Roles(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteRoleBinding deletes a given role binding in a namespace.
+func (c *KubernetesClientSetClient) DeleteRoleBinding(namespace, name string) error {
+	return c.clientset.RbacV1().RoleBindings(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteClusterRole deletes a given cluster role.
+func (c *KubernetesClientSetClient) DeleteClusterRole(name string) error {
+	return c.clientset.RbacV1().ClusterRoles().Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteClusterRoleBinding deletes a given cluster role binding.
+func (c *KubernetesClientSetClient) DeleteClusterRoleBinding(name string) error {
+	return c.clientset.RbacV1().ClusterRoleBindings().Delete(name, &metav1.DeleteOptions{})
+}
+
+// DeleteCustomResourceDefinition deletes a given custom resource definition, and the custom resources it defines.
+// The clientset has no apiextensions client, the definition is deleted with a request to the apiextensions API.
+func (c *KubernetesClientSetClient) DeleteCustomResourceDefinition(name string) error {
+	return c.clientset.Discovery().RESTClient().Delete().AbsPath(customResourceDefinitionsPath, name).Do().Error()
+}
@@ -5,6 +5,10 @@ package azurestack
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 )
 
 // DeleteNetworkInterface deletes the specified network interface.
@@ -21,3 +25,38 @@ func (az *AzureClient) DeleteNetworkInterface(ctx context.Context, resourceGroup
 	_, err = future.Result(az.interfacesClient)
 	return err
 }
+
+// ListNetworkInterfaces lists the network interfaces in the specified resource group.
+func (az *AzureClient) ListNetworkInterfaces(ctx context.Context, resourceGroup string) (armhelpers.InterfaceListResultPage, error) {
+	page, err := az.interfacesClient.List(ctx, resourceGroup)
+	return &InterfaceListResultPageClient{
+		ilrp: page,
+		err:  err,
+	}, err
+}
+
+// GetRouteTable returns the specified route table.
+func (az *AzureClient) GetRouteTable(ctx context.Context, resourceGroup, routeTableName string) (aznetwork.RouteTable, error) {
+	rt, err := az.routeTablesClient.Get(ctx, resourceGroup, routeTableName, "")
+	azRT := aznetwork.RouteTable{}
+	if err != nil {
+		return azRT, fmt.Errorf("fail to get route table, %s", err)
+	}
+	if err = DeepCopy(&azRT, rt); err != nil {
+		return azRT, fmt.Errorf("fail to convert route table, %s", err)
+	}
+	return azRT, nil
+}
+
+// GetNetworkSecurityGroup returns the specified network security group.
+func (az *AzureClient) GetNetworkSecurityGroup(ctx context.Context, resourceGroup, nsgName string) (aznetwork.SecurityGroup, error) {
+	nsg, err := az.securityGroupsClient.Get(ctx, resourceGroup, nsgName, "")
+	azNSG := aznetwork.SecurityGroup{}
+	if err != nil {
+		return azNSG, fmt.Errorf("fail to get network security group, %s", err)
+	}
+	if err = DeepCopy(&azNSG, nsg); err != nil {
+		return azNSG, fmt.Errorf("fail to convert network security group, %s", err)
+	}
+	return azNSG, nil
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2017-03-30/compute"
 	azcompute "github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2017-10-01/network"
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 )
 
 //VirtualMachineListResultPageClient Virtual Machine List Result Page Client
@@ -183,3 +185,135 @@ func (page DiskListPageClient) Values() []azcompute.Disk {
 	}
 	return l
 }
+
+// InterfaceListResultPageClient contains a page of Interface values.
+type InterfaceListResultPageClient struct {
+	ilrp network.InterfaceListResultPage
+	err  error
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *InterfaceListResultPageClient) NextWithContext(ctx context.Context) (err error) {
+	return page.ilrp.NextWithContext(ctx)
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+// Deprecated: Use NextWithContext() instead.
+func (page *InterfaceListResultPageClient) Next() error {
+	return page.ilrp.Next()
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page InterfaceListResultPageClient) NotDone() bool {
+	return page.ilrp.NotDone()
+}
+
+// Response returns the raw server response from the last page request.
+func (page InterfaceListResultPageClient) Response() aznetwork.InterfaceListResult {
+	l := aznetwork.InterfaceListResult{}
+	err := DeepCopy(&l, page.ilrp.Response())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get network interface list result, %s", err)
+	}
+	return l
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page InterfaceListResultPageClient) Values() []aznetwork.Interface {
+	l := []aznetwork.Interface{}
+	err := DeepCopy(&l, page.ilrp.Values())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get network interface list, %s", err)
+	}
+	return l
+}
+
+// ListUsagesResultPageClient contains a page of Usage values.
+type ListUsagesResultPageClient struct {
+	lurp compute.ListUsagesResultPage
+	err  error
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *ListUsagesResultPageClient) NextWithContext(ctx context.Context) (err error) {
+	return page.lurp.NextWithContext(ctx)
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+// Deprecated: Use NextWithContext() instead.
+func (page *ListUsagesResultPageClient) Next() error {
+	return page.lurp.Next()
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page ListUsagesResultPageClient) NotDone() bool {
+	return page.lurp.NotDone()
+}
+
+// Response returns the raw server response from the last page request.
+func (page ListUsagesResultPageClient) Response() azcompute.ListUsagesResult {
+	l := azcompute.ListUsagesResult{}
+	err := DeepCopy(&l, page.lurp.Response())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get usage list result, %s", err)
+	}
+	return l
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page ListUsagesResultPageClient) Values() []azcompute.Usage {
+	l := []azcompute.Usage{}
+	err := DeepCopy(&l, page.lurp.Values())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get usage list, %s", err)
+	}
+	return l
+}
+
+// ResourceSkusResultPageClient contains a page of ResourceSku values.
+type ResourceSkusResultPageClient struct {
+	rsrp compute.ResourceSkusResultPage
+	err  error
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *ResourceSkusResultPageClient) NextWithContext(ctx context.Context) (err error) {
+	return page.rsrp.NextWithContext(ctx)
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+// Deprecated: Use NextWithContext() instead.
+func (page *ResourceSkusResultPageClient) Next() error {
+	return page.rsrp.Next()
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page ResourceSkusResultPageClient) NotDone() bool {
+	return page.rsrp.NotDone()
+}
+
+// Response returns the raw server response from the last page request.
+func (page ResourceSkusResultPageClient) Response() azcompute.ResourceSkusResult {
+	l := azcompute.ResourceSkusResult{}
+	err := DeepCopy(&l, page.rsrp.Response())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get resource sku list result, %s", err)
+	}
+	return l
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page ResourceSkusResultPageClient) Values() []azcompute.ResourceSku {
+	l := []azcompute.ResourceSku{}
+	err := DeepCopy(&l, page.rsrp.Values())
+	if err != nil {
+		page.err = fmt.Errorf("fail to get resource sku list, %s", err)
+	}
+	return l
+}
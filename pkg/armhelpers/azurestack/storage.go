@@ -6,6 +6,7 @@ package azurestack
 import (
 	"bytes"
 	"context"
+	"time"
 
 	"github.com/Azure/aks-engine/pkg/armhelpers"
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2017-10-01/storage"
@@ -44,6 +45,22 @@ func (az *AzureClient) getStorageKeys(ctx context.Context, resourceGroup, accoun
 	return *storageKeysResult.Keys, nil
 }
 
+// ListStorageAccountNames returns the names of the storage accounts in the specified resource group.
+func (az *AzureClient) ListStorageAccountNames(ctx context.Context, resourceGroup string) ([]string, error) {
+	accounts, err := az.storageAccountsClient.ListByResourceGroup(ctx, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if accounts.Value != nil {
+		for _, account := range *accounts.Value {
+			names = append(names, to.String(account.Name))
+		}
+	}
+	return names, nil
+}
+
 // DeleteBlob deletes the specified blob
 // TODO(colemick): why doesn't SDK give a way to just delete a blob by URI?
 // it's what it ends up doing internally anyway...
@@ -70,6 +87,48 @@ func (as *AzureStorageClient) SaveBlockBlob(containerName, blobName string, b []
 	return blobRef.CreateBlockBlobFromReader(bytes.NewReader(b), options)
 }
 
+// GetContainerSASURI returns the URI of the container with a read-only SAS token valid until expiry
+func (as *AzureStorageClient) GetContainerSASURI(containerName string, expiry time.Time) (string, error) {
+	containerRef := getContainerRef(as.client, containerName)
+
+	return containerRef.GetSASURI(azStorage.ContainerSASOptions{
+		ContainerSASPermissions: azStorage.ContainerSASPermissions{
+			BlobServiceSASPermissions: azStorage.BlobServiceSASPermissions{
+				Read: true,
+			},
+		},
+		SASOptions: azStorage.SASOptions{
+			Expiry:   expiry,
+			UseHTTPS: true,
+		},
+	})
+}
+
+// ListBlobs returns the names of the blobs in the specified container, or none if the container does not exist
+func (as *AzureStorageClient) ListBlobs(containerName string) ([]string, error) {
+	containerRef := getContainerRef(as.client, containerName)
+	exists, err := containerRef.Exists()
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var names []string
+	params := azStorage.ListBlobsParameters{}
+	for {
+		blobs, err := containerRef.ListBlobs(params)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range blobs.Blobs {
+			names = append(names, blob.Name)
+		}
+		if blobs.NextMarker == "" {
+			return names, nil
+		}
+		params.Marker = blobs.NextMarker
+	}
+}
+
 func getContainerRef(client *azStorage.Client, containerName string) *azStorage.Container {
 	bs := client.GetBlobService()
 	return bs.GetContainerReference(containerName)
@@ -5,11 +5,20 @@ package armhelpers
 
 import (
 	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
 )
 
+// galleryImageAPIVersion is the first compute API version returning the Hyper-V generation of the image definitions
+const galleryImageAPIVersion = "2019-03-01"
+
 // ListVirtualMachines returns (the first page of) the machines in the specified resource group.
 func (az *AzureClient) ListVirtualMachines(ctx context.Context, resourceGroup string) (VirtualMachineListResultPage, error) {
 	page, err := az.virtualMachinesClient.List(ctx, resourceGroup)
@@ -21,6 +30,15 @@ func (az *AzureClient) GetVirtualMachine(ctx context.Context, resourceGroup, nam
 	return az.virtualMachinesClient.Get(ctx, resourceGroup, name, "")
 }
 
+// GetVirtualMachinePowerState returns the power state of the specified virtual machine, from its instance view.
+func (az *AzureClient) GetVirtualMachinePowerState(ctx context.Context, resourceGroup, name string) (string, error) {
+	instanceView, err := az.virtualMachinesClient.InstanceView(ctx, resourceGroup, name)
+	if err != nil {
+		return "", err
+	}
+	return getPowerState(instanceView.Statuses), nil
+}
+
 // RestartVirtualMachine restarts the specified virtual machine.
 func (az *AzureClient) RestartVirtualMachine(ctx context.Context, resourceGroup, name string) error {
 	future, err := az.virtualMachinesClient.Restart(ctx, resourceGroup, name)
@@ -78,6 +96,15 @@ func (az *AzureClient) ListVirtualMachineScaleSetVMs(ctx context.Context, resour
 	return &page, err
 }
 
+// GetVirtualMachineScaleSetVMPowerState returns the power state of a VM in a VMSS, from its instance view.
+func (az *AzureClient) GetVirtualMachineScaleSetVMPowerState(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) (string, error) {
+	instanceView, err := az.virtualMachineScaleSetVMsClient.GetInstanceView(ctx, resourceGroup, virtualMachineScaleSet, instanceID)
+	if err != nil {
+		return "", err
+	}
+	return getPowerState(instanceView.Statuses), nil
+}
+
 // DeleteVirtualMachineScaleSetVM deletes a VM in a VMSS
 func (az *AzureClient) DeleteVirtualMachineScaleSetVM(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) error {
 	future, err := az.virtualMachineScaleSetVMsClient.Delete(ctx, resourceGroup, virtualMachineScaleSet, instanceID)
@@ -150,3 +177,173 @@ func (az *AzureClient) GetAvailabilitySetFaultDomainCount(ctx context.Context, r
 	}
 	return count, nil
 }
+
+// AssignUserAssignedIDToVirtualMachine adds the specified user assigned identity to the identities of a virtual machine,
+// keeping its system assigned identity if it has one.
+func (az *AzureClient) AssignUserAssignedIDToVirtualMachine(ctx context.Context, resourceGroup, name, userAssignedIdentityID string) error {
+	vm, err := az.virtualMachinesClient.Get(ctx, resourceGroup, name, "")
+	if err != nil {
+		return err
+	}
+
+	identity := &compute.VirtualMachineIdentity{
+		Type: compute.ResourceIdentityTypeUserAssigned,
+		UserAssignedIdentities: map[string]*compute.VirtualMachineIdentityUserAssignedIdentitiesValue{
+			userAssignedIdentityID: {},
+		},
+	}
+	if vm.Identity != nil {
+		identity.Type = withUserAssignedIdentityType(vm.Identity.Type)
+		for id := range vm.Identity.UserAssignedIdentities {
+			identity.UserAssignedIdentities[id] = &compute.VirtualMachineIdentityUserAssignedIdentitiesValue{}
+		}
+	}
+
+	future, err := az.virtualMachinesClient.Update(ctx, resourceGroup, name, compute.VirtualMachineUpdate{Identity: identity})
+	if err != nil {
+		return err
+	}
+
+	if err = future.WaitForCompletionRef(ctx, az.virtualMachinesClient.Client); err != nil {
+		return err
+	}
+
+	_, err = future.Result(az.virtualMachinesClient)
+	return err
+}
+
+// AssignUserAssignedIDToVirtualMachineScaleSet adds the specified user assigned identity to the identities of a VMSS,
+// keeping its system assigned identity if it has one, and updates all of its instances to the new model.
+func (az *AzureClient) AssignUserAssignedIDToVirtualMachineScaleSet(ctx context.Context, resourceGroup, virtualMachineScaleSet, userAssignedIdentityID string) error {
+	vmss, err := az.virtualMachineScaleSetsClient.Get(ctx, resourceGroup, virtualMachineScaleSet)
+	if err != nil {
+		return err
+	}
+
+	identity := &compute.VirtualMachineScaleSetIdentity{
+		Type: compute.ResourceIdentityTypeUserAssigned,
+		UserAssignedIdentities: map[string]*compute.VirtualMachineScaleSetIdentityUserAssignedIdentitiesValue{
+			userAssignedIdentityID: {},
+		},
+	}
+	if vmss.Identity != nil {
+		identity.Type = withUserAssignedIdentityType(vmss.Identity.Type)
+		for id := range vmss.Identity.UserAssignedIdentities {
+			identity.UserAssignedIdentities[id] = &compute.VirtualMachineScaleSetIdentityUserAssignedIdentitiesValue{}
+		}
+	}
+
+	future, err := az.virtualMachineScaleSetsClient.Update(ctx, resourceGroup, virtualMachineScaleSet, compute.VirtualMachineScaleSetUpdate{Identity: identity})
+	if err != nil {
+		return err
+	}
+
+	if err = future.WaitForCompletionRef(ctx, az.virtualMachineScaleSetsClient.Client); err != nil {
+		return err
+	}
+
+	if _, err = future.Result(az.virtualMachineScaleSetsClient); err != nil {
+		return err
+	}
+
+	instancesFuture, err := az.virtualMachineScaleSetsClient.UpdateInstances(ctx, resourceGroup, virtualMachineScaleSet, compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
+		InstanceIds: &[]string{"*"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = instancesFuture.WaitForCompletionRef(ctx, az.virtualMachineScaleSetsClient.Client); err != nil {
+		return err
+	}
+
+	_, err = instancesFuture.Result(az.virtualMachineScaleSetsClient)
+	return err
+}
+
+// withUserAssignedIdentityType returns the identity type of a resource once a user assigned identity is added to it.
+func withUserAssignedIdentityType(identityType compute.ResourceIdentityType) compute.ResourceIdentityType {
+	switch identityType {
+	case compute.ResourceIdentityTypeSystemAssigned, compute.ResourceIdentityTypeSystemAssignedUserAssigned:
+		return compute.ResourceIdentityTypeSystemAssignedUserAssigned
+	default:
+		return compute.ResourceIdentityTypeUserAssigned
+	}
+}
+
+// getPowerState returns the code of the PowerState status of an instance view, an empty string if there is none
+func getPowerState(statuses *[]compute.InstanceViewStatus) string {
+	if statuses == nil {
+		return ""
+	}
+	for _, status := range *statuses {
+		if status.Code != nil && strings.HasPrefix(*status.Code, "PowerState/") {
+			return *status.Code
+		}
+	}
+	return ""
+}
+
+// ListComputeUsages lists the compute resources used by the subscription in the location, and their quota.
+func (az *AzureClient) ListComputeUsages(ctx context.Context, location string) (ListUsagesResultPage, error) {
+	page, err := az.usageClient.List(ctx, location)
+	return &page, err
+}
+
+// ListResourceSkus lists the compute resource SKUs available to the subscription, e.g. the VM sizes.
+func (az *AzureClient) ListResourceSkus(ctx context.Context) (ResourceSkusResultPage, error) {
+	page, err := az.resourceSkusClient.List(ctx)
+	return &page, err
+}
+
+// GetGalleryImage returns the specified image definition of a Shared Image Gallery, which may be in another
+// subscription. The image definition is requested with a more recent API version than the vendored compute API, to get
+// its Hyper-V generation.
+func (az *AzureClient) GetGalleryImage(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImage, error) {
+	client := compute.GalleryImagesClient{BaseClient: az.virtualMachinesClient.BaseClient}
+	client.SubscriptionID = subscriptionID
+
+	var result GalleryImage
+	req, err := client.GetPreparer(ctx, resourceGroup, gallery, image)
+	if err != nil {
+		return result, errors.Wrap(err, "preparing the request of the gallery image")
+	}
+	query := req.URL.Query()
+	query.Set("api-version", galleryImageAPIVersion)
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := client.GetSender(req)
+	if err != nil {
+		return result, errors.Wrap(err, "sending the request of the gallery image")
+	}
+	defer resp.Body.Close()
+	if err = autorest.Respond(resp, client.ByInspecting(), azure.WithErrorUnlessStatusCode(http.StatusOK)); err != nil {
+		return result, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return result, errors.Wrap(err, "reading the gallery image")
+	}
+	if err = json.Unmarshal(body, &result.GalleryImage); err != nil {
+		return result, errors.Wrap(err, "decoding the gallery image")
+	}
+	var generation struct {
+		Properties struct {
+			HyperVGeneration string `json:"hyperVGeneration"`
+		} `json:"properties"`
+	}
+	if err = json.Unmarshal(body, &generation); err != nil {
+		return result, errors.Wrap(err, "decoding the Hyper-V generation of the gallery image")
+	}
+	result.HyperVGeneration = generation.Properties.HyperVGeneration
+	return result, nil
+}
+
+// ListGalleryImageVersions lists the versions of the specified image definition of a Shared Image Gallery, which may
+// be in another subscription.
+func (az *AzureClient) ListGalleryImageVersions(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImageVersionListPage, error) {
+	client := compute.GalleryImageVersionsClient{BaseClient: az.virtualMachinesClient.BaseClient}
+	client.SubscriptionID = subscriptionID
+	page, err := client.ListByGalleryImage(ctx, resourceGroup, gallery, image)
+	return &page, err
+}
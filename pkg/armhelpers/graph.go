@@ -58,6 +58,53 @@ func (az *AzureClient) ListRoleAssignmentsForPrincipal(ctx context.Context, scop
 	return &page, err
 }
 
+// ListRoleAssignmentsForScope lists the role assignments which apply to the scope, e.g. a resource group, including the
+// inherited ones and the ones of the resources inside the scope
+func (az *AzureClient) ListRoleAssignmentsForScope(ctx context.Context, scope string) (RoleAssignmentListResultPage, error) {
+	page, err := az.authorizationClient.ListForScope(ctx, scope, "")
+	return &page, err
+}
+
+// GetDirectoryObjectIDs returns the object IDs which exist in the directory among the specified ones, it allows to find
+// the role assignments of deleted principals
+func (az *AzureClient) GetDirectoryObjectIDs(ctx context.Context, objectIDs []string) ([]string, error) {
+	var found []string
+	parameters := graphrbac.GetObjectsParameters{
+		ObjectIds:                        &objectIDs,
+		IncludeDirectoryObjectReferences: to.BoolPtr(true),
+	}
+	for page, err := az.objectsClient.GetObjectsByObjectIds(ctx, parameters); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Values() {
+			if id := getDirectoryObjectID(object); id != nil {
+				found = append(found, *id)
+			}
+		}
+	}
+	return found, nil
+}
+
+func getDirectoryObjectID(object graphrbac.BasicDirectoryObject) *string {
+	if sp, ok := object.AsServicePrincipal(); ok {
+		return sp.ObjectID
+	}
+	if user, ok := object.AsUser(); ok {
+		return user.ObjectID
+	}
+	if group, ok := object.AsADGroup(); ok {
+		return group.ObjectID
+	}
+	if app, ok := object.AsApplication(); ok {
+		return app.ObjectID
+	}
+	if do, ok := object.AsDirectoryObject(); ok {
+		return do.ObjectID
+	}
+	return nil
+}
+
 // CreateApp is a simpler method for creating an application
 func (az *AzureClient) CreateApp(ctx context.Context, appName, appURL string, replyURLs *[]string, requiredResourceAccess *[]graphrbac.RequiredResourceAccess) (applicationResp graphrbac.Application, servicePrincipalObjectID, servicePrincipalClientSecret string, err error) {
 	notBefore := time.Now()
@@ -5,11 +5,13 @@ package armhelpers
 
 import (
 	"context"
+	"net"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 	"github.com/Azure/azure-sdk-for-go/services/preview/msi/mgmt/2015-08-31-preview/msi"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-05-01/resources"
 	azStorage "github.com/Azure/azure-sdk-for-go/storage"
@@ -19,6 +21,9 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
+// DialFunc is the signature of the functions which open the connections of the Kubernetes clients to the API server
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
 // VirtualMachineListResultPage is an interface for compute.VirtualMachineListResultPage to aid in mocking
 type VirtualMachineListResultPage interface {
 	Next() error
@@ -79,6 +84,50 @@ type DiskListPage interface {
 	Values() []compute.Disk
 }
 
+// InterfaceListResultPage is an interface for network.InterfaceListResultPage to aid in mocking
+type InterfaceListResultPage interface {
+	Next() error
+	NextWithContext(ctx context.Context) (err error)
+	NotDone() bool
+	Response() network.InterfaceListResult
+	Values() []network.Interface
+}
+
+// ListUsagesResultPage is an interface for compute.ListUsagesResultPage to aid in mocking
+type ListUsagesResultPage interface {
+	Next() error
+	NextWithContext(ctx context.Context) (err error)
+	NotDone() bool
+	Response() compute.ListUsagesResult
+	Values() []compute.Usage
+}
+
+// ResourceSkusResultPage is an interface for compute.ResourceSkusResultPage to aid in mocking
+type ResourceSkusResultPage interface {
+	Next() error
+	NextWithContext(ctx context.Context) (err error)
+	NotDone() bool
+	Response() compute.ResourceSkusResult
+	Values() []compute.ResourceSku
+}
+
+// GalleryImageVersionListPage is an interface for compute.GalleryImageVersionListPage to aid in mocking
+type GalleryImageVersionListPage interface {
+	Next() error
+	NextWithContext(ctx context.Context) (err error)
+	NotDone() bool
+	Response() compute.GalleryImageVersionList
+	Values() []compute.GalleryImageVersion
+}
+
+// GalleryImage is an image definition of a Shared Image Gallery. The vendored compute API predates the Hyper-V
+// generation of the image definitions, which is added to compute.GalleryImage.
+type GalleryImage struct {
+	compute.GalleryImage
+	// HyperVGeneration is the Hyper-V generation of the VMs of the image, V1 or V2
+	HyperVGeneration string
+}
+
 // AKSEngineClient is the interface used to talk to an Azure environment.
 // This interface exposes just the subset of Azure APIs and clients needed for
 // AKS Engine.
@@ -107,6 +156,9 @@ type AKSEngineClient interface {
 	// GetVirtualMachine retrieves the specified virtual machine.
 	GetVirtualMachine(ctx context.Context, resourceGroup, name string) (compute.VirtualMachine, error)
 
+	// GetVirtualMachinePowerState returns the power state of the specified virtual machine, e.g. PowerState/running
+	GetVirtualMachinePowerState(ctx context.Context, resourceGroup, name string) (string, error)
+
 	// RestartVirtualMachine restarts the specified virtual machine.
 	RestartVirtualMachine(ctx context.Context, resourceGroup, name string) error
 
@@ -122,6 +174,9 @@ type AKSEngineClient interface {
 	// ListVirtualMachineScaleSetVMs lists the virtual machines contained in a VMSS
 	ListVirtualMachineScaleSetVMs(ctx context.Context, resourceGroup, virtualMachineScaleSet string) (VirtualMachineScaleSetVMListResultPage, error)
 
+	// GetVirtualMachineScaleSetVMPowerState returns the power state of a VM in a VMSS, e.g. PowerState/running
+	GetVirtualMachineScaleSetVMPowerState(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) (string, error)
+
 	// DeleteVirtualMachineScaleSetVM deletes a VM in a VMSS
 	DeleteVirtualMachineScaleSetVM(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) error
 
@@ -135,6 +190,27 @@ type AKSEngineClient interface {
 	// VM availability set IDs provided.
 	GetAvailabilitySetFaultDomainCount(ctx context.Context, resourceGroup string, vmasIDs []string) (int, error)
 
+	// AssignUserAssignedIDToVirtualMachine adds the specified user assigned identity to the identities of a virtual machine.
+	AssignUserAssignedIDToVirtualMachine(ctx context.Context, resourceGroup, name, userAssignedIdentityID string) error
+
+	// AssignUserAssignedIDToVirtualMachineScaleSet adds the specified user assigned identity to the identities of a VMSS
+	// and of its instances.
+	AssignUserAssignedIDToVirtualMachineScaleSet(ctx context.Context, resourceGroup, virtualMachineScaleSet, userAssignedIdentityID string) error
+
+	// ListComputeUsages lists the compute resources used by the subscription in the location, and their quota.
+	ListComputeUsages(ctx context.Context, location string) (ListUsagesResultPage, error)
+
+	// ListResourceSkus lists the compute resource SKUs available to the subscription, e.g. the VM sizes.
+	ListResourceSkus(ctx context.Context) (ResourceSkusResultPage, error)
+
+	// GetGalleryImage returns the specified image definition of a Shared Image Gallery, which may be in another
+	// subscription.
+	GetGalleryImage(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImage, error)
+
+	// ListGalleryImageVersions lists the versions of the specified image definition of a Shared Image Gallery, which
+	// may be in another subscription.
+	ListGalleryImageVersions(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImageVersionListPage, error)
+
 	//
 	// STORAGE
 
@@ -142,12 +218,24 @@ type AKSEngineClient interface {
 	// account.
 	GetStorageClient(ctx context.Context, resourceGroup, accountName string) (AKSStorageClient, error)
 
+	// ListStorageAccountNames returns the names of the storage accounts in the specified resource group.
+	ListStorageAccountNames(ctx context.Context, resourceGroup string) ([]string, error)
+
 	//
 	// NETWORK
 
 	// DeleteNetworkInterface deletes the specified network interface.
 	DeleteNetworkInterface(ctx context.Context, resourceGroup, nicName string) error
 
+	// ListNetworkInterfaces lists the network interfaces in the specified resource group.
+	ListNetworkInterfaces(ctx context.Context, resourceGroup string) (InterfaceListResultPage, error)
+
+	// GetRouteTable returns the specified route table.
+	GetRouteTable(ctx context.Context, resourceGroup, routeTableName string) (network.RouteTable, error)
+
+	// GetNetworkSecurityGroup returns the specified network security group.
+	GetNetworkSecurityGroup(ctx context.Context, resourceGroup, nsgName string) (network.SecurityGroup, error)
+
 	//
 	// GRAPH
 
@@ -159,6 +247,9 @@ type AKSEngineClient interface {
 	CreateApp(ctx context.Context, applicationName, applicationURL string, replyURLs *[]string, requiredResourceAccess *[]graphrbac.RequiredResourceAccess) (result graphrbac.Application, servicePrincipalObjectID, secret string, err error)
 	DeleteApp(ctx context.Context, applicationName, applicationObjectID string) (autorest.Response, error)
 
+	// GetDirectoryObjectIDs returns the object IDs which exist in the directory among the specified ones.
+	GetDirectoryObjectIDs(ctx context.Context, objectIDs []string) ([]string, error)
+
 	// User Assigned MSI
 	//CreateUserAssignedID - Creates a user assigned msi.
 	CreateUserAssignedID(location string, resourceGroup string, userAssignedID string) (*msi.Identity, error)
@@ -168,6 +259,7 @@ type AKSEngineClient interface {
 	CreateRoleAssignmentSimple(ctx context.Context, applicationID, roleID string) error
 	DeleteRoleAssignmentByID(ctx context.Context, roleAssignmentNameID string) (authorization.RoleAssignment, error)
 	ListRoleAssignmentsForPrincipal(ctx context.Context, scope string, principalID string) (RoleAssignmentListResultPage, error)
+	ListRoleAssignmentsForScope(ctx context.Context, scope string) (RoleAssignmentListResultPage, error)
 
 	// MANAGED DISKS
 	DeleteManagedDisk(ctx context.Context, resourceGroupName string, diskName string) error
@@ -175,6 +267,9 @@ type AKSEngineClient interface {
 
 	GetKubernetesClient(apiserverURL, kubeConfig string, interval, timeout time.Duration) (KubernetesClient, error)
 
+	// SetKubernetesClientDialer sets the function the Kubernetes clients dial the API server with, e.g. through a proxy
+	SetKubernetesClientDialer(dial DialFunc)
+
 	ListProviders(ctx context.Context) (ProviderListResultPage, error)
 
 	// DEPLOYMENTS
@@ -191,6 +286,10 @@ type AKSStorageClient interface {
 	CreateContainer(containerName string, options *azStorage.CreateContainerOptions) (bool, error)
 	// SaveBlockBlob initializes a block blob by taking the byte
 	SaveBlockBlob(containerName, blobName string, b []byte, options *azStorage.PutBlobOptions) error
+	// GetContainerSASURI returns the URI of the container with a read-only SAS token valid until expiry
+	GetContainerSASURI(containerName string, expiry time.Time) (string, error)
+	// ListBlobs returns the names of the blobs in the specified container, or none if the container does not exist
+	ListBlobs(containerName string) ([]string, error)
 }
 
 // KubernetesClient interface models client for interacting with kubernetes api server
@@ -223,4 +322,26 @@ type KubernetesClient interface {
 	GetDeployment(namespace, name string) (*appsv1.Deployment, error)
 	// UpdateDeployment updates a deployment to match the given specification.
 	UpdateDeployment(namespace string, deployment *appsv1.Deployment) (*appsv1.Deployment, error)
+	// GetDaemonSet returns a given daemonset in a namespace.
+	GetDaemonSet(namespace, name string) (*appsv1.DaemonSet, error)
+	// ListDaemonSets returns the daemonsets in a namespace.
+	ListDaemonSets(namespace string) (*appsv1.DaemonSetList, error)
+	// DeleteDaemonSet deletes a given daemonset in a namespace.
+	DeleteDaemonSet(namespace, name string) error
+	// DeleteDeployment deletes a given deployment in a namespace.
+	DeleteDeployment(namespace, name string) error
+	// DeleteService deletes a given service in a namespace.
+	DeleteService(namespace, name string) error
+	// DeleteConfigMap deletes a given config map in a namespace.
+	DeleteConfigMap(namespace, name string) error
+	// DeleteRole deletes a given role in a namespace.
+	DeleteRole(namespace, name string) error
+	// DeleteRoleBinding deletes a given role binding in a namespace.
+	DeleteRoleBinding(namespace, name string) error
+	// DeleteClusterRole deletes a given cluster role.
+	DeleteClusterRole(name string) error
+	// DeleteClusterRoleBinding deletes a given cluster role binding.
+	DeleteClusterRoleBinding(name string) error
+	// DeleteCustomResourceDefinition deletes a given custom resource definition, and the custom resources it defines.
+	DeleteCustomResourceDefinition(name string) error
 }
@@ -17,10 +17,12 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 	"github.com/Azure/azure-sdk-for-go/services/preview/msi/mgmt/2015-08-31-preview/msi"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-05-01/resources"
 	azStorage "github.com/Azure/azure-sdk-for-go/storage"
 	"github.com/Azure/go-autorest/autorest"
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -44,13 +46,18 @@ type MockAKSEngineClient struct {
 	FailListVirtualMachineScaleSets         bool
 	FailRestartVirtualMachineScaleSets      bool
 	FailGetVirtualMachine                   bool
+	FailGetVirtualMachinePowerState         bool
 	FailRestartVirtualMachine               bool
+	FailAssignUserAssignedID                bool
 	FailDeleteVirtualMachine                bool
 	FailDeleteVirtualMachineScaleSetVM      bool
 	FailSetVirtualMachineScaleSetCapacity   bool
 	FailListVirtualMachineScaleSetVMs       bool
 	FailGetStorageClient                    bool
 	FailDeleteNetworkInterface              bool
+	FailGetRouteTable                       bool
+	FailGetNetworkSecurityGroup             bool
+	FailGetGalleryImage                     bool
 	FailGetKubernetesClient                 bool
 	FailListProviders                       bool
 	ShouldSupportVMIdentity                 bool
@@ -59,34 +66,58 @@ type MockAKSEngineClient struct {
 	FakeListVirtualMachineScaleSetsResult   func() []compute.VirtualMachineScaleSet
 	FakeListVirtualMachineResult            func() []compute.VirtualMachine
 	FakeListVirtualMachineScaleSetVMsResult func() []compute.VirtualMachineScaleSetVM
+	FakeListManagedDisksResult              func() []compute.Disk
+	FakeListNetworkInterfacesResult         func() []network.Interface
+	FakeListStorageAccountNamesResult       func() []string
+	FakeListBlobsResult                     func(accountName, containerName string) []string
+	FakeListRoleAssignmentsForScopeResult   func() []authorization.RoleAssignment
+	FakeGetDirectoryObjectIDsResult         func(objectIDs []string) []string
+	FakeListComputeUsagesResult             func() []compute.Usage
+	FakeListResourceSkusResult              func() []compute.ResourceSku
+	FakeGetGalleryImageResult               func(image string) GalleryImage
+	FakeListGalleryImageVersionsResult      func(image string) []compute.GalleryImageVersion
 }
 
 //MockStorageClient mock implementation of StorageClient
 type MockStorageClient struct {
 	FailCreateContainer bool
 	FailSaveBlockBlob   bool
+	FailGetSASURI       bool
+	FakeListBlobsResult func(containerName string) []string
 }
 
 //MockKubernetesClient mock implementation of KubernetesClient
 type MockKubernetesClient struct {
-	FailListPods              bool
-	FailListNodes             bool
-	FailListServiceAccounts   bool
-	FailGetNode               bool
-	UpdateNodeFunc            func(*v1.Node) (*v1.Node, error)
-	GetNodeFunc               func(name string) (*v1.Node, error)
-	FailUpdateNode            bool
-	FailDeleteNode            bool
-	FailDeleteServiceAccount  bool
-	FailSupportEviction       bool
-	FailDeletePod             bool
-	FailEvictPod              bool
-	FailWaitForDelete         bool
-	ShouldSupportEviction     bool
-	PodsList                  *v1.PodList
-	ServiceAccountList        *v1.ServiceAccountList
-	FailGetDeploymentCount    int
-	FailUpdateDeploymentCount int
+	FailListPods                       bool
+	FailListNodes                      bool
+	FailListServiceAccounts            bool
+	FailGetNode                        bool
+	UpdateNodeFunc                     func(*v1.Node) (*v1.Node, error)
+	GetNodeFunc                        func(name string) (*v1.Node, error)
+	FailUpdateNode                     bool
+	FailDeleteNode                     bool
+	FailDeleteServiceAccount           bool
+	FailSupportEviction                bool
+	FailDeletePod                      bool
+	FailEvictPod                       bool
+	FailWaitForDelete                  bool
+	ShouldSupportEviction              bool
+	PodsList                           *v1.PodList
+	ServiceAccountList                 *v1.ServiceAccountList
+	FailGetDeploymentCount             int
+	FailUpdateDeploymentCount          int
+	FailGetDaemonSetCount              int
+	FailListDaemonSets                 bool
+	DaemonSetList                      *appsv1.DaemonSetList
+	FailDeleteDaemonSet                bool
+	FailDeleteDeployment               bool
+	FailDeleteService                  bool
+	FailDeleteConfigMap                bool
+	FailDeleteRole                     bool
+	FailDeleteRoleBinding              bool
+	FailDeleteClusterRole              bool
+	FailDeleteClusterRoleBinding       bool
+	FailDeleteCustomResourceDefinition bool
 }
 
 // MockVirtualMachineListResultPage contains a page of VirtualMachine values.
@@ -206,6 +237,211 @@ func (page MockVirtualMachineScaleSetVMListResultPage) Values() []compute.Virtua
 	return *page.Vmssvlr.Value
 }
 
+// MockDiskListPage contains a page of Disk values.
+type MockDiskListPage struct {
+	Fn func(compute.DiskList) (compute.DiskList, error)
+	Dl compute.DiskList
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned. Context is ignored for the mock implementation
+func (page *MockDiskListPage) NextWithContext(ctx context.Context) error {
+	return page.Next()
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *MockDiskListPage) Next() error {
+	next, err := page.Fn(page.Dl)
+	if err != nil {
+		return err
+	}
+	page.Dl = next
+	return nil
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page MockDiskListPage) NotDone() bool {
+	return !page.Dl.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page MockDiskListPage) Response() compute.DiskList {
+	return page.Dl
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page MockDiskListPage) Values() []compute.Disk {
+	if page.Dl.IsEmpty() {
+		return nil
+	}
+	return *page.Dl.Value
+}
+
+// MockInterfaceListResultPage contains a page of network Interface values.
+type MockInterfaceListResultPage struct {
+	Fn  func(network.InterfaceListResult) (network.InterfaceListResult, error)
+	Ilr network.InterfaceListResult
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned. Context is ignored for the mock implementation
+func (page *MockInterfaceListResultPage) NextWithContext(ctx context.Context) error {
+	return page.Next()
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *MockInterfaceListResultPage) Next() error {
+	next, err := page.Fn(page.Ilr)
+	if err != nil {
+		return err
+	}
+	page.Ilr = next
+	return nil
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page MockInterfaceListResultPage) NotDone() bool {
+	return !page.Ilr.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page MockInterfaceListResultPage) Response() network.InterfaceListResult {
+	return page.Ilr
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page MockInterfaceListResultPage) Values() []network.Interface {
+	if page.Ilr.IsEmpty() {
+		return nil
+	}
+	return *page.Ilr.Value
+}
+
+// MockListUsagesResultPage contains a page of Usage values.
+type MockListUsagesResultPage struct {
+	Fn  func(compute.ListUsagesResult) (compute.ListUsagesResult, error)
+	Lur compute.ListUsagesResult
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned. Context is ignored for the mock implementation
+func (page *MockListUsagesResultPage) NextWithContext(ctx context.Context) error {
+	return page.Next()
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *MockListUsagesResultPage) Next() error {
+	next, err := page.Fn(page.Lur)
+	if err != nil {
+		return err
+	}
+	page.Lur = next
+	return nil
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page MockListUsagesResultPage) NotDone() bool {
+	return !page.Lur.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page MockListUsagesResultPage) Response() compute.ListUsagesResult {
+	return page.Lur
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page MockListUsagesResultPage) Values() []compute.Usage {
+	if page.Lur.IsEmpty() {
+		return nil
+	}
+	return *page.Lur.Value
+}
+
+// MockResourceSkusResultPage contains a page of ResourceSku values.
+type MockResourceSkusResultPage struct {
+	Fn  func(compute.ResourceSkusResult) (compute.ResourceSkusResult, error)
+	Rsr compute.ResourceSkusResult
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned. Context is ignored for the mock implementation
+func (page *MockResourceSkusResultPage) NextWithContext(ctx context.Context) error {
+	return page.Next()
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *MockResourceSkusResultPage) Next() error {
+	next, err := page.Fn(page.Rsr)
+	if err != nil {
+		return err
+	}
+	page.Rsr = next
+	return nil
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page MockResourceSkusResultPage) NotDone() bool {
+	return !page.Rsr.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page MockResourceSkusResultPage) Response() compute.ResourceSkusResult {
+	return page.Rsr
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page MockResourceSkusResultPage) Values() []compute.ResourceSku {
+	if page.Rsr.IsEmpty() {
+		return nil
+	}
+	return *page.Rsr.Value
+}
+
+// MockGalleryImageVersionListPage contains a page of GalleryImageVersion values.
+type MockGalleryImageVersionListPage struct {
+	Fn   func(compute.GalleryImageVersionList) (compute.GalleryImageVersionList, error)
+	Givl compute.GalleryImageVersionList
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned. Context is ignored for the mock implementation
+func (page *MockGalleryImageVersionListPage) NextWithContext(ctx context.Context) error {
+	return page.Next()
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *MockGalleryImageVersionListPage) Next() error {
+	next, err := page.Fn(page.Givl)
+	if err != nil {
+		return err
+	}
+	page.Givl = next
+	return nil
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page MockGalleryImageVersionListPage) NotDone() bool {
+	return !page.Givl.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page MockGalleryImageVersionListPage) Response() compute.GalleryImageVersionList {
+	return page.Givl
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page MockGalleryImageVersionListPage) Values() []compute.GalleryImageVersion {
+	if page.Givl.IsEmpty() {
+		return nil
+	}
+	return *page.Givl.Value
+}
+
 // MockDeploymentOperationsListResultPage contains a page of DeploymentOperation values.
 type MockDeploymentOperationsListResultPage struct {
 	Fn   func(resources.DeploymentOperationsListResult) (resources.DeploymentOperationsListResult, error)
@@ -436,6 +672,98 @@ func (mkc *MockKubernetesClient) UpdateDeployment(namespace string, deployment *
 	return &appsv1.Deployment{}, nil
 }
 
+// GetDaemonSet returns a given daemonset in a namespace.
+func (mkc *MockKubernetesClient) GetDaemonSet(namespace, name string) (*appsv1.DaemonSet, error) {
+	if mkc.FailGetDaemonSetCount > 0 {
+		mkc.FailGetDaemonSetCount--
+		return nil, errors.New("GetDaemonSet failed")
+	}
+	return &appsv1.DaemonSet{}, nil
+}
+
+// ListDaemonSets returns the daemonsets in a namespace.
+func (mkc *MockKubernetesClient) ListDaemonSets(namespace string) (*appsv1.DaemonSetList, error) {
+	if mkc.FailListDaemonSets {
+		return nil, errors.New("ListDaemonSets failed")
+	}
+	if mkc.DaemonSetList != nil {
+		return mkc.DaemonSetList, nil
+	}
+	return &appsv1.DaemonSetList{}, nil
+}
+
+// DeleteDaemonSet deletes a given daemonset in a namespace.
+func (mkc *MockKubernetesClient) DeleteDaemonSet(namespace, name string) error {
+	if mkc.FailDeleteDaemonSet {
+		return errors.New("DeleteDaemonSet failed")
+	}
+	return nil
+}
+
+// DeleteDeployment deletes a given deployment in a namespace.
+func (mkc *MockKubernetesClient) DeleteDeployment(namespace, name string) error {
+	if mkc.FailDeleteDeployment {
+		return errors.New("DeleteDeployment failed")
+	}
+	return nil
+}
+
+// DeleteService deletes a given service in a namespace.
+func (mkc *MockKubernetesClient) DeleteService(namespace, name string) error {
+	if mkc.FailDeleteService {
+		return errors.New("DeleteService failed")
+	}
+	return nil
+}
+
+// DeleteConfigMap deletes a given config map in a namespace.
+func (mkc *MockKubernetesClient) DeleteConfigMap(namespace, name string) error {
+	if mkc.FailDeleteConfigMap {
+		return errors.New("DeleteConfigMap failed")
+	}
+	return nil
+}
+
+// DeleteRole deletes a given role in a namespace.
+func (mkc *MockKubernetesClient) DeleteRole(namespace, name string) error {
+	if mkc.FailDeleteRole {
+		return errors.New("DeleteRole failed")
+	}
+	return nil
+}
+
+// DeleteRoleBinding deletes a given role binding in a namespace.
+func (mkc *MockKubernetesClient) DeleteRoleBinding(namespace, name string) error {
+	if mkc.FailDeleteRoleBinding {
+		return errors.New("DeleteRoleBinding failed")
+	}
+	return nil
+}
+
+// DeleteClusterRole deletes a given cluster role.
+func (mkc *MockKubernetesClient) DeleteClusterRole(name string) error {
+	if mkc.FailDeleteClusterRole {
+		return errors.New("DeleteClusterRole failed")
+	}
+	return nil
+}
+
+// DeleteClusterRoleBinding deletes a given cluster role binding.
+func (mkc *MockKubernetesClient) DeleteClusterRoleBinding(name string) error {
+	if mkc.FailDeleteClusterRoleBinding {
+		return errors.New("DeleteClusterRoleBinding failed")
+	}
+	return nil
+}
+
+// DeleteCustomResourceDefinition deletes a given custom resource definition.
+func (mkc *MockKubernetesClient) DeleteCustomResourceDefinition(name string) error {
+	if mkc.FailDeleteCustomResourceDefinition {
+		return errors.New("DeleteCustomResourceDefinition failed")
+	}
+	return nil
+}
+
 //DeleteBlob mock
 func (msc *MockStorageClient) DeleteBlob(container, blob string, options *azStorage.DeleteBlobOptions) error {
 	return nil
@@ -457,9 +785,28 @@ func (msc *MockStorageClient) SaveBlockBlob(container, blob string, b []byte, op
 	return errors.New("SaveBlockBlob failed")
 }
 
+//GetContainerSASURI mock
+func (msc *MockStorageClient) GetContainerSASURI(container string, expiry time.Time) (string, error) {
+	if !msc.FailGetSASURI {
+		return fmt.Sprintf("https://storageaccount.blob.core.windows.net/%s?se=%s&sig=signature&sp=r&sr=c&sv=2018-03-28", container, expiry.UTC().Format(time.RFC3339)), nil
+	}
+	return "", errors.New("GetContainerSASURI failed")
+}
+
+//ListBlobs mock
+func (msc *MockStorageClient) ListBlobs(container string) ([]string, error) {
+	if msc.FakeListBlobsResult == nil {
+		return nil, nil
+	}
+	return msc.FakeListBlobsResult(container), nil
+}
+
 //AddAcceptLanguages mock
 func (mc *MockAKSEngineClient) AddAcceptLanguages(languages []string) {}
 
+//SetKubernetesClientDialer mock
+func (mc *MockAKSEngineClient) SetKubernetesClientDialer(dial DialFunc) {}
+
 // AddAuxiliaryTokens mock
 func (mc *MockAKSEngineClient) AddAuxiliaryTokens(tokens []string) {}
 
@@ -617,6 +964,14 @@ func (mc *MockAKSEngineClient) GetVirtualMachine(ctx context.Context, resourceGr
 	return mc.MakeFakeVirtualMachine(DefaultFakeVMName, defaultK8sVersionForFakeVMs), nil
 }
 
+// GetVirtualMachinePowerState mock
+func (mc *MockAKSEngineClient) GetVirtualMachinePowerState(ctx context.Context, resourceGroup, name string) (string, error) {
+	if mc.FailGetVirtualMachinePowerState {
+		return "", errors.New("GetVirtualMachinePowerState failed")
+	}
+	return "PowerState/running", nil
+}
+
 // RestartVirtualMachine mock
 func (mc *MockAKSEngineClient) RestartVirtualMachine(ctx context.Context, resourceGroup, name string) error {
 	if mc.FailRestartVirtualMachine {
@@ -625,6 +980,22 @@ func (mc *MockAKSEngineClient) RestartVirtualMachine(ctx context.Context, resour
 	return nil
 }
 
+// AssignUserAssignedIDToVirtualMachine mock
+func (mc *MockAKSEngineClient) AssignUserAssignedIDToVirtualMachine(ctx context.Context, resourceGroup, name, userAssignedIdentityID string) error {
+	if mc.FailAssignUserAssignedID {
+		return errors.New("AssignUserAssignedIDToVirtualMachine failed")
+	}
+	return nil
+}
+
+// AssignUserAssignedIDToVirtualMachineScaleSet mock
+func (mc *MockAKSEngineClient) AssignUserAssignedIDToVirtualMachineScaleSet(ctx context.Context, resourceGroup, virtualMachineScaleSet, userAssignedIdentityID string) error {
+	if mc.FailAssignUserAssignedID {
+		return errors.New("AssignUserAssignedIDToVirtualMachineScaleSet failed")
+	}
+	return nil
+}
+
 // MakeFakeVirtualMachineScaleSetVM creates a fake VMSS VM
 func (mc *MockAKSEngineClient) MakeFakeVirtualMachineScaleSetVM(orchestratorTag string) compute.VirtualMachineScaleSetVM {
 	return mc.MakeFakeVirtualMachineScaleSetVMWithGivenName(orchestratorTag, "computerName")
@@ -787,6 +1158,14 @@ func (mc *MockAKSEngineClient) ListVirtualMachineScaleSetVMs(ctx context.Context
 	}, nil
 }
 
+// GetVirtualMachineScaleSetVMPowerState mock
+func (mc *MockAKSEngineClient) GetVirtualMachineScaleSetVMPowerState(ctx context.Context, resourceGroup, virtualMachineScaleSet, instanceID string) (string, error) {
+	if mc.FailGetVirtualMachinePowerState {
+		return "", errors.New("GetVirtualMachineScaleSetVMPowerState failed")
+	}
+	return "PowerState/running", nil
+}
+
 // GetAvailabilitySet mock
 func (mc *MockAKSEngineClient) GetAvailabilitySet(ctx context.Context, resourceGroup, availabilitySetName string) (compute.AvailabilitySet, error) {
 	return compute.AvailabilitySet{}, nil
@@ -803,7 +1182,21 @@ func (mc *MockAKSEngineClient) GetStorageClient(ctx context.Context, resourceGro
 		return nil, errors.New("GetStorageClient failed")
 	}
 
-	return &MockStorageClient{}, nil
+	client := &MockStorageClient{}
+	if mc.FakeListBlobsResult != nil {
+		client.FakeListBlobsResult = func(containerName string) []string {
+			return mc.FakeListBlobsResult(accountName, containerName)
+		}
+	}
+	return client, nil
+}
+
+//ListStorageAccountNames mock
+func (mc *MockAKSEngineClient) ListStorageAccountNames(ctx context.Context, resourceGroup string) ([]string, error) {
+	if mc.FakeListStorageAccountNamesResult == nil {
+		return nil, nil
+	}
+	return mc.FakeListStorageAccountNamesResult(), nil
 }
 
 //DeleteNetworkInterface mock
@@ -815,6 +1208,46 @@ func (mc *MockAKSEngineClient) DeleteNetworkInterface(ctx context.Context, resou
 	return nil
 }
 
+//ListNetworkInterfaces mock
+func (mc *MockAKSEngineClient) ListNetworkInterfaces(ctx context.Context, resourceGroup string) (InterfaceListResultPage, error) {
+	nics := []network.Interface{}
+	if mc.FakeListNetworkInterfacesResult != nil {
+		nics = mc.FakeListNetworkInterfacesResult()
+	}
+	return &MockInterfaceListResultPage{
+		Fn: func(lastResults network.InterfaceListResult) (network.InterfaceListResult, error) {
+			return network.InterfaceListResult{}, nil
+		},
+		Ilr: network.InterfaceListResult{
+			Value: &nics,
+		},
+	}, nil
+}
+
+//GetRouteTable mock
+func (mc *MockAKSEngineClient) GetRouteTable(ctx context.Context, resourceGroup, routeTableName string) (network.RouteTable, error) {
+	if mc.FailGetRouteTable {
+		return network.RouteTable{}, errors.New("GetRouteTable failed")
+	}
+
+	return network.RouteTable{
+		Name:     to.StringPtr(routeTableName),
+		Location: to.StringPtr("westus"),
+	}, nil
+}
+
+//GetNetworkSecurityGroup mock
+func (mc *MockAKSEngineClient) GetNetworkSecurityGroup(ctx context.Context, resourceGroup, nsgName string) (network.SecurityGroup, error) {
+	if mc.FailGetNetworkSecurityGroup {
+		return network.SecurityGroup{}, errors.New("GetNetworkSecurityGroup failed")
+	}
+
+	return network.SecurityGroup{
+		Name:     to.StringPtr(nsgName),
+		Location: to.StringPtr("westus"),
+	}, nil
+}
+
 var validOSDiskResourceName = "https://00k71r4u927seqiagnt0.blob.core.windows.net/osdisk/k8s-agentpool1-12345678-0-osdisk.vhd"
 var validNicResourceName = "/subscriptions/DEC923E3-1EF1-4745-9516-37906D56DEC4/resourceGroups/acsK8sTest/providers/Microsoft.Network/networkInterfaces/k8s-agent-12345678-nic-0"
 
@@ -849,7 +1282,17 @@ func (mc *MockAKSEngineClient) DeleteApp(ctx context.Context, appName, applicati
 
 //CreateUserAssignedID - Creates a user assigned msi.
 func (mc *MockAKSEngineClient) CreateUserAssignedID(location string, resourceGroup string, userAssignedID string) (*msi.Identity, error) {
-	return &msi.Identity{}, nil
+	principalID := uuid.FromStringOrNil("11111111-1111-1111-1111-111111111111")
+	clientID := uuid.FromStringOrNil("22222222-2222-2222-2222-222222222222")
+	return &msi.Identity{
+		ID:       to.StringPtr(fmt.Sprintf("/subscriptions/sub/resourceGroups/%s/providers/Microsoft.ManagedIdentity/userAssignedIdentities/%s", resourceGroup, userAssignedID)),
+		Name:     to.StringPtr(userAssignedID),
+		Location: to.StringPtr(location),
+		IdentityProperties: &msi.IdentityProperties{
+			PrincipalID: &principalID,
+			ClientID:    &clientID,
+		},
+	}, nil
 }
 
 // RBAC Mocks
@@ -871,7 +1314,18 @@ func (mc *MockAKSEngineClient) DeleteManagedDisk(ctx context.Context, resourceGr
 
 // ListManagedDisksByResourceGroup is a wrapper around disksClient.ListManagedDisksByResourceGroup
 func (mc *MockAKSEngineClient) ListManagedDisksByResourceGroup(ctx context.Context, resourceGroupName string) (result DiskListPage, err error) {
-	return &compute.DiskListPage{}, nil
+	if mc.FakeListManagedDisksResult == nil {
+		return &compute.DiskListPage{}, nil
+	}
+	disks := mc.FakeListManagedDisksResult()
+	return &MockDiskListPage{
+		Fn: func(lastResults compute.DiskList) (compute.DiskList, error) {
+			return compute.DiskList{}, nil
+		},
+		Dl: compute.DiskList{
+			Value: &disks,
+		},
+	}, nil
 }
 
 //GetKubernetesClient mock
@@ -989,3 +1443,94 @@ func (mc *MockAKSEngineClient) ListRoleAssignmentsForPrincipal(ctx context.Conte
 		},
 	}, nil
 }
+
+// ListRoleAssignmentsForScope mock
+func (mc *MockAKSEngineClient) ListRoleAssignmentsForScope(ctx context.Context, scope string) (RoleAssignmentListResultPage, error) {
+	roleAssignments := []authorization.RoleAssignment{}
+	if mc.FakeListRoleAssignmentsForScopeResult != nil {
+		roleAssignments = mc.FakeListRoleAssignmentsForScopeResult()
+	}
+	return &MockRoleAssignmentListResultPage{
+		Fn: func(lastResults authorization.RoleAssignmentListResult) (authorization.RoleAssignmentListResult, error) {
+			return authorization.RoleAssignmentListResult{}, nil
+		},
+		Ralr: authorization.RoleAssignmentListResult{
+			Value: &roleAssignments,
+		},
+	}, nil
+}
+
+// GetDirectoryObjectIDs mock, all the objects exist unless FakeGetDirectoryObjectIDsResult is set
+func (mc *MockAKSEngineClient) GetDirectoryObjectIDs(ctx context.Context, objectIDs []string) ([]string, error) {
+	if mc.FakeGetDirectoryObjectIDsResult == nil {
+		return objectIDs, nil
+	}
+	return mc.FakeGetDirectoryObjectIDsResult(objectIDs), nil
+}
+
+// ListComputeUsages mock
+func (mc *MockAKSEngineClient) ListComputeUsages(ctx context.Context, location string) (ListUsagesResultPage, error) {
+	usages := []compute.Usage{}
+	if mc.FakeListComputeUsagesResult != nil {
+		usages = mc.FakeListComputeUsagesResult()
+	}
+	return &MockListUsagesResultPage{
+		Fn: func(lastResults compute.ListUsagesResult) (compute.ListUsagesResult, error) {
+			return compute.ListUsagesResult{}, nil
+		},
+		Lur: compute.ListUsagesResult{
+			Value: &usages,
+		},
+	}, nil
+}
+
+// ListResourceSkus mock
+func (mc *MockAKSEngineClient) ListResourceSkus(ctx context.Context) (ResourceSkusResultPage, error) {
+	skus := []compute.ResourceSku{}
+	if mc.FakeListResourceSkusResult != nil {
+		skus = mc.FakeListResourceSkusResult()
+	}
+	return &MockResourceSkusResultPage{
+		Fn: func(lastResults compute.ResourceSkusResult) (compute.ResourceSkusResult, error) {
+			return compute.ResourceSkusResult{}, nil
+		},
+		Rsr: compute.ResourceSkusResult{
+			Value: &skus,
+		},
+	}, nil
+}
+
+// GetGalleryImage mock
+func (mc *MockAKSEngineClient) GetGalleryImage(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImage, error) {
+	if mc.FailGetGalleryImage {
+		return GalleryImage{}, errors.New("GetGalleryImage failed")
+	}
+	if mc.FakeGetGalleryImageResult != nil {
+		return mc.FakeGetGalleryImageResult(image), nil
+	}
+	return GalleryImage{
+		GalleryImage: compute.GalleryImage{
+			Name: to.StringPtr(image),
+			GalleryImageProperties: &compute.GalleryImageProperties{
+				OsType: compute.Linux,
+			},
+		},
+		HyperVGeneration: "V1",
+	}, nil
+}
+
+// ListGalleryImageVersions mock
+func (mc *MockAKSEngineClient) ListGalleryImageVersions(ctx context.Context, subscriptionID, resourceGroup, gallery, image string) (GalleryImageVersionListPage, error) {
+	versions := []compute.GalleryImageVersion{}
+	if mc.FakeListGalleryImageVersionsResult != nil {
+		versions = mc.FakeListGalleryImageVersionsResult(image)
+	}
+	return &MockGalleryImageVersionListPage{
+		Fn: func(lastResults compute.GalleryImageVersionList) (compute.GalleryImageVersionList, error) {
+			return compute.GalleryImageVersionList{}, nil
+		},
+		Givl: compute.GalleryImageVersionList{
+			Value: &versions,
+		},
+	}, nil
+}
@@ -5,6 +5,8 @@ package armhelpers
 
 import (
 	"context"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 )
 
 // DeleteNetworkInterface deletes the specified network interface.
@@ -21,3 +23,19 @@ func (az *AzureClient) DeleteNetworkInterface(ctx context.Context, resourceGroup
 	_, err = future.Result(az.interfacesClient)
 	return err
 }
+
+// ListNetworkInterfaces lists the network interfaces in the specified resource group.
+func (az *AzureClient) ListNetworkInterfaces(ctx context.Context, resourceGroup string) (InterfaceListResultPage, error) {
+	page, err := az.interfacesClient.List(ctx, resourceGroup)
+	return &page, err
+}
+
+// GetRouteTable returns the specified route table.
+func (az *AzureClient) GetRouteTable(ctx context.Context, resourceGroup, routeTableName string) (network.RouteTable, error) {
+	return az.routeTablesClient.Get(ctx, resourceGroup, routeTableName, "")
+}
+
+// GetNetworkSecurityGroup returns the specified network security group.
+func (az *AzureClient) GetNetworkSecurityGroup(ctx context.Context, resourceGroup, nsgName string) (network.SecurityGroup, error) {
+	return az.securityGroupsClient.Get(ctx, resourceGroup, nsgName, "")
+}
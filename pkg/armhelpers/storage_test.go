@@ -4,9 +4,13 @@
 package armhelpers
 
 import (
+	"encoding/base64"
+	"net/url"
 	"testing"
+	"time"
 
 	. "github.com/Azure/aks-engine/pkg/test"
+	azStorage "github.com/Azure/azure-sdk-for-go/storage"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -52,3 +56,31 @@ var _ = Describe("SaveBlockBlob Test", func() {
 		Expect(err).NotTo(BeNil())
 	})
 })
+
+var _ = Describe("GetContainerSASURI Test", func() {
+	It("Should return a read-only container SAS URI", func() {
+		client, err := azStorage.NewBasicClient("fakeaccount", base64.StdEncoding.EncodeToString([]byte("fakekey")))
+		Expect(err).To(BeNil())
+		as := AzureStorageClient{client: &client}
+		expiry := time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC)
+		uri, err := as.GetContainerSASURI("fakecontainer", expiry)
+		Expect(err).To(BeNil())
+		u, err := url.Parse(uri)
+		Expect(err).To(BeNil())
+		Expect(u.Scheme).To(Equal("https"))
+		Expect(u.Host).To(Equal("fakeaccount.blob.core.windows.net"))
+		Expect(u.Path).To(Equal("/fakecontainer"))
+		Expect(u.Query().Get("sp")).To(Equal("r"))
+		Expect(u.Query().Get("sr")).To(Equal("c"))
+		Expect(u.Query().Get("se")).To(Equal("2019-07-01T00:00:00Z"))
+		Expect(u.Query().Get("sig")).NotTo(BeEmpty())
+	})
+
+	It("Should return error when SAS URI generation failed", func() {
+		client := MockStorageClient{
+			FailGetSASURI: true,
+		}
+		_, err := client.GetContainerSASURI("fakeContainerName", time.Now())
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,174 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	calicoInstallCNIStartMarker = "Start of install-cni initContainer"
+	calicoInstallCNIEndMarker   = "End of install-cni initContainer"
+)
+
+// AddonManifest is an addon manifest as it is written to /etc/kubernetes/addons on the master nodes
+type AddonManifest struct {
+	// DestinationFile is the name of the manifest in /etc/kubernetes/addons
+	DestinationFile string
+	// Content is the manifest with the placeholders the master custom data substitutes from the template parameters
+	// replaced. Placeholders which the provisioning scripts substitute from the environment of the node, such as
+	// credentials and certificates generated on the node, are left as is.
+	Content string
+}
+
+// GetAddonManifests returns the manifests of the enabled addons of a cluster, the way they are written to
+// /etc/kubernetes/addons on the master nodes when the masters are provisioned
+func GetAddonManifests(cs *api.ContainerService) ([]AddonManifest, error) {
+	p := cs.Properties
+	versions := strings.Split(p.OrchestratorProfile.OrchestratorVersion, ".")
+	if len(versions) < 2 {
+		return nil, errors.Errorf("invalid Kubernetes version %s", p.OrchestratorProfile.OrchestratorVersion)
+	}
+	parameters := getParameters(cs, DefaultGeneratorCode, "")
+
+	settings, err := kubernetesAddonSettingsInit(p)
+	if err != nil {
+		return nil, err
+	}
+	var manifests []AddonManifest
+	for _, setting := range settings {
+		if !setting.isEnabled {
+			continue
+		}
+		content, err := getComponentManifest(setting, "k8s/addons", p.OrchestratorProfile.OrchestratorVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading the manifest of %s", setting.destinationFile)
+		}
+		manifests = append(manifests, AddonManifest{
+			DestinationFile: setting.destinationFile,
+			Content:         substituteAddonPlaceholders(cs, parameters, setting.destinationFile, content),
+		})
+	}
+
+	settingsMap := kubernetesContainerAddonSettingsInit(p)
+	var addonNames []string
+	for addonName := range settingsMap {
+		addonNames = append(addonNames, addonName)
+	}
+	sort.Strings(addonNames)
+	for _, addonName := range addonNames {
+		setting := settingsMap[addonName]
+		if !setting.isEnabled {
+			continue
+		}
+		content, err := getContainerAddonManifest(p, addonName, setting, "k8s/containeraddons")
+		if err != nil {
+			return nil, errors.Wrapf(err, "rendering the manifest of addon %s", addonName)
+		}
+		manifests = append(manifests, AddonManifest{
+			DestinationFile: setting.destinationFile,
+			Content:         substituteAddonPlaceholders(cs, parameters, setting.destinationFile, content),
+		})
+	}
+	return manifests, nil
+}
+
+// substituteAddonPlaceholders replaces the placeholders of an addon manifest the way the master custom data and
+// the provisioning scripts do with sed, see masternodecustomdata.yml and configClusterAutoscalerAddon in cse_config.sh
+func substituteAddonPlaceholders(cs *api.ContainerService, parameters paramsMap, destinationFile, content string) string {
+	k := cs.Properties.OrchestratorProfile.KubernetesConfig
+	param := func(name string) string {
+		if v, ok := parameters[name].(paramsMap); ok {
+			return fmt.Sprint(v["value"])
+		}
+		return ""
+	}
+	var replacements []string
+	switch destinationFile {
+	case "kube-proxy-daemonset.yaml":
+		clusterCidr := param("kubeClusterCidr")
+		if cs.Properties.IsIPv6DualStack() {
+			clusterCidr = strings.Split(clusterCidr, ",")[0]
+		}
+		replacements = []string{
+			"<img>", param("kubernetesHyperkubeSpec"),
+			"<CIDR>", clusterCidr,
+			"<kubeProxyMode>", string(k.ProxyMode),
+		}
+	case "kube-dns-deployment.yaml":
+		replacements = []string{
+			"<img>", param("kubernetesKubeDNSSpec"),
+			"<imgMasq>", param("kubernetesDNSMasqSpec"),
+			"<imgSidecar>", param("kubernetesDNSSidecarSpec"),
+			"<domain>", param("kubernetesKubeletClusterDomain"),
+			"<clustIP>", param("kubeDNSServiceIP"),
+		}
+		if cs.Properties.OrchestratorProfile.NeedsExecHealthz() {
+			replacements = append(replacements, "<imgHealthz>", param("kubernetesExecHealthzSpec"))
+		}
+	case "coredns.yaml":
+		replacements = []string{
+			"<img>", param("kubernetesCoreDNSSpec"),
+			"<domain>", param("kubernetesKubeletClusterDomain"),
+			"<clustIP>", param("kubeDNSServiceIP"),
+		}
+	case "aad-default-admin-group-rbac.yaml":
+		replacements = []string{"<gID>", param("aadAdminGroupId")}
+	case "cluster-autoscaler-deployment.yaml":
+		var volumeMounts, volumes, hostNetwork string
+		if k.UseManagedIdentity {
+			volumeMounts = "- mountPath: /var/lib/waagent/\n          name: waagent\n          readOnly: true"
+			volumes = "- hostPath:\n          path: /var/lib/waagent/\n        name: waagent"
+			hostNetwork = "hostNetwork: true"
+		}
+		replacements = []string{
+			"<cloud>", param("kubernetesClusterAutoscalerAzureCloud"),
+			"<useManagedIdentity>", param("kubernetesClusterAutoscalerUseManagedIdentity"),
+			"<volMounts>", volumeMounts,
+			"<vols>", volumes,
+			"<hostNet>", hostNetwork,
+		}
+	case "calico-daemonset.yaml":
+		if k.NetworkPolicy != NetworkPolicyCalico {
+			break
+		}
+		replacements = []string{"<kubeClusterCidr>", param("kubeClusterCidr")}
+		if k.NetworkPlugin == NetworkPluginAzure {
+			content = removeLinesBetween(content, calicoInstallCNIStartMarker, calicoInstallCNIEndMarker)
+		} else {
+			replacements = append(replacements, "<calicoIPAMConfig>", `{"type": "host-local", "subnet": "usePodCidr"}`, "azv", "cali")
+		}
+	case "flannel-daemonset.yaml":
+		replacements = []string{"<kubeClusterCidr>", param("kubeClusterCidr")}
+	}
+	if len(replacements) == 0 {
+		return content
+	}
+	return strings.NewReplacer(replacements...).Replace(content)
+}
+
+// removeLinesBetween removes the lines from the first one containing start to the next one containing end, like
+// sed "/start/,/end/d"
+func removeLinesBetween(content, start, end string) string {
+	var result []string
+	removing := false
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case removing:
+			if strings.Contains(line, end) {
+				removing = false
+			}
+		case strings.Contains(line, start):
+			removing = true
+		default:
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
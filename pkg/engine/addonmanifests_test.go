@@ -0,0 +1,73 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestGetAddonManifests(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []api.KubernetesAddon{
+		{
+			Name:    "metrics-server",
+			Enabled: to.BoolPtr(true),
+			Containers: []api.KubernetesContainerSpec{
+				{
+					Name:  "metrics-server",
+					Image: "example.azurecr.io/metrics-server:v0.3.4",
+				},
+			},
+		},
+	}
+	if _, err := cs.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("unexpected error setting defaults: %s", err)
+	}
+
+	manifests, err := GetAddonManifests(cs)
+	if err != nil {
+		t.Fatalf("unexpected error getting addon manifests: %s", err)
+	}
+	byFile := map[string]string{}
+	for _, m := range manifests {
+		byFile[m.DestinationFile] = m.Content
+	}
+
+	kubeProxy, ok := byFile["kube-proxy-daemonset.yaml"]
+	if !ok {
+		t.Fatalf("expected kube-proxy-daemonset.yaml in the addon manifests")
+	}
+	parameters := getParameters(cs, DefaultGeneratorCode, "")
+	hyperkube := parameters["kubernetesHyperkubeSpec"].(paramsMap)["value"].(string)
+	for _, expected := range []string{"image: " + hyperkube, "--cluster-cidr=" + cs.Properties.OrchestratorProfile.KubernetesConfig.ClusterSubnet} {
+		if !strings.Contains(kubeProxy, expected) {
+			t.Errorf("expected kube-proxy-daemonset.yaml to contain %q", expected)
+		}
+	}
+	for _, placeholder := range []string{"<img>", "<CIDR>", "<kubeProxyMode>"} {
+		if strings.Contains(kubeProxy, placeholder) {
+			t.Errorf("expected %s to be substituted in kube-proxy-daemonset.yaml", placeholder)
+		}
+	}
+	if strings.Contains(byFile["coredns.yaml"], "<clustIP>") {
+		t.Errorf("expected <clustIP> to be substituted in coredns.yaml")
+	}
+	if !strings.Contains(byFile["kube-metrics-server-deployment.yaml"], "image: example.azurecr.io/metrics-server:v0.3.4") {
+		t.Errorf("expected kube-metrics-server-deployment.yaml to use the image of the addon")
+	}
+	if _, ok := byFile["kube-dns-deployment.yaml"]; ok {
+		t.Errorf("expected kube-dns-deployment.yaml not to be in the addon manifests of Kubernetes 1.14")
+	}
+}
+
+func TestRemoveLinesBetween(t *testing.T) {
+	content := "a\n# Start of init\nb\n# End of init\nc"
+	if actual := removeLinesBetween(content, "Start of init", "End of init"); actual != "a\nc" {
+		t.Errorf("expected the lines between the markers to be removed, got %q", actual)
+	}
+}
@@ -0,0 +1,697 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// armExprNode is a node of a parsed Azure Resource Manager template expression.
+type armExprNode interface{}
+
+// armStringLiteral is a single-quoted string literal, e.g. 'foo'.
+type armStringLiteral struct {
+	value string
+}
+
+// armNumberLiteral is an integer literal, e.g. 42.
+type armNumberLiteral struct {
+	text string
+}
+
+// armFunctionCall is a template function call, e.g. concat('a', 'b').
+type armFunctionCall struct {
+	name string
+	args []armExprNode
+}
+
+// armPropertyAccess is a property dereference, e.g. variables('foo').bar.
+type armPropertyAccess struct {
+	target   armExprNode
+	property string
+}
+
+// armIndexAccess is an index dereference, e.g. variables('foo')[0].
+type armIndexAccess struct {
+	target armExprNode
+	index  armExprNode
+}
+
+// isARMExpression returns true if the string value is evaluated by Azure Resource Manager
+// as a template expression rather than taken literally.
+func isARMExpression(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") && !strings.HasPrefix(s, "[[")
+}
+
+// parseARMValue parses a template string value. Literal values, including values escaped
+// with a leading "[[", are returned as an armStringLiteral.
+func parseARMValue(s string) (armExprNode, error) {
+	if strings.HasPrefix(s, "[[") {
+		return armStringLiteral{value: s[1:]}, nil
+	}
+	if !isARMExpression(s) {
+		return armStringLiteral{value: s}, nil
+	}
+	return parseARMExpression(s[1 : len(s)-1])
+}
+
+// parseARMExpression parses the body of a template expression, without the enclosing brackets.
+func parseARMExpression(s string) (armExprNode, error) {
+	p := &armExprParser{input: s}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, errors.Errorf("unexpected %q at offset %d in expression %q", p.input[p.pos:], p.pos, s)
+	}
+	return node, nil
+}
+
+type armExprParser struct {
+	input string
+	pos   int
+}
+
+func (p *armExprParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *armExprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *armExprParser) expect(c byte) error {
+	if p.peek() != c {
+		return errors.Errorf("expected %q at offset %d in expression %q", c, p.pos, p.input)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *armExprParser) parseExpression() (armExprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			name := p.parseIdentifier()
+			if name == "" {
+				return nil, errors.Errorf("expected property name at offset %d in expression %q", p.pos, p.input)
+			}
+			node = armPropertyAccess{target: node, property: name}
+		case '[':
+			p.pos++
+			index, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(']'); err != nil {
+				return nil, err
+			}
+			node = armIndexAccess{target: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *armExprParser) parsePrimary() (armExprNode, error) {
+	c := p.peek()
+	switch {
+	case c == '\'':
+		return p.parseString()
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+			p.pos++
+		}
+		return armNumberLiteral{text: p.input[start:p.pos]}, nil
+	}
+	name := p.parseIdentifier()
+	if name == "" {
+		return nil, errors.Errorf("unexpected %q at offset %d in expression %q", string(c), p.pos, p.input)
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	call := armFunctionCall{name: name}
+	if p.peek() == ')' {
+		p.pos++
+		return call, nil
+	}
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return call, nil
+		default:
+			return nil, errors.Errorf("expected ',' or ')' at offset %d in expression %q", p.pos, p.input)
+		}
+	}
+}
+
+func (p *armExprParser) parseIdentifier() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_') {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *armExprParser) parseString() (armExprNode, error) {
+	// skip the opening quote
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == '\'' {
+			// a doubled single quote is an escaped single quote
+			if p.pos+1 < len(p.input) && p.input[p.pos+1] == '\'' {
+				sb.WriteByte('\'')
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return armStringLiteral{value: sb.String()}, nil
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+	return nil, errors.Errorf("unterminated string literal in expression %q", p.input)
+}
+
+// flattenARMConcat returns the parts of a (possibly nested) concat() call.
+// Any other node is returned as a single part.
+func flattenARMConcat(node armExprNode) []armExprNode {
+	call, ok := node.(armFunctionCall)
+	if !ok || !strings.EqualFold(call.name, "concat") {
+		return []armExprNode{node}
+	}
+	var parts []armExprNode
+	for _, arg := range call.args {
+		parts = append(parts, flattenARMConcat(arg)...)
+	}
+	return parts
+}
+
+// armFunctionName returns the lower-cased function name if node is a function call.
+func armFunctionName(node armExprNode) string {
+	if call, ok := node.(armFunctionCall); ok {
+		return strings.ToLower(call.name)
+	}
+	return ""
+}
+
+// hclExpressionTranslator translates parsed template expressions into
+// Terraform (HCL2) expressions and string templates.
+type hclExpressionTranslator struct {
+	// resolveReference returns the Terraform address of the resource a reference() call points to
+	resolveReference func(path armExprNode) (string, error)
+	// wildcardCopyIndex renders every copyIndex() as "*", for matching resource names across copy loops
+	wildcardCopyIndex bool
+}
+
+// template renders a node as the contents of an HCL string template,
+// so literal text is carried over byte for byte.
+func (h hclExpressionTranslator) template(node armExprNode) (string, error) {
+	return h.templateWith(node, escapeHCLTemplate)
+}
+
+// quotedTemplate renders a node as a quoted HCL string template, usable as an expression.
+func (h hclExpressionTranslator) quotedTemplate(node armExprNode) (string, error) {
+	t, err := h.templateWith(node, escapeHCLQuoted)
+	if err != nil {
+		return "", err
+	}
+	return `"` + t + `"`, nil
+}
+
+func (h hclExpressionTranslator) templateWith(node armExprNode, escape func(string) string) (string, error) {
+	var sb strings.Builder
+	for _, part := range flattenARMConcat(node) {
+		switch p := part.(type) {
+		case armStringLiteral:
+			sb.WriteString(escape(p.value))
+			continue
+		case armNumberLiteral:
+			sb.WriteString(p.text)
+			continue
+		case armFunctionCall:
+			if strings.EqualFold(p.name, "resourceId") {
+				id, err := h.resourceIDTemplate(p, escape)
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(id)
+				continue
+			}
+		}
+		expr, err := h.expression(part)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("${" + expr + "}")
+	}
+	return sb.String(), nil
+}
+
+// expression renders a node as an HCL expression.
+func (h hclExpressionTranslator) expression(node armExprNode) (string, error) {
+	switch n := node.(type) {
+	case armStringLiteral:
+		return quoteHCLString(n.value), nil
+	case armNumberLiteral:
+		return n.text, nil
+	case armPropertyAccess:
+		if isReferenceChain(n) {
+			return h.referenceAttribute(n)
+		}
+		return h.propertyAccess(n)
+	case armIndexAccess:
+		target, err := h.expression(n.target)
+		if err != nil {
+			return "", err
+		}
+		index, err := h.expression(n.index)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%s]", target, index), nil
+	case armFunctionCall:
+		return h.functionCall(n)
+	}
+	return "", errors.Errorf("unsupported expression node %T", node)
+}
+
+func (h hclExpressionTranslator) propertyAccess(n armPropertyAccess) (string, error) {
+	switch armFunctionName(n.target) {
+	case "resourcegroup":
+		switch n.property {
+		case "location", "name", "id":
+			return fmt.Sprintf("data.azurerm_resource_group.%s.%s", terraformResourceGroupLabel, n.property), nil
+		}
+		return "", errors.Errorf("unsupported resourceGroup() property %q", n.property)
+	case "subscription":
+		switch n.property {
+		case "subscriptionId":
+			return fmt.Sprintf("data.azurerm_subscription.%s.subscription_id", terraformSubscriptionLabel), nil
+		case "tenantId":
+			return fmt.Sprintf("data.azurerm_subscription.%s.tenant_id", terraformSubscriptionLabel), nil
+		case "id":
+			return fmt.Sprintf("data.azurerm_subscription.%s.id", terraformSubscriptionLabel), nil
+		}
+		return "", errors.Errorf("unsupported subscription() property %q", n.property)
+	}
+	target, err := h.expression(n.target)
+	if err != nil {
+		return "", err
+	}
+	return target + "." + n.property, nil
+}
+
+// referenceAttributes maps runtime properties returned by reference() to the attribute
+// of the azurerm resource holding the same value, where the names differ.
+var referenceAttributes = map[string]string{
+	"dnsSettings.fqdn":      "fqdn",
+	"identity.principalId":  "identity[0].principal_id",
+	"identity.tenantId":     "identity[0].tenant_id",
+	"ipConfigurations":      "ip_configuration",
+	"privateIPAddress":      "private_ip_address",
+	"publicIPAddress":       "ip_address",
+	"ipAddress":             "ip_address",
+	"primaryEndpoints.blob": "primary_blob_endpoint",
+}
+
+// referenceAttribute translates reference(<resource>).a.b into an attribute of the Terraform resource,
+// e.g. reference(vm).identity.principalId becomes azurerm_virtual_machine.vm.identity[0].principal_id.
+func (h hclExpressionTranslator) referenceAttribute(n armPropertyAccess) (string, error) {
+	var properties []string
+	var node armExprNode = n
+	for {
+		p, ok := node.(armPropertyAccess)
+		if !ok {
+			break
+		}
+		properties = append([]string{p.property}, properties...)
+		node = p.target
+	}
+	call, ok := node.(armFunctionCall)
+	if !ok {
+		return "", errors.New("unsupported use of reference()")
+	}
+	target, err := h.functionCall(call)
+	if err != nil {
+		return "", err
+	}
+	// reference(id, apiVersion, 'Full') wraps the runtime properties in "properties"
+	if len(properties) > 1 && properties[0] == "properties" {
+		properties = properties[1:]
+	}
+	path := strings.Join(properties, ".")
+	if attribute, ok := referenceAttributes[path]; ok {
+		return target + "." + attribute, nil
+	}
+	for i, property := range properties {
+		properties[i] = camelToSnake(property)
+	}
+	return target + "." + strings.Join(properties, "."), nil
+}
+
+func (h hclExpressionTranslator) functionCall(n armFunctionCall) (string, error) {
+	name := strings.ToLower(n.name)
+	args := make([]string, len(n.args))
+	// resourceId's arguments are handled separately to keep the type segments literal
+	if name != "resourceid" {
+		for i, arg := range n.args {
+			s, err := h.expression(arg)
+			if err != nil {
+				return "", err
+			}
+			args[i] = s
+		}
+	}
+
+	requireArgs := func(min, max int) error {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			return errors.Errorf("unexpected number of arguments (%d) to %s()", len(args), n.name)
+		}
+		return nil
+	}
+
+	switch name {
+	case "variables", "parameters":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		lit, ok := n.args[0].(armStringLiteral)
+		if !ok {
+			return "", errors.Errorf("%s() requires a literal name", n.name)
+		}
+		if name == "variables" {
+			return "local." + lit.value, nil
+		}
+		return "var." + lit.value, nil
+	case "concat":
+		return h.quotedTemplate(n)
+	case "copyindex":
+		if err := requireArgs(0, 1); err != nil {
+			return "", err
+		}
+		if h.wildcardCopyIndex {
+			return "*", nil
+		}
+		if len(args) == 0 {
+			return "count.index", nil
+		}
+		return fmt.Sprintf("(count.index + %s)", args[0]), nil
+	case "base64":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("base64encode(%s)", args[0]), nil
+	case "tolower":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("lower(%s)", args[0]), nil
+	case "toupper":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("upper(%s)", args[0]), nil
+	case "trim":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("trimspace(%s)", args[0]), nil
+	case "replace":
+		if err := requireArgs(3, 3); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("replace(%s, %s, %s)", args[0], args[1], args[2]), nil
+	case "split":
+		if err := requireArgs(2, 2); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("split(%s, %s)", args[1], args[0]), nil
+	case "contains":
+		if err := requireArgs(2, 2); err != nil {
+			return "", err
+		}
+		switch armFunctionName(n.args[0]) {
+		case "split", "createarray":
+			return fmt.Sprintf("contains(%s, %s)", args[0], args[1]), nil
+		}
+		return fmt.Sprintf("strcontains(%s, %s)", args[0], args[1]), nil
+	case "startswith", "endswith":
+		if err := requireArgs(2, 2); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s, %s)", name, args[0], args[1]), nil
+	case "length":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("length(%s)", args[0]), nil
+	case "string":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("tostring(%s)", args[0]), nil
+	case "int":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("tonumber(%s)", args[0]), nil
+	case "substring":
+		if err := requireArgs(2, 3); err != nil {
+			return "", err
+		}
+		if len(args) == 2 {
+			return fmt.Sprintf("substr(%s, %s, -1)", args[0], args[1]), nil
+		}
+		return fmt.Sprintf("substr(%s, %s, %s)", args[0], args[1], args[2]), nil
+	case "take":
+		if err := requireArgs(2, 2); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("substr(%s, 0, %s)", args[0], args[1]), nil
+	case "createarray":
+		return "[" + strings.Join(args, ", ") + "]", nil
+	case "empty":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(length(%s) == 0)", args[0]), nil
+	case "true", "false":
+		if err := requireArgs(0, 0); err != nil {
+			return "", err
+		}
+		return name, nil
+	case "not":
+		if err := requireArgs(1, 1); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("!(%s)", args[0]), nil
+	case "if":
+		if err := requireArgs(3, 3); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s ? %s : %s)", args[0], args[1], args[2]), nil
+	case "equals", "and", "or", "add", "sub", "mul", "mod", "greater", "less":
+		if err := requireArgs(2, -1); err != nil {
+			return "", err
+		}
+		op := map[string]string{
+			"equals":  "==",
+			"and":     "&&",
+			"or":      "||",
+			"add":     "+",
+			"sub":     "-",
+			"mul":     "*",
+			"mod":     "%",
+			"greater": ">",
+			"less":    "<",
+		}[name]
+		return "(" + strings.Join(args, " "+op+" ") + ")", nil
+	case "div":
+		if err := requireArgs(2, 2); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("floor(%s / %s)", args[0], args[1]), nil
+	case "resourceid":
+		return h.resourceID(n)
+	case "reference":
+		if err := requireArgs(1, 3); err != nil {
+			return "", err
+		}
+		if h.resolveReference == nil {
+			return "", errors.New("reference() cannot be resolved here")
+		}
+		return h.resolveReference(n.args[0])
+	}
+	return "", errors.Errorf("template function %s() has no Terraform equivalent", n.name)
+}
+
+// resourceID renders resourceId([subscriptionId], [resourceGroupName], type, names...) as a
+// Terraform string template yielding the same fully qualified resource ID.
+func (h hclExpressionTranslator) resourceID(n armFunctionCall) (string, error) {
+	t, err := h.resourceIDTemplate(n, escapeHCLQuoted)
+	if err != nil {
+		return "", err
+	}
+	return `"` + t + `"`, nil
+}
+
+// resourceIDTemplate renders resourceId() as string template contents, escaping literal text with escape.
+func (h hclExpressionTranslator) resourceIDTemplate(n armFunctionCall, escape func(string) string) (string, error) {
+	typeIndex := -1
+	for i, arg := range n.args {
+		if lit, ok := arg.(armStringLiteral); ok && strings.Contains(lit.value, "/") {
+			typeIndex = i
+			break
+		}
+	}
+	if typeIndex < 0 || typeIndex > 2 {
+		return "", errors.New("resourceId() requires a literal resource type")
+	}
+	resourceType := strings.TrimSuffix(n.args[typeIndex].(armStringLiteral).value, "/")
+	names := n.args[typeIndex+1:]
+	typeSegments := strings.Split(resourceType, "/")
+	if len(typeSegments)-1 != len(names) {
+		return "", errors.Errorf("resourceId() for type %q expects %d names", resourceType, len(typeSegments)-1)
+	}
+
+	segment := func(node armExprNode) (string, error) {
+		return h.templateWith(node, escape)
+	}
+	var sb strings.Builder
+	switch typeIndex {
+	case 0:
+		sb.WriteString(fmt.Sprintf("${data.azurerm_resource_group.%s.id}", terraformResourceGroupLabel))
+	case 1:
+		rg, err := segment(n.args[0])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(fmt.Sprintf("/subscriptions/${data.azurerm_subscription.%s.subscription_id}/resourceGroups/%s", terraformSubscriptionLabel, rg))
+	case 2:
+		sub, err := segment(n.args[0])
+		if err != nil {
+			return "", err
+		}
+		rg, err := segment(n.args[1])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", sub, rg))
+	}
+	sb.WriteString("/providers/" + escape(typeSegments[0]))
+	for i, name := range names {
+		s, err := segment(name)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("/" + escape(typeSegments[i+1]) + "/" + s)
+	}
+	return sb.String(), nil
+}
+
+// isReferenceChain returns true if node is reference(...) or a property of it.
+func isReferenceChain(node armExprNode) bool {
+	for {
+		switch n := node.(type) {
+		case armPropertyAccess:
+			node = n.target
+		case armIndexAccess:
+			node = n.target
+		default:
+			return armFunctionName(node) == "reference"
+		}
+	}
+}
+
+// camelToSnake converts an ARM property name such as principalId to principal_id.
+func camelToSnake(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapeHCLTemplate escapes the template sequences in s so that it is taken literally
+// inside an HCL string template.
+func escapeHCLTemplate(s string) string {
+	s = strings.Replace(s, "${", "$${", -1)
+	return strings.Replace(s, "%{", "%%{", -1)
+}
+
+// escapeHCLQuoted escapes s for use inside a quoted HCL string template.
+func escapeHCLQuoted(s string) string {
+	q := quoteHCLString(s)
+	return q[1 : len(q)-1]
+}
+
+// quoteHCLString renders s as a quoted HCL string literal.
+func quoteHCLString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range escapeHCLTemplate(s) {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if unicode.IsPrint(r) {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteString(fmt.Sprintf(`\u%04x`, r))
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseARMValue(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected armExprNode
+	}{
+		{
+			value:    "plain text",
+			expected: armStringLiteral{value: "plain text"},
+		},
+		{
+			value:    "[[escaped]",
+			expected: armStringLiteral{value: "[escaped]"},
+		},
+		{
+			value: "[concat('it''s ', variables('name'), copyIndex(1))]",
+			expected: armFunctionCall{name: "concat", args: []armExprNode{
+				armStringLiteral{value: "it's "},
+				armFunctionCall{name: "variables", args: []armExprNode{armStringLiteral{value: "name"}}},
+				armFunctionCall{name: "copyIndex", args: []armExprNode{armNumberLiteral{text: "1"}}},
+			}},
+		},
+		{
+			value: "[variables('ips')[copyIndex()].id]",
+			expected: armPropertyAccess{
+				target: armIndexAccess{
+					target: armFunctionCall{name: "variables", args: []armExprNode{armStringLiteral{value: "ips"}}},
+					index:  armFunctionCall{name: "copyIndex"},
+				},
+				property: "id",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual, err := parseARMValue(c.value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", c.value, err)
+			continue
+		}
+		if diff := cmp.Diff(c.expected, actual, cmp.AllowUnexported(armStringLiteral{}, armNumberLiteral{}, armFunctionCall{}, armPropertyAccess{}, armIndexAccess{})); diff != "" {
+			t.Errorf("unexpected diff parsing %q: %s", c.value, diff)
+		}
+	}
+
+	for _, invalid := range []string{"[concat('a', ]", "[variables('a')]]", "[concat('unterminated)]"} {
+		if _, err := parseARMValue(invalid); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestHCLTemplate(t *testing.T) {
+	h := hclExpressionTranslator{}
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{
+			value:    "literal ${not} %{interpolated}",
+			expected: "literal $${not} %%{interpolated}",
+		},
+		{
+			value:    "[parameters('location')]",
+			expected: "${var.location}",
+		},
+		{
+			value:    "[concat(variables('prefix'), '-', copyIndex(variables('offset')))]",
+			expected: "${local.prefix}-${(count.index + local.offset)}",
+		},
+		{
+			value:    "[resourceId('Microsoft.Network/networkInterfaces', variables('nic'))]",
+			expected: "${data.azurerm_resource_group.cluster.id}/providers/Microsoft.Network/networkInterfaces/${local.nic}",
+		},
+		{
+			value:    "[resourceGroup().location]",
+			expected: "${data.azurerm_resource_group.cluster.location}",
+		},
+		{
+			value:    "[subscription().tenantId]",
+			expected: "${data.azurerm_subscription.current.tenant_id}",
+		},
+		{
+			value:    "[if(equals(parameters('a'), 'x'), 1, div(parameters('b'), 2))]",
+			expected: `${((var.a == "x") ? 1 : floor(var.b / 2))}`,
+		},
+		{
+			value:    "[base64(concat('line1\n\"quoted\"', variables('x')))]",
+			expected: `${base64encode("line1\n\"quoted\"${local.x}")}`,
+		},
+		{
+			value:    "[contains(split(variables('zones'), ','), parameters('location'))]",
+			expected: `${contains(split(",", local.zones), var.location)}`,
+		},
+		{
+			value:    "[substring(parameters('nameSuffix'), 0, 5)]",
+			expected: "${substr(var.nameSuffix, 0, 5)}",
+		},
+	}
+
+	for _, c := range cases {
+		node, err := parseARMValue(c.value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", c.value, err)
+			continue
+		}
+		actual, err := h.template(node)
+		if err != nil {
+			t.Errorf("unexpected error translating %q: %s", c.value, err)
+			continue
+		}
+		if actual != c.expected {
+			t.Errorf("translating %q: expected %q, got %q", c.value, c.expected, actual)
+		}
+	}
+}
+
+func TestHCLTemplateUnsupported(t *testing.T) {
+	h := hclExpressionTranslator{}
+	for _, value := range []string{
+		"[guid(resourceGroup().id)]",
+		"[reference(variables('vmName')).identity.principalId]",
+		"[resourceGroup().tags]",
+	} {
+		node, err := parseARMValue(value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", value, err)
+			continue
+		}
+		if _, err := h.template(node); err == nil {
+			t.Errorf("expected error translating %q", value)
+		}
+	}
+}
+
+func TestHCLReferenceAttribute(t *testing.T) {
+	h := hclExpressionTranslator{
+		resolveReference: func(path armExprNode) (string, error) {
+			return "azurerm_virtual_machine.master[count.index]", nil
+		},
+	}
+	cases := map[string]string{
+		"[reference(variables('vm'), '2018-06-01', 'Full').identity.principalId]": "${azurerm_virtual_machine.master[count.index].identity[0].principal_id}",
+		"[reference(variables('pip')).dnsSettings.fqdn]":                          "${azurerm_virtual_machine.master[count.index].fqdn}",
+		"[reference(variables('vm')).properties.vmId]":                            "${azurerm_virtual_machine.master[count.index].vm_id}",
+	}
+	for value, expected := range cases {
+		node, err := parseARMValue(value)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", value, err)
+			continue
+		}
+		actual, err := h.template(node)
+		if err != nil {
+			t.Errorf("unexpected error translating %q: %s", value, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("translating %q: expected %q, got %q", value, expected, actual)
+		}
+	}
+}
+
+func TestQuoteHCLString(t *testing.T) {
+	actual := quoteHCLString("a\"b\\c\n${d}\x01")
+	expected := `"a\"b\\c\n$${d}\u0001"`
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
@@ -32,10 +32,14 @@ func GenerateARMResources(cs *api.ContainerService) []interface{} {
 			msiRoleAssignment = createMSIRoleAssignment(IdentityContributorRole)
 		}
 		armResources = append(armResources, userAssignedID, msiRoleAssignment)
+		if cs.Properties.AreAgentProfilesCustomVNET() {
+			armResources = append(armResources, createMSIVNETRoleAssignmentDeployment())
+		}
 	}
 
 	if !cs.Properties.OrchestratorProfile.IsPrivateCluster() &&
 		!cs.Properties.AnyAgentHasLoadBalancerBackendAddressPoolIDs() &&
+		!cs.Properties.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() &&
 		cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku == api.StandardLoadBalancerSku {
 		isForMaster := false
 		publicIPAddress := CreatePublicIPAddress(isForMaster)
@@ -66,12 +70,14 @@ func GenerateARMResources(cs *api.ContainerService) []interface{} {
 			armResources = append(armResources, hostedMasterVnet)
 		}
 
-		if !isAzureCNI {
-			armResources = append(armResources, createRouteTable())
+		if !isAzureCNI && cs.Properties.GetCustomRouteTableID() == "" {
+			armResources = append(armResources, createRouteTable(cs))
 		}
 
-		hostedMasterNsg := createHostedMasterNSG()
-		armResources = append(armResources, hostedMasterNsg)
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			hostedMasterNsg := createHostedMasterNSG()
+			armResources = append(armResources, hostedMasterNsg)
+		}
 	} else {
 		isMasterVMSS := cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.IsVirtualMachineScaleSets()
 		var masterResources []interface{}
@@ -590,6 +590,38 @@ func TestGenerateARMResourcesWithVMSSAgentPool(t *testing.T) {
 	}
 }
 
+func TestGenerateARMResourcesWithUserAssignedIDAndCustomVNET(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.15.7", 1, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = true
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedID = "clusterIdentity"
+	if _, err := cs.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("unexpected error setting the defaults of the container service: %s", err)
+	}
+
+	hasVNETRoleAssignment := func() bool {
+		for _, resource := range GenerateARMResources(cs) {
+			if deployment, ok := resource.(ResourceGroupDeploymentARM); ok && *deployment.Name == "[concat(variables('userAssignedID'), '-vnet-role-assignment')]" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasVNETRoleAssignment() {
+		t.Errorf("expected no VNET role assignment for the user assigned identity without a custom VNET")
+	}
+
+	vnetSubnetID := "/subscriptions/SUB_ID/resourceGroups/VNET_RG/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME"
+	cs.Properties.MasterProfile.VnetSubnetID = vnetSubnetID
+	for _, profile := range cs.Properties.AgentPoolProfiles {
+		profile.VnetSubnetID = vnetSubnetID
+	}
+
+	if !hasVNETRoleAssignment() {
+		t.Errorf("expected a VNET role assignment for the user assigned identity with a custom VNET")
+	}
+}
+
 func getNICIPConfigs(n int) *[]network.InterfaceIPConfiguration {
 	var ipConfigurations []network.InterfaceIPConfiguration
 	for i := 1; i <= n; i++ {
@@ -190,3 +190,11 @@ type DeploymentARM struct {
 	DeploymentARMResource
 	resources.DeploymentExtended
 }
+
+// ResourceGroupDeploymentARM embeds the ARMResource type in resources.DeploymentExtended, for a nested deployment
+// into another resource group than the one of the cluster.
+type ResourceGroupDeploymentARM struct {
+	DeploymentARMResource
+	resources.DeploymentExtended
+	ResourceGroup *string `json:"resourceGroup,omitempty"`
+}
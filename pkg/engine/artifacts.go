@@ -4,10 +4,13 @@
 package engine
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
 
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/aks-engine/pkg/api/common"
@@ -15,10 +18,12 @@ import (
 
 // kubernetesComponentFileSpec defines a k8s component that we will deliver via file to a master node vm
 type kubernetesComponentFileSpec struct {
-	sourceFile      string // filename to source spec data from
-	base64Data      string // if not "", this base64-encoded string will take precedent over sourceFile
-	destinationFile string // the filename to write to disk on the destination OS
-	isEnabled       bool   // is this spec enabled?
+	sourceFile      string                  // filename to source spec data from
+	template        string                  // if not "", this template will take precedent over sourceFile
+	base64Data      string                  // if not "", this base64-encoded string will take precedent over sourceFile
+	destinationFile string                  // the filename to write to disk on the destination OS
+	isEnabled       bool                    // is this spec enabled?
+	overlays        []api.KubernetesOverlay // patches applied to the spec data after it is rendered
 }
 
 func kubernetesContainerAddonSettingsInit(p *api.Properties) map[string]kubernetesComponentFileSpec {
@@ -28,122 +33,26 @@ func kubernetesContainerAddonSettingsInit(p *api.Properties) map[string]kubernet
 	if p.OrchestratorProfile.KubernetesConfig == nil {
 		p.OrchestratorProfile.KubernetesConfig = &api.KubernetesConfig{}
 	}
-	o := p.OrchestratorProfile
-	k := o.KubernetesConfig
-	// TODO validate that each of these addons are actually wired in to the conveniences in getAddonFuncMap
-	return map[string]kubernetesComponentFileSpec{
-		HeapsterAddonName: {
-			sourceFile:      "kubernetesmasteraddons-heapster-deployment.yaml",
-			base64Data:      k.GetAddonScript(HeapsterAddonName),
-			destinationFile: "kube-heapster-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(HeapsterAddonName),
-		},
-		MetricsServerAddonName: {
-			sourceFile:      "kubernetesmasteraddons-metrics-server-deployment.yaml",
-			base64Data:      k.GetAddonScript(MetricsServerAddonName),
-			destinationFile: "kube-metrics-server-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(MetricsServerAddonName),
-		},
-		TillerAddonName: {
-			sourceFile:      "kubernetesmasteraddons-tiller-deployment.yaml",
-			base64Data:      k.GetAddonScript(TillerAddonName),
-			destinationFile: "kube-tiller-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(TillerAddonName),
-		},
-		AADPodIdentityAddonName: {
-			sourceFile:      "kubernetesmasteraddons-aad-pod-identity-deployment.yaml",
-			base64Data:      k.GetAddonScript(AADPodIdentityAddonName),
-			destinationFile: "aad-pod-identity-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(AADPodIdentityAddonName),
-		},
-		ACIConnectorAddonName: {
-			sourceFile:      "kubernetesmasteraddons-aci-connector-deployment.yaml",
-			base64Data:      k.GetAddonScript(ACIConnectorAddonName),
-			destinationFile: "aci-connector-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(ACIConnectorAddonName),
-		},
-		ClusterAutoscalerAddonName: {
-			sourceFile:      "kubernetesmasteraddons-cluster-autoscaler-deployment.yaml",
-			base64Data:      k.GetAddonScript(ClusterAutoscalerAddonName),
-			destinationFile: "cluster-autoscaler-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(ClusterAutoscalerAddonName),
-		},
-		BlobfuseFlexVolumeAddonName: {
-			sourceFile:      "kubernetesmasteraddons-blobfuse-flexvolume-installer.yaml",
-			base64Data:      k.GetAddonScript(BlobfuseFlexVolumeAddonName),
-			destinationFile: "blobfuse-flexvolume-installer.yaml",
-			isEnabled:       k.IsAddonEnabled(BlobfuseFlexVolumeAddonName),
-		},
-		SMBFlexVolumeAddonName: {
-			sourceFile:      "kubernetesmasteraddons-smb-flexvolume-installer.yaml",
-			base64Data:      k.GetAddonScript(SMBFlexVolumeAddonName),
-			destinationFile: "smb-flexvolume-installer.yaml",
-			isEnabled:       k.IsAddonEnabled(SMBFlexVolumeAddonName),
-		},
-		KeyVaultFlexVolumeAddonName: {
-			sourceFile:      "kubernetesmasteraddons-keyvault-flexvolume-installer.yaml",
-			base64Data:      k.GetAddonScript(KeyVaultFlexVolumeAddonName),
-			destinationFile: "keyvault-flexvolume-installer.yaml",
-			isEnabled:       k.IsAddonEnabled(KeyVaultFlexVolumeAddonName),
-		},
-		DashboardAddonName: {
-			sourceFile:      "kubernetesmasteraddons-kubernetes-dashboard-deployment.yaml",
-			base64Data:      k.GetAddonScript(DashboardAddonName),
-			destinationFile: "kubernetes-dashboard-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(DashboardAddonName),
-		},
-		ReschedulerAddonName: {
-			sourceFile:      "kubernetesmasteraddons-kube-rescheduler-deployment.yaml",
-			base64Data:      k.GetAddonScript(ReschedulerAddonName),
-			destinationFile: "kube-rescheduler-deployment.yaml",
-			isEnabled:       k.IsAddonEnabled(ReschedulerAddonName),
-		},
-		NVIDIADevicePluginAddonName: {
-			sourceFile:      "kubernetesmasteraddons-nvidia-device-plugin-daemonset.yaml",
-			base64Data:      k.GetAddonScript(NVIDIADevicePluginAddonName),
-			destinationFile: "nvidia-device-plugin.yaml",
-			isEnabled:       k.IsAddonEnabled(NVIDIADevicePluginAddonName),
-		},
-		ContainerMonitoringAddonName: {
-			sourceFile:      "kubernetesmasteraddons-omsagent-daemonset.yaml",
-			base64Data:      k.GetAddonScript(ContainerMonitoringAddonName),
-			destinationFile: "omsagent-daemonset.yaml",
-			isEnabled:       k.IsAddonEnabled(ContainerMonitoringAddonName),
-		},
-		IPMASQAgentAddonName: {
-			sourceFile:      "ip-masq-agent.yaml",
-			base64Data:      k.GetAddonScript(IPMASQAgentAddonName),
-			destinationFile: "ip-masq-agent.yaml",
-			isEnabled:       k.IsAddonEnabled(IPMASQAgentAddonName),
-		},
-		AzureCNINetworkMonitorAddonName: {
-			sourceFile:      "azure-cni-networkmonitor.yaml",
-			base64Data:      k.GetAddonScript(AzureCNINetworkMonitorAddonName),
-			destinationFile: "azure-cni-networkmonitor.yaml",
-			isEnabled:       k.IsAddonEnabled(AzureCNINetworkMonitorAddonName),
-		},
-		DNSAutoscalerAddonName: {
-			sourceFile:      "dns-autoscaler.yaml",
-			base64Data:      k.GetAddonScript(DNSAutoscalerAddonName),
-			destinationFile: "dns-autoscaler.yaml",
-			isEnabled:       k.IsAddonEnabled(DNSAutoscalerAddonName),
-		},
-		CalicoAddonName: {
-			sourceFile:      "kubernetesmasteraddons-calico-daemonset.yaml",
-			base64Data:      k.GetAddonScript(CalicoAddonName),
-			destinationFile: "calico-daemonset.yaml",
-			isEnabled:       k.IsAddonEnabled(CalicoAddonName),
-		},
-		AzureNetworkPolicyAddonName: {
-			sourceFile:      "kubernetesmasteraddons-azure-npm-daemonset.yaml",
-			base64Data:      k.GetAddonScript(AzureNetworkPolicyAddonName),
-			destinationFile: "azure-npm-daemonset.yaml",
-			isEnabled:       k.IsAddonEnabled(AzureNetworkPolicyAddonName),
-		},
+	k := p.OrchestratorProfile.KubernetesConfig
+	settings := map[string]kubernetesComponentFileSpec{}
+	for _, descriptor := range api.GetAddonDescriptors() {
+		manifest := descriptor.Manifest()
+		if manifest.SourceFile == "" && manifest.Template == "" {
+			continue
+		}
+		settings[descriptor.Name()] = kubernetesComponentFileSpec{
+			sourceFile:      manifest.SourceFile,
+			template:        manifest.Template,
+			base64Data:      k.GetAddonScript(descriptor.Name()),
+			overlays:        k.GetAddonByName(descriptor.Name()).Overlays,
+			destinationFile: manifest.DestinationFile,
+			isEnabled:       k.IsAddonEnabled(descriptor.Name()),
+		}
 	}
+	return settings
 }
 
-func kubernetesAddonSettingsInit(p *api.Properties) []kubernetesComponentFileSpec {
+func kubernetesAddonSettingsInit(p *api.Properties) ([]kubernetesComponentFileSpec, error) {
 	if p.OrchestratorProfile == nil {
 		p.OrchestratorProfile = &api.OrchestratorProfile{}
 	}
@@ -156,60 +65,70 @@ func kubernetesAddonSettingsInit(p *api.Properties) []kubernetesComponentFileSpe
 		{
 			sourceFile:      "kubernetesmasteraddons-kube-dns-deployment.yaml",
 			base64Data:      k.GetAddonScript(KubeDNSAddonName),
+			overlays:        k.GetAddonByName(KubeDNSAddonName).Overlays,
 			destinationFile: "kube-dns-deployment.yaml",
 			isEnabled:       !common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.12.0"),
 		},
 		{
 			sourceFile:      "coredns.yaml",
 			base64Data:      k.GetAddonScript(CoreDNSAddonName),
+			overlays:        k.GetAddonByName(CoreDNSAddonName).Overlays,
 			destinationFile: "coredns.yaml",
 			isEnabled:       common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.12.0"),
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-kube-proxy-daemonset.yaml",
 			base64Data:      k.GetAddonScript(KubeProxyAddonName),
+			overlays:        k.GetAddonByName(KubeProxyAddonName).Overlays,
 			destinationFile: "kube-proxy-daemonset.yaml",
 			isEnabled:       true,
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-cilium-daemonset.yaml",
 			base64Data:      k.GetAddonScript(CiliumAddonName),
+			overlays:        k.GetAddonByName(CiliumAddonName).Overlays,
 			destinationFile: "cilium-daemonset.yaml",
 			isEnabled:       k.NetworkPolicy == NetworkPolicyCilium,
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-flannel-daemonset.yaml",
 			base64Data:      k.GetAddonScript(FlannelAddonName),
+			overlays:        k.GetAddonByName(FlannelAddonName).Overlays,
 			destinationFile: "flannel-daemonset.yaml",
 			isEnabled:       k.NetworkPlugin == NetworkPluginFlannel,
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-aad-default-admin-group-rbac.yaml",
 			base64Data:      k.GetAddonScript(AADAdminGroupAddonName),
+			overlays:        k.GetAddonByName(AADAdminGroupAddonName).Overlays,
 			destinationFile: "aad-default-admin-group-rbac.yaml",
 			isEnabled:       p.AADProfile != nil && p.AADProfile.AdminGroupID != "",
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-azure-cloud-provider-deployment.yaml",
 			base64Data:      k.GetAddonScript(AzureCloudProviderAddonName),
+			overlays:        k.GetAddonByName(AzureCloudProviderAddonName).Overlays,
 			destinationFile: "azure-cloud-provider-deployment.yaml",
 			isEnabled:       true,
 		},
 		{
 			sourceFile:      "kubernetesmaster-audit-policy.yaml",
-			base64Data:      k.GetAddonScript(AuditPolicyAddonName),
+			base64Data:      getAuditPolicyData(k),
+			overlays:        k.GetAddonByName(AuditPolicyAddonName).Overlays,
 			destinationFile: "audit-policy.yaml",
 			isEnabled:       common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.8.0"),
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-pod-security-policy.yaml",
 			base64Data:      k.GetAddonScript(PodSecurityPolicyAddonName),
+			overlays:        k.GetAddonByName(PodSecurityPolicyAddonName).Overlays,
 			destinationFile: "pod-security-policy.yaml",
 			isEnabled:       to.Bool(p.OrchestratorProfile.KubernetesConfig.EnablePodSecurityPolicy) || common.IsKubernetesVersionGe(o.OrchestratorVersion, "1.15.0-beta.1"),
 		},
 		{
 			sourceFile:      "kubernetesmasteraddons-scheduled-maintenance-deployment.yaml",
 			base64Data:      k.GetAddonScript(ScheduledMaintenanceAddonName),
+			overlays:        k.GetAddonByName(ScheduledMaintenanceAddonName).Overlays,
 			destinationFile: "scheduled-maintenance-deployment.yaml",
 			isEnabled:       k.IsAddonEnabled(ScheduledMaintenanceAddonName),
 		},
@@ -228,6 +147,7 @@ func kubernetesAddonSettingsInit(p *api.Properties) []kubernetesComponentFileSpe
 			kubernetesComponentFileSpec{
 				sourceFile:      unmanagedStorageClassesSourceYaml,
 				base64Data:      p.OrchestratorProfile.KubernetesConfig.GetAddonScript(AzureStorageClassesAddonName),
+				overlays:        p.OrchestratorProfile.KubernetesConfig.GetAddonByName(AzureStorageClassesAddonName).Overlays,
 				destinationFile: "azure-storage-classes.yaml",
 				isEnabled:       p.AgentPoolProfiles[0].StorageProfile == api.StorageAccount,
 			})
@@ -235,12 +155,19 @@ func kubernetesAddonSettingsInit(p *api.Properties) []kubernetesComponentFileSpe
 			kubernetesComponentFileSpec{
 				sourceFile:      managedStorageClassesSourceYaml,
 				base64Data:      p.OrchestratorProfile.KubernetesConfig.GetAddonScript(AzureStorageClassesAddonName),
+				overlays:        p.OrchestratorProfile.KubernetesConfig.GetAddonByName(AzureStorageClassesAddonName).Overlays,
 				destinationFile: "azure-storage-classes.yaml",
 				isEnabled:       p.AgentPoolProfiles[0].StorageProfile == api.ManagedDisks,
 			})
 	}
 
-	return kubernetesComponentFileSpecs
+	chartAddons, err := getChartAddonSettings(p)
+	if err != nil {
+		return nil, err
+	}
+	kubernetesComponentFileSpecs = append(kubernetesComponentFileSpecs, chartAddons...)
+
+	return kubernetesComponentFileSpecs, nil
 }
 
 func kubernetesManifestSettingsInit(p *api.Properties) []kubernetesComponentFileSpec {
@@ -275,35 +202,48 @@ func kubernetesManifestSettingsInit(p *api.Properties) []kubernetesComponentFile
 			sourceFile:      "kubernetesmaster-kube-scheduler.yaml",
 			base64Data:      k.SchedulerConfig["data"],
 			destinationFile: "kube-scheduler.yaml",
+			overlays:        k.ManifestOverlays["kube-scheduler"],
 			isEnabled:       true,
 		},
 		{
 			sourceFile:      kubeControllerManagerYaml,
 			base64Data:      k.ControllerManagerConfig["data"],
 			destinationFile: "kube-controller-manager.yaml",
+			overlays:        k.ManifestOverlays["kube-controller-manager"],
 			isEnabled:       true,
 		},
 		{
 			sourceFile:      "kubernetesmaster-cloud-controller-manager.yaml",
 			base64Data:      k.CloudControllerManagerConfig["data"],
 			destinationFile: "cloud-controller-manager.yaml",
+			overlays:        k.ManifestOverlays["cloud-controller-manager"],
 			isEnabled:       to.Bool(k.UseCloudControllerManager),
 		},
 		{
 			sourceFile:      "kubernetesmaster-kube-apiserver.yaml",
 			base64Data:      k.APIServerConfig["data"],
 			destinationFile: "kube-apiserver.yaml",
+			overlays:        k.ManifestOverlays["kube-apiserver"],
 			isEnabled:       true,
 		},
 		{
 			sourceFile:      "kubernetesmaster-kube-addon-manager.yaml",
 			base64Data:      "", // arbitrary user-provided data not enabled for kube-addon-manager spec
 			destinationFile: "kube-addon-manager.yaml",
+			overlays:        k.ManifestOverlays["kube-addon-manager"],
 			isEnabled:       true,
 		},
 	}
 }
 
+// getAuditPolicyData returns the base64-encoded audit policy of the audit profile, or else the data of the audit policy addon
+func getAuditPolicyData(k *api.KubernetesConfig) string {
+	if k.HasAuditPolicy() {
+		return base64.StdEncoding.EncodeToString([]byte(k.AuditProfile.Policy))
+	}
+	return k.GetAddonScript(AuditPolicyAddonName)
+}
+
 func getAddonString(input, destinationPath, destinationFile string) string {
 	addonString := getBase64EncodedGzippedCustomScriptFromStr(input)
 	return buildConfigString(addonString, destinationFile, destinationPath)
@@ -316,7 +256,14 @@ func substituteConfigString(input string, kubernetesFeatureSettings []kubernetes
 	for _, setting := range kubernetesFeatureSettings {
 		if setting.isEnabled {
 			var cscript string
-			if setting.base64Data != "" {
+			if len(setting.overlays) > 0 {
+				var err error
+				cscript, err = getComponentManifest(setting, sourcePath, orchestratorVersion)
+				if err != nil {
+					return ""
+				}
+				config += getAddonString(cscript, destinationPath, setting.destinationFile)
+			} else if setting.base64Data != "" {
 				var err error
 				cscript, err = getStringFromBase64(setting.base64Data)
 				if err != nil {
@@ -324,9 +271,13 @@ func substituteConfigString(input string, kubernetesFeatureSettings []kubernetes
 				}
 				config += getAddonString(cscript, destinationPath, setting.destinationFile)
 			} else {
-				cscript = getCustomScriptFromFile(setting.sourceFile,
+				var err error
+				cscript, err = getCustomScriptFromFile(setting.sourceFile,
 					sourcePath,
 					versions[0]+"."+versions[1])
+				if err != nil {
+					return ""
+				}
 				config += buildConfigString(
 					cscript,
 					setting.destinationFile,
@@ -338,6 +289,30 @@ func substituteConfigString(input string, kubernetesFeatureSettings []kubernetes
 	return strings.Replace(input, placeholder, config, -1)
 }
 
+// getComponentManifest returns the data of a spec, either the user-provided data or its source file, with the overlays
+// of the spec applied
+func getComponentManifest(setting kubernetesComponentFileSpec, sourcePath, orchestratorVersion string) (string, error) {
+	var content string
+	if setting.base64Data != "" {
+		var err error
+		if content, err = getStringFromBase64(setting.base64Data); err != nil {
+			return "", err
+		}
+	} else {
+		versions := strings.Split(orchestratorVersion, ".")
+		customDataFilePath, err := getCustomDataFilePath(setting.sourceFile, sourcePath, versions[0]+"."+versions[1])
+		if err != nil {
+			return "", err
+		}
+		b, err := Asset(customDataFilePath)
+		if err != nil {
+			return "", err
+		}
+		content = strings.Replace(string(b), "\r\n", "\n", -1)
+	}
+	return applyOverlays(content, setting.overlays)
+}
+
 func buildConfigString(configString, destinationFile, destinationPath string) string {
 	contents := []string{
 		fmt.Sprintf("- path: %s/%s", destinationPath, destinationFile),
@@ -351,19 +326,84 @@ func buildConfigString(configString, destinationFile, destinationPath string) st
 	return strings.Join(contents, "\\n")
 }
 
-func getCustomScriptFromFile(sourceFile, sourcePath, version string) string {
-	customDataFilePath := getCustomDataFilePath(sourceFile, sourcePath, version)
-	return getBase64EncodedGzippedCustomScript(customDataFilePath)
+func getCustomScriptFromFile(sourceFile, sourcePath, version string) (string, error) {
+	customDataFilePath, err := getCustomDataFilePath(sourceFile, sourcePath, version)
+	if err != nil {
+		return "", err
+	}
+	return getBase64EncodedGzippedCustomScript(customDataFilePath), nil
 }
 
-func getCustomDataFilePath(sourceFile, sourcePath, version string) string {
+// getCustomDataFilePath returns the manifest of a source file for a Kubernetes minor version, e.g. "1.16": the one in
+// the directory of the version if there is one, or else the unversioned one. The unversioned manifests are only
+// written for the versions up to the newest version with a directory, so there is no manifest for newer versions.
+func getCustomDataFilePath(sourceFile, sourcePath, version string) (string, error) {
 	sourceFileFullPath := sourcePath + "/" + sourceFile
 	sourceFileFullPathVersioned := sourcePath + "/" + version + "/" + sourceFile
 
 	// Test to check if the versioned file can be read.
-	_, err := Asset(sourceFileFullPathVersioned)
-	if err == nil {
-		sourceFileFullPath = sourceFileFullPathVersioned
+	if _, err := Asset(sourceFileFullPathVersioned); err == nil {
+		return sourceFileFullPathVersioned, nil
+	}
+	if newest := getNewestManifestVersion(sourcePath); newest != "" && !common.IsKubernetesVersionGe(newest+".0", version+".0") {
+		return "", errors.Errorf("there is no manifest %s for Kubernetes %s, the manifests in %s are written for Kubernetes %s and older", sourceFile, version, sourcePath, newest)
+	}
+	if _, err := Asset(sourceFileFullPath); err != nil {
+		return "", errors.Errorf("there is no manifest %s for Kubernetes %s in %s", sourceFile, version, sourcePath)
+	}
+	return sourceFileFullPath, nil
+}
+
+// getNewestManifestVersion returns the newest Kubernetes minor version with a directory of manifests in a source
+// path, or "" if there is none
+func getNewestManifestVersion(sourcePath string) string {
+	dirs, err := AssetDir(sourcePath)
+	if err != nil {
+		return ""
+	}
+	var newest string
+	for _, dir := range dirs {
+		if _, err = semver.Make(dir + ".0"); err != nil {
+			continue
+		}
+		if newest == "" || common.IsKubernetesVersionGe(dir+".0", newest+".0") {
+			newest = dir
+		}
+	}
+	return newest
+}
+
+// validateManifestVersions checks that there is a manifest of each enabled master component and addon for the
+// Kubernetes version of the cluster, so that generate fails rather than delivering a manifest written for older
+// versions
+func validateManifestVersions(p *api.Properties) error {
+	if p.OrchestratorProfile == nil || !p.OrchestratorProfile.IsKubernetes() {
+		return nil
+	}
+	versions := strings.Split(p.OrchestratorProfile.OrchestratorVersion, ".")
+	if len(versions) < 2 {
+		return errors.Errorf("invalid Kubernetes version %s", p.OrchestratorProfile.OrchestratorVersion)
+	}
+	version := versions[0] + "." + versions[1]
+	addonSettings, err := kubernetesAddonSettingsInit(p)
+	if err != nil {
+		return err
+	}
+	var containerAddonSettings []kubernetesComponentFileSpec
+	for _, setting := range kubernetesContainerAddonSettingsInit(p) {
+		containerAddonSettings = append(containerAddonSettings, setting)
+	}
+	sourcePaths := []string{"k8s/manifests", "k8s/addons", "k8s/containeraddons"}
+	for i, settings := range [][]kubernetesComponentFileSpec{kubernetesManifestSettingsInit(p), addonSettings, containerAddonSettings} {
+		sourcePath := sourcePaths[i]
+		for _, setting := range settings {
+			if !setting.isEnabled || setting.base64Data != "" || setting.template != "" {
+				continue
+			}
+			if _, err = getCustomDataFilePath(setting.sourceFile, sourcePath, version); err != nil {
+				return err
+			}
+		}
 	}
-	return sourceFileFullPath
+	return nil
 }
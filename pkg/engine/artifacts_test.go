@@ -303,6 +303,110 @@ func TestKubernetesContainerAddonSettingsInit(t *testing.T) {
 	}
 }
 
+func TestKubernetesContainerAddonSettingsInitWithRegisteredAddon(t *testing.T) {
+	defer api.ResetAddonRegistry()
+
+	addon := &api.FileAddon{
+		AddonName:  "log-shipper",
+		Enabled:    true,
+		Containers: []api.KubernetesContainerSpec{{Name: "shipper", Image: "example.azurecr.io/shipper:1.0"}},
+		Config:     map[string]string{"endpoint": "https://logs.example.com"},
+		ManifestTemplate: `image: {{ContainerImage "shipper"}}
+endpoint: {{ContainerConfig "endpoint"}}`,
+	}
+	if err := api.RegisterAddon(addon); err != nil {
+		t.Fatalf("unexpected error registering addon: %s", err)
+	}
+	p := &api.Properties{
+		OrchestratorProfile: &api.OrchestratorProfile{
+			OrchestratorType:    Kubernetes,
+			OrchestratorVersion: "1.14.1",
+			KubernetesConfig: &api.KubernetesConfig{
+				Addons: []api.KubernetesAddon{
+					{
+						Name:       "log-shipper",
+						Enabled:    to.BoolPtr(true),
+						Containers: addon.Containers,
+						Config:     addon.Config,
+					},
+				},
+			},
+		},
+	}
+
+	spec, ok := kubernetesContainerAddonSettingsInit(p)["log-shipper"]
+	if !ok {
+		t.Fatalf("expected a componentFileSpec for the registered addon")
+	}
+	if !spec.isEnabled || spec.destinationFile != "log-shipper.yaml" || spec.sourceFile != "" {
+		t.Errorf("unexpected componentFileSpec for the registered addon %+v", spec)
+	}
+	if _, ok = kubernetesContainerAddonSettingsInit(p)[AppGwIngressAddonName]; ok {
+		t.Errorf("expected no componentFileSpec for %s, which has no manifest", AppGwIngressAddonName)
+	}
+
+	expected := getAddonString("image: example.azurecr.io/shipper:1.0\nendpoint: https://logs.example.com", "/etc/kubernetes/addons", "log-shipper.yaml")
+	if addons := getContainerAddonsString(p, "k8s/containeraddons"); !strings.Contains(addons, expected) {
+		t.Errorf("expected the rendered manifest of the registered addon in %s", addons)
+	}
+}
+
+func TestClusterAutoscalerAddonManifestWithFallbackPools(t *testing.T) {
+	p := &api.Properties{
+		OrchestratorProfile: &api.OrchestratorProfile{
+			OrchestratorType:    Kubernetes,
+			OrchestratorVersion: "1.15.3",
+			KubernetesConfig: &api.KubernetesConfig{
+				Addons: []api.KubernetesAddon{
+					{
+						Name:    ClusterAutoscalerAddonName,
+						Enabled: to.BoolPtr(true),
+						Containers: []api.KubernetesContainerSpec{
+							{Name: ClusterAutoscalerAddonName, Image: "cluster-autoscaler:v1.15.1"},
+						},
+						Config: map[string]string{
+							"min-nodes":            "1",
+							"max-nodes":            "5",
+							"scan-interval":        "10s",
+							"expander":             "priority",
+							"fallback-node-groups": "k8s-spot-12345678-vmss,k8s-regular-12345678-vmss",
+							"priorities":           `{1: [".*"], 10: ["^k8s-regular-12345678-vmss$"], 20: ["^k8s-spot-12345678-vmss$"]}`,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	setting := kubernetesContainerAddonSettingsInit(p)[ClusterAutoscalerAddonName]
+	manifest, err := getContainerAddonManifest(p, ClusterAutoscalerAddonName, setting, "k8s/containeraddons")
+	if err != nil {
+		t.Fatalf("unexpected error rendering the cluster-autoscaler manifest: %s", err)
+	}
+	for _, expected := range []string{
+		"  name: cluster-autoscaler-priority-expander\n",
+		"  priorities: '{1: [\".*\"], 10: [\"^k8s-regular-12345678-vmss$\"], 20: [\"^k8s-spot-12345678-vmss$\"]}'\n",
+		"        - --nodes=1:5:<vmssName>\n        - --nodes=1:5:k8s-spot-12345678-vmss\n        - --nodes=1:5:k8s-regular-12345678-vmss\n        - --expander=priority\n",
+	} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("expected %q in the cluster-autoscaler manifest %s", expected, manifest)
+		}
+	}
+
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Config["expander"] = "random"
+	p.OrchestratorProfile.KubernetesConfig.Addons[0].Config["fallback-node-groups"] = ""
+	manifest, err = getContainerAddonManifest(p, ClusterAutoscalerAddonName, setting, "k8s/containeraddons")
+	if err != nil {
+		t.Fatalf("unexpected error rendering the cluster-autoscaler manifest: %s", err)
+	}
+	if strings.Contains(manifest, "cluster-autoscaler-priority-expander\n") {
+		t.Errorf("expected no priority expander config map in the cluster-autoscaler manifest %s", manifest)
+	}
+	if !strings.Contains(manifest, "        - --nodes=1:5:<vmssName>\n        - --expander=random\n") {
+		t.Errorf("expected a single node group in the cluster-autoscaler manifest %s", manifest)
+	}
+}
+
 func TestKubernetesAddonSettingsInit(t *testing.T) {
 	mockAzureStackProperties := api.GetMockPropertiesWithCustomCloudProfile("azurestackcloud", true, true, false)
 	cases := []struct {
@@ -644,7 +748,10 @@ func TestKubernetesAddonSettingsInit(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		componentFileSpecArray := kubernetesAddonSettingsInit(c.p)
+		componentFileSpecArray, err := kubernetesAddonSettingsInit(c.p)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
 		for _, componentFileSpec := range componentFileSpecArray {
 			switch componentFileSpec.destinationFile {
 			case "kube-dns-deployment.yaml":
@@ -720,6 +827,103 @@ func TestKubernetesAddonSettingsInit(t *testing.T) {
 	}
 }
 
+func TestGetAuditPolicyData(t *testing.T) {
+	k := &api.KubernetesConfig{
+		Addons: []api.KubernetesAddon{
+			{
+				Name: AuditPolicyAddonName,
+				Data: base64.StdEncoding.EncodeToString([]byte("kind: Policy\n")),
+			},
+		},
+	}
+	if actual := getAuditPolicyData(k); actual != k.Addons[0].Data {
+		t.Errorf("expected the data of the audit policy addon without audit profile, got %s", actual)
+	}
+
+	policy := "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"
+	k.AuditProfile = &api.AuditProfile{Policy: policy}
+	if actual := getAuditPolicyData(k); actual != base64.StdEncoding.EncodeToString([]byte(policy)) {
+		t.Errorf("expected the audit policy of the audit profile, got %s", actual)
+	}
+}
+
+func TestGetCustomDataFilePath(t *testing.T) {
+	cases := []struct {
+		sourceFile    string
+		sourcePath    string
+		version       string
+		expected      string
+		expectedError string
+	}{
+		{
+			sourceFile: "kubernetesmasteraddons-kube-dns-deployment.yaml",
+			sourcePath: "k8s/addons",
+			version:    "1.10",
+			expected:   "k8s/addons/1.10/kubernetesmasteraddons-kube-dns-deployment.yaml",
+		},
+		{
+			sourceFile: "kubernetesmasteraddons-kube-dns-deployment.yaml",
+			sourcePath: "k8s/addons",
+			version:    "1.14",
+			expected:   "k8s/addons/kubernetesmasteraddons-kube-dns-deployment.yaml",
+		},
+		{
+			sourceFile: "dns-autoscaler.yaml",
+			sourcePath: "k8s/containeraddons",
+			version:    "1.16",
+			expected:   "k8s/containeraddons/dns-autoscaler.yaml",
+		},
+		{
+			sourceFile: "kubernetesmaster-kube-apiserver.yaml",
+			sourcePath: "k8s/manifests",
+			version:    "1.17",
+			expected:   "k8s/manifests/kubernetesmaster-kube-apiserver.yaml",
+		},
+		{
+			sourceFile:    "dns-autoscaler.yaml",
+			sourcePath:    "k8s/containeraddons",
+			version:       "1.17",
+			expectedError: "there is no manifest dns-autoscaler.yaml for Kubernetes 1.17, the manifests in k8s/containeraddons are written for Kubernetes 1.16 and older",
+		},
+		{
+			sourceFile:    "missing.yaml",
+			sourcePath:    "k8s/addons",
+			version:       "1.14",
+			expectedError: "there is no manifest missing.yaml for Kubernetes 1.14 in k8s/addons",
+		},
+	}
+	for _, c := range cases {
+		actual, err := getCustomDataFilePath(c.sourceFile, c.sourcePath, c.version)
+		if c.expectedError != "" {
+			if err == nil || err.Error() != c.expectedError {
+				t.Errorf("expected error %q for %s %s, got %v", c.expectedError, c.sourceFile, c.version, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s %s: %s", c.sourceFile, c.version, err)
+		}
+		if actual != c.expected {
+			t.Errorf("expected %s for %s %s, got %s", c.expected, c.sourceFile, c.version, actual)
+		}
+	}
+}
+
+func TestValidateManifestVersions(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.16.0-beta.1", 3, 2, false)
+	if _, err := cs.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("unexpected error setting defaults: %s", err)
+	}
+	if err := validateManifestVersions(cs.Properties); err != nil {
+		t.Fatalf("unexpected error validating the manifests of Kubernetes 1.16: %s", err)
+	}
+
+	cs.Properties.OrchestratorProfile.OrchestratorVersion = "1.17.0"
+	if err := validateManifestVersions(cs.Properties); err == nil || !strings.Contains(err.Error(), "for Kubernetes 1.17") {
+		t.Fatalf("expected error validating the manifests of Kubernetes 1.17, got %v", err)
+	}
+}
+
 func TestKubernetesManifestSettingsInit(t *testing.T) {
 	mockAzureStackProperties := api.GetMockPropertiesWithCustomCloudProfile("azurestackcloud", true, true, false)
 	cases := []struct {
@@ -0,0 +1,709 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/blang/semver"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultChartNamespace is the namespace chart addons are rendered in when none is configured
+	defaultChartNamespace = "kube-system"
+	addonManagerModeLabel = "addonmanager.kubernetes.io/mode"
+)
+
+// chartInstallOrder is the order Helm installs the kinds of a release in, so that kubectl applies dependencies first
+var chartInstallOrder = []string{
+	"Namespace",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ServiceAccount",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+}
+
+// clusterScopedKinds are the kinds which are not given the namespace of the release
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"PodSecurityPolicy":              true,
+	"StorageClass":                   true,
+	"PersistentVolume":               true,
+	"CustomResourceDefinition":       true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"PriorityClass":                  true,
+	"APIService":                     true,
+	"CSIDriver":                      true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+// chartKubernetesAPIVersions are the API group versions served by default by Kubernetes 1.x, with the minor versions
+// of Kubernetes which added and removed them, 0 if they were always or are still served
+var chartKubernetesAPIVersions = []struct {
+	apiVersion     string
+	added, removed uint64
+}{
+	{"v1", 0, 0},
+	{"admissionregistration.k8s.io/v1", 16, 0},
+	{"admissionregistration.k8s.io/v1beta1", 9, 0},
+	{"apiextensions.k8s.io/v1", 16, 0},
+	{"apiextensions.k8s.io/v1beta1", 7, 0},
+	{"apiregistration.k8s.io/v1", 10, 0},
+	{"apiregistration.k8s.io/v1beta1", 7, 0},
+	{"apps/v1", 9, 0},
+	{"apps/v1beta1", 0, 16},
+	{"apps/v1beta2", 8, 16},
+	{"authentication.k8s.io/v1", 6, 0},
+	{"authorization.k8s.io/v1", 6, 0},
+	{"autoscaling/v1", 0, 0},
+	{"autoscaling/v2beta1", 8, 0},
+	{"autoscaling/v2beta2", 12, 0},
+	{"batch/v1", 0, 0},
+	{"batch/v1beta1", 8, 0},
+	{"certificates.k8s.io/v1beta1", 6, 0},
+	{"coordination.k8s.io/v1", 14, 0},
+	{"coordination.k8s.io/v1beta1", 12, 0},
+	{"discovery.k8s.io/v1beta1", 17, 0},
+	{"events.k8s.io/v1beta1", 8, 0},
+	{"extensions/v1beta1", 0, 0},
+	{"networking.k8s.io/v1", 7, 0},
+	{"networking.k8s.io/v1beta1", 14, 0},
+	{"node.k8s.io/v1beta1", 14, 0},
+	{"policy/v1beta1", 0, 0},
+	{"rbac.authorization.k8s.io/v1", 8, 0},
+	{"rbac.authorization.k8s.io/v1beta1", 6, 0},
+	{"scheduling.k8s.io/v1", 14, 0},
+	{"scheduling.k8s.io/v1beta1", 11, 0},
+	{"storage.k8s.io/v1", 6, 0},
+	{"storage.k8s.io/v1beta1", 6, 0},
+}
+
+// chartMetadata is the content of the Chart.yaml file of a chart, available to templates as .Chart
+type chartMetadata struct {
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Version     string `json:"version,omitempty"`
+	AppVersion  string `json:"appVersion,omitempty"`
+	Description string `json:"description,omitempty"`
+	KubeVersion string `json:"kubeVersion,omitempty"`
+}
+
+// chartFiles are the files of a chart outside of its templates, available to templates as .Files
+type chartFiles map[string][]byte
+
+// Get returns the content of a file of the chart, "" if there is none
+func (f chartFiles) Get(name string) string {
+	return string(f[name])
+}
+
+// GetBytes returns the content of a file of the chart, nil if there is none
+func (f chartFiles) GetBytes(name string) []byte {
+	return f[name]
+}
+
+// chart is a Helm chart loaded from a directory or a packaged .tgz chart
+type chart struct {
+	metadata  chartMetadata
+	values    map[string]interface{}
+	templates map[string]string
+	files     chartFiles
+}
+
+// loadChart loads the chart in a directory or a packaged .tgz chart
+func loadChart(chartPath string) (*chart, error) {
+	info, err := os.Stat(chartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading chart")
+	}
+	files := map[string][]byte{}
+	if info.IsDir() {
+		err = filepath.Walk(chartPath, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(chartPath, p)
+			if err != nil {
+				return err
+			}
+			b, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = b
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "reading chart directory")
+		}
+	} else {
+		if files, err = readChartArchive(chartPath); err != nil {
+			return nil, err
+		}
+	}
+	return newChart(files)
+}
+
+// readChartArchive reads the files of a packaged chart, whose files are all in a directory named after the chart
+func readChartArchive(archivePath string) (map[string][]byte, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading chart archive")
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading chart archive %s", archivePath)
+	}
+	defer gz.Close()
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading chart archive %s", archivePath)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		parts := strings.SplitN(path.Clean(filepath.ToSlash(header.Name)), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s in chart archive %s", header.Name, archivePath)
+		}
+		files[parts[1]] = b
+	}
+	return files, nil
+}
+
+func newChart(files map[string][]byte) (*chart, error) {
+	c := &chart{
+		values:    map[string]interface{}{},
+		templates: map[string]string{},
+		files:     chartFiles{},
+	}
+	b, ok := files["Chart.yaml"]
+	if !ok {
+		return nil, errors.New("chart has no Chart.yaml")
+	}
+	if err := yaml.Unmarshal(b, &c.metadata); err != nil {
+		return nil, errors.Wrap(err, "parsing Chart.yaml")
+	}
+	if c.metadata.Name == "" {
+		return nil, errors.New("chart has no name in Chart.yaml")
+	}
+	if b, ok = files["values.yaml"]; ok {
+		if err := yaml.Unmarshal(b, &c.values); err != nil {
+			return nil, errors.Wrap(err, "parsing values.yaml")
+		}
+		if c.values == nil {
+			c.values = map[string]interface{}{}
+		}
+	}
+	for name, b := range files {
+		switch {
+		case strings.HasPrefix(name, "charts/") || name == "requirements.yaml":
+			return nil, errors.Errorf("chart %s has dependencies, which are not supported", c.metadata.Name)
+		case strings.HasPrefix(name, "templates/"):
+			c.templates[name] = string(b)
+		case name != "Chart.yaml" && name != "values.yaml":
+			c.files[name] = b
+		}
+	}
+	if _, _, err := c.parse(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// unsupportedChartFuncRegexp matches the error of a template calling a function missing from chartFuncMap
+var unsupportedChartFuncRegexp = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// parse parses the templates of the chart, and returns them with their names in the order they are rendered in.
+// The templates are parsed when the chart is loaded, so that a chart calling a function outside of the subset of
+// the Helm template engine in chartFuncMap is rejected before anything is rendered.
+func (c *chart) parse() (*template.Template, []string, error) {
+	t := template.New(c.metadata.Name)
+	t.Funcs(chartFuncMap(t))
+	var names []string
+	for name := range c.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := t.New(path.Join(c.metadata.Name, name)).Parse(c.templates[name]); err != nil {
+			if m := unsupportedChartFuncRegexp.FindStringSubmatch(err.Error()); m != nil {
+				return nil, nil, errors.Errorf("template %s of chart %s calls the template function %s, which is not supported, see the supported template functions in docs/topics/clusterdefinitions.md", name, c.metadata.Name, m[1])
+			}
+			return nil, nil, errors.Wrapf(err, "parsing template %s", name)
+		}
+	}
+	return t, names, nil
+}
+
+// render renders the templates of the chart the way `helm template` does, and returns the manifests as a single YAML
+// stream, in the order Helm installs them. Resources are labeled to be reconciled by kube-addon-manager, and given
+// the namespace of the release unless they are cluster scoped or already have one.
+func (c *chart) render(releaseName, namespace, kubernetesVersion string, values map[string]interface{}) (string, error) {
+	v, err := semver.Make(kubernetesVersion)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing Kubernetes version %s", kubernetesVersion)
+	}
+	top := map[string]interface{}{
+		"Values": mergeChartValues(copyChartValues(c.values), values),
+		"Release": map[string]interface{}{
+			"Name":      releaseName,
+			"Namespace": namespace,
+			"Service":   "Tiller",
+			"IsInstall": true,
+			"IsUpgrade": false,
+			"Revision":  1,
+		},
+		"Chart": c.metadata,
+		"Capabilities": map[string]interface{}{
+			"KubeVersion": map[string]interface{}{
+				"Major":      fmt.Sprint(v.Major),
+				"Minor":      fmt.Sprint(v.Minor),
+				"GitVersion": "v" + kubernetesVersion,
+			},
+			"APIVersions": getChartAPIVersions(v),
+		},
+		"Files": c.files,
+	}
+
+	t, names, err := c.parse()
+	if err != nil {
+		return "", err
+	}
+
+	var docs []chartManifest
+	for _, name := range names {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || base == "NOTES.txt" {
+			continue
+		}
+		vals := map[string]interface{}{}
+		for k, val := range top {
+			vals[k] = val
+		}
+		vals["Template"] = map[string]interface{}{
+			"Name":     path.Join(c.metadata.Name, name),
+			"BasePath": path.Join(c.metadata.Name, "templates"),
+		}
+		var buffer bytes.Buffer
+		if err = t.ExecuteTemplate(&buffer, path.Join(c.metadata.Name, name), vals); err != nil {
+			return "", errors.Wrapf(err, "rendering template %s", name)
+		}
+		rendered := strings.Replace(buffer.String(), "<no value>", "", -1)
+		for _, doc := range strings.Split("\n"+rendered, "\n---") {
+			m, err := newChartManifest(path.Join(c.metadata.Name, name), doc, namespace)
+			if err != nil {
+				return "", err
+			}
+			if m != nil {
+				docs = append(docs, *m)
+			}
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return chartInstallIndex(docs[i].kind) < chartInstallIndex(docs[j].kind)
+	})
+
+	var result []string
+	for _, doc := range docs {
+		result = append(result, fmt.Sprintf("# Source: %s\n%s", doc.source, doc.content))
+	}
+	return strings.Join(result, "---\n"), nil
+}
+
+type chartManifest struct {
+	source  string
+	kind    string
+	content string
+}
+
+// newChartManifest labels a rendered resource for kube-addon-manager, nil if the document is empty
+func newChartManifest(source, doc, namespace string) (*chartManifest, error) {
+	var resource map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &resource); err != nil {
+		return nil, errors.Wrapf(err, "parsing the output of template %s", source)
+	}
+	if len(resource) == 0 {
+		return nil, nil
+	}
+	kind, _ := resource["kind"].(string)
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		resource["metadata"] = metadata
+	}
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+		metadata["labels"] = labels
+	}
+	if _, ok := labels[addonManagerModeLabel]; !ok {
+		labels[addonManagerModeLabel] = "Reconcile"
+	}
+	if _, ok := metadata["namespace"]; !ok && !clusterScopedKinds[kind] {
+		metadata["namespace"] = namespace
+	}
+	b, err := yaml.Marshal(resource)
+	if err != nil {
+		return nil, errors.Wrapf(err, "writing the output of template %s", source)
+	}
+	return &chartManifest{source: source, kind: kind, content: string(b)}, nil
+}
+
+func chartInstallIndex(kind string) int {
+	for i, k := range chartInstallOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(chartInstallOrder)
+}
+
+// chartAPIVersions are the API group versions served by the cluster, available to templates as
+// .Capabilities.APIVersions
+type chartAPIVersions []string
+
+// Has returns true if the cluster serves the API group version, e.g. "apps/v1"
+func (a chartAPIVersions) Has(apiVersion string) bool {
+	for _, v := range a {
+		if v == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// getChartAPIVersions returns the API group versions served by default by a version of Kubernetes
+func getChartAPIVersions(kubernetesVersion semver.Version) chartAPIVersions {
+	var apiVersions chartAPIVersions
+	for _, v := range chartKubernetesAPIVersions {
+		if kubernetesVersion.Minor >= v.added && (v.removed == 0 || kubernetesVersion.Minor < v.removed) {
+			apiVersions = append(apiVersions, v.apiVersion)
+		}
+	}
+	return apiVersions
+}
+
+func copyChartValues(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = copyChartValues(m)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// mergeChartValues merges values into the default values of a chart the way Helm does: maps are merged, other values
+// replace the defaults, and null values remove them
+func mergeChartValues(defaults, values map[string]interface{}) map[string]interface{} {
+	for k, v := range values {
+		if v == nil {
+			delete(defaults, k)
+			continue
+		}
+		m, isMap := v.(map[string]interface{})
+		d, isDefaultMap := defaults[k].(map[string]interface{})
+		if isMap && isDefaultMap {
+			defaults[k] = mergeChartValues(d, m)
+		} else {
+			defaults[k] = v
+		}
+	}
+	return defaults
+}
+
+// chartFuncMap returns the subset of the functions of the Helm template engine and its sprig library that charts
+// can call. It must be kept in sync with the list of supported template functions in docs/topics/clusterdefinitions.md.
+func chartFuncMap(t *template.Template) template.FuncMap {
+	return template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			var buffer bytes.Buffer
+			if err := t.ExecuteTemplate(&buffer, name, data); err != nil {
+				return "", err
+			}
+			return buffer.String(), nil
+		},
+		"tpl": func(text string, data interface{}) (string, error) {
+			tpl := template.New("tpl").Funcs(chartFuncMap(t))
+			for _, associated := range t.Templates() {
+				if _, err := tpl.AddParseTree(associated.Name(), associated.Tree); err != nil {
+					return "", err
+				}
+			}
+			if _, err := tpl.New("tpl-text").Parse(text); err != nil {
+				return "", err
+			}
+			var buffer bytes.Buffer
+			if err := tpl.ExecuteTemplate(&buffer, "tpl-text", data); err != nil {
+				return "", err
+			}
+			return buffer.String(), nil
+		},
+		"required": func(message string, v interface{}) (interface{}, error) {
+			if v == nil || v == "" {
+				return nil, errors.New(message)
+			}
+			return v, nil
+		},
+		"toYaml": func(v interface{}) string {
+			b, err := yaml.Marshal(v)
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSuffix(string(b), "\n")
+		},
+		"fromYaml": func(s string) map[string]interface{} {
+			m := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(s), &m); err != nil {
+				m["Error"] = err.Error()
+			}
+			return m
+		},
+		"toJson": func(v interface{}) string {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return ""
+			}
+			return string(b)
+		},
+		"default": func(d interface{}, given ...interface{}) interface{} {
+			if len(given) == 0 || isEmptyChartValue(given[0]) {
+				return d
+			}
+			return given[0]
+		},
+		"empty": isEmptyChartValue,
+		"coalesce": func(v ...interface{}) interface{} {
+			for _, val := range v {
+				if !isEmptyChartValue(val) {
+					return val
+				}
+			}
+			return nil
+		},
+		"ternary": func(vt, vf interface{}, v bool) interface{} {
+			if v {
+				return vt
+			}
+			return vf
+		},
+		"quote": func(v ...interface{}) string {
+			var quoted []string
+			for _, s := range v {
+				if s != nil {
+					quoted = append(quoted, fmt.Sprintf("%q", fmt.Sprint(s)))
+				}
+			}
+			return strings.Join(quoted, " ")
+		},
+		"squote": func(v ...interface{}) string {
+			var quoted []string
+			for _, s := range v {
+				if s != nil {
+					quoted = append(quoted, fmt.Sprintf("'%v'", s))
+				}
+			}
+			return strings.Join(quoted, " ")
+		},
+		"indent": indentChartText,
+		"nindent": func(spaces int, s string) string {
+			return "\n" + indentChartText(spaces, s)
+		},
+		"trim":       strings.TrimSpace,
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trunc": func(c int, s string) string {
+			if len(s) <= c {
+				return s
+			}
+			return s[:c]
+		},
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"title":     strings.Title,
+		"replace":   func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec": func(s string) string {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err.Error()
+			}
+			return string(b)
+		},
+		"sha256sum": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"toString": func(v interface{}) string { return fmt.Sprint(v) },
+		"join": func(sep string, v interface{}) string {
+			var s []string
+			val := reflect.ValueOf(v)
+			if val.Kind() == reflect.Slice {
+				for i := 0; i < val.Len(); i++ {
+					s = append(s, fmt.Sprint(val.Index(i).Interface()))
+				}
+			}
+			return strings.Join(s, sep)
+		},
+		"list": func(v ...interface{}) []interface{} { return v },
+		"dict": func(v ...interface{}) map[string]interface{} {
+			d := map[string]interface{}{}
+			for i := 0; i+1 < len(v); i += 2 {
+				d[fmt.Sprint(v[i])] = v[i+1]
+			}
+			return d
+		},
+		"set": func(d map[string]interface{}, key string, v interface{}) map[string]interface{} {
+			d[key] = v
+			return d
+		},
+		"hasKey": func(d map[string]interface{}, key string) bool {
+			_, ok := d[key]
+			return ok
+		},
+		"semverCompare": func(constraint, version string) (bool, error) {
+			matches, err := parseChartSemverConstraint(constraint)
+			if err != nil {
+				return false, err
+			}
+			v, err := parseChartVersion(version)
+			if err != nil {
+				return false, err
+			}
+			return matches(v), nil
+		},
+	}
+}
+
+func indentChartText(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func isEmptyChartValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return val.IsNil()
+	}
+	return false
+}
+
+// renderChartAddon renders the chart of an addon for the Kubernetes version of the cluster
+func renderChartAddon(addon api.KubernetesAddon, kubernetesVersion string) (string, error) {
+	c, err := loadChart(addon.Chart.Path)
+	if err != nil {
+		return "", err
+	}
+	if addon.Chart.Version != "" && addon.Chart.Version != c.metadata.Version {
+		return "", errors.Errorf("chart %s has version %s, expected %s", c.metadata.Name, c.metadata.Version, addon.Chart.Version)
+	}
+	releaseName := addon.Chart.ReleaseName
+	if releaseName == "" {
+		releaseName = addon.Name
+	}
+	namespace := addon.Chart.Namespace
+	if namespace == "" {
+		namespace = defaultChartNamespace
+	}
+	return c.render(releaseName, namespace, kubernetesVersion, addon.Chart.Values)
+}
+
+// getChartAddonSettings renders the charts of the enabled chart addons into the files delivered to
+// /etc/kubernetes/addons with the other addons
+func getChartAddonSettings(p *api.Properties) ([]kubernetesComponentFileSpec, error) {
+	var specs []kubernetesComponentFileSpec
+	if p.OrchestratorProfile == nil || p.OrchestratorProfile.KubernetesConfig == nil {
+		return specs, nil
+	}
+	for _, addon := range p.OrchestratorProfile.KubernetesConfig.Addons {
+		if addon.Chart == nil || !addon.IsEnabled() {
+			continue
+		}
+		manifest, err := renderChartAddon(addon, p.OrchestratorProfile.OrchestratorVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rendering the chart of addon %s", addon.Name)
+		}
+		specs = append(specs, kubernetesComponentFileSpec{
+			base64Data:      base64.StdEncoding.EncodeToString([]byte(manifest)),
+			destinationFile: addon.Name + ".yaml",
+			isEnabled:       true,
+			overlays:        addon.Overlays,
+		})
+	}
+	return specs, nil
+}
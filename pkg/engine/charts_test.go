@@ -0,0 +1,300 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/blang/semver"
+)
+
+const expectedHelloChart = `# Source: hello/templates/rbac.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    addonmanager.kubernetes.io/mode: Reconcile
+  name: hello-hello
+  namespace: kube-system
+---
+# Source: hello/templates/rbac.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  labels:
+    addonmanager.kubernetes.io/mode: Reconcile
+  name: hello-hello
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  verbs:
+  - get
+  - list
+---
+# Source: hello/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    addonmanager.kubernetes.io/mode: Reconcile
+    app: hello
+    release: hello
+  name: hello-hello
+  namespace: kube-system
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: hello
+  template:
+    metadata:
+      labels:
+        app: hello
+    spec:
+      containers:
+      - image: example.azurecr.io/hello:2.0
+        name: hello
+        resources: {}
+`
+
+func TestRenderChartAddon(t *testing.T) {
+	chartDir := filepath.Join("testdata", "charts", "hello")
+	archive := packageChart(t, chartDir)
+	defer os.Remove(archive)
+
+	for _, path := range []string{chartDir, archive} {
+		addon := api.KubernetesAddon{
+			Name:    "hello",
+			Enabled: to.BoolPtr(true),
+			Chart: &api.KubernetesAddonChart{
+				Path:    path,
+				Version: "0.1.0",
+				Values: map[string]interface{}{
+					"replicaCount": 2,
+					"image":        map[string]interface{}{"tag": "2.0"},
+				},
+			},
+		}
+		manifest, err := renderChartAddon(addon, "1.14.6")
+		if err != nil {
+			t.Fatalf("unexpected error rendering chart %s: %s", path, err)
+		}
+		if manifest != expectedHelloChart {
+			t.Errorf("unexpected manifest rendering chart %s:\n%s", path, manifest)
+		}
+	}
+
+	manifest, err := renderChartAddon(api.KubernetesAddon{Name: "hello", Chart: &api.KubernetesAddonChart{Path: chartDir}}, "1.8.15")
+	if err != nil {
+		t.Fatalf("unexpected error rendering chart for Kubernetes 1.8: %s", err)
+	}
+	if !strings.Contains(manifest, "apiVersion: extensions/v1beta1\nkind: Deployment") {
+		t.Errorf("expected the Deployment of the chart to be extensions/v1beta1 for Kubernetes 1.8, got:\n%s", manifest)
+	}
+}
+
+func TestRenderChartAddonOptions(t *testing.T) {
+	addon := api.KubernetesAddon{
+		Name:    "hello",
+		Enabled: to.BoolPtr(true),
+		Chart: &api.KubernetesAddonChart{
+			Path:        filepath.Join("testdata", "charts", "hello"),
+			ReleaseName: "greeter",
+			Namespace:   "hello-system",
+			Values: map[string]interface{}{
+				"rbac":      map[string]interface{}{"create": false},
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "100m"}},
+			},
+		},
+	}
+	manifest, err := renderChartAddon(addon, "1.14.6")
+	if err != nil {
+		t.Fatalf("unexpected error rendering chart: %s", err)
+	}
+	for _, expected := range []string{"name: greeter-hello", "namespace: hello-system", "cpu: 100m", "image: example.azurecr.io/hello:1.0"} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("expected manifest to contain %q, got:\n%s", expected, manifest)
+		}
+	}
+	if strings.Contains(manifest, "kind: ClusterRole") {
+		t.Errorf("expected rbac.create to disable the ClusterRole, got:\n%s", manifest)
+	}
+
+	addon.Chart.Version = "0.2.0"
+	if _, err = renderChartAddon(addon, "1.14.6"); err == nil {
+		t.Errorf("expected error rendering a chart of another version")
+	}
+	addon.Chart = &api.KubernetesAddonChart{Path: filepath.Join("testdata", "charts", "missing")}
+	if _, err = renderChartAddon(addon, "1.14.6"); err == nil {
+		t.Errorf("expected error rendering a missing chart")
+	}
+}
+
+func TestNewChartUnsupportedFunction(t *testing.T) {
+	files := map[string][]byte{
+		"Chart.yaml":               []byte("name: hello\nversion: 0.1.0\n"),
+		"templates/configmap.yaml": []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name | snakecase }}\n"),
+	}
+	_, err := newChart(files)
+	if err == nil {
+		t.Fatalf("expected error loading a chart calling an unsupported template function")
+	}
+	expected := "template templates/configmap.yaml of chart hello calls the template function snakecase, which is not supported"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error to contain %q, got %q", expected, err.Error())
+	}
+
+	files["templates/configmap.yaml"] = []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name | lower | trunc 63 }}\n")
+	if _, err = newChart(files); err != nil {
+		t.Errorf("unexpected error loading a chart calling supported template functions: %s", err)
+	}
+}
+
+func TestGetChartAddonSettings(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons = []api.KubernetesAddon{
+		{
+			Name:    "hello",
+			Enabled: to.BoolPtr(true),
+			Chart:   &api.KubernetesAddonChart{Path: filepath.Join("testdata", "charts", "hello")},
+		},
+		{
+			Name:    "disabled",
+			Enabled: to.BoolPtr(false),
+			Chart:   &api.KubernetesAddonChart{Path: filepath.Join("testdata", "charts", "missing")},
+		},
+	}
+	specs, err := getChartAddonSettings(cs.Properties)
+	if err != nil {
+		t.Fatalf("unexpected error rendering chart addons: %s", err)
+	}
+	if len(specs) != 1 || specs[0].destinationFile != "hello.yaml" || !specs[0].isEnabled {
+		t.Fatalf("unexpected chart addon settings %+v", specs)
+	}
+	manifest, err := base64.StdEncoding.DecodeString(specs[0].base64Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), "kind: Deployment") {
+		t.Errorf("expected the chart to be rendered, got:\n%s", manifest)
+	}
+
+	settings, err := kubernetesAddonSettingsInit(cs.Properties)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var found bool
+	for _, spec := range settings {
+		if spec.destinationFile == "hello.yaml" {
+			found = spec.base64Data == specs[0].base64Data
+		}
+	}
+	if !found {
+		t.Errorf("expected the chart addon to be delivered with the other addons")
+	}
+
+	cs.Properties.OrchestratorProfile.KubernetesConfig.Addons[1].Enabled = to.BoolPtr(true)
+	if _, err = getChartAddonSettings(cs.Properties); err == nil {
+		t.Errorf("expected error rendering a missing chart")
+	}
+	if _, err = kubernetesAddonSettingsInit(cs.Properties); err == nil {
+		t.Errorf("expected the error rendering a missing chart to be returned with the addon settings")
+	}
+}
+
+func TestGetChartAPIVersions(t *testing.T) {
+	cases := []struct {
+		kubernetesVersion string
+		apiVersion        string
+		expected          bool
+	}{
+		{"1.8.15", "v1", true},
+		{"1.8.15", "apps/v1", false},
+		{"1.8.15", "rbac.authorization.k8s.io/v1", true},
+		{"1.15.12", "apps/v1beta2", true},
+		{"1.16.10", "apps/v1beta2", false},
+		{"1.16.10", "apiextensions.k8s.io/v1", true},
+		{"1.17.7", "discovery.k8s.io/v1beta1", true},
+		{"1.17.7", "example.com/v1", false},
+	}
+	for _, c := range cases {
+		if actual := getChartAPIVersions(semver.MustParse(c.kubernetesVersion)).Has(c.apiVersion); actual != c.expected {
+			t.Errorf("expected .Capabilities.APIVersions.Has %q to be %t for Kubernetes %s, got %t", c.apiVersion, c.expected, c.kubernetesVersion, actual)
+		}
+	}
+}
+
+func TestMergeChartValues(t *testing.T) {
+	defaults := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "hello", "tag": "1.0"},
+		"replicas": 1,
+		"debug":    true,
+	}
+	merged := mergeChartValues(defaults, map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "2.0"},
+		"replicas": 3,
+		"debug":    nil,
+	})
+	image := merged["image"].(map[string]interface{})
+	if image["repository"] != "hello" || image["tag"] != "2.0" {
+		t.Errorf("expected image to be merged, got %v", image)
+	}
+	if merged["replicas"] != 3 {
+		t.Errorf("expected replicas to be replaced, got %v", merged["replicas"])
+	}
+	if _, ok := merged["debug"]; ok {
+		t.Errorf("expected debug to be removed")
+	}
+}
+
+// packageChart writes the chart in a directory to a .tgz archive the way `helm package` does
+func packageChart(t *testing.T, chartDir string) string {
+	f, err := ioutil.TempFile("", "chart-*.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(chartDir, path)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: filepath.ToSlash(filepath.Join(filepath.Base(chartDir), rel)), Mode: 0644, Size: int64(len(b))}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
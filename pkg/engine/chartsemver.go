@@ -0,0 +1,206 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+// chartSemverRegexp matches the versions of chart semver constraints, where the minor and patch versions are optional
+// and any of the major, minor and patch versions can be a wildcard
+var chartSemverRegexp = regexp.MustCompile(`^v?([0-9]+|[xX*])(?:\.([0-9]+|[xX*]))?(?:\.([0-9]+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// chartSemverOperators are the operators of chart semver constraints
+const chartSemverOperators = `=|!=|>=|=>|<=|=<|>|<|~>|~|\^`
+
+// chartSemverConstraintRegexp matches a single chart semver constraint, an operator followed by a version
+var chartSemverConstraintRegexp = regexp.MustCompile(`^(` + chartSemverOperators + `)?(\S+)$`)
+
+// chartSemverOperatorRegexp matches an operator separated from its version with spaces
+var chartSemverOperatorRegexp = regexp.MustCompile(`^(` + chartSemverOperators + `)$`)
+
+// chartSemverHyphenRegexp matches the hyphen ranges of chart semver constraints, e.g. "1.14 - 1.16"
+var chartSemverHyphenRegexp = regexp.MustCompile(`(\S+)\s+-\s+(\S+)`)
+
+// chartSemver is a version of a chart semver constraint. Wildcard or missing minor and patch versions are zero in
+// version, and parts is the number of leading major, minor and patch versions which are neither.
+type chartSemver struct {
+	version semver.Version
+	parts   int
+}
+
+// next returns the smallest version greater than all the versions matching v, e.g. 1.15.0 for 1.14.x
+func (v chartSemver) next() semver.Version {
+	switch v.parts {
+	case 0:
+		return semver.Version{Major: ^uint64(0)}
+	case 1:
+		return semver.Version{Major: v.version.Major + 1}
+	case 2:
+		return semver.Version{Major: v.version.Major, Minor: v.version.Minor + 1}
+	}
+	return semver.Version{Major: v.version.Major, Minor: v.version.Minor, Patch: v.version.Patch + 1}
+}
+
+// parseChartSemver parses a version of a chart semver constraint, e.g. "1.14", "1.14.x" or "1.14-0"
+func parseChartSemver(s string) (chartSemver, error) {
+	m := chartSemverRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return chartSemver{}, errors.Errorf("invalid semantic version %q", s)
+	}
+	var v chartSemver
+	numbers := []*uint64{&v.version.Major, &v.version.Minor, &v.version.Patch}
+	for i, part := range m[1:4] {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			break
+		}
+		*numbers[i] = n
+		v.parts++
+	}
+	if m[4] != "" {
+		for _, id := range strings.Split(m[4], ".") {
+			pre, err := semver.NewPRVersion(id)
+			if err != nil {
+				return chartSemver{}, errors.Wrapf(err, "invalid semantic version %q", s)
+			}
+			v.version.Pre = append(v.version.Pre, pre)
+		}
+	}
+	return v, nil
+}
+
+// parseChartVersion parses the version compared by semverCompare, which may omit its minor and patch versions but
+// cannot have wildcards
+func parseChartVersion(s string) (semver.Version, error) {
+	s = strings.TrimSpace(s)
+	if m := chartSemverRegexp.FindStringSubmatch(s); m != nil {
+		for _, part := range m[1:4] {
+			if part == "x" || part == "X" || part == "*" {
+				return semver.Version{}, errors.Errorf("invalid semantic version %q", s)
+			}
+		}
+	}
+	v, err := parseChartSemver(s)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return v.version, nil
+}
+
+// parseChartSemverConstraint parses a constraint of the semverCompare function of sprig, which uses the syntax of
+// github.com/Masterminds/semver rather than the one of semver.ParseRange: the minor and patch versions are optional,
+// "x", "X" and "*" are wildcards, "~" and "^" select the patch and minor releases of a version, "1.14 - 1.16" is a
+// hyphen range, and constraints are separated with spaces or commas and alternatives with "||". As in that library, a
+// prerelease version only matches comparisons with a prerelease version, so ">=1.14-0" matches 1.16.0-beta.1 whereas
+// ">=1.14" does not.
+func parseChartSemverConstraint(constraint string) (func(semver.Version) bool, error) {
+	var alternatives [][]func(semver.Version) bool
+	for _, alternative := range strings.Split(constraint, "||") {
+		alternative = chartSemverHyphenRegexp.ReplaceAllString(alternative, ">=$1 <=$2")
+		alternative = strings.Replace(alternative, ",", " ", -1)
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, errors.Errorf("invalid semantic version constraint %q", constraint)
+		}
+		var ands []func(semver.Version) bool
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if chartSemverOperatorRegexp.MatchString(field) && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+			f, err := parseChartSemverComparison(field)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid semantic version constraint %q", constraint)
+			}
+			ands = append(ands, f)
+		}
+		alternatives = append(alternatives, ands)
+	}
+	return func(v semver.Version) bool {
+		for _, ands := range alternatives {
+			matches := true
+			for _, f := range ands {
+				if !f(v) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// parseChartSemverComparison parses a single comparison of a chart semver constraint, e.g. ">=1.14-0"
+func parseChartSemverComparison(s string) (func(semver.Version) bool, error) {
+	m := chartSemverConstraintRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, errors.Errorf("invalid comparison %q", s)
+	}
+	c, err := parseChartSemver(m[2])
+	if err != nil {
+		return nil, err
+	}
+	base, next := c.version, c.next()
+	var matches func(semver.Version) bool
+	switch m[1] {
+	case "", "=":
+		if c.parts == 3 {
+			matches = base.Equals
+		} else {
+			matches = func(v semver.Version) bool { return v.GE(base) && v.LT(next) }
+		}
+	case "!=":
+		if c.parts == 3 {
+			return base.NE, nil
+		}
+		matches = func(v semver.Version) bool { return v.LT(base) || v.GE(next) }
+	case ">":
+		if c.parts == 3 {
+			matches = base.LT
+		} else {
+			matches = next.LE
+		}
+	case ">=", "=>":
+		matches = base.LE
+	case "<":
+		matches = base.GT
+	case "<=", "=<":
+		if c.parts == 3 {
+			matches = base.GE
+		} else {
+			matches = next.GT
+		}
+	case "~", "~>":
+		upper := semver.Version{Major: base.Major, Minor: base.Minor + 1}
+		if c.parts < 2 {
+			upper = semver.Version{Major: base.Major + 1}
+		}
+		matches = func(v semver.Version) bool { return v.GE(base) && v.LT(upper) }
+	case "^":
+		upper := semver.Version{Major: base.Major + 1}
+		if base.Major == 0 && c.parts >= 2 {
+			upper = semver.Version{Minor: base.Minor + 1}
+			if base.Minor == 0 && c.parts == 3 {
+				upper = semver.Version{Patch: base.Patch + 1}
+			}
+		}
+		matches = func(v semver.Version) bool { return v.GE(base) && v.LT(upper) }
+	}
+	return func(v semver.Version) bool {
+		if len(v.Pre) > 0 && len(base.Pre) == 0 {
+			return false
+		}
+		return matches(v)
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"testing"
+)
+
+func TestParseChartSemverConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.14-0", "v1.14.6", true},
+		{">=1.14-0", "1.16.0-beta.1", true},
+		{">=1.14-0", "1.13.12", false},
+		{">=1.14", "1.14.0", true},
+		{">=1.14", "1.16.0-beta.1", false},
+		{">= 1.14", "1.15.3", true},
+		{"<1.16", "1.15.12", true},
+		{"<1.16", "1.16.0", false},
+		{"<1.16-0", "1.16.0-alpha.1", false},
+		{"<=1.15", "1.15.12", true},
+		{"<=1.15.x", "1.16.0", false},
+		{">1.15", "1.15.12", false},
+		{">1.15", "1.16.0", true},
+		{">1.15.2", "1.15.3", true},
+		{"^1.14.0", "1.18.2", true},
+		{"^1.14.0", "2.0.0", false},
+		{"^1.14.0", "1.13.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"~1.14.0", "1.14.9", true},
+		{"~1.14.0", "1.15.0", false},
+		{"~1", "1.18.0", true},
+		{"1.14.x", "1.14.6", true},
+		{"1.14.x", "1.15.0", false},
+		{"=1.14", "1.14.6", true},
+		{"1.14.6", "1.14.6", true},
+		{"*", "1.14.6", true},
+		{"!=1.14.6", "1.14.6", false},
+		{"!=1.14.x", "1.15.0", true},
+		{">=1.10, <1.16", "1.14.6", true},
+		{">=1.10 <1.16", "1.16.1", false},
+		{"1.10 - 1.14", "1.14.6", true},
+		{"1.10 - 1.14.0", "1.14.6", false},
+		{"<1.10 || >=1.16", "1.17.7", true},
+		{"<1.10 || >=1.16", "1.14.6", false},
+	}
+	for _, c := range cases {
+		matches, err := parseChartSemverConstraint(c.constraint)
+		if err != nil {
+			t.Errorf("unexpected error parsing constraint %q: %s", c.constraint, err)
+			continue
+		}
+		v, err := parseChartVersion(c.version)
+		if err != nil {
+			t.Errorf("unexpected error parsing version %q: %s", c.version, err)
+			continue
+		}
+		if actual := matches(v); actual != c.expected {
+			t.Errorf("expected constraint %q to be %t for version %s, got %t", c.constraint, c.expected, c.version, actual)
+		}
+	}
+
+	for _, constraint := range []string{"", ">=", ">=1.a", "1.14 ||", "=>>1.14"} {
+		if _, err := parseChartSemverConstraint(constraint); err == nil {
+			t.Errorf("expected error parsing constraint %q", constraint)
+		}
+	}
+	for _, version := range []string{"", "1.14.x", "latest"} {
+		if _, err := parseChartVersion(version); err == nil {
+			t.Errorf("expected error parsing version %q", version)
+		}
+	}
+}
@@ -138,13 +138,14 @@ const (
 const (
 	kubeConfigJSON = "k8s/kubeconfig.json"
 	// Windows custom scripts
-	kubernetesWindowsAgentCustomDataPS1   = "k8s/kuberneteswindowssetup.ps1"
-	kubernetesWindowsAgentFunctionsPS1    = "k8s/kuberneteswindowsfunctions.ps1"
-	kubernetesWindowsConfigFunctionsPS1   = "k8s/windowsconfigfunc.ps1"
-	kubernetesWindowsKubeletFunctionsPS1  = "k8s/windowskubeletfunc.ps1"
-	kubernetesWindowsCniFunctionsPS1      = "k8s/windowscnifunc.ps1"
-	kubernetesWindowsAzureCniFunctionsPS1 = "k8s/windowsazurecnifunc.ps1"
-	kubernetesWindowsOpenSSHFunctionPS1   = "k8s/windowsinstallopensshfunc.ps1"
+	kubernetesWindowsAgentCustomDataPS1     = "k8s/kuberneteswindowssetup.ps1"
+	kubernetesWindowsAgentFunctionsPS1      = "k8s/kuberneteswindowsfunctions.ps1"
+	kubernetesWindowsConfigFunctionsPS1     = "k8s/windowsconfigfunc.ps1"
+	kubernetesWindowsKubeletFunctionsPS1    = "k8s/windowskubeletfunc.ps1"
+	kubernetesWindowsCniFunctionsPS1        = "k8s/windowscnifunc.ps1"
+	kubernetesWindowsAzureCniFunctionsPS1   = "k8s/windowsazurecnifunc.ps1"
+	kubernetesWindowsOpenSSHFunctionPS1     = "k8s/windowsinstallopensshfunc.ps1"
+	kubernetesWindowsContainerdFunctionsPS1 = "k8s/windowscontainerdfunc.ps1"
 )
 
 // cloud-init (i.e. ARM customData) file references
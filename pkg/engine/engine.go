@@ -61,7 +61,8 @@ func GenerateKubeConfig(properties *api.Properties, location string) (string, er
 	if properties.OrchestratorProfile != nil &&
 		properties.OrchestratorProfile.KubernetesConfig != nil &&
 		properties.OrchestratorProfile.KubernetesConfig.PrivateCluster != nil &&
-		to.Bool(properties.OrchestratorProfile.KubernetesConfig.PrivateCluster.Enabled) {
+		to.Bool(properties.OrchestratorProfile.KubernetesConfig.PrivateCluster.Enabled) &&
+		!properties.OrchestratorProfile.KubernetesConfig.PrivateDNSZoneProvision() {
 		if properties.MasterProfile.HasMultipleNodes() {
 			// more than 1 master, use the internal lb IP
 			firstMasterIP := net.ParseIP(properties.MasterProfile.FirstConsecutiveStaticIP).To4()
@@ -75,6 +76,7 @@ func GenerateKubeConfig(properties *api.Properties, location string) (string, er
 			kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVerbatim \"reference(concat('Microsoft.Network/publicIPAddresses/', variables('masterPublicIPAddressName'))).dnsSettings.fqdn\"}}", properties.MasterProfile.FirstConsecutiveStaticIP, -1)
 		}
 	} else {
+		// public clusters and private clusters with a private DNS zone share the same master FQDN
 		kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVerbatim \"reference(concat('Microsoft.Network/publicIPAddresses/', variables('masterPublicIPAddressName'))).dnsSettings.fqdn\"}}", api.FormatProdFQDNByLocation(properties.MasterProfile.DNSPrefix, location, properties.GetCustomCloudName()), -1)
 	}
 	kubeconfig = strings.Replace(kubeconfig, "{{WrapAsVariable \"resourceGroup\"}}", properties.MasterProfile.DNSPrefix, -1)
@@ -664,6 +666,15 @@ func getAddonFuncMap(addon api.KubernetesAddon) template.FuncMap {
 		"ContainerConfig": func(name string) string {
 			return addon.Config[name]
 		},
+		"ContainerConfigList": func(name string) []string {
+			var values []string
+			for _, value := range strings.Split(addon.Config[name], ",") {
+				if value != "" {
+					values = append(values, value)
+				}
+			}
+			return values
+		},
 	}
 }
 
@@ -682,30 +693,9 @@ func getContainerAddonsString(properties *api.Properties, sourcePath string) str
 	for _, addonName := range addonNames {
 		setting := settingsMap[addonName]
 		if setting.isEnabled {
-			var input string
-			if setting.base64Data != "" {
-				var err error
-				input, err = getStringFromBase64(setting.base64Data)
-				if err != nil {
-					return ""
-				}
-			} else {
-				orchProfile := properties.OrchestratorProfile
-				versions := strings.Split(orchProfile.OrchestratorVersion, ".")
-				addon := orchProfile.KubernetesConfig.GetAddonByName(addonName)
-				templ := template.New("addon resolver template").Funcs(getAddonFuncMap(addon))
-				addonFile := getCustomDataFilePath(setting.sourceFile, sourcePath, versions[0]+"."+versions[1])
-				addonFileBytes, err := Asset(addonFile)
-				if err != nil {
-					return ""
-				}
-				_, err = templ.Parse(string(addonFileBytes))
-				if err != nil {
-					return ""
-				}
-				var buffer bytes.Buffer
-				templ.Execute(&buffer, addon)
-				input = buffer.String()
+			input, err := getContainerAddonManifest(properties, addonName, setting, sourcePath)
+			if err != nil {
+				return ""
 			}
 			result += getAddonString(input, "/etc/kubernetes/addons", setting.destinationFile)
 		}
@@ -713,6 +703,40 @@ func getContainerAddonsString(properties *api.Properties, sourcePath string) str
 	return result
 }
 
+// getContainerAddonManifest returns the manifest of a container addon, either the user-provided data or its template
+// rendered with the containers and config of the addon
+func getContainerAddonManifest(properties *api.Properties, addonName string, setting kubernetesComponentFileSpec, sourcePath string) (string, error) {
+	if setting.base64Data != "" {
+		content, err := getStringFromBase64(setting.base64Data)
+		if err != nil {
+			return "", err
+		}
+		return applyOverlays(content, setting.overlays)
+	}
+	orchProfile := properties.OrchestratorProfile
+	versions := strings.Split(orchProfile.OrchestratorVersion, ".")
+	addon := orchProfile.KubernetesConfig.GetAddonByName(addonName)
+	templ := template.New("addon resolver template").Funcs(getAddonFuncMap(addon))
+	addonTemplate := setting.template
+	if addonTemplate == "" {
+		addonFile, err := getCustomDataFilePath(setting.sourceFile, sourcePath, versions[0]+"."+versions[1])
+		if err != nil {
+			return "", err
+		}
+		addonFileBytes, err := Asset(addonFile)
+		if err != nil {
+			return "", err
+		}
+		addonTemplate = string(addonFileBytes)
+	}
+	if _, err := templ.Parse(addonTemplate); err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	templ.Execute(&buffer, addon)
+	return applyOverlays(buffer.String(), setting.overlays)
+}
+
 func getDCOSMasterProvisionScript(orchProfile *api.OrchestratorProfile, bootstrapIP string) string {
 	scriptname := dcos2Provision
 	if orchProfile.DcosConfig == nil || orchProfile.DcosConfig.BootstrapProfile == nil {
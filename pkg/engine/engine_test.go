@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -445,6 +446,330 @@ func TestTemplateOutputPresence(t *testing.T) {
 	}
 }
 
+func TestGenerateTemplateV2IPv6DualStack(t *testing.T) {
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	i18n.Initialize(locale)
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	apiModel := `{
+  "apiVersion": "vlabs",
+  "properties": {
+    "featureFlags": {
+      "blockOutboundInternet": true
+    },
+    "orchestratorProfile": {
+      "orchestratorType": "Kubernetes",
+      "orchestratorVersion": "1.16.0-beta.1",
+      "kubernetesConfig": {
+        "ipFamilies": ["IPv4", "IPv6"],
+        "networkPlugin": "kubenet",
+        "serviceCidr": "10.0.0.0/16,fd00:1234::/108",
+        "dnsServiceIP": "10.0.0.10"
+      }
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "dualstack",
+      "vmSize": "Standard_D2_v2"
+    },
+    "agentPoolProfiles": [
+      {
+        "name": "agentpool1",
+        "count": 2,
+        "vmSize": "Standard_D2_v2",
+        "availabilityProfile": "%s"
+      }
+    ],
+    "linuxProfile": {
+      "adminUsername": "azureuser",
+      "ssh": {
+        "publicKeys": [
+          {
+            "keyData": "ssh-rsa PUBLICKEY azureuser@linuxvm"
+          }
+        ]
+      }
+    },
+    "servicePrincipalProfile": {
+      "clientId": "ServicePrincipalClientID",
+      "secret": "myServicePrincipalClientSecret"
+    }
+  }
+}`
+	for _, availabilityProfile := range []string{api.AvailabilitySet, api.VirtualMachineScaleSets} {
+		availabilityProfile := availabilityProfile
+		t.Run(availabilityProfile, func(t *testing.T) {
+			containerService, err := apiloader.LoadContainerService([]byte(fmt.Sprintf(apiModel, availabilityProfile)), vlabs.APIVersion, true, false, nil)
+			if err != nil {
+				t.Fatalf("Failed to load container service: %v", err)
+			}
+			containerService.Location = "westus2"
+			if _, err = containerService.SetPropertiesDefaults(false, false); err != nil {
+				t.Fatalf("Failed to set defaults: %v", err)
+			}
+
+			templateGenerator, err := InitializeTemplateGenerator(Context{
+				Translator: &i18n.Translator{
+					Locale: locale,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to initialize template generator: %v", err)
+			}
+			armTemplate, parameters, err := templateGenerator.GenerateTemplateV2(containerService, DefaultGeneratorCode, TestAKSEngineVersion)
+			if err != nil {
+				t.Fatalf("Failed to generate arm template: %v", err)
+			}
+
+			var template struct {
+				Resources []map[string]interface{} `json:"resources"`
+			}
+			if err = json.Unmarshal([]byte(armTemplate), &template); err != nil {
+				t.Fatalf("couldn't unmarshall ARM template: %#v\n", err)
+			}
+			resources := func(resourceType string) []map[string]interface{} {
+				var found []map[string]interface{}
+				for _, r := range template.Resources {
+					if r["type"] == resourceType {
+						found = append(found, r)
+					}
+				}
+				if len(found) == 0 {
+					t.Fatalf("no resource of type %s in the ARM template", resourceType)
+				}
+				return found
+			}
+			// get follows a path of object keys and array indexes through the JSON of a resource
+			get := func(v interface{}, path ...interface{}) interface{} {
+				for _, p := range path {
+					switch k := p.(type) {
+					case string:
+						m, _ := v.(map[string]interface{})
+						v = m[k]
+					case int:
+						a, _ := v.([]interface{})
+						if k >= len(a) {
+							return nil
+						}
+						v = a[k]
+					}
+				}
+				return v
+			}
+			hasIPv6Config := func(ipConfigurations interface{}) bool {
+				configs, _ := ipConfigurations.([]interface{})
+				for i := range configs {
+					if get(configs, i, "properties", "privateIPAddressVersion") == "IPv6" {
+						return true
+					}
+				}
+				return false
+			}
+
+			vnet := resources("Microsoft.Network/virtualNetworks")[0]
+			expectedPrefixes := []interface{}{"[parameters('vnetCidr')]", "[parameters('vnetCidrIPv6')]"}
+			if prefixes := get(vnet, "properties", "addressSpace", "addressPrefixes"); !reflect.DeepEqual(prefixes, expectedPrefixes) {
+				t.Errorf("expected the VNET address prefixes %v, got %v", expectedPrefixes, prefixes)
+			}
+			subnet := get(vnet, "properties", "subnets", 0, "properties")
+			expectedPrefixes = []interface{}{"[parameters('masterSubnet')]", "[parameters('masterSubnetIPv6')]"}
+			if prefixes := get(subnet, "addressPrefixes"); !reflect.DeepEqual(prefixes, expectedPrefixes) {
+				t.Errorf("expected the subnet address prefixes %v, got %v", expectedPrefixes, prefixes)
+			}
+			// kubenet routes the IPv4 and IPv6 pod CIDRs of the nodes through the route table of the dual stack subnet
+			if routeTable := get(subnet, "routeTable", "id"); routeTable != "[variables('routeTableID')]" {
+				t.Errorf("expected the subnet to use the route table of the cluster, got %v", routeTable)
+			}
+			resources("Microsoft.Network/routeTables")
+
+			var vnetRule interface{}
+			for _, rule := range get(resources("Microsoft.Network/networkSecurityGroups")[0], "properties", "securityRules").([]interface{}) {
+				if get(rule, "name") == "allow_vnet" {
+					vnetRule = rule
+				}
+			}
+			if prefixes := get(vnetRule, "properties", "destinationAddressPrefixes"); !reflect.DeepEqual(prefixes, expectedPrefixes) {
+				t.Errorf("expected the allow_vnet security rule to allow %v, got %v", expectedPrefixes, prefixes)
+			}
+
+			var hasIPv6PublicIP bool
+			for _, ip := range resources("Microsoft.Network/publicIPAddresses") {
+				if get(ip, "properties", "publicIPAddressVersion") == "IPv6" {
+					hasIPv6PublicIP = true
+				}
+			}
+			if !hasIPv6PublicIP {
+				t.Error("expected an IPv6 public IP address for the cluster load balancer")
+			}
+			var hasIPv6BackendPool bool
+			for _, lb := range resources("Microsoft.Network/loadBalancers") {
+				pools, _ := get(lb, "properties", "backendAddressPools").([]interface{})
+				for i := range pools {
+					if get(pools, i, "name") == "[concat(parameters('masterEndpointDNSNamePrefix'), '-ipv6')]" {
+						hasIPv6BackendPool = true
+					}
+				}
+			}
+			if !hasIPv6BackendPool {
+				t.Error("expected a load balancer with an IPv6 backend address pool")
+			}
+
+			for _, nic := range resources("Microsoft.Network/networkInterfaces") {
+				if !hasIPv6Config(get(nic, "properties", "ipConfigurations")) {
+					t.Errorf("expected the network interface %v to have an IPv6 ip configuration", nic["name"])
+				}
+			}
+			if availabilityProfile == api.VirtualMachineScaleSets {
+				for _, vmss := range resources("Microsoft.Compute/virtualMachineScaleSets") {
+					if !hasIPv6Config(get(vmss, "properties", "virtualMachineProfile", "networkProfile", "networkInterfaceConfigurations", 0, "properties", "ipConfigurations")) {
+						t.Errorf("expected the scale set %v to have an IPv6 ip configuration", vmss["name"])
+					}
+				}
+			}
+
+			var params map[string]map[string]interface{}
+			if err = json.Unmarshal([]byte(parameters), &params); err != nil {
+				t.Fatalf("couldn't unmarshall ARM parameters: %#v\n", err)
+			}
+			if clusterCidr := params["kubeClusterCidr"]["value"]; clusterCidr != "10.244.0.0/16,fd00:101::/8" {
+				t.Errorf("expected the IPv4 and IPv6 cluster subnets, got %v", clusterCidr)
+			}
+		})
+	}
+}
+
+func TestGenerateTemplateV2UserDefinedRouting(t *testing.T) {
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	i18n.Initialize(locale)
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	apiModel := `{
+  "apiVersion": "vlabs",
+  "properties": {
+    "orchestratorProfile": {
+      "orchestratorType": "Kubernetes",
+      "orchestratorRelease": "1.15",
+      "kubernetesConfig": {
+        "networkPlugin": "azure",
+        "loadBalancerSku": "Standard",
+        "excludeMasterFromStandardLB": true,
+        "outboundType": "userDefinedRouting",
+        "outboundNextHopIPAddress": "10.42.0.4",
+        "packageMirrorURL": "https://mirror.example.com"
+      }
+    },
+    "masterProfile": {
+      "count": 1,
+      "dnsPrefix": "udr",
+      "vmSize": "Standard_D2_v2"
+    },
+    "agentPoolProfiles": [
+      {
+        "name": "agentpool1",
+        "count": 2,
+        "vmSize": "Standard_D2_v2",
+        "availabilityProfile": "%s"
+      }
+    ],
+    "linuxProfile": {
+      "adminUsername": "azureuser",
+      "ssh": {
+        "publicKeys": [
+          {
+            "keyData": "ssh-rsa PUBLICKEY azureuser@linuxvm"
+          }
+        ]
+      }
+    },
+    "servicePrincipalProfile": {
+      "clientId": "ServicePrincipalClientID",
+      "secret": "myServicePrincipalClientSecret"
+    }
+  }
+}`
+	for _, availabilityProfile := range []string{api.AvailabilitySet, api.VirtualMachineScaleSets} {
+		availabilityProfile := availabilityProfile
+		t.Run(availabilityProfile, func(t *testing.T) {
+			containerService, err := apiloader.LoadContainerService([]byte(fmt.Sprintf(apiModel, availabilityProfile)), "vlabs", true, false, nil)
+			if err != nil {
+				t.Fatalf("Failed to load container service: %v", err)
+			}
+			containerService.Location = "westus2"
+			if _, err = containerService.SetPropertiesDefaults(false, false); err != nil {
+				t.Fatalf("Failed to set defaults: %v", err)
+			}
+
+			templateGenerator, err := InitializeTemplateGenerator(Context{
+				Translator: &i18n.Translator{
+					Locale: locale,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to initialize template generator: %v", err)
+			}
+			armTemplate, parameters, err := templateGenerator.GenerateTemplateV2(containerService, DefaultGeneratorCode, TestAKSEngineVersion)
+			if err != nil {
+				t.Fatalf("Failed to generate arm template: %v", err)
+			}
+			if strings.Contains(armTemplate, "agentLbID") || strings.Contains(armTemplate, "agentPublicIPAddressName") {
+				t.Error("expected no agent load balancer and no agent public IP address with outboundType userDefinedRouting")
+			}
+
+			var template struct {
+				Resources []map[string]interface{} `json:"resources"`
+			}
+			if err = json.Unmarshal([]byte(armTemplate), &template); err != nil {
+				t.Fatalf("couldn't unmarshall ARM template: %#v\n", err)
+			}
+			var routeTables, vnets []map[string]interface{}
+			for _, r := range template.Resources {
+				switch r["type"] {
+				case "Microsoft.Network/routeTables":
+					routeTables = append(routeTables, r)
+				case "Microsoft.Network/virtualNetworks":
+					vnets = append(vnets, r)
+				case "Microsoft.Network/loadBalancers":
+					if properties, _ := r["properties"].(map[string]interface{}); properties["outboundRules"] != nil {
+						t.Errorf("expected no outbound rules, got %v in load balancer %v", properties["outboundRules"], r["name"])
+					}
+				}
+			}
+
+			if len(routeTables) != 1 {
+				t.Fatalf("expected a route table with Azure CNI and outboundType userDefinedRouting, got %d", len(routeTables))
+			}
+			routes, _ := json.Marshal(routeTables[0]["properties"])
+			expectedRoutes := `{"routes":[{"name":"default","properties":{"addressPrefix":"0.0.0.0/0","nextHopIpAddress":"10.42.0.4","nextHopType":"VirtualAppliance"}}]}`
+			if string(routes) != expectedRoutes {
+				t.Errorf("expected the route table properties %s, got %s", expectedRoutes, routes)
+			}
+			if len(vnets) != 1 {
+				t.Fatalf("expected a VNET, got %d", len(vnets))
+			}
+			vnet, _ := json.Marshal(vnets[0])
+			if !strings.Contains(string(vnet), `"routeTable":{"id":"[variables('routeTableID')]"}`) {
+				t.Errorf("expected the subnets to use the route table of the cluster, got %s", vnet)
+			}
+
+			var params map[string]map[string]interface{}
+			if err = json.Unmarshal([]byte(parameters), &params); err != nil {
+				t.Fatalf("couldn't unmarshall ARM parameters: %#v\n", err)
+			}
+			if mirror := params["packageMirrorURL"]["value"]; mirror != "https://mirror.example.com" {
+				t.Errorf("expected the packageMirrorURL parameter, got %v", mirror)
+			}
+		})
+	}
+}
+
 func TestIsNSeriesSKU(t *testing.T) {
 	// VMSize with GPU
 	validSkus := []string{
@@ -670,6 +995,24 @@ func TestGenerateKubeConfig(t *testing.T) {
 		t.Errorf("Failed to call GenerateKubeConfig with simple Kubernetes config from file: %v", testData)
 	}
 
+	kubeConfig, err = GenerateKubeConfig(containerService.Properties, "westus2")
+	if err != nil {
+		t.Errorf("Failed to call GenerateKubeConfig with simple Kubernetes config from file: %v", testData)
+	}
+	if !strings.Contains(kubeConfig, "https://10.239.255.249") {
+		t.Errorf("expected the kubeconfig of a private cluster to use the internal load balancer IP, got %s", kubeConfig)
+	}
+
+	containerService.Properties.OrchestratorProfile.KubernetesConfig.PrivateCluster.EnablePrivateDNSZone = to.BoolPtr(true)
+	kubeConfig, err = GenerateKubeConfig(containerService.Properties, "westus2")
+	if err != nil {
+		t.Errorf("Failed to call GenerateKubeConfig with simple Kubernetes config from file: %v", testData)
+	}
+	expectedFQDN := api.FormatProdFQDNByLocation(containerService.Properties.MasterProfile.DNSPrefix, "westus2", "")
+	if !strings.Contains(kubeConfig, "https://"+expectedFQDN) {
+		t.Errorf("expected the kubeconfig of a private cluster with a private DNS zone to use %s, got %s", expectedFQDN, kubeConfig)
+	}
+
 	containerService.Properties.AADProfile = &api.AADProfile{
 		ClientAppID: "fooClientAppID",
 		TenantID:    "fooTenantID",
@@ -0,0 +1,451 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/engine/transform"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/leonelquinteros/gotext"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of TestGoldenExamples")
+
+const (
+	goldenExamplesDir = "../../examples"
+	// golden files are suffixed _expected so IterateTestFilesDirectory does not load them as api models
+	goldenDir       = "./testdata/golden"
+	goldenClusterID = "12345678"
+	goldenMaxDiffs  = 50
+)
+
+// gzippedBase64Regex matches base64 encoded gzip streams, which start with the bytes 1f 8b 08
+var gzippedBase64Regex = regexp.MustCompile(`H4sI[A-Za-z0-9+/]+=*`)
+
+// TestGoldenExamples renders every cluster definition under examples/ and compares the normalized template and
+// parameters with the golden files under testdata/golden. Run with -update to regenerate the golden files:
+//
+//	go test ./pkg/engine -run TestGoldenExamples -update
+func TestGoldenExamples(t *testing.T) {
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	i18n.Initialize(locale)
+
+	// serve the repository so that extensions are read from extensions/
+	server := httptest.NewServer(http.FileServer(http.Dir(path.Join("..", ".."))))
+	defer server.Close()
+
+	apiModelFiles := []string{}
+	err := filepath.Walk(goldenExamplesDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(p, ".json") {
+			apiModelFiles = append(apiModelFiles, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, apiModelFile := range apiModelFiles {
+		apiModelFile := apiModelFile
+		name, err := filepath.Rel(goldenExamplesDir, apiModelFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name = strings.TrimSuffix(name, ".json")
+		t.Run(name, func(t *testing.T) {
+			template, parameters, ok := renderGoldenExample(t, locale, server.URL+"/", apiModelFile)
+			if !ok {
+				return
+			}
+			compareGoldenFile(t, filepath.Join(goldenDir, name, "azuredeploy_expected.json"), template)
+			compareGoldenFile(t, filepath.Join(goldenDir, name, "azuredeploy.parameters_expected.json"), parameters)
+		})
+	}
+}
+
+// goldenTerraformExamples are the cluster definitions under examples/ whose Terraform configuration is compared with
+// golden files, one with availability sets and one with VM scale sets
+var goldenTerraformExamples = []string{
+	"kubernetes",
+	"kubernetes-vmss/kubernetes",
+}
+
+// TestGoldenTerraform renders the Terraform configuration of representative cluster definitions and compares it with
+// the golden files under testdata/golden. Run with -update to regenerate the golden files:
+//
+//	go test ./pkg/engine -run TestGoldenTerraform -update
+func TestGoldenTerraform(t *testing.T) {
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	i18n.Initialize(locale)
+
+	for _, name := range goldenTerraformExamples {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			apiModelFile := filepath.Join(goldenExamplesDir, name+".json")
+			cs, ok := loadGoldenExample(t, locale, DefaultExtensionsRootURL, apiModelFile)
+			if !ok {
+				t.Fatalf("%s cannot be rendered offline", apiModelFile)
+			}
+			templateGenerator, err := InitializeTemplateGenerator(Context{
+				Translator: &i18n.Translator{
+					Locale: locale,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, _, err := templateGenerator.GenerateTerraformConfig(cs, DefaultGeneratorCode, TestAKSEngineVersion)
+			if err != nil {
+				t.Fatalf("generating Terraform configuration of %s: %s", apiModelFile, err)
+			}
+			normalizedConfig, err := normalizeGoldenJSON(config)
+			if err != nil {
+				t.Fatalf("normalizing Terraform configuration of %s: %s", apiModelFile, err)
+			}
+			compareGoldenFile(t, filepath.Join(goldenDir, name, "azuredeploy_expected.tf.json"), normalizedConfig)
+		})
+	}
+}
+
+// renderGoldenExample generates the normalized template and parameters of a cluster definition with all randomness
+// replaced by fixed values, and extensions served from extensionsRootURL. It returns false for files which are not
+// cluster definitions or cannot be rendered offline.
+func renderGoldenExample(t *testing.T, locale *gotext.Locale, extensionsRootURL, apiModelFile string) ([]byte, []byte, bool) {
+	t.Helper()
+	cs, ok := loadGoldenExample(t, locale, extensionsRootURL, apiModelFile)
+	if !ok {
+		return nil, nil, false
+	}
+
+	templateGenerator, err := InitializeTemplateGenerator(Context{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template, parameters string
+	if cs.Properties.OrchestratorProfile.IsKubernetes() {
+		template, parameters, err = templateGenerator.GenerateTemplateV2(cs, DefaultGeneratorCode, TestAKSEngineVersion)
+	} else {
+		template, parameters, err = templateGenerator.GenerateTemplate(cs, DefaultGeneratorCode, TestAKSEngineVersion)
+	}
+	if err != nil {
+		t.Fatalf("generating template of %s: %s", apiModelFile, err)
+	}
+
+	template = strings.Replace(template, extensionsRootURL, DefaultExtensionsRootURL, -1)
+
+	normalizedTemplate, err := normalizeGoldenJSON(template)
+	if err != nil {
+		t.Fatalf("normalizing template of %s: %s", apiModelFile, err)
+	}
+	normalizedParameters, err := normalizeGoldenJSON(parameters)
+	if err != nil {
+		t.Fatalf("normalizing parameters of %s: %s", apiModelFile, err)
+	}
+	return normalizedTemplate, normalizedParameters, true
+}
+
+// loadGoldenExample loads a cluster definition and sets its defaults, with all randomness replaced by fixed values
+// and extensions served from extensionsRootURL. It returns false for files which are not cluster definitions or
+// cannot be rendered offline.
+func loadGoldenExample(t *testing.T, locale *gotext.Locale, extensionsRootURL, apiModelFile string) (*api.ContainerService, bool) {
+	t.Helper()
+	b, err := ioutil.ReadFile(apiModelFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var probe struct {
+		APIVersion string `json:"apiVersion"`
+		Properties *struct {
+			OrchestratorProfile *json.RawMessage `json:"orchestratorProfile"`
+		} `json:"properties"`
+	}
+	if err = json.Unmarshal(b, &probe); err != nil || probe.APIVersion == "" || probe.Properties == nil || probe.Properties.OrchestratorProfile == nil {
+		t.Skipf("%s is not a cluster definition", apiModelFile)
+		return nil, false
+	}
+
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	cs, _, err := apiloader.LoadContainerServiceFromFile(apiModelFile, false, false, nil)
+	if err != nil {
+		t.Fatalf("loading %s: %s", apiModelFile, err)
+	}
+	if cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.CustomFiles != nil {
+		for _, customFile := range *cs.Properties.MasterProfile.CustomFiles {
+			if _, err = os.Stat(customFile.Source); err != nil {
+				t.Skipf("%s references custom file %s which is not part of the repository", apiModelFile, customFile.Source)
+				return nil, false
+			}
+		}
+	}
+	if cs.Properties.IsAzureStackCloud() {
+		t.Skipf("%s targets Azure Stack, whose endpoints are retrieved from the stamp when setting defaults", apiModelFile)
+		return nil, false
+	}
+	// extensions are downloaded when generating the template, serve them from the repository instead
+	for _, extension := range cs.Properties.ExtensionProfiles {
+		if extension.RootURL != "" && extension.RootURL != DefaultExtensionsRootURL {
+			t.Skipf("%s downloads extension %s from %s", apiModelFile, extension.Name, extension.RootURL)
+			return nil, false
+		}
+		extension.RootURL = extensionsRootURL
+	}
+	setGoldenProperties(cs)
+
+	if _, err = cs.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("setting defaults of %s: %s", apiModelFile, err)
+	}
+	if cs.Properties.OrchestratorProfile.KubernetesConfig != nil && cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey != "" {
+		cs.Properties.OrchestratorProfile.KubernetesConfig.EtcdEncryptionKey = "etcdEncryptionKey"
+	}
+
+	return cs, true
+}
+
+// setGoldenProperties fills in the values examples leave to the user, and fixes the cluster ID and certificates
+// so that they are not generated.
+func setGoldenProperties(cs *api.ContainerService) {
+	if cs.Location == "" {
+		cs.Location = "westus2"
+	}
+	properties := cs.Properties
+	properties.ClusterID = goldenClusterID
+	if properties.MasterProfile != nil && properties.MasterProfile.DNSPrefix == "" {
+		properties.MasterProfile.DNSPrefix = "golden"
+	}
+	if properties.HostedMasterProfile != nil && properties.HostedMasterProfile.DNSPrefix == "" {
+		properties.HostedMasterProfile.DNSPrefix = "golden"
+	}
+	if properties.CustomCloudProfile != nil && properties.CustomCloudProfile.PortalURL == "" {
+		properties.CustomCloudProfile.PortalURL = fmt.Sprintf("https://portal.%s.azurestack.external/", cs.Location)
+	}
+	if properties.LinuxProfile != nil {
+		if len(properties.LinuxProfile.SSH.PublicKeys) == 0 {
+			properties.LinuxProfile.SSH.PublicKeys = []api.PublicKey{{}}
+		}
+		for i := range properties.LinuxProfile.SSH.PublicKeys {
+			if properties.LinuxProfile.SSH.PublicKeys[i].KeyData == "" {
+				properties.LinuxProfile.SSH.PublicKeys[i].KeyData = "ssh-rsa AAAAB3NO8b9== azureuser@cluster.local"
+			}
+		}
+	}
+	if properties.WindowsProfile != nil && properties.WindowsProfile.AdminPassword == "" {
+		properties.WindowsProfile.AdminPassword = "adminPassword"
+	}
+	if properties.ServicePrincipalProfile != nil {
+		if properties.ServicePrincipalProfile.ClientID == "" {
+			properties.ServicePrincipalProfile.ClientID = "00000000-0000-0000-0000-000000000000"
+		}
+		if properties.ServicePrincipalProfile.Secret == "" && properties.ServicePrincipalProfile.KeyvaultSecretRef == nil {
+			properties.ServicePrincipalProfile.Secret = "servicePrincipalSecret"
+		}
+	}
+
+	if properties.OrchestratorProfile.IsKubernetes() {
+		properties.CertificateProfile = &api.CertificateProfile{}
+		addTestCertificateProfile(properties.CertificateProfile)
+		masterCount := 1
+		if properties.MasterProfile != nil && properties.MasterProfile.Count > 0 {
+			masterCount = properties.MasterProfile.Count
+		}
+		properties.CertificateProfile.EtcdPeerCertificates = []string{}
+		properties.CertificateProfile.EtcdPeerPrivateKeys = []string{}
+		for i := 0; i < masterCount; i++ {
+			properties.CertificateProfile.EtcdPeerCertificates = append(properties.CertificateProfile.EtcdPeerCertificates, fmt.Sprintf("etcdPeerCertificate%d", i))
+			properties.CertificateProfile.EtcdPeerPrivateKeys = append(properties.CertificateProfile.EtcdPeerPrivateKeys, fmt.Sprintf("etcdPeerPrivateKey%d", i))
+		}
+	}
+}
+
+// normalizeGoldenJSON pretty prints an ARM template, parameters document or Terraform configuration with sorted keys.
+// Gzipped payloads are replaced by the digest of their decompressed content, which unlike the compressed bytes does
+// not depend on the Go version.
+func normalizeGoldenJSON(content string) ([]byte, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return nil, err
+	}
+	data = normalizeGoldenValue(data)
+	b, err := helpers.JSONMarshalIndent(data, "", "  ", false)
+	if err != nil {
+		return nil, err
+	}
+	pretty, err := transform.PrettyPrintJSON(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(pretty + "\n"), nil
+}
+
+func normalizeGoldenValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, e := range value {
+			value[k] = normalizeGoldenValue(e)
+		}
+	case []interface{}:
+		for i, e := range value {
+			value[i] = normalizeGoldenValue(e)
+		}
+	case string:
+		return gzippedBase64Regex.ReplaceAllStringFunc(value, func(s string) string {
+			compressed, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return s
+			}
+			r, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return s
+			}
+			content, err := ioutil.ReadAll(r)
+			if err != nil {
+				return s
+			}
+			return fmt.Sprintf("<gzip sha256:%x>", sha256.Sum256(content))
+		})
+	}
+	return v
+}
+
+func compareGoldenFile(t *testing.T, goldenFile string, actual []byte) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(goldenFile, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("reading golden file %s, run with -update to create it: %s", goldenFile, err)
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+	var expectedData, actualData interface{}
+	if err = json.Unmarshal(expected, &expectedData); err != nil {
+		t.Fatalf("parsing golden file %s: %s", goldenFile, err)
+	}
+	if err = json.Unmarshal(actual, &actualData); err != nil {
+		t.Fatal(err)
+	}
+	diffs := []string{}
+	diffGoldenJSON("$", expectedData, actualData, &diffs)
+	if len(diffs) == 0 {
+		diffs = append(diffs, "formatting differs")
+	}
+	if len(diffs) > goldenMaxDiffs {
+		diffs = append(diffs[:goldenMaxDiffs], fmt.Sprintf("... and %d more", len(diffs)-goldenMaxDiffs))
+	}
+	t.Errorf("generated output differs from %s, run with -update if the change is intended:\n%s", goldenFile, strings.Join(diffs, "\n"))
+}
+
+// diffGoldenJSON appends to diffs the paths at which expected and actual differ. Array elements which are objects
+// with a name, such as ARM resources, are labeled with it.
+func diffGoldenJSON(p string, expected, actual interface{}, diffs *[]string) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := []string{}
+		for k := range e {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := e[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ev, eok := e[k]
+			av, aok := a[k]
+			switch {
+			case !eok:
+				*diffs = append(*diffs, fmt.Sprintf("+ %s.%s: %s", p, k, goldenSummary(av)))
+			case !aok:
+				*diffs = append(*diffs, fmt.Sprintf("- %s.%s: %s", p, k, goldenSummary(ev)))
+			default:
+				diffGoldenJSON(p+"."+k, ev, av, diffs)
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(e) || i < len(a); i++ {
+			switch {
+			case i >= len(a):
+				*diffs = append(*diffs, fmt.Sprintf("- %s: %s", goldenIndexPath(p, i, e[i]), goldenSummary(e[i])))
+			case i >= len(e):
+				*diffs = append(*diffs, fmt.Sprintf("+ %s: %s", goldenIndexPath(p, i, a[i]), goldenSummary(a[i])))
+			default:
+				diffGoldenJSON(goldenIndexPath(p, i, e[i]), e[i], a[i], diffs)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		*diffs = append(*diffs, fmt.Sprintf("~ %s: %s => %s", p, goldenSummary(expected), goldenSummary(actual)))
+	}
+}
+
+func goldenIndexPath(p string, i int, v interface{}) string {
+	if o, ok := v.(map[string]interface{}); ok {
+		if name, ok := o["name"].(string); ok {
+			return fmt.Sprintf("%s[%d name=%s]", p, i, name)
+		}
+	}
+	return fmt.Sprintf("%s[%d]", p, i)
+}
+
+func goldenSummary(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	const maxLength = 200
+	if len(b) > maxLength {
+		return string(b[:maxLength]) + "..."
+	}
+	return string(b)
+}
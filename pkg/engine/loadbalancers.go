@@ -239,6 +239,10 @@ func CreateLoadBalancer(prop *api.Properties, isVMSS bool) LoadBalancerARM {
 }
 
 func createOutboundRules(prop *api.Properties) *[]network.OutboundRule {
+	if prop.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() {
+		// the egress of the agents goes through the default route of the route table
+		return nil
+	}
 	currentVersion, _ := semver.Make(prop.OrchestratorProfile.OrchestratorVersion)
 	min13Version, _ := semver.Make("1.13.7")
 	min14Version, _ := semver.Make("1.14.3")
@@ -328,7 +332,9 @@ func CreateAgentLoadBalancer(prop *api.Properties, isVMSS bool) LoadBalancerARM
 func CreateMasterInternalLoadBalancer(cs *api.ContainerService) LoadBalancerARM {
 	var dependencies []string
 	if cs.Properties.MasterProfile.IsCustomVNET() {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
@@ -723,4 +723,10 @@ func TestCreateAgentLoadBalancer(t *testing.T) {
 		t.Errorf("unexpected error while comparing load balancers: %s", diff)
 	}
 
+	// the agents have no outbound rules with user-defined routing
+	cs.Properties.OrchestratorProfile.KubernetesConfig.OutboundType = api.OutboundTypeUserDefinedRouting
+	actual = CreateAgentLoadBalancer(cs.Properties, false)
+	if actual.LoadBalancer.OutboundRules != nil {
+		t.Errorf("expected no outbound rules with outboundType %s, got %v", api.OutboundTypeUserDefinedRouting, *actual.LoadBalancer.OutboundRules)
+	}
 }
@@ -32,11 +32,13 @@ func createKubernetesMasterResourcesVMAS(cs *api.ContainerService) []interface{}
 		masterResources = append(masterResources, virtualNetwork)
 	}
 
-	masterNsg := CreateNetworkSecurityGroup(cs)
-	masterResources = append(masterResources, masterNsg)
+	if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+		masterNsg := CreateNetworkSecurityGroup(cs)
+		masterResources = append(masterResources, masterNsg)
+	}
 
-	if cs.Properties.OrchestratorProfile.RequireRouteTable() {
-		masterResources = append(masterResources, createRouteTable())
+	if cs.Properties.OrchestratorProfile.HasRouteTable() && cs.Properties.GetCustomRouteTableID() == "" {
+		masterResources = append(masterResources, createRouteTable(cs))
 	}
 
 	kubernetesConfig := cs.Properties.OrchestratorProfile.KubernetesConfig
@@ -52,6 +54,10 @@ func createKubernetesMasterResourcesVMAS(cs *api.ContainerService) []interface{}
 		masterNic := createPrivateClusterNetworkInterface(cs)
 		masterResources = append(masterResources, masterNic)
 
+		if kubernetesConfig.PrivateDNSZoneProvision() {
+			masterResources = append(masterResources, createPrivateDNSZoneResources(cs)...)
+		}
+
 		var provisionJumpbox bool
 
 		if kubernetesConfig != nil {
@@ -91,7 +97,7 @@ func createKubernetesMasterResourcesVMAS(cs *api.ContainerService) []interface{}
 		masterResources = append(masterResources, keyVaultStorageAccount, keyVault)
 	}
 
-	if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+	if cs.Properties.IsIPv6DualStack() {
 		clusterIPv4PublicIPAddress := CreateClusterPublicIPAddress()
 		clusterIPv6PublicIPAddress := CreateClusterPublicIPv6Address()
 		clusterLB := CreateClusterLoadBalancerForIPv6()
@@ -137,11 +143,13 @@ func createKubernetesMasterResourcesVMSS(cs *api.ContainerService) []interface{}
 		masterResources = append(masterResources, createCosmosDBAccount())
 	}
 
-	masterNSG := CreateNetworkSecurityGroup(cs)
-	masterResources = append(masterResources, masterNSG)
+	if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+		masterNSG := CreateNetworkSecurityGroup(cs)
+		masterResources = append(masterResources, masterNSG)
+	}
 
-	if cs.Properties.OrchestratorProfile.RequireRouteTable() {
-		masterResources = append(masterResources, createRouteTable())
+	if cs.Properties.OrchestratorProfile.HasRouteTable() && cs.Properties.GetCustomRouteTableID() == "" {
+		masterResources = append(masterResources, createRouteTable(cs))
 	}
 	if !cs.Properties.MasterProfile.IsCustomVNET() {
 		masterVNET := createVirtualNetworkVMSS(cs)
@@ -162,6 +170,10 @@ func createKubernetesMasterResourcesVMSS(cs *api.ContainerService) []interface{}
 
 	kubernetesConfig := cs.Properties.OrchestratorProfile.KubernetesConfig
 
+	if kubernetesConfig.PrivateDNSZoneProvision() {
+		masterResources = append(masterResources, createPrivateDNSZoneResources(cs)...)
+	}
+
 	var isKMSEnabled bool
 	if kubernetesConfig != nil {
 		isKMSEnabled = to.Bool(kubernetesConfig.EnableEncryptionWithExternalKms)
@@ -173,7 +185,7 @@ func createKubernetesMasterResourcesVMSS(cs *api.ContainerService) []interface{}
 		masterResources = append(masterResources, keyVaultStorageAccount, keyVault)
 	}
 
-	if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+	if cs.Properties.IsIPv6DualStack() {
 		clusterIPv4PublicIPAddress := CreateClusterPublicIPAddress()
 		clusterIPv6PublicIPAddress := CreateClusterPublicIPv6Address()
 		clusterLB := CreateClusterLoadBalancerForIPv6()
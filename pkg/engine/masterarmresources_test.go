@@ -5,6 +5,7 @@ package engine
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/Azure/aks-engine/pkg/api"
@@ -851,3 +852,75 @@ func TestCreateKubernetesMasterResourcesVMSS(t *testing.T) {
 		t.Errorf("unexpected error while comparing ARM resources: %s", diff)
 	}
 }
+
+func TestCreateKubernetesMasterResourcesWithCustomRouteTableAndNSG(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+	cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin = api.NetworkPluginKubenet
+	cs.Properties.MasterProfile.VnetSubnetID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME"
+	cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+	cs.Properties.AgentPoolProfiles[0].VnetSubnetID = cs.Properties.MasterProfile.VnetSubnetID
+
+	hasResource := func(resources []interface{}) (hasNSG, hasRouteTable bool) {
+		for _, resource := range resources {
+			switch resource.(type) {
+			case NetworkSecurityGroupARM:
+				hasNSG = true
+			case RouteTableARM:
+				hasRouteTable = true
+			}
+		}
+		return hasNSG, hasRouteTable
+	}
+
+	hasNSG, hasRouteTable := hasResource(createKubernetesMasterResourcesVMAS(cs))
+	if !hasNSG || !hasRouteTable {
+		t.Errorf("expected the network security group and the route table to be created, got %t and %t", hasNSG, hasRouteTable)
+	}
+
+	cs.Properties.MasterProfile.RouteTableID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/RT_NAME"
+	cs.Properties.MasterProfile.NetworkSecurityGroupID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME"
+
+	for _, resources := range [][]interface{}{createKubernetesMasterResourcesVMAS(cs), createKubernetesMasterResourcesVMSS(cs)} {
+		hasNSG, hasRouteTable = hasResource(resources)
+		if hasNSG || hasRouteTable {
+			t.Errorf("expected the network security group and the route table brought by the user not to be created, got %t and %t", hasNSG, hasRouteTable)
+		}
+	}
+
+	nic := CreateNetworkInterfaces(cs)
+	for _, dependency := range nic.DependsOn {
+		if dependency == "[variables('nsgID')]" {
+			t.Errorf("expected the master NIC not to depend on the network security group brought by the user")
+		}
+	}
+}
+
+func TestCreateKubernetesMasterResourcesWithPrivateDNSZone(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+	cs.Properties.MasterProfile.FirstConsecutiveStaticIP = "10.239.255.239"
+	cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateCluster = &api.PrivateCluster{
+		Enabled: to.BoolPtr(true),
+	}
+
+	countPrivateDNSZoneResources := func(resources []interface{}) int {
+		var count int
+		for _, resource := range resources {
+			if r, ok := resource.(map[string]interface{}); ok && strings.HasPrefix(r["type"].(string), "Microsoft.Network/privateDnsZones") {
+				count++
+			}
+		}
+		return count
+	}
+
+	if count := countPrivateDNSZoneResources(createKubernetesMasterResourcesVMAS(cs)); count != 0 {
+		t.Errorf("expected no private DNS zone resources, got %d", count)
+	}
+
+	cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateCluster.EnablePrivateDNSZone = to.BoolPtr(true)
+
+	for _, resources := range [][]interface{}{createKubernetesMasterResourcesVMAS(cs), createKubernetesMasterResourcesVMSS(cs)} {
+		if count := countPrivateDNSZoneResources(resources); count != 3 {
+			t.Errorf("expected the private DNS zone, its VNET link and its A record, got %d resources", count)
+		}
+	}
+}
@@ -15,7 +15,9 @@ func CreateNetworkInterfaces(cs *api.ContainerService) NetworkInterfaceARM {
 
 	var dependencies []string
 	if cs.Properties.MasterProfile != nil && cs.Properties.MasterProfile.IsCustomVNET() {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
@@ -92,7 +94,7 @@ func CreateNetworkInterfaces(cs *api.ContainerService) NetworkInterfaceARM {
 	}
 
 	// add ipv6 nic config for dual stack
-	if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+	if cs.Properties.IsIPv6DualStack() {
 		ipv6Config := network.InterfaceIPConfiguration{
 			Name: to.StringPtr("ipconfigv6"),
 			InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
@@ -138,7 +140,9 @@ func CreateNetworkInterfaces(cs *api.ContainerService) NetworkInterfaceARM {
 func createPrivateClusterNetworkInterface(cs *api.ContainerService) NetworkInterfaceARM {
 	var dependencies []string
 	if cs.Properties.MasterProfile.IsCustomVNET() {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
@@ -300,12 +304,15 @@ func createAgentVMASNetworkInterface(cs *api.ContainerService, profile *api.Agen
 	var dependencies []string
 
 	if isCustomVNet {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
 	if !cs.Properties.OrchestratorProfile.IsPrivateCluster() &&
 		profile.LoadBalancerBackendAddressPoolIDs == nil &&
+		!cs.Properties.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() &&
 		cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku == api.StandardLoadBalancerSku {
 		dependencies = append(dependencies, "[variables('agentLbID')]")
 	}
@@ -351,6 +358,7 @@ func createAgentVMASNetworkInterface(cs *api.ContainerService, profile *api.Agen
 				}
 			} else {
 				if !cs.Properties.OrchestratorProfile.IsPrivateCluster() &&
+					!cs.Properties.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() &&
 					cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku == api.StandardLoadBalancerSku {
 					agentLbBackendAddressPools := network.BackendAddressPool{
 						ID: to.StringPtr("[concat(variables('agentLbID'), '/backendAddressPools/', variables('agentLbBackendPoolName'))]"),
@@ -374,7 +382,7 @@ func createAgentVMASNetworkInterface(cs *api.ContainerService, profile *api.Agen
 			}
 		}
 
-		if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+		if cs.Properties.IsIPv6DualStack() {
 			var backendPools []network.BackendAddressPool
 			if ipConfig.LoadBalancerBackendAddressPools != nil {
 				backendPools = *ipConfig.LoadBalancerBackendAddressPools
@@ -388,7 +396,7 @@ func createAgentVMASNetworkInterface(cs *api.ContainerService, profile *api.Agen
 	}
 
 	// add ipv6 nic config for dual stack
-	if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+	if cs.Properties.IsIPv6DualStack() {
 		ipv6Config := network.InterfaceIPConfiguration{
 			Name: to.StringPtr("ipconfigv6"),
 			InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
@@ -89,6 +89,12 @@ func CreateNetworkSecurityGroup(cs *api.ContainerService) NetworkSecurityGroupAR
 			},
 		}
 
+		// the master subnet of ipv6 dual stack clusters also has an IPv6 address prefix
+		if cs.Properties.IsIPv6DualStack() {
+			vnetRule.DestinationAddressPrefix = nil
+			vnetRule.DestinationAddressPrefixes = &[]string{"[parameters('masterSubnet')]", "[parameters('masterSubnetIPv6')]"}
+		}
+
 		blockOutBoundRule := network.SecurityRule{
 			Name: to.StringPtr("block_outbound"),
 			SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
@@ -155,6 +155,23 @@ func TestCreateNetworkSecurityGroup(t *testing.T) {
 	if diff != "" {
 		t.Errorf("unexpected diff while comparing nsgs : %s", diff)
 	}
+
+	// Test with ipv6 dual stack, the vnet rule allows both address prefixes of the master subnet
+	cs.Properties.OrchestratorProfile.KubernetesConfig.IPFamilies = []string{api.IPFamilyIPv4, api.IPFamilyIPv6}
+
+	actual = CreateNetworkSecurityGroup(cs)
+
+	vnetRule.DestinationAddressPrefix = nil
+	vnetRule.DestinationAddressPrefixes = &[]string{"[parameters('masterSubnet')]", "[parameters('masterSubnetIPv6')]"}
+	dualStackRules := append([]network.SecurityRule{}, rules[:len(rules)-2]...)
+	dualStackRules = append(dualStackRules, vnetRule, blockOutBoundRule)
+	expected.SecurityRules = &dualStackRules
+
+	diff = cmp.Diff(actual, expected)
+
+	if diff != "" {
+		t.Errorf("unexpected diff while comparing nsgs : %s", diff)
+	}
 }
 
 func TestCreateJumpboxNSG(t *testing.T) {
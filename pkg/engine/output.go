@@ -133,3 +133,29 @@ func (w *ArtifactWriter) WriteTLSArtifacts(containerService *api.ContainerServic
 
 	return nil
 }
+
+// WriteTerraformArtifacts saves the Terraform configuration generated by GenerateTerraformConfig
+func (w *ArtifactWriter) WriteTerraformArtifacts(config, artifactsDir string) error {
+	f := &helpers.FileSaver{
+		Translator: w.Translator,
+	}
+	return f.SaveFileString(artifactsDir, "azuredeploy.tf.json", config)
+}
+
+// WriteLinkedTemplates saves the linked templates split from the main template by SplitTemplate to the
+// linkedtemplates directory of artifactsDir
+func (w *ArtifactWriter) WriteLinkedTemplates(linkedTemplates []LinkedTemplate, artifactsDir string) error {
+	f := &helpers.FileSaver{
+		Translator: w.Translator,
+	}
+	for i := range linkedTemplates {
+		b, err := linkedTemplates[i].MarshalTemplate()
+		if err != nil {
+			return err
+		}
+		if err := f.SaveFile(path.Join(artifactsDir, LinkedTemplatesDirectory), linkedTemplates[i].FileName, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -135,3 +135,46 @@ func TestWriteTLSArtifacts(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteTerraformArtifacts(t *testing.T) {
+	writer := &ArtifactWriter{
+		Translator: &i18n.Translator{
+			Locale: nil,
+		},
+	}
+	dir := "_testoutputdir"
+	defer os.RemoveAll(dir)
+
+	if err := writer.WriteTerraformArtifacts("{}", dir); err != nil {
+		t.Fatalf("unexpected error trying to write Terraform artifacts: %s", err.Error())
+	}
+	if _, err := os.Stat(dir + "/azuredeploy.tf.json"); os.IsNotExist(err) {
+		t.Fatalf("expected file %s/azuredeploy.tf.json to be generated by WriteTerraformArtifacts", dir)
+	}
+}
+
+func TestWriteLinkedTemplates(t *testing.T) {
+	writer := &ArtifactWriter{
+		Translator: &i18n.Translator{
+			Locale: nil,
+		},
+	}
+	dir := "_testoutputdir"
+	defer os.RemoveAll(dir)
+
+	linkedTemplates := []LinkedTemplate{
+		{
+			PoolName: "agentpool1",
+			FileName: "agentpool1.json",
+			Template: map[string]interface{}{
+				"resources": []interface{}{},
+			},
+		},
+	}
+	if err := writer.WriteLinkedTemplates(linkedTemplates, dir); err != nil {
+		t.Fatalf("unexpected error trying to write linked templates: %s", err.Error())
+	}
+	if _, err := os.Stat(dir + "/linkedtemplates/agentpool1.json"); os.IsNotExist(err) {
+		t.Fatalf("expected file %s/linkedtemplates/agentpool1.json to be generated by WriteLinkedTemplates", dir)
+	}
+}
@@ -0,0 +1,587 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	placeholderSentinelPrefix     = "__aksengine_placeholder_"
+	flowPlaceholderSentinelPrefix = "__aksengine_flowplaceholder_"
+	flowListSentinelPrefix        = "__aksengine_flowlist_"
+	sentinelSuffix                = "__"
+)
+
+var (
+	documentSeparatorRegexp = regexp.MustCompile(`(?m)^---[ \t]*$`)
+	// placeholders are substituted with sed on the nodes, see substituteAddonPlaceholders
+	placeholderRegexp         = regexp.MustCompile(`<([A-Za-z][A-Za-z0-9]*)>`)
+	flowPlaceholderRegexp     = regexp.MustCompile(`\[\s*<([A-Za-z][A-Za-z0-9]*)>\s*\]`)
+	placeholderSentinelRegexp = regexp.MustCompile(placeholderSentinelPrefix + `([A-Za-z][A-Za-z0-9]*)` + sentinelSuffix)
+	// a placeholder which is the whole scalar is quoted, so that its value is a string whatever sed substitutes
+	scalarPlaceholderSentinelRegexp = regexp.MustCompile(`(: |- )` + placeholderSentinelPrefix + `([A-Za-z][A-Za-z0-9]*)` + sentinelSuffix + `\n`)
+	flowListSentinelRegexp          = regexp.MustCompile(flowListSentinelPrefix + `([0-9]+)` + sentinelSuffix)
+
+	// blockPlaceholders are substituted with several lines of YAML at a fixed indentation, which a patched object
+	// doesn't keep
+	blockPlaceholders = []string{"<hostNet>", "<volMounts>", "<vols>", "<volumeMountssl>", "<volumessl>"}
+	// sedRangeMarkers are comments which sed uses to delete a range of lines, and which a patched object doesn't keep
+	sedRangeMarkers = []string{calicoInstallCNIStartMarker, calicoInstallCNIEndMarker}
+
+	// strategicMergeKeys are the keys that identify the elements of the lists which strategic merge patches merge
+	// rather than replace, by name of the list field, as in the patchMergeKey tags of the Kubernetes API types
+	strategicMergeKeys = map[string][]string{
+		"containers":          {"name"},
+		"initContainers":      {"name"},
+		"ephemeralContainers": {"name"},
+		"env":                 {"name"},
+		"volumes":             {"name"},
+		"volumeMounts":        {"mountPath"},
+		"volumeDevices":       {"devicePath"},
+		"imagePullSecrets":    {"name"},
+		"hostAliases":         {"ip"},
+		"ports":               {"containerPort", "port"},
+	}
+)
+
+// overlayDocument is a document of a multi-document YAML manifest
+type overlayDocument struct {
+	raw     string
+	object  map[string]interface{}
+	patched bool
+	// unpatchable is why the document can't be patched, if it can't be
+	unpatchable string
+}
+
+func (d *overlayDocument) matches(target api.KubernetesOverlayTarget) bool {
+	if d.object == nil {
+		return false
+	}
+	metadata, _ := d.object["metadata"].(map[string]interface{})
+	return d.object["kind"] == target.Kind &&
+		metadata != nil && metadata["name"] == target.Name &&
+		(target.Namespace == "" || metadata["namespace"] == target.Namespace)
+}
+
+// applyOverlays applies overlays to a manifest. The documents of the manifest which are patched are re-serialized,
+// the others are kept as is.
+func applyOverlays(content string, overlays []api.KubernetesOverlay) (string, error) {
+	if len(overlays) == 0 {
+		return content, nil
+	}
+	var documents []*overlayDocument
+	for _, raw := range documentSeparatorRegexp.Split(content, -1) {
+		document, err := parseOverlayDocument(raw)
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, document)
+	}
+
+	for i, overlay := range overlays {
+		if err := applyOverlay(documents, overlay); err != nil {
+			return "", errors.Wrapf(err, "applying overlay %d", i)
+		}
+	}
+
+	var result []string
+	for _, document := range documents {
+		if !document.patched {
+			result = append(result, document.raw)
+			continue
+		}
+		out, err := serializeOverlayDocument(document.object)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(document.raw, "\n") {
+			out = "\n" + out
+		}
+		result = append(result, out)
+	}
+	return strings.Join(result, "---"), nil
+}
+
+func parseOverlayDocument(raw string) (*overlayDocument, error) {
+	document := &overlayDocument{raw: raw}
+	text := raw
+	for _, placeholder := range blockPlaceholders {
+		if strings.Contains(text, placeholder) {
+			document.unpatchable = fmt.Sprintf("it has the placeholder %s which spans several lines", placeholder)
+			text = strings.Replace(text, placeholder, "", -1)
+		}
+	}
+	for _, marker := range sedRangeMarkers {
+		if strings.Contains(text, marker) {
+			document.unpatchable = fmt.Sprintf("it has the comment %q which the nodes use to edit it", marker)
+		}
+	}
+	text = flowPlaceholderRegexp.ReplaceAllString(text, "["+flowPlaceholderSentinelPrefix+"${1}"+sentinelSuffix+"]")
+	text = placeholderRegexp.ReplaceAllString(text, placeholderSentinelPrefix+"${1}"+sentinelSuffix)
+	if strings.TrimSpace(text) == "" {
+		return document, nil
+	}
+	if err := yaml.Unmarshal([]byte(text), &document.object); err != nil {
+		return nil, errors.Wrap(err, "parsing the manifest")
+	}
+	return document, nil
+}
+
+func applyOverlay(documents []*overlayDocument, overlay api.KubernetesOverlay) error {
+	var patch interface{}
+	if err := yaml.Unmarshal([]byte(overlay.Patch), &patch); err != nil {
+		return errors.Wrap(err, "parsing the patch")
+	}
+
+	var target api.KubernetesOverlayTarget
+	if overlay.Target != nil {
+		target = *overlay.Target
+	} else {
+		p, _ := patch.(map[string]interface{})
+		metadata, _ := p["metadata"].(map[string]interface{})
+		if metadata == nil {
+			return errors.New("a strategic merge patch without target must have a kind and a metadata.name")
+		}
+		target.Kind, _ = p["kind"].(string)
+		target.Name, _ = metadata["name"].(string)
+		target.Namespace, _ = metadata["namespace"].(string)
+	}
+
+	found := false
+	for _, document := range documents {
+		if !document.matches(target) {
+			continue
+		}
+		found = true
+		if document.unpatchable != "" {
+			return errors.Errorf("%s %s cannot be patched, %s", target.Kind, target.Name, document.unpatchable)
+		}
+		// the patch is copied since its values end up in the patched object
+		p, err := deepCopyJSON(patch)
+		if err != nil {
+			return err
+		}
+		var patched interface{}
+		switch overlay.Type {
+		case "", api.OverlayTypeStrategicMerge:
+			patched, err = strategicMerge(document.object, p, "")
+		case api.OverlayTypeJSON6902:
+			patched, err = applyJSONPatch(document.object, p)
+		default:
+			err = errors.Errorf("unknown overlay type %s", overlay.Type)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "patching %s %s", target.Kind, target.Name)
+		}
+		object, ok := patched.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("patching %s %s does not result in an object", target.Kind, target.Name)
+		}
+		document.object = object
+		document.patched = true
+	}
+	if !found {
+		return errors.Errorf("the manifest has no %s %s", target.Kind, target.Name)
+	}
+	return nil
+}
+
+// serializeOverlayDocument serializes a patched document, restoring the placeholders of its manifest
+func serializeOverlayDocument(object map[string]interface{}) (string, error) {
+	var flowLists []string
+	var replaceFlowLists func(v interface{}) (interface{}, error)
+	replaceFlowLists = func(v interface{}) (interface{}, error) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for key, value := range t {
+				r, err := replaceFlowLists(value)
+				if err != nil {
+					return nil, err
+				}
+				t[key] = r
+			}
+		case []interface{}:
+			hasFlowPlaceholder := false
+			var elements []string
+			for i, value := range t {
+				if s, ok := value.(string); ok && strings.HasPrefix(s, flowPlaceholderSentinelPrefix) {
+					hasFlowPlaceholder = true
+					elements = append(elements, "<"+strings.TrimSuffix(strings.TrimPrefix(s, flowPlaceholderSentinelPrefix), sentinelSuffix)+">")
+					continue
+				}
+				r, err := replaceFlowLists(value)
+				if err != nil {
+					return nil, err
+				}
+				t[i] = r
+				b, err := json.Marshal(r)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, string(b))
+			}
+			if hasFlowPlaceholder {
+				// sed substitutes a comma-separated list of elements, so the list must stay in flow style
+				flowLists = append(flowLists, "["+strings.Join(elements, ", ")+"]")
+				return fmt.Sprintf("%s%d%s", flowListSentinelPrefix, len(flowLists)-1, sentinelSuffix), nil
+			}
+		}
+		return v, nil
+	}
+	replaced, err := replaceFlowLists(object)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(replaced)
+	if err != nil {
+		return "", err
+	}
+	out := string(b)
+	out = flowListSentinelRegexp.ReplaceAllStringFunc(out, func(s string) string {
+		i, _ := strconv.Atoi(flowListSentinelRegexp.FindStringSubmatch(s)[1])
+		return flowLists[i]
+	})
+	out = scalarPlaceholderSentinelRegexp.ReplaceAllString(out, "${1}\"<${2}>\"\n")
+	out = placeholderSentinelRegexp.ReplaceAllString(out, "<${1}>")
+	return out, nil
+}
+
+// strategicMerge applies a strategic merge patch: objects are merged, null deletes a field, the lists of
+// strategicMergeKeys are merged by key, other lists are replaced. The directive $patch: replace replaces an object
+// rather than merge it and $patch: delete deletes an element of a merged list.
+func strategicMerge(original, patch interface{}, field string) (interface{}, error) {
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		o, ok := original.(map[string]interface{})
+		directive, _ := p["$patch"].(string)
+		delete(p, "$patch")
+		if !ok || directive == "replace" {
+			return p, nil
+		}
+		if directive != "" {
+			return nil, errors.Errorf("unsupported directive $patch: %s in %s", directive, field)
+		}
+		for key, value := range p {
+			if value == nil {
+				delete(o, key)
+				continue
+			}
+			merged, err := strategicMerge(o[key], value, key)
+			if err != nil {
+				return nil, err
+			}
+			o[key] = merged
+		}
+		return o, nil
+	case []interface{}:
+		o, ok := original.([]interface{})
+		mergeKey := getStrategicMergeKey(field, p)
+		if !ok || mergeKey == "" {
+			return p, nil
+		}
+		for _, element := range p {
+			e := element.(map[string]interface{})
+			index := -1
+			for i, originalElement := range o {
+				if oe, ok := originalElement.(map[string]interface{}); ok && reflect.DeepEqual(oe[mergeKey], e[mergeKey]) {
+					index = i
+					break
+				}
+			}
+			if directive, _ := e["$patch"].(string); directive == "delete" {
+				if index < 0 {
+					return nil, errors.Errorf("%s has no element with %s %v to delete", field, mergeKey, e[mergeKey])
+				}
+				o = append(o[:index], o[index+1:]...)
+				continue
+			}
+			if index < 0 {
+				delete(e, "$patch")
+				o = append(o, e)
+				continue
+			}
+			merged, err := strategicMerge(o[index], e, field)
+			if err != nil {
+				return nil, err
+			}
+			o[index] = merged
+		}
+		return o, nil
+	default:
+		return patch, nil
+	}
+}
+
+// getStrategicMergeKey returns the merge key of a list field if all the elements of the patch have it
+func getStrategicMergeKey(field string, elements []interface{}) string {
+	for _, key := range strategicMergeKeys[field] {
+		hasKey := true
+		for _, element := range elements {
+			e, ok := element.(map[string]interface{})
+			if !ok || e[key] == nil {
+				hasKey = false
+				break
+			}
+		}
+		if hasKey {
+			return key
+		}
+	}
+	return ""
+}
+
+// applyJSONPatch applies JSON patch (RFC 6902) operations
+func applyJSONPatch(document, patch interface{}) (interface{}, error) {
+	operations, ok := patch.([]interface{})
+	if !ok {
+		return nil, errors.New("a json6902 patch must be a list of operations")
+	}
+	for i, o := range operations {
+		operation, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("operation %d is not an object", i)
+		}
+		op, _ := operation["op"].(string)
+		path, _ := operation["path"].(string)
+		tokens, err := parseJSONPointer(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d", i)
+		}
+		switch op {
+		case "add", "replace":
+			value, ok := operation["value"]
+			if !ok {
+				return nil, errors.Errorf("operation %d has no value", i)
+			}
+			document, err = jsonPatchSet(document, tokens, value, op == "add")
+		case "remove":
+			document, _, err = jsonPatchRemove(document, tokens)
+		case "test":
+			var value interface{}
+			if value, err = jsonPatchGet(document, tokens); err == nil && !reflect.DeepEqual(value, operation["value"]) {
+				err = errors.Errorf("%s is %v, not %v", path, value, operation["value"])
+			}
+		case "move", "copy":
+			from, _ := operation["from"].(string)
+			var fromTokens []string
+			if fromTokens, err = parseJSONPointer(from); err != nil {
+				break
+			}
+			var value interface{}
+			if op == "move" {
+				document, value, err = jsonPatchRemove(document, fromTokens)
+			} else if value, err = jsonPatchGet(document, fromTokens); err == nil {
+				value, err = deepCopyJSON(value)
+			}
+			if err == nil {
+				document, err = jsonPatchSet(document, tokens, value, true)
+			}
+		default:
+			err = errors.Errorf("unsupported op %q", op)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d", i)
+		}
+	}
+	return document, nil
+}
+
+func parseJSONPointer(path string) ([]string, error) {
+	if path == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.Errorf("the path %q does not start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tokens[i], "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func jsonPatchIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !allowEnd) {
+		return 0, errors.Errorf("index %s is out of range", token)
+	}
+	return i, nil
+}
+
+func jsonPatchGet(document interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch d := document.(type) {
+		case map[string]interface{}:
+			value, ok := d[token]
+			if !ok {
+				return nil, errors.Errorf("%s does not exist", token)
+			}
+			document = value
+		case []interface{}:
+			i, err := jsonPatchIndex(token, len(d), false)
+			if err != nil {
+				return nil, err
+			}
+			document = d[i]
+		default:
+			return nil, errors.Errorf("%s does not exist", token)
+		}
+	}
+	return document, nil
+}
+
+// jsonPatchSet adds or replaces the value at a path and returns the patched document
+func jsonPatchSet(document interface{}, tokens []string, value interface{}, add bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token := tokens[0]
+	switch d := document.(type) {
+	case map[string]interface{}:
+		child, ok := d[token]
+		if len(tokens) == 1 {
+			if !ok && !add {
+				return nil, errors.Errorf("%s does not exist", token)
+			}
+			d[token] = value
+			return d, nil
+		}
+		if !ok {
+			return nil, errors.Errorf("%s does not exist", token)
+		}
+		patched, err := jsonPatchSet(child, tokens[1:], value, add)
+		if err != nil {
+			return nil, err
+		}
+		d[token] = patched
+		return d, nil
+	case []interface{}:
+		if len(tokens) == 1 && add {
+			i, err := jsonPatchIndex(token, len(d), true)
+			if err != nil {
+				return nil, err
+			}
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = value
+			return d, nil
+		}
+		i, err := jsonPatchIndex(token, len(d), false)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 1 {
+			d[i] = value
+			return d, nil
+		}
+		patched, err := jsonPatchSet(d[i], tokens[1:], value, add)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = patched
+		return d, nil
+	default:
+		return nil, errors.Errorf("%s does not exist", token)
+	}
+}
+
+// jsonPatchRemove removes the value at a path and returns the patched document and the removed value
+func jsonPatchRemove(document interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("the whole document cannot be removed")
+	}
+	token := tokens[0]
+	switch d := document.(type) {
+	case map[string]interface{}:
+		child, ok := d[token]
+		if !ok {
+			return nil, nil, errors.Errorf("%s does not exist", token)
+		}
+		if len(tokens) == 1 {
+			delete(d, token)
+			return d, child, nil
+		}
+		patched, removed, err := jsonPatchRemove(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		d[token] = patched
+		return d, removed, nil
+	case []interface{}:
+		i, err := jsonPatchIndex(token, len(d), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tokens) == 1 {
+			removed := d[i]
+			return append(d[:i], d[i+1:]...), removed, nil
+		}
+		patched, removed, err := jsonPatchRemove(d[i], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		d[i] = patched
+		return d, removed, nil
+	default:
+		return nil, nil, errors.Errorf("%s does not exist", token)
+	}
+}
+
+func deepCopyJSON(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var c interface{}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// validateOverlays applies the overlays of the manifests of a cluster, so that an overlay whose target doesn't exist
+// fails the generation of the template rather than the provisioning of the master nodes
+func validateOverlays(p *api.Properties) error {
+	if p.OrchestratorProfile == nil || !p.OrchestratorProfile.IsKubernetes() {
+		return nil
+	}
+	version := p.OrchestratorProfile.OrchestratorVersion
+	for _, setting := range kubernetesManifestSettingsInit(p) {
+		if setting.isEnabled && len(setting.overlays) > 0 {
+			if _, err := getComponentManifest(setting, "k8s/manifests", version); err != nil {
+				return errors.Wrapf(err, "applying the overlays of %s", setting.destinationFile)
+			}
+		}
+	}
+	addonSettings, err := kubernetesAddonSettingsInit(p)
+	if err != nil {
+		return err
+	}
+	for _, setting := range addonSettings {
+		if setting.isEnabled && len(setting.overlays) > 0 {
+			if _, err := getComponentManifest(setting, "k8s/addons", version); err != nil {
+				return errors.Wrapf(err, "applying the overlays of %s", setting.destinationFile)
+			}
+		}
+	}
+	for addonName, setting := range kubernetesContainerAddonSettingsInit(p) {
+		if setting.isEnabled && len(setting.overlays) > 0 {
+			if _, err := getContainerAddonManifest(p, addonName, setting, "k8s/containeraddons"); err != nil {
+				return errors.Wrapf(err, "applying the overlays of addon %s", addonName)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+const overlaysTestManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: example
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: example
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: example
+        image: <img>
+        args: [<args>]
+        env:
+        - name: CLOUD
+          value: "<cloud>"
+        volumeMounts:
+        - name: config
+          mountPath: /etc/example
+      volumes:
+      - name: config
+        hostPath:
+          path: /etc/example
+      - name: logs
+        hostPath:
+          path: /var/log/example
+`
+
+func TestApplyOverlays(t *testing.T) {
+	serviceAccount := strings.Split(overlaysTestManifest, "---")[0]
+	cases := []struct {
+		name          string
+		manifest      string
+		overlays      []api.KubernetesOverlay
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "no overlays",
+			manifest: overlaysTestManifest,
+			expected: overlaysTestManifest,
+		},
+		{
+			name:     "strategic merge patch",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{
+					Patch: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: example
+spec:
+  template:
+    spec:
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: example
+        resources:
+          limits:
+            memory: 100Mi
+        env:
+        - name: DEBUG
+          value: "true"
+      volumes:
+      - name: logs
+        $patch: delete
+`,
+				},
+			},
+			expected: serviceAccount + `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: example
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - args: [<args>]
+        env:
+        - name: CLOUD
+          value: "<cloud>"
+        - name: DEBUG
+          value: "true"
+        image: "<img>"
+        name: example
+        resources:
+          limits:
+            memory: 100Mi
+        volumeMounts:
+        - mountPath: /etc/example
+          name: config
+      tolerations:
+      - operator: Exists
+      volumes:
+      - hostPath:
+          path: /etc/example
+        name: config
+`,
+		},
+		{
+			name:     "json6902 patch",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{
+					Type:   api.OverlayTypeJSON6902,
+					Target: &api.KubernetesOverlayTarget{Kind: "Deployment", Name: "example", Namespace: "kube-system"},
+					Patch: `[
+  {"op": "add", "path": "/spec/template/spec/containers/0/args/-", "value": "--v=4"},
+  {"op": "test", "path": "/spec/template/spec/volumes/1/name", "value": "logs"},
+  {"op": "remove", "path": "/spec/template/spec/volumes/1"},
+  {"op": "replace", "path": "/spec/template/spec/containers/0/volumeMounts/0/mountPath", "value": "/etc/example/config"},
+  {"op": "copy", "from": "/metadata/namespace", "path": "/metadata/labels"},
+  {"op": "move", "from": "/metadata/labels", "path": "/metadata/annotations"}
+]`,
+				},
+			},
+			expected: serviceAccount + `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations: kube-system
+  name: example
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - args: [<args>, "--v=4"]
+        env:
+        - name: CLOUD
+          value: "<cloud>"
+        image: "<img>"
+        name: example
+        volumeMounts:
+        - mountPath: /etc/example/config
+          name: config
+      volumes:
+      - hostPath:
+          path: /etc/example
+        name: config
+`,
+		},
+		{
+			name:     "strategic merge patch with target",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{
+					Type:   api.OverlayTypeStrategicMerge,
+					Target: &api.KubernetesOverlayTarget{Kind: "ServiceAccount", Name: "example"},
+					Patch:  `{"metadata": {"labels": {"team": "platform"}}}`,
+				},
+			},
+			expected: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    team: platform
+  name: example
+  namespace: kube-system
+---` + strings.SplitN(overlaysTestManifest, "---", 2)[1],
+		},
+		{
+			name:     "missing object",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{Patch: "kind: Deployment\nmetadata:\n  name: other\n"},
+			},
+			expectedError: "applying overlay 0: the manifest has no Deployment other",
+		},
+		{
+			name:     "missing namespace",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{Patch: "kind: Deployment\nmetadata:\n  name: example\n  namespace: default\n"},
+			},
+			expectedError: "applying overlay 0: the manifest has no Deployment example",
+		},
+		{
+			name:     "missing path",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{
+					Type:   api.OverlayTypeJSON6902,
+					Target: &api.KubernetesOverlayTarget{Kind: "Deployment", Name: "example"},
+					Patch:  "- op: replace\n  path: /spec/replicas\n  value: 2\n",
+				},
+			},
+			expectedError: "applying overlay 0: patching Deployment example: operation 0: replicas does not exist",
+		},
+		{
+			name:     "failed test",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{
+					Type:   api.OverlayTypeJSON6902,
+					Target: &api.KubernetesOverlayTarget{Kind: "Deployment", Name: "example"},
+					Patch:  "- op: test\n  path: /metadata/name\n  value: other\n",
+				},
+			},
+			expectedError: "applying overlay 0: patching Deployment example: operation 0: /metadata/name is example, not other",
+		},
+		{
+			name:     "delete missing element",
+			manifest: overlaysTestManifest,
+			overlays: []api.KubernetesOverlay{
+				{Patch: "kind: Deployment\nmetadata:\n  name: example\nspec:\n  template:\n    spec:\n      volumes:\n      - name: other\n        $patch: delete\n"},
+			},
+			expectedError: "applying overlay 0: patching Deployment example: volumes has no element with name other to delete",
+		},
+		{
+			name:     "block placeholder",
+			manifest: "kind: Deployment\nmetadata:\n  name: example\nspec:\n  template:\n    spec:\n      <hostNet>\n      containers: []\n",
+			overlays: []api.KubernetesOverlay{
+				{Patch: "kind: Deployment\nmetadata:\n  name: example\n"},
+			},
+			expectedError: "applying overlay 0: Deployment example cannot be patched, it has the placeholder <hostNet> which spans several lines",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := applyOverlays(c.manifest, c.overlays)
+			if c.expectedError != "" {
+				if err == nil || err.Error() != c.expectedError {
+					t.Fatalf("expected error %q, got %v", c.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != c.expected {
+				t.Fatalf("expected manifest:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateOverlays(t *testing.T) {
+	cs := api.CreateMockContainerService("testcluster", "1.14.6", 3, 2, false)
+	if _, err := cs.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("unexpected error setting defaults: %s", err)
+	}
+	k := cs.Properties.OrchestratorProfile.KubernetesConfig
+	k.ManifestOverlays = map[string][]api.KubernetesOverlay{
+		"kube-apiserver": {
+			{
+				Type:   api.OverlayTypeJSON6902,
+				Target: &api.KubernetesOverlayTarget{Kind: "Pod", Name: "kube-apiserver"},
+				Patch:  "- op: add\n  path: /spec/containers/0/args/-\n  value: --v=4\n",
+			},
+		},
+	}
+	k.Addons = append(k.Addons, api.KubernetesAddon{
+		Name: KubeProxyAddonName,
+		Overlays: []api.KubernetesOverlay{
+			{Patch: "kind: DaemonSet\nmetadata:\n  name: kube-proxy\nspec:\n  template:\n    spec:\n      tolerations:\n      - operator: Exists\n"},
+		},
+	})
+	if err := validateOverlays(cs.Properties); err != nil {
+		t.Fatalf("unexpected error validating overlays: %s", err)
+	}
+
+	var apiserver string
+	for _, setting := range kubernetesManifestSettingsInit(cs.Properties) {
+		if setting.destinationFile == "kube-apiserver.yaml" {
+			var err error
+			if apiserver, err = getComponentManifest(setting, "k8s/manifests", cs.Properties.OrchestratorProfile.OrchestratorVersion); err != nil {
+				t.Fatalf("unexpected error getting the kube-apiserver manifest: %s", err)
+			}
+		}
+	}
+	if !strings.Contains(apiserver, `args: [<args>, "--v=4"]`) {
+		t.Fatalf("expected the kube-apiserver manifest to be patched, got:\n%s", apiserver)
+	}
+
+	k.ManifestOverlays["kube-scheduler"] = []api.KubernetesOverlay{
+		{Patch: "kind: Pod\nmetadata:\n  name: scheduler\n"},
+	}
+	err := validateOverlays(cs.Properties)
+	expectedError := "applying the overlays of kube-scheduler.yaml: applying overlay 0: the manifest has no Pod scheduler"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("expected error %q, got %v", expectedError, err)
+	}
+}
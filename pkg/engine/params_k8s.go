@@ -54,6 +54,9 @@ func assignKubernetesParameters(properties *api.Properties, parametersMap params
 			if kubernetesConfig.PrivateAzureRegistryServer != "" {
 				addValue(parametersMap, "privateAzureRegistryServer", kubernetesConfig.PrivateAzureRegistryServer)
 			}
+			if kubernetesConfig.PackageMirrorURL != "" {
+				addValue(parametersMap, "packageMirrorURL", kubernetesConfig.PackageMirrorURL)
+			}
 			addValue(parametersMap, "kubernetesAddonManagerSpec", kubernetesImageBase+k8sComponents["addonmanager"])
 			if orchestratorProfile.NeedsExecHealthz() {
 				addValue(parametersMap, "kubernetesExecHealthzSpec", kubernetesImageBase+k8sComponents["exechealthz"])
@@ -143,6 +146,13 @@ func assignKubernetesParameters(properties *api.Properties, parametersMap params
 				addValue(parametersMap, "kubeServiceCidr", kubernetesConfig.ServiceCIDR)
 				addValue(parametersMap, "kubeBinariesVersion", k8sVersion)
 				addValue(parametersMap, "windowsTelemetryGUID", cloudSpecConfig.KubernetesSpecConfig.WindowsTelemetryGUID)
+				if kubernetesConfig.ContainerRuntime == api.Containerd {
+					addValue(parametersMap, "windowsContainerdURL", properties.WindowsProfile.GetWindowsContainerdURL(cloudSpecConfig))
+				}
+				if properties.WindowsProfile.HasImageRef() {
+					addValue(parametersMap, "agentWindowsImageName", properties.WindowsProfile.ImageRef.Name)
+					addValue(parametersMap, "agentWindowsImageResourceGroup", properties.WindowsProfile.ImageRef.ResourceGroup)
+				}
 			}
 		}
 
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+// createPrivateDNSZone returns the Azure Private DNS zone which publishes the API server of a private cluster.
+// The zone is named after the FQDN of the master, which is one of the subject alternative names of the
+// API server certificate, so clients inside the VNET resolve it to the internal address of the masters.
+func createPrivateDNSZone() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "Microsoft.Network/privateDnsZones",
+		"name":       "[variables('privateDNSZoneName')]",
+		"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+		"location":   "global",
+		"properties": map[string]interface{}{},
+	}
+}
+
+// createPrivateDNSZoneVirtualNetworkLink returns the link which makes the private DNS zone resolvable from the VNET of the cluster.
+func createPrivateDNSZoneVirtualNetworkLink(cs *api.ContainerService) map[string]interface{} {
+	dependencies := []string{
+		"[concat('Microsoft.Network/privateDnsZones/', variables('privateDNSZoneName'))]",
+	}
+	if !cs.Properties.MasterProfile.IsCustomVNET() {
+		dependencies = append(dependencies, "[variables('vnetID')]")
+	}
+
+	return map[string]interface{}{
+		"type":       "Microsoft.Network/privateDnsZones/virtualNetworkLinks",
+		"name":       "[concat(variables('privateDNSZoneName'), '/', variables('virtualNetworkName'))]",
+		"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+		"location":   "global",
+		"dependsOn":  dependencies,
+		"properties": map[string]interface{}{
+			"registrationEnabled": false,
+			"virtualNetwork": map[string]interface{}{
+				"id": "[variables('privateDNSZoneVnetID')]",
+			},
+		},
+	}
+}
+
+// createPrivateDNSZoneARecord returns the A record of the private DNS zone, it points to the internal load balancer
+// of the masters, or to the master itself when there is a single master.
+func createPrivateDNSZoneARecord() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "Microsoft.Network/privateDnsZones/A",
+		"name":       "[concat(variables('privateDNSZoneName'), '/@')]",
+		"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+		"dependsOn": []string{
+			"[concat('Microsoft.Network/privateDnsZones/', variables('privateDNSZoneName'))]",
+		},
+		"properties": map[string]interface{}{
+			"ttl": 300,
+			"aRecords": []interface{}{
+				map[string]interface{}{
+					"ipv4Address": "[variables('kubernetesAPIServerIP')]",
+				},
+			},
+		},
+	}
+}
+
+// createPrivateDNSZoneResources returns the private DNS zone of a private cluster, its VNET link and its A record.
+func createPrivateDNSZoneResources(cs *api.ContainerService) []interface{} {
+	return []interface{}{
+		createPrivateDNSZone(),
+		createPrivateDNSZoneVirtualNetworkLink(cs),
+		createPrivateDNSZoneARecord(),
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func TestCreatePrivateDNSZoneResources(t *testing.T) {
+	cs := &api.ContainerService{
+		Properties: &api.Properties{
+			MasterProfile: &api.MasterProfile{
+				Count: 3,
+			},
+		},
+	}
+
+	actual := createPrivateDNSZoneResources(cs)
+
+	expected := []interface{}{
+		map[string]interface{}{
+			"type":       "Microsoft.Network/privateDnsZones",
+			"name":       "[variables('privateDNSZoneName')]",
+			"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+			"location":   "global",
+			"properties": map[string]interface{}{},
+		},
+		map[string]interface{}{
+			"type":       "Microsoft.Network/privateDnsZones/virtualNetworkLinks",
+			"name":       "[concat(variables('privateDNSZoneName'), '/', variables('virtualNetworkName'))]",
+			"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+			"location":   "global",
+			"dependsOn": []string{
+				"[concat('Microsoft.Network/privateDnsZones/', variables('privateDNSZoneName'))]",
+				"[variables('vnetID')]",
+			},
+			"properties": map[string]interface{}{
+				"registrationEnabled": false,
+				"virtualNetwork": map[string]interface{}{
+					"id": "[variables('privateDNSZoneVnetID')]",
+				},
+			},
+		},
+		map[string]interface{}{
+			"type":       "Microsoft.Network/privateDnsZones/A",
+			"name":       "[concat(variables('privateDNSZoneName'), '/@')]",
+			"apiVersion": "[variables('apiVersionPrivateDNSZones')]",
+			"dependsOn": []string{
+				"[concat('Microsoft.Network/privateDnsZones/', variables('privateDNSZoneName'))]",
+			},
+			"properties": map[string]interface{}{
+				"ttl": 300,
+				"aRecords": []interface{}{
+					map[string]interface{}{
+						"ipv4Address": "[variables('kubernetesAPIServerIP')]",
+					},
+				},
+			},
+		},
+	}
+
+	diff := cmp.Diff(actual, expected)
+	if diff != "" {
+		t.Errorf("unexpected diff while comparing: %s", diff)
+	}
+
+	// With a custom VNET the link does not depend on a VNET of the template
+	cs.Properties.MasterProfile.VnetSubnetID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/virtualNetworks/VNET_NAME/subnets/SUBNET_NAME"
+
+	link := createPrivateDNSZoneVirtualNetworkLink(cs)
+
+	expectedDependencies := []string{
+		"[concat('Microsoft.Network/privateDnsZones/', variables('privateDNSZoneName'))]",
+	}
+
+	diff = cmp.Diff(link["dependsOn"], expectedDependencies)
+	if diff != "" {
+		t.Errorf("unexpected diff while comparing: %s", diff)
+	}
+}
@@ -6,6 +6,7 @@ package engine
 import (
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-09-01-preview/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-05-01/resources"
 	"github.com/Azure/go-autorest/autorest/to"
 )
 
@@ -18,6 +19,8 @@ const (
 	IdentityReaderRole IdentityRoleDefinition = "[variables('readerRoleDefinitionId')]"
 	// IdentityManagedIdentityOperatorRole means created user assigned identity or service principal will have operator access on a different managed identity
 	IdentityManagedIdentityOperatorRole IdentityRoleDefinition = "[variables('managedIdentityOperatorRoleDefinitionId')]"
+	// IdentityNetworkContributorRole means created user assigned identity will have "Network Contributor" role on the custom VNET
+	IdentityNetworkContributorRole IdentityRoleDefinition = "[variables('networkContributorRoleDefinitionId')]"
 )
 
 func createMSIRoleAssignment(identityRoleDefinition IdentityRoleDefinition) RoleAssignmentARM {
@@ -41,6 +44,48 @@ func createMSIRoleAssignment(identityRoleDefinition IdentityRoleDefinition) Role
 	}
 }
 
+// createMSIVNETRoleAssignmentDeployment gives the user assigned identity access to the custom VNET of the cluster,
+// which is usually in another resource group, so that the cloud provider can join the subnet and update the routes
+func createMSIVNETRoleAssignmentDeployment() ResourceGroupDeploymentARM {
+	roleAssignment := RoleAssignmentARM{
+		ARMResource: ARMResource{
+			APIVersion: "[variables('apiVersionAuthorizationUser')]",
+		},
+		RoleAssignment: authorization.RoleAssignment{
+			Type: to.StringPtr("Microsoft.Network/virtualNetworks/providers/roleAssignments"),
+			Name: to.StringPtr("[concat(variables('virtualNetworkName'), '/Microsoft.Authorization/', guid(variables('userAssignedIDReference'), 'vnetRoleAssignment', variables('virtualNetworkResourceGroupName')))]"),
+			RoleAssignmentPropertiesWithScope: &authorization.RoleAssignmentPropertiesWithScope{
+				RoleDefinitionID: to.StringPtr(string(IdentityNetworkContributorRole)),
+				PrincipalID:      to.StringPtr("[reference(variables('userAssignedIDReference'), variables('apiVersionManagedIdentity')).principalId]"),
+				PrincipalType:    authorization.ServicePrincipal,
+				Scope:            to.StringPtr("[concat('/subscriptions/', subscription().subscriptionId, '/resourceGroups/', variables('virtualNetworkResourceGroupName'), '/providers/Microsoft.Network/virtualNetworks/', variables('virtualNetworkName'))]"),
+			},
+		},
+	}
+
+	return ResourceGroupDeploymentARM{
+		DeploymentARMResource: DeploymentARMResource{
+			APIVersion: "[variables('apiVersionDeployments')]",
+			DependsOn: []string{
+				"[concat('Microsoft.ManagedIdentity/userAssignedIdentities/', variables('userAssignedID'))]",
+			},
+		},
+		DeploymentExtended: resources.DeploymentExtended{
+			Name: to.StringPtr("[concat(variables('userAssignedID'), '-vnet-role-assignment')]"),
+			Type: to.StringPtr("Microsoft.Resources/deployments"),
+			Properties: &resources.DeploymentPropertiesExtended{
+				Mode: resources.Incremental,
+				Template: map[string]interface{}{
+					"$schema":        "https://schema.management.azure.com/schemas/2015-01-01/deploymentTemplate.json#",
+					"contentVersion": "1.0.0.0",
+					"resources":      []interface{}{roleAssignment},
+				},
+			},
+		},
+		ResourceGroup: to.StringPtr("[variables('virtualNetworkResourceGroupName')]"),
+	}
+}
+
 // createKubernetesSpAppGIdentityOperatorAccessRoleAssignment gives identity operator access on AGIC Identity to the cluster identity
 func createKubernetesSpAppGIdentityOperatorAccessRoleAssignment(prop *api.Properties) RoleAssignmentARM {
 	kubernetesSpObjectID := ""
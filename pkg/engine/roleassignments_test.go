@@ -8,6 +8,7 @@ import (
 
 	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-09-01-preview/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-05-01/resources"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/google/go-cmp/cmp"
 )
@@ -68,6 +69,53 @@ func TestCreateMSIRoleAssignment(t *testing.T) {
 	}
 }
 
+func TestCreateMSIVNETRoleAssignmentDeployment(t *testing.T) {
+	actual := createMSIVNETRoleAssignmentDeployment()
+	expected := ResourceGroupDeploymentARM{
+		DeploymentARMResource: DeploymentARMResource{
+			APIVersion: "[variables('apiVersionDeployments')]",
+			DependsOn: []string{
+				"[concat('Microsoft.ManagedIdentity/userAssignedIdentities/', variables('userAssignedID'))]",
+			},
+		},
+		DeploymentExtended: resources.DeploymentExtended{
+			Name: to.StringPtr("[concat(variables('userAssignedID'), '-vnet-role-assignment')]"),
+			Type: to.StringPtr("Microsoft.Resources/deployments"),
+			Properties: &resources.DeploymentPropertiesExtended{
+				Mode: resources.Incremental,
+				Template: map[string]interface{}{
+					"$schema":        "https://schema.management.azure.com/schemas/2015-01-01/deploymentTemplate.json#",
+					"contentVersion": "1.0.0.0",
+					"resources": []interface{}{
+						RoleAssignmentARM{
+							ARMResource: ARMResource{
+								APIVersion: "[variables('apiVersionAuthorizationUser')]",
+							},
+							RoleAssignment: authorization.RoleAssignment{
+								Type: to.StringPtr("Microsoft.Network/virtualNetworks/providers/roleAssignments"),
+								Name: to.StringPtr("[concat(variables('virtualNetworkName'), '/Microsoft.Authorization/', guid(variables('userAssignedIDReference'), 'vnetRoleAssignment', variables('virtualNetworkResourceGroupName')))]"),
+								RoleAssignmentPropertiesWithScope: &authorization.RoleAssignmentPropertiesWithScope{
+									RoleDefinitionID: to.StringPtr("[variables('networkContributorRoleDefinitionId')]"),
+									PrincipalID:      to.StringPtr("[reference(variables('userAssignedIDReference'), variables('apiVersionManagedIdentity')).principalId]"),
+									PrincipalType:    authorization.ServicePrincipal,
+									Scope:            to.StringPtr("[concat('/subscriptions/', subscription().subscriptionId, '/resourceGroups/', variables('virtualNetworkResourceGroupName'), '/providers/Microsoft.Network/virtualNetworks/', variables('virtualNetworkName'))]"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		ResourceGroup: to.StringPtr("[variables('virtualNetworkResourceGroupName')]"),
+	}
+
+	diff := cmp.Diff(actual, expected)
+
+	if diff != "" {
+		t.Errorf("unexpected diff while comparing: %s", diff)
+	}
+}
+
 func TestCreateKubernetesSpAppGIdentityOperatorAccessRoleAssignment(t *testing.T) {
 	// using service principal
 	cs := &api.ContainerService{
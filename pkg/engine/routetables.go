@@ -4,12 +4,13 @@
 package engine
 
 import (
+	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 	"github.com/Azure/go-autorest/autorest/to"
 )
 
-func createRouteTable() RouteTableARM {
-	return RouteTableARM{
+func createRouteTable(cs *api.ContainerService) RouteTableARM {
+	routeTable := RouteTableARM{
 		ARMResource: ARMResource{
 			APIVersion: "[variables('apiVersionNetwork')]",
 		},
@@ -19,4 +20,23 @@ func createRouteTable() RouteTableARM {
 			Type:     to.StringPtr("Microsoft.Network/routeTables"),
 		},
 	}
+
+	kubernetesConfig := cs.Properties.OrchestratorProfile.KubernetesConfig
+	if kubernetesConfig.IsUserDefinedRouting() && kubernetesConfig.OutboundNextHopIPAddress != "" {
+		// the routes to the pod CIDRs added by the cloud provider are more specific than the default route
+		routeTable.RouteTable.RouteTablePropertiesFormat = &network.RouteTablePropertiesFormat{
+			Routes: &[]network.Route{
+				{
+					Name: to.StringPtr("default"),
+					RoutePropertiesFormat: &network.RoutePropertiesFormat{
+						AddressPrefix:    to.StringPtr("0.0.0.0/0"),
+						NextHopType:      network.RouteNextHopTypeVirtualAppliance,
+						NextHopIPAddress: to.StringPtr(kubernetesConfig.OutboundNextHopIPAddress),
+					},
+				},
+			},
+		}
+	}
+
+	return routeTable
 }
@@ -6,14 +6,26 @@ package engine
 import (
 	"testing"
 
+	"github.com/Azure/aks-engine/pkg/api"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/google/go-cmp/cmp"
 )
 
 func TestCreateRouteTable(t *testing.T) {
+	cs := &api.ContainerService{
+		Properties: &api.Properties{
+			OrchestratorProfile: &api.OrchestratorProfile{
+				OrchestratorType: api.Kubernetes,
+				KubernetesConfig: &api.KubernetesConfig{
+					NetworkPlugin: api.NetworkPluginKubenet,
+					OutboundType:  api.OutboundTypeLoadBalancer,
+				},
+			},
+		},
+	}
 
-	actual := createRouteTable()
+	actual := createRouteTable(cs)
 	expected := RouteTableARM{
 		ARMResource: ARMResource{
 			APIVersion: "[variables('apiVersionNetwork')]",
@@ -30,4 +42,28 @@ func TestCreateRouteTable(t *testing.T) {
 	if diff != "" {
 		t.Errorf("unexpected diff while comparing: %s", diff)
 	}
+
+	// user-defined routing adds the default route to the next hop
+	cs.Properties.OrchestratorProfile.KubernetesConfig.OutboundType = api.OutboundTypeUserDefinedRouting
+	cs.Properties.OrchestratorProfile.KubernetesConfig.OutboundNextHopIPAddress = "10.42.0.4"
+
+	actual = createRouteTable(cs)
+	expected.RouteTable.RouteTablePropertiesFormat = &network.RouteTablePropertiesFormat{
+		Routes: &[]network.Route{
+			{
+				Name: to.StringPtr("default"),
+				RoutePropertiesFormat: &network.RoutePropertiesFormat{
+					AddressPrefix:    to.StringPtr("0.0.0.0/0"),
+					NextHopType:      network.RouteNextHopTypeVirtualAppliance,
+					NextHopIPAddress: to.StringPtr("10.42.0.4"),
+				},
+			},
+		},
+	}
+
+	diff = cmp.Diff(actual, expected)
+
+	if diff != "" {
+		t.Errorf("unexpected diff while comparing: %s", diff)
+	}
 }
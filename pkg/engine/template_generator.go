@@ -101,6 +101,18 @@ func (t *TemplateGenerator) GenerateTemplate(containerService *api.ContainerServ
 		return templateRaw, parametersRaw, errors.New("Invalid distro")
 	}
 
+	if _, err = getChartAddonSettings(properties); err != nil {
+		return templateRaw, parametersRaw, err
+	}
+
+	if err = validateOverlays(properties); err != nil {
+		return templateRaw, parametersRaw, err
+	}
+
+	if err = validateManifestVersions(properties); err != nil {
+		return templateRaw, parametersRaw, err
+	}
+
 	var b bytes.Buffer
 	if err = templ.ExecuteTemplate(&b, baseFile, properties); err != nil {
 		return templateRaw, parametersRaw, err
@@ -183,9 +195,13 @@ func (t *TemplateGenerator) GetMasterCustomDataJSONObject(cs *api.ContainerServi
 		"MASTER_MANIFESTS_CONFIG_PLACEHOLDER",
 		profile.OrchestratorProfile.OrchestratorVersion)
 
-	// add addons
+	// add addons, whose charts GenerateTemplate has already rendered, returning their errors
+	addonSettings, e := kubernetesAddonSettingsInit(profile)
+	if e != nil {
+		panic(e)
+	}
 	str = substituteConfigString(str,
-		kubernetesAddonSettingsInit(profile),
+		addonSettings,
 		"k8s/addons",
 		"/etc/kubernetes/addons",
 		"MASTER_ADDONS_CONFIG_PLACEHOLDER",
@@ -332,6 +348,9 @@ func (t *TemplateGenerator) getTemplateFuncMap(cs *api.ContainerService) templat
 		"UseManagedIdentity": func() bool {
 			return cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity
 		},
+		"UserAssignedIDEnabled": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedIDEnabled()
+		},
 		"NeedsKubeDNSWithExecHealthz": func() bool {
 			return cs.Properties.OrchestratorProfile.NeedsExecHealthz()
 		},
@@ -475,7 +494,8 @@ func (t *TemplateGenerator) getTemplateFuncMap(cs *api.ContainerService) templat
 				kubernetesWindowsKubeletFunctionsPS1,
 				kubernetesWindowsCniFunctionsPS1,
 				kubernetesWindowsAzureCniFunctionsPS1,
-				kubernetesWindowsOpenSSHFunctionPS1}
+				kubernetesWindowsOpenSSHFunctionPS1,
+				kubernetesWindowsContainerdFunctionsPS1}
 
 			// Create a buffer, new zip
 			buf := new(bytes.Buffer)
@@ -630,8 +650,14 @@ func (t *TemplateGenerator) getTemplateFuncMap(cs *api.ContainerService) templat
 		"IsCustomVNET": func() bool {
 			return cs.Properties.AreAgentProfilesCustomVNET()
 		},
-		"IsIPv6DualStackFeatureEnabled": func() bool {
-			return cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack")
+		"IsIPv6DualStack": func() bool {
+			return cs.Properties.IsIPv6DualStack()
+		},
+		"HasAuditWebhook": func() bool {
+			return cs.Properties.OrchestratorProfile.KubernetesConfig.HasAuditWebhook()
+		},
+		"GetAuditWebhookKubeconfig": func() string {
+			return getBase64EncodedGzippedCustomScriptFromStr(cs.Properties.OrchestratorProfile.KubernetesConfig.GetAuditWebhookKubeconfig())
 		},
 		"GetBase64EncodedEnvironmentJSON": func() string {
 			customEnvironmentJSON, _ := cs.Properties.GetCustomEnvironmentJSON(false)
@@ -651,6 +677,17 @@ func (t *TemplateGenerator) getTemplateFuncMap(cs *api.ContainerService) templat
 }
 
 func (t *TemplateGenerator) GenerateTemplateV2(containerService *api.ContainerService, generatorCode string, acsengineVersion string) (templateRaw string, parametersRaw string, err error) {
+	if _, err = getChartAddonSettings(containerService.Properties); err != nil {
+		return "", "", err
+	}
+
+	if err = validateOverlays(containerService.Properties); err != nil {
+		return "", "", err
+	}
+
+	if err = validateManifestVersions(containerService.Properties); err != nil {
+		return "", "", err
+	}
 
 	armParams, _ := t.getParameterDescMap(containerService)
 	armResources := GenerateARMResources(containerService)
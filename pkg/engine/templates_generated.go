@@ -143,6 +143,7 @@
 // ../../parts/k8s/containeraddons/kubernetesmasteraddons-omsagent-daemonset.yaml
 // ../../parts/k8s/containeraddons/kubernetesmasteraddons-smb-flexvolume-installer.yaml
 // ../../parts/k8s/containeraddons/kubernetesmasteraddons-tiller-deployment.yaml
+// ../../parts/k8s/containeraddons/node-termination-handler.yaml
 // ../../parts/k8s/kubeconfig.json
 // ../../parts/k8s/kubernetesparams.t
 // ../../parts/k8s/kuberneteswindowsfunctions.ps1
@@ -156,6 +157,7 @@
 // ../../parts/k8s/windowsazurecnifunc.ps1
 // ../../parts/k8s/windowscnifunc.ps1
 // ../../parts/k8s/windowsconfigfunc.ps1
+// ../../parts/k8s/windowscontainerdfunc.ps1
 // ../../parts/k8s/windowsinstallopensshfunc.ps1
 // ../../parts/k8s/windowskubeletfunc.ps1
 // ../../parts/masteroutputs.t
@@ -12502,6 +12504,8 @@ configureK8s() {
     "vnetName": "${VIRTUAL_NETWORK}",
     "vnetResourceGroup": "${VIRTUAL_NETWORK_RESOURCE_GROUP}",
     "routeTableName": "${ROUTE_TABLE}",
+    "routeTableResourceGroup": "${ROUTE_TABLE_RESOURCE_GROUP}",
+    "securityGroupResourceGroup": "${NETWORK_SECURITY_GROUP_RESOURCE_GROUP}",
     "primaryAvailabilitySetName": "${PRIMARY_AVAILABILITY_SET}",
     "primaryScaleSetName": "${PRIMARY_SCALE_SET}",
     "cloudProviderBackoff": ${CLOUDPROVIDER_BACKOFF},
@@ -12727,6 +12731,7 @@ configClusterAutoscalerAddon() {
     sed -i "s|<tenantID>|$(echo $TENANT_ID | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
     sed -i "s|<rg>|$(echo $RESOURCE_GROUP | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
     sed -i "s|<vmType>|$(echo $VM_TYPE | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
+    sed -i "s|<userAssignedIdentityID>|$(echo -n $USER_ASSIGNED_IDENTITY_ID | base64)|g" $CLUSTER_AUTOSCALER_ADDON_FILE
     sed -i "s|<vmssName>|$PRIMARY_SCALE_SET|g" $CLUSTER_AUTOSCALER_ADDON_FILE
 }
 
@@ -13049,6 +13054,7 @@ ERR_APT_DAILY_TIMEOUT=98 # Timeout waiting for apt daily updates
 ERR_APT_UPDATE_TIMEOUT=99 # Timeout waiting for apt-get update to complete
 ERR_CSE_PROVISION_SCRIPT_NOT_READY_TIMEOUT=100 # Timeout waiting for cloud-init to place this (!) script on the vm
 ERR_APT_DIST_UPGRADE_TIMEOUT=101 # Timeout waiting for apt-get dist-upgrade to complete
+ERR_APT_MIRROR_CONFIG_FAIL=102 # Error configuring the apt sources to use the package mirror
 ERR_SYSCTL_RELOAD=103 # Error reloading sysctl config
 ERR_CIS_ASSIGN_ROOT_PW=111 # Error assigning root password in CIS enforcement
 ERR_CIS_ASSIGN_FILE_PERMISSION=112 # Error assigning permission to a file in CIS enforcement
@@ -13272,6 +13278,12 @@ CNI_BIN_DIR="/opt/cni/bin"
 CNI_DOWNLOADS_DIR="/opt/cni/downloads"
 CONTAINERD_DOWNLOADS_DIR="/opt/containerd/downloads"
 UBUNTU_RELEASE=$(lsb_release -r -s)
+# the package mirror serves the Ubuntu archive under /ubuntu and packages.microsoft.com under /microsoft
+if [[ -n "${PACKAGE_MIRROR_URL:-}" ]]; then
+    MICROSOFT_PACKAGES_URL="${PACKAGE_MIRROR_URL%/}/microsoft"
+else
+    MICROSOFT_PACKAGES_URL="https://packages.microsoft.com"
+fi
 
 removeEtcd() {
     if [[ $OS == $COREOS_OS_NAME ]]; then
@@ -13300,9 +13312,21 @@ installEtcd() {
     fi
 }
 
+configurePackageMirror() {
+    if [[ -z "${PACKAGE_MIRROR_URL:-}" ]]; then
+        return
+    fi
+    sed -i -E "s#https?://([a-z0-9.-]+\.)?(archive|security)\.ubuntu\.com/ubuntu/?#${PACKAGE_MIRROR_URL%/}/ubuntu/#g" /etc/apt/sources.list || exit $ERR_APT_MIRROR_CONFIG_FAIL
+    for apt_list in /etc/apt/sources.list.d/*.list; do
+        [[ -f $apt_list ]] || continue
+        sed -i "s#https://packages.microsoft.com#${MICROSOFT_PACKAGES_URL}#g" $apt_list || exit $ERR_APT_MIRROR_CONFIG_FAIL
+    done
+}
+
 installDeps() {
-    retrycmd_if_failure_no_stats 120 5 25 curl -fsSL https://packages.microsoft.com/config/ubuntu/${UBUNTU_RELEASE}/packages-microsoft-prod.deb > /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_DOWNLOAD_TIMEOUT
+    retrycmd_if_failure_no_stats 120 5 25 curl -fsSL ${MICROSOFT_PACKAGES_URL}/config/ubuntu/${UBUNTU_RELEASE}/packages-microsoft-prod.deb > /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_DOWNLOAD_TIMEOUT
     retrycmd_if_failure 60 5 10 dpkg -i /tmp/packages-microsoft-prod.deb || exit $ERR_MS_PROD_DEB_PKG_ADD_FAIL
+    configurePackageMirror
     aptmarkWALinuxAgent hold
     apt_get_update || exit $ERR_APT_UPDATE_TIMEOUT
     apt_get_dist_upgrade || exit $ERR_APT_DIST_UPGRADE_TIMEOUT
@@ -13379,9 +13403,10 @@ installMoby() {
         echo "dockerd $MOBY_VERSION is already installed, skipping Moby download"
     else
         removeMoby
-        retrycmd_if_failure_no_stats 120 5 25 curl https://packages.microsoft.com/config/ubuntu/${UBUNTU_RELEASE}/prod.list > /tmp/microsoft-prod.list || exit $ERR_MOBY_APT_LIST_TIMEOUT
+        retrycmd_if_failure_no_stats 120 5 25 curl ${MICROSOFT_PACKAGES_URL}/config/ubuntu/${UBUNTU_RELEASE}/prod.list > /tmp/microsoft-prod.list || exit $ERR_MOBY_APT_LIST_TIMEOUT
         retrycmd_if_failure 10 5 10 cp /tmp/microsoft-prod.list /etc/apt/sources.list.d/ || exit $ERR_MOBY_APT_LIST_TIMEOUT
-        retrycmd_if_failure_no_stats 120 5 25 curl https://packages.microsoft.com/keys/microsoft.asc | gpg --dearmor > /tmp/microsoft.gpg || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
+        configurePackageMirror
+        retrycmd_if_failure_no_stats 120 5 25 curl ${MICROSOFT_PACKAGES_URL}/keys/microsoft.asc | gpg --dearmor > /tmp/microsoft.gpg || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
         retrycmd_if_failure 10 5 10 cp /tmp/microsoft.gpg /etc/apt/trusted.gpg.d/ || exit $ERR_MS_GPG_KEY_DOWNLOAD_TIMEOUT
         apt_get_update || exit $ERR_APT_UPDATE_TIMEOUT
         MOBY_CLI=${MOBY_VERSION}
@@ -13645,6 +13670,10 @@ else
     FULL_INSTALL_REQUIRED=true
 fi
 
+if [[ $OS == $UBUNTU_OS_NAME ]]; then
+    configurePackageMirror
+fi
+
 if [[ $OS == $UBUNTU_OS_NAME ]] && [ "$FULL_INSTALL_REQUIRED" = "true" ]; then
     installDeps
 else
@@ -15257,7 +15286,7 @@ write_files:
     {{CloudInitData "aptPreferences"}}
 {{end}}
 
-{{if IsIPv6DualStackFeatureEnabled}}
+{{if IsIPv6DualStack}}
 - path: /etc/systemd/system/dhcpv6.service
   permissions: "0644"
   encoding: gzip
@@ -15417,7 +15446,14 @@ write_files:
             cachesize: 0
         - identity: {}
 {{end}}
-
+{{if HasAuditWebhook}}
+- path: /etc/kubernetes/audit-webhook-kubeconfig.yaml
+  permissions: "0600"
+  encoding: gzip
+  owner: root
+  content: !!binary |
+    {{GetAuditWebhookKubeconfig}}
+{{end}}
 MASTER_MANIFESTS_CONFIG_PLACEHOLDER
 
 MASTER_ADDONS_CONFIG_PLACEHOLDER
@@ -15488,7 +15524,7 @@ MASTER_CONTAINER_ADDONS_PLACEHOLDER
     sed -i "s|<advertiseAddr>|{{WrapAsVariable "kubernetesAPIServerIP"}}|g" $a
     sed -i "s|<args>|{{GetK8sRuntimeConfigKeyVals .OrchestratorProfile.KubernetesConfig.ControllerManagerConfig}}|g" /etc/kubernetes/manifests/kube-controller-manager.yaml
     sed -i "s|<args>|{{GetK8sRuntimeConfigKeyVals .OrchestratorProfile.KubernetesConfig.SchedulerConfig}}|g" /etc/kubernetes/manifests/kube-scheduler.yaml
-    {{ if IsIPv6DualStackFeatureEnabled }}
+    {{ if IsIPv6DualStack }}
     sed -i "s|<img>|{{WrapAsParameter "kubernetesHyperkubeSpec"}}|g; s|<CIDR>|',first(split(parameters('kubeClusterCidr'),',')),'|g; s|<kubeProxyMode>|{{ .OrchestratorProfile.KubernetesConfig.ProxyMode}}|g" /etc/kubernetes/addons/kube-proxy-daemonset.yaml
     {{ else }}
     sed -i "s|<img>|{{WrapAsParameter "kubernetesHyperkubeSpec"}}|g; s|<CIDR>|{{WrapAsParameter "kubeClusterCidr"}}|g; s|<kubeProxyMode>|{{ .OrchestratorProfile.KubernetesConfig.ProxyMode}}|g" /etc/kubernetes/addons/kube-proxy-daemonset.yaml
@@ -15821,7 +15857,7 @@ write_files:
     {{CloudInitData "aptPreferences"}}
 {{end}}
 
-{{if IsIPv6DualStackFeatureEnabled}}
+{{if IsIPv6DualStack}}
 - path: /etc/systemd/system/dhcpv6.service
   permissions: "0644"
   encoding: gzip
@@ -17667,8 +17703,11 @@ rules:
   verbs: ["create"]
 - apiGroups: [""]
   resources: ["configmaps"]
-  resourceNames: ["cluster-autoscaler-status"]
-  verbs: ["delete","get","update"]
+  verbs: ["list","watch"]
+- apiGroups: [""]
+  resources: ["configmaps"]
+  resourceNames: ["cluster-autoscaler-status","cluster-autoscaler-priority-expander"]
+  verbs: ["delete","get","update","watch"]
 ---
 apiVersion: rbac.authorization.k8s.io/v1beta1
 kind: ClusterRoleBinding
@@ -17715,6 +17754,7 @@ data:
   SubscriptionID: <subID>
   TenantID: <tenantID>
   VMType: <vmType>
+  UserAssignedIdentityID: "<userAssignedIdentityID>"
 kind: Secret
 metadata:
   name: cluster-autoscaler-azure
@@ -17723,6 +17763,19 @@ metadata:
     kubernetes.io/cluster-service: "true"
     addonmanager.kubernetes.io/mode: Reconcile
 ---
+{{- if eq (ContainerConfig "expander") "priority"}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cluster-autoscaler-priority-expander
+  namespace: kube-system
+  labels:
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+data:
+  priorities: '{{ContainerConfig "priorities"}}'
+---
+{{- end}}
 apiVersion: apps/v1
 kind: Deployment
 metadata:
@@ -17771,6 +17824,12 @@ spec:
         - --cloud-provider=azure
         - --skip-nodes-with-local-storage=false
         - --nodes={{ContainerConfig "min-nodes"}}:{{ContainerConfig "max-nodes"}}:<vmssName>
+        {{- range ContainerConfigList "fallback-node-groups"}}
+        - --nodes={{ContainerConfig "min-nodes"}}:{{ContainerConfig "max-nodes"}}:{{.}}
+        {{- end}}
+        {{- if ContainerConfig "expander"}}
+        - --expander={{ContainerConfig "expander"}}
+        {{- end}}
         - --scan-interval={{ContainerConfig "scan-interval"}}
         env:
         - name: ARM_CLOUD
@@ -17807,6 +17866,11 @@ spec:
               name: cluster-autoscaler-azure
         - name: ARM_USE_MANAGED_IDENTITY_EXTENSION
           value: "<useManagedIdentity>"
+        - name: ARM_USER_ASSIGNED_IDENTITY_ID
+          valueFrom:
+            secretKeyRef:
+              key: UserAssignedIdentityID
+              name: cluster-autoscaler-azure
         volumeMounts:
         - mountPath: /etc/ssl/certs/ca-certificates.crt
           name: ssl-certs
@@ -21378,8 +21442,11 @@ rules:
   verbs: ["create"]
 - apiGroups: [""]
   resources: ["configmaps"]
-  resourceNames: ["cluster-autoscaler-status"]
-  verbs: ["delete","get","update"]
+  verbs: ["list","watch"]
+- apiGroups: [""]
+  resources: ["configmaps"]
+  resourceNames: ["cluster-autoscaler-status","cluster-autoscaler-priority-expander"]
+  verbs: ["delete","get","update","watch"]
 ---
 apiVersion: rbac.authorization.k8s.io/v1beta1
 kind: ClusterRoleBinding
@@ -21426,6 +21493,7 @@ data:
   SubscriptionID: <subID>
   TenantID: <tenantID>
   VMType: <vmType>
+  UserAssignedIdentityID: "<userAssignedIdentityID>"
 kind: Secret
 metadata:
   name: cluster-autoscaler-azure
@@ -21434,6 +21502,19 @@ metadata:
     kubernetes.io/cluster-service: "true"
     addonmanager.kubernetes.io/mode: Reconcile
 ---
+{{- if eq (ContainerConfig "expander") "priority"}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cluster-autoscaler-priority-expander
+  namespace: kube-system
+  labels:
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+data:
+  priorities: '{{ContainerConfig "priorities"}}'
+---
+{{- end}}
 apiVersion: extensions/v1beta1
 kind: Deployment
 metadata:
@@ -21482,6 +21563,12 @@ spec:
         - --cloud-provider=azure
         - --skip-nodes-with-local-storage=false
         - --nodes={{ContainerConfig "min-nodes"}}:{{ContainerConfig "max-nodes"}}:<vmssName>
+        {{- range ContainerConfigList "fallback-node-groups"}}
+        - --nodes={{ContainerConfig "min-nodes"}}:{{ContainerConfig "max-nodes"}}:{{.}}
+        {{- end}}
+        {{- if ContainerConfig "expander"}}
+        - --expander={{ContainerConfig "expander"}}
+        {{- end}}
         - --scan-interval={{ContainerConfig "scan-interval"}}
         env:
         - name: ARM_CLOUD
@@ -21518,6 +21605,11 @@ spec:
               name: cluster-autoscaler-azure
         - name: ARM_USE_MANAGED_IDENTITY_EXTENSION
           value: "<useManagedIdentity>"
+        - name: ARM_USER_ASSIGNED_IDENTITY_ID
+          valueFrom:
+            secretKeyRef:
+              key: UserAssignedIdentityID
+              name: cluster-autoscaler-azure
         volumeMounts:
         - mountPath: /etc/ssl/certs/ca-certificates.crt
           name: ssl-certs
@@ -22937,6 +23029,159 @@ func k8sContaineraddonsKubernetesmasteraddonsTillerDeploymentYaml() (*asset, err
 	return a, nil
 }
 
+var _k8sContaineraddonsNodeTerminationHandlerYaml = []byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: node-termination-handler
+  namespace: kube-system
+  labels:
+    k8s-app: node-termination-handler
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: node-termination-handler
+  labels:
+    k8s-app: node-termination-handler
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+rules:
+- apiGroups: [""]
+  resources: ["nodes"]
+  verbs: ["get", "patch", "update"]
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list", "delete"]
+- apiGroups: [""]
+  resources: ["pods/eviction"]
+  verbs: ["create"]
+- apiGroups: ["extensions", "apps"]
+  resources: ["daemonsets", "replicasets", "statefulsets"]
+  verbs: ["get"]
+- apiGroups: ["batch"]
+  resources: ["jobs"]
+  verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: node-termination-handler
+  labels:
+    k8s-app: node-termination-handler
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: node-termination-handler
+subjects:
+- kind: ServiceAccount
+  name: node-termination-handler
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: node-termination-handler
+  namespace: kube-system
+  labels:
+    k8s-app: node-termination-handler
+    kubernetes.io/cluster-service: "true"
+    addonmanager.kubernetes.io/mode: Reconcile
+spec:
+  selector:
+    matchLabels:
+      k8s-app: node-termination-handler
+  updateStrategy:
+    type: RollingUpdate
+  template:
+    metadata:
+      labels:
+        k8s-app: node-termination-handler
+    spec:
+      priorityClassName: system-node-critical
+      serviceAccountName: node-termination-handler
+      # the Azure Instance Metadata Service is reached from the node network
+      hostNetwork: true
+      nodeSelector:
+        beta.kubernetes.io/os: linux
+        kubernetes.azure.com/scalesetpriority: low
+      tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      - key: kubernetes.azure.com/scalesetpriority
+        operator: Equal
+        value: low
+        effect: NoSchedule
+      containers:
+      - name: node-termination-handler
+        image: {{ContainerImage "node-termination-handler"}}
+        imagePullPolicy: IfNotPresent
+        resources:
+          requests:
+            cpu: {{ContainerCPUReqs "node-termination-handler"}}
+            memory: {{ContainerMemReqs "node-termination-handler"}}
+          limits:
+            cpu: {{ContainerCPULimits "node-termination-handler"}}
+            memory: {{ContainerMemLimits "node-termination-handler"}}
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        - name: POLL_INTERVAL
+          value: "{{ContainerConfig "poll-interval"}}"
+        - name: DRAIN_GRACE_PERIOD
+          value: "{{ContainerConfig "drain-grace-period"}}"
+        command:
+        - /bin/bash
+        - -c
+        - |
+          kubectl() { /hyperkube kubectl "$@"; }
+          # the hyperkube image has no http client, query the Azure Instance Metadata Service with HTTP/1.0 over /dev/tcp
+          imds() {
+            exec 3<>/dev/tcp/169.254.169.254/80
+            printf 'GET /metadata/%s HTTP/1.0\r\nMetadata: true\r\n\r\n' "$1" >&3
+            sed '1,/^\r$/d' <&3
+            exec 3<&-
+          }
+          ANNOTATION=kubernetes.azure.com/preempted
+          VM_NAME=$(imds "instance/compute/name?api-version=2017-08-01&format=text")
+          # a deallocated node is started again with its previous node object, which was cordoned before the eviction
+          if [ "$(kubectl get node "${NODE_NAME}" -o jsonpath='{.metadata.annotations.kubernetes\.azure\.com/preempted}')" = "true" ]; then
+            echo "Uncordoning node ${NODE_NAME} restarted after its eviction"
+            kubectl uncordon "${NODE_NAME}" && kubectl annotate node "${NODE_NAME}" "${ANNOTATION}-"
+          fi
+          while true; do
+            EVENTS=$(imds "scheduledevents?api-version=2017-11-01")
+            if echo "${EVENTS}" | grep -q '"EventType": *"Preempt"' && echo "${EVENTS}" | grep -q "\"${VM_NAME}\""; then
+              echo "VM ${VM_NAME} is about to be evicted, draining node ${NODE_NAME}"
+              kubectl annotate node "${NODE_NAME}" --overwrite "${ANNOTATION}=true"
+              kubectl drain "${NODE_NAME}" --ignore-daemonsets --delete-local-data --force --grace-period="${DRAIN_GRACE_PERIOD}" --timeout=0s
+              # the VM is evicted within 30 seconds of the notice
+              sleep 3600
+            fi
+            sleep "${POLL_INTERVAL}"
+          done
+`)
+
+func k8sContaineraddonsNodeTerminationHandlerYamlBytes() ([]byte, error) {
+	return _k8sContaineraddonsNodeTerminationHandlerYaml, nil
+}
+
+func k8sContaineraddonsNodeTerminationHandlerYaml() (*asset, error) {
+	bytes, err := k8sContaineraddonsNodeTerminationHandlerYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "k8s/containeraddons/node-termination-handler.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _k8sKubeconfigJson = []byte(`    {
         "apiVersion": "v1",
         "clusters": [
@@ -23195,6 +23440,13 @@ var _k8sKubernetesparamsT = []byte(`{{if .HasAadProfile}}
       },
       "type": "string"
     },
+    "packageMirrorURL": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The apt mirror of the Ubuntu archive and of packages.microsoft.com used by the nodes."
+      },
+      "type": "string"
+    },
     "kubernetesCcmImageSpec": {
       "defaultValue": "",
       "metadata": {
@@ -23775,9 +24027,15 @@ $global:KubeBinariesPackageSASURL = "{{WrapAsParameter "kubeBinariesSASURL"}}"
 $global:WindowsKubeBinariesURL = "{{WrapAsParameter "windowsKubeBinariesURL"}}"
 $global:KubeBinariesVersion = "{{WrapAsParameter "kubeBinariesVersion"}}"
 
+## Container runtime, docker or containerd
+$global:ContainerRuntime = "{{WrapAsParameter "containerRuntime"}}"
+
 ## Docker Version
 $global:DockerVersion = "{{WrapAsParameter "windowsDockerVersion"}}"
 
+## Containerd package
+$global:ContainerdURL = "{{WrapAsParameter "windowsContainerdURL"}}"
+
 ## VM configuration passed by Azure
 $global:WindowsTelemetryGUID = "{{WrapAsParameter "windowsTelemetryGUID"}}"
 {{if eq GetIdentitySystem "adfs"}}
@@ -23807,7 +24065,11 @@ $global:KubeletNodeLabels = "{{GetAgentKubernetesLabelsDeprecated . "',variables
 $global:KubeletConfigArgs = @( {{GetKubeletConfigKeyValsPsh .KubernetesConfig }} )
 
 $global:UseManagedIdentityExtension = "{{WrapAsVariable "useManagedIdentityExtension"}}"
+{{if UserAssignedIDEnabled}}
+$global:UserAssignedClientID = "{{WrapAsVerbatim "reference(concat('Microsoft.ManagedIdentity/userAssignedIdentities/', variables('userAssignedID')), '2018-11-30').clientId"}}"
+{{else}}
 $global:UserAssignedClientID = "{{WrapAsVariable "userAssignedClientID"}}"
+{{end}}
 $global:UseInstanceMetadata = "{{WrapAsVariable "useInstanceMetadata"}}"
 
 $global:LoadBalancerSku = "{{WrapAsVariable "loadBalancerSku"}}"
@@ -23849,13 +24111,14 @@ Expand-Archive scripts.zip -DestinationPath "C:\\AzureData\\"
 . c:\AzureData\k8s\windowscnifunc.ps1
 . c:\AzureData\k8s\windowsazurecnifunc.ps1
 . c:\AzureData\k8s\windowsinstallopensshfunc.ps1
+. c:\AzureData\k8s\windowscontainerdfunc.ps1
 
 function
 Update-ServiceFailureActions()
 {
     sc.exe failure "kubelet" actions= restart/60000/restart/60000/restart/60000 reset= 900
     sc.exe failure "kubeproxy" actions= restart/60000/restart/60000/restart/60000 reset= 900
-    sc.exe failure "docker" actions= restart/60000/restart/60000/restart/60000 reset= 900
+    sc.exe failure "$global:ContainerRuntime" actions= restart/60000/restart/60000/restart/60000 reset= 900
 }
 
 try
@@ -23879,8 +24142,21 @@ try
         Write-Log "Create required data directories as needed"
         Initialize-DataDirectories
 
-        Write-Log "Install docker"
-        Install-Docker -DockerVersion $global:DockerVersion
+        if ($global:ContainerRuntime -eq "containerd") {
+            Write-Log "Install containerd"
+            if ($global:NetworkPlugin -eq "azure") {
+                Install-Containerd -ContainerdUrl $global:ContainerdURL ` + "`" + `
+                                   -CNIBinDir $global:AzureCNIBinDir ` + "`" + `
+                                   -CNIConfDir $global:AzureCNIConfDir
+            } else {
+                Install-Containerd -ContainerdUrl $global:ContainerdURL ` + "`" + `
+                                   -CNIBinDir $global:CNIPath ` + "`" + `
+                                   -CNIConfDir $global:CNIConfigPath
+            }
+        } else {
+            Write-Log "Install docker"
+            Install-Docker -DockerVersion $global:DockerVersion
+        }
 
         Write-Log "Download kubelet binaries and unzip"
         Get-KubePackage -KubeBinariesSASURL $global:KubeBinariesPackageSASURL
@@ -23937,8 +24213,11 @@ try
                          -AgentCertificate $global:AgentCertificate
 
 
-        Write-Log "Create the Pause Container kubletwin/pause"
-        New-InfraContainer -KubeDir $global:KubeDir
+        # containerd pulls the sandbox image of its config
+        if ($global:ContainerRuntime -ne "containerd") {
+            Write-Log "Create the Pause Container kubletwin/pause"
+            New-InfraContainer -KubeDir $global:KubeDir
+        }
 
         Write-Log "Configuring networking with NetworkPlugin:$global:NetworkPlugin"
 
@@ -23990,7 +24269,8 @@ try
             -KubeClusterCIDR $global:KubeClusterCIDR ` + "`" + `
             -KubeServiceCIDR $global:KubeServiceCIDR ` + "`" + `
             -HNSModule $global:HNSModule ` + "`" + `
-            -KubeletNodeLabels $global:KubeletNodeLabels
+            -KubeletNodeLabels $global:KubeletNodeLabels ` + "`" + `
+            -ContainerRuntime $global:ContainerRuntime
 
         # Install OpenSSH if SSH enabled
         $sshEnabled = [System.Convert]::ToBoolean("{{ WindowsSSHEnabled }}")
@@ -24740,6 +25020,110 @@ func k8sWindowsconfigfuncPs1() (*asset, error) {
 	return a, nil
 }
 
+var _k8sWindowscontainerdfuncPs1 = []byte(`$global:ContainerdInstallLocation = "$Env:ProgramFiles\containerd"
+$global:ContainerdPipe = "npipe:////./pipe/containerd-containerd"
+
+function
+Install-Containerd {
+    Param(
+        [Parameter(Mandatory = $true)][string]
+        $ContainerdUrl,
+        [Parameter(Mandatory = $true)][string]
+        $CNIBinDir,
+        [Parameter(Mandatory = $true)][string]
+        $CNIConfDir,
+        [string]
+        $PauseImage = "mcr.microsoft.com/k8s/core/pause:1.2.0"
+    )
+
+    $svc = Get-Service -Name containerd -ErrorAction SilentlyContinue
+    if ($null -ne $svc) {
+        Write-Log "Stopping containerd service"
+        $svc | Stop-Service
+    }
+
+    # The zip contains containerd.exe, containerd-shim-runhcs-v1.exe and ctr.exe, with crictl.exe in bin\
+    $tempdir = New-TemporaryDirectory
+    $zipfile = [Io.path]::Combine($tempdir, "containerd.zip")
+    for ($i = 0; $i -le 10; $i++) {
+        DownloadFileOverHttp -Url $ContainerdUrl -DestinationPath $zipfile
+        if ($?) {
+            break
+        }
+        else {
+            Write-Log $Error[0].Exception.Message
+        }
+    }
+    Expand-Archive -path $zipfile -DestinationPath $global:ContainerdInstallLocation -Force
+    del $tempdir -Recurse
+
+    Add-SystemPathEntry $global:ContainerdInstallLocation
+    Add-SystemPathEntry ([Io.path]::Combine($global:ContainerdInstallLocation, "bin"))
+
+    $configFile = [Io.path]::Combine($global:ContainerdInstallLocation, "config.toml")
+    Write-Log "Writing containerd config to $configFile"
+    $config = @"
+version = 2
+root = "C:\\ProgramData\\containerd\\root"
+state = "C:\\ProgramData\\containerd\\state"
+
+[grpc]
+  address = "\\\\.\\pipe\\containerd-containerd"
+
+[plugins]
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "$PauseImage"
+    [plugins."io.containerd.grpc.v1.cri".containerd]
+      snapshotter = "windows"
+      default_runtime_name = "runhcs-wcow-process"
+      [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runhcs-wcow-process]
+        runtime_type = "io.containerd.runhcs.v1"
+    [plugins."io.containerd.grpc.v1.cri".cni]
+      bin_dir = "$($CNIBinDir.Replace("\", "\\"))"
+      conf_dir = "$($CNIConfDir.Replace("\", "\\"))"
+"@
+    $config | Out-File -encoding ASCII -filepath $configFile
+
+    $containerdExe = [Io.path]::Combine($global:ContainerdInstallLocation, "containerd.exe")
+    if ($null -eq $svc) {
+        Write-Log "Registering containerd as a service"
+        & $containerdExe --register-service --config $configFile
+    }
+    Write-Log "Starting containerd service"
+    Start-Service containerd
+}
+
+function
+Add-SystemPathEntry {
+    Param(
+        [Parameter(Mandatory = $true)][string]
+        $Directory
+    )
+    $path = [System.Environment]::GetEnvironmentVariable("Path", [System.EnvironmentVariableTarget]::Machine)
+    if (-not ($path -split ";" -contains $Directory)) {
+        [System.Environment]::SetEnvironmentVariable("Path", "$path;$Directory", [System.EnvironmentVariableTarget]::Machine)
+    }
+    if (-not ($env:Path -split ";" -contains $Directory)) {
+        $env:Path += ";$Directory"
+    }
+}
+`)
+
+func k8sWindowscontainerdfuncPs1Bytes() ([]byte, error) {
+	return _k8sWindowscontainerdfuncPs1, nil
+}
+
+func k8sWindowscontainerdfuncPs1() (*asset, error) {
+	bytes, err := k8sWindowscontainerdfuncPs1Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "k8s/windowscontainerdfunc.ps1", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _k8sWindowsinstallopensshfuncPs1 = []byte(`function
 Install-OpenSSH {
     Param(
@@ -24879,7 +25263,7 @@ Write-AzureConfig {
     "primaryAvailabilitySetName": "$PrimaryAvailabilitySetName",
     "primaryScaleSetName": "$PrimaryScaleSetName",
     "useManagedIdentityExtension": $UseManagedIdentityExtension,
-    "userAssignedIdentityID": $UserAssignedClientID,
+    "userAssignedIdentityID": "$UserAssignedClientID",
     "useInstanceMetadata": $UseInstanceMetadata,
     "loadBalancerSku": "$LoadBalancerSku",
     "excludeMasterFromStandardLB": $ExcludeMasterFromStandardLB
@@ -25050,7 +25434,9 @@ New-NSSMService {
         $KubeletStartFile,
         [string]
         [Parameter(Mandatory = $true)]
-        $KubeProxyStartFile
+        $KubeProxyStartFile,
+        [string]
+        $ContainerRuntime = "docker"
     )
 
     # setup kubelet
@@ -25059,7 +25445,7 @@ New-NSSMService {
     & "$KubeDir\nssm.exe" set Kubelet AppParameters $KubeletStartFile
     & "$KubeDir\nssm.exe" set Kubelet DisplayName Kubelet
     & "$KubeDir\nssm.exe" set Kubelet AppRestartDelay 5000
-    & "$KubeDir\nssm.exe" set Kubelet DependOnService docker
+    & "$KubeDir\nssm.exe" set Kubelet DependOnService $ContainerRuntime
     & "$KubeDir\nssm.exe" set Kubelet Description Kubelet
     & "$KubeDir\nssm.exe" set Kubelet Start SERVICE_AUTO_START
     & "$KubeDir\nssm.exe" set Kubelet ObjectName LocalSystem
@@ -25130,7 +25516,9 @@ Install-KubernetesServices {
         [Parameter(Mandatory = $true)][string]
         $HNSModule,
         [Parameter(Mandatory = $true)][string]
-        $KubeletNodeLabels
+        $KubeletNodeLabels,
+        [string]
+        $ContainerRuntime = "docker"
     )
 
     # Calculate some local paths
@@ -25147,6 +25535,14 @@ Install-KubernetesServices {
     # Only args that need to be calculated or combined with other ones on the Windows agent should be added here.
 
 
+    if ($ContainerRuntime -eq "containerd") {
+        $KubeletArgList += @("--container-runtime=remote", "--container-runtime-endpoint=$global:ContainerdPipe", "--runtime-request-timeout=15m")
+        $RemoveContainersCommand = "crictl.exe --runtime-endpoint $global:ContainerdPipe ps -q | foreach {crictl.exe --runtime-endpoint $global:ContainerdPipe rm -f ` + "`" + `$_}"
+    }
+    else {
+        $RemoveContainersCommand = "docker ps -q | foreach {docker rm ` + "`" + `$_ -f}"
+    }
+
     # Regex to strip version to Major.Minor.Build format such that the following check does not crash for version like x.y.z-alpha
     [regex]$regex = "^[0-9.]+"
     $KubeBinariesVersionStripped = $regex.Matches($KubeBinariesVersion).Value
@@ -25229,7 +25625,7 @@ if (!` + "`" + `$hnsNetwork)
 if (` + "`" + `$hnsNetwork)
 {
     # Cleanup all containers
-    docker ps -q | foreach {docker rm ` + "`" + `$_ -f}
+    $RemoveContainersCommand
 
     Write-Host "Cleaning up old HNS network found"
     Remove-HnsNetwork ` + "`" + `$hnsNetwork
@@ -25375,7 +25771,7 @@ try
     {
         # Kubelet has been restarted with existing network.
         # Cleanup all containers
-        docker ps -q | foreach {docker rm ` + "`" + `$_ -f}
+        $RemoveContainersCommand
         # cleanup network
         Write-Host "Cleaning up old HNS network found"
         Remove-HnsNetwork ` + "`" + `$hnsNetwork
@@ -25431,7 +25827,8 @@ $KubeDir\kube-proxy.exe --v=3 --proxy-mode=kernelspace --hostname-override=$env:
 
     New-NSSMService -KubeDir $KubeDir ` + "`" + `
         -KubeletStartFile $KubeletStartFile ` + "`" + `
-        -KubeProxyStartFile $KubeProxyStartFile
+        -KubeProxyStartFile $KubeProxyStartFile ` + "`" + `
+        -ContainerRuntime $ContainerRuntime
 }
 `)
 
@@ -28715,6 +29112,27 @@ var _windowsparamsT = []byte(` {{if IsKubernetes}}
       },
       "type": "string"
     },
+    "windowsContainerdURL": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The download url for the containerd package of the Windows nodes, when containerRuntime is containerd"
+      },
+      "type": "string"
+    },
+    "agentWindowsImageName": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The name of the managed image or Shared Image Gallery image definition of the Windows agent virtual machines."
+      },
+      "type": "string"
+    },
+    "agentWindowsImageResourceGroup": {
+      "defaultValue": "",
+      "metadata": {
+        "description": "The resource group of the managed image or Shared Image Gallery of the Windows agent virtual machines."
+      },
+      "type": "string"
+    },
  {{end}}
     "windowsAdminUsername": {
       "type": "string",
@@ -28982,6 +29400,7 @@ var _bindata = map[string]func() (*asset, error){
 	"k8s/containeraddons/kubernetesmasteraddons-omsagent-daemonset.yaml":                   k8sContaineraddonsKubernetesmasteraddonsOmsagentDaemonsetYaml,
 	"k8s/containeraddons/kubernetesmasteraddons-smb-flexvolume-installer.yaml":             k8sContaineraddonsKubernetesmasteraddonsSmbFlexvolumeInstallerYaml,
 	"k8s/containeraddons/kubernetesmasteraddons-tiller-deployment.yaml":                    k8sContaineraddonsKubernetesmasteraddonsTillerDeploymentYaml,
+	"k8s/containeraddons/node-termination-handler.yaml":                                    k8sContaineraddonsNodeTerminationHandlerYaml,
 	"k8s/kubeconfig.json":                                                k8sKubeconfigJson,
 	"k8s/kubernetesparams.t":                                             k8sKubernetesparamsT,
 	"k8s/kuberneteswindowsfunctions.ps1":                                 k8sKuberneteswindowsfunctionsPs1,
@@ -28995,6 +29414,7 @@ var _bindata = map[string]func() (*asset, error){
 	"k8s/windowsazurecnifunc.ps1":                                        k8sWindowsazurecnifuncPs1,
 	"k8s/windowscnifunc.ps1":                                             k8sWindowscnifuncPs1,
 	"k8s/windowsconfigfunc.ps1":                                          k8sWindowsconfigfuncPs1,
+	"k8s/windowscontainerdfunc.ps1":                                      k8sWindowscontainerdfuncPs1,
 	"k8s/windowsinstallopensshfunc.ps1":                                  k8sWindowsinstallopensshfuncPs1,
 	"k8s/windowskubeletfunc.ps1":                                         k8sWindowskubeletfuncPs1,
 	"masteroutputs.t":                                                    masteroutputsT,
@@ -29019,11 +29439,13 @@ var _bindata = map[string]func() (*asset, error){
 // directory embedded in the file by go-bindata.
 // For example if you run go-bindata on data/... and data contains the
 // following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
 // then AssetDir("data") would return []string{"foo.txt", "img"}
 // AssetDir("data/img") would return []string{"a.png", "b.png"}
 // AssetDir("foo.txt") and AssetDir("notexist") would return an error
@@ -29233,6 +29655,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"kubernetesmasteraddons-omsagent-daemonset.yaml":              {k8sContaineraddonsKubernetesmasteraddonsOmsagentDaemonsetYaml, map[string]*bintree{}},
 			"kubernetesmasteraddons-smb-flexvolume-installer.yaml":        {k8sContaineraddonsKubernetesmasteraddonsSmbFlexvolumeInstallerYaml, map[string]*bintree{}},
 			"kubernetesmasteraddons-tiller-deployment.yaml":               {k8sContaineraddonsKubernetesmasteraddonsTillerDeploymentYaml, map[string]*bintree{}},
+			"node-termination-handler.yaml":                               {k8sContaineraddonsNodeTerminationHandlerYaml, map[string]*bintree{}},
 		}},
 		"kubeconfig.json":                {k8sKubeconfigJson, map[string]*bintree{}},
 		"kubernetesparams.t":             {k8sKubernetesparamsT, map[string]*bintree{}},
@@ -29249,6 +29672,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"windowsazurecnifunc.ps1":       {k8sWindowsazurecnifuncPs1, map[string]*bintree{}},
 		"windowscnifunc.ps1":            {k8sWindowscnifuncPs1, map[string]*bintree{}},
 		"windowsconfigfunc.ps1":         {k8sWindowsconfigfuncPs1, map[string]*bintree{}},
+		"windowscontainerdfunc.ps1":     {k8sWindowscontainerdfuncPs1, map[string]*bintree{}},
 		"windowsinstallopensshfunc.ps1": {k8sWindowsinstallopensshfuncPs1, map[string]*bintree{}},
 		"windowskubeletfunc.ps1":        {k8sWindowskubeletfuncPs1, map[string]*bintree{}},
 	}},
@@ -0,0 +1,328 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTemplateSizeThreshold is the template size in bytes above which agent pools are moved to linked
+	// templates. ARM rejects templates larger than 4 MiB, this leaves room for the parameters and request envelope.
+	DefaultTemplateSizeThreshold = 3 * 1024 * 1024
+	// LinkedTemplatesDirectory is the directory of the output directory linked templates are written to
+	LinkedTemplatesDirectory = "linkedtemplates"
+	// LinkedTemplatesLocationParameter is the main template parameter holding the URI of the linked templates container
+	LinkedTemplatesLocationParameter = "linkedTemplatesLocation"
+	// LinkedTemplatesSasTokenParameter is the main template parameter holding the SAS token, including the leading '?',
+	// appended to linked template URIs
+	LinkedTemplatesSasTokenParameter = "linkedTemplatesSasToken"
+)
+
+// LinkedTemplate is the template of an agent pool split from the main template
+type LinkedTemplate struct {
+	PoolName string
+	FileName string
+	Template map[string]interface{}
+}
+
+var (
+	templateVariableRefRegex  = regexp.MustCompile(`variables\('([^']+)'\)`)
+	templateParameterRefRegex = regexp.MustCompile(`parameters\('([^']+)'\)`)
+
+	// agentPoolNameVariableSuffixes are the suffixes of the per agent pool variables, see getK8sAgentVars,
+	// which identify the resources of a pool by appearing in their names or dependencies
+	agentPoolNameVariableSuffixes = []string{"VMNamePrefix", "AvailabilitySet", "Offset", "StorageAccountOffset", "AccountName", "DataAccountName"}
+)
+
+// TemplateSize returns the size in bytes of the template when serialized without indentation, which is how it is
+// submitted to ARM.
+func TemplateSize(template map[string]interface{}) (int, error) {
+	b, err := helpers.JSONMarshal(template, false)
+	if err != nil {
+		return 0, errors.Wrap(err, "serializing template")
+	}
+	return len(b), nil
+}
+
+// MarshalTemplate returns the indented JSON of the linked template
+func (l *LinkedTemplate) MarshalTemplate() ([]byte, error) {
+	b, err := helpers.JSONMarshalIndent(l.Template, "", "  ", false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "serializing linked template of agent pool %s", l.PoolName)
+	}
+	return b, nil
+}
+
+// LinkedTemplateDeploymentName returns the name of the deployment resource of the linked template of an agent pool
+func LinkedTemplateDeploymentName(poolName string) string {
+	return fmt.Sprintf("%s-linkedtemplate", poolName)
+}
+
+// SplitTemplateBySize moves the resources of each agent pool to a linked template when the serialized template is
+// larger than threshold bytes. The rewritten template is returned together with the linked templates; if the
+// template is small enough it is returned unchanged and no linked templates are returned.
+func SplitTemplateBySize(cs *api.ContainerService, template string, threshold int) (string, []LinkedTemplate, error) {
+	if len(template) <= threshold {
+		return template, nil, nil
+	}
+	var templateMap map[string]interface{}
+	if err := json.Unmarshal([]byte(template), &templateMap); err != nil {
+		return "", nil, errors.Wrap(err, "parsing template")
+	}
+	size, err := TemplateSize(templateMap)
+	if err != nil {
+		return "", nil, err
+	}
+	if size <= threshold {
+		return template, nil, nil
+	}
+
+	poolNames := []string{}
+	for _, profile := range cs.Properties.AgentPoolProfiles {
+		poolNames = append(poolNames, profile.Name)
+	}
+	linkedTemplates, err := SplitTemplate(templateMap, poolNames)
+	if err != nil {
+		return "", nil, err
+	}
+	b, err := helpers.JSONMarshal(templateMap, false)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "serializing template")
+	}
+	return string(b), linkedTemplates, nil
+}
+
+// SplitTemplate moves the resources of each of the named agent pools out of template, which is modified in place,
+// into one linked template per pool. Each pool is replaced by a Microsoft.Resources/deployments resource linking its
+// template from the location given by the linkedTemplatesLocation and linkedTemplatesSasToken parameters. The
+// variables and parameters used by the pool resources are copied to the linked template, and parameters are passed
+// through from the main template.
+func SplitTemplate(template map[string]interface{}, poolNames []string) ([]LinkedTemplate, error) {
+	resources, ok := template["resources"].([]interface{})
+	if !ok {
+		return nil, errors.New("template has no resources")
+	}
+	variables, _ := template["variables"].(map[string]interface{})
+	parameters, _ := template["parameters"].(map[string]interface{})
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+		template["parameters"] = parameters
+	}
+
+	poolVariables := map[string]string{}
+	for _, poolName := range poolNames {
+		for _, suffix := range agentPoolNameVariableSuffixes {
+			poolVariables[poolName+suffix] = poolName
+		}
+	}
+	poolOf := func(expression string) string {
+		for _, match := range templateVariableRefRegex.FindAllStringSubmatch(expression, -1) {
+			if poolName, ok := poolVariables[match[1]]; ok {
+				return poolName
+			}
+		}
+		return ""
+	}
+
+	mainResources := []interface{}{}
+	poolResources := map[string][]interface{}{}
+	for _, r := range resources {
+		resource, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("template resource is not an object")
+		}
+		name, _ := resource["name"].(string)
+		if poolName := poolOf(name); poolName != "" {
+			poolResources[poolName] = append(poolResources[poolName], resource)
+		} else {
+			mainResources = append(mainResources, resource)
+		}
+	}
+
+	linkedTemplates := []LinkedTemplate{}
+	for _, poolName := range poolNames {
+		if len(poolResources[poolName]) == 0 {
+			continue
+		}
+		deploymentName := LinkedTemplateDeploymentName(poolName)
+		fileName := poolName + ".json"
+
+		// dependencies on resources remaining in the main template become dependencies of the deployment
+		deploymentDependsOn := []interface{}{}
+		seen := map[string]bool{}
+		for _, r := range poolResources[poolName] {
+			resource := r.(map[string]interface{})
+			dependsOn, ok := resource["dependsOn"].([]interface{})
+			if !ok {
+				continue
+			}
+			kept := []interface{}{}
+			for _, d := range dependsOn {
+				dependency, _ := d.(string)
+				if poolOf(dependency) == poolName {
+					kept = append(kept, d)
+					continue
+				}
+				// a dependency within a copy loop cannot be expressed on the deployment,
+				// the resources it points to are within the same pool in practice
+				if strings.Contains(dependency, "copyIndex(") || seen[dependency] {
+					continue
+				}
+				seen[dependency] = true
+				deploymentDependsOn = append(deploymentDependsOn, d)
+			}
+			resource["dependsOn"] = kept
+		}
+
+		linkedVariables, linkedParameterNames, err := templateReferences(poolResources[poolName], variables)
+		if err != nil {
+			return nil, errors.Wrapf(err, "collecting references of agent pool %s", poolName)
+		}
+		linkedParameters := map[string]interface{}{}
+		deploymentParameters := map[string]interface{}{}
+		for _, name := range linkedParameterNames {
+			name, ok := lookupTemplateKey(parameters, name)
+			if !ok {
+				return nil, errors.Errorf("agent pool %s references undefined parameter %s", poolName, name)
+			}
+			definition := parameters[name]
+			linkedParameters[name] = definition
+			deploymentParameters[name] = map[string]interface{}{
+				"value": fmt.Sprintf("[parameters('%s')]", name),
+			}
+		}
+
+		linkedTemplates = append(linkedTemplates, LinkedTemplate{
+			PoolName: poolName,
+			FileName: fileName,
+			Template: map[string]interface{}{
+				"$schema":        "https://schema.management.azure.com/schemas/2015-01-01/deploymentTemplate.json#",
+				"contentVersion": "1.0.0.0",
+				"parameters":     linkedParameters,
+				"variables":      linkedVariables,
+				"resources":      poolResources[poolName],
+				"outputs":        map[string]interface{}{},
+			},
+		})
+
+		mainResources = append(mainResources, map[string]interface{}{
+			"type":       "Microsoft.Resources/deployments",
+			"apiVersion": "[variables('apiVersionDeployments')]",
+			"name":       deploymentName,
+			"dependsOn":  deploymentDependsOn,
+			"properties": map[string]interface{}{
+				"mode": "Incremental",
+				"templateLink": map[string]interface{}{
+					"uri":            fmt.Sprintf("[concat(parameters('%s'), '/%s', parameters('%s'))]", LinkedTemplatesLocationParameter, fileName, LinkedTemplatesSasTokenParameter),
+					"contentVersion": "1.0.0.0",
+				},
+				"parameters": deploymentParameters,
+			},
+		})
+	}
+
+	// dependencies of the main template on split resources become dependencies on the deployment
+	for _, r := range mainResources {
+		resource := r.(map[string]interface{})
+		dependsOn, ok := resource["dependsOn"].([]interface{})
+		if !ok || resource["type"] == "Microsoft.Resources/deployments" {
+			continue
+		}
+		rewritten := []interface{}{}
+		seen := map[string]bool{}
+		for _, d := range dependsOn {
+			dependency, _ := d.(string)
+			if poolName := poolOf(dependency); poolName != "" && len(poolResources[poolName]) > 0 {
+				dependency = fmt.Sprintf("[resourceId('Microsoft.Resources/deployments', '%s')]", LinkedTemplateDeploymentName(poolName))
+				d = dependency
+			}
+			if seen[dependency] {
+				continue
+			}
+			seen[dependency] = true
+			rewritten = append(rewritten, d)
+		}
+		resource["dependsOn"] = rewritten
+	}
+
+	template["resources"] = mainResources
+	parameters[LinkedTemplatesLocationParameter] = map[string]interface{}{
+		"type": "string",
+		"metadata": map[string]interface{}{
+			"description": "The URI of the storage container holding the linked agent pool templates, without a trailing '/'",
+		},
+	}
+	parameters[LinkedTemplatesSasTokenParameter] = map[string]interface{}{
+		"type":         "securestring",
+		"defaultValue": "",
+		"metadata": map[string]interface{}{
+			"description": "The SAS token, including the leading '?', used to read the linked agent pool templates",
+		},
+	}
+	return linkedTemplates, nil
+}
+
+// templateReferences returns the variables transitively referenced by resources, and the sorted names of the
+// parameters referenced by either.
+func templateReferences(resources []interface{}, variables map[string]interface{}) (map[string]interface{}, []string, error) {
+	b, err := json.Marshal(resources)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "serializing resources")
+	}
+	referenced := map[string]interface{}{}
+	parameterNames := map[string]bool{}
+	pending := []string{string(b)}
+	for len(pending) > 0 {
+		expression := pending[0]
+		pending = pending[1:]
+		for _, match := range templateParameterRefRegex.FindAllStringSubmatch(expression, -1) {
+			parameterNames[match[1]] = true
+		}
+		for _, match := range templateVariableRefRegex.FindAllStringSubmatch(expression, -1) {
+			name, ok := lookupTemplateKey(variables, match[1])
+			if !ok {
+				return nil, nil, errors.Errorf("undefined variable %s", match[1])
+			}
+			if _, ok := referenced[name]; ok {
+				continue
+			}
+			value := variables[name]
+			referenced[name] = value
+			v, err := json.Marshal(value)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "serializing variable %s", name)
+			}
+			pending = append(pending, string(v))
+		}
+	}
+	names := []string{}
+	for name := range parameterNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return referenced, names, nil
+}
+
+// lookupTemplateKey returns the key of m matching name, which like ARM variable and parameter names is compared
+// case-insensitively.
+func lookupTemplateKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
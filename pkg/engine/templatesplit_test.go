@@ -0,0 +1,167 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/leonelquinteros/gotext"
+)
+
+func generateTemplateForSplit(t *testing.T, apiModelFilename string) (*api.ContainerService, string) {
+	t.Helper()
+	containerService := loadTerraformTestContainerService(t, apiModelFilename)
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	ctx := Context{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	templateGenerator, err := InitializeTemplateGenerator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, _, err := templateGenerator.GenerateTemplateV2(containerService, DefaultGeneratorCode, TestAKSEngineVersion)
+	if err != nil {
+		t.Fatalf("unexpected error generating template for %s: %s", apiModelFilename, err.Error())
+	}
+	return containerService, template
+}
+
+func TestSplitTemplate(t *testing.T) {
+	for _, apiModelFilename := range []string{
+		"./testdata/simple/kubernetes.json",
+		"./testdata/disks-storageaccount/kubernetes.json",
+		"./testdata/disks-managed/kubernetes-vmss.json",
+		"./testdata/largeclusters/kubernetes.json",
+		"./testdata/windows/kubernetes-hybrid.json",
+	} {
+		t.Run(apiModelFilename, func(t *testing.T) {
+			cs, templateRaw := generateTemplateForSplit(t, apiModelFilename)
+			var template map[string]interface{}
+			if err := json.Unmarshal([]byte(templateRaw), &template); err != nil {
+				t.Fatal(err)
+			}
+			resourceCount := len(template["resources"].([]interface{}))
+
+			poolNames := []string{}
+			for _, profile := range cs.Properties.AgentPoolProfiles {
+				poolNames = append(poolNames, profile.Name)
+			}
+			linkedTemplates, err := SplitTemplate(template, poolNames)
+			if err != nil {
+				t.Fatalf("unexpected error splitting template: %s", err)
+			}
+			if len(linkedTemplates) != len(poolNames) {
+				t.Fatalf("expected %d linked templates, got %d", len(poolNames), len(linkedTemplates))
+			}
+
+			parameters := template["parameters"].(map[string]interface{})
+			for _, name := range []string{LinkedTemplatesLocationParameter, LinkedTemplatesSasTokenParameter} {
+				if _, ok := parameters[name]; !ok {
+					t.Errorf("expected main template parameter %s", name)
+				}
+			}
+
+			deployments := map[string]map[string]interface{}{}
+			mainCount := 0
+			splitCount := 0
+			for _, r := range template["resources"].([]interface{}) {
+				resource := r.(map[string]interface{})
+				name := resource["name"].(string)
+				if resource["type"] == "Microsoft.Resources/deployments" && strings.HasSuffix(name, "-linkedtemplate") {
+					deployments[name] = resource
+					continue
+				}
+				mainCount++
+				for _, poolName := range poolNames {
+					if strings.Contains(name, fmt.Sprintf("variables('%sVMNamePrefix')", poolName)) {
+						t.Errorf("resource %s of pool %s was not split", name, poolName)
+					}
+				}
+			}
+
+			for _, linked := range linkedTemplates {
+				deployment, ok := deployments[LinkedTemplateDeploymentName(linked.PoolName)]
+				if !ok {
+					t.Fatalf("expected a deployment resource for pool %s", linked.PoolName)
+				}
+				properties := deployment["properties"].(map[string]interface{})
+				uri := properties["templateLink"].(map[string]interface{})["uri"].(string)
+				if !strings.Contains(uri, "/"+linked.FileName) {
+					t.Errorf("expected deployment of pool %s to link %s, got %s", linked.PoolName, linked.FileName, uri)
+				}
+				for _, d := range deployment["dependsOn"].([]interface{}) {
+					if strings.Contains(d.(string), "copyIndex(") {
+						t.Errorf("deployment of pool %s depends on %s outside of a copy loop", linked.PoolName, d)
+					}
+				}
+
+				resources := linked.Template["resources"].([]interface{})
+				splitCount += len(resources)
+				b, err := json.Marshal(resources)
+				if err != nil {
+					t.Fatal(err)
+				}
+				variables := linked.Template["variables"].(map[string]interface{})
+				linkedParameters := linked.Template["parameters"].(map[string]interface{})
+				deploymentParameters := properties["parameters"].(map[string]interface{})
+				if len(linkedParameters) != len(deploymentParameters) {
+					t.Errorf("expected pool %s deployment to pass %d parameters, got %d", linked.PoolName, len(linkedParameters), len(deploymentParameters))
+				}
+				v, err := json.Marshal(variables)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, match := range templateVariableRefRegex.FindAllStringSubmatch(string(b)+string(v), -1) {
+					if _, ok := lookupTemplateKey(variables, match[1]); !ok {
+						t.Errorf("linked template of pool %s references missing variable %s", linked.PoolName, match[1])
+					}
+				}
+				for _, match := range templateParameterRefRegex.FindAllStringSubmatch(string(b)+string(v), -1) {
+					if _, ok := lookupTemplateKey(linkedParameters, match[1]); !ok {
+						t.Errorf("linked template of pool %s references missing parameter %s", linked.PoolName, match[1])
+					}
+				}
+			}
+
+			if splitCount+mainCount != resourceCount {
+				t.Errorf("expected %d resources across templates, got %d", resourceCount, splitCount+mainCount)
+			}
+		})
+	}
+}
+
+func TestSplitTemplateBySize(t *testing.T) {
+	cs, template := generateTemplateForSplit(t, "./testdata/simple/kubernetes.json")
+
+	unchanged, linkedTemplates, err := SplitTemplateBySize(cs, template, DefaultTemplateSizeThreshold)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if unchanged != template || len(linkedTemplates) != 0 {
+		t.Errorf("expected a template below the threshold to be left unchanged")
+	}
+
+	split, linkedTemplates, err := SplitTemplateBySize(cs, template, 1024)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(linkedTemplates) != len(cs.Properties.AgentPoolProfiles) {
+		t.Errorf("expected %d linked templates, got %d", len(cs.Properties.AgentPoolProfiles), len(linkedTemplates))
+	}
+	if len(split) >= len(template) {
+		t.Errorf("expected the main template to shrink from %d bytes, got %d", len(template), len(split))
+	}
+
+	if _, _, err := SplitTemplateBySize(cs, "{", 0); err == nil {
+		t.Errorf("expected error splitting an invalid template")
+	}
+}
@@ -0,0 +1,1357 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+const (
+	// terraformResourceGroupLabel is the label of the azurerm_resource_group data source the cluster is deployed into
+	terraformResourceGroupLabel = "cluster"
+	// terraformSubscriptionLabel is the label of the azurerm_subscription data source
+	terraformSubscriptionLabel = "current"
+	// terraformResourceGroupVariable is the input variable holding the name of the target resource group
+	terraformResourceGroupVariable = "resource_group_name"
+)
+
+// TerraformReport lists the parts of the ARM template that could not be translated to Terraform
+type TerraformReport struct {
+	// UnmappedResources are ARM resources with no azurerm_* equivalent, as "<type> <name>"
+	UnmappedResources []string
+	// UnresolvedExpressions are ARM expressions carried over verbatim, as "<address>: <expression> (<reason>)"
+	UnresolvedExpressions []string
+	// UnresolvedDependencies are dependsOn entries that do not match a translated resource
+	UnresolvedDependencies []string
+}
+
+// IsEmpty returns true if everything in the ARM template was translated
+func (r *TerraformReport) IsEmpty() bool {
+	return len(r.UnmappedResources) == 0 && len(r.UnresolvedExpressions) == 0 && len(r.UnresolvedDependencies) == 0
+}
+
+// GenerateTerraformConfig generates a Terraform JSON configuration (.tf.json) that declares the same
+// azurerm_* resources as the ARM template produced by GenerateTemplateV2.
+// Parameter values are carried over as variable defaults, and ARM variables become locals.
+func (t *TemplateGenerator) GenerateTerraformConfig(containerService *api.ContainerService, generatorCode string, aksEngineVersion string) (configRaw string, report *TerraformReport, err error) {
+	armParams, err := t.getParameterDescMap(containerService)
+	if err != nil {
+		return "", nil, err
+	}
+	armVariables, err := GetKubernetesVariables(containerService)
+	if err != nil {
+		return "", nil, err
+	}
+	armResources := GenerateARMResources(containerService)
+	armOutputs := GetKubernetesOutputs(containerService)
+	parameters := getParameters(containerService, generatorCode, aksEngineVersion)
+
+	c := newTerraformConverter(armVariables)
+	config, err := c.convert(armParams, parameters, armVariables, armResources, armOutputs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b, err := helpers.JSONMarshalIndent(config, "", "  ", false)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(b), c.report, nil
+}
+
+// armMap is the JSON form of an ARM resource or one of its properties.
+type armMap map[string]interface{}
+
+// m returns the nested object at key, or nil.
+func (m armMap) m(keys ...string) armMap {
+	cur := m
+	for _, key := range keys {
+		if cur == nil {
+			return nil
+		}
+		next, ok := cur[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = next
+	}
+	return cur
+}
+
+// list returns the array of objects at key.
+func (m armMap) list(key string) []armMap {
+	if m == nil {
+		return nil
+	}
+	items, _ := m[key].([]interface{})
+	var ret []armMap
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			ret = append(ret, obj)
+		}
+	}
+	return ret
+}
+
+// s returns the string at key, or "".
+func (m armMap) s(key string) string {
+	if m == nil {
+		return ""
+	}
+	s, _ := m[key].(string)
+	return s
+}
+
+// v returns the raw value at key.
+func (m armMap) v(key string) interface{} {
+	if m == nil {
+		return nil
+	}
+	return m[key]
+}
+
+// ids returns the "id" of every object in the array at key.
+func (m armMap) ids(key string) []string {
+	var ret []string
+	for _, item := range m.list(key) {
+		if id := item.s("id"); id != "" {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// terraformResource is a resource block of the generated configuration.
+type terraformResource struct {
+	resourceType string
+	label        string
+	armType      string
+	armNames     []string
+	armRaw       armMap
+	hasCount     bool
+	dependsOn    []string
+	body         map[string]interface{}
+}
+
+func (r *terraformResource) address() string {
+	return r.resourceType + "." + r.label
+}
+
+type terraformConverter struct {
+	hcl       hclExpressionTranslator
+	keys      hclExpressionTranslator
+	variables map[string]interface{}
+	resources []*terraformResource
+	labels    map[string]bool
+	report    *TerraformReport
+}
+
+func newTerraformConverter(armVariables map[string]interface{}) *terraformConverter {
+	c := &terraformConverter{
+		variables: armVariables,
+		labels:    map[string]bool{},
+		report:    &TerraformReport{},
+	}
+	c.hcl = hclExpressionTranslator{resolveReference: c.resolveReference}
+	c.keys = hclExpressionTranslator{wildcardCopyIndex: true}
+	return c
+}
+
+func (c *terraformConverter) convert(armParams interface{}, parameters paramsMap, armVariables map[string]interface{}, armResources []interface{}, armOutputs map[string]interface{}) (map[string]interface{}, error) {
+	// first pass: name every resource so that dependsOn and reference() can be resolved
+	for _, resource := range armResources {
+		if err := c.index(resource); err != nil {
+			return nil, err
+		}
+	}
+
+	// second pass: translate resource properties
+	resourceBlocks := map[string]map[string]interface{}{}
+	for i := 0; i < len(c.resources); i++ {
+		r := c.resources[i]
+		if r.body == nil {
+			c.mapResource(r)
+		}
+	}
+	for _, r := range c.resources {
+		c.resolveDependsOn(r)
+		if resourceBlocks[r.resourceType] == nil {
+			resourceBlocks[r.resourceType] = map[string]interface{}{}
+		}
+		resourceBlocks[r.resourceType][r.label] = r.body
+	}
+
+	variables := map[string]interface{}{
+		terraformResourceGroupVariable: map[string]interface{}{
+			"type":        "string",
+			"description": "Name of the existing resource group to deploy the cluster into",
+		},
+	}
+	if params, ok := armParams.(map[string]interface{}); ok {
+		for name, desc := range params {
+			variables[name] = c.variable(name, armMap(asMap(desc)), parameters[name])
+		}
+	}
+
+	locals := map[string]interface{}{}
+	for name, value := range armVariables {
+		locals[name] = c.value("local."+name, value)
+	}
+
+	outputs := map[string]interface{}{}
+	for name, output := range armOutputs {
+		outputs[name] = map[string]interface{}{
+			"value": c.value("output."+name, armMap(asMap(output)).v("value")),
+		}
+	}
+
+	sort.Strings(c.report.UnmappedResources)
+	sort.Strings(c.report.UnresolvedExpressions)
+	sort.Strings(c.report.UnresolvedDependencies)
+
+	config := map[string]interface{}{
+		"provider": map[string]interface{}{
+			"azurerm": map[string]interface{}{
+				"features": map[string]interface{}{},
+			},
+		},
+		"data": map[string]interface{}{
+			"azurerm_resource_group": map[string]interface{}{
+				terraformResourceGroupLabel: map[string]interface{}{
+					"name": fmt.Sprintf("${var.%s}", terraformResourceGroupVariable),
+				},
+			},
+			"azurerm_subscription": map[string]interface{}{
+				terraformSubscriptionLabel: map[string]interface{}{},
+			},
+		},
+		"variable": variables,
+		"locals":   locals,
+		"resource": resourceBlocks,
+		"output":   outputs,
+	}
+	if len(c.report.UnmappedResources) > 0 {
+		config["//"] = "resources with no azurerm equivalent, not included: " + strings.Join(c.report.UnmappedResources, ", ")
+	}
+	return config, nil
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	if m, ok := v.(paramsMap); ok {
+		return m
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// terraformResourceTypes maps ARM resource types to the azurerm resource declaring them.
+var terraformResourceTypes = map[string]string{
+	"microsoft.compute/availabilitysets":               "azurerm_availability_set",
+	"microsoft.compute/virtualmachines":                "azurerm_virtual_machine",
+	"microsoft.compute/virtualmachines/extensions":     "azurerm_virtual_machine_extension",
+	"microsoft.compute/virtualmachinescalesets":        "azurerm_virtual_machine_scale_set",
+	"microsoft.compute/images":                         "azurerm_image",
+	"microsoft.network/networkinterfaces":              "azurerm_network_interface",
+	"microsoft.network/networksecuritygroups":          "azurerm_network_security_group",
+	"microsoft.network/virtualnetworks":                "azurerm_virtual_network",
+	"microsoft.network/routetables":                    "azurerm_route_table",
+	"microsoft.network/publicipaddresses":              "azurerm_public_ip",
+	"microsoft.network/loadbalancers":                  "azurerm_lb",
+	"microsoft.storage/storageaccounts":                "azurerm_storage_account",
+	"microsoft.managedidentity/userassignedidentities": "azurerm_user_assigned_identity",
+	"microsoft.authorization/roleassignments":          "azurerm_role_assignment",
+}
+
+// index registers an ARM resource under its azurerm type and label.
+func (c *terraformConverter) index(resource interface{}) error {
+	switch resource.(type) {
+	case AvailabilitySetARM, VirtualMachineARM, VirtualMachineExtensionARM, VirtualMachineScaleSetARM, ImageARM,
+		NetworkInterfaceARM, NetworkSecurityGroupARM, VirtualNetworkARM, RouteTableARM, PublicIPAddressARM, LoadBalancerARM,
+		StorageAccountARM, UserAssignedIdentitiesARM, RoleAssignmentARM, SystemRoleAssignmentARM:
+	default:
+		c.unmapped(resource)
+		return nil
+	}
+
+	raw := armMap(asMap(resource))
+	if raw == nil {
+		return errors.Errorf("unable to serialize resource %T", resource)
+	}
+	armType := raw.s("type")
+	resourceType, ok := terraformResourceTypes[strings.ToLower(armType)]
+	if !ok {
+		c.unmapped(resource)
+		return nil
+	}
+	r := &terraformResource{
+		resourceType: resourceType,
+		armType:      armType,
+		armRaw:       raw,
+		hasCount:     raw.m("copy") != nil,
+	}
+	r.label = c.uniqueLabel(resourceType, terraformLabel(raw.s("name"), resourceType))
+	if name, err := parseARMValue(raw.s("name")); err == nil {
+		r.armNames = c.pathSegments(c.inlineVariables(name))
+	}
+	c.resources = append(c.resources, r)
+	return nil
+}
+
+func (c *terraformConverter) unmapped(resource interface{}) {
+	raw := armMap(asMap(resource))
+	armType := raw.s("type")
+	if armType == "" {
+		armType = fmt.Sprintf("%T", resource)
+	}
+	c.report.UnmappedResources = append(c.report.UnmappedResources, strings.TrimSpace(armType+" "+raw.s("name")))
+}
+
+var nonLabelChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// terraformLabel derives a readable resource label from the variables and literals of an ARM name expression.
+func terraformLabel(armName, resourceType string) string {
+	var words []string
+	var collect func(node armExprNode)
+	collect = func(node armExprNode) {
+		switch n := node.(type) {
+		case armStringLiteral:
+			// resource type prefixes such as 'Microsoft.Compute/virtualMachines/' add nothing to a label
+			if !strings.HasPrefix(n.value, "Microsoft.") {
+				words = append(words, n.value)
+			}
+		case armFunctionCall:
+			switch strings.ToLower(n.name) {
+			case "variables", "parameters":
+				if len(n.args) == 1 {
+					collect(n.args[0])
+				}
+			case "copyindex", "resourcegroup", "subscription":
+			default:
+				for _, arg := range n.args {
+					collect(arg)
+				}
+			}
+		case armPropertyAccess:
+			collect(n.target)
+		case armIndexAccess:
+			collect(n.target)
+		}
+	}
+	if node, err := parseARMValue(armName); err == nil {
+		collect(node)
+	}
+	label := strings.Trim(nonLabelChars.ReplaceAllString(strings.ToLower(strings.Join(words, "_")), "_"), "_")
+	if label == "" || (label[0] >= '0' && label[0] <= '9') {
+		label = strings.TrimPrefix(resourceType, "azurerm_") + "_" + label
+	}
+	return strings.TrimRight(label, "_")
+}
+
+func (c *terraformConverter) uniqueLabel(resourceType, label string) string {
+	candidate := label
+	for i := 2; c.labels[resourceType+"."+candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", label, i)
+	}
+	c.labels[resourceType+"."+candidate] = true
+	return candidate
+}
+
+// pathSegments splits a resource name or path expression at its literal slashes and renders each
+// segment for matching, with copyIndex() offsets ignored.
+func (c *terraformConverter) pathSegments(node armExprNode) []string {
+	var segments [][]armExprNode
+	current := []armExprNode{}
+	for _, part := range flattenARMConcat(node) {
+		lit, ok := part.(armStringLiteral)
+		if !ok {
+			current = append(current, part)
+			continue
+		}
+		pieces := strings.Split(lit.value, "/")
+		for i, piece := range pieces {
+			if i > 0 {
+				segments = append(segments, current)
+				current = []armExprNode{}
+			}
+			if piece != "" {
+				current = append(current, armStringLiteral{value: piece})
+			}
+		}
+	}
+	segments = append(segments, current)
+
+	var rendered []string
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			continue
+		}
+		s, err := c.keys.template(armFunctionCall{name: "concat", args: segment})
+		if err != nil {
+			s = fmt.Sprintf("%v", segment)
+		}
+		rendered = append(rendered, s)
+	}
+	return rendered
+}
+
+// inlineVariables replaces references to string ARM variables, e.g. [variables('vnetID')], with their
+// values so that resource names and paths spelled differently compare equal.
+func (c *terraformConverter) inlineVariables(node armExprNode) armExprNode {
+	return c.inlineVariablesDepth(node, 0)
+}
+
+func (c *terraformConverter) inlineVariablesDepth(node armExprNode, depth int) armExprNode {
+	if depth > 10 {
+		return node
+	}
+	switch n := node.(type) {
+	case armFunctionCall:
+		if strings.EqualFold(n.name, "variables") && len(n.args) == 1 {
+			if lit, ok := n.args[0].(armStringLiteral); ok {
+				if value, ok := c.variables[lit.value].(string); ok {
+					if next, err := parseARMValue(value); err == nil {
+						return c.inlineVariablesDepth(next, depth+1)
+					}
+				}
+			}
+			return n
+		}
+		args := make([]armExprNode, len(n.args))
+		for i, arg := range n.args {
+			args[i] = c.inlineVariablesDepth(arg, depth)
+		}
+		return armFunctionCall{name: n.name, args: args}
+	case armPropertyAccess:
+		return armPropertyAccess{target: c.inlineVariablesDepth(n.target, depth), property: n.property}
+	case armIndexAccess:
+		return armIndexAccess{target: c.inlineVariablesDepth(n.target, depth), index: c.inlineVariablesDepth(n.index, depth)}
+	}
+	return node
+}
+
+// lookup finds the translated resource an ARM resource path (a dependsOn entry or the first
+// argument of reference()) points to.
+func (c *terraformConverter) lookup(path armExprNode) *terraformResource {
+	path = c.inlineVariables(path)
+
+	var armType string
+	var names []string
+	if call, ok := path.(armFunctionCall); ok && strings.EqualFold(call.name, "resourceId") && len(call.args) > 1 {
+		if lit, ok := call.args[0].(armStringLiteral); ok {
+			armType = strings.TrimSuffix(lit.value, "/")
+			for _, arg := range call.args[1:] {
+				names = append(names, c.pathSegments(arg)...)
+			}
+		}
+	} else {
+		segments := c.pathSegments(path)
+		if len(segments) >= 3 && len(segments)%2 == 1 && strings.Contains(segments[0], ".") {
+			typeSegments := []string{segments[0]}
+			for i := 1; i < len(segments); i += 2 {
+				typeSegments = append(typeSegments, segments[i])
+				names = append(names, segments[i+1])
+			}
+			armType = strings.Join(typeSegments, "/")
+		} else {
+			names = segments
+		}
+	}
+
+	var match *terraformResource
+	for _, r := range c.resources {
+		if armType != "" && !strings.EqualFold(r.armType, armType) {
+			continue
+		}
+		if strings.Join(r.armNames, "/") != strings.Join(names, "/") {
+			continue
+		}
+		if match != nil {
+			// ambiguous
+			return nil
+		}
+		match = r
+	}
+	return match
+}
+
+// resolveReference translates reference(<path>) to the address of the matching Terraform resource.
+func (c *terraformConverter) resolveReference(path armExprNode) (string, error) {
+	r := c.lookup(path)
+	if r == nil {
+		return "", errors.New("reference() to a resource outside of this configuration")
+	}
+	if r.hasCount {
+		return r.address() + "[count.index]", nil
+	}
+	return r.address(), nil
+}
+
+func (c *terraformConverter) resolveDependsOn(r *terraformResource) {
+	seen := map[string]bool{}
+	var dependsOn []string
+	for _, dep := range r.dependsOn {
+		node, err := parseARMValue(dep)
+		var target *terraformResource
+		if err == nil {
+			target = c.lookup(node)
+		}
+		if target == nil {
+			c.report.UnresolvedDependencies = append(c.report.UnresolvedDependencies, fmt.Sprintf("%s: %s", r.address(), dep))
+			continue
+		}
+		if target == r || seen[target.address()] {
+			continue
+		}
+		seen[target.address()] = true
+		dependsOn = append(dependsOn, target.address())
+	}
+	if len(dependsOn) > 0 {
+		r.body["depends_on"] = dependsOn
+	}
+}
+
+// str translates a single ARM string value into a Terraform string template.
+// Values that cannot be translated are kept verbatim and reported.
+func (c *terraformConverter) str(where string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	node, err := parseARMValue(s)
+	if err == nil {
+		var t string
+		if t, err = c.hcl.template(node); err == nil {
+			return t
+		}
+	}
+	c.unresolved(where, s, err)
+	return escapeHCLTemplate(s)
+}
+
+// expr translates an ARM value into an HCL expression (for use inside ${...} or jsonencode).
+func (c *terraformConverter) expr(where string, v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		node, err := parseARMValue(val)
+		if err == nil {
+			var e string
+			if e, err = c.hcl.expression(node); err == nil {
+				return e
+			}
+		}
+		c.unresolved(where, val, err)
+		return quoteHCLString(val)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, k := range keys {
+			items = append(items, fmt.Sprintf("%s = %s", quoteHCLString(k), c.expr(where+"."+k, val[k])))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for i, item := range val {
+			items = append(items, c.expr(fmt.Sprintf("%s[%d]", where, i), item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case nil:
+		return "null"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// value translates every string in an arbitrary ARM value.
+func (c *terraformConverter) value(where string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return c.str(where, val)
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			ret[k] = c.value(where+"."+k, item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, item := range val {
+			ret[i] = c.value(fmt.Sprintf("%s[%d]", where, i), item)
+		}
+		return ret
+	}
+	return v
+}
+
+func (c *terraformConverter) unresolved(where, s string, err error) {
+	c.report.UnresolvedExpressions = append(c.report.UnresolvedExpressions, fmt.Sprintf("%s: %s (%v)", where, s, err))
+}
+
+// variable translates an ARM parameter into a Terraform input variable.
+func (c *terraformConverter) variable(name string, desc armMap, value interface{}) map[string]interface{} {
+	v := map[string]interface{}{}
+	switch strings.ToLower(desc.s("type")) {
+	case "int":
+		v["type"] = "number"
+	case "bool":
+		v["type"] = "bool"
+	case "array", "object":
+		v["type"] = "any"
+	case "securestring":
+		v["type"] = "string"
+		v["sensitive"] = true
+	default:
+		v["type"] = "string"
+	}
+	if description := desc.m("metadata").s("description"); description != "" {
+		v["description"] = escapeHCLTemplate(description)
+	}
+	if pv, ok := value.(paramsMap); ok {
+		if val, ok := pv["value"]; ok {
+			v["default"] = literalValue(val)
+		} else {
+			c.report.UnresolvedExpressions = append(c.report.UnresolvedExpressions, fmt.Sprintf("var.%s: key vault reference (supply the value at plan time)", name))
+		}
+	} else if def, ok := desc["defaultValue"]; ok {
+		v["default"] = c.value("var."+name, def)
+	}
+	return v
+}
+
+// literalValue escapes template sequences in a parameter value so Terraform takes it literally.
+func literalValue(v interface{}) interface{} {
+	switch val := asJSONValue(v).(type) {
+	case string:
+		return escapeHCLTemplate(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = literalValue(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = literalValue(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+func asJSONValue(v interface{}) interface{} {
+	switch v.(type) {
+	case string, bool, float64, nil, map[string]interface{}, []interface{}:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var ret interface{}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return v
+	}
+	return ret
+}
+
+// mapResource fills in the Terraform body of an indexed resource.
+func (c *terraformConverter) mapResource(r *terraformResource) {
+	raw := r.armRaw
+	where := r.address()
+	body := map[string]interface{}{}
+	if r.resourceType != "azurerm_role_assignment" {
+		// role assignment names are GUIDs computed by ARM; Terraform generates its own
+		body["name"] = c.str(where+".name", raw.v("name"))
+		body["resource_group_name"] = fmt.Sprintf("${data.azurerm_resource_group.%s.name}", terraformResourceGroupLabel)
+	}
+	if raw.v("location") != nil {
+		body["location"] = c.str(where+".location", raw.v("location"))
+	}
+	if copy := raw.m("copy"); copy != nil {
+		body["count"] = c.str(where+".count", copy.v("count"))
+	}
+	if tags := raw.m("tags"); len(tags) > 0 {
+		body["tags"] = c.value(where+".tags", map[string]interface{}(tags))
+	}
+	if zones, ok := raw.v("zones").([]interface{}); ok && len(zones) > 0 {
+		body["zones"] = c.value(where+".zones", zones)
+	}
+	if deps, ok := raw.v("dependsOn").([]interface{}); ok {
+		for _, dep := range deps {
+			if s, ok := dep.(string); ok {
+				r.dependsOn = append(r.dependsOn, s)
+			}
+		}
+	}
+	r.body = body
+
+	props := raw.m("properties")
+	switch r.resourceType {
+	case "azurerm_availability_set":
+		c.availabilitySet(r, props)
+	case "azurerm_virtual_machine":
+		c.virtualMachine(r, props)
+	case "azurerm_virtual_machine_extension":
+		c.virtualMachineExtension(r, props)
+	case "azurerm_virtual_machine_scale_set":
+		c.virtualMachineScaleSet(r, props)
+	case "azurerm_image":
+		c.image(r, props)
+	case "azurerm_network_interface":
+		c.networkInterface(r, props)
+	case "azurerm_network_security_group":
+		c.networkSecurityGroup(r, props)
+	case "azurerm_virtual_network":
+		c.virtualNetwork(r, props)
+	case "azurerm_route_table":
+		c.routeTable(r, props)
+	case "azurerm_public_ip":
+		c.publicIP(r, props)
+	case "azurerm_lb":
+		c.loadBalancer(r, props)
+	case "azurerm_storage_account":
+		c.storageAccount(r, raw)
+	case "azurerm_user_assigned_identity":
+		// name, location and resource group are all it takes
+	case "azurerm_role_assignment":
+		c.roleAssignment(r, props)
+	}
+}
+
+// set assigns the translated value of v to key, skipping empty values.
+func (c *terraformConverter) set(body map[string]interface{}, where, key string, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		return
+	case string:
+		if val == "" {
+			return
+		}
+	}
+	body[key] = c.value(where+"."+key, v)
+}
+
+// setIDs assigns a list of translated resource IDs to key, skipping empty lists.
+func (c *terraformConverter) setIDs(body map[string]interface{}, where, key string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	var ret []interface{}
+	for _, id := range ids {
+		ret = append(ret, c.str(where+"."+key, id))
+	}
+	body[key] = ret
+}
+
+func (c *terraformConverter) availabilitySet(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "platform_fault_domain_count", props.v("platformFaultDomainCount"))
+	c.set(r.body, where, "platform_update_domain_count", props.v("platformUpdateDomainCount"))
+	if sku := r.armRaw.m("sku"); sku != nil {
+		r.body["managed"] = strings.EqualFold(sku.s("name"), "Aligned")
+	}
+}
+
+func (c *terraformConverter) identity(r *terraformResource) {
+	identity := r.armRaw.m("identity")
+	if identity == nil {
+		return
+	}
+	block := map[string]interface{}{
+		"type": identity.s("type"),
+	}
+	var ids []string
+	for id := range identity.m("userAssignedIdentities") {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	c.setIDs(block, r.address()+".identity", "identity_ids", ids)
+	r.body["identity"] = []interface{}{block}
+}
+
+// customData translates an osProfile.customData value. The azurerm provider base64-encodes
+// custom_data itself, so the outer base64() is dropped and the payload passes through unchanged.
+func (c *terraformConverter) customData(where string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	node, err := parseARMValue(s)
+	if err == nil {
+		if call, ok := node.(armFunctionCall); ok && strings.EqualFold(call.name, "base64") && len(call.args) == 1 {
+			var t string
+			if t, err = c.hcl.template(call.args[0]); err == nil {
+				return t
+			}
+		} else {
+			var e string
+			if e, err = c.hcl.expression(node); err == nil {
+				return "${base64decode(" + e + ")}"
+			}
+		}
+	}
+	c.unresolved(where, s, err)
+	return escapeHCLTemplate(s)
+}
+
+// osProfile translates osProfile into the os_profile* blocks shared by VMs and scale sets.
+func (c *terraformConverter) osProfile(r *terraformResource, osProfile armMap, nameKey string) {
+	if osProfile == nil {
+		return
+	}
+	where := r.address() + ".os_profile"
+	profile := map[string]interface{}{}
+	if nameKey == "computer_name" {
+		c.set(profile, where, nameKey, osProfile.v("computerName"))
+	} else {
+		c.set(profile, where, nameKey, osProfile.v("computerNamePrefix"))
+	}
+	c.set(profile, where, "admin_username", osProfile.v("adminUsername"))
+	c.set(profile, where, "admin_password", osProfile.v("adminPassword"))
+	if customData := c.customData(where+".custom_data", osProfile.v("customData")); customData != nil {
+		profile["custom_data"] = customData
+	}
+	r.body["os_profile"] = []interface{}{profile}
+
+	if linux := osProfile.m("linuxConfiguration"); linux != nil {
+		block := map[string]interface{}{
+			"disable_password_authentication": linux.v("disablePasswordAuthentication") == true,
+		}
+		var keys []interface{}
+		for _, key := range linux.m("ssh").list("publicKeys") {
+			k := map[string]interface{}{}
+			c.set(k, where, "path", key.v("path"))
+			c.set(k, where, "key_data", key.v("keyData"))
+			keys = append(keys, k)
+		}
+		if len(keys) > 0 {
+			block["ssh_keys"] = keys
+		}
+		r.body["os_profile_linux_config"] = []interface{}{block}
+	}
+	if windows := osProfile.m("windowsConfiguration"); windows != nil {
+		block := map[string]interface{}{
+			"provision_vm_agent": windows.v("provisionVMAgent") != false,
+		}
+		c.set(block, where, "enable_automatic_upgrades", windows.v("enableAutomaticUpdates"))
+		r.body["os_profile_windows_config"] = []interface{}{block}
+	}
+	var secrets []interface{}
+	for _, secret := range osProfile.list("secrets") {
+		block := map[string]interface{}{}
+		c.set(block, where, "source_vault_id", secret.m("sourceVault").v("id"))
+		var certs []interface{}
+		for _, cert := range secret.list("vaultCertificates") {
+			cb := map[string]interface{}{}
+			c.set(cb, where, "certificate_url", cert.v("certificateUrl"))
+			c.set(cb, where, "certificate_store", cert.v("certificateStore"))
+			certs = append(certs, cb)
+		}
+		block["vault_certificates"] = certs
+		secrets = append(secrets, block)
+	}
+	if len(secrets) > 0 {
+		r.body["os_profile_secrets"] = secrets
+	}
+}
+
+func (c *terraformConverter) imageReference(where string, ref armMap) map[string]interface{} {
+	if ref == nil {
+		return nil
+	}
+	block := map[string]interface{}{}
+	for _, k := range [][2]string{{"id", "id"}, {"publisher", "publisher"}, {"offer", "offer"}, {"sku", "sku"}, {"version", "version"}} {
+		c.set(block, where, k[1], ref.v(k[0]))
+	}
+	return block
+}
+
+func (c *terraformConverter) disk(where string, disk armMap) map[string]interface{} {
+	block := map[string]interface{}{}
+	c.set(block, where, "name", disk.v("name"))
+	c.set(block, where, "caching", disk.v("caching"))
+	c.set(block, where, "create_option", disk.v("createOption"))
+	c.set(block, where, "disk_size_gb", disk.v("diskSizeGB"))
+	c.set(block, where, "lun", disk.v("lun"))
+	c.set(block, where, "os_type", disk.v("osType"))
+	c.set(block, where, "managed_disk_type", disk.m("managedDisk").v("storageAccountType"))
+	c.set(block, where, "vhd_uri", disk.m("vhd").v("uri"))
+	c.set(block, where, "image_uri", disk.m("image").v("uri"))
+	return block
+}
+
+func (c *terraformConverter) virtualMachine(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "vm_size", props.m("hardwareProfile").v("vmSize"))
+	c.set(r.body, where, "availability_set_id", props.m("availabilitySet").v("id"))
+	c.setIDs(r.body, where, "network_interface_ids", props.m("networkProfile").ids("networkInterfaces"))
+	for _, nic := range props.m("networkProfile").list("networkInterfaces") {
+		if nic.m("properties").v("primary") == true {
+			c.set(r.body, where, "primary_network_interface_id", nic.v("id"))
+		}
+	}
+	c.identity(r)
+
+	storage := props.m("storageProfile")
+	if ref := c.imageReference(where+".storage_image_reference", storage.m("imageReference")); ref != nil {
+		r.body["storage_image_reference"] = []interface{}{ref}
+	}
+	if osDisk := storage.m("osDisk"); osDisk != nil {
+		r.body["storage_os_disk"] = []interface{}{c.disk(where+".storage_os_disk", osDisk)}
+	}
+	var dataDisks []interface{}
+	for _, disk := range storage.list("dataDisks") {
+		dataDisks = append(dataDisks, c.disk(where+".storage_data_disk", disk))
+	}
+	if len(dataDisks) > 0 {
+		r.body["storage_data_disk"] = dataDisks
+	}
+
+	c.osProfile(r, props.m("osProfile"), "computer_name")
+
+	if diag := props.m("diagnosticsProfile", "bootDiagnostics"); diag != nil {
+		block := map[string]interface{}{
+			"enabled": diag.v("enabled") == true,
+		}
+		c.set(block, where, "storage_uri", diag.v("storageUri"))
+		r.body["boot_diagnostics"] = []interface{}{block}
+	}
+	if plan := r.armRaw.m("plan"); plan != nil {
+		block := map[string]interface{}{}
+		c.set(block, where, "name", plan.v("name"))
+		c.set(block, where, "publisher", plan.v("publisher"))
+		c.set(block, where, "product", plan.v("product"))
+		r.body["plan"] = []interface{}{block}
+	}
+}
+
+// extensionSettings renders extension settings as a JSON string, built with jsonencode()
+// so that ARM expressions within, e.g. the CSE commandToExecute, are evaluated by Terraform.
+func (c *terraformConverter) extensionSettings(where string, v interface{}) interface{} {
+	settings, ok := v.(map[string]interface{})
+	if !ok || len(settings) == 0 {
+		return nil
+	}
+	return "${jsonencode(" + c.expr(where, settings) + ")}"
+}
+
+func (c *terraformConverter) virtualMachineExtension(r *terraformResource, props armMap) {
+	where := r.address()
+	// an extension is named "<vm name>/<extension name>"
+	if node, err := parseARMValue(r.armRaw.s("name")); err == nil {
+		parts := flattenARMConcat(node)
+		for i, part := range parts {
+			lit, ok := part.(armStringLiteral)
+			if !ok || !strings.Contains(lit.value, "/") {
+				continue
+			}
+			idx := strings.Index(lit.value, "/")
+			vm := append(append([]armExprNode{}, parts[:i]...), armStringLiteral{value: lit.value[:idx]})
+			ext := append([]armExprNode{armStringLiteral{value: lit.value[idx+1:]}}, parts[i+1:]...)
+			vmName, err1 := c.hcl.template(armFunctionCall{name: "concat", args: vm})
+			extName, err2 := c.hcl.template(armFunctionCall{name: "concat", args: ext})
+			if err1 == nil && err2 == nil {
+				r.body["virtual_machine_name"] = vmName
+				r.body["name"] = extName
+			}
+			break
+		}
+	}
+	c.set(r.body, where, "publisher", props.v("publisher"))
+	c.set(r.body, where, "type", props.v("type"))
+	c.set(r.body, where, "type_handler_version", props.v("typeHandlerVersion"))
+	c.set(r.body, where, "auto_upgrade_minor_version", props.v("autoUpgradeMinorVersion"))
+	if s := c.extensionSettings(where+".settings", props.v("settings")); s != nil {
+		r.body["settings"] = s
+	}
+	if s := c.extensionSettings(where+".protected_settings", props.v("protectedSettings")); s != nil {
+		r.body["protected_settings"] = s
+	}
+}
+
+func (c *terraformConverter) virtualMachineScaleSet(r *terraformResource, props armMap) {
+	where := r.address()
+	if sku := r.armRaw.m("sku"); sku != nil {
+		block := map[string]interface{}{}
+		c.set(block, where+".sku", "name", sku.v("name"))
+		c.set(block, where+".sku", "tier", sku.v("tier"))
+		c.set(block, where+".sku", "capacity", sku.v("capacity"))
+		r.body["sku"] = []interface{}{block}
+	}
+	c.set(r.body, where, "upgrade_policy_mode", props.m("upgradePolicy").v("mode"))
+	c.set(r.body, where, "overprovision", props.v("overprovision"))
+	c.set(r.body, where, "single_placement_group", props.v("singlePlacementGroup"))
+	c.identity(r)
+
+	profile := props.m("virtualMachineProfile")
+	c.set(r.body, where, "priority", profile.v("priority"))
+	c.set(r.body, where, "eviction_policy", profile.v("evictionPolicy"))
+	c.osProfile(r, profile.m("osProfile"), "computer_name_prefix")
+
+	storage := profile.m("storageProfile")
+	if ref := c.imageReference(where+".storage_profile_image_reference", storage.m("imageReference")); ref != nil {
+		r.body["storage_profile_image_reference"] = []interface{}{ref}
+	}
+	if osDisk := storage.m("osDisk"); osDisk != nil {
+		block := c.disk(where+".storage_profile_os_disk", osDisk)
+		delete(block, "disk_size_gb")
+		r.body["storage_profile_os_disk"] = []interface{}{block}
+	}
+	var dataDisks []interface{}
+	for _, disk := range storage.list("dataDisks") {
+		block := c.disk(where+".storage_profile_data_disk", disk)
+		delete(block, "name")
+		dataDisks = append(dataDisks, block)
+	}
+	if len(dataDisks) > 0 {
+		r.body["storage_profile_data_disk"] = dataDisks
+	}
+
+	var nics []interface{}
+	for _, nic := range profile.m("networkProfile").list("networkInterfaceConfigurations") {
+		nw := where + ".network_profile"
+		nicProps := nic.m("properties")
+		block := map[string]interface{}{
+			"primary": nicProps.v("primary") == true,
+		}
+		c.set(block, nw, "name", nic.v("name"))
+		c.set(block, nw, "accelerated_networking", nicProps.v("enableAcceleratedNetworking"))
+		c.set(block, nw, "ip_forwarding", nicProps.v("enableIPForwarding"))
+		c.set(block, nw, "network_security_group_id", nicProps.m("networkSecurityGroup").v("id"))
+		var ipConfigs []interface{}
+		for _, ipConfig := range nicProps.list("ipConfigurations") {
+			ipProps := ipConfig.m("properties")
+			ib := map[string]interface{}{
+				"primary": ipProps.v("primary") == true,
+			}
+			c.set(ib, nw, "name", ipConfig.v("name"))
+			c.set(ib, nw, "subnet_id", ipProps.m("subnet").v("id"))
+			c.setIDs(ib, nw, "load_balancer_backend_address_pool_ids", ipProps.ids("loadBalancerBackendAddressPools"))
+			c.setIDs(ib, nw, "load_balancer_inbound_nat_rules_ids", ipProps.ids("loadBalancerInboundNatPools"))
+			c.setIDs(ib, nw, "application_gateway_backend_address_pool_ids", ipProps.ids("applicationGatewayBackendAddressPools"))
+			if pip := ipProps.m("publicIPAddressConfiguration"); pip != nil {
+				pb := map[string]interface{}{}
+				c.set(pb, nw, "name", pip.v("name"))
+				c.set(pb, nw, "idle_timeout", pip.m("properties").v("idleTimeoutInMinutes"))
+				c.set(pb, nw, "domain_name_label", pip.m("properties", "dnsSettings").v("domainNameLabel"))
+				ib["public_ip_address_configuration"] = []interface{}{pb}
+			}
+			if v := ipProps.v("privateIPAddressVersion"); v != nil {
+				c.set(ib, nw, "private_ip_address_version", v)
+			}
+			ipConfigs = append(ipConfigs, ib)
+		}
+		block["ip_configuration"] = ipConfigs
+		nics = append(nics, block)
+	}
+	if len(nics) > 0 {
+		r.body["network_profile"] = nics
+	}
+
+	var extensions []interface{}
+	for _, ext := range profile.m("extensionProfile").list("extensions") {
+		ew := where + ".extension"
+		extProps := ext.m("properties")
+		block := map[string]interface{}{}
+		c.set(block, ew, "name", ext.v("name"))
+		c.set(block, ew, "publisher", extProps.v("publisher"))
+		c.set(block, ew, "type", extProps.v("type"))
+		c.set(block, ew, "type_handler_version", extProps.v("typeHandlerVersion"))
+		c.set(block, ew, "auto_upgrade_minor_version", extProps.v("autoUpgradeMinorVersion"))
+		if s := c.extensionSettings(ew+".settings", extProps.v("settings")); s != nil {
+			block["settings"] = s
+		}
+		if s := c.extensionSettings(ew+".protected_settings", extProps.v("protectedSettings")); s != nil {
+			block["protected_settings"] = s
+		}
+		extensions = append(extensions, block)
+	}
+	if len(extensions) > 0 {
+		r.body["extension"] = extensions
+	}
+
+	if diag := profile.m("diagnosticsProfile", "bootDiagnostics"); diag != nil {
+		block := map[string]interface{}{
+			"enabled": diag.v("enabled") == true,
+		}
+		c.set(block, where, "storage_uri", diag.v("storageUri"))
+		r.body["boot_diagnostics"] = []interface{}{block}
+	}
+}
+
+func (c *terraformConverter) image(r *terraformResource, props armMap) {
+	where := r.address()
+	osDisk := props.m("storageProfile", "osDisk")
+	if osDisk == nil {
+		return
+	}
+	block := map[string]interface{}{}
+	c.set(block, where, "os_type", osDisk.v("osType"))
+	c.set(block, where, "os_state", osDisk.v("osState"))
+	c.set(block, where, "blob_uri", osDisk.v("blobUri"))
+	c.set(block, where, "caching", osDisk.v("caching"))
+	c.set(block, where, "size_gb", osDisk.v("diskSizeGB"))
+	r.body["os_disk"] = []interface{}{block}
+}
+
+func (c *terraformConverter) networkInterface(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "enable_ip_forwarding", props.v("enableIPForwarding"))
+	c.set(r.body, where, "enable_accelerated_networking", props.v("enableAcceleratedNetworking"))
+	c.set(r.body, where, "network_security_group_id", props.m("networkSecurityGroup").v("id"))
+	var ipConfigs []interface{}
+	for _, ipConfig := range props.list("ipConfigurations") {
+		iw := where + ".ip_configuration"
+		ipProps := ipConfig.m("properties")
+		block := map[string]interface{}{
+			"private_ip_address_allocation": "Dynamic",
+		}
+		c.set(block, iw, "name", ipConfig.v("name"))
+		c.set(block, iw, "subnet_id", ipProps.m("subnet").v("id"))
+		c.set(block, iw, "private_ip_address_allocation", ipProps.v("privateIPAllocationMethod"))
+		c.set(block, iw, "private_ip_address", ipProps.v("privateIPAddress"))
+		c.set(block, iw, "private_ip_address_version", ipProps.v("privateIPAddressVersion"))
+		c.set(block, iw, "primary", ipProps.v("primary"))
+		c.set(block, iw, "public_ip_address_id", ipProps.m("publicIPAddress").v("id"))
+		c.setIDs(block, iw, "load_balancer_backend_address_pools_ids", ipProps.ids("loadBalancerBackendAddressPools"))
+		c.setIDs(block, iw, "load_balancer_inbound_nat_rules_ids", ipProps.ids("loadBalancerInboundNatRules"))
+		c.setIDs(block, iw, "application_gateway_backend_address_pools_ids", ipProps.ids("applicationGatewayBackendAddressPools"))
+		ipConfigs = append(ipConfigs, block)
+	}
+	r.body["ip_configuration"] = ipConfigs
+}
+
+func (c *terraformConverter) securityRule(where string, rule armMap) map[string]interface{} {
+	props := rule.m("properties")
+	block := map[string]interface{}{}
+	c.set(block, where, "name", rule.v("name"))
+	for _, k := range [][2]string{
+		{"description", "description"},
+		{"protocol", "protocol"},
+		{"sourcePortRange", "source_port_range"},
+		{"destinationPortRange", "destination_port_range"},
+		{"sourceAddressPrefix", "source_address_prefix"},
+		{"destinationAddressPrefix", "destination_address_prefix"},
+		{"access", "access"},
+		{"priority", "priority"},
+		{"direction", "direction"},
+	} {
+		c.set(block, where, k[1], props.v(k[0]))
+	}
+	for _, k := range [][2]string{
+		{"sourcePortRanges", "source_port_ranges"},
+		{"destinationPortRanges", "destination_port_ranges"},
+		{"sourceAddressPrefixes", "source_address_prefixes"},
+		{"destinationAddressPrefixes", "destination_address_prefixes"},
+	} {
+		if list, ok := props.v(k[0]).([]interface{}); ok && len(list) > 0 {
+			block[k[1]] = c.value(where+"."+k[1], list)
+		}
+	}
+	return block
+}
+
+func (c *terraformConverter) networkSecurityGroup(r *terraformResource, props armMap) {
+	var rules []interface{}
+	for _, rule := range props.list("securityRules") {
+		rules = append(rules, c.securityRule(r.address()+".security_rule", rule))
+	}
+	if len(rules) > 0 {
+		r.body["security_rule"] = rules
+	}
+}
+
+func (c *terraformConverter) virtualNetwork(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "address_space", props.m("addressSpace").v("addressPrefixes"))
+	c.set(r.body, where, "dns_servers", props.m("dhcpOptions").v("dnsServers"))
+	var subnets []interface{}
+	for _, subnet := range props.list("subnets") {
+		sw := where + ".subnet"
+		subnetProps := subnet.m("properties")
+		block := map[string]interface{}{}
+		c.set(block, sw, "name", subnet.v("name"))
+		c.set(block, sw, "address_prefix", subnetProps.v("addressPrefix"))
+		c.set(block, sw, "security_group", subnetProps.m("networkSecurityGroup").v("id"))
+		subnets = append(subnets, block)
+	}
+	if len(subnets) > 0 {
+		r.body["subnet"] = subnets
+	}
+}
+
+func (c *terraformConverter) routeTable(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "disable_bgp_route_propagation", props.v("disableBgpRoutePropagation"))
+	var routes []interface{}
+	for _, route := range props.list("routes") {
+		rw := where + ".route"
+		routeProps := route.m("properties")
+		block := map[string]interface{}{}
+		c.set(block, rw, "name", route.v("name"))
+		c.set(block, rw, "address_prefix", routeProps.v("addressPrefix"))
+		c.set(block, rw, "next_hop_type", routeProps.v("nextHopType"))
+		c.set(block, rw, "next_hop_in_ip_address", routeProps.v("nextHopIpAddress"))
+		routes = append(routes, block)
+	}
+	if len(routes) > 0 {
+		r.body["route"] = routes
+	}
+}
+
+func (c *terraformConverter) publicIP(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "allocation_method", props.v("publicIPAllocationMethod"))
+	c.set(r.body, where, "ip_version", props.v("publicIPAddressVersion"))
+	c.set(r.body, where, "idle_timeout_in_minutes", props.v("idleTimeoutInMinutes"))
+	c.set(r.body, where, "domain_name_label", props.m("dnsSettings").v("domainNameLabel"))
+	c.set(r.body, where, "sku", r.armRaw.m("sku").v("name"))
+}
+
+// loadBalancer translates a load balancer. Terraform declares backend pools, probes and rules
+// as resources of their own, so those are added next to the azurerm_lb resource.
+func (c *terraformConverter) loadBalancer(r *terraformResource, props armMap) {
+	where := r.address()
+	c.set(r.body, where, "sku", r.armRaw.m("sku").v("name"))
+	lbID := fmt.Sprintf("${%s.id}", r.address())
+
+	var frontends []interface{}
+	var frontendName interface{}
+	for _, frontend := range props.list("frontendIPConfigurations") {
+		fw := where + ".frontend_ip_configuration"
+		frontendProps := frontend.m("properties")
+		block := map[string]interface{}{}
+		c.set(block, fw, "name", frontend.v("name"))
+		c.set(block, fw, "subnet_id", frontendProps.m("subnet").v("id"))
+		c.set(block, fw, "private_ip_address", frontendProps.v("privateIPAddress"))
+		c.set(block, fw, "private_ip_address_allocation", frontendProps.v("privateIPAllocationMethod"))
+		c.set(block, fw, "public_ip_address_id", frontendProps.m("publicIPAddress").v("id"))
+		if zones, ok := frontend.v("zones").([]interface{}); ok && len(zones) > 0 {
+			block["zones"] = c.value(fw+".zones", zones)
+		}
+		frontends = append(frontends, block)
+		frontendName = block["name"]
+	}
+	if len(frontends) > 0 {
+		r.body["frontend_ip_configuration"] = frontends
+	}
+
+	child := func(resourceType string, armName interface{}, suffix string) map[string]interface{} {
+		name, _ := armName.(string)
+		child := &terraformResource{
+			resourceType: resourceType,
+			label:        c.uniqueLabel(resourceType, strings.TrimRight(r.label+"_"+suffix, "_")),
+			armRaw:       armMap{},
+		}
+		child.body = map[string]interface{}{
+			"name":                c.str(child.address()+".name", name),
+			"resource_group_name": r.body["resource_group_name"],
+			"loadbalancer_id":     lbID,
+		}
+		c.resources = append(c.resources, child)
+		return child.body
+	}
+
+	for _, pool := range props.list("backendAddressPools") {
+		child("azurerm_lb_backend_address_pool", pool.v("name"), terraformLabel(pool.s("name"), ""))
+	}
+	for _, probe := range props.list("probes") {
+		body := child("azurerm_lb_probe", probe.v("name"), terraformLabel(probe.s("name"), ""))
+		probeProps := probe.m("properties")
+		c.set(body, where, "protocol", probeProps.v("protocol"))
+		c.set(body, where, "port", probeProps.v("port"))
+		c.set(body, where, "request_path", probeProps.v("requestPath"))
+		c.set(body, where, "interval_in_seconds", probeProps.v("intervalInSeconds"))
+		c.set(body, where, "number_of_probes", probeProps.v("numberOfProbes"))
+	}
+	for _, rule := range props.list("loadBalancingRules") {
+		body := child("azurerm_lb_rule", rule.v("name"), terraformLabel(rule.s("name"), ""))
+		ruleProps := rule.m("properties")
+		body["frontend_ip_configuration_name"] = frontendName
+		c.set(body, where, "protocol", ruleProps.v("protocol"))
+		c.set(body, where, "frontend_port", ruleProps.v("frontendPort"))
+		c.set(body, where, "backend_port", ruleProps.v("backendPort"))
+		c.set(body, where, "backend_address_pool_id", ruleProps.m("backendAddressPool").v("id"))
+		c.set(body, where, "probe_id", ruleProps.m("probe").v("id"))
+		c.set(body, where, "enable_floating_ip", ruleProps.v("enableFloatingIP"))
+		c.set(body, where, "idle_timeout_in_minutes", ruleProps.v("idleTimeoutInMinutes"))
+		c.set(body, where, "load_distribution", ruleProps.v("loadDistribution"))
+		c.set(body, where, "disable_outbound_snat", ruleProps.v("disableOutboundSnat"))
+	}
+	for _, rule := range props.list("inboundNatRules") {
+		body := child("azurerm_lb_nat_rule", rule.v("name"), terraformLabel(rule.s("name"), ""))
+		ruleProps := rule.m("properties")
+		body["frontend_ip_configuration_name"] = frontendName
+		c.set(body, where, "protocol", ruleProps.v("protocol"))
+		c.set(body, where, "frontend_port", ruleProps.v("frontendPort"))
+		c.set(body, where, "backend_port", ruleProps.v("backendPort"))
+		c.set(body, where, "enable_floating_ip", ruleProps.v("enableFloatingIP"))
+	}
+	for _, rule := range props.list("outboundRules") {
+		body := child("azurerm_lb_outbound_rule", rule.v("name"), terraformLabel(rule.s("name"), ""))
+		ruleProps := rule.m("properties")
+		var frontendConfigs []interface{}
+		for range ruleProps.list("frontendIPConfigurations") {
+			frontendConfigs = append(frontendConfigs, map[string]interface{}{"name": frontendName})
+		}
+		body["frontend_ip_configuration"] = frontendConfigs
+		c.set(body, where, "protocol", ruleProps.v("protocol"))
+		c.set(body, where, "backend_address_pool_id", ruleProps.m("backendAddressPool").v("id"))
+		c.set(body, where, "allocated_outbound_ports", ruleProps.v("allocatedOutboundPorts"))
+		c.set(body, where, "idle_timeout_in_minutes", ruleProps.v("idleTimeoutInMinutes"))
+		c.set(body, where, "enable_tcp_reset", ruleProps.v("enableTcpReset"))
+	}
+}
+
+func (c *terraformConverter) storageAccount(r *terraformResource, raw armMap) {
+	where := r.address()
+	// ARM SKU names are "<tier>_<replication>", e.g. Standard_LRS
+	if sku := raw.m("sku").s("name"); sku != "" {
+		if node, err := parseARMValue(sku); err == nil {
+			if lit, ok := node.(armStringLiteral); ok && strings.Contains(lit.value, "_") {
+				parts := strings.SplitN(lit.value, "_", 2)
+				r.body["account_tier"] = parts[0]
+				r.body["account_replication_type"] = parts[1]
+			} else if e, err := c.hcl.expression(node); err == nil {
+				r.body["account_tier"] = fmt.Sprintf(`${split("_", %s)[0]}`, e)
+				r.body["account_replication_type"] = fmt.Sprintf(`${split("_", %s)[1]}`, e)
+			} else {
+				c.unresolved(where+".sku", sku, err)
+			}
+		}
+	}
+	c.set(r.body, where, "account_kind", raw.v("kind"))
+}
+
+func (c *terraformConverter) roleAssignment(r *terraformResource, props armMap) {
+	where := r.address()
+	scope := props.v("scope")
+	if scope == nil {
+		scope = "[resourceGroup().id]"
+	}
+	c.set(r.body, where, "scope", scope)
+	c.set(r.body, where, "role_definition_id", props.v("roleDefinitionId"))
+	c.set(r.body, where, "principal_id", props.v("principalId"))
+}
@@ -0,0 +1,265 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2018-02-14/keyvault"
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-09-01-preview/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/preview/msi/mgmt/2015-08-31-preview/msi"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/go-cmp/cmp"
+	"github.com/leonelquinteros/gotext"
+)
+
+func loadTerraformTestContainerService(t *testing.T, apiModelFilename string) *api.ContainerService {
+	t.Helper()
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	i18n.Initialize(locale)
+	apiloader := &api.Apiloader{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	containerService, _, err := apiloader.LoadContainerServiceFromFile(apiModelFilename, true, false, nil)
+	if err != nil {
+		t.Fatalf("Loading file %s got error: %s", apiModelFilename, err.Error())
+	}
+	containerService.Location = "westus2"
+	if _, err = containerService.SetPropertiesDefaults(false, false); err != nil {
+		t.Fatalf("unexpected error setting defaults for %s: %s", apiModelFilename, err.Error())
+	}
+	return containerService
+}
+
+func generateTerraformConfigFromFile(t *testing.T, apiModelFilename string) (map[string]interface{}, *TerraformReport, string) {
+	t.Helper()
+	containerService := loadTerraformTestContainerService(t, apiModelFilename)
+	locale := gotext.NewLocale(path.Join("..", "..", "translations"), "en_US")
+	ctx := Context{
+		Translator: &i18n.Translator{
+			Locale: locale,
+		},
+	}
+	templateGenerator, err := InitializeTemplateGenerator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configRaw, report, err := templateGenerator.GenerateTerraformConfig(containerService, DefaultGeneratorCode, TestAKSEngineVersion)
+	if err != nil {
+		t.Fatalf("unexpected error generating Terraform config for %s: %s", apiModelFilename, err.Error())
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(configRaw), &config); err != nil {
+		t.Fatalf("generated Terraform config for %s is not valid JSON: %s", apiModelFilename, err.Error())
+	}
+	return config, report, configRaw
+}
+
+func TestGenerateTerraformConfig(t *testing.T) {
+	config, report, raw := generateTerraformConfigFromFile(t, "./testdata/simple/kubernetes.json")
+	if !report.IsEmpty() {
+		t.Errorf("expected a complete translation, got report %+v", report)
+	}
+
+	// output must be stable across runs
+	for i := 0; i < 3; i++ {
+		_, _, again := generateTerraformConfigFromFile(t, "./testdata/simple/kubernetes.json")
+		if again != raw {
+			t.Fatalf("generated Terraform config is not deterministic")
+		}
+	}
+
+	resources := config["resource"].(map[string]interface{})
+	for _, resourceType := range []string{
+		"azurerm_availability_set",
+		"azurerm_lb",
+		"azurerm_lb_backend_address_pool",
+		"azurerm_lb_rule",
+		"azurerm_network_interface",
+		"azurerm_network_security_group",
+		"azurerm_public_ip",
+		"azurerm_virtual_machine",
+		"azurerm_virtual_machine_extension",
+		"azurerm_virtual_network",
+	} {
+		if _, ok := resources[resourceType]; !ok {
+			t.Errorf("expected resources of type %s", resourceType)
+		}
+	}
+
+	master := resources["azurerm_virtual_machine"].(map[string]interface{})["mastervmnameprefix"].(map[string]interface{})
+	if master["count"] != "${(local.masterCount - local.masterOffset)}" {
+		t.Errorf("unexpected master count %v", master["count"])
+	}
+	expectedDependsOn := []interface{}{"azurerm_network_interface.mastervmnameprefix_nic", "azurerm_availability_set.masteravailabilityset"}
+	if diff := cmp.Diff(expectedDependsOn, master["depends_on"]); diff != "" {
+		t.Errorf("unexpected diff in master depends_on: %s", diff)
+	}
+
+	cse := resources["azurerm_virtual_machine_extension"].(map[string]interface{})["mastervmnameprefix_cse_master"].(map[string]interface{})
+	if !strings.HasPrefix(cse["protected_settings"].(string), `${jsonencode({"commandToExecute" = "`) {
+		t.Errorf("expected CSE protected_settings to be built with jsonencode, got %.80s", cse["protected_settings"])
+	}
+
+	data := config["data"].(map[string]interface{})
+	if _, ok := data["azurerm_resource_group"].(map[string]interface{})[terraformResourceGroupLabel]; !ok {
+		t.Errorf("expected the %s resource group data source", terraformResourceGroupLabel)
+	}
+	variables := config["variable"].(map[string]interface{})
+	if _, ok := variables[terraformResourceGroupVariable]; !ok {
+		t.Errorf("expected the %s variable", terraformResourceGroupVariable)
+	}
+	if _, ok := config["//"]; ok {
+		t.Errorf("expected no unmapped resources comment")
+	}
+}
+
+func TestGenerateTerraformConfigCustomData(t *testing.T) {
+	config, _, _ := generateTerraformConfigFromFile(t, "./testdata/simple/kubernetes.json")
+
+	// the literal parts of customData must be carried over byte for byte, in order
+	cs := loadTerraformTestContainerService(t, "./testdata/simple/kubernetes.json")
+	var armCustomData string
+	for _, resource := range GenerateARMResources(cs) {
+		if vm, ok := resource.(VirtualMachineARM); ok && strings.Contains(*vm.Name, "masterVMNamePrefix") {
+			armCustomData = *vm.OsProfile.CustomData
+		}
+	}
+	node, err := parseARMValue(armCustomData)
+	if err != nil {
+		t.Fatalf("unexpected error parsing customData: %s", err)
+	}
+	call, ok := node.(armFunctionCall)
+	if !ok || call.name != "base64" {
+		t.Fatalf("expected customData to be base64(...), got %T", node)
+	}
+
+	resources := config["resource"].(map[string]interface{})
+	master := resources["azurerm_virtual_machine"].(map[string]interface{})["mastervmnameprefix"].(map[string]interface{})
+	customData := master["os_profile"].([]interface{})[0].(map[string]interface{})["custom_data"].(string)
+	offset := 0
+	for _, part := range flattenARMConcat(call.args[0]) {
+		lit, ok := part.(armStringLiteral)
+		if !ok {
+			continue
+		}
+		escaped := escapeHCLTemplate(lit.value)
+		i := strings.Index(customData[offset:], escaped)
+		if i < 0 {
+			t.Fatalf("customData literal not found in order: %.80q", lit.value)
+		}
+		offset += i + len(escaped)
+	}
+}
+
+func TestTerraformConverterUnmappedResources(t *testing.T) {
+	keyVault := KeyVaultARM{
+		ARMResource: ARMResource{APIVersion: "[variables('apiVersionKeyVault')]"},
+		Vault: keyvault.Vault{
+			Name:     to.StringPtr("[variables('clusterKeyVaultName')]"),
+			Type:     to.StringPtr("Microsoft.KeyVault/vaults"),
+			Location: to.StringPtr("[variables('location')]"),
+		},
+	}
+	identity := UserAssignedIdentitiesARM{
+		ARMResource: ARMResource{
+			APIVersion: "[variables('apiVersionManagedIdentity')]",
+			DependsOn:  []string{"[concat('Microsoft.KeyVault/vaults/', variables('clusterKeyVaultName'))]"},
+		},
+		Identity: msi.Identity{
+			Name:     to.StringPtr("[variables('userAssignedID')]"),
+			Type:     "Microsoft.ManagedIdentity/userAssignedIdentities",
+			Location: to.StringPtr("[variables('location')]"),
+		},
+	}
+
+	c := newTerraformConverter(map[string]interface{}{})
+	config, err := c.convert(map[string]interface{}{}, paramsMap{}, map[string]interface{}{}, []interface{}{keyVault, identity}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &TerraformReport{
+		UnmappedResources:      []string{"Microsoft.KeyVault/vaults [variables('clusterKeyVaultName')]"},
+		UnresolvedDependencies: []string{"azurerm_user_assigned_identity.userassignedid: [concat('Microsoft.KeyVault/vaults/', variables('clusterKeyVaultName'))]"},
+	}
+	if diff := cmp.Diff(expected, c.report); diff != "" {
+		t.Errorf("unexpected diff in report: %s", diff)
+	}
+	if !strings.Contains(config["//"].(string), "Microsoft.KeyVault/vaults") {
+		t.Errorf("expected the unmapped key vault to be noted in the config, got %q", config["//"])
+	}
+
+	resources := config["resource"].(map[string]map[string]interface{})
+	expectedIdentity := map[string]interface{}{
+		"name":                "${local.userAssignedID}",
+		"location":            "${local.location}",
+		"resource_group_name": "${data.azurerm_resource_group.cluster.name}",
+	}
+	if diff := cmp.Diff(expectedIdentity, resources["azurerm_user_assigned_identity"]["userassignedid"]); diff != "" {
+		t.Errorf("unexpected diff in identity: %s", diff)
+	}
+}
+
+func TestTerraformConverterReferences(t *testing.T) {
+	variables := map[string]interface{}{
+		"masterVMNamePrefix": "k8s-master-12345-",
+		"vmName":             "[concat(variables('masterVMNamePrefix'), copyIndex(variables('masterOffset')))]",
+	}
+	vm := VirtualMachineARM{
+		ARMResource: ARMResource{
+			Copy: map[string]string{"count": "[variables('masterCount')]", "name": "vmLoopNode"},
+		},
+		VirtualMachine: compute.VirtualMachine{
+			Name:     to.StringPtr("[concat(variables('masterVMNamePrefix'), copyIndex(variables('masterOffset')))]"),
+			Type:     to.StringPtr("Microsoft.Compute/virtualMachines"),
+			Location: to.StringPtr("[variables('location')]"),
+		},
+	}
+	roleAssignment := RoleAssignmentARM{
+		ARMResource: ARMResource{
+			Copy:      map[string]string{"count": "[variables('masterCount')]", "name": "vmLoopNode"},
+			DependsOn: []string{"[concat('Microsoft.Compute/virtualMachines/', variables('masterVMNamePrefix'), copyIndex())]"},
+		},
+		RoleAssignment: authorization.RoleAssignment{
+			Name: to.StringPtr("[guid(concat('Microsoft.Compute/virtualMachines/', variables('masterVMNamePrefix'), copyIndex(), 'vmidentity'))]"),
+			Type: to.StringPtr("Microsoft.Authorization/roleAssignments"),
+			RoleAssignmentPropertiesWithScope: &authorization.RoleAssignmentPropertiesWithScope{
+				RoleDefinitionID: to.StringPtr("[variables('contributorRoleDefinitionId')]"),
+				PrincipalID:      to.StringPtr("[reference(variables('vmName'), '2017-03-30', 'Full').identity.principalId]"),
+			},
+		},
+	}
+
+	c := newTerraformConverter(variables)
+	config, err := c.convert(map[string]interface{}{}, paramsMap{}, variables, []interface{}{vm, roleAssignment}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !c.report.IsEmpty() {
+		t.Errorf("expected a complete translation, got report %+v", c.report)
+	}
+
+	resources := config["resource"].(map[string]map[string]interface{})
+	expected := map[string]interface{}{
+		"count":              "${local.masterCount}",
+		"scope":              "${data.azurerm_resource_group.cluster.id}",
+		"role_definition_id": "${local.contributorRoleDefinitionId}",
+		"principal_id":       "${azurerm_virtual_machine.mastervmnameprefix[count.index].identity[0].principal_id}",
+		"depends_on":         []string{"azurerm_virtual_machine.mastervmnameprefix"},
+	}
+	for label := range resources["azurerm_role_assignment"] {
+		if diff := cmp.Diff(expected, resources["azurerm_role_assignment"][label]); diff != "" {
+			t.Errorf("unexpected diff in role assignment: %s", diff)
+		}
+	}
+}
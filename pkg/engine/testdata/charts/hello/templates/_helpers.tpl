@@ -0,0 +1,10 @@
+{{- define "hello.fullname" -}}
+{{- printf "%s-%s" .Release.Name .Chart.Name | trunc 63 | trimSuffix "-" -}}
+{{- end -}}
+{{- define "hello.deployment.apiVersion" -}}
+{{- if semverCompare ">=1.9-0" .Capabilities.KubeVersion.GitVersion -}}
+apps/v1
+{{- else -}}
+extensions/v1beta1
+{{- end -}}
+{{- end -}}
@@ -21,6 +21,7 @@ const (
 	imageReferenceFieldName       = "imageReference"
 	nameFieldName                 = "name"
 	osProfileFieldName            = "osProfile"
+	outputsFieldName              = "outputs"
 	propertiesFieldName           = "properties"
 	resourcesFieldName            = "resources"
 	storageProfileFieldName       = "storageProfile"
@@ -213,6 +214,30 @@ func (t *Transformer) NormalizeForK8sVMASScalingUp(logger *logrus.Entry, templat
 	return nil
 }
 
+// NormalizeForK8sIdentityMigration takes a template and removes all the resources but the user assigned identity of the
+// cluster and its role assignments, to grant an existing cluster the roles of a managed identity
+func (t *Transformer) NormalizeForK8sIdentityMigration(logger *logrus.Entry, templateMap map[string]interface{}) error {
+	logger.Debugf("Running NormalizeForK8sIdentityMigration...")
+	indexesToRemove := []int{}
+	resources := templateMap[resourcesFieldName].([]interface{})
+	for index, resource := range resources {
+		resourceMap, ok := resource.(map[string]interface{})
+		if !ok {
+			logger.Warnf("Template improperly formatted for resource")
+			continue
+		}
+
+		resourceName, _ := resourceMap[nameFieldName].(string)
+		if !strings.Contains(resourceName, "variables('userAssignedID')") {
+			indexesToRemove = append(indexesToRemove, index)
+		}
+	}
+	templateMap[resourcesFieldName] = removeIndexesFromArray(resources, indexesToRemove)
+	// the outputs reference resources which are not deployed anymore
+	delete(templateMap, outputsFieldName)
+	return nil
+}
+
 func removeIndexesFromArray(array []interface{}, indexes []int) []interface{} {
 	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
 	for _, index := range indexes {
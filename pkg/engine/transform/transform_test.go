@@ -148,6 +148,33 @@ func TestNormalizeForK8sSLBScalingOrUpgradeVMSS(t *testing.T) {
 	ValidateTemplate(templateMap, expectedFileContents, "TestNormalizeForK8sSLBScalingOrUpgradeVMSS")
 }
 
+func TestNormalizeForK8sIdentityMigration(t *testing.T) {
+	RegisterTestingT(t)
+	logger := logrus.New().WithField("testName", "TestNormalizeForK8sIdentityMigration")
+	templateJSON := `{
+	"resources": [
+		{"type": "Microsoft.Network/virtualNetworks", "name": "[variables('virtualNetworkName')]"},
+		{"type": "Microsoft.ManagedIdentity/userAssignedIdentities", "name": "[variables('userAssignedID')]"},
+		{"type": "Microsoft.Authorization/roleAssignments", "name": "[guid(concat(variables('userAssignedID'), 'roleAssignment', resourceGroup().id))]"},
+		{"type": "Microsoft.Compute/virtualMachines", "name": "[concat(variables('masterVMNamePrefix'), copyIndex(variables('masterOffset')))]"},
+		{"type": "Microsoft.Resources/deployments", "name": "[concat(variables('userAssignedID'), '-vnet-role-assignment')]"}
+	],
+	"outputs": {"masterFQDN": {"type": "string", "value": "[reference(concat('Microsoft.Network/publicIPAddresses/', variables('masterPublicIPAddressName'))).dnsSettings.fqdn]"}}
+}`
+	var template interface{}
+	json.Unmarshal([]byte(templateJSON), &template)
+	templateMap := template.(map[string]interface{})
+	transformer := Transformer{}
+	e := transformer.NormalizeForK8sIdentityMigration(logger, templateMap)
+	Expect(e).To(BeNil())
+	Expect(templateMap).NotTo(HaveKey("outputs"))
+	var types []string
+	for _, resource := range templateMap["resources"].([]interface{}) {
+		types = append(types, resource.(map[string]interface{})["type"].(string))
+	}
+	Expect(types).To(Equal([]string{"Microsoft.ManagedIdentity/userAssignedIdentities", "Microsoft.Authorization/roleAssignments", "Microsoft.Resources/deployments"}))
+}
+
 func ValidateTemplate(templateMap map[string]interface{}, expectedFileContents []byte, testFileName string) {
 	output, e := helpers.JSONMarshal(templateMap, false)
 	Expect(e).To(BeNil())
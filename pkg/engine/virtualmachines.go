@@ -169,7 +169,7 @@ func CreateMasterVM(cs *api.ContainerService) VirtualMachineARM {
 	imgReference := &compute.ImageReference{}
 	if cs.Properties.MasterProfile.HasImageRef() {
 		if cs.Properties.MasterProfile.HasImageGallery() {
-			imgReference.ID = to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', parameters('osImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('osImageName'), '/versions/', '%s')]", imageRef.SubscriptionID, imageRef.Gallery, imageRef.Version))
+			imgReference.ID = to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', parameters('osImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('osImageName')%s)]", imageRef.SubscriptionID, imageRef.Gallery, getGalleryImageVersionArgs(imageRef)))
 		} else {
 			imgReference.ID = to.StringPtr("[resourceId(parameters('osImageResourceGroup'), 'Microsoft.Compute/images', parameters('osImageName'))]")
 		}
@@ -371,7 +371,7 @@ func createAgentAvailabilitySetVM(cs *api.ContainerService, profile *api.AgentPo
 	addCustomTagsToVM(profile.CustomVMTags, &virtualMachine)
 
 	if useManagedIdentity {
-		if userAssignedIDEnabled && !profile.IsWindows() {
+		if userAssignedIDEnabled {
 			virtualMachine.Identity = &compute.VirtualMachineIdentity{
 				Type: compute.ResourceIdentityTypeUserAssigned,
 				UserAssignedIdentities: map[string]*compute.VirtualMachineIdentityUserAssignedIdentitiesValue{
@@ -463,6 +463,8 @@ func createAgentAvailabilitySetVM(cs *api.ContainerService, profile *api.AgentPo
 			storageProfile.ImageReference = &compute.ImageReference{
 				ID: to.StringPtr(fmt.Sprintf("[resourceId('Microsoft.Compute/images','%sCustomWindowsImage')]", profile.Name)),
 			}
+		} else if cs.Properties.WindowsProfile.HasImageRef() {
+			storageProfile.ImageReference = getWindowsImageReference(cs.Properties.WindowsProfile)
 		} else {
 			storageProfile.ImageReference = &compute.ImageReference{
 				Offer:     to.StringPtr("[parameters('agentWindowsOffer')]"),
@@ -481,7 +483,7 @@ func createAgentAvailabilitySetVM(cs *api.ContainerService, profile *api.AgentPo
 		if profile.HasImageRef() {
 			if profile.HasImageGallery() {
 				storageProfile.ImageReference = &compute.ImageReference{
-					ID: to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', parameters('%sosImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('%sosImageName'), '/versions/', '%s')]", imageRef.SubscriptionID, profile.Name, imageRef.Gallery, profile.Name, imageRef.Version)),
+					ID: to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', parameters('%sosImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('%sosImageName')%s)]", imageRef.SubscriptionID, profile.Name, imageRef.Gallery, profile.Name, getGalleryImageVersionArgs(imageRef))),
 				}
 			} else {
 				storageProfile.ImageReference = &compute.ImageReference{
@@ -532,6 +534,27 @@ func createAgentAvailabilitySetVM(cs *api.ContainerService, profile *api.AgentPo
 	}
 }
 
+// getWindowsImageReference returns the managed image, or the Shared Image Gallery image version, of the Windows agents
+func getWindowsImageReference(w *api.WindowsProfile) *compute.ImageReference {
+	if w.HasImageGallery() {
+		return &compute.ImageReference{
+			ID: to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', parameters('agentWindowsImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('agentWindowsImageName')%s)]", w.ImageRef.SubscriptionID, w.ImageRef.Gallery, getGalleryImageVersionArgs(w.ImageRef))),
+		}
+	}
+	return &compute.ImageReference{
+		ID: to.StringPtr("[resourceId(parameters('agentWindowsImageResourceGroup'), 'Microsoft.Compute/images', parameters('agentWindowsImageName'))]"),
+	}
+}
+
+// getGalleryImageVersionArgs returns the arguments of the concat expression of a Shared Image Gallery image ID which
+// append its version. A version left to latest is not appended, and Azure deploys the latest version of the image.
+func getGalleryImageVersionArgs(imageRef *api.ImageReference) string {
+	if imageRef.IsLatestVersion() {
+		return ""
+	}
+	return fmt.Sprintf(", '/versions/', '%s'", imageRef.Version)
+}
+
 func getArmDataDisks(profile *api.AgentPoolProfile) *[]compute.DataDisk {
 	var dataDisks []compute.DataDisk
 	for i, diskSize := range profile.DiskSizesGB {
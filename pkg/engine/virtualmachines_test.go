@@ -297,6 +297,24 @@ func TestCreateAgentAvailabilitySetVM(t *testing.T) {
 	if diff != "" {
 		t.Errorf("unexpected diff while expecting equal structs: %s", diff)
 	}
+
+	// Windows agents get the user assigned identity of the cluster, the same as Linux agents
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UseManagedIdentity = true
+	cs.Properties.OrchestratorProfile.KubernetesConfig.UserAssignedID = "fooAssignedID"
+
+	actualVM = createAgentAvailabilitySetVM(cs, profile)
+	expectedIdentity := &compute.VirtualMachineIdentity{
+		Type: compute.ResourceIdentityTypeUserAssigned,
+		UserAssignedIdentities: map[string]*compute.VirtualMachineIdentityUserAssignedIdentitiesValue{
+			"[variables('userAssignedIDReference')]": {},
+		},
+	}
+
+	diff = cmp.Diff(actualVM.Identity, expectedIdentity)
+
+	if diff != "" {
+		t.Errorf("unexpected diff while expecting equal structs: %s", diff)
+	}
 }
 
 func TestCreateVmWithCustomTags(t *testing.T) {
@@ -678,3 +696,67 @@ func TestCreateVMsWithCustomOS(t *testing.T) {
 		t.Error("expected custom OS to have image ref")
 	}
 }
+
+func TestGetWindowsImageReference(t *testing.T) {
+	cases := []struct {
+		name     string
+		profile  *api.WindowsProfile
+		expected *compute.ImageReference
+	}{
+		{
+			name: "managed image",
+			profile: &api.WindowsProfile{
+				ImageRef: &api.ImageReference{
+					Name:          "windows-image",
+					ResourceGroup: "images-rg",
+				},
+			},
+			expected: &compute.ImageReference{
+				ID: to.StringPtr("[resourceId(parameters('agentWindowsImageResourceGroup'), 'Microsoft.Compute/images', parameters('agentWindowsImageName'))]"),
+			},
+		},
+		{
+			name: "shared image gallery",
+			profile: &api.WindowsProfile{
+				ImageRef: &api.ImageReference{
+					Name:           "windows-image",
+					ResourceGroup:  "images-rg",
+					SubscriptionID: "00000000-0000-0000-0000-000000000000",
+					Gallery:        "gallery",
+					Version:        "1.0.0",
+				},
+			},
+			expected: &compute.ImageReference{
+				ID: to.StringPtr("[concat('/subscriptions/', '00000000-0000-0000-0000-000000000000', '/resourceGroups/', parameters('agentWindowsImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', 'gallery', '/images/', parameters('agentWindowsImageName'), '/versions/', '1.0.0')]"),
+			},
+		},
+		{
+			name: "shared image gallery latest version",
+			profile: &api.WindowsProfile{
+				ImageRef: &api.ImageReference{
+					Name:           "windows-image",
+					ResourceGroup:  "images-rg",
+					SubscriptionID: "00000000-0000-0000-0000-000000000000",
+					Gallery:        "gallery",
+					Version:        "latest",
+				},
+			},
+			expected: &compute.ImageReference{
+				ID: to.StringPtr("[concat('/subscriptions/', '00000000-0000-0000-0000-000000000000', '/resourceGroups/', parameters('agentWindowsImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', 'gallery', '/images/', parameters('agentWindowsImageName'))]"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if !c.profile.HasImageRef() {
+				t.Fatal("expected the windows profile to have an image ref")
+			}
+			if diff := cmp.Diff(getWindowsImageReference(c.profile), c.expected); diff != "" {
+				t.Errorf("unexpected diff while expecting equal image references: %s", diff)
+			}
+		})
+	}
+}
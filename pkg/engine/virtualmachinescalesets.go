@@ -35,7 +35,9 @@ func CreateMasterVMSS(cs *api.ContainerService) VirtualMachineScaleSetARM {
 	var dependencies []string
 
 	if isCustomVnet {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
@@ -233,7 +235,7 @@ func CreateMasterVMSS(cs *api.ContainerService) VirtualMachineScaleSetARM {
 	imgReference := &compute.ImageReference{}
 	if masterProfile.HasImageRef() {
 		if masterProfile.HasImageGallery() {
-			imgReference.ID = to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s',  '/resourceGroups/', parameters('osImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('osImageName'), '/versions/', '%s')]", imageRef.SubscriptionID, imageRef.Gallery, imageRef.Version))
+			imgReference.ID = to.StringPtr(fmt.Sprintf("[concat('/subscriptions/', '%s',  '/resourceGroups/', parameters('osImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', parameters('osImageName')%s)]", imageRef.SubscriptionID, imageRef.Gallery, getGalleryImageVersionArgs(imageRef)))
 		} else {
 			imgReference.ID = to.StringPtr("[resourceId(parameters('osImageResourceGroup'), 'Microsoft.Compute/images', parameters('osImageName'))]")
 		}
@@ -347,13 +349,16 @@ func CreateAgentVMSS(cs *api.ContainerService, profile *api.AgentPoolProfile) Vi
 	var dependencies []string
 
 	if profile.IsCustomVNET() {
-		dependencies = append(dependencies, "[variables('nsgID')]")
+		if cs.Properties.GetCustomNetworkSecurityGroupID() == "" {
+			dependencies = append(dependencies, "[variables('nsgID')]")
+		}
 	} else {
 		dependencies = append(dependencies, "[variables('vnetID')]")
 	}
 
 	if !cs.Properties.OrchestratorProfile.IsPrivateCluster() &&
 		profile.LoadBalancerBackendAddressPoolIDs == nil &&
+		!cs.Properties.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() &&
 		cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku == api.StandardLoadBalancerSku {
 		dependencies = append(dependencies, "[variables('agentLbID')]")
 	}
@@ -480,6 +485,7 @@ func CreateAgentVMSS(cs *api.ContainerService, profile *api.AgentPoolProfile) Vi
 				}
 			} else {
 				if !cs.Properties.OrchestratorProfile.IsPrivateCluster() &&
+					!cs.Properties.OrchestratorProfile.KubernetesConfig.IsUserDefinedRouting() &&
 					cs.Properties.OrchestratorProfile.KubernetesConfig.LoadBalancerSku == api.StandardLoadBalancerSku {
 					agentLbBackendAddressPools := compute.SubResource{
 						ID: to.StringPtr("[concat(variables('agentLbID'), '/backendAddressPools/', variables('agentLbBackendPoolName'))]"),
@@ -489,7 +495,7 @@ func CreateAgentVMSS(cs *api.ContainerService, profile *api.AgentPoolProfile) Vi
 			}
 
 			ipConfigProps.LoadBalancerBackendAddressPools = &backendAddressPools
-			if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+			if cs.Properties.IsIPv6DualStack() {
 				defaultIPv4BackendPool := compute.SubResource{
 					ID: to.StringPtr("[concat(resourceId('Microsoft.Network/loadBalancers',parameters('masterEndpointDNSNamePrefix')), '/backendAddressPools/', parameters('masterEndpointDNSNamePrefix'))]"),
 				}
@@ -515,7 +521,7 @@ func CreateAgentVMSS(cs *api.ContainerService, profile *api.AgentPoolProfile) Vi
 		ipconfig.VirtualMachineScaleSetIPConfigurationProperties = &ipConfigProps
 		ipConfigurations = append(ipConfigurations, ipconfig)
 
-		if cs.Properties.FeatureFlags.IsFeatureEnabled("EnableIPv6DualStack") {
+		if cs.Properties.IsIPv6DualStack() {
 			ipconfigv6 := compute.VirtualMachineScaleSetIPConfiguration{
 				Name: to.StringPtr(fmt.Sprintf("ipconfig%dv6", i)),
 				VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{
@@ -626,18 +632,22 @@ func CreateAgentVMSS(cs *api.ContainerService, profile *api.AgentPoolProfile) Vi
 	vmssStorageProfile := compute.VirtualMachineScaleSetStorageProfile{}
 
 	if profile.IsWindows() {
-		vmssStorageProfile.ImageReference = &compute.ImageReference{
-			Offer:     to.StringPtr("[parameters('agentWindowsOffer')]"),
-			Publisher: to.StringPtr("[parameters('agentWindowsPublisher')]"),
-			Sku:       to.StringPtr("[parameters('agentWindowsSku')]"),
-			Version:   to.StringPtr("[parameters('agentWindowsVersion')]"),
+		if cs.Properties.WindowsProfile.HasImageRef() {
+			vmssStorageProfile.ImageReference = getWindowsImageReference(cs.Properties.WindowsProfile)
+		} else {
+			vmssStorageProfile.ImageReference = &compute.ImageReference{
+				Offer:     to.StringPtr("[parameters('agentWindowsOffer')]"),
+				Publisher: to.StringPtr("[parameters('agentWindowsPublisher')]"),
+				Sku:       to.StringPtr("[parameters('agentWindowsSku')]"),
+				Version:   to.StringPtr("[parameters('agentWindowsVersion')]"),
+			}
 		}
 		vmssStorageProfile.DataDisks = getVMSSDataDisks(profile)
 	} else {
 		if profile.HasImageRef() {
 			imageRef := profile.ImageRef
 			if profile.HasImageGallery() {
-				v := fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', variables('%sosImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', variables('%sosImageName'), '/versions/', '%s')]", imageRef.SubscriptionID, profile.Name, imageRef.Gallery, profile.Name, imageRef.Version)
+				v := fmt.Sprintf("[concat('/subscriptions/', '%s', '/resourceGroups/', variables('%sosImageResourceGroup'), '/providers/Microsoft.Compute/galleries/', '%s', '/images/', variables('%sosImageName')%s)]", imageRef.SubscriptionID, profile.Name, imageRef.Gallery, profile.Name, getGalleryImageVersionArgs(imageRef))
 				vmssStorageProfile.ImageReference = &compute.ImageReference{
 					ID: to.StringPtr(v),
 				}
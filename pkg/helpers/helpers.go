@@ -6,7 +6,6 @@ package helpers
 import (
 	// "fmt"
 	"bytes"
-	"crypto/rand"
 	"crypto/rsa"
 	"encoding/json"
 	"fmt"
@@ -98,7 +97,7 @@ func EqualError(a, b error) bool {
 
 // CreateSSH creates an SSH key pair.
 func CreateSSH(rg io.Reader, s *i18n.Translator) (privateKey *rsa.PrivateKey, publicKeyString string, err error) {
-	privateKey, err = rsa.GenerateKey(rg, SSHKeySize)
+	privateKey, err = GenerateRSAKey(rg, SSHKeySize)
 	if err != nil {
 		return nil, "", s.Errorf("failed to generate private key for ssh: %q", err)
 	}
@@ -163,7 +162,7 @@ func ShellQuote(s string) string {
 
 // CreateSaveSSH generates and stashes an SSH key pair.
 func CreateSaveSSH(username, outputDirectory string, s *i18n.Translator) (privateKey *rsa.PrivateKey, publicKeyString string, err error) {
-	privateKey, publicKeyString, err = CreateSSH(rand.Reader, s)
+	privateKey, publicKeyString, err = CreateSSH(RandomReader(), s)
 	if err != nil {
 		return nil, "", err
 	}
@@ -5,6 +5,9 @@ package helpers
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -12,6 +15,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
 	"time"
@@ -26,8 +30,36 @@ const (
 	ValidityDuration = time.Hour * 24 * 365 * 30
 	// PkiKeySize is the size in bytes of the PKI key
 	PkiKeySize = 4096
+	// DefaultECDSAKeySize is the curve size of ECDSA keys when none is specified
+	DefaultECDSAKeySize = 256
 )
 
+// the key algorithms supported for PKI certificates
+const (
+	RSAKeyAlgorithm   = "RSA"
+	ECDSAKeyAlgorithm = "ECDSA"
+)
+
+// CertificateOptions selects the key and validity of a certificate. Zero values select an RSA key of PkiKeySize bits
+// and a validity of ValidityDuration.
+type CertificateOptions struct {
+	KeyAlgorithm string
+	// KeySize is the modulus size of RSA keys, or the curve size of ECDSA keys: 256, 384 or 521
+	KeySize  int
+	Validity time.Duration
+}
+
+// PkiOptions selects the options of each class of certificates created by CreatePki. Client applies to both the
+// kubelet and kubectl client certificates.
+type PkiOptions struct {
+	CA         CertificateOptions
+	APIServer  CertificateOptions
+	Client     CertificateOptions
+	EtcdServer CertificateOptions
+	EtcdClient CertificateOptions
+	EtcdPeer   CertificateOptions
+}
+
 // PkiKeyCertPair represents an PKI public and private cert pair
 type PkiKeyCertPair struct {
 	CertificatePem string
@@ -35,8 +67,8 @@ type PkiKeyCertPair struct {
 }
 
 // CreatePkiKeyCertPair generates a pair of PKI certificate and private key
-func CreatePkiKeyCertPair(commonName string) (*PkiKeyCertPair, error) {
-	caCertificate, caPrivateKey, err := createCertificate(commonName, nil, nil, false, false, nil, nil, nil)
+func CreatePkiKeyCertPair(commonName string, options CertificateOptions) (*PkiKeyCertPair, error) {
+	caCertificate, caPrivateKey, err := createCertificate(RandomReader(), commonName, nil, nil, false, false, nil, nil, nil, options)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +77,7 @@ func CreatePkiKeyCertPair(commonName string) (*PkiKeyCertPair, error) {
 }
 
 // CreatePki creates PKI certificates
-func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caPair *PkiKeyCertPair, masterCount int) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, []*PkiKeyCertPair, error) {
+func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caPair *PkiKeyCertPair, masterCount int, options PkiOptions) (*PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, *PkiKeyCertPair, []*PkiKeyCertPair, error) {
 	start := time.Now()
 	defer func(s time.Time) {
 		log.Debugf("pki: PKI asset creation took %s", time.Since(s))
@@ -60,17 +92,17 @@ func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caP
 
 	var (
 		caCertificate         *x509.Certificate
-		caPrivateKey          *rsa.PrivateKey
+		caPrivateKey          crypto.Signer
 		apiServerCertificate  *x509.Certificate
-		apiServerPrivateKey   *rsa.PrivateKey
+		apiServerPrivateKey   crypto.Signer
 		clientCertificate     *x509.Certificate
-		clientPrivateKey      *rsa.PrivateKey
+		clientPrivateKey      crypto.Signer
 		kubeConfigCertificate *x509.Certificate
-		kubeConfigPrivateKey  *rsa.PrivateKey
+		kubeConfigPrivateKey  crypto.Signer
 		etcdServerCertificate *x509.Certificate
-		etcdServerPrivateKey  *rsa.PrivateKey
+		etcdServerPrivateKey  crypto.Signer
 		etcdClientCertificate *x509.Certificate
-		etcdClientPrivateKey  *rsa.PrivateKey
+		etcdClientPrivateKey  crypto.Signer
 		etcdPeerCertPairs     []*PkiKeyCertPair
 	)
 	var group errgroup.Group
@@ -85,32 +117,41 @@ func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caP
 		return nil, nil, nil, nil, nil, nil, err
 	}
 
+	// each certificate is generated from its own stream so that seeded generation does not depend on scheduling
+	random := RandomReader()
+	randoms := make([]io.Reader, 5+masterCount)
+	for i := range randoms {
+		if randoms[i], err = forkRandomReader(random); err != nil {
+			return nil, nil, nil, nil, nil, nil, err
+		}
+	}
+
 	group.Go(func() (err error) {
-		apiServerCertificate, apiServerPrivateKey, err = createCertificate("apiserver", caCertificate, caPrivateKey, false, true, extraFQDNs, extraIPs, nil)
+		apiServerCertificate, apiServerPrivateKey, err = createCertificate(randoms[0], "apiserver", caCertificate, caPrivateKey, false, true, extraFQDNs, extraIPs, nil, options.APIServer)
 		return err
 	})
 
 	group.Go(func() (err error) {
 		organization := make([]string, 1)
 		organization[0] = "system:masters"
-		clientCertificate, clientPrivateKey, err = createCertificate("client", caCertificate, caPrivateKey, false, false, nil, nil, organization)
+		clientCertificate, clientPrivateKey, err = createCertificate(randoms[1], "client", caCertificate, caPrivateKey, false, false, nil, nil, organization, options.Client)
 		return err
 	})
 
 	group.Go(func() (err error) {
 		organization := make([]string, 1)
 		organization[0] = "system:masters"
-		kubeConfigCertificate, kubeConfigPrivateKey, err = createCertificate("client", caCertificate, caPrivateKey, false, false, nil, nil, organization)
+		kubeConfigCertificate, kubeConfigPrivateKey, err = createCertificate(randoms[2], "client", caCertificate, caPrivateKey, false, false, nil, nil, organization, options.Client)
 		return err
 	})
 
 	group.Go(func() (err error) {
-		etcdServerCertificate, etcdServerPrivateKey, err = createCertificate("etcdserver", caCertificate, caPrivateKey, true, true, nil, extraIPs, nil)
+		etcdServerCertificate, etcdServerPrivateKey, err = createCertificate(randoms[3], "etcdserver", caCertificate, caPrivateKey, true, true, nil, extraIPs, nil, options.EtcdServer)
 		return err
 	})
 
 	group.Go(func() (err error) {
-		etcdClientCertificate, etcdClientPrivateKey, err = createCertificate("etcdclient", caCertificate, caPrivateKey, true, false, nil, extraIPs, nil)
+		etcdClientCertificate, etcdClientPrivateKey, err = createCertificate(randoms[4], "etcdclient", caCertificate, caPrivateKey, true, false, nil, extraIPs, nil, options.EtcdClient)
 		return err
 	})
 
@@ -118,7 +159,7 @@ func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caP
 	for i := 0; i < masterCount; i++ {
 		i := i
 		group.Go(func() (err error) {
-			etcdPeerCertificate, etcdPeerPrivateKey, err := createCertificate("etcdpeer", caCertificate, caPrivateKey, true, false, nil, extraIPs, nil)
+			etcdPeerCertificate, etcdPeerPrivateKey, err := createCertificate(randoms[5+i], "etcdpeer", caCertificate, caPrivateKey, true, false, nil, extraIPs, nil, options.EtcdPeer)
 			etcdPeerCertPairs[i] = &PkiKeyCertPair{CertificatePem: string(certificateToPem(etcdPeerCertificate.Raw)), PrivateKeyPem: string(privateKeyToPem(etcdPeerPrivateKey))}
 			return err
 		})
@@ -137,19 +178,29 @@ func CreatePki(extraFQDNs []string, extraIPs []net.IP, clusterDomain string, caP
 		nil
 }
 
-func createCertificate(commonName string, caCertificate *x509.Certificate, caPrivateKey *rsa.PrivateKey, isEtcd bool, isServer bool, extraFQDNs []string, extraIPs []net.IP, organization []string) (*x509.Certificate, *rsa.PrivateKey, error) {
+func createCertificate(random io.Reader, commonName string, caCertificate *x509.Certificate, caPrivateKey crypto.Signer, isEtcd bool, isServer bool, extraFQDNs []string, extraIPs []net.IP, organization []string, options CertificateOptions) (*x509.Certificate, crypto.Signer, error) {
 	var err error
 
 	isCA := (caCertificate == nil)
 
 	now := time.Now()
+	if random != rand.Reader {
+		now = SeededNotBefore()
+	}
+	validity := options.Validity
+	if validity == 0 {
+		validity = ValidityDuration
+	}
+	if now.Add(validity).Before(time.Now()) {
+		return nil, nil, fmt.Errorf("the %s certificate would be expired, it would be valid from %s for %s only", commonName, now.Format(time.RFC3339), validity)
+	}
 
 	template := x509.Certificate{
 		Subject:   pkix.Name{CommonName: commonName},
 		NotBefore: now,
-		NotAfter:  now.Add(ValidityDuration),
+		NotAfter:  now.Add(validity),
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		BasicConstraintsValid: true,
 	}
 
@@ -181,14 +232,21 @@ func createCertificate(commonName string, caCertificate *x509.Certificate, caPri
 	}
 
 	snMax := new(big.Int).Lsh(big.NewInt(1), 128)
-	template.SerialNumber, err = rand.Int(rand.Reader, snMax)
+	template.SerialNumber, err = rand.Int(random, snMax)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	privateKey, _ := rsa.GenerateKey(rand.Reader, PkiKeySize)
+	privateKey, err := generatePrivateKey(random, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	// key encipherment is only meaningful for RSA key exchange
+	if _, ok := privateKey.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
 
-	var privateKeyToUse *rsa.PrivateKey
+	var privateKeyToUse crypto.Signer
 	var certificateToUse *x509.Certificate
 	if !isCA {
 		privateKeyToUse = caPrivateKey
@@ -198,7 +256,7 @@ func createCertificate(commonName string, caCertificate *x509.Certificate, caPri
 		certificateToUse = &template
 	}
 
-	certDerBytes, err := x509.CreateCertificate(rand.Reader, &template, certificateToUse, &privateKey.PublicKey, privateKeyToUse)
+	certDerBytes, err := x509.CreateCertificate(random, &template, certificateToUse, privateKey.Public(), signerFor(random, privateKeyToUse))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -222,10 +280,57 @@ func certificateToPem(derBytes []byte) []byte {
 	return pemBuffer.Bytes()
 }
 
-func privateKeyToPem(privateKey *rsa.PrivateKey) []byte {
-	pemBlock := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+// generatePrivateKey generates a key of the algorithm and size of options
+func generatePrivateKey(random io.Reader, options CertificateOptions) (crypto.Signer, error) {
+	switch options.KeyAlgorithm {
+	case "", RSAKeyAlgorithm:
+		size := options.KeySize
+		if size == 0 {
+			size = PkiKeySize
+		}
+		return GenerateRSAKey(random, size)
+	case ECDSAKeyAlgorithm:
+		curve, err := ecdsaCurve(options.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		return GenerateECDSAKey(random, curve)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", options.KeyAlgorithm)
+	}
+}
+
+// ecdsaCurve returns the NIST curve of the given size in bits
+func ecdsaCurve(size int) (elliptic.Curve, error) {
+	switch size {
+	case 0, 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ECDSA key size %d, expected 256, 384 or 521", size)
+	}
+}
+
+func privateKeyToPem(privateKey crypto.Signer) []byte {
+	var pemBlock *pem.Block
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		// only fails for curves not supported by ecdsaCurve
+		b, _ := x509.MarshalECPrivateKey(key)
+		pemBlock = &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: b,
+		}
+	case *rsa.PrivateKey:
+		pemBlock = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}
+	default:
+		return nil
 	}
 	pemBuffer := bytes.Buffer{}
 	pem.Encode(&pemBuffer, pemBlock)
@@ -241,10 +346,35 @@ func pemToCertificate(raw string) (*x509.Certificate, error) {
 	return x509.ParseCertificate(cpb.Bytes)
 }
 
-func pemToKey(raw string) (*rsa.PrivateKey, error) {
-	kpb, _ := pem.Decode([]byte(raw))
-	if kpb == nil {
-		return nil, errors.New("The raw pem is not a valid PEM formatted block")
+// pemToKey parses an RSA or ECDSA private key in PKCS #1, SEC 1 or PKCS #8 form. Blocks of EC parameters preceding
+// the key, as written by openssl ecparam -genkey, are skipped.
+func pemToKey(raw string) (crypto.Signer, error) {
+	rest := []byte(raw)
+	for {
+		var kpb *pem.Block
+		kpb, rest = pem.Decode(rest)
+		if kpb == nil {
+			return nil, errors.New("The raw pem is not a valid PEM formatted block")
+		}
+		switch kpb.Type {
+		case "EC PARAMETERS":
+			continue
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(kpb.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(kpb.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(kpb.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			return signer, nil
+		default:
+			return nil, fmt.Errorf("unsupported PEM block type %q", kpb.Type)
+		}
 	}
-	return x509.ParsePKCS1PrivateKey(kpb.Bytes)
 }
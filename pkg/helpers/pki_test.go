@@ -4,11 +4,17 @@
 package helpers
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
 	"net"
 	"testing"
+	"time"
 )
 
 func TestCreateCertificateWithOrganisation(t *testing.T) {
@@ -17,11 +23,11 @@ func TestCreateCertificateWithOrganisation(t *testing.T) {
 
 	var (
 		caCertificate   *x509.Certificate
-		caPrivateKey    *rsa.PrivateKey
+		caPrivateKey    crypto.Signer
 		testCertificate *x509.Certificate
 	)
 
-	caCertificate, caPrivateKey, err = createCertificate("ca", nil, nil, false, false, nil, nil, nil)
+	caCertificate, caPrivateKey, err = createCertificate(rand.Reader, "ca", nil, nil, false, false, nil, nil, nil, CertificateOptions{})
 	if err != nil {
 		t.Fatalf("failed to generate certificate: %s", err)
 	}
@@ -38,7 +44,7 @@ func TestCreateCertificateWithOrganisation(t *testing.T) {
 
 	organization := make([]string, 1)
 	organization[0] = "system:masters"
-	testCertificate, _, err = createCertificate("client", caCertificate, caPrivateKey, false, false, nil, nil, organization)
+	testCertificate, _, err = createCertificate(rand.Reader, "client", caCertificate, caPrivateKey, false, false, nil, nil, organization, CertificateOptions{})
 	if err != nil {
 		t.Fatalf("failed to generate certificate: %s", err)
 	}
@@ -56,11 +62,11 @@ func TestCreateCertificateWithoutOrganisation(t *testing.T) {
 
 	var (
 		caCertificate   *x509.Certificate
-		caPrivateKey    *rsa.PrivateKey
+		caPrivateKey    crypto.Signer
 		testCertificate *x509.Certificate
 	)
 
-	caCertificate, caPrivateKey, err = createCertificate("ca", nil, nil, false, false, nil, nil, nil)
+	caCertificate, caPrivateKey, err = createCertificate(rand.Reader, "ca", nil, nil, false, false, nil, nil, nil, CertificateOptions{})
 	if err != nil {
 		t.Fatalf("failed to generate certificate: %s", err)
 	}
@@ -75,7 +81,7 @@ func TestCreateCertificateWithoutOrganisation(t *testing.T) {
 		t.Fatalf("failed to generate certificate: %s", err)
 	}
 
-	testCertificate, _, err = createCertificate("client", caCertificate, caPrivateKey, false, false, nil, nil, nil)
+	testCertificate, _, err = createCertificate(rand.Reader, "client", caCertificate, caPrivateKey, false, false, nil, nil, nil, CertificateOptions{})
 	if err != nil {
 		t.Fatalf("failed to generate certificate: %s", err)
 	}
@@ -92,7 +98,7 @@ func TestSubjectAltNameInCert(t *testing.T) {
 	roots := x509.NewCertPool()
 
 	// Prepare CA and add it to certificate store.
-	caCertificate, caPrivateKey, err := createCertificate("ca", nil, nil, false, false, nil, nil, nil)
+	caCertificate, caPrivateKey, err := createCertificate(rand.Reader, "ca", nil, nil, false, false, nil, nil, nil, CertificateOptions{})
 	if err != nil {
 		t.Fatalf("failed to generate CA certificates: %s.", err)
 	}
@@ -159,7 +165,7 @@ func TestSubjectAltNameInCert(t *testing.T) {
 	masterExtraFQDNs := append(formattedDNSPrefixes, SubjectAltNames...)
 	expectedDNSNames := []string{"santest.australiaeast.cloudapp.azure.com", "santest.australiasoutheast.cloudapp.azure.com", "santest.brazilsouth.cloudapp.azure.com", "santest.canadacentral.cloudapp.azure.com", "santest.canadaeast.cloudapp.azure.com", "santest.centralindia.cloudapp.azure.com", "santest.centralus.cloudapp.azure.com", "santest.centraluseuap.cloudapp.azure.com", "santest.chinaeast.cloudapp.chinacloudapi.cn", "santest.chinaeast2.cloudapp.chinacloudapi.cn", "santest.chinanorth.cloudapp.chinacloudapi.cn", "santest.chinanorth2.cloudapp.chinacloudapi.cn", "santest.eastasia.cloudapp.azure.com", "santest.eastus.cloudapp.azure.com", "santest.eastus2.cloudapp.azure.com", "santest.eastus2euap.cloudapp.azure.com", "santest.japaneast.cloudapp.azure.com", "santest.japanwest.cloudapp.azure.com", "santest.koreacentral.cloudapp.azure.com", "santest.koreasouth.cloudapp.azure.com", "santest.northcentralus.cloudapp.azure.com", "santest.northeurope.cloudapp.azure.com", "santest.southcentralus.cloudapp.azure.com", "santest.southeastasia.cloudapp.azure.com", "santest.southindia.cloudapp.azure.com", "santest.uksouth.cloudapp.azure.com", "santest.ukwest.cloudapp.azure.com", "santest.westcentralus.cloudapp.azure.com", "santest.westeurope.cloudapp.azure.com", "santest.westindia.cloudapp.azure.com", "santest.westus.cloudapp.azure.com", "santest.westus2.cloudapp.azure.com", "santest.chinaeast.cloudapp.chinacloudapi.cn", "santest.chinanorth.cloudapp.chinacloudapi.cn", "santest.germanycentral.cloudapp.microsoftazure.de", "santest.germanynortheast.cloudapp.microsoftazure.de", "santest.usgovvirginia.cloudapp.usgovcloudapi.net", "santest.usgoviowa.cloudapp.usgovcloudapi.net", "santest.usgovarizona.cloudapp.usgovcloudapi.net", "santest.usgovtexas.cloudapp.usgovcloudapi.net", "santest.francecentral.cloudapp.azure.com", "santest.mydomain.com", "santest2.testdomain.net", "kubernetes", "kubernetes.default", "kubernetes.default.svc", "kubernetes.default.svc.cluster.local", "kubernetes.kube-system", "kubernetes.kube-system.svc", "kubernetes.kube-system.svc.cluster.local"}
 
-	apiServerPair, _, _, _, _, _, err := CreatePki(masterExtraFQDNs, extraIPs, "cluster.local", caPair, 1)
+	apiServerPair, _, _, _, _, _, err := CreatePki(masterExtraFQDNs, extraIPs, "cluster.local", caPair, 1, PkiOptions{})
 
 	if err != nil {
 		t.Fatalf("failed to generate certificates: %s.", err)
@@ -196,7 +202,7 @@ func TestSubjectAltNameInCert(t *testing.T) {
 	masterExtraFQDNs = append(formattedDNSPrefixes, SubjectAltNames...)
 	expectedDNSNames = []string{"santest.australiaeast.cloudapp.azure.com", "santest.australiasoutheast.cloudapp.azure.com", "santest.brazilsouth.cloudapp.azure.com", "santest.canadacentral.cloudapp.azure.com", "santest.canadaeast.cloudapp.azure.com", "santest.centralindia.cloudapp.azure.com", "santest.centralus.cloudapp.azure.com", "santest.centraluseuap.cloudapp.azure.com", "santest.chinaeast.cloudapp.chinacloudapi.cn", "santest.chinaeast2.cloudapp.chinacloudapi.cn", "santest.chinanorth.cloudapp.chinacloudapi.cn", "santest.chinanorth2.cloudapp.chinacloudapi.cn", "santest.eastasia.cloudapp.azure.com", "santest.eastus.cloudapp.azure.com", "santest.eastus2.cloudapp.azure.com", "santest.eastus2euap.cloudapp.azure.com", "santest.japaneast.cloudapp.azure.com", "santest.japanwest.cloudapp.azure.com", "santest.koreacentral.cloudapp.azure.com", "santest.koreasouth.cloudapp.azure.com", "santest.northcentralus.cloudapp.azure.com", "santest.northeurope.cloudapp.azure.com", "santest.southcentralus.cloudapp.azure.com", "santest.southeastasia.cloudapp.azure.com", "santest.southindia.cloudapp.azure.com", "santest.uksouth.cloudapp.azure.com", "santest.ukwest.cloudapp.azure.com", "santest.westcentralus.cloudapp.azure.com", "santest.westeurope.cloudapp.azure.com", "santest.westindia.cloudapp.azure.com", "santest.westus.cloudapp.azure.com", "santest.westus2.cloudapp.azure.com", "santest.chinaeast.cloudapp.chinacloudapi.cn", "santest.chinanorth.cloudapp.chinacloudapi.cn", "santest.germanycentral.cloudapp.microsoftazure.de", "santest.germanynortheast.cloudapp.microsoftazure.de", "santest.usgovvirginia.cloudapp.usgovcloudapi.net", "santest.usgoviowa.cloudapp.usgovcloudapi.net", "santest.usgovarizona.cloudapp.usgovcloudapi.net", "santest.usgovtexas.cloudapp.usgovcloudapi.net", "santest.francecentral.cloudapp.azure.com", "kubernetes", "kubernetes.default", "kubernetes.default.svc", "kubernetes.default.svc.cluster.local", "kubernetes.kube-system", "kubernetes.kube-system.svc", "kubernetes.kube-system.svc.cluster.local"}
 
-	apiServerPair, _, _, _, _, _, err = CreatePki(masterExtraFQDNs, extraIPs, "cluster.local", caPair, 1)
+	apiServerPair, _, _, _, _, _, err = CreatePki(masterExtraFQDNs, extraIPs, "cluster.local", caPair, 1, PkiOptions{})
 
 	if err != nil {
 		t.Fatalf("failed to generate certificates: %s.", err)
@@ -230,8 +236,122 @@ func TestSubjectAltNameInCert(t *testing.T) {
 
 func TestCreatePkiKeyCertPair(t *testing.T) {
 	subject := "foosubject"
-	_, err := CreatePkiKeyCertPair(subject)
+	_, err := CreatePkiKeyCertPair(subject, CertificateOptions{})
 	if err != nil {
 		t.Errorf("unexpected error thrown while executing CreatePkiKeyCertPair : %s", err.Error())
 	}
 }
+
+func TestCreatePkiWithOptions(t *testing.T) {
+	caPair, err := CreatePkiKeyCertPair("ca", CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm, KeySize: 384, Validity: 24 * time.Hour * 365})
+	if err != nil {
+		t.Fatalf("unexpected error creating CA: %s", err)
+	}
+	options := PkiOptions{
+		APIServer:  CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm, Validity: 24 * time.Hour * 90},
+		Client:     CertificateOptions{KeyAlgorithm: RSAKeyAlgorithm, KeySize: 2048},
+		EtcdServer: CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm},
+		EtcdClient: CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm},
+		EtcdPeer:   CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm, KeySize: 521},
+	}
+	apiServerPair, clientPair, kubeConfigPair, etcdServerPair, etcdClientPair, etcdPeerPairs, err := CreatePki([]string{"localhost"}, []net.IP{net.ParseIP("10.0.0.4")}, "cluster.local", caPair, 1, options)
+	if err != nil {
+		t.Fatalf("unexpected error creating PKI: %s", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(caPair.CertificatePem))
+	cases := []struct {
+		name      string
+		pair      *PkiKeyCertPair
+		algorithm x509.PublicKeyAlgorithm
+		bits      int
+		validity  time.Duration
+	}{
+		{"ca", caPair, x509.ECDSA, 384, 24 * time.Hour * 365},
+		{"apiserver", apiServerPair, x509.ECDSA, 256, 24 * time.Hour * 90},
+		{"client", clientPair, x509.RSA, 2048, ValidityDuration},
+		{"kubeconfig", kubeConfigPair, x509.RSA, 2048, ValidityDuration},
+		{"etcdserver", etcdServerPair, x509.ECDSA, 256, ValidityDuration},
+		{"etcdclient", etcdClientPair, x509.ECDSA, 256, ValidityDuration},
+		{"etcdpeer", etcdPeerPairs[0], x509.ECDSA, 521, ValidityDuration},
+	}
+	for _, c := range cases {
+		certificate, err := pemToCertificate(c.pair.CertificatePem)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s certificate: %s", c.name, err)
+		}
+		if certificate.PublicKeyAlgorithm != c.algorithm {
+			t.Errorf("expected %s certificate to have a %s key, got %s", c.name, c.algorithm, certificate.PublicKeyAlgorithm)
+		}
+		if validity := certificate.NotAfter.Sub(certificate.NotBefore); validity != c.validity {
+			t.Errorf("expected %s certificate to be valid for %s, got %s", c.name, c.validity, validity)
+		}
+		key, err := pemToKey(c.pair.PrivateKeyPem)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s private key: %s", c.name, err)
+		}
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			if k.Curve.Params().BitSize != c.bits {
+				t.Errorf("expected %s key to be on a %d bits curve, got %d", c.name, c.bits, k.Curve.Params().BitSize)
+			}
+		case *rsa.PrivateKey:
+			if k.N.BitLen() != c.bits {
+				t.Errorf("expected %s key to have %d bits, got %d", c.name, c.bits, k.N.BitLen())
+			}
+		}
+		if _, err = certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			t.Errorf("expected %s certificate to be signed by the CA: %s", c.name, err)
+		}
+	}
+
+	if _, err = CreatePkiKeyCertPair("ca", CertificateOptions{KeyAlgorithm: "DSA"}); err == nil {
+		t.Errorf("expected error creating a certificate with an unsupported key algorithm")
+	}
+	if _, err = CreatePkiKeyCertPair("ca", CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm, KeySize: 4096}); err == nil {
+		t.Errorf("expected error creating a certificate with an unsupported curve")
+	}
+}
+
+func TestPemToKey(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters, err := asn1.Marshal(asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"PKCS #8":               string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		"SEC 1":                 string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})),
+		"SEC 1 with parameters": string(pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: parameters})) + string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})),
+	}
+	for name, raw := range cases {
+		key, err := pemToKey(raw)
+		if err != nil {
+			t.Errorf("unexpected error parsing %s key: %s", name, err)
+			continue
+		}
+		if k, ok := key.(*ecdsa.PrivateKey); !ok || k.D.Cmp(ecdsaKey.D) != 0 {
+			t.Errorf("expected %s key to be parsed to the original ECDSA key", name)
+		}
+	}
+
+	if _, err = pemToKey(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{}}))); err == nil {
+		t.Errorf("expected error parsing a PEM block which is not a private key")
+	}
+	if _, err = pemToKey("not a pem"); err == nil {
+		t.Errorf("expected error parsing an invalid PEM block")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package helpers
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	socks5Version          = 0x05
+	socks5NoAuth           = 0x00
+	socks5UserPassAuth     = 0x02
+	socks5NoAcceptable     = 0xff
+	socks5UserPassVersion  = 0x01
+	socks5Connect          = 0x01
+	socks5AddressIPv4      = 0x01
+	socks5AddressIPv6      = 0x04
+	socks5AddressDomain    = 0x03
+	socks5Succeeded        = 0x00
+	defaultSSHProxyPort    = "22"
+	defaultSOCKS5ProxyPort = "1080"
+)
+
+// ProxyDialer opens TCP connections through a SOCKS5 proxy or an SSH bastion,
+// it lets aks-engine reach the API server of a private cluster from outside of its VNET.
+type ProxyDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NewProxyDialer returns the ProxyDialer of a proxy URL, either socks5://[user:password@]host[:port]
+// or ssh://user@host[:port]. The SSH bastion authenticates with the private key at sshKeyPath.
+// Host names are resolved by the proxy, so names only resolvable within the VNET, such as the
+// FQDN of a private DNS zone, can be reached.
+func NewProxyDialer(proxyURL, sshKeyPath string) (ProxyDialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing proxy URL %s", proxyURL)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.Errorf("proxy URL %s has no host, use socks5://host:port or ssh://user@host:port", proxyURL)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		d := &socks5Dialer{
+			address: net.JoinHostPort(u.Hostname(), portOrDefault(u, defaultSOCKS5ProxyPort)),
+		}
+		if u.User != nil {
+			d.username = u.User.Username()
+			d.password, _ = u.User.Password()
+		}
+		return d, nil
+	case "ssh":
+		if u.User == nil || u.User.Username() == "" {
+			return nil, errors.Errorf("proxy URL %s has no user, use ssh://user@host:port", proxyURL)
+		}
+		if sshKeyPath == "" {
+			return nil, errors.New("an SSH private key is required to use an SSH bastion as a proxy")
+		}
+		key, err := ioutil.ReadFile(sshKeyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading SSH private key %s", sshKeyPath)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing SSH private key %s", sshKeyPath)
+		}
+		return &sshDialer{
+			address: net.JoinHostPort(u.Hostname(), portOrDefault(u, defaultSSHProxyPort)),
+			config: &ssh.ClientConfig{
+				User:            u.User.Username(),
+				Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			},
+		}, nil
+	default:
+		return nil, errors.Errorf("proxy URL %s has an unsupported scheme %q, use socks5 or ssh", proxyURL, u.Scheme)
+	}
+}
+
+func portOrDefault(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Port()
+	}
+	return defaultPort
+}
+
+// sshDialer tunnels the connections through an SSH bastion, sharing a single SSH connection.
+type sshDialer struct {
+	address string
+	config  *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+func (d *sshDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, err := d.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.Dial(network, address)
+	if err != nil {
+		// the SSH connection may have been closed by the bastion, reconnect on the next dial
+		d.mu.Lock()
+		if d.client == client {
+			d.client.Close()
+			d.client = nil
+		}
+		d.mu.Unlock()
+		return nil, errors.Wrapf(err, "dialing %s through SSH bastion %s", address, d.address)
+	}
+	return conn, nil
+}
+
+func (d *sshDialer) getClient(ctx context.Context) (*ssh.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client != nil {
+		return d.client, nil
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dialing SSH bastion %s", d.address)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, d.address, d.config)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "connecting to SSH bastion %s", d.address)
+	}
+	d.client = ssh.NewClient(c, chans, reqs)
+	return d.client, nil
+}
+
+// socks5Dialer opens the connections through a SOCKS5 proxy (RFC 1928), with an optional
+// username and password (RFC 1929).
+type socks5Dialer struct {
+	address  string
+	username string
+	password string
+}
+
+func (d *socks5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, errors.Errorf("network %s is not supported by SOCKS5 proxy %s", network, d.address)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dialing SOCKS5 proxy %s", d.address)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := d.connect(conn, address); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "connecting to %s through SOCKS5 proxy %s", address, d.address)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (d *socks5Dialer) connect(conn net.Conn, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return errors.Errorf("invalid port %s", portString)
+	}
+
+	method := byte(socks5NoAuth)
+	if d.username != "" {
+		method = socks5UserPassAuth
+	}
+	if _, err = conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errors.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+	if reply[1] == socks5NoAcceptable || reply[1] != method {
+		return errors.New("no acceptable authentication method")
+	}
+
+	if method == socks5UserPassAuth {
+		if len(d.username) > 255 || len(d.password) > 255 {
+			return errors.New("username and password must be at most 255 bytes long")
+		}
+		auth := []byte{socks5UserPassVersion, byte(len(d.username))}
+		auth = append(auth, d.username...)
+		auth = append(auth, byte(len(d.password)))
+		auth = append(auth, d.password...)
+		if _, err = conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err = io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != socks5Succeeded {
+			return errors.New("authentication failed")
+		}
+	}
+
+	request := []byte{socks5Version, socks5Connect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			request = append(request, socks5AddressIPv4)
+			request = append(request, ip4...)
+		} else {
+			request = append(request, socks5AddressIPv6)
+			request = append(request, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.Errorf("host name %s is too long", host)
+		}
+		request = append(request, socks5AddressDomain, byte(len(host)))
+		request = append(request, host...)
+	}
+	request = append(request, 0, 0)
+	binary.BigEndian.PutUint16(request[len(request)-2:], uint16(port))
+	if _, err = conn.Write(request); err != nil {
+		return err
+	}
+
+	// VER, REP, RSV, ATYP
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != socks5Succeeded {
+		return errors.Errorf("connect failed with SOCKS5 reply code %d", header[1])
+	}
+	var boundAddressLength int
+	switch header[3] {
+	case socks5AddressIPv4:
+		boundAddressLength = net.IPv4len
+	case socks5AddressIPv6:
+		boundAddressLength = net.IPv6len
+	case socks5AddressDomain:
+		length := make([]byte, 1)
+		if _, err = io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		boundAddressLength = int(length[0])
+	default:
+		return errors.Errorf("unexpected SOCKS5 address type %d", header[3])
+	}
+	// discard the bound address and port
+	_, err = io.ReadFull(conn, make([]byte, boundAddressLength+2))
+	return err
+}
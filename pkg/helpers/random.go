@@ -0,0 +1,282 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	randomMutex     sync.Mutex
+	randomSource    io.Reader = rand.Reader
+	randomNotBefore time.Time
+)
+
+// SetRandomSeed replaces crypto/rand as the source of the keys, certificates and secrets generated by aks-engine
+// with a stream derived from seed, so that generating twice with the same seed produces identical artifacts.
+// Certificates generated afterwards are valid from the start of the UTC day of notBefore rather than from the time
+// of generation. Anyone knowing the seed can regenerate the private keys, it must be kept as secret as they are.
+func SetRandomSeed(seed string, notBefore time.Time) {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	randomSource = &lockedReader{r: newDeterministicReader(sha256.Sum256([]byte(seed)))}
+	randomNotBefore = notBefore.UTC().Truncate(24 * time.Hour)
+}
+
+// ResetRandomSource restores crypto/rand as the source of randomness
+func ResetRandomSource() {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	randomSource = rand.Reader
+	randomNotBefore = time.Time{}
+}
+
+// SeededNotBefore returns the start of the validity of certificates generated after SetRandomSeed, the start of the
+// current UTC day if SetRandomSeed was not called
+func SeededNotBefore() time.Time {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	if randomNotBefore.IsZero() {
+		return time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	return randomNotBefore
+}
+
+// RandomReader returns the source of randomness, crypto/rand unless SetRandomSeed was called
+func RandomReader() io.Reader {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return randomSource
+}
+
+// IsRandomSeeded returns true if SetRandomSeed was called
+func IsRandomSeeded() bool {
+	return RandomReader() != rand.Reader
+}
+
+// forkRandomReader returns an independent stream of randomness to be consumed concurrently with r. For crypto/rand
+// this is r itself, otherwise the stream is seeded from r so that it does not depend on the order of consumption.
+func forkRandomReader(r io.Reader) (io.Reader, error) {
+	if r == rand.Reader {
+		return r, nil
+	}
+	var key [sha256.Size]byte
+	if _, err := io.ReadFull(r, key[:]); err != nil {
+		return nil, errors.Wrap(err, "seeding random stream")
+	}
+	return newDeterministicReader(key), nil
+}
+
+// GenerateRSAKey generates an RSA private key of the given size in bits from r. Keys are only a function of the bytes
+// read when r is not crypto/rand, unlike rsa.GenerateKey which may consume a varying amount of r.
+func GenerateRSAKey(r io.Reader, bits int) (*rsa.PrivateKey, error) {
+	if r == rand.Reader {
+		return rsa.GenerateKey(r, bits)
+	}
+	if bits < 64 || bits%16 != 0 {
+		return nil, errors.Errorf("unsupported RSA key size %d", bits)
+	}
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+	for {
+		p, err := deterministicPrime(r, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := deterministicPrime(r, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+		totient := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(e, totient)
+		if d == nil {
+			continue
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		key.Precompute()
+		if err = key.Validate(); err != nil {
+			return nil, errors.Wrap(err, "validating RSA key")
+		}
+		return key, nil
+	}
+}
+
+// GenerateECDSAKey generates an ECDSA private key on the curve from r. Keys are only a function of the bytes read when
+// r is not crypto/rand.
+func GenerateECDSAKey(r io.Reader, curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	if r == rand.Reader {
+		return ecdsa.GenerateKey(curve, r)
+	}
+	d, err := randomScalar(r, curve.Params().N)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(d.Bytes())
+	return key, nil
+}
+
+// signerFor returns the signer to create certificates from r with. ECDSA signatures use a random nonce, which is taken
+// from r when it is not crypto/rand so that seeded certificates are reproducible.
+func signerFor(r io.Reader, signer crypto.Signer) crypto.Signer {
+	if key, ok := signer.(*ecdsa.PrivateKey); ok && r != rand.Reader {
+		return &deterministicECDSASigner{key: key, r: r}
+	}
+	return signer
+}
+
+// deterministicECDSASigner signs with nonces read from r
+type deterministicECDSASigner struct {
+	key *ecdsa.PrivateKey
+	r   io.Reader
+}
+
+func (s *deterministicECDSASigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *deterministicECDSASigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	n := s.key.Curve.Params().N
+	// the digest is truncated to the size of the curve order, see SEC 1 section 4.1.3
+	e := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	for {
+		k, err := randomScalar(s.r, n)
+		if err != nil {
+			return nil, err
+		}
+		x, _ := s.key.Curve.ScalarBaseMult(k.Bytes())
+		r := new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+		sig := new(big.Int).Mul(s.key.D, r)
+		sig.Add(sig, e)
+		sig.Mul(sig, new(big.Int).ModInverse(k, n))
+		sig.Mod(sig, n)
+		if sig.Sign() == 0 {
+			continue
+		}
+		return asn1.Marshal(struct {
+			R, S *big.Int
+		}{r, sig})
+	}
+}
+
+// randomScalar returns an integer in [1, n) read from r, with 64 extra bits to make the bias of the reduction negligible
+func randomScalar(r io.Reader, n *big.Int) (*big.Int, error) {
+	b := make([]byte, (n.BitLen()+64+7)/8)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, errors.Wrap(err, "reading scalar")
+	}
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, new(big.Int).Sub(n, big.NewInt(1)))
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+// smallPrimes are used to sieve candidates before the more expensive primality test
+var smallPrimes = []uint64{3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47, 53}
+
+// smallPrimesProduct is the product of smallPrimes
+var smallPrimesProduct = new(big.Int).SetUint64(16294579238595022365)
+
+// deterministicPrime returns the first prime of the given size in bits, a multiple of 8, at or after a candidate read
+// from r
+func deterministicPrime(r io.Reader, bits int) (*big.Int, error) {
+	b := make([]byte, bits/8)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, errors.Wrap(err, "reading prime candidate")
+		}
+		// the two top bits are set so that the product of two primes has twice their size
+		b[0] |= 0xc0
+		b[len(b)-1] |= 1
+		candidate := new(big.Int).SetBytes(b)
+		mod := new(big.Int).Mod(candidate, smallPrimesProduct).Uint64()
+	search:
+		for delta := uint64(0); delta < 1<<20; delta += 2 {
+			m := mod + delta
+			for _, prime := range smallPrimes {
+				if m%prime == 0 {
+					continue search
+				}
+			}
+			p := new(big.Int).Add(candidate, new(big.Int).SetUint64(delta))
+			if p.BitLen() != bits {
+				break
+			}
+			if p.ProbablyPrime(20) {
+				return p, nil
+			}
+		}
+	}
+}
+
+// deterministicReader is a stream of the SHA-256 hashes of a key and an incrementing counter
+type deterministicReader struct {
+	key     [sha256.Size]byte
+	counter uint64
+	buf     []byte
+}
+
+func newDeterministicReader(key [sha256.Size]byte) *deterministicReader {
+	return &deterministicReader{key: key}
+}
+
+func (r *deterministicReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var block [sha256.Size + 8]byte
+			copy(block[:], r.key[:])
+			binary.BigEndian.PutUint64(block[sha256.Size:], r.counter)
+			r.counter++
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// lockedReader serializes reads of the shared seeded stream
+type lockedReader struct {
+	mutex sync.Mutex
+	r     io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.r.Read(p)
+}
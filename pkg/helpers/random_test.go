@@ -0,0 +1,210 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+var testSeedDate = time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSetRandomSeed(t *testing.T) {
+	defer ResetRandomSource()
+
+	if IsRandomSeeded() {
+		t.Fatalf("expected crypto/rand to be the default source of randomness")
+	}
+
+	read := func() []byte {
+		b := make([]byte, 100)
+		if _, err := io.ReadFull(RandomReader(), b); err != nil {
+			t.Fatalf("unexpected error reading randomness: %s", err)
+		}
+		return b
+	}
+
+	SetRandomSeed("seed", testSeedDate)
+	if !IsRandomSeeded() {
+		t.Fatalf("expected the source of randomness to be seeded")
+	}
+	first := read()
+	SetRandomSeed("seed", testSeedDate)
+	if second := read(); !bytes.Equal(first, second) {
+		t.Errorf("expected the same seed to produce the same stream")
+	}
+	SetRandomSeed("other", testSeedDate)
+	if other := read(); bytes.Equal(first, other) {
+		t.Errorf("expected different seeds to produce different streams")
+	}
+
+	ResetRandomSource()
+	if RandomReader() != rand.Reader {
+		t.Errorf("expected crypto/rand to be restored as the source of randomness")
+	}
+}
+
+func TestForkRandomReader(t *testing.T) {
+	r, err := forkRandomReader(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r != rand.Reader {
+		t.Errorf("expected crypto/rand not to be forked")
+	}
+
+	parent := newDeterministicReader(sha256.Sum256([]byte("seed")))
+	a, err := forkRandomReader(parent)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := forkRandomReader(parent)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	bufA := make([]byte, 64)
+	bufB := make([]byte, 64)
+	io.ReadFull(a, bufA)
+	io.ReadFull(b, bufB)
+	if bytes.Equal(bufA, bufB) {
+		t.Errorf("expected forked streams to differ")
+	}
+}
+
+func TestGenerateRSAKey(t *testing.T) {
+	key, err := GenerateRSAKey(newDeterministicReader(sha256.Sum256([]byte("seed"))), 1024)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %s", err)
+	}
+	if key.N.BitLen() != 1024 {
+		t.Errorf("expected a 1024 bits key, got %d", key.N.BitLen())
+	}
+	if err = key.Validate(); err != nil {
+		t.Errorf("expected a valid key: %s", err)
+	}
+
+	same, err := GenerateRSAKey(newDeterministicReader(sha256.Sum256([]byte("seed"))), 1024)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %s", err)
+	}
+	if !bytes.Equal(x509.MarshalPKCS1PrivateKey(key), x509.MarshalPKCS1PrivateKey(same)) {
+		t.Errorf("expected the same stream to generate the same key")
+	}
+
+	if _, err = GenerateRSAKey(newDeterministicReader(sha256.Sum256([]byte("seed"))), 1000); err == nil {
+		t.Errorf("expected error generating a key of an unsupported size")
+	}
+}
+
+func TestCreatePkiSeeded(t *testing.T) {
+	defer ResetRandomSource()
+
+	create := func() []string {
+		SetRandomSeed("seed", testSeedDate)
+		caPair, err := CreatePkiKeyCertPair("ca", CertificateOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error creating CA: %s", err)
+		}
+		apiServer, client, kubeConfig, etcdServer, etcdClient, etcdPeers, err := CreatePki([]string{"localhost"}, []net.IP{net.ParseIP("10.0.0.4")}, "cluster.local", caPair, 2, PkiOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error creating PKI: %s", err)
+		}
+		pems := []string{}
+		for _, pair := range append([]*PkiKeyCertPair{caPair, apiServer, client, kubeConfig, etcdServer, etcdClient}, etcdPeers...) {
+			pems = append(pems, pair.CertificatePem, pair.PrivateKeyPem)
+		}
+		return pems
+	}
+
+	first := create()
+	second := create()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("expected PEM %d to be identical when generated from the same seed", i)
+		}
+	}
+
+	certificate, err := pemToCertificate(first[0])
+	if err != nil {
+		t.Fatalf("unexpected error parsing CA certificate: %s", err)
+	}
+	if !certificate.NotBefore.Equal(testSeedDate) {
+		t.Errorf("expected seeded certificates to be valid from %s, got %s", testSeedDate, certificate.NotBefore)
+	}
+}
+
+func TestCreatePkiSeededShortValidity(t *testing.T) {
+	defer ResetRandomSource()
+
+	now := time.Now()
+	options := CertificateOptions{Validity: 90 * 24 * time.Hour}
+	SetRandomSeed("seed", now)
+	caPair, err := CreatePkiKeyCertPair("ca", options)
+	if err != nil {
+		t.Fatalf("unexpected error creating CA: %s", err)
+	}
+	apiServer, _, _, _, _, _, err := CreatePki([]string{"localhost"}, []net.IP{net.ParseIP("10.0.0.4")}, "cluster.local", caPair, 1, PkiOptions{APIServer: options})
+	if err != nil {
+		t.Fatalf("unexpected error creating PKI: %s", err)
+	}
+	for _, pem := range []string{caPair.CertificatePem, apiServer.CertificatePem} {
+		certificate, err := pemToCertificate(pem)
+		if err != nil {
+			t.Fatalf("unexpected error parsing certificate: %s", err)
+		}
+		if certificate.NotBefore.After(now) {
+			t.Errorf("expected seeded certificate %s to be valid from before %s, got %s", certificate.Subject.CommonName, now, certificate.NotBefore)
+		}
+		if !certificate.NotAfter.After(now) {
+			t.Errorf("expected seeded certificate %s to be valid after %s, got %s", certificate.Subject.CommonName, now, certificate.NotAfter)
+		}
+	}
+
+	SetRandomSeed("seed", now.AddDate(0, 0, -100))
+	if _, err = CreatePkiKeyCertPair("ca", options); err == nil {
+		t.Errorf("expected an error creating a seeded certificate which would already be expired")
+	}
+}
+
+func TestCreatePkiSeededECDSA(t *testing.T) {
+	defer ResetRandomSource()
+
+	options := CertificateOptions{KeyAlgorithm: ECDSAKeyAlgorithm}
+	create := func() []string {
+		SetRandomSeed("seed", testSeedDate)
+		caPair, err := CreatePkiKeyCertPair("ca", options)
+		if err != nil {
+			t.Fatalf("unexpected error creating CA: %s", err)
+		}
+		apiServer, _, _, _, _, _, err := CreatePki([]string{"localhost"}, []net.IP{net.ParseIP("10.0.0.4")}, "cluster.local", caPair, 1, PkiOptions{APIServer: options, Client: options, EtcdServer: options, EtcdClient: options, EtcdPeer: options})
+		if err != nil {
+			t.Fatalf("unexpected error creating PKI: %s", err)
+		}
+		return []string{caPair.CertificatePem, caPair.PrivateKeyPem, apiServer.CertificatePem, apiServer.PrivateKeyPem}
+	}
+
+	first := create()
+	second := create()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("expected PEM %d to be identical when generated from the same seed", i)
+		}
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(first[0]))
+	certificate, err := pemToCertificate(first[2])
+	if err != nil {
+		t.Fatalf("unexpected error parsing apiserver certificate: %s", err)
+	}
+	if _, err = certificate.Verify(x509.VerifyOptions{Roots: roots}); err != nil {
+		t.Errorf("expected the seeded apiserver certificate to be signed by the CA: %s", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHyperVGeneration is the Hyper-V generation of the image definitions and VM sizes which do not specify one
+const defaultHyperVGeneration = "V1"
+
+// galleryImage is a Shared Image Gallery image of the cluster, and the pools of the VMs created from it
+type galleryImage struct {
+	ref    *api.ImageReference
+	osType compute.OperatingSystemTypes
+	pools  []VMRequirement
+}
+
+// ResolveGalleryImages resolves the Shared Image Gallery images of the cluster whose version is latest to their latest
+// version replicated to the location, and records it in their image reference. It verifies that the image versions are
+// replicated to the location, and that the OS type and the Hyper-V generation of the image definitions match the pools
+// and their VM sizes.
+func ResolveGalleryImages(ctx context.Context, az armhelpers.AKSEngineClient, location string, p *api.Properties) error {
+	images := getGalleryImages(p)
+	if len(images) == 0 {
+		return nil
+	}
+	vmSizes, err := getVMSizes(ctx, az, location)
+	if err != nil {
+		return errors.Wrap(err, "listing the VM sizes")
+	}
+	for _, image := range images {
+		if err = resolveGalleryImage(ctx, az, location, image, vmSizes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLatestGalleryImagePools returns the pools whose Shared Image Gallery image version is latest. Only deploy and
+// upgrade resolve it to a version, the templates of generate leave it to Azure at deployment time.
+func GetLatestGalleryImagePools(p *api.Properties) []string {
+	var pools []string
+	for _, image := range getGalleryImages(p) {
+		if image.ref.IsLatestVersion() {
+			for _, pool := range image.pools {
+				pools = append(pools, pool.Pool)
+			}
+		}
+	}
+	return pools
+}
+
+// getGalleryImages returns the Shared Image Gallery images of the masters and of the agent pools. The Windows agent
+// pools share the image of the Windows profile.
+func getGalleryImages(p *api.Properties) []*galleryImage {
+	var images []*galleryImage
+	if p.MasterProfile != nil && p.MasterProfile.HasImageGallery() {
+		images = append(images, &galleryImage{
+			ref:    p.MasterProfile.ImageRef,
+			osType: compute.Linux,
+			pools:  []VMRequirement{{Pool: "master", VMSize: p.MasterProfile.VMSize}},
+		})
+	}
+	var windowsImage *galleryImage
+	for _, pool := range p.AgentPoolProfiles {
+		requirement := VMRequirement{Pool: pool.Name, VMSize: pool.VMSize}
+		if pool.IsWindows() {
+			if p.WindowsProfile == nil || !p.WindowsProfile.HasImageGallery() {
+				continue
+			}
+			if windowsImage == nil {
+				windowsImage = &galleryImage{ref: p.WindowsProfile.ImageRef, osType: compute.Windows}
+				images = append(images, windowsImage)
+			}
+			windowsImage.pools = append(windowsImage.pools, requirement)
+		} else if pool.HasImageGallery() {
+			images = append(images, &galleryImage{
+				ref:    pool.ImageRef,
+				osType: compute.Linux,
+				pools:  []VMRequirement{requirement},
+			})
+		}
+	}
+	return images
+}
+
+func resolveGalleryImage(ctx context.Context, az armhelpers.AKSEngineClient, location string, image *galleryImage, vmSizes map[string]compute.ResourceSku) error {
+	ref := image.ref
+	definition, err := az.GetGalleryImage(ctx, ref.SubscriptionID, ref.ResourceGroup, ref.Gallery, ref.Name)
+	if err != nil {
+		return errors.Wrapf(err, "getting image %s of gallery %s", ref.Name, ref.Gallery)
+	}
+	if definition.GalleryImageProperties != nil && definition.OsType != image.osType {
+		return errors.Errorf("image %s of gallery %s is a %s image, pool %s needs a %s image", ref.Name, ref.Gallery, definition.OsType, image.pools[0].Pool, image.osType)
+	}
+
+	generation := definition.HyperVGeneration
+	if generation == "" {
+		generation = defaultHyperVGeneration
+	}
+	for _, pool := range image.pools {
+		// VM sizes not offered in the location are reported by the compute capacity check
+		if sku, ok := vmSizes[strings.ToLower(pool.VMSize)]; ok && !containsString(getHyperVGenerations(sku), generation) {
+			return errors.Errorf("VM size %s of pool %s does not support the Hyper-V generation %s of image %s of gallery %s", pool.VMSize, pool.Pool, generation, ref.Name, ref.Gallery)
+		}
+	}
+
+	version, err := getGalleryImageVersion(ctx, az, location, ref)
+	if err != nil {
+		return err
+	}
+	if ref.IsLatestVersion() {
+		log.Infof("Resolved the latest version of image %s of gallery %s to %s", ref.Name, ref.Gallery, version)
+		ref.Version = version
+	}
+	return nil
+}
+
+// getGalleryImageVersion returns the version of the image reference, or its latest version if the version is latest,
+// after verifying that it is replicated to the location. Like Azure, the latest version leaves out the versions
+// excluded from latest.
+func getGalleryImageVersion(ctx context.Context, az armhelpers.AKSEngineClient, location string, ref *api.ImageReference) (string, error) {
+	latest := ""
+	for page, err := az.ListGalleryImageVersions(ctx, ref.SubscriptionID, ref.ResourceGroup, ref.Gallery, ref.Name); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return "", errors.Wrapf(err, "listing the versions of image %s of gallery %s", ref.Name, ref.Gallery)
+		}
+		for _, version := range page.Values() {
+			name := to.String(version.Name)
+			replicated := isReplicatedTo(version, location)
+			if !ref.IsLatestVersion() {
+				if name != ref.Version {
+					continue
+				}
+				if !replicated {
+					return "", errors.Errorf("version %s of image %s of gallery %s is not replicated to location %s", name, ref.Name, ref.Gallery, location)
+				}
+				return name, nil
+			}
+			if !replicated || version.GalleryImageVersionProperties == nil || version.ProvisioningState != compute.ProvisioningState2Succeeded ||
+				version.PublishingProfile == nil || to.Bool(version.PublishingProfile.ExcludeFromLatest) {
+				continue
+			}
+			if latest == "" || compareImageVersions(name, latest) > 0 {
+				latest = name
+			}
+		}
+	}
+	if !ref.IsLatestVersion() {
+		return "", errors.Errorf("version %s of image %s of gallery %s not found", ref.Version, ref.Name, ref.Gallery)
+	}
+	if latest == "" {
+		return "", errors.Errorf("image %s of gallery %s has no version replicated to location %s", ref.Name, ref.Gallery, location)
+	}
+	return latest, nil
+}
+
+// isReplicatedTo returns true if the target regions of the image version include the location
+func isReplicatedTo(version compute.GalleryImageVersion, location string) bool {
+	if version.GalleryImageVersionProperties == nil || version.PublishingProfile == nil || version.PublishingProfile.TargetRegions == nil {
+		return false
+	}
+	for _, region := range *version.PublishingProfile.TargetRegions {
+		if helpers.NormalizeAzureRegion(to.String(region.Name)) == location {
+			return true
+		}
+	}
+	return false
+}
+
+// compareImageVersions compares two Major.Minor.Patch image versions, and returns a negative number, zero or a positive
+// number if the first one is lower, equal or greater
+func compareImageVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aPart, _ := strconv.ParseInt(aParts[i], 10, 64)
+		bPart, _ := strconv.ParseInt(bParts[i], 10, 64)
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// getHyperVGenerations returns the Hyper-V generations supported by the VM size, e.g. V1 and V2
+func getHyperVGenerations(sku compute.ResourceSku) []string {
+	if sku.Capabilities != nil {
+		for _, capability := range *sku.Capabilities {
+			if to.String(capability.Name) == "HyperVGenerations" {
+				return strings.Split(to.String(capability.Value), ",")
+			}
+		}
+	}
+	return []string{defaultHyperVGeneration}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared Image Gallery images tests", func() {
+	var (
+		client     *armhelpers.MockAKSEngineClient
+		properties *api.Properties
+	)
+
+	BeforeEach(func() {
+		client = &armhelpers.MockAKSEngineClient{}
+		client.FakeListResourceSkusResult = func() []compute.ResourceSku {
+			gen2 := newTestVMSize("Standard_D2s_v3", "standardDSv3Family", "2", "westus2", nil)
+			*gen2.Capabilities = append(*gen2.Capabilities, compute.ResourceSkuCapabilities{Name: to.StringPtr("HyperVGenerations"), Value: to.StringPtr("V1,V2")})
+			return []compute.ResourceSku{
+				newTestVMSize("Standard_D2_v2", "standardDv2Family", "2", "westus2", nil),
+				gen2,
+			}
+		}
+		client.FakeListGalleryImageVersionsResult = func(image string) []compute.GalleryImageVersion {
+			return []compute.GalleryImageVersion{
+				newTestImageVersion("1.9.0", false, "West US 2"),
+				newTestImageVersion("1.10.0", false, "West US 2", "East US"),
+				newTestImageVersion("1.11.0", false, "East US"),
+				newTestImageVersion("2.0.0", true, "West US 2"),
+			}
+		}
+		properties = &api.Properties{
+			MasterProfile: &api.MasterProfile{
+				VMSize: "Standard_D2_v2",
+			},
+			AgentPoolProfiles: []*api.AgentPoolProfile{
+				{
+					Name:   "agentpool1",
+					VMSize: "Standard_D2_v2",
+					ImageRef: &api.ImageReference{
+						Name:           "ubuntu",
+						ResourceGroup:  "images",
+						SubscriptionID: "SUB_ID",
+						Gallery:        "gallery",
+						Version:        "latest",
+					},
+				},
+			},
+		}
+	})
+
+	It("should resolve the latest version replicated to the location", func() {
+		Expect(ResolveGalleryImages(context.Background(), client, "westus2", properties)).To(Succeed())
+		Expect(properties.AgentPoolProfiles[0].ImageRef.Version).To(Equal("1.10.0"))
+	})
+
+	It("should keep a pinned version replicated to the location", func() {
+		properties.AgentPoolProfiles[0].ImageRef.Version = "1.9.0"
+		Expect(ResolveGalleryImages(context.Background(), client, "westus2", properties)).To(Succeed())
+		Expect(properties.AgentPoolProfiles[0].ImageRef.Version).To(Equal("1.9.0"))
+	})
+
+	It("should fail when the version is not replicated to the location or does not exist", func() {
+		properties.AgentPoolProfiles[0].ImageRef.Version = "1.11.0"
+		err := ResolveGalleryImages(context.Background(), client, "westus2", properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("version 1.11.0 of image ubuntu of gallery gallery is not replicated to location westus2"))
+
+		properties.AgentPoolProfiles[0].ImageRef.Version = "3.0.0"
+		err = ResolveGalleryImages(context.Background(), client, "westus2", properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("version 3.0.0 of image ubuntu of gallery gallery not found"))
+	})
+
+	It("should fail when no version is replicated to the location", func() {
+		err := ResolveGalleryImages(context.Background(), client, "northeurope", properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("image ubuntu of gallery gallery has no version replicated to location northeurope"))
+		Expect(properties.AgentPoolProfiles[0].ImageRef.Version).To(Equal("latest"))
+	})
+
+	It("should fail when the OS type of the image does not match the pool", func() {
+		properties.AgentPoolProfiles = append(properties.AgentPoolProfiles, &api.AgentPoolProfile{
+			Name:   "windowspool",
+			VMSize: "Standard_D2_v2",
+			OSType: api.Windows,
+		})
+		properties.WindowsProfile = &api.WindowsProfile{
+			ImageRef: &api.ImageReference{
+				Name:           "windows",
+				ResourceGroup:  "images",
+				SubscriptionID: "SUB_ID",
+				Gallery:        "gallery",
+				Version:        "latest",
+			},
+		}
+		err := ResolveGalleryImages(context.Background(), client, "westus2", properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("image windows of gallery gallery is a Linux image, pool windowspool needs a Windows image"))
+		Expect(properties.AgentPoolProfiles[0].ImageRef.Version).To(Equal("1.10.0"))
+	})
+
+	It("should fail when the VM size does not support the Hyper-V generation of the image", func() {
+		client.FakeGetGalleryImageResult = func(image string) armhelpers.GalleryImage {
+			return armhelpers.GalleryImage{
+				GalleryImage: compute.GalleryImage{
+					Name: to.StringPtr(image),
+					GalleryImageProperties: &compute.GalleryImageProperties{
+						OsType: compute.Linux,
+					},
+				},
+				HyperVGeneration: "V2",
+			}
+		}
+		err := ResolveGalleryImages(context.Background(), client, "westus2", properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("VM size Standard_D2_v2 of pool agentpool1 does not support the Hyper-V generation V2 of image ubuntu of gallery gallery"))
+
+		properties.AgentPoolProfiles[0].VMSize = "Standard_D2s_v3"
+		Expect(ResolveGalleryImages(context.Background(), client, "westus2", properties)).To(Succeed())
+	})
+
+	It("should not query Azure without gallery images", func() {
+		client.FailGetGalleryImage = true
+		properties.AgentPoolProfiles[0].ImageRef = nil
+		Expect(ResolveGalleryImages(context.Background(), client, "westus2", properties)).To(Succeed())
+	})
+
+	It("should list the pools whose image version is latest", func() {
+		Expect(GetLatestGalleryImagePools(properties)).To(Equal([]string{"agentpool1"}))
+
+		properties.AgentPoolProfiles[0].ImageRef.Version = "1.10.0"
+		Expect(GetLatestGalleryImagePools(properties)).To(BeEmpty())
+	})
+})
+
+func newTestImageVersion(name string, excludeFromLatest bool, regions ...string) compute.GalleryImageVersion {
+	var targetRegions []compute.TargetRegion
+	for _, region := range regions {
+		targetRegions = append(targetRegions, compute.TargetRegion{Name: to.StringPtr(region)})
+	}
+	return compute.GalleryImageVersion{
+		Name: to.StringPtr(name),
+		GalleryImageVersionProperties: &compute.GalleryImageVersionProperties{
+			ProvisioningState: compute.ProvisioningState2Succeeded,
+			PublishingProfile: &compute.GalleryImageVersionPublishingProfile{
+				ExcludeFromLatest: to.BoolPtr(excludeFromLatest),
+				TargetRegions:     &targetRegions,
+			},
+		},
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package kubernetesupgrade
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kubeSystemNamespace = "kube-system"
+
+// Kinds of the objects removed by a network policy migration, in the order they are removed: daemonsets first so
+// that the policy agents stop enforcing rules, then what they depended on, and the custom resource definitions last.
+const (
+	kindDaemonSet                = "DaemonSet"
+	kindDeployment               = "Deployment"
+	kindService                  = "Service"
+	kindConfigMap                = "ConfigMap"
+	kindRoleBinding              = "RoleBinding"
+	kindRole                     = "Role"
+	kindClusterRoleBinding       = "ClusterRoleBinding"
+	kindClusterRole              = "ClusterRole"
+	kindServiceAccount           = "ServiceAccount"
+	kindCustomResourceDefinition = "CustomResourceDefinition"
+)
+
+var migrationKindOrder = map[string]int{
+	kindDaemonSet:                0,
+	kindDeployment:               1,
+	kindService:                  2,
+	kindConfigMap:                3,
+	kindRoleBinding:              4,
+	kindRole:                     5,
+	kindClusterRoleBinding:       6,
+	kindClusterRole:              7,
+	kindServiceAccount:           8,
+	kindCustomResourceDefinition: 9,
+}
+
+// clusterScopedKinds are the kinds of the objects removed by a network policy migration which are not in the
+// kube-system namespace
+var clusterScopedKinds = map[string]bool{
+	kindClusterRoleBinding:       true,
+	kindClusterRole:              true,
+	kindCustomResourceDefinition: true,
+}
+
+// networkDaemonSets maps the names of the CNI and network policy daemonsets deployed by aks-engine
+// to the network plugin or network policy engine they run, and to the container whose image tag is their version.
+var networkDaemonSets = map[string]struct {
+	name      string
+	container string
+}{
+	"calico-node":     {name: api.NetworkPolicyCalico, container: "calico-node"},
+	"cilium":          {name: api.NetworkPolicyCilium, container: "cilium-agent"},
+	"azure-npm":       {name: api.NetworkPolicyAzure, container: "azure-npm"},
+	"kube-flannel-ds": {name: api.NetworkPluginFlannel, container: "kube-flannel"},
+}
+
+// kubeSystemObject is an object removed by a network policy migration, in the kube-system namespace unless its kind
+// is cluster scoped
+type kubeSystemObject struct {
+	kind string
+	name string
+}
+
+// path returns the namespace and name of the object, or its name if it is cluster scoped
+func (o kubeSystemObject) path() string {
+	if clusterScopedKinds[o.kind] {
+		return o.name
+	}
+	return kubeSystemNamespace + "/" + o.name
+}
+
+// calicoObjects are the objects of calico except its custom resource definitions, the same for calico 3.3 and 3.5 or
+// greater. Objects labeled with addonmanager.kubernetes.io/mode=EnsureExists are never pruned by the addon-manager.
+var calicoObjects = []kubeSystemObject{
+	{kind: kindDaemonSet, name: "calico-node"},
+	{kind: kindDeployment, name: "calico-typha"},
+	{kind: kindDeployment, name: "calico-typha-horizontal-autoscaler"},
+	{kind: kindService, name: "calico-typha"},
+	{kind: kindConfigMap, name: "calico-config"},
+	{kind: kindConfigMap, name: "calico-typha-horizontal-autoscaler"},
+	{kind: kindRoleBinding, name: "typha-cpha"},
+	{kind: kindRole, name: "typha-cpha"},
+	{kind: kindClusterRoleBinding, name: "calico-node"},
+	{kind: kindClusterRoleBinding, name: "typha-cpha"},
+	{kind: kindClusterRole, name: "calico-node"},
+	{kind: kindClusterRole, name: "typha-cpha"},
+	{kind: kindServiceAccount, name: "calico-node"},
+	{kind: kindServiceAccount, name: "typha-cpha"},
+}
+
+// calicoCustomResourceDefinitions are the custom resource definitions of calico, removing them removes the calico
+// network policies and the other calico resources of the cluster
+var calicoCustomResourceDefinitions = []kubeSystemObject{
+	{kind: kindCustomResourceDefinition, name: "bgpconfigurations.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "clusterinformations.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "felixconfigurations.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "globalnetworkpolicies.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "globalnetworksets.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "hostendpoints.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "ippools.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "networkpolicies.crd.projectcalico.org"},
+	{kind: kindCustomResourceDefinition, name: "networksets.crd.projectcalico.org"},
+}
+
+// networkPolicyEngineObjects are the objects removed when a network policy engine is switched off
+var networkPolicyEngineObjects = map[string][]kubeSystemObject{
+	api.NetworkPolicyCalico: append(append([]kubeSystemObject{}, calicoObjects...), calicoCustomResourceDefinitions...),
+	api.NetworkPolicyAzure: {
+		{kind: kindDaemonSet, name: "azure-npm"},
+	},
+}
+
+// networkPolicyMigrationHook removes objects of a network policy engine which can't be upgraded in place
+// between two of its versions, the addon-manager then deploys them again with the spec of the new version.
+type networkPolicyMigrationHook struct {
+	engine      string
+	from        string
+	to          string
+	description string
+	objects     []kubeSystemObject
+}
+
+// networkPolicyMigrationHooks are the version-specific migration hooks, in the order they apply
+var networkPolicyMigrationHooks = []networkPolicyMigrationHook{
+	{
+		engine:      api.NetworkPolicyCalico,
+		from:        "<3.5.0",
+		to:          ">=3.5.0",
+		description: "calico 3.5 moves from the calico CNI to the network plugin of the cluster",
+		objects:     calicoObjects,
+	},
+	{
+		engine:      api.NetworkPolicyCalico,
+		from:        "<3.5.0",
+		to:          ">=3.5.0",
+		description: "the calico 2 configuration custom resources, replaced by felixconfigurations and bgpconfigurations in calico 3.0, are not defined by calico 3.5 or greater",
+		objects: []kubeSystemObject{
+			{kind: kindCustomResourceDefinition, name: "globalbgpconfigs.crd.projectcalico.org"},
+			{kind: kindCustomResourceDefinition, name: "globalfelixconfigs.crd.projectcalico.org"},
+		},
+	},
+}
+
+// NetworkComponent is a CNI or network policy daemonset found in the kube-system namespace of a cluster
+type NetworkComponent struct {
+	// Name is the network plugin or network policy engine run by the daemonset, e.g. calico
+	Name      string
+	DaemonSet string
+	// Version is the tag of the image of the daemonset, e.g. v3.3.1
+	Version string
+}
+
+// NetworkPolicyMigrationStep removes an object of the kube-system namespace, or a cluster scoped object
+type NetworkPolicyMigrationStep struct {
+	Kind        string
+	Name        string
+	Description string
+}
+
+// NetworkPolicyMigrationPlan holds the ordered steps moving a cluster from its installed network policy engine,
+// and version, to the network policy engine of the api model.
+type NetworkPolicyMigrationPlan struct {
+	Installed []NetworkComponent
+	Target    string
+	Steps     []NetworkPolicyMigrationStep
+}
+
+// GetInstalledNetworkComponents returns the CNI and network policy daemonsets installed in the kube-system namespace.
+func GetInstalledNetworkComponents(client armhelpers.KubernetesClient) ([]NetworkComponent, error) {
+	daemonSets, err := client.ListDaemonSets(kubeSystemNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the daemonsets of the kube-system namespace")
+	}
+	var installed []NetworkComponent
+	for _, ds := range daemonSets.Items {
+		component, ok := networkDaemonSets[ds.Name]
+		if !ok {
+			continue
+		}
+		c := NetworkComponent{
+			Name:      component.name,
+			DaemonSet: ds.Name,
+		}
+		for _, container := range ds.Spec.Template.Spec.Containers {
+			if container.Name == component.container {
+				c.Version = getImageTag(container.Image)
+			}
+		}
+		installed = append(installed, c)
+	}
+	sort.Slice(installed, func(i, j int) bool { return installed[i].DaemonSet < installed[j].DaemonSet })
+	return installed, nil
+}
+
+// getTargetNetworkPolicy returns the network policy engine of the api model, or an empty string if there is none.
+func getTargetNetworkPolicy(k *api.KubernetesConfig) string {
+	switch {
+	case k.NetworkPolicy == api.NetworkPolicyCalico:
+		return api.NetworkPolicyCalico
+	case k.NetworkPolicy == api.NetworkPolicyCilium || k.NetworkPlugin == api.NetworkPluginCilium:
+		return api.NetworkPolicyCilium
+	case k.NetworkPlugin == api.NetworkPluginAzure && k.NetworkPolicy == api.NetworkPolicyAzure:
+		return api.NetworkPolicyAzure
+	}
+	return ""
+}
+
+// getTargetVersion returns the version of a network policy engine the upgrade deploys, if it is known.
+func getTargetVersion(k *api.KubernetesConfig, engine string) string {
+	if engine != api.NetworkPolicyCalico {
+		return ""
+	}
+	addon := k.GetAddonByName(api.CalicoAddonName)
+	if i := addon.GetAddonContainersIndexByName("calico-node"); i > -1 {
+		return getImageTag(addon.Containers[i].Image)
+	}
+	return ""
+}
+
+func getImageTag(image string) string {
+	// the tag follows the last colon which is not part of the registry host:port
+	if i := strings.LastIndex(image, ":"); i > -1 && !strings.Contains(image[i:], "/") {
+		return image[i+1:]
+	}
+	return ""
+}
+
+func isVersionLess(version, other string) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	o, err := semver.ParseTolerant(other)
+	if err != nil {
+		return false
+	}
+	return v.LT(o)
+}
+
+func isVersionInRange(version, versionRange string) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	r, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return false
+	}
+	return r(v)
+}
+
+// NewNetworkPolicyMigrationPlan validates moving the installed network components to the network plugin and network policy engine
+// of the api model, and returns the ordered steps of the migration. Network plugins such as cilium and flannel
+// configure the CNI of every node, they can't be switched by an upgrade.
+func NewNetworkPolicyMigrationPlan(installed []NetworkComponent, k *api.KubernetesConfig) (*NetworkPolicyMigrationPlan, error) {
+	if k == nil {
+		k = &api.KubernetesConfig{}
+	}
+	plan := &NetworkPolicyMigrationPlan{
+		Installed: installed,
+		Target:    getTargetNetworkPolicy(k),
+	}
+
+	isInstalled := map[string]bool{}
+	for _, c := range installed {
+		isInstalled[c.Name] = true
+	}
+	for _, plugin := range []string{api.NetworkPluginCilium, api.NetworkPluginFlannel} {
+		isTarget := plan.Target == plugin || k.NetworkPlugin == plugin
+		if isInstalled[plugin] && !isTarget {
+			return nil, errors.Errorf("switching from %s is not supported, %s is the network plugin of the cluster", plugin, plugin)
+		}
+		if !isInstalled[plugin] && isTarget {
+			return nil, errors.Errorf("switching to %s is not supported, %s would replace the network plugin of the cluster", plugin, plugin)
+		}
+	}
+
+	var objects []kubeSystemObject
+	descriptions := map[kubeSystemObject]string{}
+	add := func(o kubeSystemObject, description string) {
+		if _, ok := descriptions[o]; !ok {
+			objects = append(objects, o)
+			descriptions[o] = description
+		}
+	}
+	for _, c := range installed {
+		if c.Name == plan.Target {
+			targetVersion := getTargetVersion(k, c.Name)
+			if c.Version == "" || targetVersion == "" {
+				continue
+			}
+			if isVersionLess(targetVersion, c.Version) {
+				return nil, errors.Errorf("downgrading %s from %s to %s is not supported", c.Name, c.Version, targetVersion)
+			}
+			for _, hook := range networkPolicyMigrationHooks {
+				if hook.engine == c.Name && isVersionInRange(c.Version, hook.from) && isVersionInRange(targetVersion, hook.to) {
+					for _, o := range hook.objects {
+						add(o, fmt.Sprintf("upgrading %s from %s to %s, %s", c.Name, c.Version, targetVersion, hook.description))
+					}
+				}
+			}
+			continue
+		}
+		for _, o := range networkPolicyEngineObjects[c.Name] {
+			if plan.Target == "" {
+				add(o, fmt.Sprintf("removing network policy engine %s", c.Name))
+			} else {
+				add(o, fmt.Sprintf("switching the network policy engine from %s to %s", c.Name, plan.Target))
+			}
+		}
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return migrationKindOrder[objects[i].kind] < migrationKindOrder[objects[j].kind]
+	})
+	for _, o := range objects {
+		plan.Steps = append(plan.Steps, NetworkPolicyMigrationStep{
+			Kind:        o.kind,
+			Name:        o.name,
+			Description: descriptions[o],
+		})
+	}
+	return plan, nil
+}
+
+// Run removes the objects of the migration steps in order, objects which don't exist are skipped.
+func (p *NetworkPolicyMigrationPlan) Run(client armhelpers.KubernetesClient, logger *logrus.Entry) error {
+	for _, step := range p.Steps {
+		path := kubeSystemObject{kind: step.Kind, name: step.Name}.path()
+		logger.Infof("Deleting %s %s: %s", step.Kind, path, step.Description)
+		var err error
+		switch step.Kind {
+		case kindDaemonSet:
+			err = client.DeleteDaemonSet(kubeSystemNamespace, step.Name)
+		case kindDeployment:
+			err = client.DeleteDeployment(kubeSystemNamespace, step.Name)
+		case kindService:
+			err = client.DeleteService(kubeSystemNamespace, step.Name)
+		case kindConfigMap:
+			err = client.DeleteConfigMap(kubeSystemNamespace, step.Name)
+		case kindRoleBinding:
+			err = client.DeleteRoleBinding(kubeSystemNamespace, step.Name)
+		case kindRole:
+			err = client.DeleteRole(kubeSystemNamespace, step.Name)
+		case kindClusterRoleBinding:
+			err = client.DeleteClusterRoleBinding(step.Name)
+		case kindClusterRole:
+			err = client.DeleteClusterRole(step.Name)
+		case kindServiceAccount:
+			err = client.DeleteServiceAccount(&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: kubeSystemNamespace, Name: step.Name}})
+		case kindCustomResourceDefinition:
+			err = client.DeleteCustomResourceDefinition(step.Name)
+		default:
+			err = errors.Errorf("unsupported kind %s", step.Kind)
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "deleting %s %s", step.Kind, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package kubernetesupgrade
+
+import (
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newNetworkDaemonSet(name, container, image string) appsv1.DaemonSet {
+	ds := appsv1.DaemonSet{}
+	ds.Name = name
+	ds.Namespace = kubeSystemNamespace
+	ds.Spec.Template.Spec.Containers = []v1.Container{
+		{
+			Name:  container,
+			Image: image,
+		},
+	}
+	return ds
+}
+
+func newCalicoKubernetesConfig(calicoNodeImage string) *api.KubernetesConfig {
+	return &api.KubernetesConfig{
+		NetworkPlugin: api.NetworkPluginAzure,
+		NetworkPolicy: api.NetworkPolicyCalico,
+		Addons: []api.KubernetesAddon{
+			{
+				Name:    api.CalicoAddonName,
+				Enabled: to.BoolPtr(true),
+				Containers: []api.KubernetesContainerSpec{
+					{
+						Name:  "calico-node",
+						Image: calicoNodeImage,
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Network policy migration tests", func() {
+	It("Should find the CNI and network policy daemonsets of the cluster", func() {
+		client := &armhelpers.MockKubernetesClient{
+			DaemonSetList: &appsv1.DaemonSetList{
+				Items: []appsv1.DaemonSet{
+					newNetworkDaemonSet("kube-proxy", "kube-proxy", "k8s.gcr.io/hyperkube-amd64:v1.15.1"),
+					newNetworkDaemonSet("calico-node", "calico-node", "calico/node:v3.3.1"),
+					newNetworkDaemonSet("azure-npm", "azure-npm", "mcr.microsoft.com/containernetworking/azure-npm:v1.0.25"),
+				},
+			},
+		}
+		installed, err := GetInstalledNetworkComponents(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed).To(Equal([]NetworkComponent{
+			{Name: api.NetworkPolicyAzure, DaemonSet: "azure-npm", Version: "v1.0.25"},
+			{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.3.1"},
+		}))
+
+		client.FailListDaemonSets = true
+		_, err = GetInstalledNetworkComponents(client)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should not migrate a cluster running the network policy engine of the api model", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.8.0"}}
+		plan, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.8.0"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Target).To(Equal(api.NetworkPolicyCalico))
+		Expect(plan.Steps).To(BeEmpty())
+
+		plan, err = NewNetworkPolicyMigrationPlan(nil, &api.KubernetesConfig{NetworkPlugin: api.NetworkPluginKubenet})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Target).To(BeEmpty())
+		Expect(plan.Steps).To(BeEmpty())
+	})
+
+	It("Should replace calico 3.3 resources when upgrading to calico 3.5 or greater", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.3.1"}}
+		plan, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.8.0"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Steps).To(HaveLen(16))
+		Expect(plan.Steps[0].Kind).To(Equal(kindDaemonSet))
+		Expect(plan.Steps[0].Name).To(Equal("calico-node"))
+		Expect(plan.Steps[0].Description).To(ContainSubstring("upgrading calico from v3.3.1 to v3.8.0"))
+		// the resources of calico-3.3.1-cleanup-after-upgrade.yaml
+		for _, o := range calicoObjects {
+			Expect(plan.Steps).To(ContainElement(NetworkPolicyMigrationStep{
+				Kind:        o.kind,
+				Name:        o.name,
+				Description: "upgrading calico from v3.3.1 to v3.8.0, calico 3.5 moves from the calico CNI to the network plugin of the cluster",
+			}))
+		}
+		Expect(plan.Steps[14]).To(Equal(NetworkPolicyMigrationStep{
+			Kind:        kindCustomResourceDefinition,
+			Name:        "globalbgpconfigs.crd.projectcalico.org",
+			Description: "upgrading calico from v3.3.1 to v3.8.0, the calico 2 configuration custom resources, replaced by felixconfigurations and bgpconfigurations in calico 3.0, are not defined by calico 3.5 or greater",
+		}))
+		Expect(plan.Steps[15].Name).To(Equal("globalfelixconfigs.crd.projectcalico.org"))
+		for i := 1; i < len(plan.Steps); i++ {
+			Expect(migrationKindOrder[plan.Steps[i-1].Kind]).To(BeNumerically("<=", migrationKindOrder[plan.Steps[i].Kind]))
+		}
+	})
+
+	It("Should reject downgrading calico", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.8.0"}}
+		_, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.5.0"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("downgrading calico from v3.8.0 to v3.5.0 is not supported"))
+	})
+
+	It("Should remove the previous network policy engine when switching to another one", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyAzure, DaemonSet: "azure-npm", Version: "v1.0.25"}}
+		plan, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.8.0"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Steps).To(Equal([]NetworkPolicyMigrationStep{
+			{Kind: kindDaemonSet, Name: "azure-npm", Description: "switching the network policy engine from azure to calico"},
+		}))
+
+		installed = []NetworkComponent{{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.8.0"}}
+		plan, err = NewNetworkPolicyMigrationPlan(installed, &api.KubernetesConfig{NetworkPlugin: api.NetworkPluginAzure})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Steps).To(HaveLen(23))
+		kinds := []string{kindDaemonSet, kindDeployment, kindDeployment, kindService, kindConfigMap, kindConfigMap, kindRoleBinding, kindRole, kindClusterRoleBinding, kindClusterRoleBinding, kindClusterRole, kindClusterRole, kindServiceAccount, kindServiceAccount}
+		for i := range plan.Steps {
+			kind := kindCustomResourceDefinition
+			if i < len(kinds) {
+				kind = kinds[i]
+			}
+			Expect(plan.Steps[i].Kind).To(Equal(kind))
+			Expect(plan.Steps[i].Description).To(Equal("removing network policy engine calico"))
+		}
+		Expect(plan.Steps[14].Name).To(Equal("bgpconfigurations.crd.projectcalico.org"))
+	})
+
+	It("Should reject switching to or from a network plugin", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyCilium, DaemonSet: "cilium", Version: "v1.4"}}
+		_, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.8.0"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("switching from cilium is not supported, cilium is the network plugin of the cluster"))
+
+		installed = []NetworkComponent{{Name: api.NetworkPolicyAzure, DaemonSet: "azure-npm", Version: "v1.0.25"}}
+		_, err = NewNetworkPolicyMigrationPlan(installed, &api.KubernetesConfig{NetworkPolicy: api.NetworkPolicyCilium})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("switching to cilium is not supported, cilium would replace the network plugin of the cluster"))
+
+		_, err = NewNetworkPolicyMigrationPlan(nil, &api.KubernetesConfig{NetworkPlugin: api.NetworkPluginFlannel})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("switching to flannel is not supported, flannel would replace the network plugin of the cluster"))
+	})
+
+	It("Should delete the objects of the migration steps", func() {
+		installed := []NetworkComponent{{Name: api.NetworkPolicyCalico, DaemonSet: "calico-node", Version: "v3.3.1"}}
+		plan, err := NewNetworkPolicyMigrationPlan(installed, newCalicoKubernetesConfig("calico/node:v3.8.0"))
+		Expect(err).NotTo(HaveOccurred())
+
+		client := &armhelpers.MockKubernetesClient{}
+		Expect(plan.Run(client, log.NewEntry(log.New()))).To(Succeed())
+
+		client.FailDeleteConfigMap = true
+		err = plan.Run(client, log.NewEntry(log.New()))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deleting ConfigMap kube-system/calico-config"))
+
+		client = &armhelpers.MockKubernetesClient{FailDeleteServiceAccount: true}
+		err = plan.Run(client, log.NewEntry(log.New()))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deleting ServiceAccount kube-system/calico-node"))
+
+		client = &armhelpers.MockKubernetesClient{FailDeleteClusterRole: true}
+		err = plan.Run(client, log.NewEntry(log.New()))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deleting ClusterRole calico-node"))
+
+		client = &armhelpers.MockKubernetesClient{FailDeleteCustomResourceDefinition: true}
+		err = plan.Run(client, log.NewEntry(log.New()))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deleting CustomResourceDefinition globalbgpconfigs.crd.projectcalico.org"))
+	})
+})
@@ -42,6 +42,7 @@ type UpgradeAgentNode struct {
 	kubeConfig              string
 	timeout                 time.Duration
 	cordonDrainTimeout      time.Duration
+	LinkedTemplatesStorage  operations.LinkedTemplatesStorage
 }
 
 // DeleteNode takes state/resources of the master/agent node from ListNodeResources
@@ -49,14 +50,7 @@ type UpgradeAgentNode struct {
 // the node
 // The 'drain' flag is used to invoke 'cordon and drain' flow.
 func (kan *UpgradeAgentNode) DeleteNode(vmName *string, drain bool) error {
-	var kubeAPIServerURL string
-
-	if kan.UpgradeContainerService.Properties.HostedMasterProfile != nil {
-		apiServerListeningPort := 443
-		kubeAPIServerURL = fmt.Sprintf("https://%s:%d", kan.UpgradeContainerService.Properties.HostedMasterProfile.FQDN, apiServerListeningPort)
-	} else {
-		kubeAPIServerURL = kan.UpgradeContainerService.Properties.MasterProfile.FQDN
-	}
+	kubeAPIServerURL := getAPIServerURL(kan.UpgradeContainerService.Properties)
 
 	if vmName == nil || *vmName == "" {
 		return errors.Errorf("Error deleting VM: VM name was empty")
@@ -109,7 +103,12 @@ func (kan *UpgradeAgentNode) CreateNode(ctx context.Context, poolName string, ag
 	deploymentSuffix := random.Int31()
 	deploymentName := fmt.Sprintf("agent-%s-%d", time.Now().Format("06-01-02T15.04.05"), deploymentSuffix)
 
-	return armhelpers.DeployTemplateSync(kan.Client, kan.logger, kan.ResourceGroup, deploymentName, kan.TemplateMap, kan.ParametersMap)
+	templateMap, parametersMap, err := operations.SplitTemplateForDeployment(ctx, kan.Client, kan.logger, kan.LinkedTemplatesStorage, kan.UpgradeContainerService, deploymentName, kan.TemplateMap, kan.ParametersMap)
+	if err != nil {
+		return err
+	}
+
+	return armhelpers.DeployTemplateSync(kan.Client, kan.logger, kan.ResourceGroup, deploymentName, templateMap, parametersMap)
 }
 
 // Validate will verify that agent node has been upgraded as expected.
@@ -121,13 +120,7 @@ func (kan *UpgradeAgentNode) Validate(vmName *string) error {
 	nodeName := strings.ToLower(*vmName)
 
 	kan.logger.Infof("Validating %s", nodeName)
-	var apiserverURL string
-	if kan.UpgradeContainerService.Properties.HostedMasterProfile != nil {
-		apiServerListeningPort := 443
-		apiserverURL = fmt.Sprintf("https://%s:%d", kan.UpgradeContainerService.Properties.HostedMasterProfile.FQDN, apiServerListeningPort)
-	} else {
-		apiserverURL = kan.UpgradeContainerService.Properties.MasterProfile.FQDN
-	}
+	apiserverURL := getAPIServerURL(kan.UpgradeContainerService.Properties)
 
 	client, err := kan.Client.GetKubernetesClient(apiserverURL, kan.kubeConfig, interval, kan.timeout)
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/Azure/aks-engine/pkg/armhelpers"
 	"github.com/Azure/aks-engine/pkg/armhelpers/utils"
 	"github.com/Azure/aks-engine/pkg/i18n"
+	"github.com/Azure/aks-engine/pkg/operations"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -39,6 +40,16 @@ type ClusterTopology struct {
 	UpgradedMasterVMs *[]compute.VirtualMachine
 
 	IsVMSSToBeUpgraded IsVMSSToBeUpgradedCb
+
+	// LinkedTemplatesStorage is where agent pools are uploaded to when upgrade templates exceed the size threshold
+	LinkedTemplatesStorage operations.LinkedTemplatesStorage
+
+	// NetworkPolicyMigrationPlan moves the cluster to the network policy engine of the api model once the masters are upgraded
+	NetworkPolicyMigrationPlan *NetworkPolicyMigrationPlan
+
+	// NodeImageOnly replaces the nodes of AgentPoolsToUpgrade with nodes of the OS image of the api model,
+	// the masters and the Kubernetes version are left untouched.
+	NodeImageOnly bool
 }
 
 // AgentPoolScaleSet contains necessary data required to upgrade a VMSS
@@ -103,6 +114,18 @@ func (uc *UpgradeCluster) UpgradeCluster(az armhelpers.AKSEngineClient, kubeConf
 		return uc.Translator.Errorf("Error while querying ARM for resources: %+v", err)
 	}
 
+	// validate the network policy migration before the masters roll
+	if uc.NodeImageOnly {
+		uc.Logger.Info("Skipping the network policy migration of a node image only upgrade")
+	} else if kubeClient == nil {
+		uc.Logger.Warn("Skipping the network policy migration, the installed network components are unknown")
+	} else if err := uc.getNetworkPolicyMigrationPlan(kubeClient); err != nil {
+		uc.Logger.Errorf("Failed to plan the network policy migration: %v", err)
+		if !uc.Force {
+			return err
+		}
+	}
+
 	kc := uc.DataModel.Properties.OrchestratorProfile.KubernetesConfig
 	if kc != nil && kc.IsClusterAutoscalerEnabled() {
 		// pause the cluster-autoscaler before running upgrade and resume it afterward
@@ -126,13 +149,21 @@ func (uc *UpgradeCluster) UpgradeCluster(az armhelpers.AKSEngineClient, kubeConf
 	}
 
 	upgradeVersion := uc.DataModel.Properties.OrchestratorProfile.OrchestratorVersion
-	uc.Logger.Infof("Upgrading to Kubernetes version %s", upgradeVersion)
+	if uc.NodeImageOnly {
+		uc.Logger.Infof("Upgrading the node image of the agent pools, keeping Kubernetes version %s", upgradeVersion)
+	} else {
+		uc.Logger.Infof("Upgrading to Kubernetes version %s", upgradeVersion)
+	}
 
 	if err := uc.getUpgradeWorkflow(kubeConfig, aksEngineVersion).RunUpgrade(); err != nil {
 		return err
 	}
 
-	uc.Logger.Infof("Cluster upgraded successfully to Kubernetes version %s", upgradeVersion)
+	if uc.NodeImageOnly {
+		uc.Logger.Info("Node image of the agent pools upgraded successfully")
+	} else {
+		uc.Logger.Infof("Cluster upgraded successfully to Kubernetes version %s", upgradeVersion)
+	}
 	return nil
 }
 
@@ -165,6 +196,27 @@ func (uc *UpgradeCluster) SetClusterAutoscalerReplicaCount(kubeClient armhelpers
 	return count, nil
 }
 
+// getNetworkPolicyMigrationPlan compares the CNI and network policy daemonsets of the cluster to the api model,
+// and validates the migration between them.
+func (uc *UpgradeCluster) getNetworkPolicyMigrationPlan(kubeClient armhelpers.KubernetesClient) error {
+	installed, err := GetInstalledNetworkComponents(kubeClient)
+	if err != nil {
+		return err
+	}
+	plan, err := NewNetworkPolicyMigrationPlan(installed, uc.DataModel.Properties.OrchestratorProfile.KubernetesConfig)
+	if err != nil {
+		return err
+	}
+	for _, c := range plan.Installed {
+		uc.Logger.Infof("Found %s %s in daemonset %s", c.Name, c.Version, c.DaemonSet)
+	}
+	for _, step := range plan.Steps {
+		uc.Logger.Infof("Network policy migration will delete %s %s/%s: %s", step.Kind, kubeSystemNamespace, step.Name, step.Description)
+	}
+	uc.NetworkPolicyMigrationPlan = plan
+	return nil
+}
+
 func (uc *UpgradeCluster) getUpgradeWorkflow(kubeConfig string, aksEngineVersion string) UpgradeWorkFlow {
 	if uc.UpgradeWorkFlow != nil {
 		return uc.UpgradeWorkFlow
@@ -188,6 +240,12 @@ func (uc *UpgradeCluster) getClusterNodeStatus(kubeClient armhelpers.KubernetesC
 			if uc.IsVMSSToBeUpgraded != nil && !uc.IsVMSSToBeUpgraded(*vmScaleSet.Name, uc.DataModel) {
 				continue
 			}
+			if uc.NodeImageOnly {
+				if poolName := getScaleSetPoolName(vmScaleSet); !uc.AgentPoolsToUpgrade[poolName] {
+					uc.Logger.Infof("Skipping VMSS %s of pool %s, its node image is not upgraded", *vmScaleSet.Name, poolName)
+					continue
+				}
+			}
 			for vmScaleSetVMsPage, err := uc.Client.ListVirtualMachineScaleSetVMs(ctx, resourceGroup, *vmScaleSet.Name); vmScaleSetVMsPage.NotDone(); err = vmScaleSetVMsPage.NextWithContext(ctx) {
 				if err != nil {
 					return err
@@ -212,7 +270,7 @@ func (uc *UpgradeCluster) getClusterNodeStatus(kubeClient armhelpers.KubernetesC
 				}
 				for _, vm := range vmScaleSetVMsPage.Values() {
 					currentVersion := uc.getNodeVersion(kubeClient, strings.ToLower(*vm.VirtualMachineScaleSetVMProperties.OsProfile.ComputerName), vm.Tags, *vm.VirtualMachineScaleSetVMProperties.LatestModelApplied)
-					if uc.Force {
+					if uc.Force || uc.NodeImageOnly {
 						if currentVersion == "" {
 							currentVersion = "Unknown"
 						}
@@ -222,7 +280,7 @@ func (uc *UpgradeCluster) getClusterNodeStatus(kubeClient armhelpers.KubernetesC
 						uc.Logger.Infof("Skipping VM: %s for upgrade as the orchestrator version could not be determined.", *vm.Name)
 						continue
 					}
-					if uc.Force || currentVersion != goalVersion {
+					if uc.Force || uc.NodeImageOnly || currentVersion != goalVersion {
 						uc.Logger.Infof(
 							"VM %s in VMSS %s has a current version of %s and a desired version of %s. Upgrading this node.",
 							*vm.Name,
@@ -264,7 +322,12 @@ func (uc *UpgradeCluster) getClusterNodeStatus(kubeClient armhelpers.KubernetesC
 				availabilitySetIDs = append(availabilitySetIDs, *vm.AvailabilitySet.ID)
 			}
 
-			if uc.Force {
+			if uc.NodeImageOnly {
+				// the masters are left untouched, and every VM of the agent pools to upgrade is replaced
+				if !strings.Contains(*(vm.Name), MasterVMNamePrefix) {
+					uc.addVMToAgentPool(vm, true)
+				}
+			} else if uc.Force {
 				if currentVersion == "" {
 					currentVersion = "Unknown"
 				}
@@ -299,6 +362,18 @@ func (uc *UpgradeCluster) getClusterNodeStatus(kubeClient armhelpers.KubernetesC
 	return nil
 }
 
+// getScaleSetPoolName returns the agent pool of a VMSS, from its poolName tag or else from its name
+func getScaleSetPoolName(vmScaleSet compute.VirtualMachineScaleSet) string {
+	if vmScaleSet.Tags != nil && vmScaleSet.Tags["poolName"] != nil {
+		return *vmScaleSet.Tags["poolName"]
+	}
+	if poolName, _, err := utils.VmssNameParts(*vmScaleSet.Name); err == nil {
+		return poolName
+	}
+	poolName, _ := utils.WindowsVmssNameParts(*vmScaleSet.Name)
+	return poolName
+}
+
 func (uc *UpgradeCluster) upgradable(currentVersion string) error {
 	nodeVersion := &api.OrchestratorProfile{
 		OrchestratorType:    api.Kubernetes,
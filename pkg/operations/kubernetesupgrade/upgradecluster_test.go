@@ -15,10 +15,12 @@ import (
 	"github.com/Azure/aks-engine/pkg/i18n"
 	. "github.com/Azure/aks-engine/pkg/test"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	log "github.com/sirupsen/logrus"
 	logtest "github.com/sirupsen/logrus/hooks/test"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -128,6 +130,79 @@ var _ = Describe("Upgrade Kubernetes cluster tests", func() {
 		Expect(err.Error()).To(Equal("DeleteVirtualMachine failed"))
 	})
 
+	It("Should validate the network policy migration before upgrading masters", func() {
+		cs := api.CreateMockContainerService("testcluster", "1.10.13", 1, 1, false)
+		uc := UpgradeCluster{
+			Translator: &i18n.Translator{},
+			Logger:     log.NewEntry(log.New()),
+		}
+
+		mockClient := armhelpers.MockAKSEngineClient{
+			MockKubernetesClient: &armhelpers.MockKubernetesClient{
+				DaemonSetList: &appsv1.DaemonSetList{
+					Items: []appsv1.DaemonSet{newNetworkDaemonSet("cilium", "cilium-agent", "docker.io/cilium/cilium:v1.4")},
+				},
+			},
+		}
+		mockClient.FailDeleteVirtualMachine = true
+		uc.Client = &mockClient
+
+		uc.ClusterTopology = ClusterTopology{}
+		uc.SubscriptionID = "DEC923E3-1EF1-4745-9516-37906D56DEC4"
+		uc.ResourceGroup = "TestRg"
+		uc.DataModel = cs
+		uc.NameSuffix = "12345678"
+		uc.AgentPoolsToUpgrade = map[string]bool{"agentpool1": true}
+
+		err := uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("switching from cilium is not supported, cilium is the network plugin of the cluster"))
+
+		// with --force the upgrade goes on to the masters
+		uc.Force = true
+		err = uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("DeleteVirtualMachine failed"))
+	})
+
+	It("Should remove the previous network policy engine during upgrade operation", func() {
+		cs := api.CreateMockContainerService("testcluster", "1.10.13", 1, 1, false)
+		cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPlugin = api.NetworkPluginAzure
+		cs.Properties.OrchestratorProfile.KubernetesConfig.NetworkPolicy = ""
+		uc := UpgradeCluster{
+			Translator: &i18n.Translator{},
+			Logger:     log.NewEntry(log.New()),
+		}
+
+		mockClient := armhelpers.MockAKSEngineClient{
+			MockKubernetesClient: &armhelpers.MockKubernetesClient{
+				DaemonSetList: &appsv1.DaemonSetList{
+					Items: []appsv1.DaemonSet{newNetworkDaemonSet("azure-npm", "azure-npm", "mcr.microsoft.com/containernetworking/azure-npm:v1.0.25")},
+				},
+			},
+		}
+		uc.Client = &mockClient
+
+		uc.ClusterTopology = ClusterTopology{}
+		uc.SubscriptionID = "DEC923E3-1EF1-4745-9516-37906D56DEC4"
+		uc.ResourceGroup = "TestRg"
+		uc.DataModel = cs
+		uc.NameSuffix = "12345678"
+		uc.AgentPoolsToUpgrade = map[string]bool{"agentpool1": true}
+
+		err := uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uc.NetworkPolicyMigrationPlan.Steps).To(Equal([]NetworkPolicyMigrationStep{
+			{Kind: "DaemonSet", Name: "azure-npm", Description: "removing network policy engine azure"},
+		}))
+
+		// the upgrade stops before the agents if the migration fails
+		mockClient.MockKubernetesClient.FailDeleteDaemonSet = true
+		err = uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("deleting DaemonSet kube-system/azure-npm"))
+	})
+
 	It("Should return error message when failing to deploy template during upgrade operation", func() {
 		cs := api.CreateMockContainerService("testcluster", "1.10.13", 1, 1, false)
 		uc := UpgradeCluster{
@@ -337,6 +412,39 @@ var _ = Describe("Upgrade Kubernetes cluster tests", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(uc.AgentPoolScaleSetsToUpgrade[0].VMsToUpgrade).To(HaveLen(4))
 		})
+		It("Should only upgrade the scale sets of the agent pools to upgrade when NodeImageOnly is true", func() {
+			mockClient.FakeListVirtualMachineScaleSetsResult = func() []compute.VirtualMachineScaleSet {
+				sku := compute.Sku{}
+				location := "eastus"
+				var scaleSets []compute.VirtualMachineScaleSet
+				for _, poolName := range []string{"agentpool1", "agentpool2"} {
+					name := "k8s-" + poolName + "-12345678-vmss"
+					pool := poolName
+					scaleSets = append(scaleSets, compute.VirtualMachineScaleSet{
+						Name:                             &name,
+						Sku:                              &sku,
+						Location:                         &location,
+						Tags:                             map[string]*string{"poolName": &pool},
+						VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{},
+					})
+				}
+				return scaleSets
+			}
+			mockClient.FakeListVirtualMachineScaleSetVMsResult = func() []compute.VirtualMachineScaleSetVM {
+				return []compute.VirtualMachineScaleSetVM{
+					mockClient.MakeFakeVirtualMachineScaleSetVM("Kubernetes:1.9.10"),
+					mockClient.MakeFakeVirtualMachineScaleSetVM("Kubernetes:1.9.10"),
+				}
+			}
+			uc.AgentPoolsToUpgrade = map[string]bool{"agentpool2": true}
+			uc.NodeImageOnly = true
+
+			err := uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uc.AgentPoolScaleSetsToUpgrade).To(HaveLen(1))
+			Expect(uc.AgentPoolScaleSetsToUpgrade[0].Name).To(Equal("k8s-agentpool2-12345678-vmss"))
+			Expect(uc.AgentPoolScaleSetsToUpgrade[0].VMsToUpgrade).To(HaveLen(2))
+		})
 		It("Should not skip any VMs with upper case index", func() {
 			mockClient.FakeListVirtualMachineScaleSetVMsResult = func() []compute.VirtualMachineScaleSetVM {
 				return []compute.VirtualMachineScaleSetVM{
@@ -614,6 +722,40 @@ var _ = Describe("Upgrade Kubernetes cluster tests", func() {
 			Expect(*uc.MasterVMs).To(HaveLen(1))
 			Expect(*uc.UpgradedMasterVMs).To(HaveLen(0))
 		})
+		It("Should skip masters and upgrade every VM of the agent pools to upgrade when NodeImageOnly is true", func() {
+			mockClient.FakeListVirtualMachineResult = func() []compute.VirtualMachine {
+				return []compute.VirtualMachine{
+					mockClient.MakeFakeVirtualMachine("k8s-master-12345678-0", "Kubernetes:1.9.10"),
+					mockClient.MakeFakeVirtualMachine("k8s-agentpool1-12345678-0", "Kubernetes:1.9.10"),
+				}
+			}
+			uc.AgentPoolsToUpgrade = map[string]bool{"agentpool1": true}
+			uc.NodeImageOnly = true
+
+			err := uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*uc.MasterVMs).To(HaveLen(0))
+			Expect(*uc.UpgradedMasterVMs).To(HaveLen(0))
+			Expect(*uc.AgentPools["agentpool1"].AgentVMs).To(HaveLen(1))
+		})
+		It("Should only upgrade the VMs of the node pool to upgrade when NodeImageOnly is true", func() {
+			mockClient.FakeListVirtualMachineResult = func() []compute.VirtualMachine {
+				otherPoolVM := mockClient.MakeFakeVirtualMachine("k8s-agentpool2-12345678-0", "Kubernetes:1.9.10")
+				poolName := "agentpool2"
+				otherPoolVM.Tags["poolName"] = &poolName
+				return []compute.VirtualMachine{
+					mockClient.MakeFakeVirtualMachine("k8s-agentpool1-12345678-0", "Kubernetes:1.9.10"),
+					otherPoolVM,
+				}
+			}
+			uc.AgentPoolsToUpgrade = map[string]bool{"agentpool2": true}
+			uc.NodeImageOnly = true
+
+			err := uc.UpgradeCluster(&mockClient, "kubeConfig", TestAKSEngineVersion)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uc.AgentPools).NotTo(HaveKey("agentpool1"))
+			Expect(*uc.AgentPools["agentpool2"].AgentVMs).To(HaveLen(1))
+		})
 		It("Should set platform fault domain count based on availability sets", func() {
 			cs := api.CreateMockContainerService("testcluster", "1.10.13", 3, 2, false)
 			cs.Properties.OrchestratorProfile.KubernetesConfig = &api.KubernetesConfig{}
@@ -958,4 +1100,20 @@ var _ = Describe("Upgrade Kubernetes cluster tests", func() {
 
 		Expect(len(newNode.Spec.Taints)).To(Equal(2))
 	})
+
+	It("Should reach private clusters at the apiserver of the kubeconfig", func() {
+		cs := api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, false)
+		cs.Properties.MasterProfile.FQDN = "testcluster.westus2.cloudapp.azure.com"
+		Expect(getAPIServerURL(cs.Properties)).To(Equal("testcluster.westus2.cloudapp.azure.com"))
+
+		cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateCluster = &api.PrivateCluster{
+			Enabled: to.BoolPtr(true),
+		}
+		Expect(getAPIServerURL(cs.Properties)).To(BeEmpty())
+
+		cs.Properties.HostedMasterProfile = &api.HostedMasterProfile{
+			FQDN: "hosted.westus2.azmk8s.io",
+		}
+		Expect(getAPIServerURL(cs.Properties)).To(Equal("https://hosted.westus2.azmk8s.io:443"))
+	})
 })
@@ -33,6 +33,7 @@ type UpgradeMasterNode struct {
 	Client                  armhelpers.AKSEngineClient
 	kubeConfig              string
 	timeout                 time.Duration
+	LinkedTemplatesStorage  operations.LinkedTemplatesStorage
 }
 
 // DeleteNode takes state/resources of the master/agent node from ListNodeResources
@@ -62,12 +63,17 @@ func (kmn *UpgradeMasterNode) CreateNode(ctx context.Context, poolName string, m
 	deploymentSuffix := random.Int31()
 	deploymentName := fmt.Sprintf("master-%s-%d", time.Now().Format("06-01-02T15.04.05"), deploymentSuffix)
 
-	_, err := kmn.Client.DeployTemplate(
+	templateMap, parametersMap, err := operations.SplitTemplateForDeployment(ctx, kmn.Client, kmn.logger, kmn.LinkedTemplatesStorage, kmn.UpgradeContainerService, deploymentName, kmn.TemplateMap, kmn.ParametersMap)
+	if err != nil {
+		return err
+	}
+
+	_, err = kmn.Client.DeployTemplate(
 		ctx,
 		kmn.ResourceGroup,
 		deploymentName,
-		kmn.TemplateMap,
-		kmn.ParametersMap)
+		templateMap,
+		parametersMap)
 	return err
 }
 
@@ -85,7 +91,7 @@ func (kmn *UpgradeMasterNode) Validate(vmName *string) error {
 		return nil
 	}
 
-	apiserverURL := kmn.UpgradeContainerService.Properties.MasterProfile.FQDN
+	apiserverURL := getAPIServerURL(kmn.UpgradeContainerService.Properties)
 
 	client, err := kmn.Client.GetKubernetesClient(apiserverURL, kmn.kubeConfig, interval, kmn.timeout)
 	if err != nil {
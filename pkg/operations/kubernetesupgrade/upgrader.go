@@ -69,8 +69,14 @@ func (ku *Upgrader) Init(translator *i18n.Translator, logger *logrus.Entry, clus
 func (ku *Upgrader) RunUpgrade() error {
 	ctx, cancel := context.WithTimeout(context.Background(), clusterUpgradeTimeout)
 	defer cancel()
-	if err := ku.upgradeMasterNodes(ctx); err != nil {
-		return err
+	if !ku.ClusterTopology.NodeImageOnly {
+		if err := ku.upgradeMasterNodes(ctx); err != nil {
+			return err
+		}
+
+		if err := ku.migrateNetworkPolicy(); err != nil {
+			return err
+		}
 	}
 
 	if err := ku.upgradeAgentScaleSets(ctx); err != nil {
@@ -80,6 +86,22 @@ func (ku *Upgrader) RunUpgrade() error {
 	return ku.upgradeAgentPools(ctx)
 }
 
+// migrateNetworkPolicy runs the network policy migration plan. It runs once the masters are upgraded,
+// as the addon-manager of the previous masters would deploy the removed objects again, and before the agents
+// are upgraded, so that they join the cluster with the network policy engine of the api model.
+func (ku *Upgrader) migrateNetworkPolicy() error {
+	plan := ku.ClusterTopology.NetworkPolicyMigrationPlan
+	if plan == nil || len(plan.Steps) == 0 {
+		return nil
+	}
+	ku.logger.Infof("Migrating the network policy engine to %q...", plan.Target)
+	client, err := ku.getKubernetesClient(defaultTimeout)
+	if err != nil {
+		return ku.Translator.Errorf("error getting a Kubernetes client to migrate the network policy engine: %s", err.Error())
+	}
+	return plan.Run(client, ku.logger)
+}
+
 // Validate will run validation post upgrade
 func (ku *Upgrader) Validate() error {
 	return nil
@@ -123,6 +145,7 @@ func (ku *Upgrader) upgradeMasterNodes(ctx context.Context) error {
 	upgradeMasterNode.SubscriptionID = ku.ClusterTopology.SubscriptionID
 	upgradeMasterNode.Client = ku.Client
 	upgradeMasterNode.kubeConfig = ku.kubeConfig
+	upgradeMasterNode.LinkedTemplatesStorage = ku.ClusterTopology.LinkedTemplatesStorage
 	if ku.stepTimeout == nil {
 		upgradeMasterNode.timeout = defaultTimeout
 	} else {
@@ -275,6 +298,7 @@ func (ku *Upgrader) upgradeAgentPools(ctx context.Context) error {
 		upgradeAgentNode.ResourceGroup = ku.ClusterTopology.ResourceGroup
 		upgradeAgentNode.Client = ku.Client
 		upgradeAgentNode.kubeConfig = ku.kubeConfig
+		upgradeAgentNode.LinkedTemplatesStorage = ku.ClusterTopology.LinkedTemplatesStorage
 		if ku.stepTimeout == nil {
 			upgradeAgentNode.timeout = defaultTimeout
 		} else {
@@ -480,6 +504,11 @@ func (ku *Upgrader) upgradeAgentScaleSets(ctx context.Context) error {
 		deploymentSuffix := random.Int31()
 		deploymentName := fmt.Sprintf("agentscaleset-%s-%d", time.Now().Format("06-01-02T15.04.05"), deploymentSuffix)
 
+		templateMap, parametersMap, err = operations.SplitTemplateForDeployment(ctx, ku.Client, ku.logger, ku.ClusterTopology.LinkedTemplatesStorage, ku.DataModel, deploymentName, templateMap, parametersMap)
+		if err != nil {
+			return err
+		}
+
 		ku.logger.Infof("Deploying the agent scale sets ARM template...")
 		_, err = ku.Client.DeployTemplate(
 			ctx,
@@ -777,19 +806,27 @@ func (ku *Upgrader) copyCustomNodeProperties(client armhelpers.KubernetesClient,
 }
 
 func (ku *Upgrader) getKubernetesClient(timeout time.Duration) (armhelpers.KubernetesClient, error) {
-	apiserverURL := ku.DataModel.Properties.GetMasterFQDN()
-	if ku.DataModel.Properties.HostedMasterProfile != nil {
-		apiServerListeningPort := 443
-		apiserverURL = fmt.Sprintf("https://%s:%d", apiserverURL, apiServerListeningPort)
-	}
-
 	return ku.Client.GetKubernetesClient(
-		apiserverURL,
+		getAPIServerURL(ku.DataModel.Properties),
 		ku.kubeConfig,
 		interval,
 		timeout)
 }
 
+// getAPIServerURL returns the URL the upgrade reaches the API server at.
+// Private clusters have no public FQDN, they are reached at the server of the kubeconfig,
+// which is the internal address of the masters or the FQDN of their private DNS zone.
+func getAPIServerURL(p *api.Properties) string {
+	if p.HostedMasterProfile != nil {
+		apiServerListeningPort := 443
+		return fmt.Sprintf("https://%s:%d", p.HostedMasterProfile.FQDN, apiServerListeningPort)
+	}
+	if p.OrchestratorProfile != nil && p.OrchestratorProfile.IsPrivateCluster() {
+		return ""
+	}
+	return p.MasterProfile.FQDN
+}
+
 // return unused index within the range of agent indices, or subsequent index
 func getAvailableIndex(vms map[int]*vmInfo) int {
 	maxIndex := 0
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LinkedTemplatesContainer is the storage container linked templates are uploaded to
+	LinkedTemplatesContainer = "aksengine-linkedtemplates"
+	// LinkedTemplatesSasExpiry is how long the SAS token granting ARM read access to uploaded linked templates is valid
+	LinkedTemplatesSasExpiry = 24 * time.Hour
+)
+
+// LinkedTemplatesStorage is the storage account templates exceeding SizeThreshold bytes are split to and uploaded
+type LinkedTemplatesStorage struct {
+	ResourceGroup  string
+	StorageAccount string
+	// SizeThreshold defaults to engine.DefaultTemplateSizeThreshold when zero
+	SizeThreshold int
+}
+
+// SplitTemplateForDeployment returns the template and parameters to deploy. If the template is larger than the size
+// threshold, the resources of each agent pool are moved to linked templates which are uploaded to the storage account
+// under deploymentName, and copies of template and parameters linking them are returned. Otherwise template and
+// parameters are returned unchanged.
+func SplitTemplateForDeployment(ctx context.Context, az armhelpers.AKSEngineClient, logger *log.Entry, storage LinkedTemplatesStorage, cs *api.ContainerService, deploymentName string, template, parameters map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	threshold := storage.SizeThreshold
+	if threshold == 0 {
+		threshold = engine.DefaultTemplateSizeThreshold
+	}
+	size, err := engine.TemplateSize(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size <= threshold {
+		return template, parameters, nil
+	}
+	if storage.StorageAccount == "" {
+		return nil, nil, errors.Errorf("template is %d bytes, larger than the %d bytes threshold, and no storage account was provided for linked templates", size, threshold)
+	}
+	logger.Infof("template is %d bytes, splitting agent pools into linked templates", size)
+
+	var splitTemplate, splitParameters map[string]interface{}
+	if err = deepCopyJSON(template, &splitTemplate); err != nil {
+		return nil, nil, errors.Wrap(err, "copying template")
+	}
+	if err = deepCopyJSON(parameters, &splitParameters); err != nil {
+		return nil, nil, errors.Wrap(err, "copying parameters")
+	}
+	poolNames := []string{}
+	for _, profile := range cs.Properties.AgentPoolProfiles {
+		poolNames = append(poolNames, profile.Name)
+	}
+	linkedTemplates, err := engine.SplitTemplate(splitTemplate, poolNames)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "splitting template")
+	}
+
+	location, sasToken, err := UploadLinkedTemplates(ctx, az, logger, storage, deploymentName, linkedTemplates)
+	if err != nil {
+		return nil, nil, err
+	}
+	splitParameters[engine.LinkedTemplatesLocationParameter] = map[string]interface{}{
+		"value": location,
+	}
+	splitParameters[engine.LinkedTemplatesSasTokenParameter] = map[string]interface{}{
+		"value": sasToken,
+	}
+	return splitTemplate, splitParameters, nil
+}
+
+// UploadLinkedTemplates uploads the linked templates to the storage account under the directory prefix and returns
+// their location and a read-only SAS token, the values of the linkedTemplatesLocation and linkedTemplatesSasToken
+// template parameters.
+func UploadLinkedTemplates(ctx context.Context, az armhelpers.AKSEngineClient, logger *log.Entry, storage LinkedTemplatesStorage, prefix string, linkedTemplates []engine.LinkedTemplate) (string, string, error) {
+	storageClient, err := az.GetStorageClient(ctx, storage.ResourceGroup, storage.StorageAccount)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "getting client for storage account %s", storage.StorageAccount)
+	}
+	if _, err = storageClient.CreateContainer(LinkedTemplatesContainer, nil); err != nil {
+		return "", "", errors.Wrapf(err, "creating container %s", LinkedTemplatesContainer)
+	}
+	for i := range linkedTemplates {
+		b, err := linkedTemplates[i].MarshalTemplate()
+		if err != nil {
+			return "", "", err
+		}
+		blobName := path.Join(prefix, linkedTemplates[i].FileName)
+		logger.Debugf("uploading linked template %s to storage account %s", blobName, storage.StorageAccount)
+		if err = storageClient.SaveBlockBlob(LinkedTemplatesContainer, blobName, b, nil); err != nil {
+			return "", "", errors.Wrapf(err, "uploading linked template %s", blobName)
+		}
+	}
+
+	sasURI, err := storageClient.GetContainerSASURI(LinkedTemplatesContainer, time.Now().Add(LinkedTemplatesSasExpiry))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "generating SAS token for container %s", LinkedTemplatesContainer)
+	}
+	containerURI, query := sasURI, ""
+	if i := strings.Index(sasURI, "?"); i >= 0 {
+		containerURI, query = sasURI[:i], sasURI[i:]
+	}
+	return containerURI + "/" + prefix, query, nil
+}
+
+func deepCopyJSON(in map[string]interface{}, out *map[string]interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/engine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Split template for deployment tests", func() {
+	var (
+		cs         *api.ContainerService
+		template   map[string]interface{}
+		parameters map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		cs = &api.ContainerService{
+			Properties: &api.Properties{
+				AgentPoolProfiles: []*api.AgentPoolProfile{
+					{
+						Name: "agentpool1",
+					},
+				},
+			},
+		}
+		template = map[string]interface{}{
+			"parameters": map[string]interface{}{
+				"location": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"variables": map[string]interface{}{
+				"agentpool1VMNamePrefix": "k8s-agentpool1-",
+				"agentpool1Offset":       0,
+				"vnetID":                 "vnet",
+			},
+			"resources": []interface{}{
+				map[string]interface{}{
+					"type":     "Microsoft.Network/virtualNetworks",
+					"name":     "[variables('vnetID')]",
+					"location": "[parameters('location')]",
+				},
+				map[string]interface{}{
+					"type":      "Microsoft.Compute/virtualMachines",
+					"name":      "[concat(variables('agentpool1VMNamePrefix'), copyIndex(variables('agentpool1Offset')))]",
+					"location":  "[parameters('location')]",
+					"dependsOn": []interface{}{"[variables('vnetID')]"},
+				},
+			},
+		}
+		parameters = map[string]interface{}{
+			"location": map[string]interface{}{
+				"value": "westus2",
+			},
+		}
+	})
+
+	It("Should return the template unchanged when below the threshold", func() {
+		mockClient := armhelpers.MockAKSEngineClient{}
+		storage := LinkedTemplatesStorage{}
+		t, p, err := SplitTemplateForDeployment(context.Background(), &mockClient, log.NewEntry(log.New()), storage, cs, "deployment", template, parameters)
+		Expect(err).To(BeNil())
+		Expect(t).To(Equal(template))
+		Expect(p).To(Equal(parameters))
+	})
+
+	It("Should return error when above the threshold without a storage account", func() {
+		mockClient := armhelpers.MockAKSEngineClient{}
+		storage := LinkedTemplatesStorage{SizeThreshold: 1}
+		_, _, err := SplitTemplateForDeployment(context.Background(), &mockClient, log.NewEntry(log.New()), storage, cs, "deployment", template, parameters)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Should split and upload the template when above the threshold", func() {
+		mockClient := armhelpers.MockAKSEngineClient{}
+		storage := LinkedTemplatesStorage{ResourceGroup: "rg", StorageAccount: "storageaccount", SizeThreshold: 1}
+		t, p, err := SplitTemplateForDeployment(context.Background(), &mockClient, log.NewEntry(log.New()), storage, cs, "deployment", template, parameters)
+		Expect(err).To(BeNil())
+		Expect(t["resources"]).To(HaveLen(2))
+		Expect(t["resources"].([]interface{})[1].(map[string]interface{})["name"]).To(Equal(engine.LinkedTemplateDeploymentName("agentpool1")))
+		Expect(p[engine.LinkedTemplatesLocationParameter]).To(Equal(map[string]interface{}{
+			"value": "https://storageaccount.blob.core.windows.net/aksengine-linkedtemplates/deployment",
+		}))
+		Expect(p[engine.LinkedTemplatesSasTokenParameter].(map[string]interface{})["value"]).To(HavePrefix("?"))
+		// the input template is left untouched for later deployments
+		Expect(template["resources"]).To(HaveLen(2))
+		Expect(template["parameters"]).NotTo(HaveKey(engine.LinkedTemplatesLocationParameter))
+		Expect(parameters).NotTo(HaveKey(engine.LinkedTemplatesLocationParameter))
+	})
+
+	It("Should return error when the upload fails", func() {
+		mockClient := armhelpers.MockAKSEngineClient{FailGetStorageClient: true}
+		storage := LinkedTemplatesStorage{ResourceGroup: "rg", StorageAccount: "storageaccount", SizeThreshold: 1}
+		_, _, err := SplitTemplateForDeployment(context.Background(), &mockClient, log.NewEntry(log.New()), storage, cs, "deployment", template, parameters)
+		Expect(err).NotTo(BeNil())
+	})
+})
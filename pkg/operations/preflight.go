@@ -0,0 +1,301 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/api/common"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/aks-engine/pkg/helpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// regionalCoresUsageName is the name of the usage of the total regional vCPUs of a subscription
+const regionalCoresUsageName = "cores"
+
+// VMRequirement is a number of VMs of the same size created by an operation
+type VMRequirement struct {
+	// Pool is the name of the pool of the VMs, e.g. master
+	Pool   string
+	VMSize string
+	Count  int
+	Zones  []string
+}
+
+// GetClusterVMRequirements returns the VMs created by the deployment of a cluster
+func GetClusterVMRequirements(p *api.Properties) []VMRequirement {
+	var requirements []VMRequirement
+	if p.MasterProfile != nil {
+		requirements = append(requirements, VMRequirement{
+			Pool:   "master",
+			VMSize: p.MasterProfile.VMSize,
+			Count:  p.MasterProfile.Count,
+			Zones:  p.MasterProfile.AvailabilityZones,
+		})
+	}
+	for _, pool := range p.AgentPoolProfiles {
+		requirements = append(requirements, VMRequirement{
+			Pool:   pool.Name,
+			VMSize: pool.VMSize,
+			Count:  pool.Count,
+			Zones:  pool.AvailabilityZones,
+		})
+	}
+	if p.OrchestratorProfile != nil && p.OrchestratorProfile.KubernetesConfig != nil && p.OrchestratorProfile.KubernetesConfig.PrivateJumpboxProvision() {
+		requirements = append(requirements, VMRequirement{
+			Pool:   "jumpbox",
+			VMSize: p.OrchestratorProfile.KubernetesConfig.PrivateCluster.JumpboxProfile.VMSize,
+			Count:  1,
+		})
+	}
+	return requirements
+}
+
+// GetUpgradeSurgeVMRequirements returns the extra VM created in each agent pool while its nodes are upgraded. The
+// agent pools are upgraded one after the other, so each set of requirements must be checked separately.
+func GetUpgradeSurgeVMRequirements(p *api.Properties) [][]VMRequirement {
+	var requirementSets [][]VMRequirement
+	for _, pool := range p.AgentPoolProfiles {
+		requirementSets = append(requirementSets, []VMRequirement{{
+			Pool:   pool.Name,
+			VMSize: pool.VMSize,
+			Count:  1,
+			Zones:  pool.AvailabilityZones,
+		}})
+	}
+	return requirementSets
+}
+
+// CheckComputeCapacity verifies that the subscription can create the required VMs in the location: their sizes must be
+// offered in the location and zones, and they must not exceed the regional and VM family vCPU quotas. Each set of
+// requirements is checked on its own. The check is skipped with a warning if the resource SKUs or the compute usages
+// cannot be listed, e.g. on clouds which do not implement these APIs.
+func CheckComputeCapacity(ctx context.Context, az armhelpers.AKSEngineClient, logger *log.Entry, location string, requirementSets ...[]VMRequirement) error {
+	vmSizes, err := getVMSizes(ctx, az, location)
+	if err != nil {
+		logger.Warnf("Skipping the pre-flight check of the compute capacity, failed to list the resource SKUs: %s", err)
+		return nil
+	}
+	if len(vmSizes) == 0 {
+		logger.Warnf("Skipping the pre-flight check of the compute capacity, no VM size is listed in location %s", location)
+		return nil
+	}
+	usages, err := getComputeUsages(ctx, az, location)
+	if err != nil {
+		logger.Warnf("Skipping the pre-flight check of the compute capacity, failed to list the compute usages: %s", err)
+		return nil
+	}
+
+	var problems []string
+	found := map[string]bool{}
+	for _, requirements := range requirementSets {
+		for _, problem := range checkComputeCapacity(location, requirements, vmSizes, usages) {
+			if !found[problem] {
+				found[problem] = true
+				problems = append(problems, problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("the subscription cannot create the required VMs in location %s:\n  - %s", location, strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func checkComputeCapacity(location string, requirements []VMRequirement, vmSizes map[string]compute.ResourceSku, usages map[string]compute.Usage) []string {
+	var problems []string
+	requiredCores := map[string]int64{}
+	for _, r := range requirements {
+		if r.Count <= 0 {
+			continue
+		}
+		sku, ok := vmSizes[strings.ToLower(r.VMSize)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("VM size %s of pool %s is not offered in location %s", r.VMSize, r.Pool, location))
+			continue
+		}
+		if reason := getLocationRestriction(sku, location); reason != "" {
+			problems = append(problems, fmt.Sprintf("VM size %s of pool %s is not available to the subscription in location %s (%s)", r.VMSize, r.Pool, location, reason))
+			continue
+		}
+		for _, zone := range r.Zones {
+			if !isZoneAvailable(sku, location, zone) {
+				problems = append(problems, fmt.Sprintf("VM size %s of pool %s is not available in zone %s of location %s", r.VMSize, r.Pool, zone, location))
+			}
+		}
+		cores := getVCPUs(sku) * int64(r.Count)
+		requiredCores[regionalCoresUsageName] += cores
+		requiredCores[to.String(sku.Family)] += cores
+	}
+
+	names := make([]string, 0, len(requiredCores))
+	for name := range requiredCores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		usage, ok := usages[name]
+		if !ok || usage.Limit == nil || usage.CurrentValue == nil {
+			continue
+		}
+		available := *usage.Limit - int64(*usage.CurrentValue)
+		if requiredCores[name] > available {
+			description := name
+			if usage.Name.LocalizedValue != nil {
+				description = *usage.Name.LocalizedValue
+			}
+			problems = append(problems, fmt.Sprintf("%s: %d required, %d available out of a quota of %d", description, requiredCores[name], available, *usage.Limit))
+		}
+	}
+	return problems
+}
+
+// getVMSizes returns the VM sizes offered in the location, by lower case name
+func getVMSizes(ctx context.Context, az armhelpers.AKSEngineClient, location string) (map[string]compute.ResourceSku, error) {
+	vmSizes := map[string]compute.ResourceSku{}
+	for page, err := az.ListResourceSkus(ctx); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, sku := range page.Values() {
+			if to.String(sku.ResourceType) != "virtualMachines" || sku.Name == nil || sku.Locations == nil {
+				continue
+			}
+			for _, l := range *sku.Locations {
+				if strings.EqualFold(l, location) {
+					vmSizes[strings.ToLower(*sku.Name)] = sku
+				}
+			}
+		}
+	}
+	return vmSizes, nil
+}
+
+// getComputeUsages returns the compute usages of the subscription in the location, by name, e.g. cores for the
+// regional vCPUs or standardDSv2Family for the vCPUs of a VM family
+func getComputeUsages(ctx context.Context, az armhelpers.AKSEngineClient, location string) (map[string]compute.Usage, error) {
+	usages := map[string]compute.Usage{}
+	for page, err := az.ListComputeUsages(ctx, location); page.NotDone(); err = page.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		for _, usage := range page.Values() {
+			if usage.Name != nil && usage.Name.Value != nil {
+				usages[*usage.Name.Value] = usage
+			}
+		}
+	}
+	return usages, nil
+}
+
+// getLocationRestriction returns the reason why the VM size is not available to the subscription in the location, or
+// an empty string if it is available
+func getLocationRestriction(sku compute.ResourceSku, location string) string {
+	if sku.Restrictions == nil {
+		return ""
+	}
+	for _, restriction := range *sku.Restrictions {
+		if restriction.Type != compute.Location || restriction.Values == nil {
+			continue
+		}
+		for _, l := range *restriction.Values {
+			if strings.EqualFold(l, location) {
+				return string(restriction.ReasonCode)
+			}
+		}
+	}
+	return ""
+}
+
+// isZoneAvailable returns true if the VM size is offered in the zone of the location, and the subscription can use it
+func isZoneAvailable(sku compute.ResourceSku, location, zone string) bool {
+	offered := false
+	if sku.LocationInfo != nil {
+		for _, info := range *sku.LocationInfo {
+			if strings.EqualFold(to.String(info.Location), location) && info.Zones != nil {
+				offered = offered || containsString(*info.Zones, zone)
+			}
+		}
+	}
+	if !offered || sku.Restrictions == nil {
+		return offered
+	}
+	for _, restriction := range *sku.Restrictions {
+		if restriction.Type == compute.Zone && restriction.RestrictionInfo != nil && restriction.RestrictionInfo.Zones != nil && containsString(*restriction.RestrictionInfo.Zones, zone) {
+			return false
+		}
+	}
+	return true
+}
+
+// getVCPUs returns the number of vCPUs of the VM size
+func getVCPUs(sku compute.ResourceSku) int64 {
+	if sku.Capabilities == nil {
+		return 0
+	}
+	for _, capability := range *sku.Capabilities {
+		if to.String(capability.Name) == "vCPUs" {
+			vCPUs, _ := strconv.ParseInt(to.String(capability.Value), 10, 64)
+			return vCPUs
+		}
+	}
+	return 0
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCustomNetworkResources verifies that the route table and the network security group brought by the user, if
+// any, exist in the subscription and in the location of the cluster. The cloud provider cannot manage resources of
+// another subscription, and Azure does not associate a network interface with a security group of another region.
+func CheckCustomNetworkResources(ctx context.Context, az armhelpers.AKSEngineClient, subscriptionID, location string, p *api.Properties) error {
+	if routeTableID := p.GetCustomRouteTableID(); routeTableID != "" {
+		subscription, resourceGroup, name, err := common.GetResourceIDComponents(routeTableID, "routeTables")
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(subscription, subscriptionID) {
+			return errors.Errorf("route table %s must be in subscription %s", routeTableID, subscriptionID)
+		}
+		routeTable, err := az.GetRouteTable(ctx, resourceGroup, name)
+		if err != nil {
+			return errors.Wrapf(err, "getting route table %s", routeTableID)
+		}
+		if helpers.NormalizeAzureRegion(to.String(routeTable.Location)) != location {
+			return errors.Errorf("route table %s is in location %s, it must be in location %s", routeTableID, to.String(routeTable.Location), location)
+		}
+	}
+	if nsgID := p.GetCustomNetworkSecurityGroupID(); nsgID != "" {
+		subscription, resourceGroup, name, err := common.GetResourceIDComponents(nsgID, "networkSecurityGroups")
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(subscription, subscriptionID) {
+			return errors.Errorf("network security group %s must be in subscription %s", nsgID, subscriptionID)
+		}
+		nsg, err := az.GetNetworkSecurityGroup(ctx, resourceGroup, name)
+		if err != nil {
+			return errors.Wrapf(err, "getting network security group %s", nsgID)
+		}
+		if helpers.NormalizeAzureRegion(to.String(nsg.Location)) != location {
+			return errors.Errorf("network security group %s is in location %s, it must be in location %s", nsgID, to.String(nsg.Location), location)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package operations
+
+import (
+	"context"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/pkg/armhelpers"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Compute capacity pre-flight check tests", func() {
+	var (
+		client *armhelpers.MockAKSEngineClient
+		logger *log.Entry
+	)
+
+	BeforeEach(func() {
+		client = &armhelpers.MockAKSEngineClient{}
+		client.FakeListResourceSkusResult = func() []compute.ResourceSku {
+			return []compute.ResourceSku{
+				newTestVMSize("Standard_D2_v2", "standardDv2Family", "2", "westus2", []string{"1", "2", "3"}),
+				newTestVMSize("Standard_DS2_v2", "standardDSv2Family", "2", "westus2", []string{"1", "2"}),
+				newTestVMSize("Standard_NC6", "standardNCFamily", "6", "eastus", nil),
+			}
+		}
+		client.FakeListComputeUsagesResult = func() []compute.Usage {
+			return []compute.Usage{
+				newTestUsage("cores", "Total Regional vCPUs", 11, 20),
+				newTestUsage("standardDv2Family", "Standard Dv2 Family vCPUs", 0, 10),
+				newTestUsage("standardDSv2Family", "Standard DSv2 Family vCPUs", 8, 10),
+			}
+		}
+		logger = log.NewEntry(log.New())
+	})
+
+	It("should succeed when the VMs fit in the quotas", func() {
+		requirements := []VMRequirement{
+			{Pool: "master", VMSize: "Standard_D2_v2", Count: 3, Zones: []string{"1", "2", "3"}},
+			{Pool: "agentpool1", VMSize: "standard_ds2_v2", Count: 1},
+		}
+		Expect(CheckComputeCapacity(context.Background(), client, logger, "westus2", requirements)).To(Succeed())
+	})
+
+	It("should report the exceeded quotas and the unavailable VM sizes and zones", func() {
+		requirements := []VMRequirement{
+			{Pool: "master", VMSize: "Standard_D2_v2", Count: 3},
+			{Pool: "agentpool1", VMSize: "Standard_DS2_v2", Count: 2, Zones: []string{"2", "3"}},
+			{Pool: "gpu", VMSize: "Standard_NC6", Count: 1},
+		}
+		err := CheckComputeCapacity(context.Background(), client, logger, "westus2", requirements)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(`the subscription cannot create the required VMs in location westus2:
+  - VM size Standard_DS2_v2 of pool agentpool1 is not available in zone 3 of location westus2
+  - VM size Standard_NC6 of pool gpu is not offered in location westus2
+  - Total Regional vCPUs: 10 required, 9 available out of a quota of 20
+  - Standard DSv2 Family vCPUs: 4 required, 2 available out of a quota of 10`))
+	})
+
+	It("should check each set of requirements on its own", func() {
+		requirementSets := GetUpgradeSurgeVMRequirements(&api.Properties{
+			AgentPoolProfiles: []*api.AgentPoolProfile{
+				{Name: "agentpool1", VMSize: "Standard_DS2_v2", Count: 10},
+				{Name: "agentpool2", VMSize: "Standard_DS2_v2", Count: 10},
+			},
+		})
+		Expect(requirementSets).To(HaveLen(2))
+		Expect(CheckComputeCapacity(context.Background(), client, logger, "westus2", requirementSets...)).To(Succeed())
+		Expect(CheckComputeCapacity(context.Background(), client, logger, "westus2", append(requirementSets[0], requirementSets[1]...))).NotTo(Succeed())
+	})
+
+	It("should report the VM sizes restricted for the subscription", func() {
+		client.FakeListResourceSkusResult = func() []compute.ResourceSku {
+			sku := newTestVMSize("Standard_D2_v2", "standardDv2Family", "2", "westus2", nil)
+			sku.Restrictions = &[]compute.ResourceSkuRestrictions{
+				{Type: compute.Location, Values: &[]string{"westus2"}, ReasonCode: compute.NotAvailableForSubscription},
+			}
+			return []compute.ResourceSku{sku}
+		}
+		err := CheckComputeCapacity(context.Background(), client, logger, "westus2", []VMRequirement{{Pool: "master", VMSize: "Standard_D2_v2", Count: 1}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("VM size Standard_D2_v2 of pool master is not available to the subscription in location westus2 (NotAvailableForSubscription)"))
+	})
+
+	It("should skip the check when no VM size is listed", func() {
+		client.FakeListResourceSkusResult = nil
+		Expect(CheckComputeCapacity(context.Background(), client, logger, "westus2", []VMRequirement{{Pool: "master", VMSize: "Standard_D2_v2", Count: 100}})).To(Succeed())
+	})
+
+	It("should list the VMs of a cluster", func() {
+		cs := api.CreateMockContainerService("testcluster", "1.13.5", 3, 2, false)
+		cs.Properties.OrchestratorProfile.KubernetesConfig.PrivateCluster = &api.PrivateCluster{
+			Enabled: to.BoolPtr(true),
+			JumpboxProfile: &api.PrivateJumpboxProfile{
+				VMSize: "Standard_D1_v2",
+			},
+		}
+		requirements := GetClusterVMRequirements(cs.Properties)
+		Expect(requirements).To(HaveLen(3))
+		Expect(requirements[0].Pool).To(Equal("master"))
+		Expect(requirements[0].Count).To(Equal(3))
+		Expect(requirements[1].Count).To(Equal(2))
+		Expect(requirements[2]).To(Equal(VMRequirement{Pool: "jumpbox", VMSize: "Standard_D1_v2", Count: 1}))
+	})
+})
+
+func newTestVMSize(name, family, vCPUs, location string, zones []string) compute.ResourceSku {
+	return compute.ResourceSku{
+		ResourceType: to.StringPtr("virtualMachines"),
+		Name:         to.StringPtr(name),
+		Family:       to.StringPtr(family),
+		Locations:    &[]string{location},
+		LocationInfo: &[]compute.ResourceSkuLocationInfo{
+			{Location: to.StringPtr(location), Zones: &zones},
+		},
+		Capabilities: &[]compute.ResourceSkuCapabilities{
+			{Name: to.StringPtr("vCPUs"), Value: to.StringPtr(vCPUs)},
+		},
+	}
+}
+
+func newTestUsage(name, localizedName string, currentValue int32, limit int64) compute.Usage {
+	return compute.Usage{
+		Name: &compute.UsageName{
+			Value:          to.StringPtr(name),
+			LocalizedValue: to.StringPtr(localizedName),
+		},
+		CurrentValue: to.Int32Ptr(currentValue),
+		Limit:        to.Int64Ptr(limit),
+	}
+}
+
+var _ = Describe("Custom network resources pre-flight check tests", func() {
+	const (
+		routeTableID = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/routeTables/RT_NAME"
+		nsgID        = "/subscriptions/SUB_ID/resourceGroups/RG_NAME/providers/Microsoft.Network/networkSecurityGroups/NSG_NAME"
+	)
+
+	var (
+		client *armhelpers.MockAKSEngineClient
+		cs     *api.ContainerService
+	)
+
+	BeforeEach(func() {
+		client = &armhelpers.MockAKSEngineClient{}
+		cs = api.CreateMockContainerService("testcluster", "1.15.7", 3, 2, true)
+		cs.Properties.MasterProfile.RouteTableID = routeTableID
+		cs.Properties.MasterProfile.NetworkSecurityGroupID = nsgID
+	})
+
+	It("should succeed when the resources are in the subscription and the location of the cluster", func() {
+		Expect(CheckCustomNetworkResources(context.Background(), client, "sub_id", "westus", cs.Properties)).To(Succeed())
+	})
+
+	It("should succeed when no resource is brought by the user", func() {
+		cs.Properties.MasterProfile.RouteTableID = ""
+		cs.Properties.MasterProfile.NetworkSecurityGroupID = ""
+		client.FailGetRouteTable = true
+		client.FailGetNetworkSecurityGroup = true
+		Expect(CheckCustomNetworkResources(context.Background(), client, "SUB_ID", "eastus", cs.Properties)).To(Succeed())
+	})
+
+	It("should report the resources of another subscription or location", func() {
+		err := CheckCustomNetworkResources(context.Background(), client, "OTHER_SUB_ID", "westus", cs.Properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("route table " + routeTableID + " must be in subscription OTHER_SUB_ID"))
+
+		err = CheckCustomNetworkResources(context.Background(), client, "SUB_ID", "eastus", cs.Properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("route table " + routeTableID + " is in location westus, it must be in location eastus"))
+
+		cs.Properties.MasterProfile.RouteTableID = ""
+		err = CheckCustomNetworkResources(context.Background(), client, "SUB_ID", "eastus", cs.Properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("network security group " + nsgID + " is in location westus, it must be in location eastus"))
+	})
+
+	It("should report the resources which cannot be retrieved", func() {
+		client.FailGetNetworkSecurityGroup = true
+		err := CheckCustomNetworkResources(context.Background(), client, "SUB_ID", "westus", cs.Properties)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("getting network security group " + nsgID + ": GetNetworkSecurityGroup failed"))
+	})
+})